@@ -0,0 +1,882 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var actionUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s action [OPTIONS]
+
+Run the complete GitHub action: download the coverage artifacts of the current
+workflow run and of the latest successful run on the target branch, determine
+the changed files of the pull request, generate the report and post it to the
+pull request. The report is also written to the "coverage_report" output of the
+step.
+
+If the workflow was triggered by a push (e.g. to a protected branch without a
+pull request), the changes of the push are compared with the latest successful
+run on the pushed branch instead. The condensed report is posted as comment of
+the pushed commit and the result of the coverage checks is reported as commit
+status.
+
+The action is configured via the environment variables that are set by GitHub
+Actions (GITHUB_REPOSITORY, GITHUB_RUN_ID, GITHUB_EVENT_PATH, GITHUB_OUTPUT,
+GITHUB_API_URL and GITHUB_WORKSPACE, which is used as -repo-root) and the
+following variables, which action.yml sets from the inputs of the action:
+
+  GH_TOKEN                      The token used to access the GitHub API
+  GITHUB_BASELINE_WORKFLOW      The name of the workflow that produces the baseline coverage (default: CI)
+  GITHUB_BASELINE_WORKFLOW_REF  The ref of the workflow to use instead of GITHUB_BASELINE_WORKFLOW
+  TARGET_BRANCH                 The base branch to compare the coverage results against (default: main)
+  COVERAGE_ARTIFACT_NAME        The name of the artifact containing the coverage results (default: code-coverage)
+  COVERAGE_FILE_NAME            The name of the coverage file in the artifact (default: coverage.txt)
+  PACKAGE_COVERAGE_FILE_NAME    The name of a coverage file in the artifact recorded without -coverpkg (see -package-coverage)
+  REQUIRE_PACKAGE_COVERAGE      Treat new code that is only covered by other packages as uncovered (see -require-package-coverage)
+  COVERAGE_NEUTRAL              Fail if the PR changes the coverage at all (see -neutral)
+  GRADE                         Show a composite grade (A-F) in the title (see -grade)
+  STRICT                        Fail instead of falling back to heuristics (see -strict)
+  THEME                         Emojis and tone of the report (see -theme)
+  LAYOUT                        Structure of the report, e.g. drilldown for very large PRs (see -layout)
+  TEST_JSON_FILE_NAME           The name of a file in the artifact with the output of "go test -json" (see -test-json)
+  TEST_FILE_COVERAGE            Changed test files and the names of coverage files of only their tests in the artifact (see -test-file-coverage)
+  CHANGED_FILES_PATH            A JSON file with the changed files (default: determined via the GitHub API)
+  ROOT_PACKAGE                  The import path of the tested repository (see -root)
+  TRIM_PACKAGE                  Trim a prefix in the "Impacted Packages" column (see -trim)
+  ONLY                          Restrict the report to matching files (see -only)
+  TIMEOUT                       Abort the action if it takes longer than this duration (e.g. 5m)
+  MIN_COVERAGE_NEW_CODE         Minimum coverage of new code in percent (see -min-coverage)
+  USE_GIT_DIFF                  Use git diff for line-level coverage calculation (default: true)
+  FETCH_SOURCE                  Fetch changed files that are not checked out via the GitHub API (default: true, see -fetch-source)
+  EXCLUDE_WIRING                Exclude wiring code from the coverage calculation (see -exclude-wiring)
+  EXCLUDE_DEPRECATED            Do not count new code of deprecated functions as new code (see -exclude-deprecated)
+  SKIP_COMMENT                  Skip creating or updating the pull request comment (default: false)
+  COMMENT_MODE                  "comment" or "description" (default: comment)
+  PASSING_LABEL                 Label to add when the coverage checks pass and remove otherwise
+  FAILING_LABEL                 Label to add when the coverage checks fail and remove otherwise
+  ESCALATION_TEAM               Team ("org/team-slug") that has to approve large coverage regressions
+  ESCALATION_THRESHOLD          Drop of the overall coverage in percentage points that requires an approval (default: 0, disabled)
+  STATUS_CONTEXT                The context of the commit status that is set for push events (default: go-coverage-report)
+  STATUS_TEMPLATE               Go text template of the short summary in commit statuses and check runs
+                                (e.g. "cov {{ printf \"%%.1f\" .Coverage }}%% · new {{ printf \"%%.0f\" .NewCode }}%%")
+  CHECK_NAME                    Name of a check run with the result of the coverage checks (default: none)
+  ESCALATION_CHECK_NAME         Name of the check run of the escalation (default: Coverage regression review)
+  DEBT_ISSUE                    Title of an issue that tracks uncovered code of pushes to the target branch (default: none)
+  DEBT_ISSUE_LABEL              Label of the tracking issue of DEBT_ISSUE (default: coverage-debt)
+
+All options of the main command can be passed as well. The variables above take
+precedence over GO_COVERAGE_REPORT_* environment variables.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+// actionReportFlags maps the environment variables of the action to the
+// flags of the main command.
+var actionReportFlags = []struct{ env, flag string }{
+	{"ROOT_PACKAGE", "root"},
+	{"TRIM_PACKAGE", "trim"},
+	{"ONLY", "only"},
+	{"MIN_COVERAGE_NEW_CODE", "min-coverage"},
+	{"EXCLUDE_WIRING", "exclude-wiring"},
+	{"EXCLUDE_DEPRECATED", "exclude-deprecated"},
+	{"REQUIRE_PACKAGE_COVERAGE", "require-package-coverage"},
+	{"COVERAGE_NEUTRAL", "neutral"},
+	{"GRADE", "grade"},
+	{"STRICT", "strict"},
+	{"THEME", "theme"},
+	{"LAYOUT", "layout"},
+	{"GITHUB_WORKSPACE", "repo-root"},
+}
+
+// The default name of the check run that is created for coverage regressions.
+const escalationCheckName = "Coverage regression review"
+
+// actionConfig is the configuration of the action subcommand.
+type actionConfig struct {
+	Repository       string
+	PullRequest      int
+	RunID            int64
+	BaselineWorkflow string
+	TargetBranch     string
+	ArtifactName     string
+	CoverageFileName string
+	PackageCovName   string // optional coverage file in the artifact that was recorded without -coverpkg
+	TestJSONName     string // optional file in the artifact with the output of "go test -json"
+	TestFileCovNames string // optional test files and the coverage files of only their tests in the artifact (e.g. "pkg/a_test.go=a.out")
+	ChangedFilesPath string
+	OutputDir        string // directory for intermediate files
+	GitHubOutput     string // path of the file that receives the step outputs
+
+	UseGitDiff          bool
+	FetchSource         bool // fetch source files that are not checked out (e.g. of forks)
+	SkipComment         bool
+	CommentMode         string
+	PassingLabel        string
+	FailingLabel        string
+	EscalationTeam      string
+	EscalationThreshold float64
+	EscalationCheckName string
+	CheckName           string // name of the check run of the coverage gate; empty to not create one
+	StatusTemplate      string // see defaultStatusTemplate
+	DebtIssue           string // title of the tracking issue of uncovered code; empty to not track it
+	DebtLabel           string
+
+	// Push events (see readPushEvent)
+	PushBefore    string // commit before the push; empty for pull requests
+	PushAfter     string // pushed commit
+	StatusContext string
+	ServerURL     string // used to link the commit status to the workflow run
+}
+
+func runActionCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("action", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, actionUsage)
+		fs.PrintDefaults()
+	}
+
+	registerFlags(fs)
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	cfg, err := actionConfigFromEnv(os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	opts, err := actionOptions(fs, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	a := &action{
+		cfg:  cfg,
+		opts: opts,
+		gh:   newGitHubClient(os.Getenv("GITHUB_API_URL"), githubToken(os.LookupEnv), cfg.Repository),
+		out:  os.Stdout,
+		git:  runGit,
+	}
+
+	return a.run(ctx)
+}
+
+// actionConfigFromEnv returns the configuration of the action from the
+// environment variables described in actionUsage.
+func actionConfigFromEnv(lookupEnv func(string) (string, bool)) (actionConfig, error) {
+	env := func(name, def string) string {
+		if val, ok := lookupEnv(name); ok && val != "" {
+			return val
+		}
+		return def
+	}
+
+	cfg := actionConfig{
+		Repository:       env("GITHUB_REPOSITORY", ""),
+		BaselineWorkflow: env("GITHUB_BASELINE_WORKFLOW", "CI"),
+		TargetBranch:     env("TARGET_BRANCH", "main"),
+		ArtifactName:     env("COVERAGE_ARTIFACT_NAME", "code-coverage"),
+		CoverageFileName: env("COVERAGE_FILE_NAME", "coverage.txt"),
+		PackageCovName:   env("PACKAGE_COVERAGE_FILE_NAME", ""),
+		TestJSONName:     env("TEST_JSON_FILE_NAME", ""),
+		TestFileCovNames: env("TEST_FILE_COVERAGE", ""),
+		ChangedFilesPath: env("CHANGED_FILES_PATH", ""),
+		OutputDir:        env("OUTPUT_DIR", filepath.Join(".github", "outputs")),
+		GitHubOutput:     env("GITHUB_OUTPUT", ""),
+		CommentMode:      env("COMMENT_MODE", "comment"),
+		PassingLabel:     env("PASSING_LABEL", ""),
+		FailingLabel:     env("FAILING_LABEL", ""),
+		EscalationTeam:   env("ESCALATION_TEAM", ""),
+		CheckName:        env("CHECK_NAME", ""),
+		StatusTemplate:   env("STATUS_TEMPLATE", defaultStatusTemplate),
+		DebtIssue:        env("DEBT_ISSUE", ""),
+		DebtLabel:        env("DEBT_ISSUE_LABEL", defaultDebtLabel),
+		StatusContext:    env("STATUS_CONTEXT", "go-coverage-report"),
+		ServerURL:        env("GITHUB_SERVER_URL", ""),
+	}
+
+	// The workflow file of the ref takes precedence over the workflow name.
+	if ref := env("GITHUB_BASELINE_WORKFLOW_REF", ""); ref != "" {
+		workflow, _, _ := strings.Cut(ref, "@")
+		cfg.BaselineWorkflow = path.Base(workflow)
+	}
+
+	cfg.EscalationCheckName = env("ESCALATION_CHECK_NAME", escalationCheckName)
+
+	var err error
+	parseBool := func(name string, def bool) bool {
+		val, perr := strconv.ParseBool(env(name, strconv.FormatBool(def)))
+		if perr != nil && err == nil {
+			err = fmt.Errorf("invalid %s: %w", name, perr)
+		}
+		return val
+	}
+	cfg.UseGitDiff = parseBool("USE_GIT_DIFF", true)
+	cfg.FetchSource = parseBool("FETCH_SOURCE", true)
+	cfg.SkipComment = parseBool("SKIP_COMMENT", false)
+	if err != nil {
+		return cfg, err
+	}
+
+	if cfg.EscalationThreshold, err = strconv.ParseFloat(env("ESCALATION_THRESHOLD", "0"), 64); err != nil {
+		return cfg, fmt.Errorf("invalid ESCALATION_THRESHOLD: %w", err)
+	}
+
+	if _, err := parseStatusTemplate(cfg.StatusTemplate); err != nil {
+		return cfg, fmt.Errorf("invalid STATUS_TEMPLATE: %w", err)
+	}
+
+	if runID := env("GITHUB_RUN_ID", ""); runID != "" {
+		if cfg.RunID, err = strconv.ParseInt(runID, 10, 64); err != nil {
+			return cfg, fmt.Errorf("invalid GITHUB_RUN_ID: %w", err)
+		}
+	}
+
+	if env("GITHUB_EVENT_NAME", "") == "push" {
+		event, err := readPushEvent(env("GITHUB_EVENT_PATH", ""))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.PushBefore, cfg.PushAfter = event.Before, event.After
+		if env("TARGET_BRANCH", "") == "" {
+			cfg.TargetBranch = strings.TrimPrefix(event.Ref, "refs/heads/")
+		}
+	} else if cfg.PullRequest, err = pullRequestNumber(env("PULL_REQUEST_NUMBER", ""), env("GITHUB_EVENT_PATH", "")); err != nil {
+		return cfg, err
+	}
+
+	switch {
+	case cfg.CommentMode != "comment" && cfg.CommentMode != "description":
+		return cfg, fmt.Errorf("invalid COMMENT_MODE %q: must be \"comment\" or \"description\"", cfg.CommentMode)
+	case cfg.CheckName != "" && cfg.CheckName == cfg.EscalationCheckName:
+		return cfg, fmt.Errorf("CHECK_NAME and ESCALATION_CHECK_NAME must be different (both are %q)", cfg.CheckName)
+	case cfg.Repository == "":
+		return cfg, errors.New("missing GITHUB_REPOSITORY environment variable")
+	case cfg.RunID == 0:
+		return cfg, errors.New("missing GITHUB_RUN_ID environment variable")
+	case cfg.GitHubOutput == "":
+		return cfg, errors.New("missing GITHUB_OUTPUT environment variable")
+	}
+
+	return cfg, nil
+}
+
+// pullRequestNumber returns the number of the pull request that triggered the
+// workflow. It is taken from the explicit value if it is set and otherwise
+// from the event payload at eventPath.
+func pullRequestNumber(explicit, eventPath string) (int, error) {
+	if explicit != "" {
+		n, err := strconv.Atoi(explicit)
+		if err != nil {
+			return 0, fmt.Errorf("invalid PULL_REQUEST_NUMBER: %w", err)
+		}
+		return n, nil
+	}
+
+	if eventPath == "" {
+		return 0, errors.New("missing GITHUB_EVENT_PATH environment variable")
+	}
+
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event payload: %w", err)
+	}
+
+	var event struct {
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0, fmt.Errorf("invalid event payload: %w", err)
+	}
+
+	if event.PullRequest.Number == 0 {
+		return 0, errors.New("the workflow was not triggered by a pull request")
+	}
+
+	return event.PullRequest.Number, nil
+}
+
+// actionOptions returns the options of the report. The environment variables
+// of the action are applied to all flags that were not set on the command line.
+func actionOptions(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) (options, error) {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for _, m := range actionReportFlags {
+		val, ok := lookupEnv(m.env)
+		if !ok || val == "" || set[m.flag] {
+			continue
+		}
+		if err := fs.Set(m.flag, val); err != nil {
+			return options{}, fmt.Errorf("invalid value %q for environment variable %s: %w", val, m.env, err)
+		}
+	}
+
+	cfg, _, err := resolveFlags(fs, lookupEnv)
+	if err != nil {
+		return options{}, err
+	}
+
+	opts := optionsFromFlags(fs)
+	opts.config = cfg
+	opts.format = "markdown"
+	opts.log = os.Stderr
+
+	return opts, nil
+}
+
+// githubToken returns the token for the GitHub API from the environment
+// variables that are also used by the gh CLI.
+func githubToken(lookupEnv func(string) (string, bool)) string {
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if val, ok := lookupEnv(name); ok && val != "" {
+			return val
+		}
+	}
+
+	return ""
+}
+
+// action executes the steps of the GitHub action.
+type action struct {
+	cfg  actionConfig
+	opts options
+	gh   *githubClient
+	out  io.Writer // receives the log including workflow commands
+
+	// git runs git with the given arguments and returns its stdout.
+	git func(ctx context.Context, args ...string) ([]byte, error)
+}
+
+func runGit(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// sourceFetcher returns a sourceFetcher that reads the files of the pushed
+// commit or of the head of the pull request via the GitHub API. The head of a
+// pull request from a fork is read from the fork, whose code may not be
+// checked out in the workspace.
+func (a *action) sourceFetcher(ctx context.Context) (sourceFetcher, error) {
+	if a.cfg.PushAfter != "" {
+		return a.gh.contentFetcher(a.cfg.PushAfter), nil
+	}
+
+	pr, err := a.gh.pullRequest(ctx, a.cfg.PullRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	gh := a.gh
+	if pr.Head.Repo != nil && pr.Head.Repo.FullName != "" && pr.Head.Repo.FullName != gh.repo {
+		fork := *gh
+		fork.repo = pr.Head.Repo.FullName
+		gh = &fork
+	}
+
+	return gh.contentFetcher(pr.Head.SHA), nil
+}
+
+// group runs fn in a collapsible group of the workflow log.
+func (a *action) group(name string, fn func() error) error {
+	fmt.Fprintf(a.out, "::group::%s\n", name)
+	err := fn()
+	fmt.Fprintln(a.out, "::endgroup::")
+
+	return err
+}
+
+func (a *action) path(name string) string {
+	return filepath.Join(a.cfg.OutputDir, name)
+}
+
+func (a *action) run(ctx context.Context) error {
+	if err := os.MkdirAll(a.cfg.OutputDir, 0755); err != nil {
+		return err
+	}
+
+	oldCovPath, newCovPath := a.path("old-coverage.txt"), a.path("new-coverage.txt")
+
+	opts := a.opts
+	err := a.group("Download code coverage results from current run", func() error {
+		if a.cfg.PackageCovName != "" {
+			opts.pkgCoverage = a.path("package-coverage.txt")
+			if err := a.downloadCoverage(ctx, a.cfg.RunID, a.cfg.PackageCovName, opts.pkgCoverage); err != nil {
+				return err
+			}
+		}
+
+		if a.cfg.TestJSONName != "" {
+			opts.testJSON = a.path("test-output.json")
+			if err := a.downloadCoverage(ctx, a.cfg.RunID, a.cfg.TestJSONName, opts.testJSON); err != nil {
+				return err
+			}
+		}
+
+		if a.cfg.TestFileCovNames != "" {
+			var entries []string
+			for i, entry := range strings.Split(a.cfg.TestFileCovNames, ",") {
+				testFile, name, ok := strings.Cut(strings.TrimSpace(entry), "=")
+				if !ok {
+					return fmt.Errorf("invalid test file coverage %q: expected TEST_FILE=COVERAGE_FILE_NAME", entry)
+				}
+				coverageFile := a.path(fmt.Sprintf("test-file-coverage-%d.txt", i))
+				if err := a.downloadCoverage(ctx, a.cfg.RunID, name, coverageFile); err != nil {
+					return err
+				}
+				entries = append(entries, testFile+"="+coverageFile)
+			}
+			opts.testFiles = strings.Join(entries, ",")
+		}
+
+		return a.downloadCoverage(ctx, a.cfg.RunID, a.cfg.CoverageFileName, newCovPath)
+	})
+	if err != nil {
+		return err
+	}
+
+	var baseline *githubWorkflowRun
+	err = a.group("Download code coverage results from target branch", func() error {
+		var err error
+		baseline, err = a.gh.latestSuccessfulRun(ctx, a.cfg.BaselineWorkflow, a.cfg.TargetBranch)
+		if err != nil {
+			return fmt.Errorf("failed to find baseline run: %w", err)
+		}
+		if baseline == nil {
+			return fmt.Errorf("no successful run of workflow %q found on the target branch %q", a.cfg.BaselineWorkflow, a.cfg.TargetBranch)
+		}
+
+		fmt.Fprintf(a.out, "Using coverage of run %d (commit %s)\n", baseline.ID, baseline.HeadSHA)
+		return a.downloadCoverage(ctx, baseline.ID, a.cfg.CoverageFileName, oldCovPath)
+	})
+	if err != nil {
+		return err
+	}
+
+	changedFilesPath := a.cfg.ChangedFilesPath
+	if changedFilesPath == "" {
+		changedFilesPath = a.path("all_modified_files.json")
+		err = a.group("Determine changed files", func() error {
+			return a.writeChangedFiles(ctx, changedFilesPath)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_ = a.group("Generate git diff for line-level coverage", func() error {
+		opts.diffFile = a.generateDiff(ctx)
+		if opts.diffFile == "" && opts.baseRef == "" {
+			// Let the block-based comparison match moved code by reading the
+			// old source code from the baseline commit.
+			opts.baseRef = a.baseRef(ctx, baseline.HeadSHA)
+		}
+		return nil
+	})
+
+	var result *Result
+	err = a.group("Compare code coverage results", func() error {
+		if !opts.quiet {
+			opts.progress = newProgress(a.out, "") // already in a group
+		}
+
+		if a.cfg.FetchSource {
+			fetch, err := a.sourceFetcher(ctx)
+			if err != nil {
+				fmt.Fprintf(a.out, "::warning::Failed to determine the head commit to fetch source files from: %v\n", err)
+			}
+			opts.fetcher = fetch
+		}
+
+		var err error
+		result, err = analyze(ctx, oldCovPath, newCovPath, changedFilesPath, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		fmt.Fprintln(a.out, "::notice::No coverage report to output")
+		return nil
+	}
+
+	defer result.Close()
+
+	markdown := result.Markdown()
+	if err := os.WriteFile(a.path("coverage-comment.md"), []byte(markdown+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := a.setOutput("coverage_report", markdown); err != nil {
+		return fmt.Errorf("failed to write step output: %w", err)
+	}
+	if err := a.setOutput("report_digest", reportDigest(markdown)); err != nil {
+		return fmt.Errorf("failed to write step output: %w", err)
+	}
+
+	checkErr := result.Err
+	if warning := result.identicalProfilesWarning(); warning != "" {
+		fmt.Fprintf(a.out, "::warning::%s\n", warning)
+	}
+	for _, t := range result.IneffectiveTests() {
+		fmt.Fprintf(a.out, "::warning::Tests of package %s changed, but none of the %d new statements of the package are covered\n", t.Package, t.NewStmt)
+	}
+	for _, impact := range result.SkippedTestImpacts() {
+		for _, f := range impact.Files {
+			fmt.Fprintf(a.out, "::notice file=%s::%d tests of package %s were skipped, which may reduce the coverage of this file\n", trimPrefix(f, opts.root), len(impact.Tests), impact.Package)
+		}
+		if len(impact.Files) == 0 {
+			fmt.Fprintf(a.out, "::notice::%d tests of package %s were skipped, which may explain its coverage change of %.2f%%\n", len(impact.Tests), impact.Package, impact.Delta)
+		}
+	}
+
+	if a.cfg.CheckName != "" {
+		err := a.group("Create coverage check run", func() error {
+			sha := a.cfg.PushAfter
+			if sha == "" {
+				pr, err := a.gh.pullRequest(ctx, a.cfg.PullRequest)
+				if err != nil {
+					return err
+				}
+				sha = pr.Head.SHA
+			}
+			return a.createGateCheck(ctx, sha, result, checkErr)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.cfg.PushAfter != "" {
+		if a.cfg.DebtIssue != "" {
+			err := a.group("Update coverage debt issue", func() error {
+				return a.recordDebt(ctx, result)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return errors.Join(a.group("Post coverage report", func() error {
+			return a.postCommitReport(ctx, result, checkErr)
+		}), checkErr)
+	}
+
+	// The report is posted first, so that a failure to update the labels or
+	// to escalate does not leave the pull request without it.
+	var errs []error
+	if a.cfg.SkipComment {
+		fmt.Fprintln(a.out, "Skipping pull request comment (SKIP_COMMENT=true)")
+	} else {
+		errs = append(errs, a.group("Post coverage report", func() error {
+			return a.postReport(ctx, markdown)
+		}))
+	}
+
+	if a.cfg.PassingLabel != "" || a.cfg.FailingLabel != "" {
+		errs = append(errs, a.group("Update pull request labels", func() error {
+			return a.updateLabels(ctx, checkErr == nil)
+		}))
+	}
+
+	if a.cfg.EscalationTeam != "" && a.cfg.EscalationThreshold != 0 {
+		errs = append(errs, a.group("Check coverage regression escalation", func() error {
+			return a.escalate(ctx, result.Report)
+		}))
+	}
+
+	if checkErr != nil {
+		fmt.Fprintln(a.out, "::error::Coverage check failed")
+		errs = append(errs, checkErr)
+	}
+
+	return errors.Join(errs...)
+}
+
+// downloadCoverage writes the given coverage file of the artifact of the given
+// workflow run to dest.
+func (a *action) downloadCoverage(ctx context.Context, runID int64, fileName, dest string) error {
+	data, err := a.gh.downloadArtifactFile(ctx, runID, a.cfg.ArtifactName, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to download coverage of run %d: %w", runID, err)
+	}
+
+	return os.WriteFile(dest, data, 0644)
+}
+
+// writeChangedFiles writes the Go files that were added or modified by the
+// pull request or push to dest as JSON array. Vendored files are ignored.
+func (a *action) writeChangedFiles(ctx context.Context, dest string) error {
+	var files []string
+	var err error
+	if a.cfg.PushAfter != "" {
+		files, err = a.gh.compareFiles(ctx, a.cfg.PushBefore, a.cfg.PushAfter)
+	} else {
+		files, err = a.gh.pullRequestFiles(ctx, a.cfg.PullRequest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list changed files: %w", err)
+	}
+
+	changed := []string{}
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") && !strings.HasPrefix(f, "vendor/") {
+			changed = append(changed, f)
+		}
+	}
+	if a.cfg.PushAfter != "" {
+		fmt.Fprintf(a.out, "The push changes %d Go files\n", len(changed))
+	} else {
+		fmt.Fprintf(a.out, "The pull request changes %d Go files\n", len(changed))
+	}
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dest, data, 0644)
+}
+
+// generateDiff writes the diff of all Go files between the target branch (or
+// the commit before the push) and HEAD and returns its path. It returns an
+// empty string if the diff is disabled or could not be generated.
+func (a *action) generateDiff(ctx context.Context) string {
+	if !a.cfg.UseGitDiff {
+		fmt.Fprintln(a.out, "Git diff disabled, using block-based comparison")
+		return ""
+	}
+
+	revisions := "origin/" + a.cfg.TargetBranch + "...HEAD"
+	if a.cfg.PushAfter != "" {
+		// Fetch the commit before the push to ensure we have it
+		_, _ = a.git(ctx, "fetch", "--depth=1", "origin", a.cfg.PushBefore)
+		revisions = a.cfg.PushBefore + "..HEAD"
+	} else {
+		// Fetch the target branch to ensure we have it
+		branch := a.cfg.TargetBranch
+		_, _ = a.git(ctx, "fetch", "origin", branch+":refs/remotes/origin/"+branch)
+	}
+
+	diff, err := a.git(ctx, "diff", revisions, "--", "*.go")
+	if err != nil || len(diff) == 0 {
+		fmt.Fprintln(a.out, "No diff generated or diff is empty, falling back to block-based comparison")
+		return ""
+	}
+
+	diffPath := a.path("pr-diff.patch")
+	if err := os.WriteFile(diffPath, diff, 0644); err != nil {
+		fmt.Fprintf(a.out, "::warning::Failed to write diff: %v\n", err)
+		return ""
+	}
+
+	fmt.Fprintf(a.out, "Git diff generated successfully (%d lines)\n", bytes.Count(diff, []byte("\n")))
+	return diffPath
+}
+
+// baseRef returns the given commit if it is available in the local repository
+// (fetching it if necessary) and an empty string otherwise.
+func (a *action) baseRef(ctx context.Context, commit string) string {
+	if commit == "" {
+		return ""
+	}
+
+	if _, err := a.git(ctx, "fetch", "--depth=1", "origin", commit); err == nil {
+		return commit
+	}
+	if _, err := a.git(ctx, "cat-file", "-e", commit+"^{commit}"); err == nil {
+		return commit
+	}
+
+	return ""
+}
+
+// setOutput appends a multiline output parameter to the GITHUB_OUTPUT file.
+func (a *action) setOutput(name, value string) error {
+	f, err := os.OpenFile(a.cfg.GitHubOutput, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	const delimiter = "END_OF_COVERAGE_REPORT"
+	_, err = fmt.Fprintf(f, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// updateLabels adds the passing or failing label depending on the result of
+// the coverage checks and removes the other one.
+func (a *action) updateLabels(ctx context.Context, passed bool) error {
+	add, remove := a.cfg.PassingLabel, a.cfg.FailingLabel
+	if !passed {
+		add, remove = remove, add
+	}
+
+	if add != "" {
+		// Labels must exist in the repository before they can be added.
+		if err := a.gh.createLabel(ctx, add, "Set by go-coverage-report"); err != nil {
+			fmt.Fprintf(a.out, "::warning::Failed to create label %q: %v\n", add, err)
+		}
+		if err := a.gh.addLabel(ctx, a.cfg.PullRequest, add); err != nil {
+			return fmt.Errorf("failed to add label %q: %w", add, err)
+		}
+	}
+
+	if remove != "" {
+		pr, err := a.gh.pullRequest(ctx, a.cfg.PullRequest)
+		if err != nil {
+			return err
+		}
+		for _, l := range pr.Labels {
+			if l.Name == remove {
+				return a.gh.removeLabel(ctx, a.cfg.PullRequest, remove)
+			}
+		}
+	}
+
+	return nil
+}
+
+// escalate requests a review of the escalation team if the overall coverage
+// dropped by more than the escalation threshold and reports the state of the
+// escalation as check run.
+func (a *action) escalate(ctx context.Context, report *Report) error {
+	threshold, team := a.cfg.EscalationThreshold, a.cfg.EscalationTeam
+
+	conclusion := "success"
+	title := "No review required"
+	summary := fmt.Sprintf("The overall coverage did not drop by more than %g%%.", threshold)
+
+	if -report.OverallCoverageDelta() > threshold {
+		summary = fmt.Sprintf("The overall coverage dropped by more than %g%%, which requires an approval by @%s.", threshold, team)
+
+		approvers, err := a.gh.approvers(ctx, a.cfg.PullRequest)
+		if err != nil {
+			return fmt.Errorf("failed to list reviews: %w", err)
+		}
+
+		// An approval by any active member of the escalation team resolves
+		// the escalation.
+		approved := false
+		for _, login := range approvers {
+			ok, err := a.gh.isActiveTeamMember(ctx, team, login)
+			if err != nil {
+				return fmt.Errorf("failed to check team membership: %w", err)
+			}
+			if ok {
+				approved = true
+				title = "Approved by @" + login
+				break
+			}
+		}
+
+		if !approved {
+			fmt.Fprintf(a.out, "::warning::Overall coverage dropped by more than %g%%, requesting review from %s\n", threshold, team)
+			if err := a.gh.requestTeamReview(ctx, a.cfg.PullRequest, team); err != nil {
+				return fmt.Errorf("failed to request review: %w", err)
+			}
+			conclusion = "neutral"
+			title = "Waiting for approval by @" + team
+		}
+	}
+
+	pr, err := a.gh.pullRequest(ctx, a.cfg.PullRequest)
+	if err != nil {
+		return err
+	}
+
+	return a.gh.createCheckRun(ctx, a.cfg.EscalationCheckName, pr.Head.SHA, conclusion, title, summary)
+}
+
+// postReport posts the report as pull request comment or updates the report
+// section of the pull request description, depending on the comment mode.
+func (a *action) postReport(ctx context.Context, markdown string) error {
+	// Re-runs of the workflow (e.g. because of unrelated flaky tests) usually
+	// produce the same report. It is not posted again to avoid notifications.
+	markdown, marker := withReportDigest(markdown)
+
+	if a.cfg.CommentMode == "description" {
+		pr, err := a.gh.pullRequest(ctx, a.cfg.PullRequest)
+		if err != nil {
+			return err
+		}
+
+		if strings.Contains(pr.Body, marker) {
+			fmt.Fprintln(a.out, "Coverage report is unchanged, keeping the pull request description")
+			return nil
+		}
+
+		fmt.Fprintln(a.out, "Updating the coverage section of the pull request description")
+		return a.gh.updatePullRequestBody(ctx, a.cfg.PullRequest, updateDescriptionSection(pr.Body, markdown))
+	}
+
+	comments, err := a.gh.issueComments(ctx, a.cfg.PullRequest)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range comments {
+		if c.User.Login == "github-actions[bot]" && isReportComment(c.Body) {
+			if strings.Contains(c.Body, marker) {
+				fmt.Fprintln(a.out, "Coverage report is unchanged, keeping the existing comment")
+				return nil
+			}
+			fmt.Fprintln(a.out, "Replacing old coverage report comment")
+			if err := a.gh.deleteComment(ctx, c.ID); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	fmt.Fprintln(a.out, "Creating coverage report comment")
+	return a.gh.createComment(ctx, a.cfg.PullRequest, markdown)
+}
+
+// isReportComment returns true if the comment contains a report. Reports of
+// versions before the reportMarker are detected by their coverage table.
+func isReportComment(body string) bool {
+	return strings.Contains(body, reportMarker) || strings.Contains(body, "Coverage Δ")
+}
+
+// withReportDigest appends an HTML comment with the digest of the report to
+// the report. It is returned as marker to detect whether a posted report
+// changed.
+func withReportDigest(markdown string) (body, marker string) {
+	marker = "<!-- go-coverage-report:digest:" + reportDigest(markdown) + " -->"
+	return strings.TrimRight(markdown, "\n") + "\n\n" + marker + "\n", marker
+}
+
+// reportDigest returns the hex encoded SHA-256 digest of the report.
+func reportDigest(markdown string) string {
+	sum := sha256.Sum256([]byte(markdown))
+	return hex.EncodeToString(sum[:])
+}
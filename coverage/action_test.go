@@ -0,0 +1,349 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionConfigFromEnv(t *testing.T) {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 42}}`), 0644))
+
+	env := map[string]string{
+		"GITHUB_REPOSITORY":            "fgrosse/prioqueue",
+		"GITHUB_RUN_ID":                "8221109494",
+		"GITHUB_EVENT_PATH":            eventPath,
+		"GITHUB_OUTPUT":                "/tmp/output",
+		"GITHUB_BASELINE_WORKFLOW_REF": "fgrosse/prioqueue/.github/workflows/ci.yml@refs/heads/main",
+		"USE_GIT_DIFF":                 "false",
+		"ESCALATION_THRESHOLD":         "2.5",
+		"TARGET_BRANCH":                "",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	cfg, err := actionConfigFromEnv(lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, actionConfig{
+		Repository:          "fgrosse/prioqueue",
+		PullRequest:         42,
+		RunID:               8221109494,
+		BaselineWorkflow:    "ci.yml",
+		TargetBranch:        "main",
+		ArtifactName:        "code-coverage",
+		CoverageFileName:    "coverage.txt",
+		OutputDir:           filepath.Join(".github", "outputs"),
+		GitHubOutput:        "/tmp/output",
+		UseGitDiff:          false,
+		FetchSource:         true,
+		CommentMode:         "comment",
+		EscalationThreshold: 2.5,
+		EscalationCheckName: "Coverage regression review",
+		StatusContext:       "go-coverage-report",
+		StatusTemplate:      defaultStatusTemplate,
+		DebtLabel:           "coverage-debt",
+	}, cfg)
+
+	env["CHECK_NAME"] = "Coverage regression review"
+	_, err = actionConfigFromEnv(lookupEnv)
+	assert.ErrorContains(t, err, "CHECK_NAME and ESCALATION_CHECK_NAME must be different")
+
+	env["CHECK_NAME"] = ""
+	env["STATUS_TEMPLATE"] = "cov {{ .Coverage"
+	_, err = actionConfigFromEnv(lookupEnv)
+	assert.ErrorContains(t, err, "invalid STATUS_TEMPLATE")
+
+	delete(env, "STATUS_TEMPLATE")
+
+	env["COMMENT_MODE"] = "issue"
+	_, err = actionConfigFromEnv(lookupEnv)
+	assert.ErrorContains(t, err, "invalid COMMENT_MODE")
+
+	env["COMMENT_MODE"] = ""
+	env["SKIP_COMMENT"] = "maybe"
+	_, err = actionConfigFromEnv(lookupEnv)
+	assert.ErrorContains(t, err, "invalid SKIP_COMMENT")
+
+	delete(env, "SKIP_COMMENT")
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"ref": "refs/heads/main"}`), 0644))
+	_, err = actionConfigFromEnv(lookupEnv)
+	assert.ErrorContains(t, err, "not triggered by a pull request")
+
+	env["PULL_REQUEST_NUMBER"] = "7"
+	cfg, err = actionConfigFromEnv(lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, 7, cfg.PullRequest)
+
+	env["GITHUB_EVENT_NAME"] = "push"
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"ref": "refs/heads/trunk", "before": "abc123", "after": "def456"}`), 0644))
+	cfg, err = actionConfigFromEnv(lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.PullRequest)
+	assert.Equal(t, "abc123", cfg.PushBefore)
+	assert.Equal(t, "def456", cfg.PushAfter)
+	assert.Equal(t, "trunk", cfg.TargetBranch)
+
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"ref": "refs/heads/trunk", "before": "0000000000000000000000000000000000000000", "after": "def456"}`), 0644))
+	_, err = actionConfigFromEnv(lookupEnv)
+	assert.ErrorContains(t, err, "the push created the branch")
+}
+
+// TestActionYAML_Inputs checks that every input of the composite action is
+// passed to one of its steps, since an input without an environment variable
+// is silently ignored.
+func TestActionYAML_Inputs(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "action.yml"))
+	require.NoError(t, err)
+
+	_, inputs, ok := strings.Cut(string(data), "\ninputs:\n")
+	require.True(t, ok)
+	inputs, _, ok = strings.Cut(inputs, "\noutputs:\n")
+	require.True(t, ok)
+	_, steps, ok := strings.Cut(string(data), "\nruns:\n")
+	require.True(t, ok)
+
+	var names []string
+	for _, line := range strings.Split(inputs, "\n") {
+		if name, ok := strings.CutPrefix(line, "  "); ok && !strings.HasPrefix(name, " ") && strings.HasSuffix(name, ":") {
+			names = append(names, strings.TrimSuffix(name, ":"))
+		}
+	}
+
+	require.Contains(t, names, "fetch-source")
+	for _, name := range names {
+		assert.Contains(t, steps, "${{ inputs."+name+" }}", "input %s is not passed to the action", name)
+	}
+}
+
+func TestActionOptions(t *testing.T) {
+	fs := flag.NewFlagSet("action", flag.ContinueOnError)
+	registerFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-trim=github.com/example/"}))
+
+	env := map[string]string{
+		"ROOT_PACKAGE":                    "github.com/example/repo",
+		"TRIM_PACKAGE":                    "ignored since the flag is set",
+		"MIN_COVERAGE_NEW_CODE":           "80",
+		"EXCLUDE_WIRING":                  "",
+		"GO_COVERAGE_REPORT_HTML_THEME":   "dark",
+		"GO_COVERAGE_REPORT_MIN_COVERAGE": "10",
+	}
+	opts, err := actionOptions(fs, func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "github.com/example/repo", opts.root)
+	assert.Equal(t, "github.com/example/", opts.trim)
+	assert.Equal(t, 80.0, opts.minCoverage)
+	assert.False(t, opts.excludeWiring)
+	assert.Equal(t, "dark", opts.htmlTheme)
+	assert.Equal(t, "markdown", opts.format)
+}
+
+func newTestAction(t *testing.T, gh *fakeGitHub) (*action, *bytes.Buffer) {
+	t.Helper()
+
+	oldCov, err := os.ReadFile("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := os.ReadFile("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	gh.setArtifact(1, "coverage.txt", oldCov)
+	gh.setArtifact(2, "coverage.txt", newCov)
+
+	fs := flag.NewFlagSet("action", flag.ContinueOnError)
+	registerFlags(fs)
+	opts := optionsFromFlags(fs)
+	opts.root = "github.com/pentohq/pento"
+	opts.format = "markdown"
+
+	dir := t.TempDir()
+	var out bytes.Buffer
+	a := &action{
+		cfg: actionConfig{
+			Repository:          "example/repo",
+			PullRequest:         42,
+			RunID:               2,
+			BaselineWorkflow:    "CI",
+			TargetBranch:        "main",
+			ArtifactName:        "code-coverage",
+			CoverageFileName:    "coverage.txt",
+			OutputDir:           filepath.Join(dir, "outputs"),
+			GitHubOutput:        filepath.Join(dir, "github-output"),
+			CommentMode:         "comment",
+			StatusContext:       "go-coverage-report",
+			StatusTemplate:      defaultStatusTemplate,
+			EscalationCheckName: escalationCheckName,
+		},
+		opts: opts,
+		gh:   gh.start(),
+		out:  &out,
+		git: func(ctx context.Context, args ...string) ([]byte, error) {
+			return nil, errors.New("not a git repository")
+		},
+	}
+
+	return a, &out
+}
+
+func TestAction_Run(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.labels = []string{"coverage/failing"}
+	a, out := newTestAction(t, gh)
+	a.cfg.PassingLabel = "coverage/passing"
+	a.cfg.FailingLabel = "coverage/failing"
+
+	require.NoError(t, a.run(context.Background()))
+
+	changedFiles, err := os.ReadFile(filepath.Join(a.cfg.OutputDir, "all_modified_files.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["pkg/age/age.go"]`, string(changedFiles))
+
+	output, err := os.ReadFile(a.cfg.GitHubOutput)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(output), "coverage_report<<END_OF_COVERAGE_REPORT\n"))
+	assert.Contains(t, string(output), "Coverage Δ")
+
+	require.Len(t, gh.requests, 5, gh.requests)
+	assert.Equal(t, `DELETE /repos/example/repo/issues/comments/2`, gh.requests[0])
+	assert.True(t, strings.HasPrefix(gh.requests[1], `POST /repos/example/repo/issues/42/comments {"body":"### Coverage Report - 87.50%`), gh.requests[1])
+	assert.Equal(t, `POST /repos/example/repo/labels {"description":"Set by go-coverage-report","name":"coverage/passing"}`, gh.requests[2])
+	assert.Equal(t, `POST /repos/example/repo/issues/42/labels {"labels":["coverage/passing"]}`, gh.requests[3])
+	assert.Equal(t, `DELETE /repos/example/repo/issues/42/labels/coverage%2Ffailing`, gh.requests[4])
+
+	assert.Contains(t, out.String(), "::group::Download code coverage results from target branch\n")
+	assert.Contains(t, out.String(), "Using coverage of run 1 (commit abc123)")
+}
+
+func TestAction_Run_LabelFailure(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.failures = map[string]int{"POST /repos/example/repo/issues/42/labels": http.StatusForbidden}
+	a, _ := newTestAction(t, gh)
+	a.cfg.PassingLabel = "coverage/passing"
+	a.cfg.EscalationTeam = "example/coverage-owners"
+	a.cfg.EscalationThreshold = 50
+
+	// The report is posted and the escalation is checked although the label
+	// cannot be added.
+	err := a.run(context.Background())
+	assert.ErrorContains(t, err, `failed to add label "coverage/passing"`)
+
+	require.Len(t, gh.requests, 5, gh.requests)
+	assert.True(t, strings.HasPrefix(gh.requests[1], `POST /repos/example/repo/issues/42/comments {"body":"### Coverage Report - 87.50%`), gh.requests[1])
+	assert.Equal(t, `POST /repos/example/repo/issues/42/labels {"labels":["coverage/passing"]}`, gh.requests[3])
+	assert.Contains(t, gh.requests[4], `POST /repos/example/repo/check-runs`)
+}
+
+func TestAction_Run_BaselineWorkflowOnLaterPage(t *testing.T) {
+	gh := newFakeGitHub(t)
+	for i := int64(1); i <= 100; i++ {
+		gh.workflows = append([]fakeWorkflow{{ID: 100 + i, Name: fmt.Sprintf("Other %d", i)}}, gh.workflows...)
+	}
+	a, out := newTestAction(t, gh)
+
+	require.NoError(t, a.run(context.Background()))
+	assert.Contains(t, out.String(), "Using coverage of run 1 (commit abc123)")
+}
+
+func TestAction_Run_Description(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, _ := newTestAction(t, gh)
+	a.cfg.CommentMode = "description"
+	a.opts.minCoverage = 100
+
+	err := a.run(context.Background())
+	assert.ErrorContains(t, err, "below the required threshold")
+
+	require.Len(t, gh.requests, 1, gh.requests)
+	assert.True(t, strings.HasPrefix(gh.requests[0], `PATCH /repos/example/repo/pulls/42 {"body":"Fixes a bug\n\n<!-- go-coverage-report:start -->`), gh.requests[0])
+}
+
+func TestAction_Run_Escalation(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, _ := newTestAction(t, gh)
+	a.cfg.SkipComment = true
+	a.cfg.EscalationTeam = "example/coverage-owners"
+	a.cfg.EscalationThreshold = 0.001
+	a.opts.root = "github.com/pentohq/pento"
+
+	// Make the new coverage considerably worse than the old one.
+	gh.artifacts[2]["coverage.txt"] = bytes.ReplaceAll(gh.artifacts[2]["coverage.txt"], []byte(" 1\n"), []byte(" 0\n"))
+
+	require.NoError(t, a.run(context.Background()))
+
+	require.Len(t, gh.requests, 2, gh.requests)
+	assert.Equal(t, `POST /repos/example/repo/pulls/42/requested_reviewers {"team_reviewers":["coverage-owners"]}`, gh.requests[0])
+	assert.Contains(t, gh.requests[1], `POST /repos/example/repo/check-runs`)
+	assert.Contains(t, gh.requests[1], `"conclusion":"neutral"`)
+	assert.Contains(t, gh.requests[1], `"head_sha":"def456"`)
+}
+
+func TestAction_Run_NoBaseline(t *testing.T) {
+	a, _ := newTestAction(t, newFakeGitHub(t))
+	a.cfg.BaselineWorkflow = "Nightly"
+
+	err := a.run(context.Background())
+	assert.ErrorContains(t, err, `workflow "Nightly" does not exist`)
+}
+
+func TestAction_Run_Push(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.commitComments["def456"] = []githubComment{{ID: 3, Body: "| Coverage Δ |", User: githubUser{Login: fakeBotLogin}}}
+	a, out := newTestAction(t, gh)
+	a.cfg.PullRequest = 0
+	a.cfg.PushBefore, a.cfg.PushAfter = "abc123", "def456"
+	a.cfg.StatusContext = "coverage"
+	a.cfg.ServerURL = "https://github.com"
+	a.cfg.PassingLabel = "coverage/passing" // labels are only set on pull requests
+	a.opts.minCoverage = 100
+
+	err := a.run(context.Background())
+	assert.ErrorContains(t, err, "below the required threshold")
+
+	changedFiles, err := os.ReadFile(filepath.Join(a.cfg.OutputDir, "all_modified_files.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["pkg/age/age.go"]`, string(changedFiles))
+	assert.Contains(t, out.String(), "The push changes 1 Go files")
+
+	require.Len(t, gh.requests, 3, gh.requests)
+	assert.Equal(t, `DELETE /repos/example/repo/comments/3`, gh.requests[0])
+	assert.True(t, strings.HasPrefix(gh.requests[1], `POST /repos/example/repo/commits/def456/comments {"body":"### Coverage Report - 87.50%`), gh.requests[1])
+	assert.NotContains(t, gh.requests[1], "New Code Coverage Details")
+	assert.Equal(t, `POST /repos/example/repo/statuses/def456 {"context":"coverage","description":"Coverage 87.50% (-12.50%), new code 86.36%","state":"failure","target_url":"https://github.com/example/repo/actions/runs/2"}`, gh.requests[2])
+}
+
+func TestAction_Run_CheckRun(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, out := newTestAction(t, gh)
+	a.cfg.SkipComment = true
+	a.cfg.CheckName = "coverage / backend"
+	a.cfg.StatusTemplate = `cov {{ printf "%.1f" .Coverage }}% ({{ printf "%+.1f" .Delta }}) · new {{ printf "%.0f" .NewCode }}%`
+	a.opts.minCoverage = 90
+
+	err := a.run(context.Background())
+	assert.ErrorContains(t, err, "below the required threshold")
+
+	require.Len(t, gh.checkRuns, 1)
+	run := gh.checkRuns[0]
+	assert.Equal(t, "coverage / backend", run.Name)
+	assert.Equal(t, "def456", run.HeadSHA)
+	assert.Equal(t, "failure", run.Conclusion)
+	assert.Equal(t, "cov 87.5% (-12.5) · new 86%", run.Output.Title)
+	assert.Contains(t, run.Output.Summary, "below the required threshold")
+	assert.Contains(t, run.Output.Summary, "### Coverage Report - 87.50%")
+	assert.Contains(t, out.String(), `Creating check run "coverage / backend" on commit def456`)
+}
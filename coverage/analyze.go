@@ -0,0 +1,97 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+)
+
+// Result is the analysis of a Report: the new code of each changed file, the
+// derived scores and the outcome of the coverage checks. Analyze computes it
+// once, and the formats (e.g. Markdown, JSON and HTML), the coverage checks
+// and the integrations of the action are rendered from it instead of each
+// recomputing the same state.
+type Result struct {
+	*Report // the analyzed report
+
+	Files          map[string]FileAnalysis // new code analysis of each changed file with coverage
+	NewStmt        int64
+	CoveredNewStmt int64
+
+	Grade        *Grade            // nil unless the report is graded
+	Neutrality   *Neutrality       // nil unless the PR must be coverage-neutral
+	Gates        []GateResult      // results of the gates of the config file
+	ZeroCoverage map[string]string // see ZeroCoverageReasons
+	Summary      ReportSummary
+
+	// Err joins the failed coverage checks: -min-coverage, -neutral and the
+	// gates of the config file.
+	Err error
+}
+
+// analyze parses the inputs of the main command and analyzes the report. It
+// is the single entry point of the command and the action; the returned
+// Result is nil if no changed files remain after filtering.
+func analyze(ctx context.Context, oldCovPath, newCovPath, changedFilesPath string, opts options) (*Result, error) {
+	report, err := loadReport(ctx, oldCovPath, newCovPath, changedFilesPath, opts)
+	if err != nil || report == nil {
+		return nil, err
+	}
+
+	// The analysis does not check the context, so it is not started after
+	// the timeout expired while the inputs were loaded.
+	if err := context.Cause(ctx); err != nil {
+		report.Close()
+		return nil, err
+	}
+
+	opts.progress.step("Analyzing %d changed files", len(report.ChangedFiles))
+	result := report.Analyze()
+	if opts.perCommit {
+		commits, err := gitCommitLog(ctx, opts.baseRef)
+		if err != nil {
+			report.Close()
+			return nil, err
+		}
+		report.Commits = result.commitCoverages(commits, gitBlame(ctx, opts.baseRef))
+	}
+
+	return result, nil
+}
+
+// Analyze analyzes the report. The formats are rendered from the returned
+// Result, so the report must not be modified while it is in use.
+func (r *Report) Analyze() *Result {
+	res := &Result{Report: r, Files: r.analysis()}
+	for _, a := range res.Files {
+		res.NewStmt += a.TotalNew
+		res.CoveredNewStmt += a.CoveredNew
+	}
+
+	// The scores below read the new code analysis from the result, so the
+	// files are not analyzed again.
+	if r.Graded {
+		g := res.ComputeGrade()
+		res.Grade = &g
+	}
+	if r.Neutral {
+		n := r.Neutrality(r.NeutralEpsilon)
+		res.Neutrality = &n
+	}
+	res.Gates = res.GateResults()
+	res.ZeroCoverage = r.ZeroCoverageReasons()
+
+	res.Err = errors.Join(checkMinCoverage(res, r.MinCoverage), checkNeutral(res), checkGates(res))
+	res.Summary = ReportSummary{
+		OldCoverage:    r.Old.Percent(),
+		NewCoverage:    r.New.Percent(),
+		Delta:          r.OverallCoverageDelta(),
+		NewStmt:        res.NewStmt,
+		CoveredNewStmt: res.CoveredNewStmt,
+		Passed:         res.Err == nil,
+	}
+	if res.Grade != nil {
+		res.Summary.Grade = res.Grade.Letter
+	}
+
+	return res
+}
@@ -0,0 +1,73 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	opts := options{root: "github.com/pentohq/pento", diffFile: "testdata/04-diff.patch", minCoverage: 80, grade: true, maxLineLength: defaultMaxLineLength, sampleRate: 1}
+	result, err := analyze(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	const fileName = "github.com/pentohq/pento/pkg/age/age.go"
+	require.Contains(t, result.Files, fileName)
+	assert.Equal(t, int64(11), result.NewStmt)
+	assert.Equal(t, int64(6), result.CoveredNewStmt)
+	assert.Equal(t, result.Files[fileName].TotalNew, result.NewStmt)
+	require.NotNil(t, result.Grade)
+	assert.Nil(t, result.Neutrality)
+	assert.EqualError(t, result.Err, "new code coverage 54.55% is below the required threshold of 80.00%")
+	assert.Equal(t, ReportSummary{
+		OldCoverage:    100,
+		NewCoverage:    87.5,
+		Delta:          -12.5,
+		NewStmt:        11,
+		CoveredNewStmt: 6,
+		Grade:          result.Grade.Letter,
+		Passed:         false,
+	}, result.Summary)
+
+	// The formats are rendered from the result.
+	assert.Equal(t, *result.Grade, result.ComputeGrade())
+	assert.Contains(t, result.Markdown(), "6/11 statements")
+
+	var decoded struct{ Summary ReportSummary }
+	require.NoError(t, json.Unmarshal([]byte(result.JSON()), &decoded))
+	assert.Equal(t, result.Summary, decoded.Summary)
+	assert.Nil(t, result.Report.Summary, "the report itself is not modified")
+}
+
+func TestAnalyze_NoChangedFiles(t *testing.T) {
+	opts := options{root: "github.com/pentohq/pento", only: "cmd/**", maxLineLength: defaultMaxLineLength, sampleRate: 1}
+	result, err := analyze(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestReport_Analyze(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	// Each analysis is independent of the previous ones, so a report can be
+	// modified and analyzed again.
+	report := NewReport(oldCov, newCov, changedFiles)
+	first := report.Analyze()
+	assert.NoError(t, first.Err)
+
+	report.MinCoverage = 100
+	second := report.Analyze()
+	assert.Error(t, second.Err)
+	assert.False(t, second.Summary.Passed)
+	assert.True(t, first.Summary.Passed)
+	assert.NotSame(t, first, second)
+}
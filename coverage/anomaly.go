@@ -0,0 +1,168 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// minAnomalySamples is the number of earlier snapshots that are needed to
+// detect an anomaly. Fewer snapshots are not enough to estimate the usual
+// variation of the coverage.
+const minAnomalySamples = 5
+
+// Anomaly is a drop of the coverage of the latest snapshot that is unusually
+// large compared to the variation of the previous snapshots.
+type Anomaly struct {
+	Severity string  `json:"severity"` // always "anomaly"
+	Commit   string  `json:"commit"`   // commit of the latest snapshot
+	Branch   string  `json:"branch,omitempty"`
+	Package  string  `json:"package,omitempty"` // empty for the overall coverage
+	Coverage float64 `json:"coverage"`          // coverage of the latest snapshot in percent
+	Mean     float64 `json:"mean"`              // mean coverage of the previous snapshots
+	StdDev   float64 `json:"stddev"`            // standard deviation of the previous snapshots
+}
+
+// Drop returns the difference between the mean and the latest coverage in
+// percentage points.
+func (a Anomaly) Drop() float64 {
+	return a.Mean - a.Coverage
+}
+
+func (a Anomaly) String() string {
+	scope := "overall coverage"
+	if a.Package != "" {
+		scope = "coverage of " + a.Package
+	}
+
+	return fmt.Sprintf("%s dropped to %.2f%% at %s (mean %.2f%% ± %.2f over the previous snapshots, -%.2f percentage points)",
+		scope, a.Coverage, shortCommit(a.Commit), a.Mean, a.StdDev, a.Drop())
+}
+
+// AnomalyOptions configures DetectAnomalies.
+type AnomalyOptions struct {
+	Branch  string  // only consider snapshots of this branch (all if empty)
+	Window  int     // number of previous snapshots to compare with
+	Sigma   float64 // a drop of more than Sigma standard deviations is an anomaly
+	MinDrop float64 // minimum drop in percentage points, to ignore tiny drops of a very stable coverage
+}
+
+// DetectAnomalies checks if the overall or package coverage of the latest
+// snapshot dropped by more than opts.Sigma standard deviations of the
+// previous opts.Window snapshots (a rolling window). The snapshots must be
+// ordered by time.
+func DetectAnomalies(snapshots []Snapshot, opts AnomalyOptions) []Anomaly {
+	var branch []Snapshot
+	for _, s := range snapshots {
+		if opts.Branch == "" || s.Branch == opts.Branch {
+			branch = append(branch, s)
+		}
+	}
+	if len(branch) == 0 {
+		return nil
+	}
+
+	latest := branch[len(branch)-1]
+	previous := branch[:len(branch)-1]
+	if opts.Window > 0 && len(previous) > opts.Window {
+		previous = previous[len(previous)-opts.Window:]
+	}
+
+	var anomalies []Anomaly
+	check := func(pkg string, coverage float64, values []float64) {
+		if len(values) < minAnomalySamples {
+			return
+		}
+
+		mean, stddev := meanStdDev(values)
+		drop := mean - coverage
+		if drop > opts.Sigma*stddev && drop >= opts.MinDrop {
+			anomalies = append(anomalies, Anomaly{
+				Severity: "anomaly",
+				Commit:   latest.Commit,
+				Branch:   latest.Branch,
+				Package:  pkg,
+				Coverage: coverage,
+				Mean:     mean,
+				StdDev:   stddev,
+			})
+		}
+	}
+
+	var overall []float64
+	for _, s := range previous {
+		overall = append(overall, s.Percent())
+	}
+	check("", latest.Percent(), overall)
+
+	for _, pkg := range sortedKeys(latest.Packages) {
+		var values []float64
+		for _, s := range previous {
+			if c, ok := s.Packages[pkg]; ok {
+				values = append(values, c.Percent())
+			}
+		}
+		check(pkg, latest.Packages[pkg].Percent(), values)
+	}
+
+	return anomalies
+}
+
+// meanStdDev returns the mean and the population standard deviation.
+func meanStdDev(values []float64) (mean, stddev float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	for _, v := range values {
+		stddev += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(stddev / float64(len(values)))
+}
+
+// notifyAnomalies posts the anomalies as JSON to the given webhook URL. The
+// payload contains a "text" field with a summary, so it can be sent to chat
+// webhooks (e.g. Slack) directly.
+func notifyAnomalies(ctx context.Context, client *http.Client, url string, anomalies []Anomaly) error {
+	lines := []string{fmt.Sprintf(":rotating_light: Coverage anomaly detected at %s", shortCommit(anomalies[0].Commit))}
+	for _, a := range anomalies {
+		lines = append(lines, "• "+a.String())
+	}
+
+	payload := struct {
+		Text      string    `json:"text"`
+		Severity  string    `json:"severity"`
+		Anomalies []Anomaly `json:"anomalies"`
+	}{strings.Join(lines, "\n"), "anomaly", anomalies}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification to %s failed: %s", req.URL.Host, resp.Status)
+	}
+
+	return nil
+}
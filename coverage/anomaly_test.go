@@ -0,0 +1,88 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	snapshot := func(i int, branch string, covered, pkgCovered int64) Snapshot {
+		return Snapshot{
+			Commit:      fmt.Sprintf("commit%02d", i),
+			Branch:      branch,
+			Time:        time.Date(2026, 1, i, 0, 0, 0, 0, time.UTC),
+			TotalStmt:   1000,
+			CoveredStmt: covered,
+			Packages: map[string]StmtCount{
+				"example.com/app/a": {Total: 100, Covered: pkgCovered},
+				"example.com/app/b": {Total: 100, Covered: 50},
+			},
+		}
+	}
+
+	var snapshots []Snapshot
+	for i, covered := range []int64{800, 802, 799, 801, 800, 798, 802} {
+		snapshots = append(snapshots, snapshot(i+1, "main", covered, 80))
+	}
+	opts := AnomalyOptions{Branch: "main", Window: 20, Sigma: 3, MinDrop: 0.5}
+
+	// Regular variation
+	assert.Empty(t, DetectAnomalies(append(snapshots, snapshot(8, "main", 797, 80)), opts))
+
+	// Other branches are ignored.
+	assert.Empty(t, DetectAnomalies(append(snapshots, snapshot(8, "feature", 500, 10)), opts))
+
+	// Deleted tests of package a
+	anomalies := DetectAnomalies(append(snapshots, snapshot(8, "main", 740, 20)), opts)
+	require.Len(t, anomalies, 2)
+	assert.Equal(t, "", anomalies[0].Package)
+	assert.Equal(t, "commit08", anomalies[0].Commit)
+	assert.Equal(t, "anomaly", anomalies[0].Severity)
+	assert.InDelta(t, 74.0, anomalies[0].Coverage, 0.001)
+	assert.InDelta(t, 80.03, anomalies[0].Mean, 0.01)
+	assert.InDelta(t, 6.03, anomalies[0].Drop(), 0.01)
+	assert.Equal(t, "example.com/app/a", anomalies[1].Package)
+	assert.Equal(t, 0.0, anomalies[1].StdDev)
+
+	// Not enough snapshots
+	assert.Empty(t, DetectAnomalies([]Snapshot{snapshots[0], snapshot(2, "main", 100, 0)}, opts))
+
+	// Tiny drops of a constant coverage are not an anomaly.
+	opts.MinDrop = 1.5
+	assert.Empty(t, DetectAnomalies(append(snapshots, snapshot(8, "main", 800, 79)), opts))
+}
+
+func TestNotifyAnomalies(t *testing.T) {
+	var payload struct {
+		Text      string    `json:"text"`
+		Severity  string    `json:"severity"`
+		Anomalies []Anomaly `json:"anomalies"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer srv.Close()
+
+	anomalies := []Anomaly{{Severity: "anomaly", Commit: "0123456789abcdef", Coverage: 70, Mean: 80, StdDev: 1}}
+	require.NoError(t, notifyAnomalies(context.Background(), srv.Client(), srv.URL, anomalies))
+
+	assert.Equal(t, "anomaly", payload.Severity)
+	assert.Equal(t, anomalies, payload.Anomalies)
+	assert.Contains(t, payload.Text, "overall coverage dropped to 70.00% at 0123456")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+	assert.Error(t, notifyAnomalies(context.Background(), failing.Client(), failing.URL, anomalies))
+}
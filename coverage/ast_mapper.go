@@ -1,10 +1,11 @@
-package main
+package coverage
 
 import (
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
+	"strings"
 )
 
 // StatementLineMapper maps statements to their line numbers using AST parsing
@@ -137,11 +138,143 @@ func (m *StatementLineMapper) GetStatementLinesInRange(filePath string, startLin
 	}
 
 	statementsInRange := make(map[int]bool)
-	for line := startLine; line <= endLine; line++ {
-		if allStatements[line] {
+	for line := range allStatements {
+		if startLine <= line && line <= endLine {
 			statementsInRange[line] = true
 		}
 	}
 
 	return statementsInRange, nil
 }
+
+// FunctionInfo describes the position and cyclomatic complexity of a function
+// declaration in a Go source file.
+type FunctionInfo struct {
+	Name       string
+	StartLine  int
+	EndLine    int
+	Complexity int
+}
+
+// GetFunctions returns all function declarations of the given file together
+// with their cyclomatic complexity.
+func (m *StatementLineMapper) GetFunctions(filePath string) ([]FunctionInfo, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseFile(m.fset, filePath, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var funcs []FunctionInfo
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		funcs = append(funcs, FunctionInfo{
+			Name:       fn.Name.Name,
+			StartLine:  m.fset.Position(fn.Pos()).Line,
+			EndLine:    m.fset.Position(fn.End()).Line,
+			Complexity: cyclomaticComplexity(fn.Body),
+		})
+	}
+
+	return funcs, nil
+}
+
+// cyclomaticComplexity returns the cyclomatic complexity of the given node,
+// i.e. one plus the number of decision points it contains.
+func cyclomaticComplexity(n ast.Node) int {
+	complexity := 1
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil { // the default case is not a decision point
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+
+	return complexity
+}
+
+// sourceRange is a range of a source file between two positions. Columns are
+// 1-based byte offsets and the end is exclusive.
+type sourceRange struct {
+	startLine, startCol, endLine, endCol int
+}
+
+// contains returns whether the given position is inside of the range.
+func (s sourceRange) contains(line, col int) bool {
+	afterStart := line > s.startLine || (line == s.startLine && col >= s.startCol)
+	beforeEnd := line < s.endLine || (line == s.endLine && col < s.endCol)
+	return afterStart && beforeEnd
+}
+
+// getErrorPaths returns the ranges of the bodies of all "if err != nil"
+// statements of the given file.
+func (m *StatementLineMapper) getErrorPaths(filePath string) ([]sourceRange, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseFile(m.fset, filePath, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []sourceRange
+	ast.Inspect(file, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.IfStmt)
+		if ok && isErrorCheck(stmt.Cond) {
+			start, end := m.fset.Position(stmt.Body.Lbrace), m.fset.Position(stmt.Body.End())
+			paths = append(paths, sourceRange{start.Line, start.Column, end.Line, end.Column})
+		}
+		return true
+	})
+
+	return paths, nil
+}
+
+// isErrorCheck returns whether the expression compares an error with nil,
+// e.g. "err != nil" or "s.lastErr != nil".
+func isErrorCheck(cond ast.Expr) bool {
+	bin, ok := cond.(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ {
+		return false
+	}
+
+	isNil := func(e ast.Expr) bool {
+		ident, ok := e.(*ast.Ident)
+		return ok && ident.Name == "nil"
+	}
+	isErr := func(e ast.Expr) bool {
+		var name string
+		switch e := e.(type) {
+		case *ast.Ident:
+			name = e.Name
+		case *ast.SelectorExpr:
+			name = e.Sel.Name
+		}
+		return name == "err" || strings.HasSuffix(name, "Err")
+	}
+
+	return (isNil(bin.Y) && isErr(bin.X)) || (isNil(bin.X) && isErr(bin.Y))
+}
@@ -1,4 +1,4 @@
-package main
+package coverage
 
 import (
 	"os"
@@ -131,3 +131,39 @@ func TestStatementLineMapper_RealWorldExample(t *testing.T) {
 	// Just verify we found some statements
 	assert.Greater(t, len(statementLines), 0, "Should find at least some statements")
 }
+
+func TestStatementLineMapper_GetFunctions(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	code := `package main
+
+func simple() int {
+	return 1
+}
+
+func complex(x int, ok bool) int {
+	if x > 0 && ok {
+		return x
+	}
+	for i := 0; i < x; i++ {
+		switch i {
+		case 1:
+			return i
+		default:
+		}
+	}
+	return 0
+}
+`
+	err := os.WriteFile(testFile, []byte(code), 0644)
+	require.NoError(t, err)
+
+	mapper := NewStatementLineMapper()
+	funcs, err := mapper.GetFunctions(testFile)
+	require.NoError(t, err)
+	require.Len(t, funcs, 2)
+
+	assert.Equal(t, FunctionInfo{Name: "simple", StartLine: 3, EndLine: 5, Complexity: 1}, funcs[0])
+	assert.Equal(t, FunctionInfo{Name: "complex", StartLine: 7, EndLine: 19, Complexity: 5}, funcs[1])
+}
@@ -0,0 +1,109 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditCSVHeader is the header of the CSV export (see -format=csv).
+var auditCSVHeader = []string{
+	"timestamp", "commit", "file", "package", "changed",
+	"old_statements", "old_covered", "old_coverage",
+	"new_statements", "new_covered", "new_coverage", "delta",
+}
+
+// CSV returns the coverage of every file of the old and new coverage as CSV
+// with the given timestamp and the commit of the report in each row, so that
+// the rows can be attached to change records or merged across commits.
+func (r *Report) CSV(at time.Time) string {
+	changed := make(map[string]bool, len(r.ChangedFiles))
+	for _, f := range r.ChangedFiles {
+		changed[f] = true
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(auditCSVHeader)
+
+	timestamp := at.UTC().Format(time.RFC3339)
+	for _, fileName := range sortedKeys(unionKeys(r.Old.Files, r.New.Files)) {
+		oldProfile, newProfile := r.Old.Files[fileName], r.New.Files[fileName]
+		_ = w.Write([]string{
+			timestamp,
+			r.Commit,
+			fileName,
+			path.Dir(fileName),
+			strconv.FormatBool(changed[fileName]),
+			strconv.FormatInt(oldProfile.GetTotal(), 10),
+			strconv.FormatInt(oldProfile.GetCovered(), 10),
+			fmt.Sprintf("%.2f", oldProfile.CoveragePercent()),
+			strconv.FormatInt(newProfile.GetTotal(), 10),
+			strconv.FormatInt(newProfile.GetCovered(), 10),
+			fmt.Sprintf("%.2f", newProfile.CoveragePercent()),
+			fmt.Sprintf("%.2f", newProfile.CoveragePercent()-oldProfile.CoveragePercent()),
+		})
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// PDF returns a printable rendering of the report with the given timestamp
+// (see -format=pdf). It contains the summary, the coverage of the changed
+// packages and the coverage of the changed files.
+func (r *Result) PDF(at time.Time) []byte {
+	prCov, _, totalNew, coveredNew := r.PRCoverageInfo()
+	n := r.numbers()
+
+	lines := []string{
+		"Coverage Report",
+		"",
+		"Generated:        " + at.UTC().Format(time.RFC3339),
+	}
+	if r.Commit != "" {
+		lines = append(lines, "Commit:           "+r.Commit)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Overall coverage: %s (previously %s, %s)", n.Percent(r.New.Percent()), n.Percent(r.Old.Percent()), n.Delta(r.OverallCoverageDelta())),
+		fmt.Sprintf("New code:         %s (%s of %s statements)", prCov, n.Count(coveredNew), n.Count(totalNew)),
+	)
+
+	gate := "passed"
+	if err := r.Err; err != nil {
+		gate = "failed: " + strings.ReplaceAll(err.Error(), "\n", "; ")
+	}
+	if r.MinCoverage > 0 || r.Neutral || len(r.Gates) > 0 {
+		lines = append(lines, "Coverage gate:    "+gate)
+	}
+
+	oldPkgs, newPkgs := r.Old.ByPackage(), r.New.ByPackage()
+	lines = append(lines, "", "Changed packages", "")
+	lines = append(lines, fmt.Sprintf("%-56s %9s %9s %9s", "Package", "Old", "New", "Change"))
+	for _, pkg := range r.ChangedPackages {
+		var oldPercent, newPercent float64
+		if c, ok := oldPkgs[pkg]; ok {
+			oldPercent = c.Percent()
+		}
+		if c, ok := newPkgs[pkg]; ok {
+			newPercent = c.Percent()
+		}
+		lines = append(lines, fmt.Sprintf("%-56s %9s %9s %9s", pkg, n.Percent(oldPercent), n.Percent(newPercent), n.Delta(newPercent-oldPercent)))
+	}
+
+	lines = append(lines, "", "Changed files", "")
+	lines = append(lines, fmt.Sprintf("%-56s %9s %9s %9s", "File", "Old", "New", "Change"))
+	for _, fileName := range r.ChangedFiles {
+		oldProfile, newProfile := r.Old.Files[fileName], r.New.Files[fileName]
+		if newProfile == nil {
+			continue // not a Go file with statements
+		}
+		lines = append(lines, fmt.Sprintf("%-56s %9s %9s %9s", fileName, n.Percent(oldProfile.CoveragePercent()), n.Percent(newProfile.CoveragePercent()), n.Delta(newProfile.CoveragePercent()-oldProfile.CoveragePercent())))
+	}
+
+	return renderPDF(lines)
+}
@@ -0,0 +1,78 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_CSV(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.Commit = "2222222222"
+
+	lines := strings.Split(strings.TrimSpace(report.CSV(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))), "\n")
+	require.Len(t, lines, 1+len(newCov.Files))
+	assert.Equal(t, "timestamp,commit,file,package,changed,old_statements,old_covered,old_coverage,new_statements,new_covered,new_coverage,delta", lines[0])
+	assert.Contains(t, lines, "2026-01-02T03:04:05Z,2222222222,github.com/fgrosse/prioqueue/min_heap.go,github.com/fgrosse/prioqueue,true,"+
+		fmt.Sprintf("%d,%d,100.00,%d,%d,%.2f,%.2f",
+			oldCov.Files["github.com/fgrosse/prioqueue/min_heap.go"].TotalStmt,
+			oldCov.Files["github.com/fgrosse/prioqueue/min_heap.go"].CoveredStmt,
+			newCov.Files["github.com/fgrosse/prioqueue/min_heap.go"].TotalStmt,
+			newCov.Files["github.com/fgrosse/prioqueue/min_heap.go"].CoveredStmt,
+			newCov.Files["github.com/fgrosse/prioqueue/min_heap.go"].CoveragePercent(),
+			newCov.Files["github.com/fgrosse/prioqueue/min_heap.go"].CoveragePercent()-100,
+		))
+}
+
+func TestReport_PDF(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.Commit = "2222222222"
+	report.MinCoverage = 100
+
+	pdf := report.Analyze().PDF(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "(Commit:           2222222222) '")
+	assert.Contains(t, string(pdf), "(Overall coverage: 90.20% \\(previously 100.00%, -9.80%\\)) '")
+	assert.Contains(t, string(pdf), "(Coverage gate:    failed: new code coverage")
+
+	// The cross-reference table must point to the objects.
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, startxref)
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	assert.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n0 6\n")))
+	for i, offset := range regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf, -1) {
+		n, _ := strconv.Atoi(string(offset[1]))
+		assert.True(t, bytes.HasPrefix(pdf[n:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
+
+func TestRenderPDF_Pages(t *testing.T) {
+	lines := make([]string, pdfLinesPerPage+1)
+	lines[0] = "café " + strings.Repeat("x", 200)
+
+	pdf := string(renderPDF(lines))
+	assert.Contains(t, pdf, "/Count 2")
+	assert.Contains(t, pdf, "(caf? "+strings.Repeat("x", pdfMaxLineChars-5)+") '")
+}
@@ -0,0 +1,191 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backfillTimeLayouts are the formats of dates in the names of the coverage
+// files of "history backfill". Colons are often not allowed in file names,
+// so times may also use dashes.
+var backfillTimeLayouts = []string{time.RFC3339, "2006-01-02T15-04-05Z", "2006-01-02T15-04-05", "2006-01-02"}
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// BackfillFile is a historical coverage file whose commit and/or time is
+// encoded in its name, e.g. "3f2a9c1.out", "2024-01-31.out" or
+// "2024-01-31_3f2a9c1.out" (parts are separated by underscores).
+type BackfillFile struct {
+	Path   string
+	Commit string
+	Time   time.Time
+}
+
+// ParseBackfillName returns the commit and time that are encoded in the name
+// of a coverage file. Either of them may be empty.
+func ParseBackfillName(path string) (BackfillFile, error) {
+	f := BackfillFile{Path: path}
+	name := filepath.Base(path)
+	if ext := filepath.Ext(name); ext != "" {
+		name = strings.TrimSuffix(name, ext)
+	}
+
+parts:
+	for _, part := range strings.Split(name, "_") {
+		if f.Commit == "" && commitPattern.MatchString(part) {
+			f.Commit = part
+			continue
+		}
+		if f.Time.IsZero() {
+			for _, layout := range backfillTimeLayouts {
+				if t, err := time.Parse(layout, part); err == nil {
+					f.Time = t.UTC()
+					continue parts
+				}
+			}
+		}
+
+		return f, fmt.Errorf("cannot determine the commit or date from file name %q", filepath.Base(path))
+	}
+
+	return f, nil
+}
+
+// gitLookup resolves the parts of a BackfillFile that are not encoded in
+// its name.
+type gitLookup interface {
+	// CommitTime returns the commit time of the given commit.
+	CommitTime(ctx context.Context, commit string) (time.Time, error)
+	// LastCommitBefore returns the last commit of the branch before t.
+	LastCommitBefore(ctx context.Context, branch string, t time.Time) (string, error)
+}
+
+// localGit looks up commits in the repository of the working directory.
+type localGit struct{}
+
+func (localGit) CommitTime(ctx context.Context, commit string) (time.Time, error) {
+	out, err := exec.CommandContext(ctx, "git", "show", "-s", "--format=%cI", commit).Output()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+}
+
+func (localGit) LastCommitBefore(ctx context.Context, branch string, t time.Time) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "rev-list", "-1", "--before="+t.Format(time.RFC3339), branch).Output()
+	if err != nil {
+		return "", err
+	}
+	if commit := strings.TrimSpace(string(out)); commit != "" {
+		return commit, nil
+	}
+
+	return "", fmt.Errorf("no commit of %s before %s", branch, t.Format(time.RFC3339))
+}
+
+// resolve fills in the commit or time of the file via git. Without a commit,
+// the last commit of the day (or before the exact time) is used or, if git
+// does not know it, the name of the file. Without a time, the commit time is
+// used or, if git does not know the commit, the modification time of the file.
+func (f *BackfillFile) resolve(ctx context.Context, git gitLookup, branch string) error {
+	if f.Commit == "" {
+		before := f.Time
+		if before.Equal(before.Truncate(24 * time.Hour)) {
+			before = before.Add(24 * time.Hour) // a date without time of day
+		}
+		if branch == "" {
+			branch = "HEAD"
+		}
+
+		var err error
+		f.Commit, err = git.LastCommitBefore(ctx, branch, before)
+		if err != nil {
+			f.Commit = strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path))
+		}
+	}
+
+	if f.Time.IsZero() {
+		var err error
+		f.Time, err = git.CommitTime(ctx, f.Commit)
+		if err != nil {
+			info, err := os.Stat(f.Path)
+			if err != nil {
+				return err
+			}
+			f.Time = info.ModTime()
+		}
+		f.Time = f.Time.UTC()
+	}
+
+	return nil
+}
+
+// backfill adds a snapshot of every coverage file in dir to the history.
+// Commits that are already part of the history are skipped, so the backfill
+// can be repeated safely. The snapshots are added in chronological order.
+func (h *History) backfill(ctx context.Context, dir string, git gitLookup, branch, trim string) (added, skipped int, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var files []BackfillFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		f, err := ParseBackfillName(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := f.resolve(ctx, git, branch); err != nil {
+			return 0, 0, err
+		}
+		files = append(files, f)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Time.Before(files[j].Time)
+	})
+
+	snapshots, err := h.Snapshots()
+	if err != nil {
+		return 0, 0, err
+	}
+	known := make(map[string]bool, len(snapshots))
+	for _, s := range snapshots {
+		known[s.Commit] = true
+	}
+
+	for _, f := range files {
+		if known[f.Commit] {
+			skipped++
+			continue
+		}
+
+		cov, err := ParseCoverageContext(ctx, f.Path)
+		if err != nil {
+			return added, skipped, fmt.Errorf("failed to parse %s: %w", f.Path, err)
+		}
+		if trim != "" {
+			cov.TrimPrefix(trim)
+		}
+
+		if err := h.Add(NewSnapshot(cov, f.Commit, branch, f.Time)); err != nil {
+			return added, skipped, err
+		}
+		known[f.Commit] = true
+		added++
+	}
+
+	return added, skipped, nil
+}
@@ -0,0 +1,95 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBackfillName(t *testing.T) {
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		commit string
+		time   time.Time
+	}{
+		{"3f2a9c1.out", "3f2a9c1", time.Time{}},
+		{"2024-01-31.txt", "", day},
+		{"2024-01-31_3f2a9c1.out", "3f2a9c1", day},
+		{"3f2a9c1_2024-01-31T10-30-00Z", "3f2a9c1", day.Add(10*time.Hour + 30*time.Minute)},
+		{"2024-01-31T10:30:00+01:00.out", "", day.Add(9*time.Hour + 30*time.Minute)},
+	}
+
+	for _, c := range cases {
+		f, err := ParseBackfillName(filepath.Join("dir", c.name))
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.commit, f.Commit, c.name)
+		assert.True(t, c.time.Equal(f.Time), "%s: %s", c.name, f.Time)
+	}
+
+	_, err := ParseBackfillName("coverage.out")
+	assert.Error(t, err)
+}
+
+type fakeGit struct {
+	times   map[string]time.Time
+	commits map[string]string // date -> last commit before the end of the day
+}
+
+func (g fakeGit) CommitTime(_ context.Context, commit string) (time.Time, error) {
+	if t, ok := g.times[commit]; ok {
+		return t, nil
+	}
+	return time.Time{}, errors.New("unknown commit")
+}
+
+func (g fakeGit) LastCommitBefore(_ context.Context, _ string, t time.Time) (string, error) {
+	if c, ok := g.commits[t.Add(-24*time.Hour).Format("2006-01-02")]; ok {
+		return c, nil
+	}
+	return "", errors.New("no commit")
+}
+
+func TestHistory_Backfill(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, profile string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("mode: set\n"+profile), 0644))
+	}
+	write("aaaaaaa.out", "example.com/app/a.go:1.1,2.2 2 1\n")
+	write("2024-01-02.out", "example.com/app/a.go:1.1,2.2 2 0\n")
+	write("2024-01-03.out", "example.com/app/a.go:1.1,2.2 4 1\n")
+	write(".gitkeep", "")
+
+	git := fakeGit{
+		times:   map[string]time.Time{"aaaaaaa": time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		commits: map[string]string{"2024-01-02": "bbbbbbb"},
+	}
+
+	h := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, h.Add(Snapshot{Commit: "bbbbbbb", Time: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}))
+
+	added, skipped, err := h.backfill(context.Background(), dir, git, "main", "example.com/app/")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 1, skipped)
+
+	snapshots, err := h.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, "aaaaaaa", snapshots[0].Commit)
+	assert.Equal(t, "main", snapshots[0].Branch)
+	assert.Equal(t, StmtCount{Total: 2, Covered: 2}, snapshots[0].Files["a.go"])
+	assert.Equal(t, "bbbbbbb", snapshots[1].Commit)
+	assert.Equal(t, "2024-01-03", snapshots[2].Commit, "the file name is used if git does not know a commit")
+
+	added, skipped, err = h.backfill(context.Background(), dir, git, "main", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 3, skipped)
+}
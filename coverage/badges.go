@@ -0,0 +1,218 @@
+package coverage
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The markers that delimit the coverage section in the README of a package.
+const (
+	badgeStartMarker = "<!-- go-coverage-report:badge:start -->"
+	badgeEndMarker   = "<!-- go-coverage-report:badge:end -->"
+)
+
+var badgesUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s badges [OPTIONS] <COVERAGE_FILE>
+
+Update the coverage badges in the README files of the packages of a Go module.
+Every README.md below the module root that contains the following markers gets
+a coverage badge and a table with the coverage of the package in its directory
+and of all packages below it inserted between them:
+
+  %s
+  %s
+
+README files without the markers are left untouched. The badges are static
+images of shields.io, so no coverage service is needed. Commit the updated
+files, e.g. in the workflow that runs on the main branch.
+
+The module path is read from the go.mod file in the module root unless it is
+set via -module.
+
+OPTIONS:
+`, filepath.Base(os.Args[0]), badgeStartMarker, badgeEndMarker))
+
+func runBadgesCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("badges", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, badgesUsage)
+		fs.PrintDefaults()
+	}
+
+	dir := fs.String("dir", ".", "root directory of the module")
+	module := fs.String("module", "", "module path (default: read from go.mod in -dir)")
+	check := fs.Bool("check", false, "do not write the README files but fail if any of them is outdated")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly 1 argument but got %d", fs.NArg())
+	}
+
+	if *module == "" {
+		var err error
+		*module, err = readModulePath(filepath.Join(*dir, "go.mod"))
+		if err != nil {
+			return fmt.Errorf("failed to determine module path (use -module): %w", err)
+		}
+	}
+
+	cov, err := ParseCoverageContext(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to parse coverage: %w", err)
+	}
+
+	updates, err := updateBadges(*dir, *module, cov)
+	if err != nil {
+		return err
+	}
+
+	var outdated []string
+	for _, u := range updates {
+		if *check {
+			outdated = append(outdated, u.Path)
+			continue
+		}
+		if err := os.WriteFile(u.Path, []byte(u.Content), 0644); err != nil {
+			return err
+		}
+		fmt.Println("Updated", u.Path)
+	}
+
+	if len(outdated) > 0 {
+		return fmt.Errorf("outdated coverage badges: %s", strings.Join(outdated, ", "))
+	}
+	if len(updates) == 0 {
+		fmt.Println("All coverage badges are up to date")
+	}
+
+	return nil
+}
+
+// badgeUpdate is the new content of a README file.
+type badgeUpdate struct {
+	Path    string
+	Content string
+}
+
+// updateBadges returns the README files below dir whose coverage section
+// differs from the coverage of the packages in their directory.
+func updateBadges(dir, module string, cov *Coverage) ([]badgeUpdate, error) {
+	packages := cov.Filter(func(fileName string) bool {
+		pkg := path.Dir(fileName)
+		return pkg == module || strings.HasPrefix(pkg, module+"/")
+	}).ByPackage()
+
+	var updates []badgeUpdate
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() && p != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "testdata"):
+			return filepath.SkipDir
+		case d.IsDir() || d.Name() != "README.md":
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		pkg := module
+		if rel != "." {
+			pkg = path.Join(module, filepath.ToSlash(rel))
+		}
+
+		content, ok := updateBadgeSection(string(data), badgeSection(pkg, packages))
+		if ok && content != string(data) {
+			updates = append(updates, badgeUpdate{Path: p, Content: content})
+		}
+		return nil
+	})
+
+	return updates, err
+}
+
+// updateBadgeSection returns the README with the text between the badge
+// markers replaced by the section. If the README does not contain the
+// markers, ok is false.
+func updateBadgeSection(readme, section string) (string, bool) {
+	start := strings.Index(readme, badgeStartMarker)
+	if start < 0 {
+		return readme, false
+	}
+
+	end := strings.Index(readme[start:], badgeEndMarker)
+	if end < 0 {
+		return readme, false
+	}
+	end += start
+
+	return readme[:start+len(badgeStartMarker)] + "\n" + section + readme[end:], true
+}
+
+// badgeSection returns the badge and the coverage table of the package pkg
+// and all packages below it.
+func badgeSection(pkg string, packages map[string]*Coverage) string {
+	var names []string
+	var total, covered int64
+	for name, c := range packages {
+		if name == pkg || strings.HasPrefix(name, pkg+"/") {
+			names = append(names, name)
+			total += c.TotalStmt
+			covered += c.CoveredStmt
+		}
+	}
+	sort.Strings(names)
+
+	var section strings.Builder
+	if len(names) == 0 {
+		fmt.Fprintf(&section, "![coverage](%s)\n", badgeURL("no coverage", "lightgrey"))
+		return section.String()
+	}
+
+	percent := float64(covered) / float64(total) * 100
+	fmt.Fprintf(&section, "![coverage](%s)\n\n", badgeURL(fmt.Sprintf("%.1f%%", percent), badgeColor(percent)))
+	fmt.Fprintln(&section, "| Package | Statements | Covered | Coverage |")
+	fmt.Fprintln(&section, "|---------|------------|---------|----------|")
+	for _, name := range names {
+		c := packages[name]
+		fmt.Fprintf(&section, "| %s | %d | %d | %.2f%% |\n", name, c.TotalStmt, c.CoveredStmt, c.Percent())
+	}
+
+	return section.String()
+}
+
+// badgeURL returns the URL of a static shields.io badge with the label
+// "coverage".
+func badgeURL(message, color string) string {
+	// Dashes and underscores have a special meaning in the path of a badge.
+	escape := strings.NewReplacer("-", "--", "_", "__").Replace
+	return fmt.Sprintf("https://img.shields.io/badge/coverage-%s-%s", url.PathEscape(escape(message)), color)
+}
+
+func badgeColor(percent float64) string {
+	switch {
+	case percent >= 80:
+		return "brightgreen"
+	case percent >= 60:
+		return "yellow"
+	case percent >= 40:
+		return "orange"
+	default:
+		return "red"
+	}
+}
@@ -0,0 +1,68 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateBadges(t *testing.T) {
+	profiles, err := ParseProfilesFromReader(strings.NewReader(`mode: set
+example.com/app/main.go:1.1,2.2 4 1
+example.com/app/store/store.go:1.1,2.2 3 1
+example.com/app/store/store.go:3.1,4.2 1 0
+example.com/app/store/sql/sql.go:1.1,2.2 4 0
+example.com/other/other.go:1.1,2.2 1 1
+`))
+	require.NoError(t, err)
+	cov := New(profiles)
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("README.md", "# App\n\nNo markers here.\n")
+	write("store/README.md", "# Store\n\n"+badgeStartMarker+"\nold\n"+badgeEndMarker+"\n\nUsage\n")
+	write("docs/README.md", badgeStartMarker+badgeEndMarker)
+	write("vendor/example.com/lib/README.md", badgeStartMarker+badgeEndMarker)
+
+	updates, err := updateBadges(dir, "example.com/app", cov)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+
+	assert.Equal(t, filepath.Join(dir, "docs", "README.md"), updates[0].Path)
+	assert.Equal(t, badgeStartMarker+"\n![coverage](https://img.shields.io/badge/coverage-no%20coverage-lightgrey)\n"+badgeEndMarker, updates[0].Content)
+
+	assert.Equal(t, filepath.Join(dir, "store", "README.md"), updates[1].Path)
+	assert.Equal(t, `# Store
+
+`+badgeStartMarker+`
+![coverage](https://img.shields.io/badge/coverage-37.5%25-red)
+
+| Package | Statements | Covered | Coverage |
+|---------|------------|---------|----------|
+| example.com/app/store | 4 | 3 | 75.00% |
+| example.com/app/store/sql | 4 | 0 | 0.00% |
+`+badgeEndMarker+`
+
+Usage
+`, updates[1].Content)
+
+	// Up to date files are not updated again.
+	for _, u := range updates {
+		write(u.Path[len(dir)+1:], u.Content)
+	}
+	updates, err = updateBadges(dir, "example.com/app", cov)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+}
+
+func TestBadgeURL(t *testing.T) {
+	assert.Equal(t, "https://img.shields.io/badge/coverage-84.2%25-brightgreen", badgeURL("84.2%", badgeColor(84.2)))
+	assert.Equal(t, "https://img.shields.io/badge/coverage-a--b__c-orange", badgeURL("a-b_c", badgeColor(40)))
+}
@@ -0,0 +1,159 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// newBlocks returns the blocks of newProfile that do not exist in oldProfile.
+// If the old and new source code of the file is available, blocks are matched
+// by the source code they span so that code which merely moved within the
+// file is not reported as new. Otherwise blocks are matched by position.
+func (r *Report) newBlocks(fileName string, oldProfile, newProfile *Profile) []ProfileBlock {
+	oldKeys, newKeys, ok := r.blockContentKeys(fileName, oldProfile.Blocks, newProfile.Blocks)
+	if !ok {
+		oldKeys, newKeys = blockPositionKeys(oldProfile.Blocks), blockPositionKeys(newProfile.Blocks)
+	}
+
+	// The same source code may occur multiple times in a file (e.g. error
+	// handling), so each old block can only be matched once.
+	unmatched := make(map[blockKey]int, len(oldKeys))
+	for _, key := range oldKeys {
+		unmatched[key]++
+	}
+
+	var blocks []ProfileBlock
+	for i, block := range newProfile.Blocks {
+		if unmatched[newKeys[i]] > 0 {
+			unmatched[newKeys[i]]--
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// blockKey identifies a block either by its position or by a hash of the
+// source code it spans.
+type blockKey struct {
+	startLine, startCol, endLine, endCol int
+	content                              [sha256.Size]byte
+}
+
+// blockPositionKeys returns a key for each block that consists of its start
+// and end position.
+func blockPositionKeys(blocks []ProfileBlock) []blockKey {
+	keys := make([]blockKey, len(blocks))
+	for i, b := range blocks {
+		keys[i] = blockKey{startLine: b.StartLine, startCol: b.StartCol, endLine: b.EndLine, endCol: b.EndCol}
+	}
+
+	return keys
+}
+
+// blockContentKeys returns a key for each old and new block that is derived
+// from the source code spanned by the block. It returns false if the old or
+// new source of the file is not available or does not match the profiles.
+func (r *Report) blockContentKeys(fileName string, oldBlocks, newBlocks []ProfileBlock) (oldKeys, newKeys []blockKey, ok bool) {
+	oldLines, err := r.readOldSourceLines(fileName)
+	if err != nil || oldLines == nil {
+		return nil, nil, false
+	}
+
+	newLines, err := r.source.readLines(fileName)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	oldKeys, ok = blockContentKeysFromSource(oldLines, oldBlocks)
+	if !ok {
+		return nil, nil, false
+	}
+
+	newKeys, ok = blockContentKeysFromSource(newLines, newBlocks)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return oldKeys, newKeys, true
+}
+
+func blockContentKeysFromSource(lines map[int]string, blocks []ProfileBlock) ([]blockKey, bool) {
+	keys := make([]blockKey, len(blocks))
+	for i, b := range blocks {
+		src, ok := b.Source(lines)
+		if !ok {
+			return nil, false
+		}
+
+		// Normalize whitespace so re-indented code (e.g. when it was moved
+		// into another block) still matches.
+		keys[i].content = sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s", b.NumStmt, strings.Join(strings.Fields(src), " "))))
+	}
+
+	return keys, true
+}
+
+// readOldSourceLines returns the lines of the old version of the given file.
+// It returns nil if the old source code is not available.
+func (r *Report) readOldSourceLines(fileName string) (map[int]string, error) {
+	if r.oldSourceLines == nil {
+		if r.BaseRef == "" {
+			return nil, nil
+		}
+		r.oldSourceLines = gitSourceLines(context.Background(), r.BaseRef, r.source)
+	}
+
+	if r.oldSourceCache == nil {
+		r.oldSourceCache = map[string]map[int]string{}
+	}
+
+	if lines, ok := r.oldSourceCache[fileName]; ok {
+		return lines, nil
+	}
+
+	lines, err := r.oldSourceLines(fileName)
+	if err != nil {
+		// Remember that the file is not available so we don't try again.
+		lines = nil
+	}
+	r.oldSourceCache[fileName] = lines
+
+	return lines, err
+}
+
+// gitSourceLines returns a function that reads the lines of the source file
+// of a coverage profile at the given git revision. The lines are scanned like
+// the lines of the tree. The git processes are killed once the context is
+// done.
+func gitSourceLines(ctx context.Context, ref string, tree sourceTree) func(fileName string) (map[int]string, error) {
+	return func(fileName string) (map[int]string, error) {
+		src, err := gitSourceFile(ctx, ref, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		return tree.scanLines(bytes.NewReader(src))
+	}
+}
+
+// gitSourceFile returns the content of the source file of a coverage profile
+// at the given git revision.
+func gitSourceFile(ctx context.Context, ref, fileName string) ([]byte, error) {
+	for _, path := range sourcePathCandidates(fileName) {
+		// The "./" prefix makes git resolve the path relative to the current
+		// directory instead of the repository root.
+		out, err := exec.CommandContext(ctx, "git", "show", ref+":./"+filepath.ToSlash(path)).Output()
+		if err == nil {
+			return out, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no source found for %s at %s", fileName, ref)
+}
@@ -0,0 +1,66 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_NewBlocksByContent(t *testing.T) {
+	oldSource := "package p\n\nfunc a() int {\n\treturn 1\n}\n"
+	newSource := "package p\n\nfunc b() int {\n\treturn 2\n}\n\nfunc a() int {\n\t\treturn   1\n}\n"
+
+	fileName := filepath.Join(t.TempDir(), "p.go")
+	require.NoError(t, os.WriteFile(fileName, []byte(newSource), 0644))
+
+	oldProfile := &Profile{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 14, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1},
+	}}
+	newProfile := &Profile{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 14, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+		{StartLine: 7, StartCol: 14, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 1},
+	}}
+
+	report := NewReport(New([]*Profile{oldProfile}), New([]*Profile{newProfile}), []string{fileName})
+
+	// Without the old source, blocks are compared by position so the moved
+	// function a is reported instead of the new function b.
+	assert.Equal(t, newProfile.Blocks[1:], report.newBlocks(fileName, oldProfile, newProfile))
+
+	report = NewReport(New([]*Profile{oldProfile}), New([]*Profile{newProfile}), []string{fileName})
+	report.oldSourceLines = func(string) (map[int]string, error) {
+		return sourceTree{}.scanLines(strings.NewReader(oldSource))
+	}
+	assert.Equal(t, newProfile.Blocks[:1], report.newBlocks(fileName, oldProfile, newProfile))
+
+	total, covered := report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 1, total)
+	assert.EqualValues(t, 0, covered)
+}
+
+func TestReport_NewBlocksByContent_Duplicates(t *testing.T) {
+	lines := map[int]string{1: "return err", 2: "return err"}
+	blocks := []ProfileBlock{
+		{StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 11, NumStmt: 1},
+		{StartLine: 2, StartCol: 1, EndLine: 2, EndCol: 11, NumStmt: 1},
+	}
+
+	keys, ok := blockContentKeysFromSource(lines, blocks)
+	require.True(t, ok)
+	assert.Equal(t, keys[0], keys[1])
+
+	report := &Report{oldSourceLines: func(string) (map[int]string, error) {
+		return map[int]string{1: "return err"}, nil
+	}}
+
+	fileName := filepath.Join(t.TempDir(), "p.go")
+	require.NoError(t, os.WriteFile(fileName, []byte("return err\nreturn err\n"), 0644))
+
+	oldProfile := &Profile{Blocks: blocks[:1]}
+	newProfile := &Profile{Blocks: blocks}
+	assert.Equal(t, blocks[1:], report.newBlocks(fileName, oldProfile, newProfile))
+}
@@ -1,4 +1,4 @@
-package main
+package coverage
 
 import (
 	"encoding/json"
@@ -13,7 +13,7 @@ func ParseChangedFiles(filename, prefix string) ([]string, error) {
 	}
 
 	var files []string
-	err = json.Unmarshal(data, &files)
+	err = json.Unmarshal(trimBOM(data), &files)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,122 @@
+// Package coverage compares the code coverage of two versions of a Go module
+// and reports the coverage of the changed files and of the new code. It
+// implements the go-coverage-report command and its GitHub action.
+//
+// Compare, the methods of Result and PostComment let custom workflows compute
+// the coverage gate, render the report in any format and post it to a pull
+// request without running the command.
+package coverage
+
+import (
+	"context"
+	"flag"
+	"io"
+)
+
+// Options are the settings of Compare. They correspond to the flags of the
+// main command with a similar name, and the zero value of a field uses the
+// default of its flag.
+type Options struct {
+	Root       string // import path of the module that prefixes the changed files (see -root)
+	Trim       string // prefix to trim from the packages of the report (see -trim)
+	DiffFile   string // unified diff for line-level coverage of new code (see -diff)
+	BaseRef    string // git revision of the old coverage to match moved code (see -base-ref)
+	RepoRoot   string // directory to read the source code from (see -repo-root)
+	Only       string // glob patterns to restrict the report to (see -only)
+	IgnoreFile string // gitignore style patterns of files to leave out (see -ignore-file)
+
+	MinCoverage       float64 // minimum coverage of new code in percent (see -min-coverage)
+	Neutral           bool    // fail if the coverage changes at all (see -neutral)
+	NeutralEpsilon    float64 // tolerated change with Neutral (see -neutral-epsilon)
+	Grade             bool    // compute the composite grade (see -grade)
+	TestGaps          bool    // add the Test Gap Priorities section (see -test-gaps)
+	ReviewEffort      bool    // estimate the review effort in the summary (see -review-effort)
+	Strict            bool    // fail instead of falling back to heuristics (see -strict)
+	ExcludeWiring     bool    // see -exclude-wiring
+	ExcludeDeprecated bool    // see -exclude-deprecated
+
+	Theme     string // emojis and tone of the Markdown report (see -theme)
+	Layout    string // structure of the Markdown report (see -layout)
+	HTMLTheme string // color theme of the HTML report (see -html-theme)
+
+	// Log receives the notes and warnings of the analysis, e.g. about changed
+	// files whose source code is not available. They are discarded if Log is
+	// nil.
+	Log io.Writer
+
+	// Config contains the package criticality, the grade weights, the folding
+	// rules and the gates (see LoadConfig). Its Options are not applied, the
+	// fields above set the options instead.
+	Config *Config
+}
+
+// options returns the options of the main command that correspond to o.
+func (o Options) options() options {
+	// The defaults of the fields are the defaults of the flags.
+	fs := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	registerFlags(fs)
+	opts := optionsFromFlags(fs)
+
+	opts.root = o.Root
+	opts.trim = o.Trim
+	opts.diffFile = o.DiffFile
+	opts.baseRef = o.BaseRef
+	opts.repoRoot = o.RepoRoot
+	opts.only = o.Only
+	opts.ignoreFile = o.IgnoreFile
+	opts.minCoverage = o.MinCoverage
+	opts.neutral = o.Neutral
+	if o.NeutralEpsilon != 0 {
+		opts.epsilon = o.NeutralEpsilon
+	}
+	opts.grade = o.Grade
+	opts.testGaps = o.TestGaps
+	opts.reviewEffort = o.ReviewEffort
+	opts.strict = o.Strict
+	opts.excludeWiring = o.ExcludeWiring
+	opts.skipDeprecated = o.ExcludeDeprecated
+	if o.Theme != "" {
+		opts.theme = o.Theme
+	}
+	if o.Layout != "" {
+		opts.layout = o.Layout
+	}
+	if o.HTMLTheme != "" {
+		opts.htmlTheme = o.HTMLTheme
+	}
+	opts.config = o.Config
+	opts.log = o.Log
+
+	return opts
+}
+
+// Compare compares the old and the new coverage file of the files that are
+// listed in the changed files file like the main command. The Result is nil
+// if no changed files remain after filtering. Its Err reports whether the
+// coverage gate failed, and it must be closed after it was rendered.
+func Compare(ctx context.Context, oldCoverage, newCoverage, changedFiles string, o Options) (*Result, error) {
+	return analyze(ctx, oldCoverage, newCoverage, changedFiles, o.options())
+}
+
+// PullRequest identifies the pull request of PostComment.
+type PullRequest struct {
+	Repository string // owner/name
+	Number     int
+	Token      string // needs the "pull-requests: write" permission
+	APIURL     string // default https://api.github.com
+}
+
+// PostComment posts the Markdown report as comment of the pull request like
+// the action does: the report comment of a previous run is replaced, and an
+// unchanged report is not posted again. Previous reports are only recognized
+// among the comments of github-actions[bot], i.e. if the token is the
+// GITHUB_TOKEN of a workflow.
+func PostComment(ctx context.Context, pr PullRequest, markdown string) error {
+	a := &action{
+		cfg: actionConfig{PullRequest: pr.Number},
+		gh:  newGitHubClient(pr.APIURL, pr.Token, pr.Repository),
+		out: io.Discard,
+	}
+
+	return a.postReport(ctx, markdown)
+}
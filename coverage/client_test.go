@@ -0,0 +1,122 @@
+package coverage
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	fs := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	registerFlags(fs)
+	assert.Equal(t, optionsFromFlags(fs), Options{}.options())
+
+	cfg := &Config{Gates: map[string]string{"new code": "new_code >= 80"}}
+	opts := Options{Root: "github.com/example/repo", MinCoverage: 80, NeutralEpsilon: 0.5, ExcludeDeprecated: true, Layout: layoutDrilldown, Config: cfg}.options()
+	assert.Equal(t, "github.com/example/repo", opts.root)
+	assert.Equal(t, 80.0, opts.minCoverage)
+	assert.Equal(t, 0.5, opts.epsilon)
+	assert.True(t, opts.skipDeprecated)
+	assert.Equal(t, layoutDrilldown, opts.layout)
+	assert.Equal(t, themeClassic, opts.theme)
+	assert.Same(t, cfg, opts.config)
+
+	var log bytes.Buffer
+	opts = Options{Log: &log}.options()
+	opts.logf("WARNING: %d files", 2)
+	assert.Equal(t, "WARNING: 2 files\n", log.String())
+}
+
+// TestExportedAPI checks that importers of the package can use all exported
+// functions and methods, i.e. that their signatures and the exported fields
+// of the exported types only refer to exported types of the package.
+func TestExportedAPI(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		ok, err := build.Default.MatchFile(".", fi.Name())
+		return err == nil && ok && !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+
+	var files []*ast.File
+	for _, f := range pkgs["coverage"].Files {
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("github.com/fgrosse/go-coverage-report/coverage", fset, files, nil)
+	require.NoError(t, err)
+
+	unexported := func(typ types.Type) []string {
+		var names []string
+		seen := map[types.Type]bool{}
+		var walk func(types.Type)
+		walk = func(typ types.Type) {
+			if seen[typ] {
+				return
+			}
+			seen[typ] = true
+
+			switch typ := typ.(type) {
+			case *types.Named:
+				if typ.Obj().Pkg() == pkg && !typ.Obj().Exported() {
+					names = append(names, typ.Obj().Name())
+				}
+			case *types.Pointer:
+				walk(typ.Elem())
+			case *types.Slice:
+				walk(typ.Elem())
+			case *types.Array:
+				walk(typ.Elem())
+			case *types.Map:
+				walk(typ.Key())
+				walk(typ.Elem())
+			case *types.Signature:
+				for i := 0; i < typ.Params().Len(); i++ {
+					walk(typ.Params().At(i).Type())
+				}
+				for i := 0; i < typ.Results().Len(); i++ {
+					walk(typ.Results().At(i).Type())
+				}
+			case *types.Struct:
+				for i := 0; i < typ.NumFields(); i++ {
+					if typ.Field(i).Exported() {
+						walk(typ.Field(i).Type())
+					}
+				}
+			}
+		}
+		walk(typ)
+		return names
+	}
+
+	for _, name := range pkg.Scope().Names() {
+		obj := pkg.Scope().Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+
+		assert.Empty(t, unexported(obj.Type().Underlying()), name)
+
+		named, ok := obj.Type().(*types.Named)
+		if _, isType := obj.(*types.TypeName); !isType || !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			if m := named.Method(i); m.Exported() {
+				assert.Empty(t, unexported(m.Type()), name+"."+m.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CommitCoverage is the coverage of the new lines that a single commit of
+// the pull request introduced (see -per-commit).
+type CommitCoverage struct {
+	Commit  string
+	Subject string
+	Lines   int // new lines with statements that were last changed by the commit
+	Covered int
+}
+
+// Percent returns the percentage of covered lines.
+func (c CommitCoverage) Percent() float64 {
+	if c.Lines == 0 {
+		return 0
+	}
+
+	return float64(c.Covered) / float64(c.Lines) * 100
+}
+
+// gitCommit is a commit of the pull request.
+type gitCommit struct {
+	SHA     string
+	Subject string
+}
+
+// commitCoverages attributes every new line with statements to the commit
+// that last changed it according to blame and returns the coverage of each
+// of the given commits in the same order. Lines of other commits (e.g. of
+// the base branch) are ignored.
+func (r *Result) commitCoverages(commits []gitCommit, blame func(fileName string) (map[int]string, error)) []CommitCoverage {
+	result := make([]CommitCoverage, len(commits))
+	index := make(map[string]int, len(commits))
+	for i, c := range commits {
+		result[i] = CommitCoverage{Commit: c.SHA, Subject: c.Subject}
+		index[c.SHA] = i
+	}
+
+	fileBlocks := map[string][]NewCodeBlock{}
+	for _, block := range r.getNewCodeBlocks() {
+		fileBlocks[block.FileName] = append(fileBlocks[block.FileName], block)
+	}
+
+	for _, fileName := range sortedKeys(fileBlocks) {
+		sourceLines, err := r.source.readLines(fileName)
+		if err != nil {
+			continue
+		}
+
+		lineCommits, err := blame(fileName)
+		if err != nil {
+			continue
+		}
+
+		for lineNum, covered := range r.newLineCoverage(fileName, sourceLines, fileBlocks[fileName]) {
+			i, ok := index[lineCommits[lineNum]]
+			if !ok {
+				continue
+			}
+
+			result[i].Lines++
+			if covered {
+				result[i].Covered++
+			}
+		}
+	}
+
+	return result
+}
+
+// belowMinCoverageByCommit returns true if the new lines of any commit are
+// below -min-coverage.
+func (r *Report) belowMinCoverageByCommit() bool {
+	return r.MinCoverage > 0 && slices.ContainsFunc(r.Commits, func(c CommitCoverage) bool {
+		return c.Lines > 0 && c.Percent() < r.MinCoverage
+	})
+}
+
+func (r *Report) addCommitDetails(report *strings.Builder) {
+	if len(r.Commits) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldCommits, r.belowMinCoverageByCommit))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Coverage by Commit</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "New lines with statements are attributed to the commit that last changed them.")
+	fmt.Fprintln(report)
+	fmt.Fprintf(report, "| Commit | New Lines | Coverage | %s |\n", r.theme().statusHeader)
+	fmt.Fprintln(report, "|--------|-----------|----------|---------|")
+
+	for _, c := range r.Commits {
+		commit := c.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		subject := strings.ReplaceAll(c.Subject, "|", `\|`)
+
+		if c.Lines == 0 {
+			fmt.Fprintf(report, "| `%s` %s | 0 | - | |\n", commit, subject)
+			continue
+		}
+
+		emoji := r.theme().newCodeStatus(c.Percent())
+		if r.MinCoverage > 0 {
+			emoji = r.theme().passed
+			if c.Percent() < r.MinCoverage {
+				emoji = r.theme().failed
+			}
+		}
+		n := r.numbers()
+		fmt.Fprintf(report, "| `%s` %s | %s | %s (%s/%s) | %s |\n", commit, subject, n.Count(int64(c.Lines)), n.Percent(c.Percent()), n.Count(int64(c.Covered)), n.Count(int64(c.Lines)), emoji)
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+// gitCommitLog returns the commits between the given git revision and HEAD
+// from oldest to newest. Merge commits are left out.
+func gitCommitLog(ctx context.Context, baseRef string) ([]gitCommit, error) {
+	out, err := exec.CommandContext(ctx, "git", "log", "--reverse", "--no-merges", "--format=%H%x00%s", baseRef+"..HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits since %s: %w", baseRef, err)
+	}
+
+	var commits []gitCommit
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if sha, subject, ok := strings.Cut(line, "\x00"); ok {
+			commits = append(commits, gitCommit{SHA: sha, Subject: subject})
+		}
+	}
+
+	return commits, nil
+}
+
+// gitBlame returns a function that maps each line of the source file of a
+// coverage profile to the commit that last changed it since the given git
+// revision. Lines that did not change are attributed to the boundary commit.
+func gitBlame(ctx context.Context, baseRef string) func(fileName string) (map[int]string, error) {
+	return func(fileName string) (map[int]string, error) {
+		for _, path := range sourcePathCandidates(fileName) {
+			out, err := exec.CommandContext(ctx, "git", "blame", "--porcelain", baseRef+"..HEAD", "--", filepath.ToSlash(path)).Output()
+			if err == nil {
+				return parseBlamePorcelain(out), nil
+			}
+		}
+
+		return nil, fmt.Errorf("failed to blame %s", fileName)
+	}
+}
+
+// parseBlamePorcelain returns the commit of each line of the output of
+// "git blame --porcelain".
+func parseBlamePorcelain(out []byte) map[int]string {
+	lines := map[int]string{}
+	header := true
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			header = true // the next line starts the next entry
+			continue
+		}
+		if !header {
+			continue // e.g. "author" or "summary" of the current entry
+		}
+		header = false
+
+		// <sha> <line in original file> <line in final file> [<lines in group>]
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		if n, err := strconv.Atoi(fields[2]); err == nil {
+			lines[n] = fields[0]
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,88 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlamePorcelain(t *testing.T) {
+	out := strings.Join([]string{
+		"1111111111111111111111111111111111111111 1 1 2",
+		"author Alice",
+		"summary Add Load",
+		"boundary",
+		"filename svc.go",
+		"\tpackage svc",
+		"1111111111111111111111111111111111111111 2 2",
+		"\t",
+		"2222222222222222222222222222222222222222 3 3 1",
+		"author Bob",
+		"summary Handle debug | mode",
+		"previous 1111111111111111111111111111111111111111 svc.go",
+		"filename svc.go",
+		"\tfunc Load() int {",
+		"",
+	}, "\n")
+
+	assert.Equal(t, map[int]string{
+		1: "1111111111111111111111111111111111111111",
+		2: "1111111111111111111111111111111111111111",
+		3: "2222222222222222222222222222222222222222",
+	}, parseBlamePorcelain([]byte(out)))
+}
+
+func TestReport_CommitCoverages(t *testing.T) {
+	src := "package svc\n\nfunc Load() int {\n\tif debug {\n\t\treturn 2\n\t}\n\treturn 1\n}\n"
+
+	fileName := filepath.Join(t.TempDir(), "svc", "svc.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fileName), 0755))
+	require.NoError(t, os.WriteFile(fileName, []byte(src), 0644))
+
+	oldCov := New([]*Profile{{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 18, EndLine: 4, EndCol: 10, NumStmt: 1, Count: 1},
+	}, TotalStmt: 1, CoveredStmt: 1}})
+	newCov := New([]*Profile{{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 18, EndLine: 4, EndCol: 11, NumStmt: 1, Count: 1},
+		{StartLine: 4, StartCol: 11, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+		{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 1, Count: 1},
+	}, TotalStmt: 3, CoveredStmt: 2}})
+
+	report := NewReport(oldCov, newCov, []string{fileName})
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{fileName: {AddedLines: map[int]bool{4: true, 5: true, 6: true, 7: true}}}}
+
+	commits := []gitCommit{
+		{SHA: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Subject: "Handle debug | mode"},
+		{SHA: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", Subject: "Fix return value"},
+		{SHA: "cccccccccccccccccccccccccccccccccccccccc", Subject: "Update docs"},
+	}
+	blame := func(name string) (map[int]string, error) {
+		assert.Equal(t, fileName, name)
+		return map[int]string{
+			1: "0000000000000000000000000000000000000000",
+			4: commits[0].SHA,
+			5: commits[0].SHA,
+			6: commits[0].SHA,
+			7: commits[1].SHA,
+		}, nil
+	}
+
+	report.Commits = report.Analyze().commitCoverages(commits, blame)
+	assert.Equal(t, []CommitCoverage{
+		{Commit: commits[0].SHA, Subject: commits[0].Subject, Lines: 3, Covered: 1},
+		{Commit: commits[1].SHA, Subject: commits[1].Subject, Lines: 1, Covered: 1},
+		{Commit: commits[2].SHA, Subject: commits[2].Subject},
+	}, report.Commits)
+
+	report.MinCoverage = 50
+	report.Config = &Config{Fold: map[string]string{foldCommits: foldAuto}}
+	markdown := report.Analyze().Markdown()
+	assert.Contains(t, markdown, "<details open>\n\n<summary>Coverage by Commit</summary>")
+	assert.Contains(t, markdown, "| `aaaaaaa` Handle debug \\| mode | 3 | 33.33% (1/3) | :x: |\n")
+	assert.Contains(t, markdown, "| `bbbbbbb` Fix return value | 1 | 100.00% (1/1) | :white_check_mark: |\n")
+	assert.Contains(t, markdown, "| `ccccccc` Update docs | 0 | - | |\n")
+}
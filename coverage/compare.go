@@ -0,0 +1,231 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var compareReportsUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s compare-reports [OPTIONS] <OLD_REPORT_FILE> <NEW_REPORT_FILE>
+
+Compare two reports that were created with -format=json, typically by two
+versions of this tool on identical inputs, and print every metric that
+differs: the summary, the overall coverage, the changed files and packages,
+the coverage of each file and the new code of each changed file. The command
+fails if any metric differs, so it can validate an upgrade of the tool:
+
+  %[1]s -format=json old.txt new.txt changed.json > before.json
+  go-coverage-report-next -format=json old.txt new.txt changed.json > after.json
+  %[1]s compare-reports before.json after.json
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runCompareReportsCommand(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("compare-reports", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, compareReportsUsage)
+		fs.PrintDefaults()
+	}
+
+	epsilon := fs.Float64("epsilon", 0, "maximum difference of percentages in percentage points that is not reported (e.g. 0.01 for rounding)")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected exactly two report files")
+	}
+
+	oldReport, err := readComparableReport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newReport, err := readComparableReport(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	return writeReportComparison(os.Stdout, compareReports(oldReport, newReport, *epsilon))
+}
+
+// comparableReport contains the metrics of a JSON report (see Report.JSON)
+// that are compared by the compare-reports command.
+type comparableReport struct {
+	Old, New        *Coverage
+	ChangedFiles    []string
+	ChangedPackages []string
+	Analysis        map[string]FileAnalysis
+	Summary         *ReportSummary
+}
+
+// readComparableReport reads a report that was created with -format=json.
+func readComparableReport(fileName string) (*comparableReport, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	r := new(comparableReport)
+	if err := json.Unmarshal(trimBOM(data), r); err != nil {
+		return nil, fmt.Errorf("invalid JSON report %q: %w", fileName, err)
+	}
+	if r.Old == nil || r.New == nil {
+		return nil, fmt.Errorf("invalid JSON report %q: missing coverage (the report must be created with -format=json)", fileName)
+	}
+
+	return r, nil
+}
+
+// ReportDifference is a metric whose value differs between two reports. The
+// metric is named after its path in the JSON report, e.g.
+// "Summary.NewCoverage" or "Analysis[github.com/acme/app/a.go].CoveredNew".
+type ReportDifference struct {
+	Metric   string
+	Old, New string // "missing" if the metric only exists in one report
+}
+
+// ReportComparison is the result of compareReports.
+type ReportComparison struct {
+	Metrics     int // number of compared metrics
+	Differences []ReportDifference
+}
+
+// compareReports compares the metrics of two JSON reports. Percentages that
+// differ by at most epsilon percentage points are considered equal.
+func compareReports(oldReport, newReport *comparableReport, epsilon float64) ReportComparison {
+	c := &reportComparer{epsilon: epsilon}
+
+	if oldReport.Summary != nil && newReport.Summary != nil {
+		o, n := oldReport.Summary, newReport.Summary
+		c.percent("Summary.OldCoverage", o.OldCoverage, n.OldCoverage)
+		c.percent("Summary.NewCoverage", o.NewCoverage, n.NewCoverage)
+		c.percent("Summary.Delta", o.Delta, n.Delta)
+		c.count("Summary.NewStmt", o.NewStmt, n.NewStmt)
+		c.count("Summary.CoveredNewStmt", o.CoveredNewStmt, n.CoveredNewStmt)
+		c.text("Summary.Grade", o.Grade, n.Grade)
+		c.text("Summary.Passed", strconv.FormatBool(o.Passed), strconv.FormatBool(n.Passed))
+	} else {
+		c.presence("Summary", oldReport.Summary != nil, newReport.Summary != nil)
+	}
+
+	c.coverage("Old", oldReport.Old, newReport.Old)
+	c.coverage("New", oldReport.New, newReport.New)
+
+	c.set("ChangedFiles", oldReport.ChangedFiles, newReport.ChangedFiles)
+	c.set("ChangedPackages", oldReport.ChangedPackages, newReport.ChangedPackages)
+
+	for _, fileName := range sortedKeys(unionKeys(oldReport.Analysis, newReport.Analysis)) {
+		metric := "Analysis[" + fileName + "]"
+		o, inOld := oldReport.Analysis[fileName]
+		n, inNew := newReport.Analysis[fileName]
+		if !inOld || !inNew {
+			c.presence(metric, inOld, inNew)
+			continue
+		}
+		c.count(metric+".TotalNew", o.TotalNew, n.TotalNew)
+		c.count(metric+".CoveredNew", o.CoveredNew, n.CoveredNew)
+		c.count(metric+".Blocks", int64(len(o.Blocks)), int64(len(n.Blocks)))
+	}
+
+	return c.result
+}
+
+// reportComparer collects the differences of the compared metrics.
+type reportComparer struct {
+	epsilon float64
+	result  ReportComparison
+}
+
+func (c *reportComparer) text(metric, oldVal, newVal string) {
+	c.result.Metrics++
+	if oldVal != newVal {
+		c.result.Differences = append(c.result.Differences, ReportDifference{Metric: metric, Old: oldVal, New: newVal})
+	}
+}
+
+func (c *reportComparer) count(metric string, oldVal, newVal int64) {
+	c.text(metric, strconv.FormatInt(oldVal, 10), strconv.FormatInt(newVal, 10))
+}
+
+func (c *reportComparer) percent(metric string, oldVal, newVal float64) {
+	c.result.Metrics++
+	if math.Abs(oldVal-newVal) > c.epsilon {
+		c.result.Differences = append(c.result.Differences, ReportDifference{
+			Metric: metric,
+			Old:    strconv.FormatFloat(oldVal, 'f', -1, 64) + "%",
+			New:    strconv.FormatFloat(newVal, 'f', -1, 64) + "%",
+		})
+	}
+}
+
+func (c *reportComparer) presence(metric string, inOld, inNew bool) {
+	value := func(present bool) string {
+		if present {
+			return "present"
+		}
+		return "missing"
+	}
+	c.text(metric, value(inOld), value(inNew))
+}
+
+func (c *reportComparer) set(metric string, oldVals, newVals []string) {
+	for _, v := range sortedKeys(unionKeys(toSet(oldVals), toSet(newVals))) {
+		c.presence(metric+"["+v+"]", slices.Contains(oldVals, v), slices.Contains(newVals, v))
+	}
+}
+
+func (c *reportComparer) coverage(metric string, oldCov, newCov *Coverage) {
+	c.count(metric+".TotalStmt", oldCov.TotalStmt, newCov.TotalStmt)
+	c.count(metric+".CoveredStmt", oldCov.CoveredStmt, newCov.CoveredStmt)
+
+	for _, fileName := range sortedKeys(unionKeys(oldCov.Files, newCov.Files)) {
+		fileMetric := metric + ".Files[" + fileName + "]"
+		o, n := oldCov.Files[fileName], newCov.Files[fileName]
+		if o == nil || n == nil {
+			c.presence(fileMetric, o != nil, n != nil)
+			continue
+		}
+		c.count(fileMetric+".TotalStmt", o.TotalStmt, n.TotalStmt)
+		c.count(fileMetric+".CoveredStmt", o.CoveredStmt, n.CoveredStmt)
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
+
+// writeReportComparison prints the differing metrics as a table and returns
+// an error if any metric differs.
+func writeReportComparison(w io.Writer, c ReportComparison) error {
+	if len(c.Differences) == 0 {
+		_, err := fmt.Fprintf(w, "All %d metrics are equal\n", c.Metrics)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 1, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tOLD\tNEW")
+	for _, d := range c.Differences {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Metric, d.Old, d.New)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d of %d metrics differ", len(c.Differences), c.Metrics)
+}
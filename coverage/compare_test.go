@@ -0,0 +1,118 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeJSONReport writes the JSON report of fixture 04 and returns its path.
+func writeJSONReport(t *testing.T, name string, modify func(*Report)) string {
+	t.Helper()
+
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	if modify != nil {
+		modify(report)
+	}
+
+	fileName := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(fileName, []byte(report.Analyze().JSON()), 0644))
+
+	return fileName
+}
+
+func TestCompareReports(t *testing.T) {
+	before, err := readComparableReport(writeJSONReport(t, "before.json", nil))
+	require.NoError(t, err)
+
+	same := compareReports(before, before, 0)
+	assert.Empty(t, same.Differences)
+	assert.Equal(t, 20, same.Metrics)
+
+	// Without the diff, the new code is estimated from the changed files.
+	after, err := readComparableReport(writeJSONReport(t, "after.json", func(r *Report) { r.DiffInfo = nil }))
+	require.NoError(t, err)
+
+	const age = "Analysis[github.com/pentohq/pento/pkg/age/age.go]"
+	assert.Equal(t, []ReportDifference{
+		{Metric: "Summary.NewStmt", Old: "11", New: "22"},
+		{Metric: "Summary.CoveredNewStmt", Old: "6", New: "19"},
+		{Metric: age + ".TotalNew", Old: "11", New: "22"},
+		{Metric: age + ".CoveredNew", Old: "6", New: "19"},
+		{Metric: age + ".Blocks", Old: "6", New: "16"},
+	}, compareReports(before, after, 0).Differences)
+}
+
+func TestCompareReports_Files(t *testing.T) {
+	oldReport := &comparableReport{
+		Old:          &Coverage{Files: map[string]*Profile{"a.go": {TotalStmt: 2}}, TotalStmt: 2},
+		New:          &Coverage{Files: map[string]*Profile{"a.go": {TotalStmt: 2, CoveredStmt: 1}}, TotalStmt: 2, CoveredStmt: 1},
+		ChangedFiles: []string{"a.go"},
+		Summary:      &ReportSummary{NewCoverage: 50, Delta: 50},
+	}
+	newReport := &comparableReport{
+		Old:          &Coverage{Files: map[string]*Profile{"a.go": {TotalStmt: 2}}, TotalStmt: 2},
+		New:          &Coverage{Files: map[string]*Profile{"a.go": {TotalStmt: 2, CoveredStmt: 1}, "b.go": {}}, TotalStmt: 2, CoveredStmt: 1},
+		ChangedFiles: []string{"b.go", "a.go"},
+		Summary:      &ReportSummary{NewCoverage: 50.004, Delta: 50.004},
+	}
+
+	assert.Equal(t, []ReportDifference{
+		{Metric: "Summary.NewCoverage", Old: "50%", New: "50.004%"},
+		{Metric: "Summary.Delta", Old: "50%", New: "50.004%"},
+		{Metric: "New.Files[b.go]", Old: "missing", New: "present"},
+		{Metric: "ChangedFiles[b.go]", Old: "missing", New: "present"},
+	}, compareReports(oldReport, newReport, 0).Differences)
+
+	assert.Equal(t, []ReportDifference{
+		{Metric: "New.Files[b.go]", Old: "missing", New: "present"},
+		{Metric: "ChangedFiles[b.go]", Old: "missing", New: "present"},
+	}, compareReports(oldReport, newReport, 0.01).Differences)
+
+	newReport.Summary = nil
+	assert.Contains(t, compareReports(oldReport, newReport, 0).Differences, ReportDifference{Metric: "Summary", Old: "present", New: "missing"})
+}
+
+func TestWriteReportComparison(t *testing.T) {
+	var out bytes.Buffer
+	err := writeReportComparison(&out, ReportComparison{Metrics: 7, Differences: []ReportDifference{
+		{Metric: "Summary.NewStmt", Old: "11", New: "9"},
+		{Metric: "ChangedFiles[b.go]", Old: "missing", New: "present"},
+	}})
+	assert.EqualError(t, err, "2 of 7 metrics differ")
+	assert.Equal(t, ""+
+		"METRIC              OLD      NEW\n"+
+		"Summary.NewStmt     11       9\n"+
+		"ChangedFiles[b.go]  missing  present\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, writeReportComparison(&out, ReportComparison{Metrics: 7}))
+	assert.Equal(t, "All 7 metrics are equal\n", out.String())
+}
+
+func TestRunCompareReportsCommand(t *testing.T) {
+	before := writeJSONReport(t, "before.json", nil)
+	after := writeJSONReport(t, "after.json", func(r *Report) { r.MinCoverage = 80 })
+
+	assert.NoError(t, runCompareReportsCommand(context.Background(), []string{before, before}))
+	assert.EqualError(t, runCompareReportsCommand(context.Background(), []string{before, after}), "1 of 20 metrics differ")
+
+	notAReport := filepath.Join(t.TempDir(), "report.md")
+	require.NoError(t, os.WriteFile(notAReport, []byte(`{"Summary": {}}`), 0644))
+	assert.ErrorContains(t, runCompareReportsCommand(context.Background(), []string{before, notAReport}), "missing coverage (the report must be created with -format=json)")
+}
@@ -0,0 +1,231 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Config contains the optional settings that can be loaded from a JSON file
+// via the -config flag. All fields are optional.
+type Config struct {
+	// Options sets the values of command line flags (e.g. "min-coverage": 80).
+	// Values passed via environment variables or flags take precedence.
+	Options map[string]any `json:"options"`
+
+	// Criticality maps Go package patterns (e.g. "github.com/acme/app/billing/...")
+	// to a weight that is used to prioritize test gaps in these packages.
+	// Packages that do not match any pattern have a criticality of 1.
+	Criticality map[string]float64 `json:"criticality"`
+
+	// Grade configures the weights of the composite grade (see -grade).
+	Grade *GradeWeights `json:"grade"`
+
+	// Fold maps sections of the Markdown report (e.g. "packages") or
+	// "default" to "closed", "open" or "auto". Sections with the rule "auto"
+	// are only expanded if they contain a violation such as a missed
+	// threshold or a coverage regression.
+	Fold map[string]string `json:"fold"`
+
+	// Gates maps names to boolean expressions over report variables (e.g.
+	// "new_code >= 80 || (delta >= 0 && total >= 70)"). The report fails if
+	// any gate evaluates to false (see gateVariables).
+	Gates map[string]string `json:"gates"`
+}
+
+// LoadConfig reads the JSON configuration file at the given path.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(Config)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", filename, err)
+	}
+
+	for pattern, weight := range cfg.Criticality {
+		if weight < 0 {
+			return nil, fmt.Errorf("invalid config file %q: negative criticality %v for %q", filename, weight, pattern)
+		}
+	}
+
+	if g := cfg.Grade; g != nil {
+		if g.NewCode < 0 || g.Delta < 0 || g.ErrorPaths < 0 {
+			return nil, fmt.Errorf("invalid config file %q: grade weights must not be negative", filename)
+		}
+		if g.NewCode+g.Delta+g.ErrorPaths == 0 {
+			return nil, fmt.Errorf("invalid config file %q: at least one grade weight must be positive", filename)
+		}
+	}
+
+	if err := validateFold(cfg.Fold); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", filename, err)
+	}
+
+	if err := validateGates(cfg.Gates); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", filename, err)
+	}
+
+	return cfg, nil
+}
+
+// PackageCriticality returns the configured criticality of the package that
+// contains the given file. If multiple patterns match, the most specific
+// (i.e. longest) pattern wins.
+func (c *Config) PackageCriticality(fileName string) float64 {
+	_, weight := c.criticalityRule(fileName)
+	return weight
+}
+
+// criticalityRule returns the pattern that determines the criticality of the
+// given file and its weight. The pattern is empty if no rule matches.
+func (c *Config) criticalityRule(fileName string) (pattern string, weight float64) {
+	if c == nil {
+		return "", 1
+	}
+
+	pkg := path.Dir(fileName)
+	weight = 1
+	for p, w := range c.Criticality {
+		if matchPackagePattern(p, pkg) && len(p) > len(pattern) {
+			weight, pattern = w, p
+		}
+	}
+
+	return pattern, weight
+}
+
+// Lint checks the configuration for unknown options, invalid values and
+// unsupported patterns and returns a description of every problem it found.
+func (c *Config) Lint() []string {
+	// Options are validated on a scratch FlagSet so we do not modify any
+	// flags that are actually used.
+	scratch := flag.NewFlagSet("lint", flag.ContinueOnError)
+	scratch.SetOutput(io.Discard)
+	registerFlags(scratch)
+
+	var problems []string
+	for _, name := range sortedKeys(c.Options) {
+		switch {
+		case scratch.Lookup(name) == nil:
+			problems = append(problems, fmt.Sprintf("options: unknown option %q", name))
+		case name == "config":
+			problems = append(problems, `options: the "config" option cannot be set in the config file itself`)
+		default:
+			if err := scratch.Set(name, fmt.Sprint(c.Options[name])); err != nil {
+				problems = append(problems, fmt.Sprintf("options: invalid value %v for %q: %v", c.Options[name], name, err))
+			}
+		}
+	}
+
+	for _, pattern := range sortedKeys(c.Criticality) {
+		if strings.Contains(pattern, "*") {
+			problems = append(problems, fmt.Sprintf("criticality: pattern %q uses \"*\" but only a trailing \"/...\" is supported", pattern))
+		}
+	}
+
+	return problems
+}
+
+// Sources of an effective option value as reported by resolveFlags.
+const (
+	sourceDefault = "default"
+	sourceConfig  = "config file"
+	sourceEnv     = "environment"
+	sourceFlag    = "flag"
+)
+
+// resolveFlags applies the values of environment variables and the config
+// file to all flags of fs that have not been set explicitly on the command
+// line. Flags take precedence over environment variables, which in turn take
+// precedence over the config file. It returns the loaded config (if any) and
+// the source of the effective value of each flag.
+func resolveFlags(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) (*Config, map[string]string, error) {
+	sources := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) { sources[f.Name] = sourceDefault })
+	fs.Visit(func(f *flag.Flag) { sources[f.Name] = sourceFlag })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || sources[f.Name] != sourceDefault {
+			return
+		}
+		if val, ok := lookupEnv(envVarName(f.Name)); ok {
+			if err = fs.Set(f.Name, val); err != nil {
+				err = fmt.Errorf("invalid value %q for environment variable %s: %w", val, envVarName(f.Name), err)
+			}
+			sources[f.Name] = sourceEnv
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configFile := fs.Lookup("config").Value.String()
+	if configFile == "" {
+		return nil, sources, nil
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if problems := cfg.Lint(); len(problems) > 0 {
+		return nil, nil, fmt.Errorf("invalid config file %q: %s", configFile, strings.Join(problems, "; "))
+	}
+
+	for name, val := range cfg.Options {
+		if sources[name] != sourceDefault {
+			continue
+		}
+		if err := fs.Set(name, fmt.Sprint(val)); err != nil {
+			return nil, nil, fmt.Errorf("invalid config file %q: %w", configFile, err)
+		}
+		sources[name] = sourceConfig
+	}
+
+	return cfg, sources, nil
+}
+
+// envVarName returns the name of the environment variable that can be used
+// to set the flag with the given name (e.g. GO_COVERAGE_REPORT_MIN_COVERAGE).
+func envVarName(flagName string) string {
+	return "GO_COVERAGE_REPORT_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// matchPackagePattern reports whether the package path matches the given
+// pattern. Patterns follow the conventions of the go tool, i.e. a trailing
+// "/..." matches the package itself and all of its sub packages.
+func matchPackagePattern(pattern, pkg string) bool {
+	if pattern == "..." {
+		return true
+	}
+
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+
+	return pkg == pattern
+}
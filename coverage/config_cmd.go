@@ -0,0 +1,214 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var configUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s config lint [-config FILE]
+       %[1]s config explain [OPTIONS] [-profile COVERAGE_FILE] [FILE...]
+
+COMMANDS:
+  lint     Validate the configuration file and report all problems it contains.
+  explain  Print the effective configuration after applying the configuration file,
+           environment variables and flags (in this order of precedence). For each
+           FILE (a path as it appears in the coverage profile) and for each file of
+           the optional COVERAGE_FILE, print which rules apply to it and which parts
+           of it are excluded from the coverage calculation.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runConfigCommand(_ context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return errors.New("missing config command")
+	}
+
+	fs := flag.NewFlagSet("config "+args[0], flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, configUsage)
+		fs.PrintDefaults()
+	}
+	registerFlags(fs)
+
+	switch args[0] {
+	case "lint":
+		_ = fs.Parse(args[1:])
+		return configLint(os.Stdout, fs, os.LookupEnv)
+	case "explain":
+		profile := fs.String("profile", "", "coverage profile whose files should be explained")
+		_ = fs.Parse(args[1:])
+		return configExplain(os.Stdout, fs, *profile, fs.Args(), os.LookupEnv)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+// configLint validates the config file that is configured via fs or the
+// environment and prints the result to w.
+func configLint(w io.Writer, fs *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	configFile := fs.Lookup("config").Value.String()
+	if configFile == "" {
+		configFile, _ = lookupEnv(envVarName("config"))
+	}
+	if configFile == "" {
+		return errors.New("no config file specified (use -config or " + envVarName("config") + ")")
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	problems := cfg.Lint()
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "%s: OK\n", configFile)
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(w, "%s: %s\n", configFile, p)
+	}
+
+	return fmt.Errorf("found %d problem(s) in %s", len(problems), configFile)
+}
+
+// configExplain prints the effective configuration and explains which rules
+// apply to the given files and the files of the optional coverage profile.
+func configExplain(w io.Writer, fs *flag.FlagSet, profile string, files []string, lookupEnv func(string) (string, bool)) error {
+	cfg, sources, err := resolveFlags(fs, lookupEnv)
+	if err != nil {
+		return err
+	}
+
+	opts := optionsFromFlags(fs)
+
+	fmt.Fprintln(w, "Effective configuration:")
+	fmt.Fprintln(w)
+	scratch := flag.NewFlagSet("explain", flag.ContinueOnError)
+	registerFlags(scratch)
+	scratch.VisitAll(func(f *flag.Flag) {
+		source := sources[f.Name]
+		if source == sourceEnv {
+			source += " (" + envVarName(f.Name) + ")"
+		}
+		fmt.Fprintf(w, "  -%-16s %-24q %s\n", f.Name, fs.Lookup(f.Name).Value.String(), source)
+	})
+	fmt.Fprintln(w)
+
+	if cfg != nil && len(cfg.Criticality) > 0 {
+		fmt.Fprintln(w, "Criticality rules:")
+		fmt.Fprintln(w)
+		for _, pattern := range sortedKeys(cfg.Criticality) {
+			fmt.Fprintf(w, "  %s = %g\n", pattern, cfg.Criticality[pattern])
+		}
+		fmt.Fprintln(w)
+	}
+
+	if cfg != nil && len(cfg.Fold) > 0 {
+		fmt.Fprintln(w, "Fold rules:")
+		fmt.Fprintln(w)
+		for _, section := range foldSections {
+			fmt.Fprintf(w, "  %s = %s\n", section, cfg.FoldRule(section))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if cfg != nil && len(cfg.Gates) > 0 {
+		fmt.Fprintln(w, "Gates:")
+		fmt.Fprintln(w)
+		for _, name := range sortedKeys(cfg.Gates) {
+			fmt.Fprintf(w, "  %s: %s\n", name, cfg.Gates[name])
+		}
+		fmt.Fprintln(w)
+	}
+
+	if profile != "" {
+		cov, err := ParseCoverage(profile)
+		if err != nil {
+			return fmt.Errorf("failed to parse coverage profile: %w", err)
+		}
+		for fileName := range cov.Files {
+			files = append(files, fileName)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	tree, err := newSourceTree(opts)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(files)
+	fmt.Fprintln(w, "Files:")
+	for _, fileName := range files {
+		fmt.Fprintln(w)
+		explainFile(w, cfg, opts, tree, fileName)
+	}
+
+	return nil
+}
+
+// explainFile prints which rules apply to the given file.
+func explainFile(w io.Writer, cfg *Config, opts options, tree sourceTree, fileName string) {
+	fmt.Fprintf(w, "  %s\n", fileName)
+
+	pattern, weight := cfg.criticalityRule(fileName)
+	if pattern == "" {
+		fmt.Fprintf(w, "    criticality: %g (default)\n", weight)
+	} else {
+		fmt.Fprintf(w, "    criticality: %g (rule %q)\n", weight, pattern)
+	}
+
+	fmt.Fprintln(w, "    included:    yes")
+
+	path, ok := tree.find(fileName)
+	if !ok {
+		fmt.Fprintln(w, "    source:      not found locally, excluded regions cannot be determined")
+		return
+	}
+	fmt.Fprintf(w, "    source:      %s\n", path)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(w, "    source:      %v\n", err)
+		return
+	}
+
+	var regions []excludedRegion
+	for _, find := range exclusionFinders(opts) {
+		r, err := find(path, src)
+		if err != nil {
+			fmt.Fprintf(w, "    excluded:    failed to parse source: %v\n", err)
+			return
+		}
+		regions = append(regions, r...)
+	}
+
+	if len(regions) == 0 {
+		fmt.Fprintln(w, "    excluded:    nothing")
+		return
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].StartLine < regions[j].StartLine })
+	for i, r := range regions {
+		label := "excluded:"
+		if i > 0 {
+			label = ""
+		}
+		fmt.Fprintf(w, "    %-12s lines %d-%d (%s)\n", label, r.StartLine, r.EndLine, r.Reason)
+	}
+}
@@ -0,0 +1,65 @@
+package coverage
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noEnv(string) (string, bool) { return "", false }
+
+func TestConfigLint(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{"options": {"format": "json"}}`), 0644)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-config", configFile}))
+
+	var out strings.Builder
+	require.NoError(t, configLint(&out, fs, noEnv))
+	assert.Equal(t, configFile+": OK\n", out.String())
+
+	err = os.WriteFile(configFile, []byte(`{"options": {"fromat": "json"}}`), 0644)
+	require.NoError(t, err)
+
+	out.Reset()
+	err = configLint(&out, fs, noEnv)
+	assert.EqualError(t, err, "found 1 problem(s) in "+configFile)
+	assert.Equal(t, configFile+`: options: unknown option "fromat"`+"\n", out.String())
+}
+
+func TestConfigExplain(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{
+		"options": {"exclude-wiring": true},
+		"criticality": {"example.com/calculator/...": 2}
+	}`), 0644)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-config", configFile}))
+
+	var out strings.Builder
+	err = configExplain(&out, fs, "", []string{
+		"example.com/calculator/math.go",
+		"example.com/missing/file.go",
+	}, noEnv)
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), `-exclude-wiring   "true"                   config file`)
+	assert.Contains(t, out.String(), `-min-coverage     "0"                      default`)
+	assert.Contains(t, out.String(), "example.com/calculator/... = 2")
+	assert.Contains(t, out.String(), `criticality: 2 (rule "example.com/calculator/...")`)
+	assert.Contains(t, out.String(), "criticality: 1 (default)")
+	assert.Contains(t, out.String(), "source:      testdata/example.com/calculator/math.go")
+	assert.Contains(t, out.String(), "excluded:    nothing")
+	assert.Contains(t, out.String(), "source:      not found locally")
+}
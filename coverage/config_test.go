@@ -0,0 +1,138 @@
+package coverage
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{
+		"criticality": {
+			"example.com/app/billing/...": 3,
+			"example.com/app/billing/internal": 5
+		}
+	}`), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3.0, cfg.PackageCriticality("example.com/app/billing/invoice.go"))
+	assert.Equal(t, 3.0, cfg.PackageCriticality("example.com/app/billing/tax/vat.go"))
+	assert.Equal(t, 5.0, cfg.PackageCriticality("example.com/app/billing/internal/db.go"))
+	assert.Equal(t, 1.0, cfg.PackageCriticality("example.com/app/billingv2/invoice.go"))
+	assert.Equal(t, 1.0, cfg.PackageCriticality("example.com/app/main.go"))
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{"criticality": {"example.com/...": -1}}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadConfig(configFile)
+	assert.ErrorContains(t, err, "negative criticality")
+
+	err = os.WriteFile(configFile, []byte(`{"criticality": [1, 2]}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadConfig(configFile)
+	assert.ErrorContains(t, err, "invalid config file")
+
+	err = os.WriteFile(configFile, []byte(`{"grade": {"new_code": 1, "delta": -1}}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadConfig(configFile)
+	assert.ErrorContains(t, err, "grade weights must not be negative")
+
+	err = os.WriteFile(configFile, []byte(`{"fold": {"packages": "expanded"}}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadConfig(configFile)
+	assert.ErrorContains(t, err, `invalid fold rule "expanded" for section "packages"`)
+}
+
+func TestConfig_PackageCriticality_NilConfig(t *testing.T) {
+	var cfg *Config
+	assert.Equal(t, 1.0, cfg.PackageCriticality("example.com/app/main.go"))
+}
+
+func TestMatchPackagePattern(t *testing.T) {
+	cases := []struct {
+		pattern, pkg string
+		match        bool
+	}{
+		{"...", "example.com/foo", true},
+		{"example.com/foo", "example.com/foo", true},
+		{"example.com/foo", "example.com/foo/bar", false},
+		{"example.com/foo/...", "example.com/foo", true},
+		{"example.com/foo/...", "example.com/foo/bar", true},
+		{"example.com/foo/...", "example.com/foobar", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, matchPackagePattern(c.pattern, c.pkg), "pattern=%q pkg=%q", c.pattern, c.pkg)
+	}
+}
+
+func TestConfig_Lint(t *testing.T) {
+	cfg := &Config{
+		Options: map[string]any{
+			"min-coverage": "eighty",
+			"format":       "json",
+			"config":       "other.json",
+			"colour":       true,
+		},
+		Criticality: map[string]float64{
+			"example.com/app/*": 2,
+		},
+	}
+
+	problems := cfg.Lint()
+	require.Len(t, problems, 4)
+	assert.Contains(t, problems[0], `unknown option "colour"`)
+	assert.Contains(t, problems[1], `"config" option cannot be set`)
+	assert.Contains(t, problems[2], `invalid value eighty for "min-coverage"`)
+	assert.Contains(t, problems[3], `pattern "example.com/app/*"`)
+}
+
+func TestResolveFlags(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{
+		"options": {"format": "json", "min-coverage": 50, "trim": "example.com/app"}
+	}`), 0644)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-config", configFile, "-format", "rdjson"}))
+
+	env := map[string]string{"GO_COVERAGE_REPORT_MIN_COVERAGE": "80"}
+	lookupEnv := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	cfg, sources, err := resolveFlags(fs, lookupEnv)
+	require.NoError(t, err)
+	require.NotNil(t, cfg)
+
+	opts := optionsFromFlags(fs)
+	assert.Equal(t, "rdjson", opts.format)
+	assert.Equal(t, 80.0, opts.minCoverage)
+	assert.Equal(t, "example.com/app", opts.trim)
+	assert.Equal(t, sourceFlag, sources["format"])
+	assert.Equal(t, sourceEnv, sources["min-coverage"])
+	assert.Equal(t, sourceConfig, sources["trim"])
+	assert.Equal(t, sourceDefault, sources["root"])
+}
+
+func TestEnvVarName(t *testing.T) {
+	assert.Equal(t, "GO_COVERAGE_REPORT_MIN_COVERAGE", envVarName("min-coverage"))
+	assert.Equal(t, "GO_COVERAGE_REPORT_FORMAT", envVarName("format"))
+}
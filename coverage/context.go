@@ -0,0 +1,79 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// newCommandContext returns the context of a command that is canceled when
+// the process receives SIGINT or SIGTERM or, if timeout is greater than zero,
+// when the timeout expires.
+func newCommandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// subcommandTimeout returns the timeout of the subcommand with the given name,
+// which can only be set via the GO_COVERAGE_REPORT_TIMEOUT environment
+// variable since subcommands have their own flags. The action subcommand
+// prefers the TIMEOUT variable that action.yml sets from its input.
+func subcommandTimeout(name string, lookupEnv func(string) (string, bool)) (time.Duration, error) {
+	vars := []string{envVarName("timeout")}
+	if name == "action" {
+		vars = append([]string{"TIMEOUT"}, vars...)
+	}
+
+	for _, v := range vars {
+		val, ok := lookupEnv(v)
+		if !ok || val == "" {
+			continue
+		}
+
+		timeout, err := time.ParseDuration(val)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", v, err)
+		}
+		return timeout, nil
+	}
+
+	return 0, nil
+}
+
+// commandError returns the error of a command that ran with the given
+// context. If the context is done, the cause of the cancellation is returned
+// instead of the error it caused (e.g. a failed request).
+func commandError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("aborted: %w", context.Cause(ctx))
+	}
+
+	return err
+}
+
+// contextReader is an io.Reader that fails once its context is done, which
+// allows to abort reading huge inputs.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := context.Cause(cr.ctx); err != nil {
+		return 0, err
+	}
+
+	return cr.r.Read(p)
+}
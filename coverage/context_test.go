@@ -0,0 +1,82 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandError(t *testing.T) {
+	assert.EqualError(t, commandError(context.Background(), errors.New("test")), "test")
+
+	ctx, cancel := newCommandContext(10 * time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	// The command returns once it notices that its context is done.
+	_, err := ParseCoverageContext(ctx, "testdata/01-new-coverage.txt")
+	assert.EqualError(t, commandError(ctx, err), "aborted: timed out after 10ms")
+	assert.NoError(t, commandError(ctx, nil))
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := contextReader{ctx: ctx, r: strings.NewReader("mode: set\n")}
+
+	_, err := ParseProfilesFromReader(r)
+	require.NoError(t, err)
+
+	cancel()
+	_, err = ParseProfilesFromReader(contextReader{ctx: ctx, r: strings.NewReader("mode: set\n")})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParseCoverageContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ParseCoverageContext(ctx, "testdata/01-new-coverage.txt")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = parseUnifiedDiffContext(ctx, "testdata/01-diff.patch", parseOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSubcommandTimeout(t *testing.T) {
+	env := map[string]string{}
+	lookupEnv := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	timeout, err := subcommandTimeout("history", lookupEnv)
+	require.NoError(t, err)
+	assert.Zero(t, timeout)
+
+	env["GO_COVERAGE_REPORT_TIMEOUT"] = "2m"
+	timeout, err = subcommandTimeout("history", lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, timeout)
+
+	// The TIMEOUT input of the action is only read by the action.
+	env["TIMEOUT"] = "5m"
+	timeout, err = subcommandTimeout("history", lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, timeout)
+	timeout, err = subcommandTimeout("action", lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, timeout)
+
+	env["TIMEOUT"] = "soon"
+	_, err = subcommandTimeout("action", lookupEnv)
+	assert.EqualError(t, err, `invalid TIMEOUT: time: invalid duration "soon"`)
+
+	env["GO_COVERAGE_REPORT_TIMEOUT"] = "soon"
+	_, err = subcommandTimeout("history", lookupEnv)
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package coverage
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TestFileContribution is the coverage contributed by the tests of a changed
+// test file (see Report.TestFileCoverage).
+type TestFileContribution struct {
+	FileName    string
+	CoveredStmt int64 // statements covered by the tests of the file
+	UniqueStmt  int64 // covered statements that no other test file covers
+	UniqueNew   int64 // new statements of the PR among UniqueStmt
+}
+
+// parseTestFileCoverage parses the value of -test-file-coverage, a comma
+// separated list of test files and the coverage files of their tests (e.g.
+// "pkg/a_test.go=a.out,pkg/b_test.go=b.out"). Test files are relative to the
+// given root like the changed files. It returns the coverage files by the full
+// name of their test file.
+func parseTestFileCoverage(arg, root string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(arg, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		testFile, coverageFile, ok := strings.Cut(entry, "=")
+		if !ok || testFile == "" || coverageFile == "" {
+			return nil, fmt.Errorf("invalid test file coverage %q: expected TEST_FILE=COVERAGE_FILE", entry)
+		}
+		if !strings.HasSuffix(testFile, "_test.go") {
+			return nil, fmt.Errorf("invalid test file coverage %q: %s is not a test file", entry, testFile)
+		}
+
+		result[path.Join(root, testFile)] = coverageFile
+	}
+
+	return result, nil
+}
+
+// TestFileContributions returns the contribution of each changed test file
+// for which the coverage of its tests is known. A statement is uniquely
+// covered by a test file if the tests of no other test file of
+// TestFileCoverage cover it, i.e. it becomes uncovered if the file is removed.
+// This requires the coverage of all test files whose tests may cover the same
+// code, not only of the changed ones. Only blocks of the new coverage are
+// counted.
+func (r *Result) TestFileContributions() []TestFileContribution {
+	if len(r.TestFileCoverage) == 0 {
+		return nil
+	}
+
+	// The number of test files that cover each block of the new coverage.
+	// Blocks that are not part of it (e.g. because they were excluded) are
+	// not counted.
+	coveredBy := make(map[string]map[blockPosition]int)
+	for fileName, p := range r.New.Files {
+		coveredBy[fileName] = make(map[blockPosition]int, len(p.Blocks))
+		for _, b := range p.Blocks {
+			coveredBy[fileName][positionOf(b)] = 0
+		}
+	}
+	for _, cov := range r.TestFileCoverage {
+		for fileName, p := range cov.Files {
+			for _, b := range p.Blocks {
+				if _, ok := coveredBy[fileName][positionOf(b)]; ok && b.Count > 0 {
+					coveredBy[fileName][positionOf(b)]++
+				}
+			}
+		}
+	}
+
+	newStmt := r.newStatementsByBlock()
+
+	var result []TestFileContribution
+	for _, testFile := range r.ChangedFiles {
+		cov := r.TestFileCoverage[testFile]
+		if cov == nil {
+			continue
+		}
+
+		c := TestFileContribution{FileName: testFile}
+		for fileName, p := range cov.Files {
+			for _, b := range p.Blocks {
+				n, ok := coveredBy[fileName][positionOf(b)]
+				if !ok || b.Count == 0 {
+					continue
+				}
+				c.CoveredStmt += int64(b.NumStmt)
+				if n == 1 {
+					c.UniqueStmt += int64(b.NumStmt)
+					c.UniqueNew += int64(newStmt[fileName][[2]int{b.StartLine, b.EndLine}])
+				}
+			}
+		}
+		result = append(result, c)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].FileName < result[j].FileName })
+
+	return result
+}
+
+// newStatementsByBlock returns the number of new statements of each block of
+// the changed files by the start and end line of the block.
+func (r *Result) newStatementsByBlock() map[string]map[[2]int]int {
+	result := make(map[string]map[[2]int]int)
+	if totalNew, _ := r.calculateNewCodeCoverage(); totalNew == 0 {
+		return result
+	}
+
+	for _, b := range r.getNewCodeBlocks() {
+		if result[b.FileName] == nil {
+			result[b.FileName] = make(map[[2]int]int)
+		}
+		result[b.FileName][[2]int{b.StartLine, b.EndLine}] += b.NumStmt
+	}
+
+	return result
+}
+
+// addTestFileContributions adds a table with the contribution of each changed
+// test file to the coverage. Test files without coverage are listed without
+// numbers.
+func (r *Result) addTestFileContributions(report *strings.Builder, files []string) {
+	contributions := make(map[string]TestFileContribution)
+	for _, c := range r.TestFileContributions() {
+		contributions[c.FileName] = c
+	}
+
+	fmt.Fprintln(report, "| Changed Test File | Covered | Uniquely Covered | Uniquely Covered New Code |")
+	fmt.Fprintln(report, "|-------------------|---------|------------------|---------------------------|")
+	for _, name := range files {
+		c, ok := contributions[name]
+		if !ok {
+			fmt.Fprintf(report, "| %s | N/A | N/A | N/A |\n", name)
+			continue
+		}
+		n := r.numbers()
+		fmt.Fprintf(report, "| %s | %s | %s | %s |\n", name, n.Count(c.CoveredStmt), n.Count(c.UniqueStmt), n.Count(c.UniqueNew))
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "_The counts refer to ***code statements*** covered by the tests of each file. "+
+		"Uniquely covered statements are not covered by the tests of any other test file, "+
+		"so their coverage is lost if the file is removed._")
+	fmt.Fprintln(report)
+}
@@ -0,0 +1,78 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestFileCoverage(t *testing.T) {
+	files, err := parseTestFileCoverage("pkg/a_test.go=a.out, pkg/b_test.go=cover/b.out,", "example.com/app")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"example.com/app/pkg/a_test.go": "a.out",
+		"example.com/app/pkg/b_test.go": "cover/b.out",
+	}, files)
+
+	_, err = parseTestFileCoverage("a.out", "")
+	assert.EqualError(t, err, `invalid test file coverage "a.out": expected TEST_FILE=COVERAGE_FILE`)
+
+	_, err = parseTestFileCoverage("pkg/a.go=a.out", "")
+	assert.EqualError(t, err, `invalid test file coverage "pkg/a.go=a.out": pkg/a.go is not a test file`)
+}
+
+func TestReport_TestFileContributions(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	const pkg = "github.com/pentohq/pento/pkg/age/"
+	testFileCoverage := func(lines ...string) *Coverage {
+		profiles, err := ParseProfilesFromReader(strings.NewReader("mode: count\n" + strings.Join(lines, "\n") + "\n"))
+		require.NoError(t, err)
+		return New(profiles)
+	}
+
+	report := NewReport(oldCov, newCov, []string{pkg + "age.go", pkg + "age_test.go", pkg + "days_test.go"})
+	report.DiffInfo = diffInfo
+	assert.Nil(t, report.Analyze().TestFileContributions())
+
+	report.TestFileCoverage = map[string]*Coverage{
+		// Covers the new Days function and the start of Years.
+		pkg + "age_test.go": testFileCoverage(
+			pkg+"age.go:15.20,17.34 1 3",
+			pkg+"age.go:53.25,55.24 2 1",
+			pkg+"age.go:55.24,57.3 1 0",
+			pkg+"age.go:61.2,62.36 2 1",
+			"github.com/pentohq/pento/pkg/other/other.go:1.1,2.2 5 1", // not part of the new coverage
+		),
+		// An unchanged test file that also covers the start of Years.
+		pkg + "years_test.go": testFileCoverage(
+			pkg+"age.go:15.20,17.34 1 1",
+			pkg+"age.go:21.2,23.37 2 1",
+		),
+	}
+
+	assert.Equal(t, []TestFileContribution{
+		{FileName: pkg + "age_test.go", CoveredStmt: 5, UniqueStmt: 4, UniqueNew: 4},
+	}, report.Analyze().TestFileContributions())
+
+	assert.Contains(t, report.Analyze().Markdown(), "### Changed unit test files\n\n"+
+		"| Changed Test File | Covered | Uniquely Covered | Uniquely Covered New Code |\n"+
+		"|-------------------|---------|------------------|---------------------------|\n"+
+		"| "+pkg+"age_test.go | 5 | 4 | 4 |\n"+
+		"| "+pkg+"days_test.go | N/A | N/A | N/A |\n")
+
+	report.Layout = layoutDrilldown
+	assert.Contains(t, report.Analyze().Markdown(), "Changed unit test files: `age_test.go` (4 of 5 statements covered uniquely), `days_test.go`\n")
+
+	report.TrimPrefix("github.com/pentohq/pento")
+	assert.Equal(t, []TestFileContribution{
+		{FileName: "pkg/age/age_test.go", CoveredStmt: 5, UniqueStmt: 4, UniqueNew: 4},
+	}, report.Analyze().TestFileContributions())
+}
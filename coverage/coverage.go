@@ -0,0 +1,191 @@
+package coverage
+
+import (
+	"context"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+type Coverage struct {
+	Files       map[string]*Profile
+	TotalStmt   int64
+	CoveredStmt int64
+	MissedStmt  int64
+	Exclusions  []Exclusion `json:",omitempty"`
+}
+
+// Exclusion records a region of a source file whose statements have been
+// removed from the coverage calculation.
+type Exclusion struct {
+	FileName  string
+	StartLine int
+	EndLine   int
+	NumStmt   int64
+	Reason    string
+	Statement bool `json:",omitempty"` // a single statement on StartLine (see ExcludeStatement)
+}
+
+func ParseCoverage(filename string) (*Coverage, error) {
+	return ParseCoverageContext(context.Background(), filename)
+}
+
+// ParseCoverageContext is like ParseCoverage but stops reading the file once
+// the context is done.
+func ParseCoverageContext(ctx context.Context, filename string) (*Coverage, error) {
+	return parseCoverageFile(ctx, filename, parseOptions{})
+}
+
+// parseCoverageFile parses the coverage profile with the given options.
+func parseCoverageFile(ctx context.Context, filename string, o parseOptions) (*Coverage, error) {
+	pp, err := parseProfilesFile(ctx, filename, nil, o)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse profiles")
+	}
+
+	return New(pp), nil
+}
+
+func New(profiles []*Profile) *Coverage {
+	cov := &Coverage{Files: map[string]*Profile{}}
+	for _, p := range profiles {
+		cov.add(p)
+	}
+
+	return cov
+}
+
+func (c *Coverage) add(p *Profile) {
+	if p == nil {
+		return
+	}
+
+	if _, ok := c.Files[p.FileName]; ok {
+		// If we actually got here something went very wrong. It should never
+		// happen, so it's not worth adding an error return value here.
+		panic(errors.Errorf("profile for file %q already exists", p.FileName))
+	}
+
+	c.Files[p.FileName] = p
+	c.TotalStmt += p.TotalStmt
+	c.CoveredStmt += p.CoveredStmt
+	c.MissedStmt += p.MissedStmt
+}
+
+func (c *Coverage) Percent() float64 {
+	if c.TotalStmt == 0 {
+		return 0
+	}
+
+	return float64(c.CoveredStmt) / float64(c.TotalStmt) * 100
+}
+
+func (c *Coverage) ByPackage() map[string]*Coverage {
+	packages := map[string][]string{} // maps package paths to files
+	for file := range c.Files {
+		pkg := path.Dir(file)
+		packages[pkg] = append(packages[pkg], file)
+	}
+
+	pkgCovs := make(map[string]*Coverage, len(packages))
+	for pkg, files := range packages {
+		var profiles []*Profile
+		for _, file := range files {
+			profiles = append(profiles, c.Files[file])
+		}
+
+		pkgCovs[pkg] = New(profiles)
+	}
+
+	return pkgCovs
+}
+
+func (c *Coverage) TrimPrefix(prefix string) {
+	for name, cov := range c.Files {
+		delete(c.Files, cov.FileName)
+		cov.FileName = trimPrefix(name, prefix)
+		c.Files[cov.FileName] = cov
+	}
+
+	for i, e := range c.Exclusions {
+		c.Exclusions[i].FileName = trimPrefix(e.FileName, prefix)
+	}
+}
+
+// Filter returns a copy of the coverage that only contains the files for
+// which keep returns true.
+func (c *Coverage) Filter(keep func(fileName string) bool) *Coverage {
+	var profiles []*Profile
+	for name, p := range c.Files {
+		if keep(name) {
+			profiles = append(profiles, p)
+		}
+	}
+
+	filtered := New(profiles)
+	for _, e := range c.Exclusions {
+		if keep(e.FileName) {
+			filtered.Exclusions = append(filtered.Exclusions, e)
+		}
+	}
+
+	return filtered
+}
+
+// Exclude removes all blocks of the given file that start within the lines
+// [startLine, endLine] from the coverage calculation and records the exclusion
+// with the given reason. It returns the number of excluded statements.
+func (c *Coverage) Exclude(fileName string, startLine, endLine int, reason string) int64 {
+	p, ok := c.Files[fileName]
+	if !ok {
+		return 0
+	}
+
+	var blocks []ProfileBlock
+	var excluded, excludedCovered int64
+	for _, b := range p.Blocks {
+		if b.StartLine < startLine || b.StartLine > endLine {
+			blocks = append(blocks, b)
+			continue
+		}
+
+		excluded += int64(b.NumStmt)
+		if b.Count > 0 {
+			excludedCovered += int64(b.NumStmt)
+		}
+	}
+
+	if excluded == 0 {
+		return 0
+	}
+
+	p.Blocks = blocks
+	p.TotalStmt -= excluded
+	p.CoveredStmt -= excludedCovered
+	p.MissedStmt = p.TotalStmt - p.CoveredStmt
+
+	c.TotalStmt -= excluded
+	c.CoveredStmt -= excludedCovered
+	c.MissedStmt = c.TotalStmt - c.CoveredStmt
+
+	c.Exclusions = append(c.Exclusions, Exclusion{
+		FileName:  fileName,
+		StartLine: startLine,
+		EndLine:   endLine,
+		NumStmt:   excluded,
+		Reason:    reason,
+	})
+
+	return excluded
+}
+
+// ExcludedStmt returns the total number of statements that have been excluded
+// from the coverage calculation.
+func (c *Coverage) ExcludedStmt() int64 {
+	var n int64
+	for _, e := range c.Exclusions {
+		n += e.NumStmt
+	}
+
+	return n
+}
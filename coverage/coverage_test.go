@@ -0,0 +1,73 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 102, cov.TotalStmt)
+	assert.EqualValues(t, 92, cov.CoveredStmt)
+	assert.EqualValues(t, 10, cov.MissedStmt)
+	assert.InDelta(t, 90.196, cov.Percent(), 0.001)
+}
+
+func TestCoverage_ByPackage(t *testing.T) {
+	cov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+
+	pkgs := cov.ByPackage()
+	assert.Len(t, pkgs, 1)
+
+	pkgCov := pkgs["github.com/fgrosse/prioqueue"]
+	assert.NotNil(t, pkgCov)
+	assert.EqualValues(t, 102, pkgCov.TotalStmt)
+	assert.EqualValues(t, 92, pkgCov.CoveredStmt)
+	assert.EqualValues(t, 10, pkgCov.MissedStmt)
+}
+
+func TestCoverage_Exclude(t *testing.T) {
+	cov, err := ParseCoverage("testdata/03-new-coverage.txt")
+	require.NoError(t, err)
+
+	// Exclude the Power function which is not covered at all
+	excluded := cov.Exclude("example.com/calculator/math.go", 24, 30, "test")
+	assert.EqualValues(t, 5, excluded)
+
+	assert.EqualValues(t, 6, cov.TotalStmt)
+	assert.EqualValues(t, 6, cov.CoveredStmt)
+	assert.EqualValues(t, 0, cov.MissedStmt)
+	assert.EqualValues(t, 5, cov.ExcludedStmt())
+	assert.Equal(t, []Exclusion{
+		{FileName: "example.com/calculator/math.go", StartLine: 24, EndLine: 30, NumStmt: 5, Reason: "test"},
+	}, cov.Exclusions)
+
+	assert.Zero(t, cov.Exclude("example.com/calculator/math.go", 100, 200, "test"))
+	assert.Zero(t, cov.Exclude("example.com/calculator/unknown.go", 1, 200, "test"))
+	assert.Len(t, cov.Exclusions, 1)
+}
+
+func TestCoverage_Filter(t *testing.T) {
+	cov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	cov.Exclusions = []Exclusion{
+		{FileName: "github.com/fgrosse/prioqueue/min_heap.go", StartLine: 1, EndLine: 2},
+		{FileName: "github.com/fgrosse/prioqueue/max_heap.go", StartLine: 1, EndLine: 2},
+	}
+
+	filtered := cov.Filter(func(fileName string) bool {
+		return fileName == "github.com/fgrosse/prioqueue/min_heap.go"
+	})
+
+	minHeap := cov.Files["github.com/fgrosse/prioqueue/min_heap.go"]
+	assert.Len(t, filtered.Files, 1)
+	assert.Equal(t, minHeap.TotalStmt, filtered.TotalStmt)
+	assert.Equal(t, minHeap.CoveredStmt, filtered.CoveredStmt)
+	assert.Equal(t, cov.Exclusions[:1], filtered.Exclusions)
+	assert.EqualValues(t, 102, cov.TotalStmt, "the original coverage is not modified")
+}
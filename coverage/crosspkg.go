@@ -0,0 +1,130 @@
+package coverage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// blockPosition identifies a block of a coverage profile by its position.
+type blockPosition struct {
+	startLine, startCol, endLine, endCol int
+}
+
+func positionOf(b ProfileBlock) blockPosition {
+	return blockPosition{b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+}
+
+// externalBlocks returns the positions of all blocks of the given file that
+// are covered in the new coverage but not by the tests of the package of the
+// file itself (see Report.PackageCoverage). This happens if the new coverage
+// was recorded with -coverpkg, which attributes coverage to all packages that
+// are executed by a test.
+func (r *Report) externalBlocks(fileName string) map[blockPosition]bool {
+	if r.PackageCoverage == nil {
+		return nil
+	}
+	if external, ok := r.externalCache[fileName]; ok {
+		return external
+	}
+
+	inPackage := map[blockPosition]bool{}
+	if p := r.PackageCoverage.Files[fileName]; p != nil {
+		for _, b := range p.Blocks {
+			if b.Count > 0 {
+				inPackage[positionOf(b)] = true
+			}
+		}
+	}
+
+	external := map[blockPosition]bool{}
+	if p := r.New.Files[fileName]; p != nil {
+		for _, b := range p.Blocks {
+			if b.Count > 0 && !inPackage[positionOf(b)] {
+				external[positionOf(b)] = true
+			}
+		}
+	}
+
+	if r.externalCache == nil {
+		r.externalCache = map[string]map[blockPosition]bool{}
+	}
+	r.externalCache[fileName] = external
+
+	return external
+}
+
+// newProfile returns the new profile of the given file that is used to
+// calculate the coverage of new code. If RequirePackageCoverage is set, blocks
+// that are only covered by tests of other packages are treated as uncovered.
+// If ExcludeDeprecated is set, blocks of deprecated functions are left out.
+func (r *Report) newProfile(fileName string) *Profile {
+	p := r.New.Files[fileName]
+	if p == nil {
+		return nil
+	}
+
+	var external map[blockPosition]bool
+	if r.RequirePackageCoverage {
+		external = r.externalBlocks(fileName)
+	}
+	var deprecated []funcExtent
+	if r.ExcludeDeprecated {
+		deprecated = r.deprecatedExtents(fileName)
+	}
+	if len(external) == 0 && len(deprecated) == 0 {
+		return p
+	}
+
+	result := &Profile{FileName: p.FileName, Mode: p.Mode}
+	for _, b := range p.Blocks {
+		if inDeprecatedFunction(deprecated, b) {
+			continue
+		}
+		if external[positionOf(b)] {
+			b.Count = 0
+		}
+		result.TotalStmt += int64(b.NumStmt)
+		if b.Count > 0 {
+			result.CoveredStmt += int64(b.NumStmt)
+		}
+		result.Blocks = append(result.Blocks, b)
+	}
+	result.MissedStmt = result.TotalStmt - result.CoveredStmt
+
+	return result
+}
+
+// externalNewStatements returns the number of new statements that are only
+// covered by tests of other packages.
+func (r *Result) externalNewStatements() int64 {
+	var n int64
+	for _, block := range r.getNewCodeBlocks() {
+		if block.External {
+			n += int64(block.NumStmt)
+		}
+	}
+
+	return n
+}
+
+// addExternalCoverageNote explains how much of the new code is only covered
+// by tests of other packages.
+func (r *Result) addExternalCoverageNote(report *strings.Builder) {
+	if r.PackageCoverage == nil {
+		return
+	}
+
+	n := r.externalNewStatements()
+	if n == 0 {
+		return
+	}
+
+	note := fmt.Sprintf("%d new statements are only covered by external tests, i.e. by tests of other packages (`-coverpkg`).", n)
+	if r.RequirePackageCoverage {
+		note += " They are counted as uncovered since new code must be covered by the tests of its own package."
+	}
+
+	fmt.Fprintln(report, "> [!NOTE]")
+	fmt.Fprintln(report, "> "+note)
+	fmt.Fprintln(report)
+}
@@ -0,0 +1,85 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCrossPackageTestReport() *Report {
+	profile := func(counts ...int) *Profile {
+		p := &Profile{FileName: "example.com/a/a.go", Mode: "set"}
+		for i, count := range counts {
+			p.Blocks = append(p.Blocks, ProfileBlock{StartLine: 10 * (i + 1), StartCol: 1, EndLine: 10*(i+1) + 5, EndCol: 2, NumStmt: 2, Count: count})
+			p.TotalStmt += 2
+			if count > 0 {
+				p.CoveredStmt += 2
+			}
+		}
+		p.MissedStmt = p.TotalStmt - p.CoveredStmt
+		return p
+	}
+
+	oldCov := New([]*Profile{profile(1)})
+	newCov := New([]*Profile{profile(1, 1, 1)})
+
+	report := NewReport(oldCov, newCov, []string{"example.com/a/a.go"})
+	report.astMapper = nil
+	report.PackageCoverage = New([]*Profile{profile(1, 1, 0)})
+
+	return report
+}
+
+func TestReport_ExternalCoverage(t *testing.T) {
+	report := newCrossPackageTestReport()
+
+	blocks := report.Analyze().getNewCodeBlocks()
+	require.Len(t, blocks, 2)
+	assert.False(t, blocks[0].External)
+	assert.True(t, blocks[1].External)
+	assert.True(t, blocks[1].Covered)
+
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 4, totalNew)
+	assert.EqualValues(t, 4, coveredNew)
+
+	assert.Contains(t, report.Analyze().Markdown(), "> 2 new statements are only covered by external tests")
+}
+
+func TestReport_RequirePackageCoverage(t *testing.T) {
+	report := newCrossPackageTestReport()
+	report.RequirePackageCoverage = true
+
+	blocks := report.Analyze().getNewCodeBlocks()
+	require.Len(t, blocks, 2)
+	assert.True(t, blocks[1].External)
+	assert.False(t, blocks[1].Covered)
+
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 4, totalNew)
+	assert.EqualValues(t, 2, coveredNew)
+
+	// The overall coverage is not affected.
+	assert.EqualValues(t, 6, report.New.CoveredStmt)
+	assert.Contains(t, report.Analyze().Markdown(), "They are counted as uncovered")
+
+	// The file is not part of the package coverage if its package has no tests.
+	report = newCrossPackageTestReport()
+	report.RequirePackageCoverage = true
+	report.PackageCoverage = New(nil)
+	totalNew, coveredNew = report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 4, totalNew)
+	assert.EqualValues(t, 0, coveredNew)
+}
+
+func TestReport_ExternalCoverage_Disabled(t *testing.T) {
+	report := newCrossPackageTestReport()
+	report.PackageCoverage = nil
+
+	for _, block := range report.Analyze().getNewCodeBlocks() {
+		assert.False(t, block.External)
+	}
+	assert.False(t, strings.Contains(report.Analyze().Markdown(), "external tests"))
+}
@@ -0,0 +1,150 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportSummary contains the key numbers of a report. It is included in the
+// JSON report so that the reports of many pull requests can be listed on the
+// pull request dashboard of the site without analyzing them again.
+type ReportSummary struct {
+	OldCoverage    float64
+	NewCoverage    float64
+	Delta          float64
+	NewStmt        int64
+	CoveredNewStmt int64
+	Grade          string `json:",omitempty"`
+	Passed         bool   // false if the new code coverage is below -min-coverage, -neutral or a gate failed
+
+	// Generated is the time at which the JSON report was created. The
+	// dashboard lists the newest reports first.
+	Generated time.Time
+}
+
+// pullRequestReport is a JSON report of a pull request that is listed on the
+// dashboard.
+type pullRequestReport struct {
+	Name    string // the pull request number or the name of the report file
+	Link    string
+	Time    time.Time
+	Summary ReportSummary
+}
+
+// pullRequestNumberPattern extracts the number of a pull request from the
+// name of its report file (e.g. "pr-123.json").
+var pullRequestNumberPattern = regexp.MustCompile(`\d+`)
+
+// pullNumberPlaceholder is replaced by the number of a pull request in the
+// URL of the -pull-url flag.
+const pullNumberPlaceholder = "{number}"
+
+// readPullRequestReports reads all JSON reports (see -format=json) matching
+// the given glob pattern and returns them ordered from the newest to the
+// oldest report. If pullURL is not empty, each pull request is linked via
+// pullURL with its number in place of pullNumberPlaceholder.
+func readPullRequestReports(pattern, pullURL string) ([]pullRequestReport, error) {
+	if pullURL != "" && !strings.Contains(pullURL, pullNumberPlaceholder) {
+		return nil, fmt.Errorf("pull request URL %q does not contain %s", pullURL, pullNumberPlaceholder)
+	}
+
+	fileNames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []pullRequestReport
+	for _, fileName := range fileNames {
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		var report struct{ Summary *ReportSummary }
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("invalid JSON report %s: %w", fileName, err)
+		}
+		if report.Summary == nil {
+			return nil, fmt.Errorf("JSON report %s does not contain a summary; it was created by an older version", fileName)
+		}
+
+		pr := pullRequestReport{
+			Name:    strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)),
+			Time:    report.Summary.Generated.UTC(),
+			Summary: *report.Summary,
+		}
+		if number := pullRequestNumberPattern.FindString(pr.Name); number != "" {
+			pr.Name = "#" + number
+			if pullURL != "" {
+				pr.Link = strings.ReplaceAll(pullURL, pullNumberPlaceholder, number)
+			}
+		}
+
+		reports = append(reports, pr)
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].Time.After(reports[j].Time)
+	})
+
+	return reports, nil
+}
+
+// dashboardPage is the data that is passed to dashboardTemplate.
+type dashboardPage struct {
+	SiteTitle string
+	Reports   []pullRequestReport
+}
+
+// writeDashboard writes the pull request dashboard of the site to dir.
+func writeDashboard(dir, title string, reports []pullRequestReport) error {
+	var buf bytes.Buffer
+	if err := dashboardTemplate.Execute(&buf, dashboardPage{SiteTitle: title, Reports: reports}); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "pulls.html"), buf.Bytes(), 0644)
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Pull Requests - {{ .SiteTitle }}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 4px 12px; text-align: left; }
+td.num { text-align: right; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+.muted { color: #6e7781; }
+</style>
+</head>
+<body>
+<p><a href="index.html">{{ .SiteTitle }}</a></p>
+<h1>Pull Requests</h1>
+<table>
+<tr><th>Pull Request</th><th>Analyzed</th><th>Grade</th><th>Coverage</th><th>Change</th><th>New Code</th><th>Gate</th></tr>
+{{- range .Reports }}
+<tr><td>{{ if .Link }}<a href="{{ .Link }}">{{ .Name }}</a>{{ else }}{{ .Name }}{{ end }}</td><td class="muted">{{ if .Time.IsZero }}-{{ else }}{{ .Time.Format "2006-01-02 15:04" }}{{ end }}</td><td>{{ with .Summary.Grade }}{{ . }}{{ else }}-{{ end }}</td><td class="num">{{ printf "%.2f%%" .Summary.NewCoverage }}</td><td class="num">{{ printf "%+.2f%%" .Summary.Delta }}</td><td class="num">{{ .Summary.CoveredNewStmt }}/{{ .Summary.NewStmt }}</td><td>{{ if .Summary.Passed }}<span class="passed">passed</span>{{ else }}<span class="failed">failed</span>{{ end }}</td></tr>
+{{- else }}
+<tr><td colspan="7" class="muted">No pull requests have been analyzed yet.</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,74 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPullRequestReports(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.MinCoverage = 100
+	report.Graded = true
+
+	dir := t.TempDir()
+	older := filepath.Join(dir, "pr-41.json")
+	newer := filepath.Join(dir, "pr-42.json")
+	write := func(fileName string, result *Result, generated time.Time) {
+		result.Summary.Generated = generated
+		require.NoError(t, os.WriteFile(fileName, []byte(result.JSON()), 0644))
+	}
+	write(older, NewReport(oldCov, oldCov, nil).Analyze(), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	write(newer, report.Analyze(), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	// The modification times of the files are not used since they are reset
+	// by checkouts and artifact downloads.
+	require.NoError(t, os.Chtimes(newer, time.Time{}, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	reports, err := readPullRequestReports(filepath.Join(dir, "*.json"), "https://github.com/fgrosse/prioqueue/pull/{number}?tab=files%20changed")
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+
+	assert.Equal(t, "#42", reports[0].Name)
+	assert.Equal(t, "https://github.com/fgrosse/prioqueue/pull/42?tab=files%20changed", reports[0].Link)
+	assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), reports[0].Time)
+	assert.False(t, reports[0].Summary.Passed)
+	assert.NotEmpty(t, reports[0].Summary.Grade)
+	assert.InDelta(t, -9.80, reports[0].Summary.Delta, 0.01)
+	assert.Equal(t, "#41", reports[1].Name)
+	assert.True(t, reports[1].Summary.Passed)
+	assert.Empty(t, reports[1].Summary.Grade)
+
+	require.NoError(t, writeDashboard(dir, "Prioqueue", reports))
+	page, err := os.ReadFile(filepath.Join(dir, "pulls.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(page), `<a href="https://github.com/fgrosse/prioqueue/pull/42?tab=files%20changed">#42</a>`)
+	assert.Contains(t, string(page), `<td class="muted">2026-01-02 00:00</td>`)
+	assert.Contains(t, string(page), `<td class="num">-9.80%</td>`)
+	assert.Contains(t, string(page), `<span class="failed">failed</span>`)
+	assert.Contains(t, string(page), `<span class="passed">passed</span>`)
+}
+
+func TestReadPullRequestReports_OldReport(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "7.json")
+	require.NoError(t, os.WriteFile(fileName, []byte(`{"Old": null}`), 0644))
+
+	_, err := readPullRequestReports(fileName, "")
+	assert.ErrorContains(t, err, "does not contain a summary")
+}
+
+func TestReadPullRequestReports_InvalidPullURL(t *testing.T) {
+	_, err := readPullRequestReports(filepath.Join(t.TempDir(), "*.json"), "https://github.com/acme/app/pull/%s")
+	assert.EqualError(t, err, `pull request URL "https://github.com/acme/app/pull/%s" does not contain {number}`)
+}
@@ -0,0 +1,264 @@
+package coverage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultDebtLabel is the label of the tracking issue of uncovered new code
+// (see DEBT_ISSUE).
+const defaultDebtLabel = "coverage-debt"
+
+// codeOwnersPaths are the locations of the CODEOWNERS file that GitHub
+// supports, in the order in which GitHub looks for it.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners maps the files of a repository to their owners as configured in
+// a CODEOWNERS file.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *CoverageIgnore // a single gitignore style pattern
+	depth   int             // number of path segments for patterns like "docs/*"; 0 for any
+	owners  []string        // e.g. "@alice", "@example/team" or an email address
+}
+
+// ParseCodeOwners parses the lines of a CODEOWNERS file. Each line is a
+// gitignore style pattern followed by the owners of the matching files.
+// Unlike in .gitignore, a pattern ending with "/*" only matches the files
+// directly inside the directory. Blank lines and comments are ignored.
+func ParseCodeOwners(r io.Reader) (*CodeOwners, error) {
+	owners := new(CodeOwners)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		pattern, err := ParseCoverageIgnore(strings.NewReader(fields[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		rule := codeOwnersRule{pattern: pattern, owners: fields[1:]}
+		if strings.HasSuffix(fields[0], "/*") {
+			rule.depth = strings.Count(strings.Trim(fields[0], "/"), "/") + 1
+		}
+
+		owners.rules = append(owners.rules, rule)
+	}
+
+	return owners, scanner.Err()
+}
+
+// readCodeOwners parses the CODEOWNERS file of the repository at root. It
+// returns nil if the repository has no CODEOWNERS file.
+func readCodeOwners(root string) (*CodeOwners, error) {
+	for _, name := range codeOwnersPaths {
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return ParseCodeOwners(f)
+	}
+
+	return nil, nil
+}
+
+// Owners returns the owners of the given slash separated path relative to the
+// repository root. As with GitHub, the last matching pattern takes precedence
+// and a pattern without owners removes the owners of the file.
+func (c *CodeOwners) Owners(name string) []string {
+	if c == nil {
+		return nil
+	}
+
+	depth := strings.Count(strings.Trim(name, "/"), "/") + 1
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		rule := c.rules[i]
+		if (rule.depth == 0 || rule.depth == depth) && rule.pattern.Match(name) {
+			if len(rule.owners) == 0 {
+				return nil
+			}
+			return rule.owners
+		}
+	}
+
+	return nil
+}
+
+// DebtItem is a block of uncovered new code that was merged into the target
+// branch.
+type DebtItem struct {
+	Path      string // relative to the repository root
+	StartLine int
+	EndLine   int
+	NumStmt   int
+	Owners    []string
+}
+
+// Debt returns the uncovered new code blocks of the report with the owners of
+// their files.
+func (r *Result) Debt(owners *CodeOwners) []DebtItem {
+	var items []DebtItem
+	for _, block := range r.getNewCodeBlocks() {
+		if block.Covered {
+			continue
+		}
+
+		path := r.repositoryPath(block.FileName)
+		items = append(items, DebtItem{
+			Path:      path,
+			StartLine: block.StartLine,
+			EndLine:   block.EndLine,
+			NumStmt:   block.NumStmt,
+			Owners:    owners.Owners(path),
+		})
+	}
+
+	return items
+}
+
+// debtMarker identifies the section of a commit in the tracking issue, so the
+// commit is only recorded once if the workflow is re-run.
+func debtMarker(sha string) string {
+	return "<!-- go-coverage-report:debt:" + sha + " -->"
+}
+
+// debtSection returns the Markdown section of the tracking issue that lists
+// the uncovered new code of the given commit as task list. The lines link to
+// the code at that commit on blobURL (e.g. https://github.com/owner/repo/blob).
+func debtSection(items []DebtItem, sha, blobURL string) string {
+	var stmt int
+	for _, item := range items {
+		stmt += item.NumStmt
+	}
+
+	section := new(strings.Builder)
+	fmt.Fprintln(section, debtMarker(sha))
+	fmt.Fprintf(section, "### %s (%d uncovered %s)\n", shortCommit(sha), stmt, plural(int64(stmt), "statement"))
+	fmt.Fprintln(section)
+	for _, item := range items {
+		lines := fmt.Sprintf("L%d", item.StartLine)
+		if item.EndLine > item.StartLine {
+			lines += fmt.Sprintf("-L%d", item.EndLine)
+		}
+
+		line := fmt.Sprintf("- [ ] [%s#%s](%s/%s/%s#%s) (%d %s)", item.Path, lines, blobURL, sha, item.Path, lines, item.NumStmt, plural(int64(item.NumStmt), "statement"))
+		if len(item.Owners) > 0 {
+			line += " " + strings.Join(item.Owners, " ")
+		}
+		fmt.Fprintln(section, line)
+	}
+
+	return section.String()
+}
+
+// debtAssignees returns the GitHub users among the owners of the items.
+// Teams and email addresses cannot be assigned to issues.
+func debtAssignees(items []DebtItem) []string {
+	seen := map[string]bool{}
+	var logins []string
+	for _, item := range items {
+		for _, owner := range item.Owners {
+			login, ok := strings.CutPrefix(owner, "@")
+			if !ok || strings.Contains(login, "/") || seen[login] {
+				continue
+			}
+			seen[login] = true
+			logins = append(logins, login)
+		}
+	}
+
+	return logins
+}
+
+// recordDebt adds the uncovered new code of the pushed commit to the open
+// tracking issue with the title of DEBT_ISSUE, which is created if there is
+// none. This way, coverage debt that was accepted despite the warnings of
+// the report (e.g. because the coverage checks are not required) is still
+// tracked after the merge.
+func (a *action) recordDebt(ctx context.Context, result *Result) error {
+	sha := a.cfg.PushAfter
+
+	owners, err := readCodeOwners(a.opts.repoRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read CODEOWNERS: %w", err)
+	}
+
+	items := result.Debt(owners)
+	if len(items) == 0 {
+		fmt.Fprintln(a.out, "The push adds no uncovered code")
+		return nil
+	}
+
+	serverURL := a.cfg.ServerURL
+	if serverURL == "" {
+		serverURL = "https://github.com"
+	}
+	blobURL := fmt.Sprintf("%s/%s/blob", strings.TrimSuffix(serverURL, "/"), a.cfg.Repository)
+	section := debtSection(items, sha, blobURL)
+	assignees := debtAssignees(items)
+
+	issues, err := a.gh.openIssues(ctx, a.cfg.DebtLabel)
+	if err != nil {
+		return fmt.Errorf("failed to list issues: %w", err)
+	}
+
+	var issue *githubIssue
+	for i := range issues {
+		if issues[i].Title == a.cfg.DebtIssue {
+			issue = &issues[i]
+			break
+		}
+	}
+
+	switch {
+	case issue != nil && strings.Contains(issue.Body, debtMarker(sha)):
+		fmt.Fprintf(a.out, "Commit %s is already recorded in issue #%d\n", shortCommit(sha), issue.Number)
+		return nil
+	case issue != nil:
+		fmt.Fprintf(a.out, "Adding %d uncovered code blocks to issue #%d\n", len(items), issue.Number)
+		body := strings.TrimRight(issue.Body, "\n") + "\n\n" + section
+		if err := a.gh.updateIssueBody(ctx, issue.Number, body); err != nil {
+			return err
+		}
+	default:
+		// Labels must exist in the repository before they can be added.
+		if err := a.gh.createLabel(ctx, a.cfg.DebtLabel, "Uncovered code tracked by go-coverage-report"); err != nil {
+			fmt.Fprintf(a.out, "::warning::Failed to create label %q: %v\n", a.cfg.DebtLabel, err)
+		}
+
+		body := "This issue lists new code that was merged without test coverage. " +
+			"Check off the blocks once they are covered or close the issue if the debt is accepted.\n\n" + section
+
+		fmt.Fprintf(a.out, "Creating issue %q with %d uncovered code blocks\n", a.cfg.DebtIssue, len(items))
+		if issue, err = a.gh.createIssue(ctx, a.cfg.DebtIssue, body, []string{a.cfg.DebtLabel}); err != nil {
+			return err
+		}
+	}
+
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	// Owners that cannot be assigned (e.g. without access to the repository)
+	// are ignored by GitHub.
+	return a.gh.addAssignees(ctx, issue.Number, assignees)
+}
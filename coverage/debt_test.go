@@ -0,0 +1,48 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeOwners(t *testing.T) {
+	owners, err := ParseCodeOwners(strings.NewReader(`
+# Default owners
+*       @example/maintainers
+
+*.md    docs@example.com  # inline comment
+/pkg/   @alice
+/pkg/generated/
+docs/*  @bob @example/docs
+`))
+	require.NoError(t, err)
+
+	tests := map[string][]string{
+		"main.go":                {"@example/maintainers"},
+		"README.md":              {"docs@example.com"},
+		"pkg/age/age.go":         {"@alice"},
+		"pkg/age/README.md":      {"@alice"},
+		"pkg/generated/types.go": nil,
+		"docs/guide.go":          {"@bob", "@example/docs"},
+		"docs/api/types.go":      {"@example/maintainers"},
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, owners.Owners(name), name)
+	}
+
+	assert.Nil(t, (*CodeOwners)(nil).Owners("main.go"))
+}
+
+func TestDebtAssignees(t *testing.T) {
+	items := []DebtItem{
+		{Path: "a.go", Owners: []string{"@alice", "@example/team", "bob@example.com"}},
+		{Path: "b.go", Owners: []string{"@carol", "@alice"}},
+		{Path: "c.go"},
+	}
+
+	assert.Equal(t, []string{"alice", "carol"}, debtAssignees(items))
+}
@@ -0,0 +1,121 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var deploymentReviewUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s deployment-review [OPTIONS]
+
+Review a deployment as custom deployment protection rule of a GitHub
+environment: the deployment is approved if the latest check run with the name
+given via -check (e.g. the job that runs the coverage report) succeeded on the
+commit that is deployed, and rejected otherwise.
+
+Deployment protection rules are implemented by GitHub Apps. The App that is
+enabled for the environment receives a "deployment_protection_rule" webhook for
+each deployment; pass its payload via -event and an installation token of the
+App via the GH_TOKEN or GITHUB_TOKEN environment variable. The App needs read
+access to checks and read and write access to deployments.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+// deploymentProtectionRuleEvent is the payload of the
+// "deployment_protection_rule" webhook.
+type deploymentProtectionRuleEvent struct {
+	Action      string `json:"action"`
+	Environment string `json:"environment"`
+	CallbackURL string `json:"deployment_callback_url"`
+	Deployment  struct {
+		SHA string `json:"sha"`
+	} `json:"deployment"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func runDeploymentReviewCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("deployment-review", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, deploymentReviewUsage)
+		fs.PrintDefaults()
+	}
+
+	eventPath := fs.String("event", os.Getenv("GITHUB_EVENT_PATH"), "path to the payload of the deployment_protection_rule webhook")
+	checkName := fs.String("check", "", "name of the check run of the coverage gate that must have succeeded (required)")
+	apiURL := fs.String("api-url", os.Getenv("GITHUB_API_URL"), "base URL of the GitHub API (default https://api.github.com)")
+	_ = fs.Parse(args)
+
+	if *checkName == "" {
+		fs.Usage()
+		return errors.New("missing -check flag")
+	}
+
+	event, err := readDeploymentProtectionRuleEvent(*eventPath)
+	if err != nil {
+		return err
+	}
+
+	gh := newGitHubClient(*apiURL, githubToken(os.LookupEnv), event.Repository.FullName)
+	return reviewDeployment(ctx, gh, event, *checkName, os.Stdout)
+}
+
+func readDeploymentProtectionRuleEvent(fileName string) (*deploymentProtectionRuleEvent, error) {
+	if fileName == "" {
+		return nil, errors.New("missing -event flag")
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event payload: %w", err)
+	}
+
+	var event deploymentProtectionRuleEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+
+	switch {
+	case event.Action != "requested":
+		return nil, fmt.Errorf("unexpected event action %q, expected a deployment_protection_rule event", event.Action)
+	case event.CallbackURL == "" || event.Deployment.SHA == "" || event.Repository.FullName == "":
+		return nil, errors.New("invalid event payload: missing deployment_callback_url, deployment.sha or repository.full_name")
+	}
+
+	return &event, nil
+}
+
+// reviewDeployment approves the deployment if the latest check run with the
+// given name succeeded on the deployed commit and rejects it otherwise.
+func reviewDeployment(ctx context.Context, gh *githubClient, event *deploymentProtectionRuleEvent, checkName string, out io.Writer) error {
+	run, err := gh.latestCheckRun(ctx, event.Deployment.SHA, checkName)
+	if err != nil {
+		return fmt.Errorf("failed to find check run: %w", err)
+	}
+
+	state := "rejected"
+	var comment string
+	switch {
+	case run == nil:
+		comment = fmt.Sprintf("The coverage gate %q did not run on commit %s.", checkName, event.Deployment.SHA)
+	case run.Status != "completed":
+		comment = fmt.Sprintf("The coverage gate %q has not completed on commit %s yet. Re-run the deployment once it passed.", checkName, event.Deployment.SHA)
+	case run.Conclusion != "success":
+		comment = fmt.Sprintf("The coverage gate %q did not pass on commit %s (%s): %s", checkName, event.Deployment.SHA, run.Conclusion, run.HTMLURL)
+	default:
+		state = "approved"
+		comment = fmt.Sprintf("The coverage gate %q passed on commit %s: %s", checkName, event.Deployment.SHA, run.HTMLURL)
+	}
+
+	fmt.Fprintf(out, "Deployment to %s %s: %s\n", event.Environment, state, comment)
+	return gh.reviewDeploymentProtectionRule(ctx, event.CallbackURL, event.Environment, state, comment)
+}
@@ -0,0 +1,75 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewDeployment(t *testing.T) {
+	tests := map[string]struct {
+		checkRuns string
+		state     string
+	}{
+		"passed":  {`[{"status": "completed", "conclusion": "success", "html_url": "https://example.com/1"}]`, "approved"},
+		"failed":  {`[{"status": "completed", "conclusion": "failure", "html_url": "https://example.com/1"}]`, "rejected"},
+		"pending": {`[{"status": "in_progress"}]`, "rejected"},
+		"missing": {`[]`, "rejected"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var callback string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				switch r.URL.Path {
+				case "/repos/example/repo/commits/def456/check-runs":
+					assert.Equal(t, "coverage", r.URL.Query().Get("check_name"))
+					fmt.Fprintf(w, `{"check_runs": %s}`, tt.checkRuns)
+				case "/repos/example/repo/actions/runs/9/deployment_protection_rule":
+					body, _ := io.ReadAll(r.Body)
+					callback = string(body)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			eventPath := filepath.Join(t.TempDir(), "event.json")
+			require.NoError(t, os.WriteFile(eventPath, []byte(fmt.Sprintf(`{
+				"action": "requested",
+				"environment": "production",
+				"deployment_callback_url": "%s/repos/example/repo/actions/runs/9/deployment_protection_rule",
+				"deployment": {"sha": "def456"},
+				"repository": {"full_name": "example/repo"}
+			}`, srv.URL)), 0644))
+
+			event, err := readDeploymentProtectionRuleEvent(eventPath)
+			require.NoError(t, err)
+
+			var out bytes.Buffer
+			gh := newGitHubClient(srv.URL, "secret", event.Repository.FullName)
+			require.NoError(t, reviewDeployment(context.Background(), gh, event, "coverage", &out))
+			assert.Contains(t, callback, `"environment_name":"production"`)
+			assert.Contains(t, callback, fmt.Sprintf(`"state":%q`, tt.state))
+			assert.Contains(t, out.String(), "Deployment to production "+tt.state)
+		})
+	}
+}
+
+func TestReadDeploymentProtectionRuleEvent_Invalid(t *testing.T) {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	require.NoError(t, os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 42}}`), 0644))
+
+	_, err := readDeploymentProtectionRuleEvent(eventPath)
+	assert.ErrorContains(t, err, "expected a deployment_protection_rule event")
+}
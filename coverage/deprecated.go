@@ -0,0 +1,185 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strings"
+)
+
+// DeprecatedFunc is a changed function whose doc comment contains a
+// "Deprecated: " paragraph, together with the coverage of the whole function.
+type DeprecatedFunc struct {
+	FileName               string
+	Name                   string
+	Line                   int
+	TotalStmt, CoveredStmt int64
+}
+
+// Percent returns the percentage of covered statements of the function.
+func (d DeprecatedFunc) Percent() float64 {
+	if d.TotalStmt == 0 {
+		return 0
+	}
+
+	return float64(d.CoveredStmt) / float64(d.TotalStmt) * 100
+}
+
+// DeprecatedFunctions returns the deprecated functions of the changed files
+// that contain new code, sorted by file and line. Files whose source code
+// cannot be found locally are ignored.
+func (r *Report) DeprecatedFunctions() []DeprecatedFunc {
+	var result []DeprecatedFunc
+	for _, fileName := range r.ChangedFiles {
+		newProfile := r.New.Files[fileName]
+		if newProfile == nil {
+			continue
+		}
+
+		extents := r.deprecatedExtents(fileName)
+		if len(extents) == 0 {
+			continue
+		}
+
+		for i, f := range functionCoverage(fileName, newProfile, extents) {
+			if !r.changesFunction(fileName, extents[i]) {
+				continue
+			}
+			result = append(result, DeprecatedFunc{
+				FileName:    fileName,
+				Name:        f.Name,
+				Line:        f.Line,
+				TotalStmt:   f.TotalStmt,
+				CoveredStmt: f.CoveredStmt,
+			})
+		}
+	}
+
+	return result
+}
+
+// changesFunction returns whether the new code of the file changes the given
+// function. The new code is determined like for the coverage of new code, but
+// without excluding deprecated functions (see newProfile).
+func (r *Report) changesFunction(fileName string, e funcExtent) bool {
+	oldProfile, newProfile := r.Old.Files[fileName], r.New.Files[fileName]
+	if oldProfile == nil {
+		return true // the entire file is new
+	}
+
+	if r.DiffInfo != nil {
+		fileDiff := r.DiffInfo.findFileDiff(fileName)
+		if fileDiff == nil || len(fileDiff.AddedLines) == 0 {
+			return true // all blocks are counted as new
+		}
+		return len(fileDiff.changedLinesInRange(e.startLine, e.endLine)) > 0
+	}
+
+	for _, b := range r.newBlocks(fileName, oldProfile, newProfile) {
+		if e.contains(b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deprecatedExtents returns the extents of the deprecated functions and
+// methods of the given file of the coverage profile.
+func (r *Report) deprecatedExtents(fileName string) []funcExtent {
+	if extents, ok := r.deprecatedCache[fileName]; ok {
+		return extents
+	}
+
+	var extents []funcExtent
+	if path, ok := r.source.find(fileName); ok {
+		extents = deprecatedFuncExtents(path)
+	}
+
+	if r.deprecatedCache == nil {
+		r.deprecatedCache = map[string][]funcExtent{}
+	}
+	r.deprecatedCache[fileName] = extents
+
+	return extents
+}
+
+// deprecatedFuncExtents parses the Go source file at the given path and
+// returns the extents of all functions and methods with a deprecation notice.
+func deprecatedFuncExtents(path string) []funcExtent {
+	m := NewStatementLineMapper()
+	file, err := parser.ParseFile(m.fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	var extents []funcExtent
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil && isDeprecated(fn.Doc) {
+			extents = append(extents, m.extent(qualifiedFuncName(fn), false, fn))
+		}
+	}
+
+	return extents
+}
+
+// isDeprecated returns whether the doc comment contains a paragraph that
+// starts with "Deprecated: ", which is the convention of Go to mark deprecated
+// identifiers.
+func isDeprecated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	lines := strings.Split(doc.Text(), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Deprecated: ") && (i == 0 || lines[i-1] == "") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inDeprecatedFunction returns whether the block starts inside of one of the
+// given deprecated functions.
+func inDeprecatedFunction(extents []funcExtent, b ProfileBlock) bool {
+	for _, e := range extents {
+		if e.contains(b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Report) addDeprecatedDetails(report *strings.Builder) {
+	funcs := r.DeprecatedFunctions()
+	if len(funcs) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, "---")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, r.detailsTag(foldDeprecated, func() bool { return false }))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Modifying Deprecated Code</summary>")
+	fmt.Fprintln(report)
+	if r.ExcludeDeprecated {
+		fmt.Fprintln(report, "The following deprecated functions were changed. Their new code is not counted as new code and does not affect the coverage thresholds.")
+	} else {
+		fmt.Fprintln(report, "The following deprecated functions were changed. Consider whether testing code that is slated for removal is worth the effort.")
+	}
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| Function | File | Coverage of Function |")
+	fmt.Fprintln(report, "|----------|------|----------------------|")
+
+	for _, f := range funcs {
+		n := r.numbers()
+		fmt.Fprintf(report, "| `%s` | %s:%d | %s (%s/%s statements) |\n", f.Name, f.FileName, f.Line, n.Percent(f.Percent()), n.Count(int64(f.CoveredStmt)), n.Count(int64(f.TotalStmt)))
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
@@ -0,0 +1,146 @@
+package coverage
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const deprecatedTestSource = `package legacy
+
+// Parse parses the input.
+//
+// Deprecated: Use ParseStrict instead.
+func Parse(s string) int {
+	if s == "" {
+		return 0
+	}
+	return len(s)
+}
+
+// ParseStrict parses the input.
+func ParseStrict(s string) int {
+	return len(s)
+}
+
+// Deprecated: Use New instead.
+func (c *Client) Close() {
+	c.closed = true
+}
+
+type Client struct{ closed bool }
+`
+
+func newDeprecatedTestReport(t *testing.T) *Report {
+	t.Helper()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "legacy")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "legacy.go"), []byte(deprecatedTestSource), 0644))
+
+	const fileName = "example.com/app/legacy/legacy.go"
+	oldCov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 6, StartCol: 26, EndLine: 7, EndCol: 13, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 10, StartCol: 2, EndLine: 10, EndCol: 15, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 14, StartCol: 32, EndLine: 16, EndCol: 2, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 19, StartCol: 25, EndLine: 21, EndCol: 2, NumStmt: 1, Count: 1},
+	)})
+	newCov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 6, StartCol: 26, EndLine: 7, EndCol: 13, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 7, StartCol: 13, EndLine: 9, EndCol: 3, NumStmt: 1, Count: 0},
+		ProfileBlock{StartLine: 10, StartCol: 2, EndLine: 10, EndCol: 15, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 14, StartCol: 32, EndLine: 16, EndCol: 2, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 19, StartCol: 25, EndLine: 21, EndCol: 2, NumStmt: 1, Count: 1},
+	)})
+
+	report := NewReport(oldCov, newCov, []string{fileName})
+	report.source = sourceTree{root: root}
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{
+		"legacy/legacy.go": {AddedLines: map[int]bool{7: true, 8: true, 9: true, 15: true}},
+	}}
+
+	return report
+}
+
+func TestReport_DeprecatedFunctions(t *testing.T) {
+	report := newDeprecatedTestReport(t)
+
+	assert.Equal(t, []DeprecatedFunc{{
+		FileName:    "example.com/app/legacy/legacy.go",
+		Name:        "Parse",
+		Line:        6,
+		TotalStmt:   3,
+		CoveredStmt: 2,
+	}}, report.DeprecatedFunctions(), "the unchanged method Close is not reported")
+
+	actual := report.Analyze().Markdown()
+	assert.Contains(t, actual, strings.Join([]string{
+		"<summary>Modifying Deprecated Code</summary>",
+		"",
+		"The following deprecated functions were changed. Consider whether testing code that is slated for removal is worth the effort.",
+		"",
+		"| Function | File | Coverage of Function |",
+		"|----------|------|----------------------|",
+		"| `Parse` | example.com/app/legacy/legacy.go:6 | 66.67% (2/3 statements) |",
+	}, "\n"))
+}
+
+func TestReport_ExcludeDeprecated(t *testing.T) {
+	report := newDeprecatedTestReport(t)
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 4, totalNew)
+	assert.EqualValues(t, 2, coveredNew)
+	require.Error(t, checkMinCoverage(report.Analyze(), 80))
+
+	report.ExcludeDeprecated = true
+	totalNew, coveredNew = report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 1, totalNew, "only the new code of ParseStrict is counted")
+	assert.EqualValues(t, 1, coveredNew)
+	assert.NoError(t, checkMinCoverage(report.Analyze(), 80))
+
+	// The function is still listed in the report, and the overall coverage
+	// is not affected.
+	assert.Len(t, report.DeprecatedFunctions(), 1)
+	assert.Contains(t, report.Analyze().Markdown(), "Their new code is not counted as new code and does not affect the coverage thresholds.")
+	assert.EqualValues(t, 5, report.New.TotalStmt)
+}
+
+func TestIsDeprecated(t *testing.T) {
+	src := `package p
+
+// A is deprecated.
+//
+// Deprecated: use B.
+func A() {}
+
+// Deprecated: use B.
+func C() {}
+
+// D mentions that it is not Deprecated: at all.
+func D() {}
+
+// E is not deprecated.
+// Deprecated: only a paragraph that starts with the notice counts.
+func E() {}
+
+func F() {}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, parser.ParseComments)
+	require.NoError(t, err)
+
+	var deprecated []string
+	for _, decl := range file.Decls {
+		if fn := decl.(*ast.FuncDecl); isDeprecated(fn.Doc) {
+			deprecated = append(deprecated, fn.Name.Name)
+		}
+	}
+	assert.Equal(t, []string{"A", "C"}, deprecated)
+}
@@ -0,0 +1,130 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The markers that delimit the coverage section in a pull request
+// description. They are HTML comments so they are not rendered by GitHub.
+const (
+	descriptionStartMarker = "<!-- go-coverage-report:start -->"
+	descriptionEndMarker   = "<!-- go-coverage-report:end -->"
+)
+
+var descriptionUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s description [OPTIONS] <REPORT_FILE>
+
+Read a pull request description from stdin and print it to stdout with the
+report from REPORT_FILE inserted between the following markers:
+
+  %s
+  %s
+
+If the description already contains the markers, only the text between them is
+replaced and everything else is left untouched. Otherwise the section is
+appended to the end of the description. This allows keeping the coverage report
+in the description of a pull request instead of in a comment.
+
+OPTIONS:
+`, filepath.Base(os.Args[0]), descriptionStartMarker, descriptionEndMarker))
+
+func runDescriptionCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("description", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, descriptionUsage)
+		fs.PrintDefaults()
+	}
+
+	remove := fs.Bool("remove", false, "remove the coverage section from the description instead of updating it (REPORT_FILE is not required)")
+	_ = fs.Parse(args)
+
+	var report []byte
+	switch {
+	case *remove && fs.NArg() == 0:
+	case !*remove && fs.NArg() == 1:
+		var err error
+		report, err = os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to read report: %w", err)
+		}
+	default:
+		fs.Usage()
+		return errors.New("expected exactly one report file")
+	}
+
+	body, err := io.ReadAll(contextReader{ctx: ctx, r: os.Stdin})
+	if err != nil {
+		return fmt.Errorf("failed to read description: %w", err)
+	}
+
+	var result string
+	if *remove {
+		result = removeDescriptionSection(string(body))
+	} else {
+		result = updateDescriptionSection(string(body), string(report))
+	}
+
+	_, err = fmt.Fprint(os.Stdout, result)
+	return err
+}
+
+// updateDescriptionSection returns the description with the coverage section
+// set to the given report. An existing section is replaced in place, otherwise
+// the section is appended to the description.
+func updateDescriptionSection(description, report string) string {
+	section := descriptionStartMarker + "\n" + strings.TrimSpace(report) + "\n" + descriptionEndMarker
+
+	before, after, ok := cutDescriptionSection(description)
+	if ok {
+		return before + section + after
+	}
+
+	description = strings.TrimRight(description, "\r\n\t ")
+	if description == "" {
+		return section + "\n"
+	}
+
+	return description + "\n\n" + section + "\n"
+}
+
+// removeDescriptionSection returns the description without the coverage
+// section including its markers.
+func removeDescriptionSection(description string) string {
+	before, after, ok := cutDescriptionSection(description)
+	if !ok {
+		return description
+	}
+
+	before = strings.TrimRight(before, "\r\n\t ")
+	after = strings.TrimLeft(after, "\r\n")
+	if before == "" || after == "" {
+		return before + after
+	}
+
+	return before + "\n\n" + after
+}
+
+// cutDescriptionSection returns the text before the start marker and after
+// the end marker of the coverage section. If the description does not contain
+// a complete section, ok is false.
+func cutDescriptionSection(description string) (before, after string, ok bool) {
+	start := strings.Index(description, descriptionStartMarker)
+	if start < 0 {
+		return "", "", false
+	}
+
+	end := strings.Index(description[start:], descriptionEndMarker)
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + len(descriptionEndMarker)
+
+	return description[:start], description[end:], true
+}
@@ -0,0 +1,52 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateDescriptionSection(t *testing.T) {
+	section := func(report string) string {
+		return descriptionStartMarker + "\n" + report + "\n" + descriptionEndMarker
+	}
+
+	cases := map[string]struct {
+		description string
+		want        string
+	}{
+		"empty": {
+			description: "",
+			want:        section("new report") + "\n",
+		},
+		"append": {
+			description: "Fixes a bug.\r\n",
+			want:        "Fixes a bug.\n\n" + section("new report") + "\n",
+		},
+		"replace": {
+			description: "Fixes a bug.\n\n" + section("old report") + "\n\nMore text.",
+			want:        "Fixes a bug.\n\n" + section("new report") + "\n\nMore text.",
+		},
+		"only first section is replaced": {
+			description: section("old") + "\n" + section("other"),
+			want:        section("new report") + "\n" + section("other"),
+		},
+		"missing end marker": {
+			description: "Text " + descriptionStartMarker,
+			want:        "Text " + descriptionStartMarker + "\n\n" + section("new report") + "\n",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.want, updateDescriptionSection(c.description, "\nnew report\n\n"))
+		})
+	}
+}
+
+func TestRemoveDescriptionSection(t *testing.T) {
+	body := updateDescriptionSection("Fixes a bug.", "report")
+	assert.Equal(t, "Fixes a bug.", removeDescriptionSection(body))
+	assert.Equal(t, "Before\n\nAfter", removeDescriptionSection("Before\n"+descriptionStartMarker+"x"+descriptionEndMarker+"\nAfter"))
+	assert.Equal(t, "unchanged", removeDescriptionSection("unchanged"))
+}
@@ -0,0 +1,357 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileDiff represents the lines that were added/modified in a file
+type FileDiff struct {
+	FileName      string
+	AddedLines    map[int]bool // line numbers that were added
+	ModifiedLines map[int]bool // line numbers that were modified (for now, treat same as added)
+	DeletedLines  map[int]bool // line numbers of the old file that were deleted (unified diffs only)
+	Hunks         []DiffHunk   // hunks of the diff in order (unified diffs only)
+	RenamedFrom   string       // path of the file before it was renamed or moved (unified diffs only)
+
+	oldToNew map[int]int // maps old to new line numbers of unchanged lines inside of hunks
+}
+
+// DiffHunk is the position of a single hunk of a unified diff in the old and
+// in the new version of the file.
+type DiffHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+}
+
+// DiffInfo contains diff information for all changed files
+type DiffInfo struct {
+	Files map[string]*FileDiff // maps file path to its diff
+}
+
+// ParseDiffInfo parses a JSON file containing diff information
+// Expected format: { "file.go": { "added_lines": [1, 2, 3], "modified_lines": [5, 6] } }
+func ParseDiffInfo(filename string) (*DiffInfo, error) {
+	if filename == "" {
+		return nil, nil // No diff info provided
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDiffInfo(data)
+}
+
+func parseDiffInfo(data []byte) (*DiffInfo, error) {
+	var rawDiff map[string]struct {
+		AddedLines    []int `json:"added_lines"`
+		ModifiedLines []int `json:"modified_lines"`
+	}
+
+	err := json.Unmarshal(trimBOM(data), &rawDiff)
+	if err != nil {
+		return nil, err
+	}
+
+	diffInfo := &DiffInfo{
+		Files: make(map[string]*FileDiff),
+	}
+
+	for fileName, fileDiff := range rawDiff {
+		fd := &FileDiff{
+			FileName:      fileName,
+			AddedLines:    make(map[int]bool),
+			ModifiedLines: make(map[int]bool),
+		}
+
+		for _, line := range fileDiff.AddedLines {
+			if line < 1 || line > maxLineNumber {
+				return nil, fmt.Errorf("invalid added line %d in %q", line, fileName)
+			}
+			fd.AddedLines[line] = true
+		}
+		for _, line := range fileDiff.ModifiedLines {
+			if line < 1 || line > maxLineNumber {
+				return nil, fmt.Errorf("invalid modified line %d in %q", line, fileName)
+			}
+			fd.ModifiedLines[line] = true
+		}
+
+		diffInfo.Files[fileName] = fd
+	}
+
+	return diffInfo, nil
+}
+
+// ParseUnifiedDiff parses a unified diff format (git diff output)
+// This is an alternative format that's more standard
+func ParseUnifiedDiff(filename string) (*DiffInfo, error) {
+	return parseUnifiedDiffContext(context.Background(), filename, parseOptions{})
+}
+
+// parseUnifiedDiffContext is like ParseUnifiedDiff but stops reading the file
+// once the context is done.
+func parseUnifiedDiffContext(ctx context.Context, filename string, o parseOptions) (*DiffInfo, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseUnifiedDiff(contextReader{ctx: ctx, r: file}, o)
+}
+
+func parseUnifiedDiff(r io.Reader, o parseOptions) (*DiffInfo, error) {
+	diffInfo := &DiffInfo{
+		Files: make(map[string]*FileDiff),
+	}
+
+	scanner := newLineReader(r, o.maxLineLength)
+	var currentFile *FileDiff
+	var currentLine, currentOldLine int
+	var renamedFrom string
+
+	newFileDiff := func(fileName string) *FileDiff {
+		fd := &FileDiff{
+			FileName:      fileName,
+			AddedLines:    make(map[int]bool),
+			ModifiedLines: make(map[int]bool),
+			DeletedLines:  make(map[int]bool),
+			oldToNew:      make(map[int]int),
+		}
+		diffInfo.Files[fileName] = fd
+		return fd
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Renames are part of the extended header of git diffs. Files that
+		// were only renamed have no "+++" header at all.
+		if strings.HasPrefix(line, "diff --git ") {
+			currentFile, renamedFrom = nil, ""
+			continue
+		}
+		if strings.HasPrefix(line, "rename from ") && currentFile == nil {
+			renamedFrom = strings.TrimPrefix(line, "rename from ")
+			continue
+		}
+		if strings.HasPrefix(line, "rename to ") && currentFile == nil && renamedFrom != "" {
+			currentFile = newFileDiff(strings.TrimPrefix(line, "rename to "))
+			currentFile.RenamedFrom = renamedFrom
+			continue
+		}
+
+		// Check for file header: +++ b/path/to/file.go
+		if strings.HasPrefix(line, "+++ b/") {
+			fileName := strings.TrimPrefix(line, "+++ b/")
+			if currentFile == nil || currentFile.FileName != fileName {
+				currentFile = newFileDiff(fileName)
+			}
+			continue
+		}
+
+		// Check for hunk header: @@ -old_start,old_count +new_start,new_count @@
+		if strings.HasPrefix(line, "@@") {
+			parts := strings.Split(line, " ")
+			if len(parts) >= 3 && strings.HasPrefix(parts[2], "+") {
+				// Parse +new_start,new_count
+				start, count, ok := parseHunkRange(strings.TrimPrefix(parts[2], "+"))
+				if !ok {
+					return nil, fmt.Errorf("invalid hunk header %q", line)
+				}
+				currentLine = start
+
+				// The old range is only needed to map old line numbers
+				// to new ones, so hunks without one are simply skipped.
+				oldStart, oldCount, ok := parseHunkRange(strings.TrimPrefix(parts[1], "-"))
+				if ok && strings.HasPrefix(parts[1], "-") && currentFile != nil {
+					currentOldLine = oldStart
+					currentFile.Hunks = append(currentFile.Hunks, DiffHunk{
+						OldStart: oldStart,
+						OldLines: oldCount,
+						NewStart: start,
+						NewLines: count,
+					})
+				} else {
+					currentOldLine = 0
+				}
+			}
+			continue
+		}
+
+		if currentFile == nil {
+			continue
+		}
+
+		// Lines starting with + are added lines
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			if currentLine > maxLineNumber {
+				return nil, fmt.Errorf("line number of added line in %q exceeds %d", currentFile.FileName, maxLineNumber)
+			}
+			currentFile.AddedLines[currentLine] = true
+			currentLine++
+		} else if strings.HasPrefix(line, " ") {
+			// Context line (unchanged)
+			if currentOldLine > 0 {
+				currentFile.oldToNew[currentOldLine] = currentLine
+				currentOldLine++
+			}
+			currentLine++
+		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") && currentOldLine > 0 {
+			if currentOldLine > maxLineNumber {
+				return nil, fmt.Errorf("line number of deleted line in %q exceeds %d", currentFile.FileName, maxLineNumber)
+			}
+			currentFile.DeletedLines[currentOldLine] = true
+			currentOldLine++
+		}
+	}
+
+	return diffInfo, scanner.Err()
+}
+
+// parseHunkRange parses the "start,count" range of a hunk header. The count
+// defaults to 1 if it is omitted.
+func parseHunkRange(s string) (start, count int, ok bool) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start < 0 || start > maxLineNumber {
+		return 0, 0, false
+	}
+
+	count = 1
+	if hasCount {
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 0 || count > maxLineNumber {
+			// Only the start is required to track added lines.
+			count = 0
+		}
+	}
+
+	return start, count, true
+}
+
+// mapOldLine returns the line number in the new version of the file of the
+// given line of the old version. It returns false if the line was deleted or
+// if the diff contains no hunk information to map lines.
+func (fd *FileDiff) mapOldLine(line int) (int, bool) {
+	if len(fd.Hunks) == 0 || fd.DeletedLines[line] {
+		return 0, false
+	}
+
+	if newLine, ok := fd.oldToNew[line]; ok {
+		return newLine, true
+	}
+
+	// Lines outside of hunks are shifted by all hunks before them.
+	offset := 0
+	for _, h := range fd.Hunks {
+		oldEnd := h.OldStart + h.OldLines - 1
+		if h.OldLines == 0 {
+			// Pure insertions are placed after the OldStart line.
+			oldEnd = h.OldStart
+		}
+
+		if line <= oldEnd {
+			if line >= h.OldStart && h.OldLines > 0 {
+				// Inside of a hunk but neither context nor deleted,
+				// which means the diff was truncated.
+				return 0, false
+			}
+			break
+		}
+
+		offset += h.NewLines - h.OldLines
+	}
+
+	return line + offset, true
+}
+
+// findFileDiff tries to find a FileDiff for the given fileName
+// It handles the case where fileName might have a package prefix (e.g., "github.com/user/repo/cmd/file.go")
+// while the diff has relative paths (e.g., "cmd/file.go")
+func (d *DiffInfo) findFileDiff(fileName string) *FileDiff {
+	fileDiff, _ := d.matchFileDiff(fileName)
+	return fileDiff
+}
+
+// matchFileDiff is like findFileDiff but also returns the path of the file in
+// the diff, which differs from fileName if it was matched by suffix.
+func (d *DiffInfo) matchFileDiff(fileName string) (*FileDiff, string) {
+	if d == nil {
+		return nil, ""
+	}
+
+	// Try exact match first
+	if fileDiff, ok := d.Files[fileName]; ok {
+		return fileDiff, fileName
+	}
+
+	// Try to match by suffix - the diff path should be a suffix of the coverage path
+	// Coverage: "github.com/user/repo/cmd/file.go"
+	// Diff:     "cmd/file.go"
+	for diffPath, fileDiff := range d.Files {
+		if strings.HasSuffix(fileName, diffPath) {
+			return fileDiff, diffPath
+		}
+	}
+
+	// Try the reverse - maybe the coverage path is shorter
+	for diffPath, fileDiff := range d.Files {
+		if strings.HasSuffix(diffPath, fileName) {
+			return fileDiff, diffPath
+		}
+	}
+
+	return nil, ""
+}
+
+// IsLineAdded checks if a specific line was added in the diff
+func (d *DiffInfo) IsLineAdded(fileName string, lineNum int) bool {
+	fileDiff := d.findFileDiff(fileName)
+	if fileDiff == nil {
+		return false
+	}
+
+	return fileDiff.AddedLines[lineNum] || fileDiff.ModifiedLines[lineNum]
+}
+
+// IsLineInRange checks if any line in the range [startLine, endLine] was added
+func (d *DiffInfo) IsLineInRange(fileName string, startLine, endLine int) bool {
+	fileDiff := d.findFileDiff(fileName)
+	if fileDiff == nil {
+		return false
+	}
+
+	return len(fileDiff.changedLinesInRange(startLine, endLine)) > 0
+}
+
+// changedLinesInRange returns all added or modified lines in the range
+// [startLine, endLine]. It iterates over the changed lines instead of the
+// range since coverage blocks can span many more lines than were changed.
+func (fd *FileDiff) changedLinesInRange(startLine, endLine int) []int {
+	var lines []int
+	for line := range fd.AddedLines {
+		if startLine <= line && line <= endLine {
+			lines = append(lines, line)
+		}
+	}
+	for line := range fd.ModifiedLines {
+		if startLine <= line && line <= endLine && !fd.AddedLines[line] {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -1,7 +1,9 @@
-package main
+package coverage
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,6 +52,89 @@ index 1234567..abcdefg 100644
 	assert.False(t, diffInfo.IsLineAdded("test.go", 11), "Line 11 should not be marked as added")
 }
 
+func TestParseUnifiedDiff_Renames(t *testing.T) {
+	diff := `diff --git a/pkg/old/util.go b/pkg/new/util.go
+similarity index 100%
+rename from pkg/old/util.go
+rename to pkg/new/util.go
+diff --git a/pkg/a/a.go b/pkg/b/b.go
+similarity index 80%
+rename from pkg/a/a.go
+rename to pkg/b/b.go
+index 1234567..abcdefg 100644
+--- a/pkg/a/a.go
++++ b/pkg/b/b.go
+@@ -1,3 +1,4 @@
+-package a
++package b
++
+ func F() {}
+diff --git a/c.go b/c.go
+--- a/c.go
++++ b/c.go
+@@ -1 +1,2 @@
+ package c
++// rename from x.go
+`
+	diffInfo, err := parseUnifiedDiff(strings.NewReader(diff), parseOptions{})
+	require.NoError(t, err)
+	require.Len(t, diffInfo.Files, 3)
+
+	assert.Equal(t, "pkg/old/util.go", diffInfo.Files["pkg/new/util.go"].RenamedFrom)
+	assert.Empty(t, diffInfo.Files["pkg/new/util.go"].AddedLines)
+
+	assert.Equal(t, "pkg/a/a.go", diffInfo.Files["pkg/b/b.go"].RenamedFrom)
+	assert.Equal(t, map[int]bool{1: true, 2: true}, diffInfo.Files["pkg/b/b.go"].AddedLines)
+
+	assert.Empty(t, diffInfo.Files["c.go"].RenamedFrom)
+	assert.Equal(t, map[int]bool{2: true}, diffInfo.Files["c.go"].AddedLines)
+}
+
+func TestFileDiff_MapOldLine(t *testing.T) {
+	diffContent := `diff --git a/test.go b/test.go
+--- a/test.go
++++ b/test.go
+@@ -3,4 +3,5 @@
+ three
+-four
++FOUR
++FOUR AND A HALF
+ five
+ six
+@@ -10,0 +12,2 @@
++eleven
++twelve
+`
+
+	diffInfo, err := parseUnifiedDiff(strings.NewReader(diffContent), parseOptions{})
+	require.NoError(t, err)
+	fd := diffInfo.Files["test.go"]
+	require.NotNil(t, fd)
+
+	assert.Equal(t, []DiffHunk{
+		{OldStart: 3, OldLines: 4, NewStart: 3, NewLines: 5},
+		{OldStart: 10, OldLines: 0, NewStart: 12, NewLines: 2},
+	}, fd.Hunks)
+	assert.Equal(t, map[int]bool{4: true}, fd.DeletedLines)
+
+	cases := map[int]int{
+		1:  1,  // before all hunks
+		3:  3,  // context line
+		5:  6,  // context line after the replacement
+		7:  8,  // after the first hunk
+		10: 11, // the insertion happens after this line
+		11: 14, // after both hunks
+	}
+	for oldLine, newLine := range cases {
+		actual, ok := fd.mapOldLine(oldLine)
+		assert.True(t, ok, "line %d", oldLine)
+		assert.Equal(t, newLine, actual, "line %d", oldLine)
+	}
+
+	_, ok := fd.mapOldLine(4)
+	assert.False(t, ok, "deleted lines cannot be mapped")
+}
+
 func TestIsLineInRange(t *testing.T) {
 	diffInfo := &DiffInfo{
 		Files: map[string]*FileDiff{
@@ -257,3 +342,84 @@ func TestCalculateNewCodeCoverageFromDiff_PathMismatch(t *testing.T) {
 	assert.Equal(t, int64(5), totalNew, "Should count 5 new statements despite path mismatch")
 	assert.Equal(t, int64(5), coveredNew, "Should count 5 covered new statements despite path mismatch")
 }
+
+func TestParseUnifiedDiff_Hardening(t *testing.T) {
+	longLine := "+" + strings.Repeat("x", 1<<20)
+	diff := "+++ b/gen.go\n@@ -0,0 +1,2 @@\n" + longLine + "\n+\xff\xfe\n"
+	diffInfo, err := parseUnifiedDiff(strings.NewReader(diff), parseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]bool{1: true, 2: true}, diffInfo.Files["gen.go"].AddedLines)
+
+	_, err = parseUnifiedDiff(strings.NewReader("+++ b/a.go\n@@ -1 +99999999999 @@\n+x\n"), parseOptions{})
+	assert.ErrorContains(t, err, "invalid hunk header")
+
+	_, err = parseUnifiedDiff(strings.NewReader("+++ b/a.go\n@@ -1 +x,1 @@\n+x\n"), parseOptions{})
+	assert.ErrorContains(t, err, "invalid hunk header")
+
+	// Lines that exceed the maximum line length are truncated but still counted.
+	diff = "+++ b/gen.go\n@@ -0,0 +7,2 @@\n+" + strings.Repeat("x", defaultMaxLineLength+1) + "\n+y\n"
+	diffInfo, err = parseUnifiedDiff(strings.NewReader(diff), parseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]bool{7: true, 8: true}, diffInfo.Files["gen.go"].AddedLines)
+}
+
+func TestParseDiffInfo_Hardening(t *testing.T) {
+	_, err := parseDiffInfo([]byte(`{"a.go": {"added_lines": [1, -5]}}`))
+	assert.ErrorContains(t, err, "invalid added line -5")
+
+	_, err = parseDiffInfo([]byte(`{"a.go": {"modified_lines": [1000000000]}}`))
+	assert.ErrorContains(t, err, "invalid modified line 1000000000")
+
+	diffInfo, err := parseDiffInfo([]byte("\xEF\xBB\xBF{\"a.go\": {\"added_lines\": [3]}}\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[int]bool{3: true}, diffInfo.Files["a.go"].AddedLines)
+}
+
+func FuzzParseUnifiedDiff(f *testing.F) {
+	for _, name := range []string{"testdata/01-diff.patch", "testdata/04-diff.patch", "testdata/05-diff.patch"} {
+		data, err := os.ReadFile(name)
+		require.NoError(f, err)
+		f.Add(data)
+	}
+	f.Add([]byte("+++ b/a.go\n@@ -1,2 +3,4 @@\n+x\n y\n-z\n"))
+
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(f, err)
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		diffInfo, err := parseUnifiedDiff(bytes.NewReader(data), parseOptions{})
+		if err != nil {
+			return
+		}
+
+		for _, fd := range diffInfo.Files {
+			for line := range fd.AddedLines {
+				if line > maxLineNumber {
+					t.Fatalf("added line %d exceeds maximum line number", line)
+				}
+			}
+		}
+
+		report := NewReport(oldCov, newCov, []string{"github.com/pentohq/pento/pkg/age/age.go"})
+		report.DiffInfo = diffInfo
+		_ = report.Analyze().Markdown()
+	})
+}
+
+func FuzzParseDiffInfo(f *testing.F) {
+	f.Add([]byte(`{"a.go": {"added_lines": [1, 2, 3], "modified_lines": [5, 6]}}`))
+	f.Add([]byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		diffInfo, err := parseDiffInfo(data)
+		if err != nil {
+			return
+		}
+
+		for fileName := range diffInfo.Files {
+			diffInfo.IsLineInRange(fileName, 1, 100)
+		}
+	})
+}
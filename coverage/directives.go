@@ -0,0 +1,77 @@
+package coverage
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+const (
+	coverageOffDirective = "//coverage:off"
+	coverageOnDirective  = "//coverage:on"
+)
+
+// LineRange is an inclusive range of lines in a source file.
+type LineRange struct {
+	StartLine, EndLine int
+}
+
+// CoverageDirectiveRegions returns the regions of the given Go source code
+// that are enclosed by "//coverage:off" and "//coverage:on" comments. A region
+// that is never switched back on extends to the end of the file.
+func CoverageDirectiveRegions(fileName string, src []byte) ([]LineRange, error) {
+	if !bytes.Contains(src, []byte(coverageOffDirective)) {
+		return nil, nil // fast path, no need to parse the file
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []LineRange
+	start := 0 // zero means coverage is currently on
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			switch strings.TrimSpace(c.Text) {
+			case coverageOffDirective:
+				if start == 0 {
+					start = fset.Position(c.Pos()).Line
+				}
+			case coverageOnDirective:
+				if start != 0 {
+					regions = append(regions, LineRange{StartLine: start, EndLine: fset.Position(c.Pos()).Line})
+					start = 0
+				}
+			}
+		}
+	}
+
+	if start != 0 {
+		end := fset.File(file.Pos()).LineCount()
+		regions = append(regions, LineRange{StartLine: start, EndLine: end})
+	}
+
+	return regions, nil
+}
+
+// ApplyCoverageDirectives excludes all regions marked via coverage directives
+// in the source files of the given coverage profile. Files in skip are
+// ignored, which is useful for the old coverage profile since the local
+// source code of changed files does not match the old profile anymore.
+// Files whose source code cannot be found locally are ignored as well.
+func ApplyCoverageDirectives(cov *Coverage, skip map[string]bool) error {
+	return applySourceExclusions(sourceTree{}, cov, skip, coverageDirectiveExclusions, nil)
+}
+
+// coverageDirectiveExclusions is the exclusionFinder for coverage directives.
+func coverageDirectiveExclusions(path string, src []byte) ([]excludedRegion, error) {
+	ranges, err := CoverageDirectiveRegions(path, src)
+	regions := make([]excludedRegion, len(ranges))
+	for i, r := range ranges {
+		regions[i] = excludedRegion{LineRange: r, Reason: coverageOffDirective + " directive"}
+	}
+	return regions, err
+}
@@ -0,0 +1,114 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const directivesTestSource = `package foo
+
+func Dispatch(cmd string) int {
+	//coverage:off
+	switch cmd {
+	case "a":
+		return 1
+	case "b":
+		return 2
+	}
+	//coverage:on
+	return 0
+}
+
+func Unfinished() int {
+	// coverage:off is only detected without a space
+	x := 1
+	//coverage:off
+	return x
+}
+`
+
+func TestCoverageDirectiveRegions(t *testing.T) {
+	regions, err := CoverageDirectiveRegions("foo.go", []byte(directivesTestSource))
+	require.NoError(t, err)
+
+	assert.Equal(t, []LineRange{
+		{StartLine: 4, EndLine: 11},
+		{StartLine: 18, EndLine: 20},
+	}, regions)
+}
+
+func TestCoverageDirectiveRegions_NoDirectives(t *testing.T) {
+	regions, err := CoverageDirectiveRegions("foo.go", []byte("this is not even Go code"))
+	require.NoError(t, err)
+	assert.Empty(t, regions)
+}
+
+func TestApplyCoverageDirectives(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "foo.go")
+	err := os.WriteFile(fileName, []byte(directivesTestSource), 0644)
+	require.NoError(t, err)
+
+	newCov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 3, StartCol: 31, EndLine: 5, EndCol: 13, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 6, StartCol: 11, EndLine: 7, EndCol: 11, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 8, StartCol: 11, EndLine: 9, EndCol: 11, NumStmt: 1, Count: 0},
+		ProfileBlock{StartLine: 12, StartCol: 2, EndLine: 12, EndCol: 10, NumStmt: 1, Count: 0},
+		ProfileBlock{StartLine: 15, StartCol: 24, EndLine: 19, EndCol: 10, NumStmt: 2, Count: 0},
+	)})
+
+	err = ApplyCoverageDirectives(newCov, nil)
+	require.NoError(t, err)
+
+	// Only the two case clauses start within the region of the directives.
+	assert.EqualValues(t, 4, newCov.TotalStmt)
+	assert.EqualValues(t, 1, newCov.CoveredStmt)
+	assert.EqualValues(t, 3, newCov.MissedStmt)
+	assert.EqualValues(t, 2, newCov.ExcludedStmt())
+
+	p := newCov.Files[fileName]
+	assert.EqualValues(t, 4, p.TotalStmt)
+	assert.EqualValues(t, 1, p.CoveredStmt)
+	assert.Len(t, p.Blocks, 3)
+
+	oldCov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 6, StartCol: 11, EndLine: 7, EndCol: 11, NumStmt: 1, Count: 1},
+	)})
+
+	err = ApplyCoverageDirectives(oldCov, map[string]bool{fileName: true})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, oldCov.TotalStmt, "skipped files should not be modified")
+}
+
+func TestReport_Markdown_ExcludedCode(t *testing.T) {
+	newCov := New([]*Profile{newTestProfile("example.com/foo/foo.go",
+		ProfileBlock{StartLine: 3, EndLine: 5, NumStmt: 2, Count: 1},
+		ProfileBlock{StartLine: 6, EndLine: 7, NumStmt: 3, Count: 0},
+	)})
+	newCov.Exclude("example.com/foo/foo.go", 6, 10, "//coverage:off directive")
+
+	report := NewReport(New(nil), newCov, []string{"example.com/foo/foo.go"})
+	actual := report.Analyze().Markdown()
+
+	assert.Contains(t, actual, "<summary>Excluded Code (3 statements)</summary>")
+	assert.Contains(t, actual, "| example.com/foo/foo.go | 6-10 | 3 | //coverage:off directive |")
+	assert.Contains(t, actual, "| **New Code** | N/A | 100.00% | 2/2 statements | :star2: |")
+}
+
+// newTestProfile creates a Profile from the given blocks and computes the
+// statement counts just like ParseProfiles does.
+func newTestProfile(fileName string, blocks ...ProfileBlock) *Profile {
+	p := &Profile{FileName: fileName, Mode: "set", Blocks: blocks}
+	for _, b := range blocks {
+		p.TotalStmt += int64(b.NumStmt)
+		if b.Count > 0 {
+			p.CoveredStmt += int64(b.NumStmt)
+		}
+	}
+	p.MissedStmt = p.TotalStmt - p.CoveredStmt
+
+	return p
+}
@@ -0,0 +1,273 @@
+package coverage
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The layouts of the Markdown report that can be selected via Report.Layout.
+const (
+	layoutFlat      = "flat"      // separate sections for packages, files and new code
+	layoutDrilldown = "drilldown" // nested sections: package → file → function
+	layoutAuto      = "auto"      // drilldown if many files changed
+)
+
+// drilldownMinFiles is the number of changed files from which the auto layout
+// renders the drilldown, since the flat sections become unwieldy.
+const drilldownMinFiles = 50
+
+// validateLayout returns an error if the given layout of the Markdown report
+// is not supported. An empty layout selects the flat layout.
+func validateLayout(layout string) error {
+	switch layout {
+	case "", layoutFlat, layoutDrilldown, layoutAuto:
+		return nil
+	default:
+		return fmt.Errorf("unsupported layout: %q (supported layouts: %s, %s and %s)", layout, layoutFlat, layoutDrilldown, layoutAuto)
+	}
+}
+
+// drilldown returns whether the Markdown report uses the drilldown layout.
+func (r *Report) drilldown() bool {
+	switch r.Layout {
+	case layoutDrilldown:
+		return true
+	case layoutAuto:
+		return len(r.ChangedFiles) >= drilldownMinFiles
+	default:
+		return false
+	}
+}
+
+// addDrilldown replaces the package, file and new code sections of the flat
+// layout with a single section of nested <details> elements: each changed
+// package contains its changed files, and each file contains the new code of
+// its functions. Every level shows its coverage and the coverage of its new
+// code, so reviewers of large pull requests only expand what interests them.
+func (r *Result) addDrilldown(report *strings.Builder) {
+	fileBlocks := make(map[string][]NewCodeBlock)
+	if totalNew, _ := r.calculateNewCodeCoverage(); totalNew > 0 {
+		for _, block := range r.getNewCodeBlocks() {
+			fileBlocks[block.FileName] = append(fileBlocks[block.FileName], block)
+		}
+	}
+
+	pkgFiles := make(map[string][]string)
+	hasTestFiles := false
+	for _, f := range r.ChangedFiles {
+		pkg := path.Dir(f)
+		pkgFiles[pkg] = append(pkgFiles[pkg], f)
+		hasTestFiles = hasTestFiles || strings.HasSuffix(f, "_test.go")
+	}
+
+	fmt.Fprintln(report, "---")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, r.detailsTag(foldPackages, r.hasPackageRegression))
+	fmt.Fprintln(report)
+	fmt.Fprintf(report, "<summary>Coverage by Package (%s, %s)</summary>\n", countOf(len(r.ChangedPackages), "package"), countOf(len(r.ChangedFiles), "file"))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "Expand a package to see its changed files and a file to see the new code of its functions. "+
+		"Coverage values refer to ***code statements***, the value in brackets is the change since the old version of the code.")
+	fmt.Fprintln(report)
+
+	contributions := make(map[string]TestFileContribution)
+	for _, c := range r.TestFileContributions() {
+		contributions[c.FileName] = c
+	}
+
+	oldPkgs, newPkgs := r.Old.ByPackage(), r.New.ByPackage()
+	for _, pkg := range r.ChangedPackages {
+		var oldPercent, newPercent float64
+		if cov, ok := oldPkgs[pkg]; ok {
+			oldPercent = cov.Percent()
+		}
+		if cov, ok := newPkgs[pkg]; ok {
+			newPercent = cov.Percent()
+		}
+
+		var totalNew, coveredNew int64
+		var testFiles []string
+		for _, f := range pkgFiles[pkg] {
+			total, covered := r.newCodeCoverageOf(f)
+			totalNew += total
+			coveredNew += covered
+			if strings.HasSuffix(f, "_test.go") {
+				name := "`" + path.Base(f) + "`"
+				if c, ok := contributions[f]; ok {
+					name += fmt.Sprintf(" (%s of %s statements covered uniquely)", r.numbers().Count(c.UniqueStmt), r.numbers().Count(c.CoveredStmt))
+				}
+				testFiles = append(testFiles, name)
+			}
+		}
+
+		emoji, diffStr := r.emojiScore(newPercent, oldPercent)
+		fmt.Fprintln(report, "<details>")
+		fmt.Fprintln(report)
+		fmt.Fprintf(report, "<summary><b>%s</b> · %s · %s%s</summary>\n",
+			pkg, r.coverageSummary(newPercent, diffStr, emoji), countOf(len(pkgFiles[pkg]), "file"), r.newCodeSummary(totalNew, coveredNew))
+		fmt.Fprintln(report)
+
+		for _, f := range pkgFiles[pkg] {
+			if nb, ok := r.notBuilt(f); ok {
+				fmt.Fprintf(report, "`%s` · not built on %s (`%s`)\n", path.Base(f), nb.Platform, nb.Constraint)
+				fmt.Fprintln(report)
+			} else if !strings.HasSuffix(f, "_test.go") {
+				r.addFileDrilldown(report, f, fileBlocks[f])
+			}
+		}
+
+		if len(testFiles) > 0 {
+			fmt.Fprintf(report, "Changed unit test files: %s\n", strings.Join(testFiles, ", "))
+			fmt.Fprintln(report)
+		}
+
+		fmt.Fprintln(report, "</details>")
+		fmt.Fprintln(report)
+	}
+
+	if hasTestFiles {
+		r.addExampleDetails(report)
+	}
+
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+// addFileDrilldown adds the nested section of a changed file, which lists the
+// new code of the file grouped by function.
+func (r *Result) addFileDrilldown(report *strings.Builder, fileName string, blocks []NewCodeBlock) {
+	oldProfile, newProfile := r.Old.Files[fileName], r.New.Files[fileName]
+
+	var oldPercent, newPercent float64
+	if oldProfile != nil {
+		oldPercent = oldProfile.CoveragePercent()
+	}
+	if newProfile != nil {
+		newPercent = newProfile.CoveragePercent()
+	}
+
+	totalNew, coveredNew := r.newCodeCoverageOf(fileName)
+	emoji, diffStr := r.emojiScore(newPercent, oldPercent)
+
+	fmt.Fprintln(report, "<details>")
+	fmt.Fprintln(report)
+	var reason string
+	if why := r.zeroCoverageReason(fileName); why != "" {
+		reason = " · " + why
+	}
+
+	fmt.Fprintf(report, "<summary>%s · %s · %s statements, %s missed%s%s</summary>\n",
+		path.Base(fileName), r.coverageSummary(newPercent, diffStr, emoji),
+		r.valueWithDelta(oldProfile.GetTotal(), newProfile.GetTotal()),
+		r.valueWithDelta(oldProfile.GetMissed(), newProfile.GetMissed()),
+		r.newCodeSummary(totalNew, coveredNew), reason)
+	fmt.Fprintln(report)
+
+	if len(blocks) == 0 {
+		fmt.Fprintln(report, "This file contains no new code.")
+		fmt.Fprintln(report)
+	}
+
+	sourceLines, _ := r.source.readLines(fileName)
+	for _, fn := range newCodeByFunction(r.source, fileName, blocks) {
+		if fn.name != "" && sourceLines != nil {
+			lines := LineCoverage(r.newLineCoverage(fileName, sourceLines, fn.blocks))
+			fmt.Fprintf(report, "**`%s`** · %d/%d new lines covered\n", fn.name, len(lines.Covered()), len(lines))
+			fmt.Fprintln(report)
+		}
+		r.writeNewCodeDiff(report, fileName, sourceLines, fn.blocks)
+	}
+
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+// functionBlocks are the new code blocks of a single function.
+type functionBlocks struct {
+	name   string // empty for code outside of functions or without source code
+	line   int
+	blocks []NewCodeBlock
+}
+
+// newCodeByFunction groups the new code blocks of a file by the function that
+// contains them, ordered by the position of the functions in the file. The
+// blocks of closures belong to their enclosing function. If the source code of
+// the file cannot be found, all blocks are returned as a single group.
+func newCodeByFunction(tree sourceTree, fileName string, blocks []NewCodeBlock) []functionBlocks {
+	var extents []funcExtent
+	if path, ok := tree.find(fileName); ok {
+		extents, _ = NewStatementLineMapper().getFunctionExtents(path)
+	}
+
+	var groups []functionBlocks
+	index := make(map[int]int) // by the line of the function
+	for _, b := range blocks {
+		var name string
+		var line int
+		for _, e := range extents {
+			if !e.closure && e.contains(b.profileBlock()) {
+				name, line = e.name, e.startLine
+				break
+			}
+		}
+
+		i, ok := index[line]
+		if !ok {
+			i = len(groups)
+			index[line] = i
+			groups = append(groups, functionBlocks{name: name, line: line})
+		}
+		groups[i].blocks = append(groups[i].blocks, b)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].line < groups[j].line })
+
+	return groups
+}
+
+// coverageSummary returns the coverage and its change for the <summary> of a
+// drilldown section, e.g. "87.50% (<b>-12.50%</b>) :skull:".
+func (r *Report) coverageSummary(percent float64, diffStr, emoji string) string {
+	return strings.TrimSpace(fmt.Sprintf("%s (%s) %s", r.numbers().Percent(percent), summaryHTML(diffStr), summaryHTML(emoji)))
+}
+
+// newCodeSummary returns the coverage of new code for the <summary> of a
+// drilldown section, or an empty string if there is no new code.
+func (r *Report) newCodeSummary(totalNew, coveredNew int64) string {
+	if totalNew == 0 {
+		return ""
+	}
+
+	n := r.numbers()
+	return fmt.Sprintf(" · new code %s (%s/%s statements)", n.Percent(float64(coveredNew)/float64(totalNew)*100), n.Count(coveredNew), n.Count(totalNew))
+}
+
+// countOf returns the count with the given noun in singular or plural.
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// summaryHTML converts the Markdown emphasis of the themes (e.g. "**-9.80%**")
+// to HTML, since GitHub does not render Markdown inside of <summary> elements.
+func summaryHTML(s string) string {
+	parts := strings.Split(s, "**")
+	if len(parts)%2 == 0 {
+		return s // unbalanced emphasis
+	}
+
+	var result strings.Builder
+	for i, part := range parts {
+		if i%2 == 1 {
+			part = "<b>" + part + "</b>"
+		}
+		result.WriteString(part)
+	}
+
+	return strings.TrimSpace(result.String())
+}
@@ -0,0 +1,115 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Drilldown(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	report.Layout = layoutDrilldown
+
+	actual := report.Analyze().Markdown()
+	assert.NotContains(t, actual, "<summary>Impacted Packages</summary>")
+	assert.NotContains(t, actual, "<summary>Coverage by file</summary>")
+	assert.NotContains(t, actual, "<summary>New Code Coverage Details</summary>")
+
+	expected := "<summary>Coverage by Package (1 package, 1 file)</summary>\n" + `
+Expand a package to see its changed files and a file to see the new code of its functions. Coverage values refer to ***code statements***, the value in brackets is the change since the old version of the code.
+
+<details>
+
+<summary><b>github.com/pentohq/pento/pkg/age</b> · 87.50% (<b>-12.50%</b>) :skull: · 1 file · new code 54.55% (6/11 statements)</summary>
+
+<details>
+
+<summary>age.go · 87.50% (<b>-12.50%</b>) :skull: · 24 (+9) statements, 3 (+3) missed · new code 54.55% (6/11 statements)</summary>
+
+` + "**`Years`** · 3/3 new lines covered" + `
+
+` + "```diff" + `
++ 	// Random stuff that should not get merged
++ 	days := a.Days()
++ 	slog.Error("days", "days", days)
+` + "```" + `
+
+` + "**`Days`** · 5/10 new lines covered" + `
+
+` + "```diff" + `
++ func (a Age) Days() int {
++ 	daysSinceBirth := a.Now.DaysSince(a.BirthDate)
++ 	if daysSinceBirth < 0 {
+- 		return 0
+- 	}
+- 	if daysSinceBirth > 100000 {
+- 		return daysSinceBirth
+- 	}
++ 	daysInYears := 1 * 365
++ 	return daysSinceBirth - daysInYears
+` + "```" + `
+
+</details>
+
+</details>
+
+</details>
+`
+	assert.Contains(t, actual, expected)
+}
+
+func TestReport_Drilldown_WithoutSourceCode(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.Layout = layoutDrilldown
+	actual := report.Analyze().Markdown()
+
+	// Without source code, the blocks of a file are listed without functions.
+	assert.Contains(t, actual, "<summary>Coverage by Package (2 packages, 2 files)</summary>")
+	assert.Contains(t, actual, "<summary>min_heap.go · 80.77% (<b>-19.23%</b>) :skull: · 52 (+2) statements, 10 (+10) missed · new code 85.71% (42/49 statements)</summary>\n\n```diff\n- Line 48 (1 statement) - NOT COVERED ✗\n")
+	assert.NotContains(t, actual, "new lines covered")
+}
+
+func TestReport_Drilldown_Auto(t *testing.T) {
+	report := &Report{Layout: layoutAuto, ChangedFiles: make([]string, drilldownMinFiles-1)}
+	assert.False(t, report.drilldown())
+
+	report.ChangedFiles = append(report.ChangedFiles, "a.go")
+	assert.True(t, report.drilldown())
+
+	report.Layout = ""
+	assert.False(t, report.drilldown())
+}
+
+func TestValidateLayout(t *testing.T) {
+	for _, layout := range []string{"", layoutFlat, layoutDrilldown, layoutAuto} {
+		assert.NoError(t, validateLayout(layout), layout)
+	}
+	assert.EqualError(t, validateLayout("tree"), `unsupported layout: "tree" (supported layouts: flat, drilldown and auto)`)
+}
+
+func TestSummaryHTML(t *testing.T) {
+	assert.Equal(t, "<b>-9.80%</b>", summaryHTML("**-9.80%**"))
+	assert.Equal(t, ":skull: :skull:", summaryHTML(":skull: :skull: "))
+	assert.Equal(t, "**open", summaryHTML("**open"))
+	assert.Equal(t, "ø", summaryHTML("ø"))
+	assert.Equal(t, "1 file, 2 files", strings.Join([]string{countOf(1, "file"), countOf(2, "file")}, ", "))
+}
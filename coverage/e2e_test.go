@@ -0,0 +1,309 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFixtureRepo creates a git repository for the age package of the
+// testdata/04 inputs. The main branch contains the old version of the package
+// and HEAD the new one (see testdata/04-diff.patch). The remote tracking
+// branch origin/main points to main as if it had been fetched. It returns the
+// directory of the repository and a function to run git in it.
+func newFixtureRepo(t *testing.T) (dir string, git func(ctx context.Context, args ...string) ([]byte, error)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir = t.TempDir()
+	git = func(ctx context.Context, args ...string) ([]byte, error) {
+		args = append([]string{"-C", dir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+		return runGit(ctx, args...)
+	}
+	mustGit := func(args ...string) {
+		_, err := git(context.Background(), args...)
+		require.NoError(t, err)
+	}
+
+	src, err := os.ReadFile("testdata/github.com/pentohq/pento/pkg/age/age.go")
+	require.NoError(t, err)
+	patch, err := filepath.Abs("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg", "age"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "age", "age.go"), src, 0644))
+
+	mustGit("init", "--quiet", "--initial-branch=main")
+	mustGit("apply", "--reverse", patch)
+	mustGit("add", "--all")
+	mustGit("commit", "--quiet", "--message=Add age package")
+	mustGit("update-ref", "refs/remotes/origin/main", "main")
+	mustGit("checkout", "--quiet", "-b", "feature")
+	mustGit("apply", patch)
+	mustGit("commit", "--quiet", "--all", "--message=Log invalid ages")
+
+	return dir, git
+}
+
+// newEndToEndAction returns an action for the fake that works on a fixture
+// repository like in a real workflow.
+func newEndToEndAction(t *testing.T, gh *fakeGitHub) (*action, *bytes.Buffer) {
+	t.Helper()
+
+	a, out := newTestAction(t, gh)
+	a.opts.repoRoot, a.git = newFixtureRepo(t)
+	a.cfg.UseGitDiff = true
+
+	return a, out
+}
+
+func TestEndToEnd_CommentUpsert(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, out := newEndToEndAction(t, gh)
+
+	// The report must be the same as the one of the main command with the
+	// diff of the testdata.
+	opts := a.opts
+	opts.diffFile = "testdata/04-diff.patch"
+	expected, err := loadReport(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts)
+	require.NoError(t, err)
+
+	body, _ := withReportDigest(expected.Analyze().Markdown())
+	for run := 1; run <= 2; run++ {
+		require.NoError(t, a.run(context.Background()), "run %d", run)
+
+		require.Len(t, gh.comments, 2, "run %d", run)
+		assert.Equal(t, "alice", gh.comments[0].User.Login)
+		assert.Equal(t, fakeBotLogin, gh.comments[1].User.Login)
+		assert.Equal(t, body, gh.comments[1].Body, "run %d", run)
+	}
+
+	assert.Contains(t, out.String(), "Git diff generated successfully")
+	assert.Contains(t, out.String(), "Replacing old coverage report comment")
+
+	// The unchanged report of the second run is not posted again.
+	assert.Contains(t, out.String(), "Coverage report is unchanged, keeping the existing comment")
+	assert.Len(t, gh.requests, 2, "only the first run deletes and creates a comment")
+}
+
+func TestEndToEnd_CommentUpsert_Drilldown(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, out := newEndToEndAction(t, gh)
+	a.opts.layout = layoutDrilldown
+
+	for run := 1; run <= 2; run++ {
+		require.NoError(t, a.run(context.Background()), "run %d", run)
+
+		require.Len(t, gh.comments, 2, "run %d", run)
+		assert.Equal(t, "alice", gh.comments[0].User.Login)
+		assert.Contains(t, gh.comments[1].Body, "<summary>Coverage by Package (1 package, 1 file)</summary>", "run %d", run)
+	}
+
+	// The drilldown layout has no "Coverage Δ" column, so the report of the
+	// first run is only found via its marker.
+	assert.NotContains(t, gh.comments[1].Body, "Coverage Δ")
+	assert.Contains(t, gh.comments[1].Body, reportMarker)
+	assert.Contains(t, out.String(), "Coverage report is unchanged, keeping the existing comment")
+	assert.Len(t, gh.requests, 2, "only the first run deletes and creates a comment")
+}
+
+func TestEndToEnd_DescriptionUpsert(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, _ := newEndToEndAction(t, gh)
+	a.cfg.CommentMode = "description"
+
+	for run := 1; run <= 2; run++ {
+		require.NoError(t, a.run(context.Background()), "run %d", run)
+
+		assert.True(t, strings.HasPrefix(gh.body, "Fixes a bug\n\n"+descriptionStartMarker), gh.body)
+		assert.Equal(t, 1, strings.Count(gh.body, descriptionStartMarker), "run %d", run)
+		assert.Contains(t, gh.body, "### Coverage Report")
+	}
+
+	// The comments of the pull request are not touched and the unchanged
+	// report of the second run is not written again.
+	assert.Len(t, gh.comments, 2)
+	assert.Len(t, gh.requests, 1)
+}
+
+func TestEndToEnd_Labels(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.labels = []string{"bug"}
+	a, _ := newEndToEndAction(t, gh)
+	a.cfg.PassingLabel = "coverage/passing"
+	a.cfg.FailingLabel = "coverage/failing"
+
+	require.NoError(t, a.run(context.Background()))
+	assert.Equal(t, []string{"bug", "coverage/passing"}, gh.labels)
+
+	a.opts.minCoverage = 100
+	assert.Error(t, a.run(context.Background()))
+	assert.Equal(t, []string{"bug", "coverage/failing"}, gh.labels)
+	assert.Equal(t, map[string]bool{"coverage/passing": true, "coverage/failing": true}, gh.repoLabels)
+}
+
+func TestEndToEnd_EscalationCheck(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, _ := newEndToEndAction(t, gh)
+	a.cfg.SkipComment = true
+	a.cfg.EscalationTeam = "example/coverage-owners"
+	a.cfg.EscalationThreshold = 1
+
+	deployment := &deploymentProtectionRuleEvent{Environment: "production"}
+	deployment.Deployment.SHA = "def456"
+	deployment.CallbackURL = a.gh.baseURL + "/repos/example/repo/actions/runs/3/deployment_protection_rule"
+
+	// The coverage dropped by 12.5 percentage points.
+	require.NoError(t, a.run(context.Background()))
+	require.Len(t, gh.checkRuns, 1)
+	assert.Equal(t, escalationCheckName, gh.checkRuns[0].Name)
+	assert.Equal(t, "def456", gh.checkRuns[0].HeadSHA)
+	assert.Equal(t, "neutral", gh.checkRuns[0].Conclusion)
+	assert.Equal(t, "Waiting for approval by @example/coverage-owners", gh.checkRuns[0].Output.Title)
+
+	require.NoError(t, reviewDeployment(context.Background(), a.gh, deployment, escalationCheckName, &bytes.Buffer{}))
+	assert.Equal(t, "rejected", gh.deployments[0]["state"])
+
+	// A member of the team approves the pull request.
+	gh.reviews = append(gh.reviews, fakeReview{State: "APPROVED", User: githubUser{Login: "carol"}})
+
+	require.NoError(t, a.run(context.Background()))
+	require.Len(t, gh.checkRuns, 2)
+	assert.Equal(t, "success", gh.checkRuns[1].Conclusion)
+	assert.Equal(t, "Approved by @carol", gh.checkRuns[1].Output.Title)
+
+	require.NoError(t, reviewDeployment(context.Background(), a.gh, deployment, escalationCheckName, &bytes.Buffer{}))
+	assert.Equal(t, "approved", gh.deployments[1]["state"])
+}
+
+func TestEndToEnd_ArtifactFetch(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, _ := newEndToEndAction(t, gh)
+	a.cfg.PackageCovName = "package-coverage.txt"
+	a.cfg.TestJSONName = "test.json"
+	a.cfg.TestFileCovNames = "pkg/age/age_test.go=age-test-coverage.txt"
+
+	testJSON := []byte(`{"Action":"skip","Package":"github.com/pentohq/pento/pkg/age","Test":"TestAge"}` + "\n")
+	gh.setArtifact(2, "package-coverage.txt", gh.artifacts[2]["coverage.txt"])
+	gh.setArtifact(2, "test.json", testJSON)
+	gh.setArtifact(2, "age-test-coverage.txt", gh.artifacts[2]["coverage.txt"])
+
+	require.NoError(t, a.run(context.Background()))
+
+	for name, content := range map[string][]byte{
+		"old-coverage.txt":         gh.artifacts[1]["coverage.txt"],
+		"new-coverage.txt":         gh.artifacts[2]["coverage.txt"],
+		"package-coverage.txt":     gh.artifacts[2]["coverage.txt"],
+		"test-output.json":         testJSON,
+		"test-file-coverage-0.txt": gh.artifacts[2]["coverage.txt"],
+	} {
+		data, err := os.ReadFile(filepath.Join(a.cfg.OutputDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, string(content), string(data), name)
+	}
+	assert.Contains(t, gh.comments[1].Body, "TestAge")
+
+	// The current run did not upload the test output.
+	delete(gh.artifacts[2], "test.json")
+	err := a.run(context.Background())
+	assert.ErrorContains(t, err, `artifact "code-coverage" of run 2 does not contain test.json`)
+}
+
+func TestEndToEnd_Push(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, out := newEndToEndAction(t, gh)
+	a.cfg.PullRequest = 0
+	a.cfg.PushBefore, a.cfg.PushAfter = "abc123", "def456"
+	a.cfg.StatusContext = "coverage"
+	a.cfg.PassingLabel = "coverage/passing"
+
+	// The fixture repository has no commit abc123, so the diff falls back to
+	// the block-based comparison.
+	for run := 1; run <= 2; run++ {
+		require.NoError(t, a.run(context.Background()), "run %d", run)
+
+		require.Len(t, gh.commitComments["def456"], 1, "run %d", run)
+		assert.True(t, strings.HasPrefix(gh.commitComments["def456"][0].Body, "### Coverage Report"))
+	}
+	assert.Contains(t, out.String(), "falling back to block-based comparison")
+
+	require.Len(t, gh.statuses, 2)
+	assert.Equal(t, fakeStatus{SHA: "def456", State: "success", Context: "coverage", Description: "Coverage 87.50% (-12.50%), new code 86.36%"}, gh.statuses[1])
+
+	// Pull requests are not touched.
+	assert.Len(t, gh.comments, 2)
+	assert.Empty(t, gh.labels)
+}
+
+func TestEndToEnd_DebtIssue(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, out := newEndToEndAction(t, gh)
+	a.cfg.PullRequest = 0
+	a.cfg.PushBefore, a.cfg.PushAfter = "abc123", "def456"
+	a.cfg.DebtIssue, a.cfg.DebtLabel = "Uncovered code", "coverage-debt"
+	a.cfg.ServerURL = "https://github.example.com"
+
+	codeOwners := "* @example/maintainers\n/pkg/age/ @alice @example/age-team\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(a.opts.repoRoot, ".github"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(a.opts.repoRoot, ".github", "CODEOWNERS"), []byte(codeOwners), 0644))
+
+	for run := 1; run <= 2; run++ {
+		require.NoError(t, a.run(context.Background()), "run %d", run)
+	}
+	assert.Contains(t, out.String(), "Commit def456 is already recorded in issue #1")
+
+	require.Len(t, gh.issues, 1)
+	issue := gh.issues[0]
+	assert.Equal(t, "Uncovered code", issue.Title)
+	assert.Equal(t, []string{"coverage-debt"}, issue.Labels)
+	assert.Equal(t, []string{"alice"}, issue.Assignees)
+	assert.True(t, strings.HasSuffix(issue.Body, "\n\n<!-- go-coverage-report:debt:def456 -->\n"+
+		"### def456 (3 uncovered statements)\n\n"+
+		"- [ ] [pkg/age/age.go#L55-L57](https://github.example.com/example/repo/blob/def456/pkg/age/age.go#L55-L57) (1 statement) @alice @example/age-team\n"+
+		"- [ ] [pkg/age/age.go#L58](https://github.example.com/example/repo/blob/def456/pkg/age/age.go#L58) (1 statement) @alice @example/age-team\n"+
+		"- [ ] [pkg/age/age.go#L58-L60](https://github.example.com/example/repo/blob/def456/pkg/age/age.go#L58-L60) (1 statement) @alice @example/age-team\n"), issue.Body)
+
+	// The uncovered code of later pushes is added to the open issue.
+	gh.issues[0].Body = "Earlier debt\n"
+	require.NoError(t, a.run(context.Background()))
+	require.Len(t, gh.issues, 1)
+	assert.True(t, strings.HasPrefix(gh.issues[0].Body, "Earlier debt\n\n<!-- go-coverage-report:debt:def456 -->\n"), gh.issues[0].Body)
+	assert.Equal(t, []string{"alice", "alice"}, gh.issues[0].Assignees)
+}
+
+func TestEndToEnd_FetchSource(t *testing.T) {
+	gh := newFakeGitHub(t)
+	a, out := newEndToEndAction(t, gh)
+
+	opts := a.opts
+	opts.diffFile = "testdata/04-diff.patch"
+	expected, err := loadReport(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts)
+	require.NoError(t, err)
+	body, _ := withReportDigest(expected.Analyze().Markdown())
+
+	// The source files are fetched into a temporary directory.
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	// The head of a pull request from a fork is not checked out in the
+	// workspace, so the source code is fetched via the API.
+	require.NoError(t, os.Remove(filepath.Join(a.opts.repoRoot, "pkg", "age", "age.go")))
+	a.cfg.FetchSource = true
+
+	require.NoError(t, a.run(context.Background()))
+	assert.Equal(t, body, gh.comments[len(gh.comments)-1].Body)
+	assert.Contains(t, body, "+ \tdaysInYears := 1 * 365")
+	fetched, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, fetched, "the fetched files are removed")
+	assert.NotContains(t, out.String(), "::warning::")
+}
@@ -0,0 +1,83 @@
+package coverage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The review effort levels of a pull request (see ReviewEffort).
+const (
+	effortSmall  = "small"
+	effortMedium = "medium"
+	effortLarge  = "large"
+)
+
+// ReviewEffort is a rough estimate of how much scrutiny a pull request needs,
+// based on the amount of new code and how much of it is not covered by tests.
+type ReviewEffort struct {
+	Level        string // small, medium or large
+	NewStmt      int64
+	UncoveredNew int64 // new statements that are not covered
+	FallingFiles int   // changed files whose coverage decreased
+}
+
+// ReviewEffort estimates the review effort of the pull request. Uncovered
+// new statements weigh twice as much as covered ones, since nothing but the
+// reviewer checks them, and each changed file with falling coverage weighs as
+// much as 10 new statements.
+func (r *Result) ReviewEffort() ReviewEffort {
+	totalNew, coveredNew := r.calculateNewCodeCoverage()
+	e := ReviewEffort{NewStmt: totalNew, UncoveredNew: totalNew - coveredNew}
+	for _, name := range r.ChangedFiles {
+		oldProfile, newProfile := r.Old.Files[name], r.New.Files[name]
+		if oldProfile != nil && newProfile != nil && newProfile.CoveragePercent() < oldProfile.CoveragePercent() {
+			e.FallingFiles++
+		}
+	}
+
+	score := e.NewStmt + e.UncoveredNew + 10*int64(e.FallingFiles)
+	switch {
+	case score < 50:
+		e.Level = effortSmall
+	case score < 250:
+		e.Level = effortMedium
+	default:
+		e.Level = effortLarge
+	}
+
+	return e
+}
+
+// addReviewEffort adds a line with the estimated review effort if
+// ReviewEffortEstimate is set, so reviewers get a sense of the pull request
+// before they open the diff.
+func (r *Result) addReviewEffort(report *strings.Builder) {
+	if !r.ReviewEffortEstimate {
+		return
+	}
+
+	e := r.ReviewEffort()
+	n := r.numbers()
+
+	newCode := "no new statements"
+	if e.NewStmt > 0 {
+		newCode = fmt.Sprintf("%s new %s, %s uncovered", n.Count(e.NewStmt), plural(e.NewStmt, "statement"), n.Count(e.UncoveredNew))
+	}
+
+	falling := "no changed files with falling coverage"
+	if e.FallingFiles > 0 {
+		falling = fmt.Sprintf("%s changed %s with falling coverage", n.Count(int64(e.FallingFiles)), plural(int64(e.FallingFiles), "file"))
+	}
+
+	fmt.Fprintf(report, "**Review effort: %s** · %s · %s\n", e.Level, newCode, falling)
+	fmt.Fprintln(report)
+}
+
+// plural returns the noun in plural unless n is 1.
+func plural(n int64, noun string) string {
+	if n == 1 {
+		return noun
+	}
+
+	return noun + "s"
+}
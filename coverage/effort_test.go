@@ -0,0 +1,57 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_ReviewEffort(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	assert.NotContains(t, report.Analyze().Markdown(), "Review effort", "the estimate is opt-in")
+
+	report.ReviewEffortEstimate = true
+	assert.Equal(t, ReviewEffort{Level: effortSmall, NewStmt: 11, UncoveredNew: 5, FallingFiles: 1}, report.Analyze().ReviewEffort())
+	assert.Contains(t, report.Analyze().Markdown(), "**Review effort: small** · 11 new statements, 5 uncovered · 1 changed file with falling coverage\n")
+
+	report = NewReport(newCov, newCov, changedFiles)
+	report.ReviewEffortEstimate = true
+	assert.Contains(t, report.Analyze().Markdown(), "**Review effort: small** · no new statements · no changed files with falling coverage\n")
+}
+
+func TestReport_ReviewEffort_Levels(t *testing.T) {
+	oldCov := New([]*Profile{newTestProfile("pkg/a.go", ProfileBlock{StartLine: 1, EndLine: 2, NumStmt: 10, Count: 1})})
+
+	tests := map[string]struct {
+		stmt, covered int
+		level         string
+	}{
+		"small":  {stmt: 40, covered: 40, level: effortSmall},
+		"medium": {stmt: 100, covered: 50, level: effortMedium},
+		"large":  {stmt: 200, covered: 10, level: effortLarge},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			newCov := New([]*Profile{newTestProfile("pkg/a.go",
+				ProfileBlock{StartLine: 1, EndLine: 2, NumStmt: 10, Count: 1},
+				ProfileBlock{StartLine: 3, EndLine: 4, NumStmt: tt.covered - 10, Count: 1},
+				ProfileBlock{StartLine: 5, EndLine: 6, NumStmt: tt.stmt - tt.covered, Count: 0},
+			)})
+			report := NewReport(oldCov, newCov, []string{"pkg/a.go"})
+			assert.Equal(t, tt.level, report.Analyze().ReviewEffort().Level)
+		})
+	}
+}
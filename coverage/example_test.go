@@ -0,0 +1,82 @@
+package coverage_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/fgrosse/go-coverage-report/coverage"
+)
+
+func ExampleCompare() {
+	result, err := coverage.Compare(context.Background(),
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		coverage.Options{
+			Root:        "github.com/pentohq/pento",
+			DiffFile:    "testdata/04-diff.patch",
+			MinCoverage: 80,
+		},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer result.Close()
+
+	fmt.Printf("new code coverage: %d of %d statements\n", result.CoveredNewStmt, result.NewStmt)
+	fmt.Println("gate:", result.Err)
+	// Output:
+	// new code coverage: 6 of 11 statements
+	// gate: new code coverage 54.55% is below the required threshold of 80.00%
+}
+
+func ExampleResult_HTML() {
+	result, err := coverage.Compare(context.Background(),
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		coverage.Options{Root: "github.com/pentohq/pento"},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer result.Close()
+
+	html := result.HTML()
+	fmt.Println(html[:strings.Index(html, "\n")])
+	// Output:
+	// <!DOCTYPE html>
+}
+
+func ExamplePostComment() {
+	// A fake GitHub API without previous comments.
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println(r.Method, r.URL.Path)
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, "[]")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "{}")
+	}))
+	defer api.Close()
+
+	pr := coverage.PullRequest{
+		Repository: "pentohq/pento",
+		Number:     42,
+		Token:      "token",
+		APIURL:     api.URL,
+	}
+
+	err := coverage.PostComment(context.Background(), pr, "### Coverage Report\n")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// GET /repos/pentohq/pento/issues/42/comments
+	// POST /repos/pentohq/pento/issues/42/comments
+}
@@ -0,0 +1,216 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ExampleFunc is an Example function of a changed test file together with the
+// coverage of the code it documents.
+type ExampleFunc struct {
+	FileName string
+	Name     string // name of the function, e.g. "ExampleHeap_Push_sorted"
+	Line     int
+
+	// Documents is the documented identifier, e.g. "Heap.Push" for the
+	// method Push of type Heap, or empty for an example of the package.
+	Documents string
+
+	// Testable is true if the example has an output comment, so that it is
+	// executed by go test and contributes to the coverage.
+	Testable bool
+
+	// Found is false if the documented code is not part of the coverage.
+	Found                  bool
+	TotalStmt, CoveredStmt int64
+}
+
+// Percent returns the percentage of covered statements of the documented code.
+func (e ExampleFunc) Percent() float64 {
+	if e.TotalStmt == 0 {
+		return 0
+	}
+
+	return float64(e.CoveredStmt) / float64(e.TotalStmt) * 100
+}
+
+// Examples returns the Example functions of all changed test files whose
+// source code can be found locally. Functions that do not follow the naming
+// convention of examples (see go doc testing) are ignored.
+func (r *Report) Examples() []ExampleFunc {
+	var examples []ExampleFunc
+	for _, fileName := range r.ChangedFiles {
+		if !strings.HasSuffix(fileName, "_test.go") {
+			continue
+		}
+
+		sourcePath, ok := r.source.find(fileName)
+		if !ok {
+			continue
+		}
+		src, err := os.ReadFile(sourcePath)
+		if err != nil {
+			continue
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, sourcePath, src, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+
+		pkg := path.Dir(fileName)
+		pkgCov := r.New.Filter(func(name string) bool { return path.Dir(name) == pkg })
+		funcs := qualifiedFunctions(r.source, pkgCov)
+
+		var fileExamples []ExampleFunc
+		for _, ex := range doc.Examples(file) {
+			e := ExampleFunc{
+				FileName:  fileName,
+				Name:      "Example" + ex.Name,
+				Line:      fset.Position(ex.Code.Pos()).Line,
+				Documents: exampleIdentifier(ex.Name),
+				Testable:  ex.Output != "" || ex.EmptyOutput,
+			}
+			e.documentedCoverage(pkgCov, funcs)
+			fileExamples = append(fileExamples, e)
+		}
+
+		// The examples are sorted by name, but the order of the source code
+		// is easier to follow.
+		sort.Slice(fileExamples, func(i, j int) bool {
+			return fileExamples[i].Line < fileExamples[j].Line
+		})
+		examples = append(examples, fileExamples...)
+	}
+
+	return examples
+}
+
+// exampleIdentifier returns the identifier that is documented by an example
+// with the given name without the "Example" prefix (e.g. "Heap.Push" for
+// "Heap_Push_sorted"). Suffixes start with a lower-case letter. The
+// identifier of package examples is empty.
+func exampleIdentifier(name string) string {
+	if name == "" || name[0] == '_' {
+		return ""
+	}
+
+	parts := strings.Split(name, "_")
+	if last := parts[len(parts)-1]; len(parts) > 1 && last != "" && unicode.IsLower(rune(last[0])) {
+		parts = parts[:len(parts)-1]
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// qualifiedFunctions returns the coverage of all functions of the coverage
+// whose source code can be found locally. Unlike Functions, the names of
+// methods include their receiver type (e.g. "(*Heap).Push").
+func qualifiedFunctions(tree sourceTree, cov *Coverage) []FunctionCoverage {
+	m := NewStatementLineMapper()
+
+	var funcs []FunctionCoverage
+	for _, fileName := range sortedKeys(cov.Files) {
+		sourcePath, ok := tree.find(fileName)
+		if !ok {
+			continue
+		}
+
+		file, err := parser.ParseFile(m.fset, sourcePath, nil, 0)
+		if err != nil {
+			continue
+		}
+
+		var extents []funcExtent
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				extents = append(extents, m.extent(qualifiedFuncName(fn), false, fn))
+			}
+		}
+		funcs = append(funcs, functionCoverage(fileName, cov.Files[fileName], extents)...)
+	}
+
+	return funcs
+}
+
+// documentedCoverage sets the coverage of the code that the example documents:
+// the function, all methods of the type, the method, or the whole package.
+func (e *ExampleFunc) documentedCoverage(pkgCov *Coverage, funcs []FunctionCoverage) {
+	if e.Documents == "" {
+		e.Found = pkgCov.TotalStmt > 0
+		e.TotalStmt, e.CoveredStmt = pkgCov.TotalStmt, pkgCov.CoveredStmt
+		return
+	}
+
+	typ, method, isMethod := strings.Cut(e.Documents, ".")
+	for _, f := range funcs {
+		if strings.HasSuffix(f.FileName, "_test.go") {
+			continue
+		}
+
+		recv, name, ok := strings.Cut(f.Name, ".")
+		recv = strings.TrimSuffix(strings.TrimPrefix(recv, "(*"), ")")
+		recv = strings.TrimSuffix(recv, "[...]")
+
+		var match bool
+		switch {
+		case isMethod:
+			match = ok && recv == typ && name == method
+		case ok:
+			match = recv == typ // all methods of the type
+		default:
+			match = f.Name == e.Documents
+		}
+
+		if match {
+			e.Found = true
+			e.TotalStmt += f.TotalStmt
+			e.CoveredStmt += f.CoveredStmt
+		}
+	}
+}
+
+func (r *Report) addExampleDetails(report *strings.Builder) {
+	examples := r.Examples()
+	if len(examples) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, "#### Examples")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "Only examples with an output comment are run by `go test` and contribute to the coverage of the code they document.")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| Example | Documents | Runs in Tests | Coverage of Documented Code |")
+	fmt.Fprintln(report, "|---------|-----------|---------------|-----------------------------|")
+
+	for _, e := range examples {
+		documents := e.Documents
+		if documents == "" {
+			documents = "package " + path.Base(path.Dir(e.FileName))
+		}
+
+		runs := r.theme().passed
+		if !e.Testable {
+			runs = r.theme().failed + " (no output comment)"
+		}
+
+		coverage := "N/A"
+		if e.Found {
+			n := r.numbers()
+			coverage = fmt.Sprintf("%s (%s/%s statements)", n.Percent(e.Percent()), n.Count(int64(e.CoveredStmt)), n.Count(int64(e.TotalStmt)))
+		}
+
+		fmt.Fprintf(report, "| %s (%s:%d) | %s | %s | %s |\n", e.Name, path.Base(e.FileName), e.Line, documents, runs, coverage)
+	}
+
+	fmt.Fprintln(report)
+}
@@ -0,0 +1,129 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const examplesTestSource = `package heap_test
+
+import "example.com/app/heap"
+
+func ExampleNew() {
+	h := heap.New()
+	_ = h
+	// Output:
+}
+
+func ExampleHeap_Push() {
+	h := heap.New()
+	h.Push(1)
+}
+
+func ExampleHeap_sorted() {
+	// Unordered output: 1
+}
+
+func Example_usage() {
+	// Output: usage
+}
+
+func ExampleMissing() {}
+
+func TestHeap(t *testing.T) {}
+`
+
+func TestReport_Examples(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "heap")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "heap.go"), []byte(`package heap
+
+type Heap struct{ items []int }
+
+func New() *Heap {
+	return &Heap{}
+}
+
+func (h *Heap) Push(x int) {
+	h.items = append(h.items, x)
+	h.up(len(h.items) - 1)
+}
+
+func (h *Heap) up(i int) {
+	_ = i
+}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "heap_test.go"), []byte(examplesTestSource), 0644))
+
+	newCov := New([]*Profile{newTestProfile("example.com/app/heap/heap.go",
+		ProfileBlock{StartLine: 5, StartCol: 18, EndLine: 7, EndCol: 2, NumStmt: 1, Count: 1},
+		ProfileBlock{StartLine: 9, StartCol: 28, EndLine: 12, EndCol: 2, NumStmt: 2, Count: 0},
+		ProfileBlock{StartLine: 14, StartCol: 26, EndLine: 16, EndCol: 2, NumStmt: 1, Count: 1},
+	)})
+	report := NewReport(newCov, newCov, []string{"example.com/app/heap/heap_test.go"})
+	report.source = sourceTree{root: root}
+
+	examples := report.Examples()
+	require.Len(t, examples, 5)
+
+	assert.Equal(t, ExampleFunc{
+		FileName:    "example.com/app/heap/heap_test.go",
+		Name:        "ExampleNew",
+		Line:        5,
+		Documents:   "New",
+		Testable:    true,
+		Found:       true,
+		TotalStmt:   1,
+		CoveredStmt: 1,
+	}, examples[0])
+
+	assert.Equal(t, "Heap.Push", examples[1].Documents)
+	assert.False(t, examples[1].Testable)
+	assert.EqualValues(t, 2, examples[1].TotalStmt)
+	assert.EqualValues(t, 0, examples[1].CoveredStmt)
+
+	assert.Equal(t, "Heap", examples[2].Documents, "all methods of the type")
+	assert.True(t, examples[2].Testable)
+	assert.EqualValues(t, 3, examples[2].TotalStmt)
+
+	assert.Equal(t, "Example_usage", examples[3].Name)
+	assert.Equal(t, "", examples[3].Documents)
+	assert.EqualValues(t, 4, examples[3].TotalStmt)
+
+	assert.False(t, examples[4].Found)
+
+	actual := report.Analyze().Markdown()
+	assert.Contains(t, actual, strings.Join([]string{
+		"#### Examples",
+		"",
+		"Only examples with an output comment are run by `go test` and contribute to the coverage of the code they document.",
+		"",
+		"| Example | Documents | Runs in Tests | Coverage of Documented Code |",
+		"|---------|-----------|---------------|-----------------------------|",
+		"| ExampleNew (heap_test.go:5) | New | :white_check_mark: | 100.00% (1/1 statements) |",
+		"| ExampleHeap_Push (heap_test.go:11) | Heap.Push | :x: (no output comment) | 0.00% (0/2 statements) |",
+		"| ExampleHeap_sorted (heap_test.go:16) | Heap | :white_check_mark: | 33.33% (1/3 statements) |",
+		"| Example_usage (heap_test.go:20) | package heap | :white_check_mark: | 50.00% (2/4 statements) |",
+		"| ExampleMissing (heap_test.go:24) | Missing | :x: (no output comment) | N/A |",
+	}, "\n"))
+}
+
+func TestExampleIdentifier(t *testing.T) {
+	for name, expected := range map[string]string{
+		"":                 "",
+		"_second":          "",
+		"New":              "New",
+		"New_empty":        "New",
+		"Heap":             "Heap",
+		"Heap_Push":        "Heap.Push",
+		"Heap_Push_sorted": "Heap.Push",
+	} {
+		assert.Equal(t, expected, exampleIdentifier(name), name)
+	}
+}
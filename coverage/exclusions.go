@@ -0,0 +1,70 @@
+package coverage
+
+import (
+	"os"
+)
+
+// excludedRegion is a range of lines that should be excluded from the
+// coverage calculation for the given reason. If Column is set, only the
+// statement at StartLine and Column is excluded.
+type excludedRegion struct {
+	LineRange
+	Column int
+	Reason string
+}
+
+// exclusionFinder detects regions of a Go source file that should be excluded
+// from the coverage calculation.
+type exclusionFinder func(path string, src []byte) ([]excludedRegion, error)
+
+// exclusionFinders returns all exclusion finders that are enabled via the
+// given options. Coverage directives and waivers are always enabled.
+func exclusionFinders(opts options) []exclusionFinder {
+	finders := []exclusionFinder{coverageDirectiveExclusions, coverageWaivers}
+	if opts.excludeWiring {
+		finders = append(finders, wiringRegions)
+	}
+
+	return finders
+}
+
+// applySourceExclusions reads the source code of all files of the coverage
+// profile (except those in skip) from the tree and excludes the regions
+// returned by find. Files whose source code cannot be found or cannot be
+// parsed are ignored, since the coverage paths may not be resolvable in every
+// setup. The progress of reading the files is reported to p, which may be nil.
+func applySourceExclusions(tree sourceTree, cov *Coverage, skip map[string]bool, find exclusionFinder, p *progress) error {
+	done := 0
+	for fileName := range cov.Files {
+		done++
+		p.files(done, len(cov.Files))
+		if skip[fileName] {
+			continue
+		}
+
+		path, ok := tree.find(fileName)
+		if !ok {
+			continue
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		regions, err := find(path, src)
+		if err != nil {
+			continue
+		}
+
+		for _, r := range regions {
+			if r.Column > 0 {
+				cov.ExcludeStatement(fileName, r.StartLine, r.Column, r.Reason)
+				continue
+			}
+			cov.Exclude(fileName, r.StartLine, r.EndLine, r.Reason)
+		}
+	}
+
+	return nil
+}
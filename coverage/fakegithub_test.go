@@ -0,0 +1,372 @@
+package coverage
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub is an in-memory fake of the parts of the GitHub API that are
+// used by the action for the repository "example/repo" with the pull request
+// 42 (head commit def456). It keeps the state of comments, labels, check
+// runs, commit statuses, issues and deployment reviews, so that end-to-end tests can
+// run the action multiple times and check the result. All non-GET requests
+// are recorded.
+//
+// Workflow "CI" (ID 7) has a successful run 1 for commit abc123 on the main
+// branch. The artifacts of all runs are named "code-coverage".
+type fakeGitHub struct {
+	t *testing.T
+
+	mu             sync.Mutex
+	workflows      []fakeWorkflow
+	artifacts      map[int64]map[string][]byte // run ID -> file name -> content
+	files          []fakeFile                  // files of the pull request and the push abc123...def456
+	labels         []string                    // labels of the pull request
+	repoLabels     map[string]bool
+	body           string          // description of the pull request
+	comments       []githubComment // comments of the pull request
+	commitComments map[string][]githubComment
+	reviews        []fakeReview
+	teamMembers    map[string]bool // active members of the team example/coverage-owners
+	checkRuns      []fakeCheckRun
+	statuses       []fakeStatus
+	deployments    []map[string]string // reviews of deployment protection rules
+	issues         []fakeIssue         // issues other than the pull request
+	nextID         int64
+	requests       []string       // "METHOD path body" of all non-GET requests
+	failures       map[string]int // "METHOD path" -> status code of requests that fail
+}
+
+type fakeWorkflow struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type fakeFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+type fakeReview struct {
+	State string     `json:"state"`
+	User  githubUser `json:"user"`
+}
+
+type fakeCheckRun struct {
+	Name       string `json:"name"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	Output     struct {
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+	} `json:"output"`
+}
+
+type fakeIssue struct {
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	Labels    []string `json:"-"`
+	Assignees []string `json:"-"`
+}
+
+type fakeStatus struct {
+	SHA         string `json:"-"`
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url"`
+}
+
+// fakeBotLogin is the user of all comments that are created via the fake.
+const fakeBotLogin = "github-actions[bot]"
+
+// newFakeGitHub returns a fake with a pull request that has a comment of a
+// user and an outdated coverage report of a previous run.
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	return &fakeGitHub{
+		t:         t,
+		workflows: []fakeWorkflow{{ID: 7, Name: "CI"}},
+		artifacts: map[int64]map[string][]byte{},
+		files: []fakeFile{
+			{Filename: "pkg/age/age.go", Status: "modified"},
+			{Filename: "pkg/age/old.go", Status: "removed"},
+			{Filename: "vendor/example.com/lib/lib.go", Status: "modified"},
+			{Filename: "README.md", Status: "modified"},
+		},
+		repoLabels: map[string]bool{},
+		body:       "Fixes a bug",
+		comments: []githubComment{
+			{ID: 1, Body: "LGTM", User: githubUser{Login: "alice"}},
+			{ID: 2, Body: "| Coverage Δ |", User: githubUser{Login: fakeBotLogin}},
+		},
+		commitComments: map[string][]githubComment{},
+		reviews: []fakeReview{
+			{State: "APPROVED", User: githubUser{Login: "bob"}},
+			{State: "COMMENTED", User: githubUser{Login: "carol"}},
+		},
+		teamMembers: map[string]bool{"carol": true},
+		nextID:      100,
+	}
+}
+
+// start starts a server for the fake and returns a client for it.
+func (f *fakeGitHub) start() *githubClient {
+	srv := httptest.NewServer(f)
+	f.t.Cleanup(srv.Close)
+
+	return newGitHubClient(srv.URL, "secret", "example/repo")
+}
+
+// setArtifact sets the file of the artifact of the given run.
+func (f *fakeGitHub) setArtifact(runID int64, fileName string, content []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.artifacts[runID] == nil {
+		f.artifacts[runID] = map[string][]byte{}
+	}
+	f.artifacts[runID][fileName] = content
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if r.Method != http.MethodGet {
+		f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.EscapedPath()+" "+string(body)))
+	}
+
+	assert.Equal(f.t, "Bearer secret", r.Header.Get("Authorization"))
+
+	if status, ok := f.failures[r.Method+" "+r.URL.Path]; ok {
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"message": "Failure of the fake"}`)
+		return
+	}
+
+	decode := func(v any) {
+		require.NoError(f.t, json.Unmarshal(body, v), "%s %s", r.Method, r.URL.Path)
+	}
+	reply := func(status int, v any) {
+		w.WriteHeader(status)
+		require.NoError(f.t, json.NewEncoder(w).Encode(v))
+	}
+	// Lists are not paginated, so only the first page has items.
+	replyPage := func(v any) {
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		reply(http.StatusOK, v)
+	}
+	newComment := func() githubComment {
+		var in struct {
+			Body string `json:"body"`
+		}
+		decode(&in)
+		f.nextID++
+		return githubComment{ID: f.nextID, Body: in.Body, User: githubUser{Login: fakeBotLogin}}
+	}
+
+	var id int64
+	var name string
+	path := r.URL.Path
+	switch {
+	case path == "/repos/example/repo/actions/workflows":
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min(perPage*(page-1), len(f.workflows))
+		reply(http.StatusOK, map[string]any{"workflows": f.workflows[start:min(start+perPage, len(f.workflows))]})
+	case path == "/repos/example/repo/actions/workflows/7/runs":
+		assert.Equal(f.t, "main", r.URL.Query().Get("branch"))
+		assert.Equal(f.t, "success", r.URL.Query().Get("status"))
+		fmt.Fprint(w, `{"workflow_runs": [{"id": 1, "head_sha": "abc123"}]}`)
+	case sscanPath(path, "/repos/example/repo/actions/runs/%d/artifacts", &id):
+		assert.Equal(f.t, "code-coverage", r.URL.Query().Get("name"))
+		fmt.Fprintf(w, `{"artifacts": [{"name": "code-coverage", "archive_download_url": "http://%s/download/%d"}]}`, r.Host, id)
+	case sscanPath(path, "/download/%d", &id):
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, fileName := range sortedKeys(f.artifacts[id]) {
+			fw, err := zw.Create(fileName)
+			require.NoError(f.t, err)
+			_, _ = fw.Write(f.artifacts[id][fileName])
+		}
+		require.NoError(f.t, zw.Close())
+		_, _ = w.Write(buf.Bytes())
+
+	case path == "/repos/example/repo/pulls/42/files":
+		replyPage(f.files)
+	case path == "/repos/example/repo/compare/abc123...def456":
+		reply(http.StatusOK, map[string]any{"files": f.files})
+	case path == "/repos/example/repo/pulls/42" && r.Method == http.MethodGet:
+		labels := []map[string]string{}
+		for _, l := range f.labels {
+			labels = append(labels, map[string]string{"name": l})
+		}
+		reply(http.StatusOK, map[string]any{"number": 42, "body": f.body, "head": map[string]string{"sha": "def456"}, "labels": labels})
+	case strings.HasPrefix(path, "/repos/example/repo/contents/"):
+		// The head of the pull request is the testdata of the fixture repository.
+		assert.Equal(f.t, "def456", r.URL.Query().Get("ref"))
+		content, err := os.ReadFile(filepath.Join("testdata", "github.com", "pentohq", "pento", strings.TrimPrefix(path, "/repos/example/repo/contents/")))
+		if err != nil {
+			reply(http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		reply(http.StatusOK, map[string]string{"encoding": "base64", "content": base64.StdEncoding.EncodeToString(content)})
+	case path == "/repos/example/repo/pulls/42" && r.Method == http.MethodPatch:
+		var in struct {
+			Body string `json:"body"`
+		}
+		decode(&in)
+		f.body = in.Body
+		reply(http.StatusOK, map[string]any{"number": 42})
+
+	case path == "/repos/example/repo/issues/42/comments" && r.Method == http.MethodGet:
+		replyPage(f.comments)
+	case path == "/repos/example/repo/issues/42/comments" && r.Method == http.MethodPost:
+		c := newComment()
+		f.comments = append(f.comments, c)
+		reply(http.StatusCreated, c)
+	case sscanPath(path, "/repos/example/repo/issues/comments/%d", &id) && r.Method == http.MethodDelete:
+		f.comments = slices.DeleteFunc(f.comments, func(c githubComment) bool { return c.ID == id })
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(path, "/repos/example/repo/commits/") && strings.HasSuffix(path, "/comments"):
+		sha := strings.TrimSuffix(strings.TrimPrefix(path, "/repos/example/repo/commits/"), "/comments")
+		if r.Method == http.MethodGet {
+			replyPage(f.commitComments[sha])
+			return
+		}
+		c := newComment()
+		f.commitComments[sha] = append(f.commitComments[sha], c)
+		reply(http.StatusCreated, c)
+	case sscanPath(path, "/repos/example/repo/comments/%d", &id) && r.Method == http.MethodDelete:
+		for sha, comments := range f.commitComments {
+			f.commitComments[sha] = slices.DeleteFunc(comments, func(c githubComment) bool { return c.ID == id })
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/repos/example/repo/labels":
+		var in struct {
+			Name string `json:"name"`
+		}
+		decode(&in)
+		if f.repoLabels[in.Name] {
+			reply(http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+			return
+		}
+		f.repoLabels[in.Name] = true
+		reply(http.StatusCreated, in)
+	case path == "/repos/example/repo/issues/42/labels":
+		var in struct {
+			Labels []string `json:"labels"`
+		}
+		decode(&in)
+		for _, l := range in.Labels {
+			if !slices.Contains(f.labels, l) {
+				f.labels = append(f.labels, l)
+			}
+		}
+		reply(http.StatusOK, in.Labels)
+	case strings.HasPrefix(path, "/repos/example/repo/issues/42/labels/") && r.Method == http.MethodDelete:
+		name, _ = url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/repos/example/repo/issues/42/labels/"))
+		f.labels = slices.DeleteFunc(f.labels, func(l string) bool { return l == name })
+		reply(http.StatusOK, f.labels)
+
+	case path == "/repos/example/repo/issues" && r.Method == http.MethodGet:
+		assert.Equal(f.t, "open", r.URL.Query().Get("state"))
+		issues := []fakeIssue{}
+		for _, issue := range f.issues {
+			if slices.Contains(issue.Labels, r.URL.Query().Get("labels")) {
+				issues = append(issues, issue)
+			}
+		}
+		replyPage(issues)
+	case path == "/repos/example/repo/issues" && r.Method == http.MethodPost:
+		var issue fakeIssue
+		decode(&issue)
+		var in struct {
+			Labels []string `json:"labels"`
+		}
+		decode(&in)
+		issue.Number, issue.Labels = len(f.issues)+1, in.Labels
+		f.issues = append(f.issues, issue)
+		reply(http.StatusCreated, issue)
+	case sscanPath(path, "/repos/example/repo/issues/%d", &id) && id != 42 && r.Method == http.MethodPatch:
+		decode(&f.issues[id-1])
+		reply(http.StatusOK, f.issues[id-1])
+	case sscanPath(path, "/repos/example/repo/issues/%d/assignees", &id) && id != 42:
+		var in struct {
+			Assignees []string `json:"assignees"`
+		}
+		decode(&in)
+		f.issues[id-1].Assignees = append(f.issues[id-1].Assignees, in.Assignees...)
+		reply(http.StatusCreated, f.issues[id-1])
+
+	case path == "/repos/example/repo/pulls/42/reviews":
+		replyPage(f.reviews)
+	case path == "/repos/example/repo/pulls/42/requested_reviewers":
+		reply(http.StatusCreated, map[string]any{"number": 42})
+	case strings.HasPrefix(path, "/orgs/example/teams/coverage-owners/memberships/"):
+		if !f.teamMembers[strings.TrimPrefix(path, "/orgs/example/teams/coverage-owners/memberships/")] {
+			reply(http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		fmt.Fprint(w, `{"state": "active"}`)
+
+	case path == "/repos/example/repo/check-runs":
+		var run fakeCheckRun
+		decode(&run)
+		f.checkRuns = append(f.checkRuns, run)
+		reply(http.StatusCreated, run)
+	case strings.HasPrefix(path, "/repos/example/repo/commits/") && strings.HasSuffix(path, "/check-runs"):
+		sha := strings.TrimSuffix(strings.TrimPrefix(path, "/repos/example/repo/commits/"), "/check-runs")
+		runs := []fakeCheckRun{}
+		for i := len(f.checkRuns) - 1; i >= 0; i-- {
+			if run := f.checkRuns[i]; run.HeadSHA == sha && run.Name == r.URL.Query().Get("check_name") {
+				runs = append(runs, run)
+				break // filter=latest
+			}
+		}
+		reply(http.StatusOK, map[string]any{"check_runs": runs})
+	case strings.HasPrefix(path, "/repos/example/repo/statuses/"):
+		status := fakeStatus{SHA: strings.TrimPrefix(path, "/repos/example/repo/statuses/")}
+		decode(&status)
+		f.statuses = append(f.statuses, status)
+		reply(http.StatusCreated, status)
+
+	case sscanPath(path, "/repos/example/repo/actions/runs/%d/deployment_protection_rule", &id):
+		var review map[string]string
+		decode(&review)
+		f.deployments = append(f.deployments, review)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		reply(http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func sscanPath(path, format string, id *int64) bool {
+	n, err := fmt.Sscanf(path, format, id)
+	return err == nil && n == 1 && fmt.Sprintf(format, *id) == path
+}
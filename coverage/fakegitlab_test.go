@@ -0,0 +1,156 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitLab is an in-memory fake of the merge request notes API of GitLab
+// for the project "group/project" with the merge request 7. It authenticates
+// requests via the PRIVATE-TOKEN "secret", which belongs to the user with ID
+// 1, and keeps the state of the notes, so that tests of GitLab integrations
+// can check the result. All non-GET requests are recorded.
+type fakeGitLab struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	notes    []fakeGitLabNote
+	nextID   int64
+	requests []string // "METHOD path body" of all non-GET requests
+}
+
+type fakeGitLabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type fakeGitLabNote struct {
+	ID     int64          `json:"id"`
+	Body   string         `json:"body"`
+	System bool           `json:"system"` // e.g. "added 1 commit"
+	Author fakeGitLabUser `json:"author"`
+}
+
+// start starts the fake and returns the base URL of its API.
+func (f *fakeGitLab) start() string {
+	srv := httptest.NewServer(f)
+	f.t.Cleanup(srv.Close)
+
+	return srv.URL + "/api/v4"
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if r.Method != http.MethodGet {
+		f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.EscapedPath()+" "+string(body)))
+	}
+
+	if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "401 Unauthorized"}`)
+		return
+	}
+
+	var in struct {
+		Body string `json:"body"`
+	}
+	if len(body) > 0 {
+		require.NoError(f.t, json.Unmarshal(body, &in))
+	}
+	reply := func(v any) {
+		require.NoError(f.t, json.NewEncoder(w).Encode(v))
+	}
+
+	var id int64
+	switch path := r.URL.EscapedPath(); {
+	case path == "/api/v4/user":
+		reply(fakeGitLabUser{ID: 1, Username: "coverage-bot"})
+	case path == "/api/v4/projects/group%2Fproject/merge_requests/7/notes" && r.Method == http.MethodGet:
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if perPage <= 0 {
+			perPage = 20
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min(perPage*(max(page, 1)-1), len(f.notes))
+		reply(f.notes[start:min(start+perPage, len(f.notes))])
+	case path == "/api/v4/projects/group%2Fproject/merge_requests/7/notes" && r.Method == http.MethodPost:
+		f.nextID++
+		note := fakeGitLabNote{ID: f.nextID, Body: in.Body, Author: fakeGitLabUser{ID: 1, Username: "coverage-bot"}}
+		f.notes = append(f.notes, note)
+		w.WriteHeader(http.StatusCreated)
+		reply(note)
+	case sscanPath(path, "/api/v4/projects/group%%2Fproject/merge_requests/7/notes/%d", &id) && r.Method == http.MethodPut:
+		for i := range f.notes {
+			if f.notes[i].ID == id {
+				f.notes[i].Body = in.Body
+				reply(f.notes[i])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "404 Not found"}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "404 Not found"}`)
+	}
+}
+
+func TestFakeGitLab(t *testing.T) {
+	gl := &fakeGitLab{t: t, nextID: 1000}
+	for i := int64(1); i <= 30; i++ {
+		gl.notes = append(gl.notes, fakeGitLabNote{ID: i, Body: "LGTM", Author: fakeGitLabUser{ID: 2}})
+	}
+	api := gl.start()
+
+	do := func(method, path, token, body string) (int, string) {
+		req, err := http.NewRequest(method, api+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("PRIVATE-TOKEN", token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	status, body := do(http.MethodGet, "/user", "invalid", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, `{"message": "401 Unauthorized"}`, body)
+
+	const notes = "/projects/group%2Fproject/merge_requests/7/notes"
+	status, body = do(http.MethodPost, notes, "secret", `{"body":"Coverage Δ"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, `{"id":1001,"body":"Coverage Δ","system":false,"author":{"id":1,"username":"coverage-bot"}}`, body)
+
+	status, _ = do(http.MethodPut, notes+"/1001", "secret", `{"body":"Coverage Δ 2"}`)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = do(http.MethodPut, notes+"/9999", "secret", `{"body":"Coverage Δ 2"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// The notes are paginated like the GitLab API.
+	var page []fakeGitLabNote
+	_, body = do(http.MethodGet, notes+"?per_page=20&page=2", "secret", "")
+	require.NoError(t, json.Unmarshal([]byte(body), &page))
+	require.Len(t, page, 11)
+	assert.Equal(t, "Coverage Δ 2", page[10].Body)
+
+	assert.Equal(t, []string{
+		`POST /api/v4/projects/group%2Fproject/merge_requests/7/notes {"body":"Coverage Δ"}`,
+		`PUT /api/v4/projects/group%2Fproject/merge_requests/7/notes/1001 {"body":"Coverage Δ 2"}`,
+		`PUT /api/v4/projects/group%2Fproject/merge_requests/7/notes/9999 {"body":"Coverage Δ 2"}`,
+	}, gl.requests)
+}
@@ -0,0 +1,141 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sourceFetcher returns the content of the file at the given slash separated
+// path relative to the repository root, e.g. from a revision that is not
+// checked out.
+type sourceFetcher func(ctx context.Context, path string) ([]byte, error)
+
+// parseSourceFetcher returns the fetcher of the value of -fetch-source:
+// "git:REV" reads the files via "git show" from a revision of the repository
+// in dir (or the working directory) and "github:OWNER/REPO@REF" reads them
+// via the contents API of GitHub, authenticated by GH_TOKEN or GITHUB_TOKEN.
+func parseSourceFetcher(value, dir string) (sourceFetcher, error) {
+	kind, source, _ := strings.Cut(value, ":")
+	switch kind {
+	case "git":
+		if source == "" {
+			break
+		}
+		return func(ctx context.Context, path string) ([]byte, error) {
+			args := []string{"show", source + ":" + path}
+			if dir != "" {
+				args = append([]string{"-C", dir}, args...)
+			}
+			return runGit(ctx, args...)
+		}, nil
+	case "github":
+		repo, ref, ok := strings.Cut(source, "@")
+		if !ok || ref == "" || strings.Count(repo, "/") != 1 {
+			break
+		}
+		gh := newGitHubClient(os.Getenv("GITHUB_API_URL"), githubToken(os.LookupEnv), repo)
+		return gh.contentFetcher(ref), nil
+	}
+
+	return nil, fmt.Errorf("invalid source %q: expected git:REV or github:OWNER/REPO@REF", value)
+}
+
+// missingSourceFiles returns the changed Go files whose source code cannot be
+// found in the tree. Test files are left out since they have no coverage.
+func missingSourceFiles(tree sourceTree, changedFiles []string) []string {
+	var missing []string
+	for _, name := range changedFiles {
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if _, ok := tree.find(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
+
+// fetchMissingSources fetches the changed Go files whose source code cannot be
+// found in the tree into a temporary directory, which becomes the fetched
+// directory of the tree. This way, the sections of the report that need the
+// source code (e.g. the new code and the analysis of its syntax tree) are
+// still rendered if the head of a pull request from a fork is not checked out
+// where it is expected. The files that could not be fetched are returned. The
+// directory is removed via sourceTree.removeFetched. Files that fail to be
+// fetched are reported via logf.
+func fetchMissingSources(ctx context.Context, tree *sourceTree, fetch sourceFetcher, changedFiles []string, root string, logf func(format string, args ...any)) (fetched int, missing []string, err error) {
+	for _, name := range missingSourceFiles(*tree, changedFiles) {
+		dest := filepath.FromSlash(name)
+		if !filepath.IsLocal(dest) {
+			missing = append(missing, name)
+			continue
+		}
+
+		path := name
+		if root != "" {
+			path = strings.TrimPrefix(name, root+"/")
+		}
+
+		content, err := fetch(ctx, path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fetched, missing, ctx.Err()
+			}
+			logf("WARNING: failed to fetch the source code of %s: %v", path, err)
+			missing = append(missing, name)
+			continue
+		}
+
+		if tree.fetched == "" {
+			if tree.fetched, err = os.MkdirTemp("", "go-coverage-report-source-*"); err != nil {
+				return fetched, missing, err
+			}
+		}
+
+		dest = filepath.Join(tree.fetched, dest)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fetched, missing, err
+		}
+		if err := os.WriteFile(dest, content, 0644); err != nil {
+			return fetched, missing, err
+		}
+		fetched++
+	}
+
+	return fetched, missing, nil
+}
+
+// fetchedPath returns the path of the fetched source file of the coverage
+// profile name, if there is one.
+func (t sourceTree) fetchedPath(fileName string) (string, bool) {
+	if t.fetched == "" || !filepath.IsLocal(filepath.FromSlash(fileName)) {
+		return "", false
+	}
+
+	path := filepath.Join(t.fetched, filepath.FromSlash(fileName))
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", false
+	}
+
+	return path, true
+}
+
+// isFetched returns whether the source file at the given path was fetched by
+// fetchMissingSources. Other files of its package are not available next to
+// it.
+func (t sourceTree) isFetched(path string) bool {
+	return t.fetched != "" && strings.HasPrefix(path, t.fetched+string(filepath.Separator))
+}
+
+// removeFetched removes the files of fetchMissingSources.
+func (t sourceTree) removeFetched() error {
+	if t.fetched == "" {
+		return nil
+	}
+
+	return os.RemoveAll(t.fetched)
+}
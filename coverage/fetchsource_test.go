@@ -0,0 +1,72 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSourceFetcher(t *testing.T) {
+	for _, value := range []string{"", "git:", "github:example/repo", "github:repo@main", "svn:trunk"} {
+		_, err := parseSourceFetcher(value, "")
+		assert.Error(t, err, value)
+	}
+
+	// Files are read from the revision instead of the working tree.
+	dir, _ := newFixtureRepo(t)
+	require.NoError(t, os.Remove(filepath.Join(dir, "pkg", "age", "age.go")))
+
+	fetch, err := parseSourceFetcher("git:feature", dir)
+	require.NoError(t, err)
+	content, err := fetch(context.Background(), "pkg/age/age.go")
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/github.com/pentohq/pento/pkg/age/age.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(content))
+
+	_, err = fetch(context.Background(), "pkg/age/missing.go")
+	assert.Error(t, err)
+}
+
+func TestFetchMissingSources(t *testing.T) {
+	var requested []string
+	fetch := func(ctx context.Context, path string) ([]byte, error) {
+		requested = append(requested, path)
+		if path == "pkg/gone/gone.go" {
+			return nil, errors.New("404 Not Found")
+		}
+		return []byte("package fork\n"), nil
+	}
+
+	changedFiles := []string{
+		"example.com/repo/pkg/fork/fork.go",
+		"example.com/repo/pkg/fork/fork_test.go",
+		"example.com/repo/pkg/gone/gone.go",
+		"example.com/repo/README.md",
+		"github.com/pentohq/pento/pkg/age/age.go", // available in the testdata
+	}
+	var tree sourceTree
+	fetched, missing, err := fetchMissingSources(context.Background(), &tree, fetch, changedFiles, "example.com/repo", t.Logf)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tree.removeFetched() })
+	assert.Equal(t, 1, fetched)
+	assert.Equal(t, []string{"example.com/repo/pkg/gone/gone.go"}, missing)
+	assert.Equal(t, []string{"pkg/fork/fork.go", "pkg/gone/gone.go"}, requested)
+
+	path, ok := tree.find("example.com/repo/pkg/fork/fork.go")
+	require.True(t, ok)
+	assert.True(t, tree.isFetched(path))
+	assert.True(t, packageHasTests(tree, "example.com/repo/pkg/fork/fork.go"), "the other files of the package are unknown")
+	_, ok = sourceTree{}.find("example.com/repo/pkg/fork/fork.go")
+	assert.False(t, ok, "only the tree of the fetched files finds them")
+
+	require.NoError(t, tree.removeFetched())
+	assert.NoDirExists(t, tree.fetched)
+	_, ok = tree.find("example.com/repo/pkg/fork/fork.go")
+	assert.False(t, ok)
+}
@@ -0,0 +1,79 @@
+package coverage
+
+import (
+	"path"
+	"strings"
+)
+
+// pathFilter returns a function that reports whether a file of the coverage
+// profile matches any of the given comma separated glob patterns. Patterns
+// are matched against the path relative to root (if the file is inside of it)
+// as well as against the full path. Each path segment is matched via
+// path.Match and a "**" segment matches any number of segments.
+func pathFilter(patterns, root string) func(fileName string) bool {
+	var globs []string
+	for _, p := range strings.Split(patterns, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			globs = append(globs, strings.Trim(p, "/"))
+		}
+	}
+
+	return func(fileName string) bool {
+		candidates := []string{fileName}
+		if rel, ok := relativeToRoot(fileName, root); ok {
+			candidates = append(candidates, rel)
+		}
+
+		for _, glob := range globs {
+			for _, name := range candidates {
+				if matchGlob(glob, name) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// relativeToRoot returns the path of a file of the coverage profile relative
+// to the given import path. It returns false if the file is not inside of it.
+func relativeToRoot(fileName, root string) (string, bool) {
+	if root == "" {
+		return "", false
+	}
+
+	return strings.CutPrefix(fileName, strings.TrimSuffix(root, "/")+"/")
+}
+
+// matchGlob reports whether the slash separated name matches the pattern.
+// In addition to the syntax of path.Match, a "**" segment matches zero or
+// more path segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,42 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"pkg/service/**", "pkg/service/a.go", true},
+		{"pkg/service/**", "pkg/service/sub/a.go", true},
+		{"pkg/service/**", "pkg/services/a.go", false},
+		{"pkg/*/a.go", "pkg/service/a.go", true},
+		{"pkg/*/a.go", "pkg/service/sub/a.go", false},
+		{"**/a.go", "a.go", true},
+		{"**/a.go", "pkg/service/a.go", true},
+		{"**/*_test.go", "pkg/a.go", false},
+		{"pkg/service", "pkg/service/a.go", false},
+		{"pkg/[", "pkg/[", false}, // invalid pattern
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, matchGlob(c.pattern, c.name), "%q %q", c.pattern, c.name)
+	}
+}
+
+func TestPathFilter(t *testing.T) {
+	keep := pathFilter(" pkg/service/**, cmd/*/main.go ,", "example.com/app/")
+
+	assert.True(t, keep("example.com/app/pkg/service/a.go"))
+	assert.True(t, keep("example.com/app/cmd/server/main.go"))
+	assert.False(t, keep("example.com/app/pkg/other/a.go"))
+	assert.False(t, keep("example.com/other/pkg/service/a.go"))
+
+	keep = pathFilter("example.com/app/pkg/**", "")
+	assert.True(t, keep("example.com/app/pkg/service/a.go"))
+	assert.False(t, keep("example.com/app/cmd/main.go"))
+}
@@ -0,0 +1,189 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var renderFixtureUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s render-fixture [OPTIONS] <DIRECTORY> [NAME...]
+
+Render the expected Markdown report of the test fixtures in DIRECTORY (e.g.
+coverage/testdata) and write it to <NAME>-report.md. Without
+names, all fixtures of the directory are rendered.
+
+A fixture is a set of inputs of the main command that share a name prefix:
+
+  <NAME>-old-coverage.txt    OLD_COVERAGE_FILE
+  <NAME>-new-coverage.txt    NEW_COVERAGE_FILE
+  <NAME>-changed-files.json  CHANGED_FILES_FILE
+  <NAME>-flags.txt           optional flags of the main command, one per line
+                             (e.g. -root=github.com/example/repo); paths are
+                             relative to DIRECTORY
+
+Source files are searched in DIRECTORY/.. unless -repo-root is set in the flags
+file. Environment variables and configuration files that are not referenced in
+the flags file are ignored, so the reports do not depend on the environment.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+// fixturePathFlags are the flags of the main command whose values are paths,
+// which are relative to the fixture directory.
+var fixturePathFlags = []string{"diff", "config", "previous", "package-coverage", "test-json", "ignore-file", "repo-root"}
+
+func runRenderFixtureCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("render-fixture", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, renderFixtureUsage)
+		fs.PrintDefaults()
+	}
+
+	check := fs.Bool("check", false, "do not write the reports but fail if any of them is outdated")
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing fixture directory")
+	}
+
+	dir := fs.Arg(0)
+	names := fs.Args()[1:]
+	if len(names) == 0 {
+		var err error
+		if names, err = findFixtures(dir); err != nil {
+			return err
+		}
+	}
+
+	var outdated []string
+	for _, name := range names {
+		report, err := renderFixture(ctx, dir, name)
+		if err != nil {
+			return fmt.Errorf("fixture %s: %w", name, err)
+		}
+
+		path := fixtureReportPath(dir, name)
+		if *check {
+			current, err := os.ReadFile(path)
+			if err != nil || string(current) != report {
+				outdated = append(outdated, path)
+			}
+			continue
+		}
+
+		if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+			return err
+		}
+		fmt.Println("Wrote", path)
+	}
+
+	if len(outdated) > 0 {
+		return fmt.Errorf("outdated fixture reports (run %s render-fixture %s): %s", filepath.Base(os.Args[0]), dir, strings.Join(outdated, ", "))
+	}
+
+	return nil
+}
+
+// findFixtures returns the names of all fixtures in the directory.
+func findFixtures(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*-new-coverage.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = strings.TrimSuffix(filepath.Base(m), "-new-coverage.txt")
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func fixtureReportPath(dir, name string) string {
+	return filepath.Join(dir, name+"-report.md")
+}
+
+// renderFixture returns the Markdown report of the fixture with the given
+// name in dir.
+func renderFixture(ctx context.Context, dir, name string) (string, error) {
+	args, err := readFixtureFlags(filepath.Join(dir, name+"-flags.txt"))
+	if err != nil {
+		return "", err
+	}
+
+	fs := flag.NewFlagSet("render-fixture", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return "", fmt.Errorf("invalid flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected arguments in flags file: %v", fs.Args())
+	}
+
+	for _, name := range fixturePathFlags {
+		if val := fs.Lookup(name).Value.String(); val != "" && !filepath.IsAbs(val) {
+			_ = fs.Set(name, filepath.Join(dir, val))
+		}
+	}
+
+	opts := optionsFromFlags(fs)
+	if opts.repoRoot == "" {
+		opts.repoRoot = filepath.Dir(filepath.Clean(dir))
+	}
+	if opts.configFile != "" {
+		if opts.config, err = LoadConfig(opts.configFile); err != nil {
+			return "", err
+		}
+	}
+
+	path := func(suffix string) string {
+		return filepath.Join(dir, name+suffix)
+	}
+	result, err := analyze(ctx, path("-old-coverage.txt"), path("-new-coverage.txt"), path("-changed-files.json"), opts)
+	if err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", errors.New("no changed files")
+	}
+	defer result.Close()
+
+	return result.Markdown(), nil
+}
+
+// readFixtureFlags returns the flags of the flags file of a fixture. Empty
+// lines and lines starting with # are ignored. A missing file has no flags.
+func readFixtureFlags(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			args = append(args, line)
+		}
+	}
+
+	return args, s.Err()
+}
@@ -0,0 +1,109 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the expected reports of the test fixtures instead of
+// comparing them: go test -run TestRenderFixture . -update-golden
+var updateGolden = flag.Bool("update-golden", false, "update the expected reports in testdata")
+
+// assertGolden compares the Markdown report with the expected report of the
+// fixture with the given name in testdata.
+func assertGolden(t *testing.T, name, actual string) {
+	t.Helper()
+
+	expected, err := os.ReadFile(fixtureReportPath("testdata", name))
+	require.NoError(t, err, "run go test . -update-golden to create the expected report")
+	assert.Equal(t, string(expected), actual)
+}
+
+func TestRenderFixture(t *testing.T) {
+	names, err := findFixtures("testdata")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"01", "02", "03", "04", "05"}, names)
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			actual, err := renderFixture(context.Background(), "testdata", name)
+			require.NoError(t, err)
+
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(fixtureReportPath("testdata", name), []byte(actual), 0644))
+				return
+			}
+			assertGolden(t, name, actual)
+		})
+	}
+}
+
+// TestRenderFixture_CRLF checks that the Windows variant of a fixture (CRLF
+// line endings and byte order marks in all inputs and source files) is
+// reported exactly like the original.
+func TestRenderFixture_CRLF(t *testing.T) {
+	for _, name := range []string{"05-old-coverage.txt", "05-diff.patch", "crlf/github.com/pentohq/pento/pkg/age/age.go"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(data, utf8BOM), "%s has no byte order mark", name)
+		require.Contains(t, string(data), "\r\n", "%s has no CRLF line endings", name)
+	}
+
+	original, err := renderFixture(context.Background(), "testdata", "04")
+	require.NoError(t, err)
+	windows, err := renderFixture(context.Background(), "testdata", "05")
+	require.NoError(t, err)
+	assert.Equal(t, original, windows)
+}
+
+func TestRenderFixtureCommand_Check(t *testing.T) {
+	dir := t.TempDir()
+	for _, suffix := range []string{"-old-coverage.txt", "-new-coverage.txt", "-changed-files.json", "-flags.txt"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "02"+suffix))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "02"+suffix), data, 0644))
+	}
+	// The source files of the fixture are in the testdata of the package.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "02-flags.txt"), []byte("# prioqueue\n\n-root=github.com/fgrosse/prioqueue\n-repo-root="+mustAbs(t, ".")+"\n"), 0644))
+
+	err := runRenderFixtureCommand(context.Background(), []string{"-check", dir})
+	assert.ErrorContains(t, err, "outdated fixture reports")
+
+	require.NoError(t, runRenderFixtureCommand(context.Background(), []string{dir}))
+	require.NoError(t, runRenderFixtureCommand(context.Background(), []string{"-check", dir}))
+
+	actual, err := os.ReadFile(fixtureReportPath(dir, "02"))
+	require.NoError(t, err)
+	assertGolden(t, "02", string(actual))
+
+	err = runRenderFixtureCommand(context.Background(), []string{dir, "05"})
+	assert.ErrorContains(t, err, "fixture 05")
+}
+
+func TestReadFixtureFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.txt")
+
+	args, err := readFixtureFlags(path)
+	require.NoError(t, err)
+	assert.Empty(t, args)
+
+	require.NoError(t, os.WriteFile(path, []byte("# comment\n-root=example.com/app\n\n  -min-coverage=80  \n"), 0644))
+	args, err = readFixtureFlags(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-root=example.com/app", "-min-coverage=80"}, args)
+}
+
+func mustAbs(t *testing.T, path string) string {
+	t.Helper()
+
+	abs, err := filepath.Abs(path)
+	require.NoError(t, err)
+	return abs
+}
@@ -0,0 +1,116 @@
+package coverage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Names of the collapsible sections of the Markdown report that can be
+// configured via the "fold" object of the config file.
+const (
+	foldNewCode      = "new_code"      // New Code Coverage Details
+	foldCommits      = "commits"       // Coverage by Commit (see -per-commit)
+	foldLineChanges  = "line_changes"  // Coverage Changes in Unchanged Lines
+	foldNeutrality   = "neutrality"    // Coverage Differences (see -neutral)
+	foldTestGaps     = "test_gaps"     // Test Gap Priorities
+	foldSkippedTests = "skipped_tests" // Skipped Tests
+	foldSharding     = "sharding"      // Sharding Advice
+	foldTargets      = "targets"       // Coverage Targets
+	foldDeprecated   = "deprecated"    // Modifying Deprecated Code
+	foldExcluded     = "excluded"      // Excluded Code
+	foldPackages     = "packages"      // Impacted Packages
+	foldFiles        = "files"         // Coverage by file
+	foldShards       = "shards"        // Coverage by Shard (see NEW_COVERAGE_FILE)
+
+	// foldDefault sets the rule of all sections without their own rule.
+	foldDefault = "default"
+)
+
+var foldSections = []string{foldNewCode, foldCommits, foldLineChanges, foldNeutrality, foldTestGaps, foldSkippedTests, foldSharding, foldTargets, foldDeprecated, foldExcluded, foldPackages, foldFiles, foldShards}
+
+// Fold rules of a section.
+const (
+	foldClosed = "closed" // always collapsed (the default)
+	foldOpen   = "open"   // always expanded
+	foldAuto   = "auto"   // expanded if the section contains a violation
+)
+
+// validateFold returns an error if the fold rules contain an unknown section
+// or rule.
+func validateFold(rules map[string]string) error {
+	for section, rule := range rules {
+		if section != foldDefault && !slices.Contains(foldSections, section) {
+			return fmt.Errorf("unknown fold section %q (valid sections: %s, %s)", section, foldDefault, strings.Join(foldSections, ", "))
+		}
+		if rule != foldClosed && rule != foldOpen && rule != foldAuto {
+			return fmt.Errorf("invalid fold rule %q for section %q (valid rules: %s, %s, %s)", rule, section, foldAuto, foldOpen, foldClosed)
+		}
+	}
+
+	return nil
+}
+
+// FoldRule returns the configured fold rule of the given section.
+func (c *Config) FoldRule(section string) string {
+	if c == nil {
+		return foldClosed
+	}
+	if rule, ok := c.Fold[section]; ok {
+		return rule
+	}
+	if rule, ok := c.Fold[foldDefault]; ok {
+		return rule
+	}
+
+	return foldClosed
+}
+
+// detailsTag returns the opening <details> tag of the given section. It is
+// expanded if the fold rule of the section is "open", or if it is "auto" and
+// violation returns true.
+func (r *Report) detailsTag(section string, violation func() bool) string {
+	switch r.Config.FoldRule(section) {
+	case foldOpen:
+		return "<details open>"
+	case foldAuto:
+		if violation() {
+			return "<details open>"
+		}
+	}
+
+	return "<details>"
+}
+
+// belowMinCoverage returns true if the coverage of the new code is below
+// -min-coverage.
+func (r *Result) belowMinCoverage() bool {
+	return checkMinCoverage(r, r.MinCoverage) != nil
+}
+
+// hasPackageRegression returns true if the coverage of any changed package
+// decreased.
+func (r *Report) hasPackageRegression() bool {
+	oldPkgs, newPkgs := r.Old.ByPackage(), r.New.ByPackage()
+	for _, pkg := range r.ChangedPackages {
+		oldCov, newCov := oldPkgs[pkg], newPkgs[pkg]
+		if oldCov != nil && newCov != nil && newCov.Percent() < oldCov.Percent() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasFileRegression returns true if the coverage of any changed file
+// decreased.
+func (r *Report) hasFileRegression() bool {
+	for _, name := range r.ChangedFiles {
+		oldProfile, newProfile := r.Old.Files[name], r.New.Files[name]
+		if oldProfile != nil && newProfile != nil && newProfile.CoveragePercent() < oldProfile.CoveragePercent() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,39 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Markdown_Fold(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	assert.NotContains(t, report.Analyze().Markdown(), "<details open>", "all sections are collapsed by default")
+
+	// The coverage of the package and of min_heap.go decreased.
+	report.Config = &Config{Fold: map[string]string{foldDefault: foldAuto}}
+	md := report.Analyze().Markdown()
+	assert.Contains(t, md, "<details open>\n\n<summary>Impacted Packages</summary>")
+	assert.Contains(t, md, "<details open>\n\n<summary>Coverage by file</summary>")
+	assert.Contains(t, md, "<details>\n\n<summary>New Code Coverage Details</summary>", "there is no coverage threshold")
+
+	report.MinCoverage = 90
+	report.TestGapPriorities = true
+	report.Config = &Config{Fold: map[string]string{foldDefault: foldAuto, foldPackages: foldClosed, foldFiles: foldClosed}}
+	md = report.Analyze().Markdown()
+	assert.Contains(t, md, "<details open>\n\n<summary>New Code Coverage Details</summary>")
+	assert.Contains(t, md, "<details open>\n\n<summary>Test Gap Priorities</summary>")
+	assert.Equal(t, 2, strings.Count(md, "<details open>"), md)
+
+	report.Config = &Config{Fold: map[string]string{foldPackages: foldOpen}}
+	assert.Contains(t, report.Analyze().Markdown(), "<details open>\n\n<summary>Impacted Packages</summary>")
+}
@@ -0,0 +1,197 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"os"
+	"sort"
+)
+
+// FunctionCoverage is the coverage of a single function as reported by "go
+// tool cover -func". Closures are listed separately and named like in stack
+// traces and profiles (e.g. "Run.func1", nested closures "Run.func1.1" and
+// closures in methods "(*Server).Run.func1"). Their statements are counted for
+// the enclosing function as well, so the numbers of all other functions match
+// the output of "go tool cover -func".
+type FunctionCoverage struct {
+	FileName    string `json:"file"`
+	Name        string `json:"name"`
+	Line        int    `json:"line"`
+	Closure     bool   `json:"closure,omitempty"`
+	TotalStmt   int64  `json:"statements"`
+	CoveredStmt int64  `json:"covered"`
+}
+
+// Percent returns the percentage of covered statements of the function.
+func (f FunctionCoverage) Percent() float64 {
+	if f.TotalStmt == 0 {
+		return 0
+	}
+
+	return float64(f.CoveredStmt) / float64(f.TotalStmt) * 100
+}
+
+// funcExtent is the position of a function declaration or closure in a file.
+type funcExtent struct {
+	name                                 string
+	closure                              bool
+	startLine, startCol, endLine, endCol int
+}
+
+// contains returns whether the block starts inside of the function.
+func (e funcExtent) contains(b ProfileBlock) bool {
+	afterStart := b.StartLine > e.startLine || (b.StartLine == e.startLine && b.StartCol >= e.startCol)
+	beforeEnd := b.StartLine < e.endLine || (b.StartLine == e.endLine && b.StartCol < e.endCol)
+	return afterStart && beforeEnd
+}
+
+// getFunctionExtents returns the positions of all function declarations and
+// closures of the given file in the order of their appearance.
+func (m *StatementLineMapper) getFunctionExtents(filePath string) ([]funcExtent, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseFile(m.fset, filePath, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var extents []funcExtent
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		extents = append(extents, m.extent(fn.Name.Name, false, fn))
+		extents = m.closureExtents(extents, qualifiedFuncName(fn), ".func", fn.Body)
+	}
+
+	return extents, nil
+}
+
+// closureExtents appends the extents of all closures in the given node. The
+// closures are numbered like by the compiler: "F.func1", "F.func2" for the
+// closures of F and "F.func1.1" for closures within F.func1.
+func (m *StatementLineMapper) closureExtents(extents []funcExtent, parent, sep string, n ast.Node) []funcExtent {
+	i := 0
+	ast.Inspect(n, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+
+		i++
+		name := fmt.Sprintf("%s%s%d", parent, sep, i)
+		extents = append(extents, m.extent(name, true, lit))
+		extents = m.closureExtents(extents, name, ".", lit.Body)
+
+		return false // nested closures are numbered relative to this one
+	})
+
+	return extents
+}
+
+func (m *StatementLineMapper) extent(name string, closure bool, n ast.Node) funcExtent {
+	start, end := m.fset.Position(n.Pos()), m.fset.Position(n.End())
+	return funcExtent{
+		name:      name,
+		closure:   closure,
+		startLine: start.Line,
+		startCol:  start.Column,
+		endLine:   end.Line,
+		endCol:    end.Column,
+	}
+}
+
+// qualifiedFuncName returns the name of the function including its receiver
+// type as it is used in the names of closures, e.g. "(*Server).Run".
+func qualifiedFuncName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	typ := fn.Recv.List[0].Type
+	star, pointer := typ.(*ast.StarExpr)
+	if pointer {
+		typ = star.X
+	}
+
+	var recv string
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		recv = receiverTypeName(t.X) + "[...]"
+	case *ast.IndexListExpr:
+		recv = receiverTypeName(t.X) + "[...]"
+	default:
+		recv = receiverTypeName(t)
+	}
+
+	if pointer {
+		recv = "(*" + recv + ")"
+	}
+
+	return recv + "." + fn.Name.Name
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return "?"
+}
+
+// functionCoverage returns the coverage of each of the given functions of a
+// file.
+func functionCoverage(fileName string, profile *Profile, extents []funcExtent) []FunctionCoverage {
+	result := make([]FunctionCoverage, len(extents))
+	for i, e := range extents {
+		result[i] = FunctionCoverage{FileName: fileName, Name: e.name, Line: e.startLine, Closure: e.closure}
+		for _, b := range profile.Blocks {
+			if !e.contains(b) {
+				continue
+			}
+
+			result[i].TotalStmt += int64(b.NumStmt)
+			if b.Count > 0 {
+				result[i].CoveredStmt += int64(b.NumStmt)
+			}
+		}
+	}
+
+	return result
+}
+
+// functions returns the coverage of each function and closure of all files
+// whose source code can be found in the tree (see sourceTree.find), sorted by file
+// and line. The second return value lists the files without source code.
+func (c *Coverage) functions(tree sourceTree, mapper *StatementLineMapper) (funcs []FunctionCoverage, missing []string) {
+	for _, fileName := range sortedKeys(c.Files) {
+		path, ok := tree.find(fileName)
+		if !ok {
+			missing = append(missing, fileName)
+			continue
+		}
+
+		extents, err := mapper.getFunctionExtents(path)
+		if err != nil {
+			missing = append(missing, fileName)
+			continue
+		}
+
+		funcs = append(funcs, functionCoverage(fileName, c.Files[fileName], extents)...)
+	}
+
+	sort.SliceStable(funcs, func(i, j int) bool {
+		if funcs[i].FileName != funcs[j].FileName {
+			return funcs[i].FileName < funcs[j].FileName
+		}
+		return funcs[i].Line < funcs[j].Line
+	})
+
+	return funcs, missing
+}
@@ -0,0 +1,82 @@
+package coverage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage_Functions(t *testing.T) {
+	src := `package cl
+
+func F(x int) int {
+	f := func() int {
+		return 1
+	}
+	if x > 0 { return f() }
+	g := func() int { return 2 }
+	_ = g
+	return 0
+}
+
+type S struct{}
+
+func (s *S) Run() {
+	go func() {
+		defer func() {
+			recover()
+		}()
+	}()
+}
+`
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "cl", "a.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fileName), 0755))
+	require.NoError(t, os.WriteFile(fileName, []byte(src), 0644))
+
+	// Written by "go test -coverprofile" if F is called with x <= 0 and Run is
+	// never called.
+	profile := &Profile{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 4, StartCol: 2, EndLine: 4, EndCol: 18, NumStmt: 1, Count: 1},
+		{StartLine: 5, StartCol: 3, EndLine: 6, EndCol: 1, NumStmt: 1, Count: 0},
+		{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 11, NumStmt: 1, Count: 1},
+		{StartLine: 7, StartCol: 13, EndLine: 7, EndCol: 25, NumStmt: 1, Count: 0},
+		{StartLine: 8, StartCol: 2, EndLine: 8, EndCol: 18, NumStmt: 1, Count: 1},
+		{StartLine: 8, StartCol: 20, EndLine: 8, EndCol: 30, NumStmt: 1, Count: 0},
+		{StartLine: 9, StartCol: 2, EndLine: 10, EndCol: 10, NumStmt: 2, Count: 1},
+		{StartLine: 15, StartCol: 19, EndLine: 16, EndCol: 12, NumStmt: 1, Count: 0},
+		{StartLine: 16, StartCol: 12, EndLine: 17, EndCol: 16, NumStmt: 1, Count: 0},
+		{StartLine: 17, StartCol: 16, EndLine: 19, EndCol: 4, NumStmt: 1, Count: 0},
+	}}
+
+	funcs, missing := New([]*Profile{profile}).functions(sourceTree{}, NewStatementLineMapper())
+	assert.Empty(t, missing)
+
+	type row struct {
+		Name           string
+		Line           int
+		Total, Covered int64
+	}
+	var rows []row
+	for _, f := range funcs {
+		rows = append(rows, row{f.Name, f.Line, f.TotalStmt, f.CoveredStmt})
+	}
+
+	assert.Equal(t, []row{
+		{"F", 3, 8, 5}, // same as "go tool cover -func"
+		{"F.func1", 4, 1, 0},
+		{"F.func2", 8, 1, 0},
+		{"Run", 15, 3, 0},
+		{"(*S).Run.func1", 16, 2, 0},
+		{"(*S).Run.func1.1", 17, 1, 0},
+	}, rows)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeFuncsText(&buf, funcs[:3]))
+	assert.Contains(t, buf.String(), fileName+":3:\tF\t\t62.5%\n")
+	assert.Regexp(t, `\ntotal:\t+\(statements\)\t62.5%\n$`, buf.String())
+}
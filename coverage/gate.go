@@ -0,0 +1,430 @@
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gateVariables are the report variables that can be used in the expressions
+// of the "gates" object of the config file.
+var gateVariables = []string{
+	"total",             // overall coverage after the PR in percent
+	"old_total",         // overall coverage before the PR in percent
+	"delta",             // change of the overall coverage in percentage points
+	"new_code",          // coverage of the new code in percent (100 without new code)
+	"new_statements",    // number of new statements
+	"new_covered",       // number of covered new statements
+	"changed_files",     // number of changed files
+	"min_package_delta", // largest decrease of a changed package in percentage points (0 if none decreased)
+}
+
+// GateResult is the outcome of evaluating a gate of the config file.
+type GateResult struct {
+	Name   string
+	Expr   string
+	Passed bool
+	Values map[string]float64 // the variables used by the expression
+	Err    error              // set if the expression is invalid
+}
+
+// validateGates returns an error if the expression of any gate is invalid.
+func validateGates(gates map[string]string) error {
+	for _, name := range sortedKeys(gates) {
+		if _, err := parseGateExpr(gates[name]); err != nil {
+			return fmt.Errorf("invalid gate %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// GateResults evaluates all gates of the config file in the order of their
+// names.
+func (r *Result) GateResults() []GateResult {
+	if r.Config == nil || len(r.Config.Gates) == 0 {
+		return nil
+	}
+
+	values := r.gateValues()
+	results := make([]GateResult, 0, len(r.Config.Gates))
+	for _, name := range sortedKeys(r.Config.Gates) {
+		result := GateResult{Name: name, Expr: r.Config.Gates[name]}
+		expr, err := parseGateExpr(result.Expr)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+
+		result.Passed = expr.eval(values) != 0
+		result.Values = make(map[string]float64)
+		for _, v := range expr.variables() {
+			result.Values[v] = values[v]
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// FailedGates returns the results of all gates that did not pass.
+func (r *Result) FailedGates() []GateResult {
+	var failed []GateResult
+	for _, result := range r.Gates {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// checkGates returns an error if any gate of the config file did not pass.
+func checkGates(result *Result) error {
+	var errs []error
+	for _, g := range result.FailedGates() {
+		errs = append(errs, fmt.Errorf("gate %q failed: %s", g.Name, g.details()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// details returns the expression of the gate and the values of its variables,
+// e.g. "new_code >= 80 (new_code = 54.55)".
+func (g GateResult) details() string {
+	if g.Err != nil {
+		return fmt.Sprintf("%s (%v)", g.Expr, g.Err)
+	}
+
+	values := make([]string, 0, len(g.Values))
+	for _, name := range sortedKeys(g.Values) {
+		values = append(values, fmt.Sprintf("%s = %s", name, strconv.FormatFloat(math.Round(g.Values[name]*100)/100, 'f', -1, 64)))
+	}
+
+	return fmt.Sprintf("%s (%s)", g.Expr, strings.Join(values, ", "))
+}
+
+// addGateWarning adds a warning for every gate of the config file that did
+// not pass.
+func (r *Result) addGateWarning(report *strings.Builder) {
+	failed := r.FailedGates()
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, "> [!WARNING]")
+	for i, g := range failed {
+		if i > 0 {
+			fmt.Fprintln(report, ">")
+		}
+		fmt.Fprintf(report, "> **Coverage gate not met:** `%s`: `%s`\n", g.Name, g.details())
+	}
+	fmt.Fprintln(report)
+}
+
+// gateValues returns the values of all gateVariables.
+func (r *Result) gateValues() map[string]float64 {
+	totalNew, coveredNew := r.calculateNewCodeCoverage()
+	newCode := 100.0
+	if totalNew > 0 {
+		newCode = float64(coveredNew) / float64(totalNew) * 100
+	}
+
+	var minPackageDelta float64
+	oldPkgs, newPkgs := r.Old.ByPackage(), r.New.ByPackage()
+	for _, pkg := range r.ChangedPackages {
+		oldCov, newCov := oldPkgs[pkg], newPkgs[pkg]
+		if oldCov != nil && newCov != nil {
+			minPackageDelta = min(minPackageDelta, newCov.Percent()-oldCov.Percent())
+		}
+	}
+
+	return map[string]float64{
+		"total":             r.New.Percent(),
+		"old_total":         r.Old.Percent(),
+		"delta":             r.OverallCoverageDelta(),
+		"new_code":          newCode,
+		"new_statements":    float64(totalNew),
+		"new_covered":       float64(coveredNew),
+		"changed_files":     float64(len(r.ChangedFiles)),
+		"min_package_delta": minPackageDelta,
+	}
+}
+
+// gateNode is a node of a parsed gate expression. Boolean values are
+// represented as 1 (true) and 0 (false).
+type gateNode struct {
+	op          string // operator, "num" or "var"
+	num         float64
+	name        string
+	left, right *gateNode
+	boolean     bool // whether the node evaluates to a boolean
+}
+
+func (n *gateNode) eval(values map[string]float64) float64 {
+	switch n.op {
+	case "num":
+		return n.num
+	case "var":
+		return values[n.name]
+	case "!":
+		return boolValue(n.left.eval(values) == 0)
+	case "neg":
+		return -n.left.eval(values)
+	case "||":
+		return boolValue(n.left.eval(values) != 0 || n.right.eval(values) != 0)
+	case "&&":
+		return boolValue(n.left.eval(values) != 0 && n.right.eval(values) != 0)
+	}
+
+	l, r := n.left.eval(values), n.right.eval(values)
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		return l / r
+	case "<":
+		return boolValue(l < r)
+	case "<=":
+		return boolValue(l <= r)
+	case ">":
+		return boolValue(l > r)
+	case ">=":
+		return boolValue(l >= r)
+	case "==":
+		return boolValue(l == r)
+	case "!=":
+		return boolValue(l != r)
+	}
+
+	panic("unknown gate operator " + n.op)
+}
+
+// variables returns the sorted names of all variables used by the expression.
+func (n *gateNode) variables() []string {
+	var names []string
+	var walk func(n *gateNode)
+	walk = func(n *gateNode) {
+		if n == nil {
+			return
+		}
+		if n.op == "var" && !slices.Contains(names, n.name) {
+			names = append(names, n.name)
+		}
+		walk(n.left)
+		walk(n.right)
+	}
+	walk(n)
+	slices.Sort(names)
+
+	return names
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// gateToken is a token of a gate expression and its position in it.
+type gateToken struct {
+	text string // empty at the end of the expression
+	pos  int
+}
+
+// gateOperators are the operators of gate expressions. Operators that are a
+// prefix of another operator come after it.
+var gateOperators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")"}
+
+func tokenizeGateExpr(expr string) ([]gateToken, error) {
+	var tokens []gateToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, gateToken{expr[i:j], i})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] >= 'a' && expr[j] <= 'z' || expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, gateToken{expr[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range gateOperators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, gateToken{op, i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, gateToken{"", len(expr)}), nil
+}
+
+// parseGateExpr parses a boolean expression over the gateVariables. It
+// supports numbers, the arithmetic operators + - * /, the comparisons
+// < <= > >= == !=, the logical operators && || ! and parentheses, with the
+// precedence of Go.
+func parseGateExpr(expr string) (*gateNode, error) {
+	tokens, err := tokenizeGateExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &gateParser{tokens: tokens}
+	n, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.text != "" {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	if !n.boolean {
+		return nil, errors.New("expression must be a comparison or a logical expression, e.g. new_code >= 80")
+	}
+
+	return n, nil
+}
+
+// gateLevels are the binary operators of gate expressions from the lowest to
+// the highest precedence.
+var gateLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+type gateParser struct {
+	tokens []gateToken
+	pos    int
+}
+
+func (p *gateParser) peek() gateToken {
+	return p.tokens[p.pos]
+}
+
+func (p *gateParser) next() gateToken {
+	t := p.tokens[p.pos]
+	if t.text != "" {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *gateParser) parseBinary(level int) (*gateNode, error) {
+	if level == len(gateLevels) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for slices.Contains(gateLevels[level], p.peek().text) {
+		op := p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		n := &gateNode{op: op.text, left: left, right: right}
+		switch level {
+		case 0, 1: // logical operators
+			if !left.boolean || !right.boolean {
+				return nil, fmt.Errorf("operator %s at position %d requires comparisons on both sides", op.text, op.pos+1)
+			}
+			n.boolean = true
+		case 2: // comparisons
+			if left.boolean || right.boolean {
+				return nil, fmt.Errorf("operator %s at position %d requires numbers on both sides", op.text, op.pos+1)
+			}
+			n.boolean = true
+		default: // arithmetic
+			if left.boolean || right.boolean {
+				return nil, fmt.Errorf("operator %s at position %d requires numbers on both sides", op.text, op.pos+1)
+			}
+		}
+		left = n
+	}
+
+	return left, nil
+}
+
+func (p *gateParser) parseUnary() (*gateNode, error) {
+	t := p.next()
+	switch {
+	case t.text == "!" || t.text == "-":
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "!" {
+			if !operand.boolean {
+				return nil, fmt.Errorf("operator ! at position %d requires a comparison", t.pos+1)
+			}
+			return &gateNode{op: "!", left: operand, boolean: true}, nil
+		}
+		if operand.boolean {
+			return nil, fmt.Errorf("operator - at position %d requires a number", t.pos+1)
+		}
+		return &gateNode{op: "neg", left: operand}, nil
+
+	case t.text == "(":
+		n, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.text != ")" {
+			return nil, fmt.Errorf("missing ) for ( at position %d", t.pos+1)
+		}
+		return n, nil
+
+	case t.text == "":
+		return nil, errors.New("unexpected end of expression")
+
+	case t.text[0] >= '0' && t.text[0] <= '9' || t.text[0] == '.':
+		num, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos+1)
+		}
+		return &gateNode{op: "num", num: num}, nil
+
+	case slices.Contains(gateVariables, t.text):
+		return &gateNode{op: "var", name: t.text}, nil
+
+	case t.text[0] == '_' || t.text[0] >= 'a' && t.text[0] <= 'z' || t.text[0] >= 'A' && t.text[0] <= 'Z':
+		return nil, fmt.Errorf("unknown variable %q at position %d (valid variables: %s)", t.text, t.pos+1, strings.Join(gateVariables, ", "))
+
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+}
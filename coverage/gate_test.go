@@ -0,0 +1,119 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGateExpr(t *testing.T) {
+	values := map[string]float64{"new_code": 54.5, "delta": -2, "total": 71, "new_statements": 11}
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"new_code >= 80", false},
+		{"new_code >= 80 || (delta >= 0 && total >= 70)", false},
+		{"new_code >= 50 || (delta >= 0 && total >= 70)", true},
+		{"new_code >= 80 || delta >= -2 && total >= 70", true},
+		{"(new_code >= 80 || delta >= -2) && total >= 80", false},
+		{"!(delta < 0)", false},
+		{"new_statements == 0 || new_code >= 50", true},
+		{"total - delta * 2 > 74.5", true},
+		{"-delta / 2 <= 1 && changed_files != 1", true},
+		{"new_code*new_statements/100 >= 5.99 && delta<=0", true},
+	}
+	for _, c := range cases {
+		n, err := parseGateExpr(c.expr)
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, c.want, n.eval(values) != 0, c.expr)
+	}
+}
+
+func TestParseGateExpr_Errors(t *testing.T) {
+	cases := map[string]string{
+		"":                          "unexpected end of expression",
+		"new_code":                  "expression must be a comparison or a logical expression, e.g. new_code >= 80",
+		"new_code >= 80 &&":         "unexpected end of expression",
+		"(new_code >= 80":           "missing ) for ( at position 1",
+		"new_code >= 80)":           `unexpected ")" at position 15`,
+		"coverage >= 80":            `unknown variable "coverage" at position 1 (valid variables: total, old_total, delta, new_code, new_statements, new_covered, changed_files, min_package_delta)`,
+		"new_code >= 80%":           `unexpected character '%' at position 15`,
+		"new_code >= 80 && 1":       "operator && at position 16 requires comparisons on both sides",
+		"0 < new_code < 80":         "operator < at position 14 requires numbers on both sides",
+		"!new_code":                 "operator ! at position 1 requires a comparison",
+		"-(delta < 0)":              "operator - at position 1 requires a number",
+		"total >= 1.2.3":            `invalid number "1.2.3" at position 10`,
+		"(delta < 0) + 1 > 0":       "operator + at position 13 requires numbers on both sides",
+		"new_code >= 80 new_code":   `unexpected "new_code" at position 16`,
+		"new_code >= 80 || ()":      `unexpected ")" at position 20`,
+		"new_code >= 80 || == 1":    `unexpected "==" at position 19`,
+		"total >= .7e2":             `unexpected "e2" at position 12`,
+		"new_code >= 80 || x >= 80": `unknown variable "x" at position 19 (valid variables: total, old_total, delta, new_code, new_statements, new_covered, changed_files, min_package_delta)`,
+	}
+	for expr, want := range cases {
+		_, err := parseGateExpr(expr)
+		assert.EqualError(t, err, want, expr)
+	}
+}
+
+func TestReport_Gates(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	assert.Empty(t, report.Analyze().GateResults())
+	assert.NoError(t, checkGates(report.Analyze()))
+
+	report.Config = &Config{Gates: map[string]string{
+		"new-code":  "new_code >= 80 || (delta >= 0 && total >= 70)",
+		"packages":  "min_package_delta > -15",
+		"reviewers": "changed_files <= 20",
+	}}
+
+	results := report.Analyze().GateResults()
+	require.Len(t, results, 3)
+	assert.Equal(t, "new-code", results[0].Name)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, []string{"delta", "new_code", "total"}, sortedKeys(results[0].Values))
+	assert.True(t, results[1].Passed)
+	assert.True(t, results[2].Passed)
+
+	err = checkGates(report.Analyze())
+	assert.EqualError(t, err, `gate "new-code" failed: new_code >= 80 || (delta >= 0 && total >= 70) (delta = -12.5, new_code = 54.55, total = 87.5)`)
+
+	markdown := report.Analyze().Markdown()
+	assert.Contains(t, markdown, "> [!WARNING]\n> **Coverage gate not met:** `new-code`: `new_code >= 80 || (delta >= 0 && total >= 70) (delta = -12.5, new_code = 54.55, total = 87.5)`\n")
+	assert.False(t, report.Analyze().Summary.Passed)
+
+	report.Config.Gates = map[string]string{"lenient": "new_code >= 50"}
+	assert.NoError(t, checkGates(report.Analyze()))
+	assert.NotContains(t, report.Analyze().Markdown(), "Coverage gate not met")
+}
+
+func TestLoadConfig_Gates(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{"gates": {"new-code": "new_code >= 80", "total": "total >= 70 &&"}}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadConfig(configFile)
+	assert.ErrorContains(t, err, `invalid gate "total": unexpected end of expression`)
+
+	err = os.WriteFile(configFile, []byte(`{"gates": {"new-code": "new_code >= 80"}}`), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"new-code": "new_code >= 80"}, cfg.Gates)
+}
@@ -0,0 +1,556 @@
+package coverage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// githubClient is a minimal client of the GitHub REST API that implements
+// only what the action subcommand needs.
+type githubClient struct {
+	baseURL string // e.g. https://api.github.com
+	token   string
+	repo    string // owner/name
+	http    *http.Client
+}
+
+// githubError is returned for all responses of the GitHub API that do not
+// have a 2xx status code.
+type githubError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *githubError) Error() string {
+	return fmt.Sprintf("GitHub API: %d %s", e.StatusCode, e.Message)
+}
+
+// isGitHubStatus returns true if err is a githubError with the given status.
+func isGitHubStatus(err error, status int) bool {
+	var ghErr *githubError
+	return errors.As(err, &ghErr) && ghErr.StatusCode == status
+}
+
+func newGitHubClient(baseURL, token, repo string) *githubClient {
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+
+	return &githubClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		repo:    repo,
+		http:    http.DefaultClient,
+	}
+}
+
+// do sends a request to the API endpoint at the given path (relative to the
+// base URL) and decodes the JSON response into out if it is not nil.
+func (c *githubClient) do(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false) // keep comments readable in request logs
+		if err := enc.Encode(in); err != nil {
+			return err
+		}
+		body = &buf
+	}
+
+	u := endpoint
+	if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		u = c.baseURL + "/" + strings.TrimPrefix(endpoint, "/")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var msg struct{ Message string }
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&msg)
+		if msg.Message == "" {
+			msg.Message = http.StatusText(resp.StatusCode)
+		}
+		return &githubError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s %s: %s", method, req.URL.Path, msg.Message)}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if w, ok := out.(io.Writer); ok {
+		_, err = io.Copy(w, io.LimitReader(resp.Body, maxArchiveSize))
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// getAll fetches all pages of a list endpoint.
+func getAll[T any](ctx context.Context, c *githubClient, endpoint string) ([]T, error) {
+	const perPage = 100
+
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+
+	var result []T
+	for page := 1; ; page++ {
+		var items []T
+		err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s%sper_page=%d&page=%d", endpoint, sep, perPage, page), nil, &items)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, items...)
+		if len(items) < perPage {
+			return result, nil
+		}
+	}
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubPullRequest struct {
+	Number int    `json:"number"`
+	Body   string `json:"body"`
+	Head   struct {
+		SHA  string `json:"sha"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"` // nil if the fork was deleted
+	} `json:"head"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+type githubComment struct {
+	ID   int64      `json:"id"`
+	Body string     `json:"body"`
+	User githubUser `json:"user"`
+}
+
+type githubWorkflowRun struct {
+	ID      int64  `json:"id"`
+	HeadSHA string `json:"head_sha"`
+}
+
+func (c *githubClient) repoPath(format string, args ...any) string {
+	return "repos/" + c.repo + "/" + fmt.Sprintf(format, args...)
+}
+
+// latestSuccessfulRun returns the latest successful run of the given workflow
+// that was triggered by a push to the given branch. The workflow can be given
+// by its name or by its file name. If there is no such run, nil is returned.
+func (c *githubClient) latestSuccessfulRun(ctx context.Context, workflow, branch string) (*githubWorkflowRun, error) {
+	id, err := c.workflowID(ctx, workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"branch": {branch}, "event": {"push"}, "status": {"success"}, "per_page": {"1"}}
+	var runs struct {
+		WorkflowRuns []githubWorkflowRun `json:"workflow_runs"`
+	}
+	err = c.do(ctx, http.MethodGet, c.repoPath("actions/workflows/%s/runs?%s", id, query.Encode()), nil, &runs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runs.WorkflowRuns) == 0 {
+		return nil, nil
+	}
+
+	return &runs.WorkflowRuns[0], nil
+}
+
+// workflowID returns the identifier of the workflow with the given name or
+// file name that can be used in API paths.
+func (c *githubClient) workflowID(ctx context.Context, workflow string) (string, error) {
+	if strings.HasSuffix(workflow, ".yml") || strings.HasSuffix(workflow, ".yaml") {
+		return url.PathEscape(path.Base(workflow)), nil
+	}
+
+	const perPage = 100
+	for page := 1; ; page++ {
+		var workflows struct {
+			Workflows []struct {
+				ID   int64  `json:"id"`
+				Name string `json:"name"`
+			} `json:"workflows"`
+		}
+		err := c.do(ctx, http.MethodGet, c.repoPath("actions/workflows?per_page=%d&page=%d", perPage, page), nil, &workflows)
+		if err != nil {
+			return "", err
+		}
+
+		for _, w := range workflows.Workflows {
+			if w.Name == workflow {
+				return fmt.Sprint(w.ID), nil
+			}
+		}
+		if len(workflows.Workflows) < perPage {
+			return "", fmt.Errorf("workflow %q does not exist", workflow)
+		}
+	}
+}
+
+// downloadArtifactFile returns the content of a single file of the artifact
+// with the given name that was uploaded by the given workflow run.
+func (c *githubClient) downloadArtifactFile(ctx context.Context, runID int64, artifactName, fileName string) ([]byte, error) {
+	var artifacts struct {
+		Artifacts []struct {
+			Name        string `json:"name"`
+			Expired     bool   `json:"expired"`
+			DownloadURL string `json:"archive_download_url"`
+		} `json:"artifacts"`
+	}
+	err := c.do(ctx, http.MethodGet, c.repoPath("actions/runs/%d/artifacts?name=%s", runID, url.QueryEscape(artifactName)), nil, &artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range artifacts.Artifacts {
+		if a.Name != artifactName || a.Expired {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := c.do(ctx, http.MethodGet, a.DownloadURL, nil, &buf); err != nil {
+			return nil, err
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			return nil, fmt.Errorf("invalid artifact %q: %w", artifactName, err)
+		}
+
+		for _, f := range zr.File {
+			if f.Name != fileName {
+				continue
+			}
+
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("invalid artifact %q: %w", artifactName, err)
+			}
+			defer rc.Close()
+
+			return io.ReadAll(rc)
+		}
+
+		return nil, fmt.Errorf("artifact %q of run %d does not contain %s", artifactName, runID, fileName)
+	}
+
+	return nil, fmt.Errorf("run %d has no artifact %q", runID, artifactName)
+}
+
+func (c *githubClient) pullRequest(ctx context.Context, number int) (*githubPullRequest, error) {
+	var pr githubPullRequest
+	if err := c.do(ctx, http.MethodGet, c.repoPath("pulls/%d", number), nil, &pr); err != nil {
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// fileContent returns the content of the file at the given path of the
+// repository at ref. The contents API supports files of up to 1 MB.
+func (c *githubClient) fileContent(ctx context.Context, filePath, ref string) ([]byte, error) {
+	segments := strings.Split(filePath, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	var content struct {
+		Encoding string `json:"encoding"`
+		Content  string `json:"content"`
+	}
+	endpoint := c.repoPath("contents/%s?ref=%s", strings.Join(segments, "/"), url.QueryEscape(ref))
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, &content); err != nil {
+		return nil, err
+	}
+	if content.Encoding != "base64" {
+		return nil, fmt.Errorf("unsupported encoding %q of %s (files larger than 1 MB are not supported)", content.Encoding, filePath)
+	}
+
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+}
+
+// contentFetcher returns a sourceFetcher that reads the files of the
+// repository at ref via fileContent.
+func (c *githubClient) contentFetcher(ref string) sourceFetcher {
+	return func(ctx context.Context, filePath string) ([]byte, error) {
+		return c.fileContent(ctx, filePath, ref)
+	}
+}
+
+func (c *githubClient) updatePullRequestBody(ctx context.Context, number int, body string) error {
+	return c.do(ctx, http.MethodPatch, c.repoPath("pulls/%d", number), map[string]string{"body": body}, nil)
+}
+
+// pullRequestFiles returns the paths of all files of the pull request that
+// were added or modified. Removed files are omitted.
+func (c *githubClient) pullRequestFiles(ctx context.Context, number int) ([]string, error) {
+	type file struct {
+		Filename string `json:"filename"`
+		Status   string `json:"status"`
+	}
+
+	files, err := getAll[file](ctx, c, c.repoPath("pulls/%d/files", number))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, f := range files {
+		if f.Status != "removed" {
+			result = append(result, f.Filename)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *githubClient) issueComments(ctx context.Context, number int) ([]githubComment, error) {
+	return getAll[githubComment](ctx, c, c.repoPath("issues/%d/comments", number))
+}
+
+func (c *githubClient) createComment(ctx context.Context, number int, body string) error {
+	return c.do(ctx, http.MethodPost, c.repoPath("issues/%d/comments", number), map[string]string{"body": body}, nil)
+}
+
+func (c *githubClient) deleteComment(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, c.repoPath("issues/comments/%d", id), nil, nil)
+}
+
+// compareFiles returns the paths of all files that were added or modified
+// between the base and the head commit. Removed files are omitted. The
+// compare API lists at most 300 files.
+func (c *githubClient) compareFiles(ctx context.Context, base, head string) ([]string, error) {
+	var comparison struct {
+		Files []struct {
+			Filename string `json:"filename"`
+			Status   string `json:"status"`
+		} `json:"files"`
+	}
+	err := c.do(ctx, http.MethodGet, c.repoPath("compare/%s...%s", url.PathEscape(base), url.PathEscape(head)), nil, &comparison)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, f := range comparison.Files {
+		if f.Status != "removed" {
+			result = append(result, f.Filename)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *githubClient) commitComments(ctx context.Context, sha string) ([]githubComment, error) {
+	return getAll[githubComment](ctx, c, c.repoPath("commits/%s/comments", url.PathEscape(sha)))
+}
+
+func (c *githubClient) createCommitComment(ctx context.Context, sha, body string) error {
+	return c.do(ctx, http.MethodPost, c.repoPath("commits/%s/comments", url.PathEscape(sha)), map[string]string{"body": body}, nil)
+}
+
+func (c *githubClient) deleteCommitComment(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, c.repoPath("comments/%d", id), nil, nil)
+}
+
+// createCommitStatus sets the status of the given context for a commit. The
+// description is truncated to the 140 characters that GitHub accepts.
+func (c *githubClient) createCommitStatus(ctx context.Context, sha, state, statusContext, description, targetURL string) error {
+	if len(description) > 140 {
+		description = description[:137] + "..."
+	}
+
+	status := map[string]string{"state": state, "context": statusContext, "description": description}
+	if targetURL != "" {
+		status["target_url"] = targetURL
+	}
+
+	return c.do(ctx, http.MethodPost, c.repoPath("statuses/%s", url.PathEscape(sha)), status, nil)
+}
+
+// createLabel creates a label in the repository. It is no error if the label
+// already exists.
+func (c *githubClient) createLabel(ctx context.Context, name, description string) error {
+	err := c.do(ctx, http.MethodPost, c.repoPath("labels"), map[string]string{"name": name, "description": description}, nil)
+	if isGitHubStatus(err, http.StatusUnprocessableEntity) {
+		return nil // already exists
+	}
+
+	return err
+}
+
+func (c *githubClient) addLabel(ctx context.Context, number int, label string) error {
+	return c.do(ctx, http.MethodPost, c.repoPath("issues/%d/labels", number), map[string][]string{"labels": {label}}, nil)
+}
+
+func (c *githubClient) removeLabel(ctx context.Context, number int, label string) error {
+	return c.do(ctx, http.MethodDelete, c.repoPath("issues/%d/labels/%s", number, url.PathEscape(label)), nil, nil)
+}
+
+type githubIssue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// openIssues returns the open issues with the given label. Pull requests,
+// which the API lists as issues as well, are included.
+func (c *githubClient) openIssues(ctx context.Context, label string) ([]githubIssue, error) {
+	query := url.Values{"state": {"open"}, "labels": {label}}
+	return getAll[githubIssue](ctx, c, c.repoPath("issues?%s", query.Encode()))
+}
+
+func (c *githubClient) createIssue(ctx context.Context, title, body string, labels []string) (*githubIssue, error) {
+	var issue githubIssue
+	err := c.do(ctx, http.MethodPost, c.repoPath("issues"), map[string]any{"title": title, "body": body, "labels": labels}, &issue)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+func (c *githubClient) updateIssueBody(ctx context.Context, number int, body string) error {
+	return c.do(ctx, http.MethodPatch, c.repoPath("issues/%d", number), map[string]string{"body": body}, nil)
+}
+
+// addAssignees adds users to the assignees of an issue. GitHub silently
+// ignores users that cannot be assigned.
+func (c *githubClient) addAssignees(ctx context.Context, number int, logins []string) error {
+	return c.do(ctx, http.MethodPost, c.repoPath("issues/%d/assignees", number), map[string][]string{"assignees": logins}, nil)
+}
+
+// approvers returns the logins of all users that approved the pull request.
+func (c *githubClient) approvers(ctx context.Context, number int) ([]string, error) {
+	type review struct {
+		State string     `json:"state"`
+		User  githubUser `json:"user"`
+	}
+
+	reviews, err := getAll[review](ctx, c, c.repoPath("pulls/%d/reviews", number))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var result []string
+	for _, r := range reviews {
+		if r.State == "APPROVED" && !seen[r.User.Login] {
+			seen[r.User.Login] = true
+			result = append(result, r.User.Login)
+		}
+	}
+
+	return result, nil
+}
+
+// isActiveTeamMember returns true if the user is an active member of the
+// team given as "org/team-slug".
+func (c *githubClient) isActiveTeamMember(ctx context.Context, team, login string) (bool, error) {
+	org, slug, _ := strings.Cut(team, "/")
+
+	var membership struct {
+		State string `json:"state"`
+	}
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("orgs/%s/teams/%s/memberships/%s", org, slug, url.PathEscape(login)), nil, &membership)
+	if isGitHubStatus(err, http.StatusNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return membership.State == "active", nil
+}
+
+// requestTeamReview requests a review of the team given as "org/team-slug".
+func (c *githubClient) requestTeamReview(ctx context.Context, number int, team string) error {
+	_, slug, _ := strings.Cut(team, "/")
+	return c.do(ctx, http.MethodPost, c.repoPath("pulls/%d/requested_reviewers", number), map[string][]string{"team_reviewers": {slug}}, nil)
+}
+
+// createCheckRun creates a completed check run for the given commit.
+func (c *githubClient) createCheckRun(ctx context.Context, name, headSHA, conclusion, title, summary string) error {
+	return c.do(ctx, http.MethodPost, c.repoPath("check-runs"), map[string]any{
+		"name":       name,
+		"head_sha":   headSHA,
+		"status":     "completed",
+		"conclusion": conclusion,
+		"output":     map[string]string{"title": title, "summary": summary},
+	}, nil)
+}
+
+type githubCheckRun struct {
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// latestCheckRun returns the latest check run with the given name for the
+// given commit. If there is no such run, nil is returned.
+func (c *githubClient) latestCheckRun(ctx context.Context, sha, name string) (*githubCheckRun, error) {
+	query := url.Values{"check_name": {name}, "filter": {"latest"}, "per_page": {"1"}}
+	var runs struct {
+		CheckRuns []githubCheckRun `json:"check_runs"`
+	}
+	err := c.do(ctx, http.MethodGet, c.repoPath("commits/%s/check-runs?%s", url.PathEscape(sha), query.Encode()), nil, &runs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runs.CheckRuns) == 0 {
+		return nil, nil
+	}
+
+	return &runs.CheckRuns[0], nil
+}
+
+// reviewDeploymentProtectionRule approves or rejects a deployment via the
+// callback URL of a deployment_protection_rule webhook.
+func (c *githubClient) reviewDeploymentProtectionRule(ctx context.Context, callbackURL, environment, state, comment string) error {
+	return c.do(ctx, http.MethodPost, callbackURL, map[string]string{
+		"environment_name": environment,
+		"state":            state,
+		"comment":          comment,
+	}, nil)
+}
@@ -0,0 +1,139 @@
+package coverage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GradeWeights configures how much each component contributes to the
+// composite grade of a PR (see the -grade flag).
+type GradeWeights struct {
+	NewCode    float64 `json:"new_code"`    // coverage of the new code
+	Delta      float64 `json:"delta"`       // change of the overall coverage
+	ErrorPaths float64 `json:"error_paths"` // coverage of new code in "if err != nil" blocks
+}
+
+// defaultGradeWeights is used if the config file does not configure weights.
+var defaultGradeWeights = GradeWeights{NewCode: 0.5, Delta: 0.3, ErrorPaths: 0.2}
+
+// deltaPenalty is the number of points the delta component loses per
+// percentage point of decreased overall coverage.
+const deltaPenalty = 20
+
+// Grade is a single-letter rating of a PR that is computed from the weighted
+// scores (0 to 100) of its components. Components that do not apply (e.g.
+// error paths if the PR adds none) are left out.
+type Grade struct {
+	Letter     string
+	Score      float64
+	NewCode    *float64 `json:",omitempty"` // percentage of covered new statements
+	Delta      *float64 `json:",omitempty"` // 100 minus deltaPenalty points per percentage point of decrease
+	ErrorPaths *float64 `json:",omitempty"` // percentage of covered new statements in error paths
+}
+
+// GradeWeights returns the configured weights or the defaults.
+func (c *Config) GradeWeights() GradeWeights {
+	if c == nil || c.Grade == nil {
+		return defaultGradeWeights
+	}
+
+	return *c.Grade
+}
+
+// ComputeGrade returns the composite grade of the PR.
+func (r *Result) ComputeGrade() Grade {
+	weights := r.Config.GradeWeights()
+
+	var g Grade
+	var sum, totalWeight float64
+	add := func(score float64, weight float64) *float64 {
+		sum += score * weight
+		totalWeight += weight
+		return &score
+	}
+
+	if totalNew, coveredNew := r.calculateNewCodeCoverage(); totalNew > 0 {
+		g.NewCode = add(float64(coveredNew)/float64(totalNew)*100, weights.NewCode)
+	}
+
+	g.Delta = add(min(max(100+r.OverallCoverageDelta()*deltaPenalty, 0), 100), weights.Delta)
+
+	if total, covered := r.errorPathCoverage(); total > 0 {
+		g.ErrorPaths = add(float64(covered)/float64(total)*100, weights.ErrorPaths)
+	}
+
+	if totalWeight > 0 {
+		g.Score = sum / totalWeight
+	}
+	g.Letter = gradeLetter(g.Score)
+
+	return g
+}
+
+func gradeLetter(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// errorPathCoverage returns the number of new statements inside the body of
+// an "if err != nil" statement and how many of them are covered.
+func (r *Result) errorPathCoverage() (total, covered int64) {
+	if r.astMapper == nil {
+		return 0, 0
+	}
+
+	paths := map[string][]sourceRange{}
+	for _, block := range r.getNewCodeBlocks() {
+		ranges, ok := paths[block.FileName]
+		if !ok {
+			if path, found := r.source.find(block.FileName); found {
+				ranges, _ = r.astMapper.getErrorPaths(path)
+			}
+			paths[block.FileName] = ranges
+		}
+
+		for _, rng := range ranges {
+			if rng.contains(block.StartLine, block.StartCol) {
+				total += int64(block.NumStmt)
+				if block.Covered {
+					covered += int64(block.NumStmt)
+				}
+				break
+			}
+		}
+	}
+
+	return total, covered
+}
+
+// addGradeDetails explains how the grade in the title was computed.
+func (r *Result) addGradeDetails(report *strings.Builder) {
+	if r.Grade == nil {
+		return
+	}
+
+	g := *r.Grade
+	weights := r.Config.GradeWeights()
+
+	var parts []string
+	if g.NewCode != nil {
+		parts = append(parts, fmt.Sprintf("new code coverage %s (weight %g)", r.numbers().Percent(*g.NewCode), weights.NewCode))
+	}
+	parts = append(parts, fmt.Sprintf("coverage change %.2f (weight %g)", *g.Delta, weights.Delta))
+	if g.ErrorPaths != nil {
+		parts = append(parts, fmt.Sprintf("error path coverage %s (weight %g)", r.numbers().Percent(*g.ErrorPaths), weights.ErrorPaths))
+	}
+
+	fmt.Fprintf(report, "**Grade %s** (%.1f/100): %s.\n", g.Letter, g.Score, strings.Join(parts, ", "))
+	fmt.Fprintln(report)
+}
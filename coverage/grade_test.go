@@ -0,0 +1,80 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_ComputeGrade(t *testing.T) {
+	src := `package svc
+
+func Load(name string) (string, error) {
+	data, err := read(name)
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
+`
+	fileName := filepath.Join(t.TempDir(), "svc", "svc.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fileName), 0755))
+	require.NoError(t, os.WriteFile(fileName, []byte(src), 0644))
+
+	newCov := New([]*Profile{{
+		FileName: fileName,
+		Mode:     "set",
+		Blocks: []ProfileBlock{
+			{StartLine: 3, StartCol: 40, EndLine: 5, EndCol: 16, NumStmt: 2, Count: 1},
+			{StartLine: 6, StartCol: 3, EndLine: 6, EndCol: 17, NumStmt: 1, Count: 0}, // error path
+			{StartLine: 8, StartCol: 2, EndLine: 8, EndCol: 18, NumStmt: 1, Count: 1},
+		},
+		TotalStmt:   4,
+		CoveredStmt: 3,
+	}})
+
+	report := NewReport(New(nil), newCov, []string{fileName})
+	report.Graded = true
+
+	g := report.Analyze().ComputeGrade()
+	require.NotNil(t, g.NewCode)
+	require.NotNil(t, g.Delta)
+	require.NotNil(t, g.ErrorPaths)
+	assert.Equal(t, 75.0, *g.NewCode)
+	assert.Equal(t, 100.0, *g.Delta)
+	assert.Equal(t, 0.0, *g.ErrorPaths)
+	assert.InDelta(t, 0.5*75+0.3*100, g.Score, 0.001)
+	assert.Equal(t, "D", g.Letter)
+
+	assert.True(t, strings.HasSuffix(report.Analyze().Title(), " - Grade **D**"))
+	assert.Contains(t, report.Analyze().Markdown(), "**Grade D** (67.5/100): new code coverage 75.00% (weight 0.5), coverage change 100.00 (weight 0.3), error path coverage 0.00% (weight 0.2).")
+
+	report.Config = &Config{Grade: &GradeWeights{NewCode: 1}}
+	assert.Equal(t, "C", report.Analyze().ComputeGrade().Letter)
+}
+
+func TestReport_ComputeGrade_Delta(t *testing.T) {
+	oldCov := New([]*Profile{{FileName: "example.com/a/a.go", Blocks: []ProfileBlock{{StartLine: 1, EndLine: 2, NumStmt: 100, Count: 1}}, TotalStmt: 100, CoveredStmt: 100}})
+	newCov := New([]*Profile{{FileName: "example.com/a/a.go", Blocks: []ProfileBlock{{StartLine: 1, EndLine: 2, NumStmt: 100, Count: 1}}, TotalStmt: 102, CoveredStmt: 100}})
+
+	report := NewReport(oldCov, newCov, []string{"example.com/a/a.go"})
+	report.astMapper = nil
+
+	g := report.Analyze().ComputeGrade()
+	assert.Nil(t, g.NewCode)
+	assert.Nil(t, g.ErrorPaths)
+	assert.InDelta(t, 100-1.96*deltaPenalty, *g.Delta, 0.1)
+	assert.Equal(t, "D", g.Letter)
+
+	assert.NotContains(t, report.Analyze().Title(), "Grade")
+}
+
+func TestGradeLetter(t *testing.T) {
+	for score, letter := range map[float64]string{100: "A", 90: "A", 89.9: "B", 80: "B", 70: "C", 60: "D", 59.9: "F", 0: "F"} {
+		assert.Equal(t, letter, gradeLetter(score), "score %v", score)
+	}
+}
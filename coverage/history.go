@@ -0,0 +1,217 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is the coverage of a single commit as stored in the history.
+type Snapshot struct {
+	Commit      string               `json:"commit"`
+	Branch      string               `json:"branch,omitempty"`
+	Time        time.Time            `json:"time"`
+	TotalStmt   int64                `json:"total"`
+	CoveredStmt int64                `json:"covered"`
+	Packages    map[string]StmtCount `json:"packages,omitempty"`
+	Files       map[string]StmtCount `json:"files,omitempty"`
+
+	// Moves maps the old names of the files that were renamed or moved by
+	// the commit to their new names (see MovedFiles). When the history is
+	// read, the coverage of these files in all earlier snapshots is
+	// transferred to the new names and packages.
+	Moves map[string]string `json:"moves,omitempty"`
+}
+
+// StmtCount is the number of total and covered statements of a package or
+// file in a Snapshot.
+type StmtCount struct {
+	Total   int64 `json:"total"`
+	Covered int64 `json:"covered"`
+}
+
+// Percent returns the coverage in percent or 0 if there are no statements.
+func (c StmtCount) Percent() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+
+	return float64(c.Covered) / float64(c.Total) * 100
+}
+
+// Percent returns the overall coverage of the snapshot in percent.
+func (s Snapshot) Percent() float64 {
+	return StmtCount{Total: s.TotalStmt, Covered: s.CoveredStmt}.Percent()
+}
+
+// NewSnapshot creates a Snapshot of the given coverage.
+func NewSnapshot(cov *Coverage, commit, branch string, t time.Time) Snapshot {
+	s := Snapshot{
+		Commit:      commit,
+		Branch:      branch,
+		Time:        t.UTC(),
+		TotalStmt:   cov.TotalStmt,
+		CoveredStmt: cov.CoveredStmt,
+		Packages:    map[string]StmtCount{},
+		Files:       map[string]StmtCount{},
+	}
+
+	for pkg, pkgCov := range cov.ByPackage() {
+		s.Packages[pkg] = StmtCount{Total: pkgCov.TotalStmt, Covered: pkgCov.CoveredStmt}
+	}
+	for name, p := range cov.Files {
+		s.Files[name] = StmtCount{Total: p.TotalStmt, Covered: p.CoveredStmt}
+	}
+
+	return s
+}
+
+// MovedFiles returns the files of the coverage profile that were renamed or
+// moved according to the diff, mapped from their old to their new name. The
+// paths of the diff are relative to the repository, so the old names get the
+// same prefix as the new names in the profile (e.g. "github.com/user/repo/").
+func MovedFiles(cov *Coverage, diffInfo *DiffInfo) map[string]string {
+	moves := map[string]string{}
+	for fileName := range cov.Files {
+		fileDiff, diffPath := diffInfo.matchFileDiff(fileName)
+		if fileDiff == nil || fileDiff.RenamedFrom == "" || !strings.HasSuffix(fileName, diffPath) {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(fileName, diffPath)
+		moves[prefix+fileDiff.RenamedFrom] = fileName
+	}
+
+	return moves
+}
+
+// transferMoves moves the coverage of the files in moves (see Snapshot.Moves)
+// to their new names and packages, so that the trends of files and packages
+// are not interrupted by the move. Files that already exist under their new
+// name are left as they are.
+func (s *Snapshot) transferMoves(moves map[string]string) {
+	for oldName, newName := range moves {
+		c, ok := s.Files[oldName]
+		if _, exists := s.Files[newName]; !ok || exists {
+			continue
+		}
+
+		delete(s.Files, oldName)
+		s.Files[newName] = c
+
+		oldPkg, newPkg := path.Dir(oldName), path.Dir(newName)
+		if oldPkg == newPkg || s.Packages == nil {
+			continue
+		}
+
+		if rest, ok := s.Packages[oldPkg]; ok {
+			rest.Total -= c.Total
+			rest.Covered -= c.Covered
+			if rest.Total <= 0 {
+				delete(s.Packages, oldPkg)
+			} else {
+				s.Packages[oldPkg] = rest
+			}
+		}
+
+		pkg := s.Packages[newPkg]
+		pkg.Total += c.Total
+		pkg.Covered += c.Covered
+		s.Packages[newPkg] = pkg
+	}
+}
+
+// History stores coverage snapshots in a JSON Lines file that is only ever
+// appended to, which makes it easy to keep it in a Git branch or to cache it
+// between CI runs.
+type History struct {
+	path string
+}
+
+// OpenHistory returns the History that is stored in the file at the given
+// path. The file is created when the first snapshot is added.
+func OpenHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Add appends the given snapshot to the history.
+func (h *History) Add(s Snapshot) error {
+	if s.Commit == "" {
+		return errors.New("snapshot has no commit")
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Snapshots returns all snapshots of the history ordered by time. If a commit
+// was recorded multiple times, only the last snapshot is returned. The moves
+// of all snapshots are applied to the snapshots before them (see
+// Snapshot.Moves). A history file that does not exist yet is treated as empty
+// history.
+func (h *History) Snapshots() ([]Snapshot, error) {
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	var snapshots []Snapshot
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var s Snapshot
+		if err := json.Unmarshal(line, &s); err != nil {
+			return nil, fmt.Errorf("invalid snapshot in line %d of %s: %w", i+1, h.path, err)
+		}
+
+		if j, ok := index[s.Commit]; ok {
+			snapshots[j] = s
+			continue
+		}
+
+		index[s.Commit] = len(snapshots)
+		snapshots = append(snapshots, s)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+
+	for i, s := range snapshots {
+		if len(s.Moves) == 0 {
+			continue
+		}
+		for j := range snapshots[:i] {
+			snapshots[j].transferMoves(s.Moves)
+		}
+	}
+
+	return snapshots, nil
+}
@@ -0,0 +1,188 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var historyUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %[1]s history record [OPTIONS] <COVERAGE_FILE>
+       %[1]s history backfill [OPTIONS] <DIRECTORY>
+       %[1]s history import [OPTIONS] <MODULE> <VERSION...>
+       %[1]s history check [OPTIONS]
+
+COMMANDS:
+  record    Add the coverage of a commit (usually on the main branch) to the
+            history file. The history is used by the "site" command to render
+            coverage trends. With the -diff of the commit, the coverage trend
+            of renamed or moved files continues at their new location.
+  backfill  Add all coverage files of a directory to the history file. The
+            files must be named after their commit SHA, their date (e.g.
+            2024-01-31 or 2024-01-31T10-00-00Z) or both, separated by an
+            underscore (e.g. 2024-01-31_3f2a9c1.out). Missing commits and
+            dates are looked up via git. Commits that are already part of
+            the history are skipped.
+  import    Add the coverage of released versions of a module to the history
+            file. Each version is downloaded from the Go module proxy (the
+            first proxy of GOPROXY or -proxy) into a temporary directory and
+            its tests are run with "go test -coverprofile". The snapshots use
+            the release time and the commit of the version if the proxy
+            knows it. Versions that are already part of the history are
+            skipped, so the import can be repeated after failures.
+  check     Check if the overall or package coverage of the latest snapshot
+            dropped by more than -sigma standard deviations of the previous
+            snapshots, e.g. because tests were deleted accidentally or a build
+            tag is missing. Anomalies are printed and posted to the -notify
+            webhooks with the severity "anomaly".
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runHistoryCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, historyUsage)
+		return errors.New("missing history command")
+	}
+
+	fs := flag.NewFlagSet("history "+args[0], flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, historyUsage)
+		fs.PrintDefaults()
+	}
+
+	switch args[0] {
+	case "record":
+		historyFile := fs.String("history", "coverage-history.jsonl", "path to the history file")
+		commit := fs.String("commit", "", "the commit SHA of the coverage profile (required)")
+		branch := fs.String("branch", "", "the branch of the commit")
+		timestamp := fs.String("time", "", "the time of the commit in RFC 3339 format (default: now)")
+		trim := fs.String("trim", "", "trim a prefix from all file and package paths")
+		excludeWiring := fs.Bool("exclude-wiring", false, "exclude func main and dependency injection wiring code from the coverage calculation")
+		diffFile := fs.String("diff", "", "path to the git diff of the commit (e.g. git diff HEAD^ HEAD); the coverage trend of files it renames or moves is transferred to their new location")
+		_ = fs.Parse(args[1:])
+
+		if fs.NArg() != 1 || *commit == "" {
+			fs.Usage()
+			return errors.New("expected a coverage file and the -commit flag")
+		}
+
+		t := time.Now()
+		if *timestamp != "" {
+			var err error
+			t, err = time.Parse(time.RFC3339, *timestamp)
+			if err != nil {
+				return fmt.Errorf("invalid time: %w", err)
+			}
+		}
+
+		cov, err := ParseCoverageContext(ctx, fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to parse coverage: %w", err)
+		}
+
+		for _, find := range exclusionFinders(options{excludeWiring: *excludeWiring}) {
+			if err := applySourceExclusions(sourceTree{}, cov, nil, find, nil); err != nil {
+				return fmt.Errorf("failed to apply exclusions: %w", err)
+			}
+		}
+
+		if *trim != "" {
+			cov.TrimPrefix(*trim)
+		}
+
+		snapshot := NewSnapshot(cov, *commit, *branch, t)
+		if *diffFile != "" {
+			diffInfo, err := parseUnifiedDiffContext(ctx, *diffFile, parseOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to parse diff: %w", err)
+			}
+			if moves := MovedFiles(cov, diffInfo); len(moves) > 0 {
+				snapshot.Moves = moves
+			}
+		}
+
+		return OpenHistory(*historyFile).Add(snapshot)
+	case "backfill":
+		historyFile := fs.String("history", "coverage-history.jsonl", "path to the history file")
+		branch := fs.String("branch", "", "the branch of the commits, also used to look up the commits of dates (default: HEAD)")
+		trim := fs.String("trim", "", "trim a prefix from all file and package paths")
+		_ = fs.Parse(args[1:])
+
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return errors.New("expected a directory of coverage files")
+		}
+
+		added, skipped, err := OpenHistory(*historyFile).backfill(ctx, fs.Arg(0), localGit{}, *branch, *trim)
+		fmt.Fprintf(os.Stderr, "Added %d snapshots to %s (%d already recorded)\n", added, *historyFile, skipped)
+		return err
+	case "import":
+		historyFile := fs.String("history", "coverage-history.jsonl", "path to the history file")
+		branch := fs.String("branch", "", "the branch of the snapshots")
+		trim := fs.String("trim", "", "trim a prefix from all file and package paths")
+		proxyURL := fs.String("proxy", "", "URL of the Go module proxy (default: the first proxy of GOPROXY or "+defaultModuleProxy+")")
+		_ = fs.Parse(args[1:])
+
+		if fs.NArg() < 2 {
+			fs.Usage()
+			return errors.New("expected a module path and at least one version")
+		}
+
+		proxy := moduleProxyFromEnv(os.Getenv("GOPROXY"))
+		if *proxyURL != "" {
+			proxy = moduleProxy{url: strings.TrimSuffix(*proxyURL, "/")}
+		}
+
+		added, skipped, err := OpenHistory(*historyFile).importReleases(ctx, proxy, goTestCoverage, fs.Arg(0), fs.Args()[1:], *branch, *trim, os.Stderr)
+		fmt.Fprintf(os.Stderr, "Added %d snapshots to %s (%d already recorded)\n", added, *historyFile, skipped)
+		return err
+	case "check":
+		historyFile := fs.String("history", "coverage-history.jsonl", "path to the history file")
+		branch := fs.String("branch", "", "only consider snapshots of this branch")
+		window := fs.Int("window", 20, "number of previous snapshots to compare with")
+		sigma := fs.Float64("sigma", 3, "number of standard deviations a drop must exceed to be an anomaly")
+		minDrop := fs.Float64("min-drop", 0.5, "minimum drop in percentage points to be an anomaly")
+		fail := fs.Bool("fail", false, "exit with an error if an anomaly was detected")
+		var webhooks []string
+		fs.Func("notify", "URL of a webhook that anomalies are posted to as JSON (can be repeated)", func(s string) error {
+			webhooks = append(webhooks, s)
+			return nil
+		})
+		_ = fs.Parse(args[1:])
+
+		snapshots, err := OpenHistory(*historyFile).Snapshots()
+		if err != nil {
+			return err
+		}
+
+		anomalies := DetectAnomalies(snapshots, AnomalyOptions{Branch: *branch, Window: *window, Sigma: *sigma, MinDrop: *minDrop})
+		if len(anomalies) == 0 {
+			fmt.Println("No coverage anomaly detected")
+			return nil
+		}
+
+		for _, a := range anomalies {
+			fmt.Println("ANOMALY:", a)
+		}
+		for _, url := range webhooks {
+			if err := notifyAnomalies(ctx, http.DefaultClient, url, anomalies); err != nil {
+				return err
+			}
+		}
+		if *fail {
+			return fmt.Errorf("detected %d coverage anomalies", len(anomalies))
+		}
+
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown history command %q", args[0])
+	}
+}
@@ -0,0 +1,112 @@
+package coverage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	cov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	s := NewSnapshot(cov, "abc", "main", ts)
+
+	assert.Equal(t, "abc", s.Commit)
+	assert.Equal(t, "main", s.Branch)
+	assert.Equal(t, ts.UTC(), s.Time)
+	assert.Equal(t, cov.TotalStmt, s.TotalStmt)
+	assert.Equal(t, cov.CoveredStmt, s.CoveredStmt)
+	assert.Equal(t, StmtCount{Total: 102, Covered: 92}, s.Packages["github.com/fgrosse/prioqueue"])
+	assert.Len(t, s.Files, len(cov.Files))
+	assert.InDelta(t, cov.Percent(), s.Percent(), 0.001)
+}
+
+func TestHistory(t *testing.T) {
+	h := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	snapshots, err := h.Snapshots()
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	require.NoError(t, h.Add(Snapshot{Commit: "b", Time: day(2), TotalStmt: 10, CoveredStmt: 5}))
+	require.NoError(t, h.Add(Snapshot{Commit: "a", Time: day(1), TotalStmt: 10, CoveredStmt: 4}))
+	require.NoError(t, h.Add(Snapshot{Commit: "b", Time: day(2), TotalStmt: 10, CoveredStmt: 6}))
+	assert.Error(t, h.Add(Snapshot{}))
+
+	snapshots, err = h.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "a", snapshots[0].Commit)
+	assert.Equal(t, "b", snapshots[1].Commit)
+	assert.Equal(t, int64(6), snapshots[1].CoveredStmt, "the last snapshot of a commit should win")
+}
+
+func TestHistory_Moves(t *testing.T) {
+	h := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, h.Add(Snapshot{
+		Commit: "a", Time: day(1), TotalStmt: 30, CoveredStmt: 20,
+		Packages: map[string]StmtCount{"ex.com/old": {Total: 30, Covered: 20}},
+		Files: map[string]StmtCount{
+			"ex.com/old/util.go": {Total: 10, Covered: 9},
+			"ex.com/old/old.go":  {Total: 20, Covered: 11},
+		},
+	}))
+	require.NoError(t, h.Add(Snapshot{
+		Commit: "b", Time: day(2), TotalStmt: 30, CoveredStmt: 20,
+		Packages: map[string]StmtCount{"ex.com/old": {Total: 20, Covered: 11}, "ex.com/new": {Total: 10, Covered: 9}},
+		Files: map[string]StmtCount{
+			"ex.com/new/util.go": {Total: 10, Covered: 9},
+			"ex.com/old/old.go":  {Total: 20, Covered: 11},
+		},
+		Moves: map[string]string{"ex.com/old/util.go": "ex.com/new/util.go"},
+	}))
+	require.NoError(t, h.Add(Snapshot{
+		Commit: "c", Time: day(3), TotalStmt: 30, CoveredStmt: 20,
+		Packages: map[string]StmtCount{"ex.com/new": {Total: 30, Covered: 20}},
+		Files: map[string]StmtCount{
+			"ex.com/new/util.go": {Total: 10, Covered: 9},
+			"ex.com/new/new.go":  {Total: 20, Covered: 11},
+		},
+		Moves: map[string]string{"ex.com/old/old.go": "ex.com/new/new.go"},
+	}))
+
+	snapshots, err := h.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+
+	// The trail of both files continues at their new location, so the
+	// packages do not show a drop or spike at the commits that moved them.
+	for _, s := range snapshots {
+		assert.Equal(t, map[string]StmtCount{
+			"ex.com/new/util.go": {Total: 10, Covered: 9},
+			"ex.com/new/new.go":  {Total: 20, Covered: 11},
+		}, s.Files, s.Commit)
+		assert.Equal(t, map[string]StmtCount{"ex.com/new": {Total: 30, Covered: 20}}, s.Packages, s.Commit)
+	}
+}
+
+func TestMovedFiles(t *testing.T) {
+	cov := New([]*Profile{
+		newTestProfile("github.com/acme/app/pkg/new/util.go"),
+		newTestProfile("github.com/acme/app/pkg/b/b.go"),
+		newTestProfile("github.com/acme/app/c.go"),
+	})
+	diffInfo := &DiffInfo{Files: map[string]*FileDiff{
+		"pkg/new/util.go": {RenamedFrom: "pkg/old/util.go"},
+		"pkg/b/b.go":      {RenamedFrom: "pkg/b/a.go", AddedLines: map[int]bool{1: true}},
+		"c.go":            {AddedLines: map[int]bool{1: true}},
+	}}
+
+	assert.Equal(t, map[string]string{
+		"github.com/acme/app/pkg/old/util.go": "github.com/acme/app/pkg/new/util.go",
+		"github.com/acme/app/pkg/b/a.go":      "github.com/acme/app/pkg/b/b.go",
+	}, MovedFiles(cov, diffInfo))
+}
@@ -0,0 +1,391 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"os"
+	"strings"
+)
+
+// htmlReport is the data that is passed to htmlTemplate.
+type htmlReport struct {
+	Theme       string
+	Title       string
+	OldCoverage string
+	NewCoverage string
+	Delta       string
+	NewCode     string // coverage of new code or empty if there is no new code
+	NewStmt     string
+	NewCovered  string
+	Warning     string
+	Files       []htmlFile
+}
+
+type htmlFile struct {
+	Name       string
+	Coverage   string
+	Delta      string
+	Snippets   [][]htmlLine // runs of consecutive new lines
+	Source     []htmlLine   // the full file or nil if the source is not available
+	NewMissing bool         // true if there is new code but the source is not available
+}
+
+type htmlLine struct {
+	Num      int
+	Code     template.HTML // syntax highlighted source code
+	Coverage string        // "covered", "uncovered" or empty if the line has no statements
+	New      bool          // true if the line is new in this change
+}
+
+// HTML themes that can be selected via Report.HTMLTheme.
+const (
+	htmlThemeAuto  = "auto" // follows the prefers-color-scheme of the browser
+	htmlThemeLight = "light"
+	htmlThemeDark  = "dark"
+)
+
+// HTML renders the report as standalone HTML page that shows the new code and
+// the full source of each changed file with syntax highlighting and an overlay
+// of the covered and uncovered lines.
+func (r *Result) HTML() string {
+	return r.executeHTML("page")
+}
+
+// HTMLFragment renders the same content as HTML but as a single <div> element
+// that can be embedded into other pages (e.g. internal dashboards). All colors
+// are defined as CSS custom properties (e.g. --gcr-bg) on the
+// .go-coverage-report element so they can be overridden by the embedding page.
+func (r *Result) HTMLFragment() string {
+	return r.executeHTML("fragment")
+}
+
+func (r *Result) executeHTML(name string) string {
+	var buf bytes.Buffer
+	err := htmlTemplate.ExecuteTemplate(&buf, name, r.htmlReport())
+	if err != nil {
+		panic(err) // should never happen since the template is static
+	}
+
+	return buf.String()
+}
+
+func (r *Result) htmlReport() htmlReport {
+	oldCov, newCov, _, _ := r.OverallCoverageInfo()
+	data := htmlReport{
+		Theme:       r.HTMLTheme,
+		Title:       strings.TrimPrefix(strings.ReplaceAll(r.Title(), "**", ""), "### "),
+		OldCoverage: oldCov,
+		NewCoverage: newCov,
+		Delta:       r.numbers().Delta(r.OverallCoverageDelta()),
+	}
+
+	if data.Theme == "" {
+		data.Theme = htmlThemeAuto
+	}
+
+	prCov, _, totalNew, coveredNew := r.PRCoverageInfo()
+	if totalNew > 0 {
+		data.NewCode, data.NewStmt, data.NewCovered = prCov, r.numbers().Count(totalNew), r.numbers().Count(coveredNew)
+		newCodeCoverage := float64(coveredNew) / float64(totalNew) * 100
+		if r.MinCoverage > 0 && newCodeCoverage < r.MinCoverage {
+			data.Warning = fmt.Sprintf("New code coverage is %s, which is below the required threshold of %s.", r.numbers().Percent(newCodeCoverage), r.numbers().Percent(r.MinCoverage))
+		}
+	}
+
+	fileBlocks := map[string][]NewCodeBlock{}
+	for _, block := range r.getNewCodeBlocks() {
+		fileBlocks[block.FileName] = append(fileBlocks[block.FileName], block)
+	}
+
+	for _, name := range r.ChangedFiles {
+		newProfile := r.New.Files[name]
+		if newProfile == nil || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		var oldPercent float64
+		if oldProfile := r.Old.Files[name]; oldProfile != nil {
+			oldPercent = oldProfile.CoveragePercent()
+		}
+
+		newPercent := newProfile.CoveragePercent()
+		file := htmlFile{
+			Name:     name,
+			Coverage: r.numbers().Percent(newPercent),
+			Delta:    r.numbers().Delta(newPercent - oldPercent),
+		}
+
+		sourceLines, _ := r.source.readLines(name)
+		newLines := r.newLineCoverage(name, sourceLines, fileBlocks[name])
+		file.Source = htmlSourceLines(r.source, name, newProfile, newLines)
+		file.Snippets = htmlSnippets(file.Source)
+		file.NewMissing = file.Source == nil && len(newLines) > 0
+
+		data.Files = append(data.Files, file)
+	}
+
+	return data
+}
+
+// htmlSourceLines returns all lines of the given file with their coverage
+// status or nil if the source file cannot be found.
+func htmlSourceLines(tree sourceTree, fileName string, profile *Profile, newLines map[int]bool) []htmlLine {
+	path, ok := tree.find(fileName)
+	if !ok {
+		return nil
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	code := highlightGo(trimBOM(src))
+	coverage := lineCoverage(profile, len(code))
+	lines := make([]htmlLine, len(code))
+	for i := range code {
+		num := i + 1
+		_, isNew := newLines[num]
+		lines[i] = htmlLine{Num: num, Code: code[i], New: isNew}
+		if covered, ok := coverage[num]; ok {
+			lines[i].Coverage = "uncovered"
+			if covered {
+				lines[i].Coverage = "covered"
+			}
+		}
+		if covered, ok := newLines[num]; ok {
+			// The coverage of new lines is determined like in the markdown report.
+			lines[i].Coverage = "uncovered"
+			if covered {
+				lines[i].Coverage = "covered"
+			}
+		}
+	}
+
+	return lines
+}
+
+// htmlSnippets returns each run of consecutive new lines.
+func htmlSnippets(lines []htmlLine) [][]htmlLine {
+	var snippets [][]htmlLine
+	var current []htmlLine
+	for _, line := range lines {
+		if line.New {
+			current = append(current, line)
+			continue
+		}
+		if len(current) > 0 {
+			snippets = append(snippets, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		snippets = append(snippets, current)
+	}
+
+	return snippets
+}
+
+// highlightGo tokenizes the given Go source code and returns each line as
+// HTML with spans for keywords, literals and comments. Tokens that span
+// multiple lines (e.g. raw strings or block comments) are split so that each
+// line is valid HTML on its own.
+func highlightGo(src []byte) []template.HTML {
+	type span struct {
+		start, end int
+		class      string
+	}
+
+	var spans []span
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		var class string
+		switch {
+		case tok.IsKeyword():
+			class, lit = "kw", tok.String()
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		case tok == token.COMMENT:
+			class = "com"
+		default:
+			continue
+		}
+
+		start := file.Offset(pos)
+		end := start + len(lit)
+		if end > len(src) || string(src[start:end]) != lit {
+			continue // e.g. comments with carriage returns that were removed by the scanner
+		}
+		spans = append(spans, span{start, end, class})
+	}
+
+	var lines []template.HTML
+	var line strings.Builder
+	open := ""
+	flush := func() {
+		if open != "" {
+			line.WriteString("</span>")
+		}
+		lines = append(lines, template.HTML(line.String()))
+		line.Reset()
+		if open != "" {
+			fmt.Fprintf(&line, `<span class="%s">`, open)
+		}
+	}
+
+	text := string(src)
+	text = strings.TrimSuffix(text, "\n")
+	offset := 0
+	for _, sp := range spans {
+		writeHTMLText(&line, text, offset, min(sp.start, len(text)), flush)
+		if sp.start >= len(text) {
+			break
+		}
+		open = sp.class
+		fmt.Fprintf(&line, `<span class="%s">`, open)
+		writeHTMLText(&line, text, sp.start, min(sp.end, len(text)), flush)
+		line.WriteString("</span>")
+		open = ""
+		offset = min(sp.end, len(text))
+	}
+	writeHTMLText(&line, text, offset, len(text), flush)
+	lines = append(lines, template.HTML(line.String()))
+
+	return lines
+}
+
+// writeHTMLText writes the escaped text between start and end to line and
+// calls newLine for each line break.
+func writeHTMLText(line *strings.Builder, text string, start, end int, newLine func()) {
+	for i, part := range strings.Split(text[start:end], "\n") {
+		if i > 0 {
+			newLine()
+		}
+		line.WriteString(template.HTMLEscapeString(strings.TrimSuffix(part, "\r")))
+	}
+}
+
+var htmlTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}</title>
+<style>
+body { margin: 0; }
+.go-coverage-report { min-height: 100vh; box-sizing: border-box; padding: 2em; }
+</style>
+</head>
+<body>
+{{ template "fragment" . }}
+</body>
+</html>
+{{ define "fragment" -}}
+<div class="go-coverage-report" data-theme="{{ .Theme }}">
+<style>
+.go-coverage-report {
+  --gcr-font: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+  --gcr-mono-font: ui-monospace, SFMono-Regular, Menlo, monospace;
+  --gcr-fg: #1f2328;
+  --gcr-bg: #ffffff;
+  --gcr-border: #d0d7de;
+  --gcr-muted: #6e7781;
+  --gcr-covered-bg: #dafbe1;
+  --gcr-uncovered-bg: #ffebe9;
+  --gcr-new-marker: #0969da;
+  --gcr-warning-border: #9a6700;
+  --gcr-warning-bg: #fff8c5;
+  --gcr-keyword: #cf222e;
+  --gcr-string: #0a3069;
+  --gcr-number: #0550ae;
+  --gcr-comment: #6e7781;
+}
+.go-coverage-report[data-theme="dark"] {
+{{- template "dark" }}
+}
+@media (prefers-color-scheme: dark) {
+  .go-coverage-report[data-theme="auto"] {
+  {{- template "dark" }}
+  }
+}
+.go-coverage-report { font-family: var(--gcr-font); color: var(--gcr-fg); background: var(--gcr-bg); }
+.go-coverage-report table.summary { border-collapse: collapse; margin-bottom: 1em; }
+.go-coverage-report table.summary th, .go-coverage-report table.summary td { border: 1px solid var(--gcr-border); padding: 4px 12px; text-align: left; }
+.go-coverage-report .warning { border-left: 4px solid var(--gcr-warning-border); background: var(--gcr-warning-bg); padding: 8px 12px; }
+.go-coverage-report table.code { border-collapse: collapse; font-family: var(--gcr-mono-font); font-size: 12px; width: 100%; margin-bottom: 1em; }
+.go-coverage-report table.code td { padding: 0 8px; white-space: pre; }
+.go-coverage-report table.code td.num { color: var(--gcr-muted); text-align: right; user-select: none; width: 1%; }
+.go-coverage-report tr.covered td.code { background: var(--gcr-covered-bg); }
+.go-coverage-report tr.uncovered td.code { background: var(--gcr-uncovered-bg); }
+.go-coverage-report tr.new td.num { border-right: 3px solid var(--gcr-new-marker); }
+.go-coverage-report .kw { color: var(--gcr-keyword); }
+.go-coverage-report .str { color: var(--gcr-string); }
+.go-coverage-report .num { color: var(--gcr-number); }
+.go-coverage-report .com { color: var(--gcr-comment); font-style: italic; }
+</style>
+<h1>{{ .Title }}</h1>
+<table class="summary">
+<tr><th>Metric</th><th>Old Coverage</th><th>New Coverage</th><th>Change</th></tr>
+<tr><td>Total</td><td>{{ .OldCoverage }}</td><td>{{ .NewCoverage }}</td><td>{{ .Delta }}</td></tr>
+{{- if .NewCode }}
+<tr><td>New Code</td><td>N/A</td><td>{{ .NewCode }}</td><td>{{ .NewCovered }}/{{ .NewStmt }} statements</td></tr>
+{{- end }}
+</table>
+{{- if .Warning }}
+<p class="warning"><strong>Coverage threshold not met:</strong> {{ .Warning }}</p>
+{{- end }}
+{{- range .Files }}
+<h2>{{ .Name }} <small>{{ .Coverage }} ({{ .Delta }})</small></h2>
+{{- if .NewMissing }}
+<p>The source code of this file is not available.</p>
+{{- end }}
+{{- range .Snippets }}
+<table class="code">
+{{- range . }}
+<tr class="{{ .Coverage }} new"><td class="num">{{ .Num }}</td><td class="code">{{ .Code }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .Source }}
+<details>
+<summary>Full file</summary>
+<table class="code">
+{{- range .Source }}
+<tr class="{{ .Coverage }}{{ if .New }} new{{ end }}"><td class="num">{{ .Num }}</td><td class="code">{{ .Code }}</td></tr>
+{{- end }}
+</table>
+</details>
+{{- end }}
+{{- end }}
+</div>
+{{- end }}
+{{ define "dark" }}
+  --gcr-fg: #e6edf3;
+  --gcr-bg: #0d1117;
+  --gcr-border: #30363d;
+  --gcr-muted: #8b949e;
+  --gcr-covered-bg: #12261e;
+  --gcr-uncovered-bg: #2d1517;
+  --gcr-new-marker: #2f81f7;
+  --gcr-warning-border: #9e6a03;
+  --gcr-warning-bg: #272115;
+  --gcr-keyword: #ff7b72;
+  --gcr-string: #a5d6ff;
+  --gcr-number: #79c0ff;
+  --gcr-comment: #8b949e;
+{{- end }}
+`))
@@ -0,0 +1,98 @@
+package coverage
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlightGo(t *testing.T) {
+	src := "package foo\n\n// Answer is 42.\nvar s = `a\n<b>` + \"c\" // done\n"
+
+	assert.Equal(t, []template.HTML{
+		`<span class="kw">package</span> foo`,
+		``,
+		`<span class="com">// Answer is 42.</span>`,
+		`<span class="kw">var</span> s = <span class="str">` + "`a</span>",
+		`<span class="str">&lt;b&gt;` + "`</span>" + ` + <span class="str">&#34;c&#34;</span> <span class="com">// done</span>`,
+	}, highlightGo([]byte(src)))
+}
+
+func TestReport_HTML(t *testing.T) {
+	report := newTestReport(t,
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		"github.com/pentohq/pento",
+		"testdata/04-diff.patch",
+	)
+
+	data := report.Analyze().htmlReport()
+	require.Len(t, data.Files, 1)
+
+	file := data.Files[0]
+	assert.Equal(t, "github.com/pentohq/pento/pkg/age/age.go", file.Name)
+	assert.False(t, file.NewMissing)
+	require.NotEmpty(t, file.Source)
+	require.NotEmpty(t, file.Snippets)
+
+	for _, snippet := range file.Snippets {
+		for _, line := range snippet {
+			assert.True(t, line.New)
+			assert.Equal(t, file.Source[line.Num-1], line)
+		}
+	}
+
+	days := file.Source[55] // "return 0" of the new Days function
+	assert.Equal(t, 56, days.Num)
+	assert.True(t, days.New)
+	assert.Equal(t, "uncovered", days.Coverage)
+	assert.Equal(t, template.HTML("\t\t<span class=\"kw\">return</span> <span class=\"num\">0</span>"), days.Code)
+
+	html := report.Analyze().HTML()
+	assert.Contains(t, html, "<!DOCTYPE html>")
+	assert.Contains(t, html, `<tr class="uncovered new"><td class="num">56</td>`)
+}
+
+func TestReport_HTML_MissingSource(t *testing.T) {
+	report := newTestReport(t,
+		"testdata/01-old-coverage.txt",
+		"testdata/01-new-coverage.txt",
+		"testdata/01-changed-files.json",
+		"github.com/fgrosse/prioqueue",
+		"testdata/01-diff.patch",
+	)
+
+	files := report.Analyze().htmlReport().Files
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		assert.Nil(t, file.Source)
+		assert.Empty(t, file.Snippets)
+	}
+	assert.Contains(t, report.Analyze().HTML(), "The source code of this file is not available.")
+}
+
+func TestReport_HTMLFragment(t *testing.T) {
+	report := newTestReport(t,
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		"github.com/pentohq/pento",
+		"testdata/04-diff.patch",
+	)
+
+	fragment := report.Analyze().HTMLFragment()
+	assert.True(t, strings.HasPrefix(fragment, `<div class="go-coverage-report" data-theme="auto">`))
+	assert.True(t, strings.HasSuffix(fragment, "</div>"))
+	assert.NotContains(t, fragment, "<html")
+	assert.Contains(t, fragment, "--gcr-bg:")
+
+	report.HTMLTheme = htmlThemeDark
+	page := report.Analyze().HTML()
+	assert.Contains(t, page, "<html")
+	assert.Contains(t, page, report.Analyze().HTMLFragment())
+	assert.Contains(t, page, `data-theme="dark"`)
+}
@@ -0,0 +1,94 @@
+package coverage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxIdenticalProfilesFiles limits the number of changed files that are
+// listed in the warning about identical coverage profiles.
+const maxIdenticalProfilesFiles = 5
+
+// IdenticalProfiles returns the changed Go files of the PR if the old and new
+// coverage profiles contain exactly the same blocks although the PR changes
+// the code of these files. Changed code moves or adds blocks, so identical
+// profiles usually mean that the same artifact was used for both sides, e.g.
+// because the artifact of the main branch was downloaded twice. Test files and
+// files without coverage data are ignored, and with a diff only files with
+// added lines count as changed.
+func (r *Report) IdenticalProfiles() []string {
+	var changed []string
+	for _, f := range r.ChangedFiles {
+		if strings.HasSuffix(f, "_test.go") || r.New.Files[f] == nil {
+			continue
+		}
+		if r.DiffInfo != nil {
+			if d := r.DiffInfo.findFileDiff(f); d == nil || len(d.AddedLines) == 0 {
+				continue
+			}
+		}
+		changed = append(changed, f)
+	}
+
+	if len(changed) == 0 || !sameBlocks(r.Old, r.New) {
+		return nil
+	}
+
+	return changed
+}
+
+// sameBlocks returns whether both coverages contain the same files with blocks
+// at the same positions, regardless of how often the blocks were executed.
+func sameBlocks(a, b *Coverage) bool {
+	if len(a.Files) != len(b.Files) {
+		return false
+	}
+
+	for fileName, pa := range a.Files {
+		pb := b.Files[fileName]
+		if pb == nil || len(pa.Blocks) != len(pb.Blocks) {
+			return false
+		}
+		for i, block := range pa.Blocks {
+			other := pb.Blocks[i]
+			if block.StartLine != other.StartLine || block.StartCol != other.StartCol ||
+				block.EndLine != other.EndLine || block.EndCol != other.EndCol ||
+				block.NumStmt != other.NumStmt {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// identicalProfilesWarning returns the warning about identical coverage
+// profiles without Markdown formatting, or an empty string.
+func (r *Report) identicalProfilesWarning() string {
+	changed := r.IdenticalProfiles()
+	if len(changed) == 0 {
+		return ""
+	}
+
+	names := baseNames(changed)
+	if len(names) > maxIdenticalProfilesFiles {
+		names = append(names[:maxIdenticalProfilesFiles], fmt.Sprintf("%d more", len(names)-maxIdenticalProfilesFiles))
+	}
+
+	return fmt.Sprintf("The old and new coverage profiles contain exactly the same code blocks, although this PR changes the code of %s (%s). "+
+		"Did you upload the wrong artifact? The old coverage must come from the base branch and the new coverage from the head of this PR.",
+		countOf(len(changed), "file"), strings.Join(names, ", "))
+}
+
+// addIdenticalProfilesWarning adds a prominent warning if the old and new
+// coverage profiles are suspiciously identical (see IdenticalProfiles).
+func (r *Report) addIdenticalProfilesWarning(report *strings.Builder) {
+	warning := r.identicalProfilesWarning()
+	if warning == "" {
+		return
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "> [!CAUTION]")
+	fmt.Fprintln(report, "> **Identical coverage profiles:** "+warning)
+}
@@ -0,0 +1,63 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_IdenticalProfiles(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	assert.Empty(t, report.IdenticalProfiles())
+	assert.NotContains(t, report.Analyze().Markdown(), "[!CAUTION]")
+
+	// The new coverage was uploaded for both sides.
+	report = NewReport(newCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	assert.Equal(t, []string{"github.com/pentohq/pento/pkg/age/age.go"}, report.IdenticalProfiles())
+	assert.Contains(t, report.Analyze().Markdown(), "\n\n> [!CAUTION]\n"+
+		"> **Identical coverage profiles:** The old and new coverage profiles contain exactly the same code blocks, although this PR changes the code of 1 file (age.go). "+
+		"Did you upload the wrong artifact? The old coverage must come from the base branch and the new coverage from the head of this PR.\n\n#### Overall Coverage Summary")
+
+	// Without a diff, all changed files with coverage data count.
+	report.DiffInfo = nil
+	assert.Len(t, report.IdenticalProfiles(), 1)
+
+	// Changes of tests or of files without coverage data do not change blocks.
+	report = NewReport(newCov, newCov, []string{"github.com/pentohq/pento/pkg/age/age_test.go", "github.com/pentohq/pento/cmd/main.go"})
+	assert.Empty(t, report.IdenticalProfiles())
+
+	// A diff without added lines (e.g. only deleted comments) can keep all blocks.
+	report = NewReport(newCov, newCov, changedFiles)
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{"pkg/age/age.go": {DeletedLines: map[int]bool{3: true}}}}
+	assert.Empty(t, report.IdenticalProfiles())
+}
+
+func TestSameBlocks(t *testing.T) {
+	block := ProfileBlock{StartLine: 1, StartCol: 2, EndLine: 3, EndCol: 4, NumStmt: 2, Count: 1}
+	cov := func(blocks ...ProfileBlock) *Coverage {
+		return New([]*Profile{{FileName: "a.go", Blocks: blocks}})
+	}
+
+	unexecuted := block
+	unexecuted.Count = 0
+	moved := block
+	moved.StartLine, moved.EndLine = 2, 4
+
+	assert.True(t, sameBlocks(cov(block), cov(block)))
+	assert.True(t, sameBlocks(cov(block), cov(unexecuted)), "counts are ignored")
+	assert.False(t, sameBlocks(cov(block), cov(moved)))
+	assert.False(t, sameBlocks(cov(block), cov(block, moved)))
+	assert.False(t, sameBlocks(cov(block), New([]*Profile{{FileName: "b.go", Blocks: []ProfileBlock{block}}})))
+}
@@ -0,0 +1,153 @@
+package coverage
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultIgnoreFile is the name of the ignore file that is read from the
+// repository root (or the working directory) if -ignore-file is not set.
+const defaultIgnoreFile = ".coverageignore"
+
+// CoverageIgnore is a list of gitignore style patterns of files that are
+// removed from the coverage before anything is calculated.
+type CoverageIgnore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	pattern  string
+	negate   bool // the pattern started with "!"
+	dirOnly  bool // the pattern ended with "/"
+	anchored bool // the pattern contained a "/" before its end
+}
+
+// ParseCoverageIgnore parses the lines of a .coverageignore file. The syntax
+// follows .gitignore: blank lines and lines starting with "#" are ignored, a
+// leading "!" re-includes files that a previous pattern ignored, a trailing
+// "/" only matches directories and a pattern that contains a "/" is relative
+// to the root of the repository. Otherwise, the pattern matches files and
+// directories at any depth. Patterns support "*", "?", character classes and
+// "**" segments.
+func ParseCoverageIgnore(r io.Reader) (*CoverageIgnore, error) {
+	ignore := new(CoverageIgnore)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		rule.pattern = line
+		ignore.rules = append(ignore.rules, rule)
+	}
+
+	return ignore, scanner.Err()
+}
+
+// ReadCoverageIgnore parses the given .coverageignore file.
+func ReadCoverageIgnore(fileName string) (*CoverageIgnore, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseCoverageIgnore(f)
+}
+
+// loadCoverageIgnore returns the ignore file that is configured via
+// -ignore-file. Without the flag, the .coverageignore file of the repository
+// root is used if it exists. It returns nil if there is no ignore file.
+func loadCoverageIgnore(opts options) (*CoverageIgnore, error) {
+	if opts.ignoreFile != "" {
+		return ReadCoverageIgnore(opts.ignoreFile)
+	}
+
+	ignore, err := ReadCoverageIgnore(filepath.Join(opts.repoRoot, defaultIgnoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return ignore, err
+}
+
+// Match reports whether the given slash separated path relative to the
+// repository root is ignored. As with git, files inside an ignored directory
+// cannot be re-included by a negated pattern.
+func (c *CoverageIgnore) Match(name string) bool {
+	if c == nil {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		if c.match(segments[:i], true) {
+			return true
+		}
+	}
+
+	return c.match(segments, false)
+}
+
+// match returns whether the last matching rule ignores the given path.
+func (c *CoverageIgnore) match(segments []string, isDir bool) bool {
+	ignored := false
+	for _, rule := range c.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		var ok bool
+		if rule.anchored {
+			ok = matchGlob(rule.pattern, strings.Join(segments, "/"))
+		} else {
+			ok, _ = path.Match(rule.pattern, segments[len(segments)-1])
+		}
+
+		if ok {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// Filter returns a function that returns true for all files of the coverage
+// profile that are not ignored. Paths are relative to root if the file is
+// inside of it.
+func (c *CoverageIgnore) Filter(root string) func(fileName string) bool {
+	return func(fileName string) bool {
+		if rel, ok := relativeToRoot(fileName, root); ok {
+			fileName = rel
+		}
+
+		return !c.Match(fileName)
+	}
+}
@@ -0,0 +1,78 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverageIgnore_Match(t *testing.T) {
+	ignore, err := ParseCoverageIgnore(strings.NewReader(`
+# generated code
+*.pb.go
+!keep.pb.go
+mocks/
+/internal/testutil
+docs/**/*.go
+\#weird.go
+vendor/
+!vendor/example.com/lib/a.go
+`))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"api/v1/service.pb.go", true},
+		{"service.pb.go", true},
+		{"api/v1/keep.pb.go", false},
+		{"pkg/mocks/client.go", true},
+		{"mocks/client.go", true},
+		{"pkg/mocks.go", false}, // directory pattern
+		{"internal/testutil/a.go", true},
+		{"pkg/internal/testutil/a.go", false}, // anchored
+		{"docs/a.go", true},
+		{"docs/examples/a/b.go", true},
+		{"#weird.go", true},
+		{"vendor/example.com/lib/a.go", true}, // parent directory is ignored
+		{"pkg/service/a.go", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, ignore.Match(c.name), c.name)
+	}
+
+	var none *CoverageIgnore
+	assert.False(t, none.Match("a.go"))
+}
+
+func TestCoverageIgnore_Filter(t *testing.T) {
+	ignore, err := ParseCoverageIgnore(strings.NewReader("/gen/\n"))
+	require.NoError(t, err)
+
+	keep := ignore.Filter("example.com/app")
+	assert.False(t, keep("example.com/app/gen/a.go"))
+	assert.True(t, keep("example.com/app/pkg/gen.go"))
+	assert.True(t, keep("example.com/other/gen/a.go"))
+}
+
+func TestLoadCoverageIgnore(t *testing.T) {
+	dir := t.TempDir()
+
+	ignore, err := loadCoverageIgnore(options{repoRoot: dir})
+	require.NoError(t, err)
+	assert.Nil(t, ignore)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, defaultIgnoreFile), []byte("*.pb.go\n"), 0644))
+	ignore, err = loadCoverageIgnore(options{repoRoot: dir})
+	require.NoError(t, err)
+	assert.True(t, ignore.Match("a.pb.go"))
+
+	_, err = loadCoverageIgnore(options{ignoreFile: filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+}
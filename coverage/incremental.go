@@ -0,0 +1,168 @@
+package coverage
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"sort"
+)
+
+// analysisVersion is part of each fingerprint so that analyses of an older
+// version of the tool are never reused if the analysis itself changes.
+const analysisVersion = 2
+
+// FileAnalysis is the result of the new code analysis of a single changed
+// file. It is included in the JSON report so that a later run on the same pull
+// request can reuse it for all files whose inputs did not change (see the
+// -previous flag).
+type FileAnalysis struct {
+	Fingerprint string         // hash of all inputs of the analysis
+	TotalNew    int64          // number of new statements
+	CoveredNew  int64          // number of covered new statements
+	Blocks      []NewCodeBlock // new code blocks including their source code
+}
+
+// ReadPreviousAnalysis reads the per file analysis from a report that was
+// created with -format=json.
+func ReadPreviousAnalysis(fileName string) (map[string]FileAnalysis, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous struct {
+		Analysis map[string]FileAnalysis
+	}
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return nil, fmt.Errorf("invalid JSON report: %w", err)
+	}
+
+	return previous.Analysis, nil
+}
+
+// analysis returns the analysis of all changed files that have coverage data.
+func (r *Report) analysis() map[string]FileAnalysis {
+	result := make(map[string]FileAnalysis, len(r.ChangedFiles))
+	for _, fileName := range r.ChangedFiles {
+		if r.New.Files[fileName] == nil {
+			continue
+		}
+
+		if prev, ok := r.previousAnalysis(fileName); ok {
+			result[fileName] = prev
+			continue
+		}
+
+		a := FileAnalysis{Fingerprint: r.fingerprint(fileName)}
+		a.TotalNew, a.CoveredNew = r.fileNewCodeCoverage(fileName)
+		a.Blocks = r.fileNewCodeBlocks(fileName)
+		result[fileName] = a
+	}
+
+	return result
+}
+
+// previousAnalysis returns the analysis of the given file from the previous
+// run if all of its inputs are unchanged.
+func (r *Report) previousAnalysis(fileName string) (FileAnalysis, bool) {
+	if len(r.Previous) == 0 {
+		return FileAnalysis{}, false
+	}
+
+	prev, ok := r.Previous[fileName]
+	if !ok || prev.Fingerprint != r.fingerprint(fileName) {
+		return FileAnalysis{}, false
+	}
+
+	return prev, true
+}
+
+// ReusedFiles returns the number of changed files whose analysis is reused
+// from the previous run.
+func (r *Report) ReusedFiles() int {
+	var n int
+	for _, fileName := range r.ChangedFiles {
+		if _, ok := r.previousAnalysis(fileName); ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// fingerprint returns a hash of everything the new code analysis of the given
+// file depends on: the old, new and package coverage blocks, the changed lines
+// of the diff and the source code of the file.
+func (r *Report) fingerprint(fileName string) string {
+	if fp, ok := r.fingerprints[fileName]; ok {
+		return fp
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\x00%s\x00%s\x00%t\x00", analysisVersion, fileName, r.BaseRef, r.astMapper != nil)
+
+	hashProfile(h, r.Old.Files[fileName])
+	hashProfile(h, r.New.Files[fileName])
+	if r.PackageCoverage != nil {
+		fmt.Fprintf(h, "pkg\x00%t\x00", r.RequirePackageCoverage)
+		hashProfile(h, r.PackageCoverage.Files[fileName])
+	}
+
+	if r.DiffInfo == nil {
+		h.Write([]byte("nodiff\x00"))
+	} else if fileDiff := r.DiffInfo.findFileDiff(fileName); fileDiff != nil {
+		hashLines(h, fileDiff.AddedLines)
+		hashLines(h, fileDiff.ModifiedLines)
+	}
+
+	if path, ok := r.source.find(fileName); ok {
+		if data, err := os.ReadFile(path); err == nil {
+			h.Write(data)
+		}
+	}
+
+	fp := hex.EncodeToString(h.Sum(nil))
+	if r.fingerprints == nil {
+		r.fingerprints = make(map[string]string)
+	}
+	r.fingerprints[fileName] = fp
+
+	return fp
+}
+
+func hashProfile(h hash.Hash, p *Profile) {
+	if p == nil {
+		h.Write([]byte("none\x00"))
+		return
+	}
+
+	buf := make([]byte, 0, 6*binary.MaxVarintLen64)
+	for _, b := range p.Blocks {
+		buf = buf[:0]
+		for _, v := range []int{b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count} {
+			buf = binary.AppendVarint(buf, int64(v))
+		}
+		h.Write(buf)
+	}
+	h.Write([]byte("\x00"))
+}
+
+func hashLines(h hash.Hash, lines map[int]bool) {
+	sorted := make([]int, 0, len(lines))
+	for line, ok := range lines {
+		if ok {
+			sorted = append(sorted, line)
+		}
+	}
+	sort.Ints(sorted)
+
+	buf := make([]byte, 0, binary.MaxVarintLen64)
+	for _, line := range sorted {
+		h.Write(binary.AppendVarint(buf[:0], int64(line)))
+	}
+	h.Write([]byte("\x00"))
+}
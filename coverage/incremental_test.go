@@ -0,0 +1,84 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIncrementalTestReport(t *testing.T) *Report {
+	t.Helper()
+
+	return newTestReport(t,
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		"github.com/pentohq/pento",
+		"testdata/04-diff.patch",
+	)
+}
+
+func TestReport_Previous(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, os.WriteFile(fileName, []byte(newIncrementalTestReport(t).Analyze().JSON()), 0644))
+
+	previous, err := ReadPreviousAnalysis(fileName)
+	require.NoError(t, err)
+	require.Len(t, previous, 1)
+
+	expected := newIncrementalTestReport(t)
+	totalNew, coveredNew := expected.Analyze().calculateNewCodeCoverage()
+	for name, a := range previous {
+		assert.Equal(t, totalNew, a.TotalNew)
+		assert.Equal(t, coveredNew, a.CoveredNew)
+		assert.Equal(t, expected.Analyze().getNewCodeBlocks(), a.Blocks)
+
+		// Mark the previous analysis so we can tell if it was reused.
+		a.TotalNew, a.CoveredNew = 1000, 1
+		previous[name] = a
+	}
+
+	report := newIncrementalTestReport(t)
+	report.Previous = previous
+	assert.Equal(t, 1, report.ReusedFiles())
+
+	totalNew, coveredNew = report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 1000, totalNew)
+	assert.EqualValues(t, 1, coveredNew)
+
+	// A file whose coverage changed is analyzed again.
+	report = newIncrementalTestReport(t)
+	report.Previous = previous
+	for _, p := range report.New.Files {
+		p.Blocks[0].Count++
+	}
+	assert.Zero(t, report.ReusedFiles())
+	assert.Equal(t, expected.Analyze().getNewCodeBlocks(), report.Analyze().getNewCodeBlocks())
+}
+
+func TestReport_Fingerprint(t *testing.T) {
+	report := newIncrementalTestReport(t)
+	fileName := report.ChangedFiles[0]
+	fp := report.fingerprint(fileName)
+
+	assert.Equal(t, fp, newIncrementalTestReport(t).fingerprint(fileName), "fingerprints must be deterministic")
+
+	other := newIncrementalTestReport(t)
+	other.DiffInfo = nil
+	assert.NotEqual(t, fp, other.fingerprint(fileName))
+
+	other = newIncrementalTestReport(t)
+	other.DiffInfo.findFileDiff(fileName).AddedLines[1000] = true
+	assert.NotEqual(t, fp, other.fingerprint(fileName))
+}
+
+func TestReadPreviousAnalysis_Invalid(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, os.WriteFile(fileName, []byte("# Coverage"), 0644))
+
+	_, err := ReadPreviousAnalysis(fileName)
+	assert.ErrorContains(t, err, "invalid JSON report")
+}
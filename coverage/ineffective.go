@@ -0,0 +1,103 @@
+package coverage
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// IneffectiveTest describes a package in which the PR changes test files,
+// although none of the new code of the changed production files of the same
+// package is covered. This usually means that the tests do not exercise the
+// new code or that they are skipped, e.g. because of build tags.
+type IneffectiveTest struct {
+	Package   string
+	TestFiles []string // changed test files of the package
+	Files     []string // changed production files with new code
+	NewStmt   int64    // number of new statements in Files, none of which is covered
+}
+
+// IneffectiveTests returns all packages in which changed tests do not cover
+// any of the new production code.
+func (r *Result) IneffectiveTests() []IneffectiveTest {
+	testFiles := map[string][]string{}
+	for _, f := range r.ChangedFiles {
+		if strings.HasSuffix(f, "_test.go") {
+			pkg := path.Dir(f)
+			testFiles[pkg] = append(testFiles[pkg], f)
+		}
+	}
+
+	if len(testFiles) == 0 {
+		return nil
+	}
+
+	result := map[string]*IneffectiveTest{}
+	covered := map[string]bool{}
+	for _, f := range r.ChangedFiles {
+		pkg := path.Dir(f)
+		if strings.HasSuffix(f, "_test.go") || testFiles[pkg] == nil || covered[pkg] {
+			continue
+		}
+
+		totalNew, coveredNew := r.newCodeCoverageOf(f)
+		if coveredNew > 0 {
+			covered[pkg] = true
+			continue
+		}
+		if totalNew == 0 {
+			continue
+		}
+
+		t, ok := result[pkg]
+		if !ok {
+			t = &IneffectiveTest{Package: pkg, TestFiles: testFiles[pkg]}
+			result[pkg] = t
+		}
+		t.Files = append(t.Files, f)
+		t.NewStmt += totalNew
+	}
+
+	var list []IneffectiveTest
+	for pkg, t := range result {
+		if !covered[pkg] {
+			list = append(list, *t)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Package < list[j].Package
+	})
+
+	return list
+}
+
+// addIneffectiveTestsWarning warns about changed tests that do not cover any
+// new code of their package.
+func (r *Result) addIneffectiveTestsWarning(report *strings.Builder) {
+	list := r.IneffectiveTests()
+	if len(list) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, "> [!WARNING]")
+	fmt.Fprintln(report, "> **Tests without effect on new code:** This PR changes tests, but none of the new code of the following packages is covered. The tests may not exercise the new code or may be skipped (e.g. by build tags).")
+	for _, t := range list {
+		fmt.Fprintf(report, "> - `%s`: %s changed, %d new statements in %s uncovered\n", t.Package, codeList(baseNames(t.TestFiles)), t.NewStmt, codeList(baseNames(t.Files)))
+	}
+	fmt.Fprintln(report)
+}
+
+func baseNames(files []string) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = path.Base(f)
+	}
+
+	return names
+}
+
+func codeList(items []string) string {
+	return "`" + strings.Join(items, "`, `") + "`"
+}
@@ -0,0 +1,61 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_IneffectiveTests(t *testing.T) {
+	profile := func(fileName string, count int) *Profile {
+		return &Profile{
+			FileName:    fileName,
+			Mode:        "set",
+			Blocks:      []ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 5, EndCol: 2, NumStmt: 3, Count: count}},
+			TotalStmt:   3,
+			CoveredStmt: int64(min(count, 1) * 3),
+		}
+	}
+
+	newCov := New([]*Profile{
+		profile("example.com/a/a.go", 0),
+		profile("example.com/a/b.go", 0),
+		profile("example.com/b/b.go", 1),
+		profile("example.com/c/c.go", 0),
+	})
+
+	report := NewReport(New(nil), newCov, []string{
+		"example.com/a/a.go",
+		"example.com/a/a_test.go",
+		"example.com/a/b.go",
+		"example.com/b/b.go",
+		"example.com/b/b_test.go",
+		"example.com/c/c.go", // no tests changed
+	})
+	report.astMapper = nil
+
+	list := report.Analyze().IneffectiveTests()
+	require.Len(t, list, 1)
+	assert.Equal(t, IneffectiveTest{
+		Package:   "example.com/a",
+		TestFiles: []string{"example.com/a/a_test.go"},
+		Files:     []string{"example.com/a/a.go", "example.com/a/b.go"},
+		NewStmt:   6,
+	}, list[0])
+
+	assert.Contains(t, report.Analyze().Markdown(), "> - `example.com/a`: `a_test.go` changed, 6 new statements in `a.go`, `b.go` uncovered\n")
+}
+
+func TestReport_IneffectiveTests_PartiallyCovered(t *testing.T) {
+	newCov := New([]*Profile{
+		{FileName: "example.com/a/a.go", Blocks: []ProfileBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 0}}, TotalStmt: 1},
+		{FileName: "example.com/a/b.go", Blocks: []ProfileBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1}}, TotalStmt: 1, CoveredStmt: 1},
+	})
+
+	report := NewReport(New(nil), newCov, []string{"example.com/a/a.go", "example.com/a/a_test.go", "example.com/a/b.go"})
+	report.astMapper = nil
+
+	assert.Empty(t, report.Analyze().IneffectiveTests())
+	assert.NotContains(t, report.Analyze().Markdown(), "Tests without effect")
+}
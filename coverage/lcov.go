@@ -0,0 +1,102 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// isLCOV returns true if the first line of a coverage file is the first line
+// of an LCOV tracefile instead of the "mode: " line of a Go coverage profile.
+func isLCOV(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("TN:")) || bytes.HasPrefix(line, []byte("SF:"))
+}
+
+// parseLCOV parses a single line of an LCOV tracefile as it is written by
+// "bazel coverage" with rules_go. The file of a record is given by its "SF:"
+// line and each "DA:<line>,<hits>" line becomes a block of a single statement
+// that spans the whole line. All other lines (functions, branches, summaries)
+// are ignored, and so are the records of files that are not Go files.
+func (pp *profileParser) parseLCOV(line []byte) error {
+	switch {
+	case bytes.HasPrefix(line, []byte("SF:")):
+		source := lcovFileName(string(line[len("SF:"):]), pp.lcovRoot)
+		pp.lcovFile = nil
+		if !strings.HasSuffix(source, ".go") || pp.skipped[source] {
+			return nil
+		}
+
+		p := pp.files[source]
+		if p == nil {
+			name, ok := pp.fileName(source)
+			if !ok {
+				pp.skipped[source] = true
+				return nil
+			}
+			p = &Profile{FileName: name, Mode: pp.mode}
+			pp.files[source] = p
+		}
+		pp.lcovFile = p
+
+	case bytes.HasPrefix(line, []byte("DA:")):
+		if pp.lcovFile == nil {
+			return nil
+		}
+
+		// The optional third field is a checksum of the line.
+		fields := strings.Split(string(line[len("DA:"):]), ",")
+		if len(fields) < 2 {
+			return fmt.Errorf("line %q doesn't match expected format: missing execution count", line)
+		}
+		lineNum, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %q doesn't match expected format: %v", line, err)
+		}
+		hits, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("line %q doesn't match expected format: %v", line, err)
+		}
+
+		// The column range of the statements is unknown, so the block covers
+		// the whole line (see ProfileBlock.LineSpan).
+		b := ProfileBlock{StartLine: lineNum, StartCol: 1, EndLine: lineNum, NumStmt: 1, Count: hits}
+		if err := checkBlock(pp.lcovFile.FileName, b); err != nil {
+			return fmt.Errorf("line %q is invalid: %v", line, err)
+		}
+		pp.lcovFile.Blocks = append(pp.lcovFile.Blocks, b)
+
+	case bytes.Equal(line, []byte("end_of_record")):
+		pp.lcovFile = nil
+	}
+
+	return nil
+}
+
+// lcovFileName maps the file name of an LCOV record to the file name of the
+// Go coverage profile. Bazel reports files relative to the workspace, but
+// depending on the version and the rules also as absolute path in the
+// execution root (".../execroot/<workspace>/...") or in the output tree of
+// generated files ("bazel-out/<config>/bin/..."). These prefixes are removed
+// and the root is prepended.
+func lcovFileName(name, root string) string {
+	name = normalizeProfilePath(strings.TrimSpace(name))
+	if _, rest, ok := strings.Cut(name, "/execroot/"); ok {
+		if _, rel, ok := strings.Cut(rest, "/"); ok {
+			name = rel
+		}
+	}
+	if strings.HasPrefix(name, "bazel-out/") {
+		if parts := strings.SplitN(name, "/", 4); len(parts) == 4 && parts[2] == "bin" {
+			name = parts[3]
+		}
+	}
+	name = strings.TrimPrefix(name, "./")
+
+	if root != "" {
+		return path.Join(root, name)
+	}
+
+	return name
+}
@@ -0,0 +1,76 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfiles_LCOV(t *testing.T) {
+	o := parseOptions{lcovRoot: "github.com/example/monorepo"}
+	profiles, err := parseProfilesReader(strings.NewReader(`SF:pkg/app/app.go
+FN:3,New
+FNDA:1,New
+DA:3,1
+DA:4,1
+DA:7,0
+LH:2
+LF:3
+end_of_record
+SF:/home/user/.cache/bazel/_bazel_user/123/execroot/monorepo/pkg/app/app.go
+DA:7,2,c2VjcmV0
+end_of_record
+SF:bazel-out/k8-fastbuild/bin/pkg/gen/gen.go
+DA:1,0
+end_of_record
+SF:pkg/app/app.py
+DA:1,1
+end_of_record
+`), o)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	app := profiles[0]
+	assert.Equal(t, "github.com/example/monorepo/pkg/app/app.go", app.FileName)
+	assert.Equal(t, "count", app.Mode)
+	assert.Equal(t, []ProfileBlock{
+		{StartLine: 3, StartCol: 1, EndLine: 3, NumStmt: 1, Count: 1},
+		{StartLine: 4, StartCol: 1, EndLine: 4, NumStmt: 1, Count: 1},
+		{StartLine: 7, StartCol: 1, EndLine: 7, NumStmt: 1, Count: 2},
+	}, app.Blocks)
+	assert.Equal(t, int64(3), app.TotalStmt)
+	assert.Equal(t, int64(3), app.CoveredStmt)
+
+	assert.Equal(t, "github.com/example/monorepo/pkg/gen/gen.go", profiles[1].FileName)
+	assert.Equal(t, int64(0), profiles[1].CoveredStmt)
+}
+
+func TestParseProfiles_LCOVErrors(t *testing.T) {
+	_, err := ParseProfilesFromReader(strings.NewReader("SF:a.go\nDA:x,1\n"))
+	assert.Error(t, err)
+
+	_, err = ParseProfilesFromReader(strings.NewReader("SF:a.go\nDA:0,1\n"))
+	assert.Error(t, err)
+
+	_, err = ParseProfilesFromReader(strings.NewReader("SF:a.go\nDA:1\n"))
+	assert.Error(t, err)
+
+	// Lines of skipped records are not validated.
+	_, err = ParseProfilesFromReader(strings.NewReader("SF:a.py\nDA:0,1\n"))
+	assert.NoError(t, err)
+}
+
+func TestLCOVFileName(t *testing.T) {
+	assert.Equal(t, "pkg/a.go", lcovFileName("pkg/a.go", ""))
+	assert.Equal(t, "pkg/a.go", lcovFileName("./pkg/a.go", ""))
+	assert.Equal(t, "pkg/a.go", lcovFileName("/private/var/tmp/_bazel/abc/execroot/_main/pkg/a.go", ""))
+	assert.Equal(t, "pkg/a.go", lcovFileName("bazel-out/darwin_arm64-fastbuild/bin/pkg/a.go", ""))
+	assert.Equal(t, "pkg/a.go", lcovFileName("/tmp/execroot/ws/bazel-out/k8-opt/bin/pkg/a.go", ""))
+	assert.Equal(t, "external/dep/a.go", lcovFileName("external/dep/a.go", ""))
+	assert.Equal(t, "pkg/a.go", lcovFileName(`C:\_bazel\abc\execroot\_main\pkg\a.go`, ""))
+	assert.Equal(t, "C:/my workspace/pkg/a.go", lcovFileName(`c:\my workspace\pkg\a.go`, ""))
+
+	assert.Equal(t, "example.com/repo/pkg/a.go", lcovFileName("pkg/a.go", "example.com/repo"))
+}
@@ -0,0 +1,130 @@
+package coverage
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// LineCoverageChange is a line that was not changed in this PR but whose
+// coverage did change, e.g. because a test was removed or a new code path now
+// reaches it.
+type LineCoverageChange struct {
+	FileName string
+	OldLine  int  // line number in the old version of the file
+	NewLine  int  // line number in the new version of the file
+	Covered  bool // true if the line is newly covered, false if it is newly uncovered
+}
+
+// LineCoverageChanges compares the coverage of all unchanged lines of the
+// changed files by mapping the old profile onto the new file via the hunks of
+// the diff. It returns nil if no unified diff is available.
+func (r *Report) LineCoverageChanges() []LineCoverageChange {
+	var changes []LineCoverageChange
+	for _, fileName := range r.ChangedFiles {
+		oldProfile := r.Old.Files[fileName]
+		newProfile := r.New.Files[fileName]
+		fileDiff := r.DiffInfo.findFileDiff(fileName)
+		if oldProfile == nil || newProfile == nil || fileDiff == nil || len(fileDiff.Hunks) == 0 {
+			continue
+		}
+
+		oldCoverage := lineCoverage(oldProfile, maxLineNumber)
+		newCoverage := lineCoverage(newProfile, maxLineNumber)
+		for oldLine, oldCovered := range oldCoverage {
+			newLine, ok := fileDiff.mapOldLine(oldLine)
+			if !ok {
+				continue
+			}
+
+			newCovered, ok := newCoverage[newLine]
+			if !ok || newCovered == oldCovered {
+				continue
+			}
+
+			changes = append(changes, LineCoverageChange{
+				FileName: fileName,
+				OldLine:  oldLine,
+				NewLine:  newLine,
+				Covered:  newCovered,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].FileName != changes[j].FileName {
+			return changes[i].FileName < changes[j].FileName
+		}
+		return changes[i].NewLine < changes[j].NewLine
+	})
+
+	return changes
+}
+
+// addLineCoverageChanges lists unchanged lines whose coverage changed.
+func (r *Report) addLineCoverageChanges(report *strings.Builder) {
+	changes := r.LineCoverageChanges()
+	if len(changes) == 0 {
+		return
+	}
+
+	type fileChanges struct{ covered, uncovered []int }
+	byFile := map[string]*fileChanges{}
+	var files []string
+	for _, c := range changes {
+		fc, ok := byFile[c.FileName]
+		if !ok {
+			fc = &fileChanges{}
+			byFile[c.FileName] = fc
+			files = append(files, c.FileName)
+		}
+		if c.Covered {
+			fc.covered = append(fc.covered, c.NewLine)
+		} else {
+			fc.uncovered = append(fc.uncovered, c.NewLine)
+		}
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldLineChanges, func() bool {
+		return slices.ContainsFunc(changes, func(c LineCoverageChange) bool { return !c.Covered })
+	}))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Coverage Changes in Unchanged Lines</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The following lines were not changed in this PR but their coverage changed.")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| File | Newly Uncovered Lines | Newly Covered Lines |")
+	fmt.Fprintln(report, "|------|-----------------------|---------------------|")
+	for _, file := range files {
+		fmt.Fprintf(report, "| %s | %s | %s |\n", file, formatLineRanges(byFile[file].uncovered), formatLineRanges(byFile[file].covered))
+	}
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+// formatLineRanges formats sorted line numbers as comma separated ranges
+// (e.g. "3, 7-9").
+func formatLineRanges(lines []int) string {
+	var ranges []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+
+		if i == j {
+			ranges = append(ranges, fmt.Sprint(lines[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+
+	if len(ranges) == 0 {
+		return "-"
+	}
+
+	return strings.Join(ranges, ", ")
+}
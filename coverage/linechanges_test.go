@@ -0,0 +1,50 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_LineCoverageChanges(t *testing.T) {
+	diffInfo, err := parseUnifiedDiff(strings.NewReader(`--- a/p.go
++++ b/p.go
+@@ -2,0 +3,2 @@
++added
++added
+`), parseOptions{})
+	require.NoError(t, err)
+
+	oldProfile := &Profile{FileName: "p.go", Blocks: []ProfileBlock{
+		{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 1},
+		{StartLine: 4, EndLine: 5, NumStmt: 1, Count: 0},
+		{StartLine: 6, EndLine: 6, NumStmt: 1, Count: 1},
+	}}
+	newProfile := &Profile{FileName: "p.go", Blocks: []ProfileBlock{
+		{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 0},
+		{StartLine: 3, EndLine: 4, NumStmt: 1, Count: 1},
+		{StartLine: 6, EndLine: 7, NumStmt: 1, Count: 1},
+		{StartLine: 8, EndLine: 8, NumStmt: 1, Count: 1},
+	}}
+
+	report := NewReport(New([]*Profile{oldProfile}), New([]*Profile{newProfile}), []string{"p.go"})
+	assert.Empty(t, report.LineCoverageChanges(), "without a diff lines cannot be mapped")
+
+	report.DiffInfo = diffInfo
+	assert.Equal(t, []LineCoverageChange{
+		{FileName: "p.go", OldLine: 1, NewLine: 1, Covered: false},
+		{FileName: "p.go", OldLine: 4, NewLine: 6, Covered: true},
+		{FileName: "p.go", OldLine: 5, NewLine: 7, Covered: true},
+	}, report.LineCoverageChanges())
+
+	markdown := report.Analyze().Markdown()
+	assert.Contains(t, markdown, "| p.go | 1 | 6-7 |")
+}
+
+func TestFormatLineRanges(t *testing.T) {
+	assert.Equal(t, "-", formatLineRanges(nil))
+	assert.Equal(t, "3", formatLineRanges([]int{3}))
+	assert.Equal(t, "1-3, 5, 7-8", formatLineRanges([]int{1, 2, 3, 5, 7, 8}))
+}
@@ -0,0 +1,82 @@
+package coverage
+
+import "sort"
+
+// LineCoverage maps the line numbers of a file to whether the line is
+// covered. Lines that are not part of any coverage block are not included.
+type LineCoverage map[int]bool
+
+// Covered returns the sorted line numbers of all covered lines.
+func (lc LineCoverage) Covered() []int {
+	return lc.filter(true)
+}
+
+// Uncovered returns the sorted line numbers of all uncovered lines.
+func (lc LineCoverage) Uncovered() []int {
+	return lc.filter(false)
+}
+
+func (lc LineCoverage) filter(covered bool) []int {
+	lines := []int{}
+	for _, line := range lc.sortedLines() {
+		if lc[line] == covered {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func (lc LineCoverage) sortedLines() []int {
+	lines := make([]int, 0, len(lc))
+	for line := range lc {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	return lines
+}
+
+// lines expands the coverage into the per-line coverage of each file. A line
+// is covered if ANY block that includes it is covered.
+//
+// Coverage blocks span whole line ranges including lines that only contain
+// comments or closing braces. If mapper is not nil, lines that do not contain
+// a statement are dropped for all files whose source code can be found in the
+// tree (see sourceTree.find).
+func (c *Coverage) lines(tree sourceTree, mapper *StatementLineMapper) map[string]LineCoverage {
+	result := make(map[string]LineCoverage, len(c.Files))
+	for fileName, profile := range c.Files {
+		coverage := LineCoverage(lineCoverage(profile, maxLineNumber))
+
+		if mapper != nil {
+			if path, ok := tree.find(fileName); ok {
+				if statementLines, err := mapper.GetStatementLines(path); err == nil {
+					for line := range coverage {
+						if !statementLines[line] {
+							delete(coverage, line)
+						}
+					}
+				}
+			}
+		}
+
+		result[fileName] = coverage
+	}
+
+	return result
+}
+
+// lineCoverage returns for each line up to maxLine that is part of a coverage
+// block whether it is covered. A line is covered if ANY block that includes it
+// is covered.
+func lineCoverage(profile *Profile, maxLine int) map[int]bool {
+	coverage := map[int]bool{}
+	for _, block := range profile.Blocks {
+		for line := block.StartLine; line <= min(block.EndLine, maxLine); line++ {
+			coverage[line] = coverage[line] || block.Count > 0
+		}
+	}
+
+	return coverage
+}
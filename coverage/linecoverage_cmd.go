@@ -0,0 +1,176 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+var linesUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s lines [OPTIONS] <COVERAGE_FILE>
+
+Print the covered and uncovered lines of each file of the coverage profile.
+By default, only lines that contain a statement are printed if the source code
+of the file can be found locally.
+
+The text format prints one line per range of lines with the same coverage
+(e.g. "example.com/foo/bar.go:12-14 uncovered"). The json format prints an
+object that maps each file to its covered and uncovered lines.
+
+With -func, the coverage of each function is printed like by "go tool cover
+-func" instead. Closures are listed as well and named like in stack traces
+(e.g. "Run.func1"); their statements also count for the enclosing function.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runLinesCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lines", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, linesUsage)
+		fs.PrintDefaults()
+	}
+
+	format := fs.String("format", "text", "output format: text or json")
+	trim := fs.String("trim", "", "trim a prefix from all file paths")
+	useAST := fs.Bool("ast", true, "only print lines that contain statements if the source code can be found")
+	funcs := fs.Bool("func", false, "print the coverage of each function and closure instead of lines")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one coverage file")
+	}
+
+	cov, err := ParseCoverageContext(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to parse coverage: %w", err)
+	}
+
+	if *funcs {
+		return runFuncCoverage(cov, *format, *trim)
+	}
+
+	var mapper *StatementLineMapper
+	if *useAST {
+		mapper = NewStatementLineMapper()
+	}
+
+	lines := cov.lines(sourceTree{}, mapper)
+	if *trim != "" {
+		trimmed := make(map[string]LineCoverage, len(lines))
+		for name, lc := range lines {
+			trimmed[trimPrefix(name, *trim)] = lc
+		}
+		lines = trimmed
+	}
+
+	switch *format {
+	case "text":
+		return writeLinesText(os.Stdout, lines)
+	case "json":
+		return writeLinesJSON(os.Stdout, lines)
+	default:
+		return fmt.Errorf("unsupported format: %q", *format)
+	}
+}
+
+func runFuncCoverage(cov *Coverage, format, trim string) error {
+	funcs, missing := cov.functions(sourceTree{}, NewStatementLineMapper())
+	for _, fileName := range missing {
+		fmt.Fprintf(os.Stderr, "Skipping %s since its source code cannot be found\n", fileName)
+	}
+
+	for i := range funcs {
+		funcs[i].FileName = trimPrefix(funcs[i].FileName, trim)
+	}
+
+	switch format {
+	case "text":
+		return writeFuncsText(os.Stdout, funcs)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(funcs)
+	default:
+		return fmt.Errorf("unsupported format: %q", format)
+	}
+}
+
+// writeFuncsText writes the function coverage in the format of "go tool cover
+// -func" including the total coverage of all functions.
+func writeFuncsText(w io.Writer, funcs []FunctionCoverage) error {
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+
+	var total, covered int64
+	for _, f := range funcs {
+		fmt.Fprintf(tw, "%s:%d:\t%s\t%.1f%%\n", f.FileName, f.Line, f.Name, f.Percent())
+		if !f.Closure {
+			total += f.TotalStmt
+			covered += f.CoveredStmt
+		}
+	}
+
+	totalPercent := 0.0
+	if total > 0 {
+		totalPercent = float64(covered) / float64(total) * 100
+	}
+	fmt.Fprintf(tw, "total:\t(statements)\t%.1f%%\n", totalPercent)
+
+	return tw.Flush()
+}
+
+func writeLinesText(w io.Writer, lines map[string]LineCoverage) error {
+	for _, fileName := range sortedKeys(lines) {
+		lc := lines[fileName]
+		numbers := lc.sortedLines()
+
+		// Print consecutive lines with the same coverage as a single range.
+		for i := 0; i < len(numbers); {
+			j := i
+			for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 && lc[numbers[j+1]] == lc[numbers[i]] {
+				j++
+			}
+
+			status := "uncovered"
+			if lc[numbers[i]] {
+				status = "covered"
+			}
+
+			lineRange := fmt.Sprint(numbers[i])
+			if i != j {
+				lineRange = fmt.Sprintf("%d-%d", numbers[i], numbers[j])
+			}
+
+			if _, err := fmt.Fprintf(w, "%s:%s %s\n", fileName, lineRange, status); err != nil {
+				return err
+			}
+			i = j + 1
+		}
+	}
+
+	return nil
+}
+
+func writeLinesJSON(w io.Writer, lines map[string]LineCoverage) error {
+	type fileLines struct {
+		Covered   []int `json:"covered"`
+		Uncovered []int `json:"uncovered"`
+	}
+
+	result := make(map[string]fileLines, len(lines))
+	for fileName, lc := range lines {
+		result[fileName] = fileLines{Covered: lc.Covered(), Uncovered: lc.Uncovered()}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(result)
+}
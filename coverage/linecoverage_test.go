@@ -0,0 +1,47 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverage_Lines(t *testing.T) {
+	cov := New([]*Profile{{
+		FileName: "example.com/calculator/math.go",
+		Blocks: []ProfileBlock{
+			{StartLine: 17, StartCol: 36, EndLine: 18, EndCol: 12, NumStmt: 1, Count: 1},
+			{StartLine: 18, StartCol: 12, EndLine: 20, EndCol: 3, NumStmt: 1, Count: 0},
+			{StartLine: 21, StartCol: 2, EndLine: 21, EndCol: 14, NumStmt: 1, Count: 1},
+		},
+	}})
+
+	lines := cov.lines(sourceTree{}, nil)
+	assert.Equal(t, LineCoverage{17: true, 18: true, 19: false, 20: false, 21: true}, lines["example.com/calculator/math.go"])
+
+	// With the AST, the function signature and closing brace are dropped.
+	lines = cov.lines(sourceTree{}, NewStatementLineMapper())
+	lc := lines["example.com/calculator/math.go"]
+	assert.Equal(t, []int{18, 21}, lc.Covered())
+	assert.Equal(t, []int{19}, lc.Uncovered())
+}
+
+func TestWriteLinesText(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeLinesText(&buf, map[string]LineCoverage{
+		"b.go": {1: true},
+		"a.go": {1: true, 2: true, 3: false, 5: false, 6: true},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a.go:1-2 covered\na.go:3 uncovered\na.go:5 uncovered\na.go:6 covered\nb.go:1 covered\n", buf.String())
+}
+
+func TestWriteLinesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeLinesJSON(&buf, map[string]LineCoverage{"a.go": {1: true, 2: false}})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a.go": {"covered": [1], "uncovered": [2]}}`, buf.String())
+}
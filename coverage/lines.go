@@ -0,0 +1,144 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// maxLineNumber is the largest line number that is accepted in coverage
+// profiles and diffs. Larger values only occur in corrupted input and would
+// make us iterate over absurdly large line ranges.
+const maxLineNumber = 1 << 24
+
+// truncationMarker is appended to source lines that were cut off because they
+// exceed the maximum line length.
+const truncationMarker = " …[truncated]"
+
+// defaultMaxLineLength is the maximum number of bytes of a single line that
+// is kept when reading input files unless another maximum is set via the
+// -max-line-length flag. Longer lines (e.g. in generated files) are truncated.
+const defaultMaxLineLength = 1 << 20
+
+// utf8BOM is the byte order mark that some Windows editors and tools write at
+// the beginning of UTF-8 files.
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// trimBOM removes a leading UTF-8 byte order mark from data.
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
+
+// lineReader reads lines of arbitrary length like a bufio.Scanner but
+// truncates lines longer than its maximum length instead of failing. Both LF
+// and CRLF line endings are accepted and a leading UTF-8 byte order mark is
+// skipped, so files written on Windows are read like any other file.
+type lineReader struct {
+	r         *bufio.Reader
+	maxLen    int
+	line      []byte
+	truncated bool
+	err       error
+}
+
+// lineLimit returns the maximum line length n or the defaultMaxLineLength if n
+// is not positive.
+func lineLimit(n int) int {
+	if n <= 0 {
+		return defaultMaxLineLength
+	}
+
+	return n
+}
+
+// newLineReader returns a lineReader that keeps at most maxLen bytes of each
+// line read from r (see lineLimit).
+func newLineReader(r io.Reader, maxLen int) *lineReader {
+	br := bufio.NewReader(r)
+	if prefix, _ := br.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	return &lineReader{r: br, maxLen: lineLimit(maxLen)}
+}
+
+// Scan advances to the next line and reports whether there was one.
+func (lr *lineReader) Scan() bool {
+	if lr.err != nil {
+		return false
+	}
+
+	lr.line = lr.line[:0]
+	lr.truncated = false
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.append(chunk)
+
+		switch {
+		case err == nil:
+			lr.trimEOL()
+			return true
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			lr.err = err
+			if len(chunk) == 0 && len(lr.line) == 0 && !lr.truncated {
+				return false
+			}
+			lr.trimEOL()
+			return true
+		default:
+			lr.err = err
+			return false
+		}
+	}
+}
+
+// append adds as much of chunk to the current line as fits.
+func (lr *lineReader) append(chunk []byte) {
+	room := lr.maxLen - len(lr.line)
+	if len(chunk) <= room {
+		lr.line = append(lr.line, chunk...)
+		return
+	}
+
+	room = max(room, 0)
+	lr.line = append(lr.line, chunk[:room]...)
+
+	// The line ending itself does not count towards the line length.
+	if len(bytes.TrimRight(chunk[room:], "\r\n")) > 0 {
+		lr.truncated = true
+	}
+}
+
+func (lr *lineReader) trimEOL() {
+	lr.line = bytes.TrimSuffix(lr.line, []byte("\n"))
+	lr.line = bytes.TrimSuffix(lr.line, []byte("\r"))
+}
+
+// Text returns the current line without its line ending. If the line was
+// truncated, only its first bytes are returned.
+func (lr *lineReader) Text() string {
+	return string(lr.line)
+}
+
+// Bytes returns the current line like Text but without allocating a string.
+// The returned slice is only valid until the next call to Scan.
+func (lr *lineReader) Bytes() []byte {
+	return lr.line
+}
+
+// Truncated reports whether the current line exceeded the maximum length.
+func (lr *lineReader) Truncated() bool {
+	return lr.truncated
+}
+
+// Err returns the first non-EOF error that was encountered.
+func (lr *lineReader) Err() error {
+	if errors.Is(lr.err, io.EOF) {
+		return nil
+	}
+
+	return lr.err
+}
@@ -0,0 +1,87 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineReader(t *testing.T) {
+	input := "short\r\n" + strings.Repeat("a", 10) + "\n" + strings.Repeat("b", 11) + "\n\nlast"
+	lr := newLineReader(strings.NewReader(input), 10)
+
+	type line struct {
+		text      string
+		truncated bool
+	}
+
+	var lines []line
+	for lr.Scan() {
+		lines = append(lines, line{lr.Text(), lr.Truncated()})
+	}
+	require.NoError(t, lr.Err())
+
+	assert.Equal(t, []line{
+		{"short", false},
+		{"aaaaaaaaaa", false},
+		{"bbbbbbbbbb", true},
+		{"", false},
+		{"last", false},
+	}, lines)
+}
+
+func TestLineReader_ByteOrderMark(t *testing.T) {
+	lr := newLineReader(strings.NewReader("\xEF\xBB\xBFmode: set\r\n\xEF\xBB\xBFsecond\r\n"), 100)
+
+	var lines []string
+	for lr.Scan() {
+		lines = append(lines, lr.Text())
+	}
+	require.NoError(t, lr.Err())
+
+	// Only the byte order mark at the beginning of the file is removed.
+	assert.Equal(t, []string{"mode: set", "\xEF\xBB\xBFsecond"}, lines)
+
+	lr = newLineReader(strings.NewReader("\xEF\xBB"), 100)
+	require.True(t, lr.Scan())
+	assert.Equal(t, "\xEF\xBB", lr.Text())
+}
+
+func TestLineReader_LongerThanBuffer(t *testing.T) {
+	long := strings.Repeat("x", 10_000)
+	lr := newLineReader(strings.NewReader(long+"\n"+long), 20_000)
+
+	require.True(t, lr.Scan())
+	assert.Equal(t, long, lr.Text())
+	assert.False(t, lr.Truncated())
+	require.True(t, lr.Scan())
+	assert.Equal(t, long, lr.Text())
+	assert.False(t, lr.Scan())
+	require.NoError(t, lr.Err())
+}
+
+func TestReadSourceLines_Truncated(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "gen.go")
+	err := os.WriteFile(fileName, []byte("package gen\n\nvar data = \"äöüäöüäöü\"\n"), 0644)
+	require.NoError(t, err)
+
+	tree := sourceTree{maxLineLength: 15} // cuts the "ö" in half
+	lines, err := tree.readLines(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, "package gen", lines[1])
+	assert.Equal(t, "var data = \"ä"+truncationMarker, lines[3])
+}
+
+func TestReadSourceLines_CRLF(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "win.go")
+	err := os.WriteFile(fileName, []byte("\xEF\xBB\xBFpackage win\r\n\r\nfunc F() {}\r\n"), 0644)
+	require.NoError(t, err)
+
+	lines, err := sourceTree{}.readLines(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "package win", 2: "", 3: "func F() {}"}, lines)
+}
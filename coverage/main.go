@@ -0,0 +1,771 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var usage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s [OPTIONS] <OLD_COVERAGE_FILE> <NEW_COVERAGE_FILE> <CHANGED_FILES_FILE>
+       %[1]s config lint|explain [OPTIONS]
+       %[1]s share [OPTIONS] <OLD_COVERAGE_FILE> <NEW_COVERAGE_FILE> <CHANGED_FILES_FILE>
+       %[1]s history record [OPTIONS] <COVERAGE_FILE>
+       %[1]s history backfill [OPTIONS] <DIRECTORY>
+       %[1]s history import [OPTIONS] <MODULE> <VERSION...>
+       %[1]s history check [OPTIONS]
+       %[1]s site [OPTIONS]
+       %[1]s description [OPTIONS] <REPORT_FILE>
+       %[1]s compare-reports [OPTIONS] <OLD_REPORT_FILE> <NEW_REPORT_FILE>
+       %[1]s lines [OPTIONS] <COVERAGE_FILE>
+       %[1]s uncovered [OPTIONS] <COVERAGE_FILE>
+       %[1]s action [OPTIONS]
+       %[1]s deployment-review [OPTIONS]
+       %[1]s protect [OPTIONS] <REPOSITORY...>
+       %[1]s release-report [OPTIONS] <OLD_COVERAGE_FILE> <NEW_COVERAGE_FILE>
+       %[1]s manifest [OPTIONS] <COVERAGE_FILE>
+       %[1]s badges [OPTIONS] <COVERAGE_FILE>
+       %[1]s render-fixture [OPTIONS] <DIRECTORY> [NAME...]
+       %[1]s version [-check]
+       %[1]s update [OPTIONS]
+
+Parse the OLD_COVERAGE_FILE and NEW_COVERAGE_FILE and compare the coverage of the
+files listed in CHANGED_FILES_FILE. The result is printed to stdout as a simple
+Markdown table with emojis indicating the coverage change per package.
+
+You can use the -root flag to add a prefix to all paths in the list of changed
+files. This is useful to map the changed files (e.g., ["foo/my_file.go"] to their
+coverage profile which uses the full package name to identify the files
+(e.g., "github.com/fgrosse/example/foo/my_file.go"). Note that currently,
+packages with a different name than their directory are not supported.
+
+ARGUMENTS:
+  OLD_COVERAGE_FILE   The path to the old coverage file in the format produced by go test -coverprofile
+                      or an LCOV tracefile such as the coverage.dat of bazel coverage
+  NEW_COVERAGE_FILE   The path to the new coverage file in the same format as OLD_COVERAGE_FILE, or a
+                      comma separated list of the coverage files of a CI matrix with optional labels
+                      (e.g. "linux=cover-linux.out,windows=cover-windows.out"), which are merged
+  CHANGED_FILES_FILE  The path to the file containing the list of changed files encoded as JSON string array
+
+All options can also be set via the "options" object of the configuration file or
+via environment variables named after the flag (e.g. GO_COVERAGE_REPORT_MIN_COVERAGE).
+Flags take precedence over environment variables, which take precedence over the
+configuration file.
+
+OPTIONS:
+  -diff string
+        Path to git diff file (unified diff format) for accurate line-level coverage calculation
+`, filepath.Base(os.Args[0])))
+
+type options struct {
+	root        string
+	trim        string
+	format      string
+	minCoverage float64
+	diffFile    string
+	configFile  string
+	baseRef     string
+	commit      string
+	signKey     string // see -sign-key
+	signature   string // see -signature
+	previous    string
+	pkgCoverage string
+	testJSON    string
+	testFiles   string
+	fillFrom    string
+	repoRoot    string
+	fetchSource string
+	only        string
+	ignoreFile  string
+	pathPlugin  string
+	timeout     time.Duration
+	sampleAbove int
+	sampleRate  float64
+	epsilon     float64
+
+	excludeWiring   bool
+	skipDeprecated  bool
+	perCommit       bool
+	neutral         bool
+	grade           bool
+	testGaps        bool
+	reviewEffort    bool
+	requirePkgCover bool
+	strict          bool
+	quiet           bool
+	maxLineLength   int
+	htmlTheme       string
+	theme           string
+	layout          string
+	precision       int
+	numberLocale    string
+
+	fetcher  sourceFetcher // set by the action instead of fetchSource
+	flags    []string      // effective flags (see effectiveFlags)
+	config   *Config       // loaded from configFile
+	progress *progress     // set by the command unless quiet
+	log      io.Writer     // notes and warnings of the analysis; nil discards them
+}
+
+// logf writes a note or warning of the analysis to the log of the options.
+func (o options) logf(format string, args ...any) {
+	if o.log != nil {
+		fmt.Fprintf(o.log, format+"\n", args...)
+	}
+}
+
+// subcommands maps the name of each subcommand to the function that executes
+// it with the remaining command line arguments.
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"config":            runConfigCommand,
+	"share":             runShareCommand,
+	"history":           runHistoryCommand,
+	"site":              runSiteCommand,
+	"description":       runDescriptionCommand,
+	"compare-reports":   runCompareReportsCommand,
+	"lines":             runLinesCommand,
+	"uncovered":         runUncoveredCommand,
+	"action":            runActionCommand,
+	"deployment-review": runDeploymentReviewCommand,
+	"protect":           runProtectCommand,
+	"release-report":    runReleaseReportCommand,
+	"manifest":          runManifestCommand,
+	"badges":            runBadgesCommand,
+	"render-fixture":    runRenderFixtureCommand,
+	"version":           runVersionCommand,
+	"update":            runUpdateCommand,
+}
+
+// Main runs the go-coverage-report command of the given release version
+// (e.g. "1.5.1" or "dev") with the arguments of the process and exits on
+// errors.
+func Main(releaseVersion string) {
+	log.SetFlags(0)
+	version = releaseVersion
+
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			timeout, err := subcommandTimeout(os.Args[1], os.LookupEnv)
+			if err != nil {
+				log.Fatalln("ERROR:", err)
+			}
+
+			ctx, cancel := newCommandContext(timeout)
+			err = commandError(ctx, cmd(ctx, os.Args[2:]))
+			cancel()
+			if err != nil {
+				log.Fatalln("ERROR:", err)
+			}
+			return
+		}
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+
+	registerFlags(flag.CommandLine)
+
+	oldCov, newCov, changedFiles, opts := programArgs()
+	ctx, cancel := newCommandContext(opts.timeout)
+	err := commandError(ctx, run(ctx, oldCov, newCov, changedFiles, opts))
+	cancel()
+	if err != nil {
+		log.Fatalln("ERROR:", err)
+	}
+}
+
+// registerFlags defines all flags of the main command on the given FlagSet.
+func registerFlags(fs *flag.FlagSet) {
+	fs.String("root", "", "The import path of the tested repository to add as prefix to all paths of the changed files")
+	fs.String("trim", "", "trim a prefix in the \"Impacted Packages\" column of the markdown report")
+	fs.String("format", "markdown", "output format: markdown, json, html, html-fragment, rdjson, rdjsonl (reviewdog diagnostic format), csv, pdf (audit exports) or term-diff (side-by-side view for terminals)")
+	fs.String("commit", "", "commit SHA of the new coverage that is recorded in the csv and pdf formats")
+	fs.String("sign-key", "", "PEM file with an Ed25519 private key (PKCS #8) to sign the report with, e.g. the csv and pdf audit exports (requires -signature)")
+	fs.String("signature", "", "file to write the detached Ed25519 signature of the report to (requires -sign-key)")
+	fs.Float64("min-coverage", 0, "minimum coverage threshold for new code in percentage (0 to disable)")
+	fs.String("diff", "", "path to git diff file (unified diff format) for accurate line-level coverage calculation")
+	fs.String("config", "", "path to an optional JSON configuration file")
+	fs.String("previous", "", "JSON report (-format=json) of a previous run on the same pull request; the analysis of files whose coverage, diff and source did not change is reused")
+	fs.String("only", "", "comma separated glob patterns (e.g. 'pkg/service/**') to restrict the report to matching files; paths are relative to -root")
+	fs.String("ignore-file", "", "file with gitignore style patterns of files to leave out of the report; paths are relative to -root (default: .coverageignore in -repo-root if it exists)")
+	fs.String("path-plugin", "", "Go plugin (.so) exporting MapPath and/or Classify functions to map the file names of the coverage files and to exclude files (e.g. for custom build systems); requires a binary built with cgo")
+	fs.String("repo-root", "", "directory of the repository; source files are only read from inside of it (default: search relative to the working directory)")
+	fs.String("fetch-source", "", "fetch the source code of changed files that are not available locally (e.g. the head of a pull request from a fork) from git:REV (via git show) or github:OWNER/REPO@REF (via the contents API, authenticated by GH_TOKEN or GITHUB_TOKEN)")
+	fs.String("base-ref", "", "git revision of the old coverage; without -diff, code blocks are matched by their source code to detect moved code")
+	fs.Bool("per-commit", false, "show the coverage of the new lines of each commit since -base-ref, attributing lines via git blame (requires -base-ref)")
+	fs.String("package-coverage", "", "coverage file of the same tests recorded without -coverpkg; new code that is only covered by tests of other packages is marked as covered by external tests")
+	fs.Bool("require-package-coverage", false, "treat new code that is only covered by tests of other packages as uncovered (requires -package-coverage)")
+	fs.String("fill-not-built", "", "comma separated list of coverage files of other platforms (e.g. 'windows=cover-windows.out'), whose profiles of changed files that are not built on this platform are merged into the new coverage")
+	fs.String("test-file-coverage", "", "comma separated list of changed test files and the coverage files of only their tests (e.g. 'pkg/a_test.go=a.out,pkg/b_test.go=b.out'); the report shows how many statements each test file covers uniquely; paths are relative to -root")
+	fs.String("test-json", "", "output of \"go test -json\"; skipped tests of changed packages and of packages whose coverage decreased are listed in the report")
+	fs.Bool("neutral", false, "fail if the coverage changes at all, e.g. for mechanical refactorings; the blocks whose coverage differs are listed in the report")
+	fs.Float64("neutral-epsilon", 0.01, "maximum change of a package coverage in percentage points that is tolerated with -neutral")
+	fs.Bool("test-gaps", false, "add a \"Test Gap Priorities\" section that ranks the changed files with uncovered new code by where tests are needed most, weighted by the \"criticality\" of the config file")
+	fs.Bool("review-effort", false, "estimate in the summary of the report how much effort reviewing the pull request takes, based on the new statements, how many of them are uncovered and the changed files with falling coverage")
+	fs.Bool("grade", false, "show a composite grade (A-F) of new code coverage, overall coverage change and error path coverage in the title; weights can be set via the \"grade\" object of the config file")
+	fs.Bool("exclude-deprecated", false, "do not count new code of functions with a \"Deprecated: \" doc comment as new code, so it does not affect the thresholds; changed deprecated functions are listed in the report either way")
+	fs.Bool("exclude-wiring", false, "exclude func main and dependency injection wiring code (wire_gen.go, fx modules) from the coverage calculation")
+	fs.String("theme", themeClassic, "emojis and tone of the markdown report: classic, minimal, strict-no-fun or celebratory")
+	fs.String("layout", layoutFlat, "structure of the markdown report: flat, drilldown (nested package, file and function sections) or auto (drilldown from 50 changed files)")
+	fs.Int("precision", defaultNumberFormat.Precision, "decimal places of percentages in the markdown, html, pdf and term-diff formats (0 to 3)")
+	fs.String("number-locale", "", "thousands and decimal separators of numbers in the markdown, html, pdf and term-diff formats, e.g. en (1,234,567.89) or de (1.234.567,89); empty for none")
+	fs.String("html-theme", htmlThemeAuto, "color theme of the html and html-fragment formats: auto, light or dark")
+	fs.Duration("timeout", 0, "abort if the report is not done after this duration (e.g. 5m); 0 disables the timeout")
+	fs.Int("sample-above", 0, "estimate the total and package coverage from a sample of files if the coverage files are larger than this many MB (0 to disable)")
+	fs.Float64("sample-rate", 0.1, "fraction of files to sample when -sample-above is exceeded")
+	fs.Bool("strict", false, "fail instead of silently falling back to heuristics (path suffix matching of the diff, estimated statement counts, missing diffs or unreadable source code)")
+	fs.Bool("quiet", false, "do not report the progress of the analysis on stderr")
+	fs.Int("max-line-length", defaultMaxLineLength, "maximum length of a line in bytes when reading input and source files; longer lines are truncated")
+}
+
+func programArgs() (oldCov, newCov, changedFile string, opts options) {
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 3 {
+		if len(args) > 0 {
+			log.Printf("ERROR: Expected exactly 3 arguments but got %d\n\n", len(args))
+		}
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg, _, err := resolveFlags(flag.CommandLine, os.LookupEnv)
+	if err != nil {
+		log.Fatalln("ERROR:", err)
+	}
+
+	opts = optionsFromFlags(flag.CommandLine)
+	opts.config = cfg
+	opts.log = os.Stderr
+
+	return args[0], args[1], args[2], opts
+}
+
+// optionsFromFlags returns the options of the main command from a FlagSet
+// that was set up via registerFlags.
+func optionsFromFlags(fs *flag.FlagSet) options {
+	var minCoverage float64
+	fmt.Sscanf(fs.Lookup("min-coverage").Value.String(), "%f", &minCoverage)
+
+	var maxLineLength int
+	fmt.Sscanf(fs.Lookup("max-line-length").Value.String(), "%d", &maxLineLength)
+
+	timeout, _ := time.ParseDuration(fs.Lookup("timeout").Value.String())
+
+	var sampleAbove int
+	fmt.Sscanf(fs.Lookup("sample-above").Value.String(), "%d", &sampleAbove)
+
+	var sampleRate float64
+	fmt.Sscanf(fs.Lookup("sample-rate").Value.String(), "%f", &sampleRate)
+
+	var epsilon float64
+	fmt.Sscanf(fs.Lookup("neutral-epsilon").Value.String(), "%f", &epsilon)
+
+	var precision int
+	fmt.Sscanf(fs.Lookup("precision").Value.String(), "%d", &precision)
+
+	return options{
+		root:        fs.Lookup("root").Value.String(),
+		trim:        fs.Lookup("trim").Value.String(),
+		format:      fs.Lookup("format").Value.String(),
+		minCoverage: minCoverage,
+		diffFile:    fs.Lookup("diff").Value.String(),
+		configFile:  fs.Lookup("config").Value.String(),
+		baseRef:     fs.Lookup("base-ref").Value.String(),
+		commit:      fs.Lookup("commit").Value.String(),
+		signKey:     fs.Lookup("sign-key").Value.String(),
+		signature:   fs.Lookup("signature").Value.String(),
+		previous:    fs.Lookup("previous").Value.String(),
+		pkgCoverage: fs.Lookup("package-coverage").Value.String(),
+		testJSON:    fs.Lookup("test-json").Value.String(),
+		testFiles:   fs.Lookup("test-file-coverage").Value.String(),
+		fillFrom:    fs.Lookup("fill-not-built").Value.String(),
+		repoRoot:    fs.Lookup("repo-root").Value.String(),
+		fetchSource: fs.Lookup("fetch-source").Value.String(),
+		only:        fs.Lookup("only").Value.String(),
+		ignoreFile:  fs.Lookup("ignore-file").Value.String(),
+		pathPlugin:  fs.Lookup("path-plugin").Value.String(),
+		timeout:     timeout,
+		sampleAbove: sampleAbove,
+		sampleRate:  sampleRate,
+		epsilon:     epsilon,
+
+		excludeWiring:   fs.Lookup("exclude-wiring").Value.String() == "true",
+		skipDeprecated:  fs.Lookup("exclude-deprecated").Value.String() == "true",
+		perCommit:       fs.Lookup("per-commit").Value.String() == "true",
+		neutral:         fs.Lookup("neutral").Value.String() == "true",
+		grade:           fs.Lookup("grade").Value.String() == "true",
+		testGaps:        fs.Lookup("test-gaps").Value.String() == "true",
+		reviewEffort:    fs.Lookup("review-effort").Value.String() == "true",
+		requirePkgCover: fs.Lookup("require-package-coverage").Value.String() == "true",
+		strict:          fs.Lookup("strict").Value.String() == "true",
+		quiet:           fs.Lookup("quiet").Value.String() == "true",
+		maxLineLength:   maxLineLength,
+		htmlTheme:       fs.Lookup("html-theme").Value.String(),
+		theme:           fs.Lookup("theme").Value.String(),
+		layout:          fs.Lookup("layout").Value.String(),
+		precision:       precision,
+		numberLocale:    fs.Lookup("number-locale").Value.String(),
+
+		flags: effectiveFlags(fs),
+	}
+}
+
+func run(ctx context.Context, oldCovPath, newCovPath, changedFilesPath string, opts options) error {
+	var signingKey ed25519.PrivateKey
+	if opts.signKey != "" || opts.signature != "" {
+		if opts.signKey == "" || opts.signature == "" {
+			return errors.New("-sign-key and -signature must be used together")
+		}
+		var err error
+		signingKey, err = loadSigningKey(opts.signKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts.progress = newCommandProgress(opts.quiet)
+	opts.progress.begin()
+	defer opts.progress.end()
+
+	result, err := analyze(ctx, oldCovPath, newCovPath, changedFilesPath, opts)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		log.Println("Skipping report since there are no changed files")
+		return nil
+	}
+
+	defer result.Close()
+
+	if warning := result.identicalProfilesWarning(); warning != "" {
+		log.Println("WARNING:", warning)
+	}
+
+	opts.progress.step("Rendering the %s report", opts.format)
+
+	// The signature covers exactly the bytes that are written to stdout.
+	var out io.Writer = os.Stdout
+	var signed bytes.Buffer
+	if signingKey != nil {
+		out = io.MultiWriter(os.Stdout, &signed)
+	}
+
+	switch strings.ToLower(opts.format) {
+	case "markdown":
+		fmt.Fprintln(out, result.Markdown())
+	case "json":
+		result.Reproduction, err = newReproduction(oldCovPath, newCovPath, changedFilesPath, opts)
+		if err != nil {
+			return fmt.Errorf("failed to hash inputs: %w", err)
+		}
+		result.Summary.Generated = time.Now().UTC()
+		fmt.Fprintln(out, result.JSON())
+	case "html":
+		fmt.Fprintln(out, result.HTML())
+	case "html-fragment":
+		fmt.Fprintln(out, result.HTMLFragment())
+	case "rdjson":
+		fmt.Fprintln(out, result.RDJSON())
+	case "rdjsonl":
+		if diagnostics := result.RDJSONL(); diagnostics != "" {
+			fmt.Fprintln(out, diagnostics)
+		}
+	case "csv":
+		fmt.Fprint(out, result.CSV(time.Now()))
+	case "pdf":
+		if _, err := out.Write(result.PDF(time.Now())); err != nil {
+			return err
+		}
+	case "term-diff":
+		fmt.Fprintln(out, result.TermDiff(terminalWidth(), os.Getenv("NO_COLOR") == ""))
+	default:
+		return fmt.Errorf("unsupported format: %q", opts.format)
+	}
+
+	if signingKey != nil {
+		if err := os.WriteFile(opts.signature, ed25519.Sign(signingKey, signed.Bytes()), 0644); err != nil {
+			return fmt.Errorf("failed to write signature: %w", err)
+		}
+	}
+
+	return result.Err
+}
+
+// loadReport parses all inputs of the main command and returns the Report. If
+// no changed files remain after filtering, the returned report is nil. The
+// report must be closed once it was rendered (see Report.Close).
+func loadReport(ctx context.Context, oldCovPath, newCovPath, changedFilesPath string, opts options) (report *Report, err error) {
+	if opts.maxLineLength <= 0 {
+		return nil, fmt.Errorf("invalid max line length %d: must be greater than 0", opts.maxLineLength)
+	}
+	parse := parseOptions{maxLineLength: opts.maxLineLength, lcovRoot: opts.root}
+
+	var pathPlugin *PathPlugin
+	if opts.pathPlugin != "" {
+		var err error
+		pathPlugin, err = LoadPathPlugin(opts.pathPlugin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load path plugin: %w", err)
+		}
+		parse.mapPath = pathPlugin.mapper()
+	}
+
+	tree, err := newSourceTree(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fetch := opts.fetcher
+	if fetch == nil && opts.fetchSource != "" {
+		if fetch, err = parseSourceFetcher(opts.fetchSource, tree.root); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.htmlTheme {
+	case "", htmlThemeAuto, htmlThemeLight, htmlThemeDark:
+	default:
+		return nil, fmt.Errorf("unsupported html theme: %q", opts.htmlTheme)
+	}
+
+	if err := validateLayout(opts.layout); err != nil {
+		return nil, err
+	}
+	if err := validateTheme(opts.theme); err != nil {
+		return nil, err
+	}
+	numbers, err := newNumberFormat(opts.precision, opts.numberLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.sampleRate <= 0 || opts.sampleRate > 1 {
+		return nil, fmt.Errorf("invalid sample rate %g: must be greater than 0 and at most 1", opts.sampleRate)
+	}
+
+	if opts.epsilon < 0 {
+		return nil, fmt.Errorf("invalid neutral epsilon %g: must not be negative", opts.epsilon)
+	}
+
+	if opts.requirePkgCover && opts.pkgCoverage == "" {
+		return nil, errors.New("-require-package-coverage requires -package-coverage")
+	}
+
+	changedFiles, err := ParseChangedFiles(changedFilesPath, opts.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load changed files: %w", err)
+	}
+
+	// The fetched source files are read while the report is rendered, so they
+	// are only removed here if there is no report.
+	defer func() {
+		if report == nil {
+			_ = tree.removeFetched()
+		}
+	}()
+
+	// Without the source code, the new code and the analyses of the syntax
+	// tree of a file are left out of the report.
+	if fetch != nil {
+		opts.progress.step("Fetching the source code of changed files that are not available locally")
+		fetched, missing, err := fetchMissingSources(ctx, &tree, fetch, changedFiles, opts.root, opts.logf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch source code: %w", err)
+		}
+		if fetched > 0 {
+			opts.logf("Fetched the source code of %d changed files that are not available locally", fetched)
+		}
+		if len(missing) > 0 {
+			opts.logf("WARNING: the source code of %d changed files could not be fetched, so their new code is left out of the report", len(missing))
+		}
+	} else if missing := missingSourceFiles(tree, changedFiles); len(missing) > 0 {
+		opts.logf("WARNING: the source code of %d changed files (e.g. %s) is not available, so their new code is left out of the report; see -fetch-source", len(missing), missing[0])
+	}
+
+	// The new coverage may consist of the shards of a CI matrix.
+	shards := parseShards(newCovPath)
+	newCovPaths := []string{newCovPath}
+	if shards != nil {
+		newCovPaths = shardPaths(shards)
+	}
+
+	var sample *Sample
+	if opts.sampleAbove > 0 {
+		exceeded, err := exceedsSize(opts.sampleAbove, append([]string{oldCovPath}, newCovPaths...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine size of coverage files: %w", err)
+		}
+		if exceeded {
+			opts.logf("Coverage files exceed %d MB, estimating coverage from a %g%% sample of files", opts.sampleAbove, opts.sampleRate*100)
+			sample = &Sample{Rate: opts.sampleRate}
+		}
+	}
+
+	parseCoverage := func(fileName string) (*Coverage, error) {
+		if sample == nil {
+			return parseCoverageFile(ctx, fileName, parse)
+		}
+
+		changed := make(map[string]bool, len(changedFiles))
+		for _, f := range changedFiles {
+			changed[f] = true
+		}
+		return parseCoverageSample(ctx, fileName, sample.Rate, changed, parse)
+	}
+
+	opts.progress.step("Parsing old coverage %s", oldCovPath)
+	oldCov, err := parseCoverage(oldCovPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old coverage: %w", err)
+	}
+
+	var newCov *Coverage
+	if shards == nil {
+		opts.progress.step("Parsing new coverage %s", newCovPath)
+		newCov, err = parseCoverage(newCovPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse new coverage: %w", err)
+		}
+	} else {
+		shardCovs := make([]*Coverage, len(shards))
+		for i := range shards {
+			opts.progress.step("Parsing new coverage %s of shard %q", shards[i].Path, shards[i].Label)
+			shards[i].Coverage, err = parseCoverage(shards[i].Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse new coverage of shard %q: %w", shards[i].Label, err)
+			}
+			shardCovs[i] = shards[i].Coverage
+		}
+
+		newCov, err = MergeCoverage(shardCovs...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge new coverage of shards: %w", err)
+		}
+	}
+
+	// Changed files that are excluded by their build constraints on this
+	// platform have no coverage. Their coverage may come from other platforms.
+	notBuilt := findNotBuiltFiles(tree, changedFiles, newCov, &build.Default)
+	if opts.fillFrom != "" && len(notBuilt) > 0 {
+		fill := parseFillCoverage(opts.fillFrom)
+		for i := range fill {
+			opts.progress.step("Parsing coverage %s of %q to fill files that are not built", fill[i].Path, fill[i].Label)
+			fill[i].Coverage, err = parseCoverage(fill[i].Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse coverage of %q: %w", fill[i].Label, err)
+			}
+		}
+
+		newCov, err = fillNotBuiltFiles(newCov, notBuilt, fill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fill coverage of files that are not built: %w", err)
+		}
+	}
+
+	// Restrict the whole report including the overall and package coverage
+	// to the files that are kept.
+	restrict := func(keep func(fileName string) bool) {
+		oldCov = oldCov.Filter(keep)
+		newCov = newCov.Filter(keep)
+		for i := range shards {
+			shards[i].Coverage = shards[i].Coverage.Filter(keep)
+		}
+
+		var keptNotBuilt []NotBuiltFile
+		for _, f := range notBuilt {
+			if keep(f.FileName) {
+				keptNotBuilt = append(keptNotBuilt, f)
+			}
+		}
+		notBuilt = keptNotBuilt
+
+		var filtered []string
+		for _, f := range changedFiles {
+			if keep(f) {
+				filtered = append(filtered, f)
+			}
+		}
+		changedFiles = filtered
+	}
+
+	if opts.only != "" {
+		restrict(pathFilter(opts.only, opts.root))
+	}
+
+	ignore, err := loadCoverageIgnore(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+	if ignore != nil {
+		restrict(ignore.Filter(opts.root))
+	}
+
+	if len(changedFiles) == 0 {
+		return nil, nil
+	}
+
+	// Exclude code regions marked via //coverage:off directives and other
+	// enabled exclusions. The old profile is only updated for unchanged files
+	// since we only have the current version of the source code available.
+	changed := make(map[string]bool, len(changedFiles))
+	for _, f := range changedFiles {
+		changed[f] = true
+	}
+	if pathPlugin != nil {
+		pathPlugin.ExcludeClassified(oldCov)
+		pathPlugin.ExcludeClassified(newCov)
+		for _, shard := range shards {
+			pathPlugin.ExcludeClassified(shard.Coverage)
+		}
+	}
+	opts.progress.step("Reading the source code of %d files to apply exclusions", len(newCov.Files))
+	for _, find := range exclusionFinders(opts) {
+		if err := applySourceExclusions(tree, oldCov, changed, find, opts.progress); err != nil {
+			return nil, fmt.Errorf("failed to apply exclusions to old coverage: %w", err)
+		}
+		if err := applySourceExclusions(tree, newCov, nil, find, opts.progress); err != nil {
+			return nil, fmt.Errorf("failed to apply exclusions to new coverage: %w", err)
+		}
+		for _, shard := range shards {
+			if err := applySourceExclusions(tree, shard.Coverage, nil, find, opts.progress); err != nil {
+				return nil, fmt.Errorf("failed to apply exclusions to new coverage of shard %q: %w", shard.Label, err)
+			}
+		}
+	}
+
+	// Parse diff information if provided
+	var diffInfo *DiffInfo
+	if opts.diffFile != "" {
+		opts.progress.step("Parsing diff %s", opts.diffFile)
+		diffInfo, err = parseUnifiedDiffContext(ctx, opts.diffFile, parse)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse diff file: %w", err)
+		}
+		opts.logf("Using git diff information from %s for accurate line-level coverage", opts.diffFile)
+	}
+
+	// The package coverage is only needed for the changed files.
+	var pkgCov *Coverage
+	if opts.pkgCoverage != "" {
+		pkgCov, err = parseCoverageSample(ctx, opts.pkgCoverage, 0, changed, parse)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse package coverage: %w", err)
+		}
+	}
+
+	// The coverage of the tests of single test files is only needed for the
+	// contribution of the changed test files.
+	var testFileCov map[string]*Coverage
+	if opts.testFiles != "" {
+		coverageFiles, err := parseTestFileCoverage(opts.testFiles, opts.root)
+		if err != nil {
+			return nil, err
+		}
+		testFileCov = make(map[string]*Coverage, len(coverageFiles))
+		for testFile, coverageFile := range coverageFiles {
+			testFileCov[testFile], err = parseCoverageFile(ctx, coverageFile, parse)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse coverage of test file %s: %w", testFile, err)
+			}
+		}
+	}
+
+	testOutput := new(TestOutput)
+	if opts.testJSON != "" {
+		testOutput, err = parseTestOutputFile(ctx, opts.testJSON, parse)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse test output: %w", err)
+		}
+	}
+
+	report = NewReport(oldCov, newCov, changedFiles)
+	report.source = tree
+	report.MinCoverage = opts.minCoverage
+	report.SkippedTests = testOutput.Skipped
+	report.TestTimings = testOutput.Packages
+	report.FailedPackages = testOutput.Failed
+	report.UntestedPackages = testOutput.NoTestFiles
+	report.Neutral = opts.neutral
+	report.NeutralEpsilon = opts.epsilon
+	report.Graded = opts.grade
+	report.TestGapPriorities = opts.testGaps
+	report.ReviewEffortEstimate = opts.reviewEffort
+	report.PackageCoverage = pkgCov
+	report.TestFileCoverage = testFileCov
+	report.RequirePackageCoverage = opts.requirePkgCover
+	report.ExcludeDeprecated = opts.skipDeprecated
+	report.DiffInfo = diffInfo
+	report.Config = opts.config
+	report.RootPackage = opts.root
+	report.HTMLTheme = opts.htmlTheme
+	report.Theme = opts.theme
+	report.Numbers = &numbers
+	report.Layout = opts.layout
+	report.BaseRef = opts.baseRef
+	report.Commit = opts.commit
+	report.Shards = shards
+	report.NotBuilt = notBuilt
+	if sample != nil {
+		sample.OldError = sampleError(oldCov, sample.Rate)
+		sample.NewError = sampleError(newCov, sample.Rate)
+		report.Sample = sample
+	}
+	if opts.baseRef != "" {
+		report.oldSourceLines = gitSourceLines(ctx, opts.baseRef, tree)
+	}
+	if opts.perCommit && opts.baseRef == "" {
+		return nil, fmt.Errorf("-per-commit requires -base-ref")
+	}
+	if opts.trim != "" {
+		report.TrimPrefix(opts.trim)
+	}
+	if opts.previous != "" {
+		report.Previous, err = ReadPreviousAnalysis(opts.previous)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read previous report: %w", err)
+		}
+		opts.logf("Reusing the analysis of %d of %d changed files from %s", report.ReusedFiles(), len(changedFiles), opts.previous)
+	}
+	if opts.strict {
+		opts.progress.step("Checking %d changed files for heuristic fallbacks", len(changedFiles))
+		if err := report.StrictErrors(); err != nil {
+			return nil, fmt.Errorf("strict mode: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// checkMinCoverage returns an error if the coverage of the new code of the
+// report is below the given threshold in percent. A threshold of 0 disables
+// the check.
+func checkMinCoverage(result *Result, minCoverage float64) error {
+	if minCoverage <= 0 {
+		return nil
+	}
+
+	totalNew, coveredNew := result.calculateNewCodeCoverage()
+	if totalNew > 0 {
+		newCodeCoverage := float64(coveredNew) / float64(totalNew) * 100
+		if newCodeCoverage < minCoverage {
+			return fmt.Errorf("new code coverage %.2f%% is below the required threshold of %.2f%%", newCodeCoverage, minCoverage)
+		}
+	}
+
+	return nil
+}
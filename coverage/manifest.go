@@ -0,0 +1,225 @@
+package coverage
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var manifestUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s manifest [OPTIONS] <COVERAGE_FILE>
+
+Write a coverage manifest of a Go module as JSON: the coverage of every package
+of the module together with a SHA-256 digest of its source files and the test
+commands that produced the coverage. The output only depends on its inputs, so
+it can be attached to build provenance, e.g. as predicate of an in-toto
+attestation via "cosign attest --type %s".
+
+The module path is read from the go.mod file in the working directory unless it
+is set via -module. Packages outside of the module are left out.
+
+OPTIONS:
+`, filepath.Base(os.Args[0]), manifestPredicateType))
+
+// manifestPredicateType identifies the format of the manifest.
+const manifestPredicateType = "https://github.com/fgrosse/go-coverage-report/manifest/v1"
+
+// Manifest lists the coverage of every package of a module.
+type Manifest struct {
+	PredicateType string            `json:"predicateType"`
+	Module        string            `json:"module"`
+	Commit        string            `json:"commit,omitempty"`
+	Generator     ManifestGenerator `json:"generator"`
+	TestCommands  []string          `json:"testCommands"`
+	Coverage      ManifestCoverage  `json:"coverage"`
+	Packages      []ManifestPackage `json:"packages"`
+}
+
+type ManifestGenerator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type ManifestCoverage struct {
+	Statements int64   `json:"statements"`
+	Covered    int64   `json:"covered"`
+	Percent    float64 `json:"percent"`
+}
+
+// ManifestPackage is the coverage of a single package. The digest is the
+// SHA-256 hash of the names and contents of the source files of the package
+// in the coverage profile. It is empty if the source code of any of these
+// files is not available.
+type ManifestPackage struct {
+	Package  string            `json:"package"`
+	Files    int               `json:"files"`
+	Coverage ManifestCoverage  `json:"coverage"`
+	Digest   map[string]string `json:"digest,omitempty"`
+}
+
+func runManifestCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, manifestUsage)
+		fs.PrintDefaults()
+	}
+
+	module := fs.String("module", "", "module path of the packages to include (default: read from go.mod)")
+	commit := fs.String("commit", "", "commit SHA of the coverage profile")
+	output := fs.String("o", "", "path of the manifest file (default: stdout)")
+	var testCommands []string
+	fs.Func("test-command", "test command that produced the coverage profile (can be repeated)", func(s string) error {
+		testCommands = append(testCommands, s)
+		return nil
+	})
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly 1 argument but got %d", fs.NArg())
+	}
+
+	if *module == "" {
+		var err error
+		*module, err = readModulePath("go.mod")
+		if err != nil {
+			return fmt.Errorf("failed to determine module path (use -module): %w", err)
+		}
+	}
+
+	cov, err := ParseCoverageContext(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to parse coverage: %w", err)
+	}
+
+	m := newManifest(sourceTree{}, cov, *module, *commit, testCommands)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(*output, data, 0644)
+}
+
+// newManifest returns the manifest of all packages of the given module in
+// the coverage. The digests of the packages are computed from the source code
+// in the tree.
+func newManifest(tree sourceTree, cov *Coverage, module, commit string, testCommands []string) *Manifest {
+	m := &Manifest{
+		PredicateType: manifestPredicateType,
+		Module:        module,
+		Commit:        commit,
+		Generator:     ManifestGenerator{Name: "go-coverage-report", Version: normalizeVersion(version)},
+		TestCommands:  testCommands,
+		Packages:      []ManifestPackage{},
+	}
+	if m.TestCommands == nil {
+		m.TestCommands = []string{}
+	}
+
+	cov = cov.Filter(func(fileName string) bool {
+		pkg := path.Dir(fileName)
+		return pkg == module || strings.HasPrefix(pkg, module+"/")
+	})
+	m.Coverage = manifestCoverage(cov)
+
+	for pkg, pkgCov := range cov.ByPackage() {
+		p := ManifestPackage{
+			Package:  pkg,
+			Files:    len(pkgCov.Files),
+			Coverage: manifestCoverage(pkgCov),
+		}
+		if digest, ok := sourceDigest(tree, sortedKeys(pkgCov.Files)); ok {
+			p.Digest = map[string]string{"sha256": digest}
+		}
+		m.Packages = append(m.Packages, p)
+	}
+
+	sort.Slice(m.Packages, func(i, j int) bool {
+		return m.Packages[i].Package < m.Packages[j].Package
+	})
+
+	return m
+}
+
+func manifestCoverage(cov *Coverage) ManifestCoverage {
+	return ManifestCoverage{
+		Statements: cov.TotalStmt,
+		Covered:    cov.CoveredStmt,
+		Percent:    roundPercent(cov.Percent()),
+	}
+}
+
+// roundPercent rounds to two decimals so that the manifest does not depend on
+// floating point formatting details.
+func roundPercent(p float64) float64 {
+	return float64(int64(p*100+0.5)) / 100
+}
+
+// sourceDigest returns the hex encoded SHA-256 hash of the names and contents
+// of the given files of a coverage profile. It returns false if any of the
+// files can't be read (see sourceTree.find).
+func sourceDigest(tree sourceTree, fileNames []string) (string, bool) {
+	h := sha256.New()
+	for _, fileName := range fileNames {
+		sourcePath, ok := tree.find(fileName)
+		if !ok {
+			return "", false
+		}
+
+		f, err := os.Open(sourcePath)
+		if err != nil {
+			return "", false
+		}
+
+		info, err := f.Stat()
+		if err == nil {
+			fmt.Fprintf(h, "%s\x00%d\x00", fileName, info.Size())
+			_, err = io.Copy(h, f)
+		}
+		f.Close()
+		if err != nil {
+			return "", false
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// readModulePath returns the module path declared in the given go.mod file.
+func readModulePath(goModFile string) (string, error) {
+	f, err := os.Open(goModFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("%s does not declare a module", goModFile)
+}
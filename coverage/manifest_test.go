@@ -0,0 +1,60 @@
+package coverage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewManifest(t *testing.T) {
+	cov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	cov.add(&Profile{FileName: "example.com/other/other.go", TotalStmt: 1})
+
+	m := newManifest(sourceTree{}, cov, "github.com/fgrosse/prioqueue", "2222222222", []string{"go test -coverprofile=coverage.txt ./..."})
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	again, err := json.Marshal(newManifest(sourceTree{}, cov, "github.com/fgrosse/prioqueue", "2222222222", []string{"go test -coverprofile=coverage.txt ./..."}))
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again), "the manifest must be stable")
+
+	assert.Equal(t, manifestPredicateType, m.PredicateType)
+	assert.Equal(t, ManifestCoverage{Statements: cov.TotalStmt - 1, Covered: cov.CoveredStmt, Percent: 90.2}, m.Coverage)
+	require.Len(t, m.Packages, 1)
+	assert.Equal(t, "github.com/fgrosse/prioqueue", m.Packages[0].Package)
+	assert.Nil(t, m.Packages[0].Digest, "the source code is not available")
+}
+
+func TestSourceDigest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "svc")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("package svc\n"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("package svc\n\nvar x = 1\n"), 0644))
+
+	digest, ok := sourceDigest(sourceTree{}, []string{a, b})
+	require.True(t, ok)
+	assert.Len(t, digest, 64)
+
+	require.NoError(t, os.WriteFile(b, []byte("package svc\n\nvar x = 2\n"), 0644))
+	changed, ok := sourceDigest(sourceTree{}, []string{a, b})
+	require.True(t, ok)
+	assert.NotEqual(t, digest, changed)
+
+	_, ok = sourceDigest(sourceTree{}, []string{a, filepath.Join(dir, "missing.go")})
+	assert.False(t, ok)
+}
+
+func TestReadModulePath(t *testing.T) {
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	require.NoError(t, os.WriteFile(goMod, []byte("// comment\nmodule github.com/acme/app\n\ngo 1.21\n"), 0644))
+
+	module, err := readModulePath(goMod)
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/acme/app", module)
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package coverage
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the file into memory and returns its content. The returned
+// function unmaps the file and must be called once the data is not used
+// anymore. Since the data is read-only, it must never be modified.
+func mmapFile(fileName string) (data []byte, closeFile func() error, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close() // the mapping stays valid after closing the file
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := info.Size()
+	if size == 0 || !info.Mode().IsRegular() || int64(int(size)) != size {
+		// Empty files cannot be mapped and pipes or devices have no size,
+		// so we read them the regular way.
+		data, err := os.ReadFile(fileName)
+		return data, func() error { return nil }, err
+	}
+
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: fileName, Err: err}
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,256 @@
+package coverage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxNeutralityBlocks limits the number of differing blocks that are listed in
+// the Markdown report.
+const maxNeutralityBlocks = 50
+
+// Neutrality is the result of comparing the old and new coverage of a PR that
+// must not change coverage at all, e.g. a mechanical refactoring (see the
+// -neutral flag).
+type Neutrality struct {
+	OldCovered, NewCovered int64
+	Packages               []PackageDifference // packages whose coverage changed by more than the epsilon
+	Blocks                 []BlockDifference   // blocks whose coverage differs
+}
+
+// PackageDifference is the coverage of a package before and after the PR.
+type PackageDifference struct {
+	Package  string
+	Old, New float64
+}
+
+// BlockDifference is a block that only exists in the old or new coverage or
+// whose coverage changed.
+type BlockDifference struct {
+	FileName           string
+	StartLine, EndLine int
+	NumStmt            int
+	Change             string // "now covered", "now uncovered", "removed" or "added"
+	Covered            bool   // whether the removed or added block is covered
+}
+
+// Neutral returns whether the covered statements and all package percentages
+// are unchanged.
+func (n Neutrality) Neutral() bool {
+	return n.OldCovered == n.NewCovered && len(n.Packages) == 0
+}
+
+// Neutrality compares the old and new coverage. Package percentages may change
+// by at most epsilon percentage points. Blocks of changed files are matched by
+// their source code if it is available (see BaseRef), so that moved code is
+// not reported as removed and added.
+func (r *Report) Neutrality(epsilon float64) Neutrality {
+	result := Neutrality{OldCovered: r.Old.CoveredStmt, NewCovered: r.New.CoveredStmt}
+
+	oldCovPkgs := r.Old.ByPackage()
+	newCovPkgs := r.New.ByPackage()
+	for pkg := range unionKeys(oldCovPkgs, newCovPkgs) {
+		var oldPercent, newPercent float64
+		if cov, ok := oldCovPkgs[pkg]; ok {
+			oldPercent = cov.Percent()
+		}
+		if cov, ok := newCovPkgs[pkg]; ok {
+			newPercent = cov.Percent()
+		}
+
+		if math.Abs(newPercent-oldPercent) > epsilon {
+			result.Packages = append(result.Packages, PackageDifference{Package: pkg, Old: oldPercent, New: newPercent})
+		}
+	}
+
+	sort.Slice(result.Packages, func(i, j int) bool {
+		return result.Packages[i].Package < result.Packages[j].Package
+	})
+
+	changed := make(map[string]bool, len(r.ChangedFiles))
+	for _, f := range r.ChangedFiles {
+		changed[f] = true
+	}
+
+	fileNames := make([]string, 0, len(r.New.Files))
+	for fileName := range unionKeys(r.Old.Files, r.New.Files) {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		oldProfile, newProfile := r.Old.Files[fileName], r.New.Files[fileName]
+		if oldProfile == nil {
+			oldProfile = &Profile{FileName: fileName}
+		}
+		if newProfile == nil {
+			newProfile = &Profile{FileName: fileName}
+		}
+
+		result.Blocks = append(result.Blocks, r.blockDifferences(fileName, oldProfile, newProfile, changed[fileName])...)
+	}
+
+	return result
+}
+
+// blockDifferences returns the blocks of the given file whose coverage
+// differs between the old and new profile.
+func (r *Report) blockDifferences(fileName string, oldProfile, newProfile *Profile, changed bool) []BlockDifference {
+	var oldKeys, newKeys []blockKey
+	ok := false
+	if changed {
+		oldKeys, newKeys, ok = r.blockContentKeys(fileName, oldProfile.Blocks, newProfile.Blocks)
+	}
+	if !ok {
+		oldKeys, newKeys = blockPositionKeys(oldProfile.Blocks), blockPositionKeys(newProfile.Blocks)
+	}
+
+	// Indexes of the old blocks with each key that are not matched yet.
+	unmatched := make(map[blockKey][]int, len(oldKeys))
+	for i, key := range oldKeys {
+		unmatched[key] = append(unmatched[key], i)
+	}
+
+	var result []BlockDifference
+	for i, b := range newProfile.Blocks {
+		diff := BlockDifference{FileName: fileName, StartLine: b.StartLine, EndLine: b.EndLine, NumStmt: b.NumStmt}
+
+		candidates := unmatched[newKeys[i]]
+		if len(candidates) == 0 {
+			diff.Change, diff.Covered = "added", b.Count > 0
+			result = append(result, diff)
+			continue
+		}
+
+		// Prefer an old block with the same coverage so that duplicated
+		// source code is not reported as differing.
+		match := 0
+		for j, idx := range candidates {
+			if (oldProfile.Blocks[idx].Count > 0) == (b.Count > 0) {
+				match = j
+				break
+			}
+		}
+		old := oldProfile.Blocks[candidates[match]]
+		unmatched[newKeys[i]] = append(candidates[:match:match], candidates[match+1:]...)
+
+		switch {
+		case old.Count == 0 && b.Count > 0:
+			diff.Change = "now covered"
+		case old.Count > 0 && b.Count == 0:
+			diff.Change = "now uncovered"
+		default:
+			continue
+		}
+		result = append(result, diff)
+	}
+
+	var removed []int
+	for _, indexes := range unmatched {
+		removed = append(removed, indexes...)
+	}
+	sort.Ints(removed)
+
+	for _, idx := range removed {
+		b := oldProfile.Blocks[idx]
+		result = append(result, BlockDifference{
+			FileName:  fileName,
+			StartLine: b.StartLine,
+			EndLine:   b.EndLine,
+			NumStmt:   b.NumStmt,
+			Change:    "removed",
+			Covered:   b.Count > 0,
+		})
+	}
+
+	return result
+}
+
+func unionKeys[V any](a, b map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	return keys
+}
+
+// checkNeutral returns an error if the report requires a coverage-neutral PR
+// but the coverage changed.
+func checkNeutral(result *Result) error {
+	n := result.Neutrality
+	if n == nil || n.Neutral() {
+		return nil
+	}
+
+	return fmt.Errorf("coverage changed although the PR must be coverage-neutral: %d covered statements before, %d after, %d packages and %d blocks differ",
+		n.OldCovered, n.NewCovered, len(n.Packages), len(n.Blocks))
+}
+
+// addNeutralityDetails states whether a coverage-neutral PR changed the
+// coverage and lists the packages and blocks that differ.
+func (r *Result) addNeutralityDetails(report *strings.Builder) {
+	n := r.Neutrality
+	if n == nil {
+		return
+	}
+
+	if n.Neutral() {
+		fmt.Fprintln(report, "> [!TIP]")
+		fmt.Fprintln(report, "> **Coverage-neutral:** The coverage of this PR is unchanged.")
+		fmt.Fprintln(report)
+		return
+	}
+
+	fmt.Fprintln(report, "> [!CAUTION]")
+	fmt.Fprintf(report, "> **Coverage changed:** This PR must not change the coverage, but %d statements were covered before and %d are covered now.\n", n.OldCovered, n.NewCovered)
+	fmt.Fprintln(report)
+
+	if len(n.Packages) == 0 && len(n.Blocks) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldNeutrality, func() bool { return true }))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Coverage Differences</summary>")
+	fmt.Fprintln(report)
+
+	if len(n.Packages) > 0 {
+		fmt.Fprintln(report, "| Package | Old Coverage | New Coverage |")
+		fmt.Fprintln(report, "|---------|--------------|--------------|")
+		for _, p := range n.Packages {
+			fmt.Fprintf(report, "| %s | %s | %s |\n", p.Package, r.numbers().Percent(p.Old), r.numbers().Percent(p.New))
+		}
+		fmt.Fprintln(report)
+	}
+
+	if len(n.Blocks) > 0 {
+		fmt.Fprintln(report, "| Block | Statements | Change |")
+		fmt.Fprintln(report, "|-------|------------|--------|")
+		for i, b := range n.Blocks {
+			if i == maxNeutralityBlocks {
+				fmt.Fprintf(report, "\n_%d more blocks differ._\n", len(n.Blocks)-maxNeutralityBlocks)
+				break
+			}
+
+			change := b.Change
+			if b.Change == "added" || b.Change == "removed" {
+				state := "uncovered"
+				if b.Covered {
+					state = "covered"
+				}
+				change += " (" + state + ")"
+			}
+			fmt.Fprintf(report, "| %s:%d-%d | %d | %s |\n", b.FileName, b.StartLine, b.EndLine, b.NumStmt, change)
+		}
+		fmt.Fprintln(report)
+	}
+
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
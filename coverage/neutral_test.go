@@ -0,0 +1,92 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Neutrality(t *testing.T) {
+	block := func(startLine, count int) ProfileBlock {
+		return ProfileBlock{StartLine: startLine, StartCol: 1, EndLine: startLine + 2, EndCol: 2, NumStmt: 2, Count: count}
+	}
+	profile := func(fileName string, blocks ...ProfileBlock) *Profile {
+		p := &Profile{FileName: fileName, Mode: "set", Blocks: blocks}
+		for _, b := range blocks {
+			p.TotalStmt += int64(b.NumStmt)
+			if b.Count > 0 {
+				p.CoveredStmt += int64(b.NumStmt)
+			}
+		}
+		p.MissedStmt = p.TotalStmt - p.CoveredStmt
+		return p
+	}
+
+	oldCov := New([]*Profile{
+		profile("example.com/a/a.go", block(1, 1), block(5, 0)),
+		profile("example.com/b/b.go", block(1, 1)),
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		newCov := New([]*Profile{
+			profile("example.com/a/a.go", block(1, 1), block(5, 0)),
+			profile("example.com/b/b.go", block(1, 1)),
+		})
+
+		report := NewReport(oldCov, newCov, []string{"example.com/a/a.go"})
+		report.Neutral = true
+
+		n := report.Neutrality(0.01)
+		assert.True(t, n.Neutral())
+		assert.Empty(t, n.Blocks)
+		assert.NoError(t, checkNeutral(report.Analyze()))
+		assert.Contains(t, report.Analyze().Markdown(), "**Coverage-neutral:**")
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		newCov := New([]*Profile{
+			profile("example.com/a/a.go", block(1, 0), block(5, 1)),
+			profile("example.com/b/b.go", block(1, 1), block(10, 0)),
+		})
+
+		report := NewReport(oldCov, newCov, []string{"example.com/a/a.go", "example.com/b/b.go"})
+		report.Neutral = true
+		report.NeutralEpsilon = 0.01
+
+		n := report.Neutrality(report.NeutralEpsilon)
+		assert.False(t, n.Neutral())
+		assert.Equal(t, int64(4), n.OldCovered)
+		assert.Equal(t, int64(4), n.NewCovered)
+		assert.Equal(t, []PackageDifference{{Package: "example.com/b", Old: 100, New: 50}}, n.Packages)
+		assert.Equal(t, []BlockDifference{
+			{FileName: "example.com/a/a.go", StartLine: 1, EndLine: 3, NumStmt: 2, Change: "now uncovered"},
+			{FileName: "example.com/a/a.go", StartLine: 5, EndLine: 7, NumStmt: 2, Change: "now covered"},
+			{FileName: "example.com/b/b.go", StartLine: 10, EndLine: 12, NumStmt: 2, Change: "added"},
+		}, n.Blocks)
+
+		require.Error(t, checkNeutral(report.Analyze()))
+
+		markdown := report.Analyze().Markdown()
+		assert.Contains(t, markdown, "**Coverage changed:**")
+		assert.Contains(t, markdown, "| example.com/b | 100.00% | 50.00% |\n")
+		assert.Contains(t, markdown, "| example.com/b/b.go:10-12 | 2 | added (uncovered) |\n")
+	})
+
+	t.Run("epsilon", func(t *testing.T) {
+		newCov := New([]*Profile{
+			profile("example.com/a/a.go", block(1, 1), block(5, 0)),
+			profile("example.com/b/b.go", block(1, 1), block(10, 0)),
+		})
+
+		report := NewReport(oldCov, newCov, []string{"example.com/b/b.go"})
+		assert.True(t, report.Neutrality(50).Neutral())
+		assert.False(t, report.Neutrality(49).Neutral())
+	})
+}
+
+func TestReport_Neutrality_Disabled(t *testing.T) {
+	report := NewReport(New(nil), New([]*Profile{{FileName: "example.com/a/a.go", TotalStmt: 1}}), []string{"example.com/a/a.go"})
+	assert.NoError(t, checkNeutral(report.Analyze()))
+	assert.NotContains(t, report.Analyze().Markdown(), "Coverage-neutral")
+}
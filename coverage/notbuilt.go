@@ -0,0 +1,172 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// NotBuiltFile is a changed file without coverage because its build
+// constraints exclude it from the build on the platform of the tests, e.g. a
+// file with "//go:build windows" that was tested on Linux.
+type NotBuiltFile struct {
+	FileName   string
+	Platform   string // GOOS/GOARCH of the tests
+	Constraint string // e.g. "//go:build windows", "file name" or "cgo"
+	FilledFrom string `json:",omitempty"` // label of the coverage that filled the gap (see -fill-not-built)
+}
+
+// findNotBuiltFiles returns the changed Go files without coverage whose build
+// constraints exclude them on the platform of the given build context. Files
+// whose source code is not found in the tree are skipped.
+func findNotBuiltFiles(tree sourceTree, changedFiles []string, cov *Coverage, ctxt *build.Context) []NotBuiltFile {
+	var result []NotBuiltFile
+	for _, name := range changedFiles {
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || cov.Files[name] != nil {
+			continue
+		}
+
+		sourcePath, ok := tree.find(name)
+		if !ok {
+			continue
+		}
+
+		match, err := ctxt.MatchFile(filepath.Dir(sourcePath), filepath.Base(sourcePath))
+		if err != nil {
+			continue
+		}
+
+		var constraint string
+		switch {
+		case !match:
+			constraint = buildConstraintOf(sourcePath)
+		case !ctxt.CgoEnabled && importsC(sourcePath):
+			constraint = "cgo"
+		default:
+			continue
+		}
+
+		result = append(result, NotBuiltFile{
+			FileName:   name,
+			Platform:   ctxt.GOOS + "/" + ctxt.GOARCH,
+			Constraint: constraint,
+		})
+	}
+
+	return result
+}
+
+// buildConstraintOf describes the build constraint of a file that is
+// excluded from the build: its //go:build line if it has one and the "file
+// name" (e.g. a _windows.go suffix) otherwise.
+func buildConstraintOf(sourcePath string) string {
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return "file name"
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "//go:build ") {
+			return line
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+
+	return "file name"
+}
+
+// importsC returns whether the Go source file uses cgo, which excludes it from
+// the build if cgo is disabled.
+func importsC(sourcePath string) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), sourcePath, nil, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+
+	for _, imp := range f.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path == "C" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseFillCoverage parses the value of -fill-not-built like the shards of the
+// NEW_COVERAGE_FILE argument (see parseShards), so a single file does not
+// need a label.
+func parseFillCoverage(arg string) []Shard {
+	if shards := parseShards(arg); shards != nil {
+		return shards
+	}
+
+	arg = strings.TrimSpace(arg)
+	return []Shard{{Label: strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg)), Path: arg}}
+}
+
+// fillNotBuiltFiles merges the profile of each file that is not built into
+// the coverage from the first of the given coverages (e.g. of other jobs of a
+// CI matrix) that contains it and records its label in FilledFrom.
+func fillNotBuiltFiles(cov *Coverage, notBuilt []NotBuiltFile, fill []Shard) (*Coverage, error) {
+	var profiles []*Profile
+	for i, f := range notBuilt {
+		for _, s := range fill {
+			if p := s.Coverage.Files[f.FileName]; p != nil {
+				profiles = append(profiles, p)
+				notBuilt[i].FilledFrom = s.Label
+				break
+			}
+		}
+	}
+
+	if len(profiles) == 0 {
+		return cov, nil
+	}
+
+	return MergeCoverage(cov, New(profiles))
+}
+
+// notBuilt returns the file if it is not built on the platform of the tests
+// and its coverage was not filled from other coverage.
+func (r *Report) notBuilt(fileName string) (NotBuiltFile, bool) {
+	for _, f := range r.NotBuilt {
+		if f.FileName == fileName && f.FilledFrom == "" {
+			return f, true
+		}
+	}
+
+	return NotBuiltFile{}, false
+}
+
+// addNotBuiltFiles lists the changed files that are excluded from the build by
+// their build constraints instead of reporting them with a coverage of 0%.
+func (r *Report) addNotBuiltFiles(report *strings.Builder) {
+	if len(r.NotBuilt) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, "### Not built on this platform")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The build constraints of these changed files exclude them from the build on the platform of the tests, "+
+		"so their coverage is unknown rather than 0%.")
+	fmt.Fprintln(report)
+	for _, f := range r.NotBuilt {
+		line := fmt.Sprintf("- %s: excluded on %s by `%s`", f.FileName, f.Platform, f.Constraint)
+		if f.FilledFrom != "" {
+			line += fmt.Sprintf(", coverage taken from %q", f.FilledFrom)
+		}
+		fmt.Fprintln(report, line)
+	}
+	fmt.Fprintln(report)
+}
@@ -0,0 +1,98 @@
+package coverage
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindNotBuiltFiles(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "pkg")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range map[string]string{
+		"sys_windows.go": "package pkg\n\nfunc sys() {}\n",
+		"tagged.go":      "// Copyright notice\n\n//go:build windows && !arm64\n\npackage pkg\n",
+		"cgo.go":         "package pkg\n\nimport \"C\"\n",
+		"uncovered.go":   "package pkg\n\nfunc uncovered() {}\n",
+		"covered.go":     "//go:build windows\n\npackage pkg\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	tree := sourceTree{root: root}
+
+	const pkg = "example.com/app/pkg/"
+	linux := build.Default
+	linux.GOOS, linux.GOARCH, linux.CgoEnabled = "linux", "amd64", false
+
+	changedFiles := []string{pkg + "cgo.go", pkg + "covered.go", pkg + "missing.go", pkg + "sys_windows.go", pkg + "sys_windows_test.go", pkg + "tagged.go", pkg + "uncovered.go"}
+	cov := New([]*Profile{newTestProfile(pkg+"covered.go", ProfileBlock{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1})})
+
+	assert.Equal(t, []NotBuiltFile{
+		{FileName: pkg + "cgo.go", Platform: "linux/amd64", Constraint: "cgo"},
+		{FileName: pkg + "sys_windows.go", Platform: "linux/amd64", Constraint: "file name"},
+		{FileName: pkg + "tagged.go", Platform: "linux/amd64", Constraint: "//go:build windows && !arm64"},
+	}, findNotBuiltFiles(tree, changedFiles, cov, &linux))
+
+	windows := linux
+	windows.GOOS = "windows"
+	assert.Equal(t, []NotBuiltFile{
+		{FileName: pkg + "cgo.go", Platform: "windows/amd64", Constraint: "cgo"},
+	}, findNotBuiltFiles(tree, changedFiles, cov, &windows))
+}
+
+func TestFillNotBuiltFiles(t *testing.T) {
+	assert.Equal(t, []Shard{{Label: "cover-windows", Path: "out/cover-windows.out"}}, parseFillCoverage("out/cover-windows.out"))
+	assert.Equal(t, []Shard{{Label: "windows", Path: "w.out"}, {Label: "darwin", Path: "d.out"}}, parseFillCoverage("windows=w.out,darwin=d.out"))
+
+	block := ProfileBlock{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 2, Count: 1}
+	cov := New([]*Profile{newTestProfile("pkg/a.go", block)})
+	notBuilt := []NotBuiltFile{
+		{FileName: "pkg/sys_windows.go", Platform: "linux/amd64", Constraint: "file name"},
+		{FileName: "pkg/sys_plan9.go", Platform: "linux/amd64", Constraint: "file name"},
+	}
+	fill := []Shard{
+		{Label: "darwin", Coverage: New([]*Profile{newTestProfile("pkg/a.go", block)})},
+		{Label: "windows", Coverage: New([]*Profile{newTestProfile("pkg/a.go", block), newTestProfile("pkg/sys_windows.go", block)})},
+	}
+
+	filled, err := fillNotBuiltFiles(cov, notBuilt, fill)
+	require.NoError(t, err)
+	assert.Equal(t, "windows", notBuilt[0].FilledFrom)
+	assert.Empty(t, notBuilt[1].FilledFrom)
+	assert.EqualValues(t, 4, filled.TotalStmt, "only the files that are not built are merged")
+	assert.EqualValues(t, 2, filled.Files["pkg/sys_windows.go"].CoveredStmt)
+}
+
+func TestReport_NotBuiltFiles(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+
+	const pkg = "github.com/pentohq/pento/pkg/age/"
+	report := NewReport(oldCov, newCov, []string{pkg + "age.go", pkg + "age_windows.go", pkg + "age_plan9.go"})
+	report.NotBuilt = []NotBuiltFile{
+		{FileName: pkg + "age_plan9.go", Platform: "linux/amd64", Constraint: "file name", FilledFrom: "plan9"},
+		{FileName: pkg + "age_windows.go", Platform: "linux/amd64", Constraint: "//go:build windows"},
+	}
+
+	markdown := report.Analyze().Markdown()
+	assert.NotContains(t, markdown, "| "+pkg+"age_windows.go |")
+	assert.Contains(t, markdown, "| "+pkg+"age_plan9.go |")
+	assert.Contains(t, markdown, "### Not built on this platform\n\n"+
+		"The build constraints of these changed files exclude them from the build on the platform of the tests, so their coverage is unknown rather than 0%.\n\n"+
+		"- "+pkg+"age_plan9.go: excluded on linux/amd64 by `file name`, coverage taken from \"plan9\"\n"+
+		"- "+pkg+"age_windows.go: excluded on linux/amd64 by `//go:build windows`\n")
+
+	report.Layout = layoutDrilldown
+	assert.Contains(t, report.Analyze().Markdown(), "`age_windows.go` · not built on linux/amd64 (`//go:build windows`)\n")
+
+	report.TrimPrefix("github.com/pentohq/pento")
+	assert.Equal(t, "pkg/age/age_windows.go", report.NotBuilt[1].FileName)
+}
@@ -0,0 +1,138 @@
+package coverage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat configures how the human readable formats of the report
+// (Markdown, HTML, PDF and the terminal diff) show percentages and statement
+// counts. Machine readable formats such as JSON and CSV are not affected.
+type NumberFormat struct {
+	Precision int    // decimal places of percentages (0 to 3)
+	Locale    string // separators of the numbers (see numberLocales), empty for none
+}
+
+// defaultNumberFormat shows percentages with two decimal places and counts
+// without thousands separators.
+var defaultNumberFormat = NumberFormat{Precision: 2}
+
+// numberLocales are the thousands and decimal separators of the supported
+// locales. Locales with a region (e.g. "de-DE") fall back to their language.
+var numberLocales = map[string]struct{ thousands, decimal string }{
+	"en":    {",", "."},
+	"de":    {".", ","},
+	"de-CH": {"’", "."},
+	"es":    {".", ","},
+	"fr":    {"\u202f", ","}, // narrow no-break space
+	"it":    {".", ","},
+	"nl":    {".", ","},
+	"pt":    {".", ","},
+	"si":    {"\u2009", "."}, // thin space as recommended by the SI brochure
+}
+
+// newNumberFormat returns the number format with the given precision and
+// locale after validating them.
+func newNumberFormat(precision int, locale string) (NumberFormat, error) {
+	if precision < 0 || precision > 3 {
+		return NumberFormat{}, fmt.Errorf("invalid precision %d: must be between 0 and 3", precision)
+	}
+
+	if locale != "" {
+		if _, ok := lookupNumberLocale(locale); !ok {
+			locales := make([]string, 0, len(numberLocales))
+			for name := range numberLocales {
+				locales = append(locales, name)
+			}
+			sort.Strings(locales)
+			return NumberFormat{}, fmt.Errorf("unknown number locale %q: must be one of %s", locale, strings.Join(locales, ", "))
+		}
+	}
+
+	return NumberFormat{Precision: precision, Locale: locale}, nil
+}
+
+// lookupNumberLocale returns the separators of the locale or of its language.
+func lookupNumberLocale(locale string) (struct{ thousands, decimal string }, bool) {
+	locale = strings.ReplaceAll(locale, "_", "-")
+	if sep, ok := numberLocales[locale]; ok {
+		return sep, true
+	}
+
+	lang, _, _ := strings.Cut(locale, "-")
+	sep, ok := numberLocales[strings.ToLower(lang)]
+	return sep, ok
+}
+
+// Percent formats the percentage, e.g. "87.50%".
+func (f NumberFormat) Percent(p float64) string {
+	return f.Number(p) + "%"
+}
+
+// Delta formats the change of a percentage with its sign, e.g. "+1.25%".
+func (f NumberFormat) Delta(p float64) string {
+	s := f.Number(p)
+	if !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+
+	return s + "%"
+}
+
+// Number formats the number with the precision and separators of the format.
+func (f NumberFormat) Number(v float64) string {
+	s := strconv.FormatFloat(v, 'f', f.Precision, 64)
+	sep, ok := lookupNumberLocale(f.Locale)
+	if !ok {
+		return s
+	}
+
+	intPart, fraction, hasFraction := strings.Cut(s, ".")
+	s = groupThousands(intPart, sep.thousands)
+	if hasFraction {
+		s += sep.decimal + fraction
+	}
+
+	return s
+}
+
+// Count formats a statement or line count, e.g. "1,234,567".
+func (f NumberFormat) Count(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if sep, ok := lookupNumberLocale(f.Locale); ok {
+		s = groupThousands(s, sep.thousands)
+	}
+
+	return s
+}
+
+// groupThousands inserts the separator between groups of three digits of the
+// integer, which may have a sign.
+func groupThousands(digits, sep string) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// numbers returns the number format of the report.
+func (r *Report) numbers() NumberFormat {
+	if r.Numbers == nil {
+		return defaultNumberFormat
+	}
+
+	return *r.Numbers
+}
@@ -0,0 +1,67 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNumberFormat(t *testing.T) {
+	f, err := newNumberFormat(3, "de_DE")
+	require.NoError(t, err)
+	assert.Equal(t, NumberFormat{Precision: 3, Locale: "de_DE"}, f)
+
+	_, err = newNumberFormat(4, "")
+	assert.EqualError(t, err, "invalid precision 4: must be between 0 and 3")
+
+	_, err = newNumberFormat(2, "xx")
+	assert.EqualError(t, err, `unknown number locale "xx": must be one of de, de-CH, en, es, fr, it, nl, pt, si`)
+}
+
+func TestNumberFormat(t *testing.T) {
+	tests := map[string]struct {
+		format  NumberFormat
+		percent string
+		delta   string
+		count   string
+	}{
+		"default":        {defaultNumberFormat, "87.50%", "-1234.57%", "1234567"},
+		"no decimals":    {NumberFormat{Precision: 0}, "88%", "-1235%", "1234567"},
+		"en":             {NumberFormat{Precision: 1, Locale: "en-US"}, "87.5%", "-1,234.6%", "1,234,567"},
+		"de":             {NumberFormat{Precision: 3, Locale: "de"}, "87,500%", "-1.234,568%", "1.234.567"},
+		"swiss german":   {NumberFormat{Precision: 2, Locale: "de-CH"}, "87.50%", "-1’234.57%", "1’234’567"},
+		"french":         {NumberFormat{Precision: 2, Locale: "fr"}, "87,50%", "-1\u202f234,57%", "1\u202f234\u202f567"},
+		"unknown locale": {NumberFormat{Precision: 2, Locale: "xx"}, "87.50%", "-1234.57%", "1234567"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.percent, tt.format.Percent(87.5))
+			assert.Equal(t, tt.delta, tt.format.Delta(-1234.5678))
+			assert.Equal(t, tt.count, tt.format.Count(1234567))
+		})
+	}
+
+	assert.Equal(t, "+12.50%", defaultNumberFormat.Delta(12.5))
+	assert.Equal(t, "-123", NumberFormat{Locale: "en"}.Count(-123))
+	assert.Equal(t, "-123,456", NumberFormat{Locale: "en"}.Count(-123456))
+}
+
+func TestReport_NumberFormat(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.Numbers = &NumberFormat{Precision: 1, Locale: "de"}
+
+	markdown := report.Analyze().Markdown()
+	assert.Contains(t, markdown, "### Coverage Report - 87,5% (**-12,5%**) - **decrease**")
+	assert.Contains(t, markdown, "| **Total** | 100,0% | 87,5% | **-12,5%** |")
+	assert.Contains(t, markdown, "| github.com/pentohq/pento/pkg/age/age.go | 87,5% (**-12,5%**) | 24 (+9) | 21 (+6) | 3 (+3) |")
+	assert.Contains(t, report.Analyze().HTML(), "<td>87,5%</td>")
+}
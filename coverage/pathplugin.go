@@ -0,0 +1,116 @@
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"plugin"
+)
+
+// PathPlugin is a user provided Go plugin that adapts the coverage profiles
+// of build systems which this tool does not know. A plugin is a main package
+// built with "go build -buildmode=plugin" that exports at least one of the
+// following functions:
+//
+//	// MapPath returns the file name of the profile in the form of the
+//	// changed files (usually the import path), or "" to drop the file.
+//	func MapPath(fileName string) string
+//
+//	// Classify returns a reason to exclude the whole file from the
+//	// coverage calculation (e.g. "generated"), or "" to keep it.
+//	func Classify(fileName string) string
+//
+// Classify is called with the mapped file names.
+type PathPlugin struct {
+	MapPath  func(fileName string) string
+	Classify func(fileName string) string
+}
+
+// errPluginsUnsupported is returned by LoadPathPlugin if this binary cannot
+// open Go plugins.
+var errPluginsUnsupported = errors.New("path plugins are not supported by this binary: Go plugins require a binary that is built with cgo (CGO_ENABLED=1) on Linux, FreeBSD or macOS, which the release binaries are not; install it via \"go install\" with the Go version of the plugin instead")
+
+// LoadPathPlugin opens the Go plugin at the given path. Go plugins are only
+// supported by binaries that are built with cgo on Linux, FreeBSD and macOS,
+// and the plugin must be built with the same Go version and dependencies as
+// this tool.
+func LoadPathPlugin(path string) (*PathPlugin, error) {
+	if !pluginsSupported {
+		return nil, errPluginsUnsupported
+	}
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPathPlugin(p.Lookup)
+}
+
+// newPathPlugin looks up the functions of a PathPlugin. The functions may be
+// exported as functions or as variables of a function type.
+func newPathPlugin(lookup func(symbol string) (plugin.Symbol, error)) (*PathPlugin, error) {
+	find := func(name string) (func(string) string, error) {
+		sym, err := lookup(name)
+		if err != nil {
+			return nil, nil // the functions are optional
+		}
+
+		switch fn := sym.(type) {
+		case func(string) string:
+			return fn, nil
+		case *func(string) string:
+			return *fn, nil
+		default:
+			return nil, fmt.Errorf("%s has type %T but must be a func(string) string", name, sym)
+		}
+	}
+
+	var p PathPlugin
+	var err error
+	if p.MapPath, err = find("MapPath"); err != nil {
+		return nil, err
+	}
+	if p.Classify, err = find("Classify"); err != nil {
+		return nil, err
+	}
+	if p.MapPath == nil && p.Classify == nil {
+		return nil, fmt.Errorf("plugin exports neither MapPath nor Classify")
+	}
+
+	return &p, nil
+}
+
+// mapper returns the function that maps the file names of coverage profiles
+// while they are parsed (see parseOptions), or nil if the plugin does not map
+// paths.
+func (p *PathPlugin) mapper() func(fileName string) (string, bool) {
+	if p.MapPath == nil {
+		return nil
+	}
+
+	return func(fileName string) (string, bool) {
+		mapped := p.MapPath(fileName)
+		return mapped, mapped != ""
+	}
+}
+
+// ExcludeClassified excludes all files of the coverage for which Classify
+// returns a reason.
+func (p *PathPlugin) ExcludeClassified(cov *Coverage) {
+	if p.Classify == nil {
+		return
+	}
+
+	for _, fileName := range sortedKeys(cov.Files) {
+		reason := p.Classify(fileName)
+		if reason == "" {
+			continue
+		}
+
+		var lastLine int
+		for _, b := range cov.Files[fileName].Blocks {
+			lastLine = max(lastLine, b.EndLine)
+		}
+		cov.Exclude(fileName, 1, lastLine, reason)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build cgo && (linux || freebsd || darwin)
+
+package coverage
+
+// pluginsSupported reports whether this binary can open Go plugins.
+const pluginsSupported = true
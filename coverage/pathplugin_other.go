@@ -0,0 +1,7 @@
+//go:build !cgo || !(linux || freebsd || darwin)
+
+package coverage
+
+// pluginsSupported reports whether this binary can open Go plugins, which
+// requires cgo (e.g. not the release binaries, which are built without it).
+const pluginsSupported = false
@@ -0,0 +1,88 @@
+package coverage
+
+import (
+	"errors"
+	"plugin"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPathPlugin(t *testing.T) {
+	mapPath := func(fileName string) string { return strings.TrimPrefix(fileName, "/build/") }
+	classify := func(fileName string) string { return "" }
+
+	lookup := func(symbols map[string]plugin.Symbol) func(string) (plugin.Symbol, error) {
+		return func(name string) (plugin.Symbol, error) {
+			if sym, ok := symbols[name]; ok {
+				return sym, nil
+			}
+			return nil, errors.New("symbol not found")
+		}
+	}
+
+	p, err := newPathPlugin(lookup(map[string]plugin.Symbol{"MapPath": mapPath}))
+	require.NoError(t, err)
+	assert.Equal(t, "a.go", p.MapPath("/build/a.go"))
+	assert.Nil(t, p.Classify)
+
+	p, err = newPathPlugin(lookup(map[string]plugin.Symbol{"Classify": &classify}))
+	require.NoError(t, err)
+	assert.Nil(t, p.MapPath)
+	assert.Nil(t, p.mapper())
+	assert.NotNil(t, p.Classify)
+
+	_, err = newPathPlugin(lookup(map[string]plugin.Symbol{"MapPath": func(string) (string, bool) { return "", false }}))
+	assert.EqualError(t, err, "MapPath has type func(string) (string, bool) but must be a func(string) string")
+
+	_, err = newPathPlugin(lookup(nil))
+	assert.Error(t, err)
+}
+
+func TestPathPlugin(t *testing.T) {
+	p := &PathPlugin{
+		MapPath: func(fileName string) string {
+			if strings.HasSuffix(fileName, ".pb.go") {
+				return ""
+			}
+			name := strings.TrimPrefix(fileName, "/sandbox/linux/")
+			return "example.com/app/" + strings.TrimPrefix(name, "/sandbox/darwin/")
+		},
+		Classify: func(fileName string) string {
+			if strings.HasSuffix(fileName, "_gen.go") {
+				return "generated"
+			}
+			return ""
+		},
+	}
+
+	profiles, err := parseProfilesReader(strings.NewReader(`mode: set
+/sandbox/linux/a.go:1.1,2.2 2 1
+/sandbox/darwin/a.go:1.1,2.2 2 0
+/sandbox/darwin/a.go:3.1,4.2 1 0
+/sandbox/linux/api.pb.go:1.1,2.2 5 0
+/sandbox/linux/b_gen.go:1.1,9.2 4 0
+`), parseOptions{mapPath: p.mapper()})
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "example.com/app/a.go", profiles[0].FileName)
+	assert.Equal(t, int64(3), profiles[0].TotalStmt)
+	assert.Equal(t, int64(2), profiles[0].CoveredStmt)
+
+	cov := New(profiles)
+	p.ExcludeClassified(cov)
+	assert.Equal(t, int64(3), cov.TotalStmt)
+	assert.Equal(t, []Exclusion{{FileName: "example.com/app/b_gen.go", StartLine: 1, EndLine: 9, NumStmt: 4, Reason: "generated"}}, cov.Exclusions)
+}
+
+func TestLoadPathPlugin_Unsupported(t *testing.T) {
+	_, err := LoadPathPlugin("testdata/missing.so")
+	require.Error(t, err)
+	if pluginsSupported {
+		assert.NotErrorIs(t, err, errPluginsUnsupported)
+	} else {
+		assert.ErrorIs(t, err, errPluginsUnsupported)
+	}
+}
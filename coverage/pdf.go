@@ -0,0 +1,102 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Layout of the pages of renderPDF in PDF units (1/72 inch) on A4 paper.
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfFontSize     = 8
+	pdfLineHeight   = 10
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	pdfMaxLineChars = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6) // Courier glyphs are 0.6em wide
+)
+
+// renderPDF returns a minimal PDF document that shows the given lines in a
+// monospace font, split into as many pages as needed. Lines that are too
+// long are truncated and characters outside of ASCII are replaced by "?",
+// since the standard fonts of PDF readers only cover Latin-1. Thousands
+// separators are replaced by their ASCII equivalent.
+func renderPDF(lines []string) []byte {
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(format string, args ...any) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&buf, format, args...)
+		buf.WriteString("\nendobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1 to 3 are the catalog, the page tree and the font. Each page
+	// consists of a page object followed by its content stream.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, " (%s) '", pdfEscape(line))
+		}
+		content.WriteString(" ET")
+
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 5+2*i)
+		object("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfEscape returns the line as content of a PDF string literal.
+func pdfEscape(line string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		if n == pdfMaxLineChars {
+			break
+		}
+		n++
+
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\u202f' || r == '\u2009':
+			b.WriteByte(' ') // thousands separators (see numberLocales)
+		case r == '’':
+			b.WriteByte('\'')
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,598 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file provides support for parsing coverage profiles
+// generated by "go test -coverprofile=cover.out".
+// It is mostly a copy of golang.org/x/tools/cover/profile.go with some modifications.
+
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Profile represents the profiling data for a specific file.
+type Profile struct {
+	FileName string `json:"-"`
+	Mode     string
+	Blocks   []ProfileBlock `json:"-"`
+
+	TotalStmt   int64
+	CoveredStmt int64
+	MissedStmt  int64
+}
+
+// ProfileBlock represents a single block of profiling data.
+type ProfileBlock struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	NumStmt, Count      int
+}
+
+// Source returns the exact source code of the block, i.e. everything between
+// its start and end position, given the lines of the file as returned by
+// sourceTree.readLines. It returns false if the block does not fit the lines.
+func (b ProfileBlock) Source(lines map[int]string) (string, bool) {
+	var src strings.Builder
+	for n := b.StartLine; n <= b.EndLine; n++ {
+		line, ok := lines[n]
+		if !ok {
+			return "", false
+		}
+
+		src.WriteString(b.LineSpan(n, line))
+		src.WriteByte('\n')
+	}
+
+	return src.String(), true
+}
+
+// LineSpan returns the part of the given source line that belongs to the
+// block. Lines between the first and the last line of the block are returned
+// as they are. Columns are 1-based byte offsets and the end column is
+// exclusive; blocks without columns span their first and last line entirely.
+func (b ProfileBlock) LineSpan(lineNum int, line string) string {
+	if lineNum < b.StartLine || lineNum > b.EndLine {
+		return ""
+	}
+
+	from, to := 0, len(line)
+	if lineNum == b.StartLine && b.StartCol > 0 {
+		from = min(b.StartCol-1, len(line))
+	}
+	if lineNum == b.EndLine && b.EndCol > 0 {
+		to = min(max(b.EndCol-1, from), len(line))
+	}
+
+	return line[from:to]
+}
+
+type byFileName []*Profile
+
+func (p byFileName) Len() int           { return len(p) }
+func (p byFileName) Less(i, j int) bool { return p[i].FileName < p[j].FileName }
+func (p byFileName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// ParseProfiles parses profile data in the specified file and returns a
+// Profile for each source file described therein.
+func ParseProfiles(fileName string) ([]*Profile, error) {
+	return ParseProfilesContext(context.Background(), fileName)
+}
+
+// ParseProfilesContext is like ParseProfiles but stops reading the file once
+// the context is done. The file is memory-mapped if the platform supports it
+// so that huge profiles don't need to be copied into memory.
+func ParseProfilesContext(ctx context.Context, fileName string) ([]*Profile, error) {
+	return parseProfilesFile(ctx, fileName, nil, parseOptions{})
+}
+
+// parseOptions are the settings of parsing the input files, i.e. coverage
+// profiles, diffs and the output of "go test -json". The zero value parses
+// them with the defaults.
+type parseOptions struct {
+	maxLineLength int // see -max-line-length and lineLimit
+
+	// lcovRoot is the import path that is prepended to the workspace
+	// relative file names of LCOV coverage files (e.g. Bazel's
+	// coverage.dat), so that they match the file names of Go coverage
+	// profiles. It is set via the -root flag.
+	lcovRoot string
+
+	// mapPath maps the file names of coverage profiles while they are
+	// parsed. It returns false to drop a file. It is nil unless a path
+	// plugin is loaded via the -path-plugin flag.
+	mapPath func(fileName string) (string, bool)
+}
+
+// parseProfilesFile parses the profiles of all files in the given coverage
+// file for which include returns true. If include is nil, all files are
+// included.
+func parseProfilesFile(ctx context.Context, fileName string, include func(fileName string) bool, o parseOptions) ([]*Profile, error) {
+	data, closeFile, err := mmapFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	data = trimBOM(data)
+	maxLen := lineLimit(o.maxLineLength)
+	p := newProfileParser(o)
+	p.include = include
+	for n := 0; len(data) > 0; n++ {
+		// Checking the context for every line would be too expensive.
+		if n%4096 == 0 {
+			if err := context.Cause(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+
+		if len(line) > maxLen {
+			return nil, errLineTooLong(line[:maxLen], maxLen)
+		}
+		if err := p.parse(line); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.profiles()
+}
+
+// ParseProfilesFromReader parses profile data from the Reader and
+// returns a Profile for each source file described therein.
+func ParseProfilesFromReader(rd io.Reader) ([]*Profile, error) {
+	return parseProfilesReader(rd, parseOptions{})
+}
+
+func parseProfilesReader(rd io.Reader, o parseOptions) ([]*Profile, error) {
+	p := newProfileParser(o)
+	s := newLineReader(rd, o.maxLineLength)
+	for s.Scan() {
+		if s.Truncated() {
+			return nil, errLineTooLong(s.Bytes(), lineLimit(o.maxLineLength))
+		}
+		if err := p.parse(s.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return p.profiles()
+}
+
+func errLineTooLong(line []byte, maxLen int) error {
+	return fmt.Errorf("line starting with %q exceeds the maximum line length of %d bytes", line[:min(len(line), 50)], maxLen)
+}
+
+// profileParser collects the blocks of a coverage profile line by line. It
+// avoids allocations per line since profiles of large repositories easily
+// have millions of lines: lines are parsed as byte slices and file names are
+// only allocated once per file.
+type profileParser struct {
+	mode    string
+	files   map[string]*Profile                  // by file name in the profile
+	include func(fileName string) bool           // optional filter for the files to parse
+	mapPath func(fileName string) (string, bool) // optional mapping of the file names (see parseOptions)
+	skipped map[string]bool                      // files that were not included
+
+	lcov     bool     // the input is an LCOV tracefile (see parseLCOV)
+	lcovFile *Profile // profile of the current LCOV record
+	lcovRoot string   // see parseOptions
+}
+
+func newProfileParser(o parseOptions) *profileParser {
+	return &profileParser{
+		files:    make(map[string]*Profile),
+		mapPath:  o.mapPath,
+		skipped:  make(map[string]bool),
+		lcovRoot: o.lcovRoot,
+	}
+}
+
+// parse parses a single line of the profile. The line is not retained.
+func (pp *profileParser) parse(line []byte) error {
+	// First line is "mode: foo", where foo is "set", "count", or "atomic".
+	// Rest of file is in the format
+	//	encoding/base64/base64.go:34.44,37.40 3 1
+	// where the fields are: name.go:line.column,line.column numberOfStatements count
+	if pp.lcov {
+		return pp.parseLCOV(line)
+	}
+	if pp.mode == "" {
+		if isLCOV(line) {
+			pp.mode, pp.lcov = "count", true
+			return pp.parseLCOV(line)
+		}
+
+		const prefix = "mode: "
+		if !bytes.HasPrefix(line, []byte(prefix)) || len(line) == len(prefix) {
+			return fmt.Errorf("bad mode line: %s", line)
+		}
+		pp.mode = string(line[len(prefix):])
+		return nil
+	}
+
+	fn, b, err := parseLine(line)
+	if err != nil {
+		return fmt.Errorf("line %q doesn't match expected format: %v", line, err)
+	}
+
+	// The compiler optimizes the conversion in the map lookup so that no
+	// string is allocated for files we have seen before.
+	p := pp.files[string(fn)]
+	if p == nil {
+		if pp.skipped[string(fn)] {
+			return nil
+		}
+
+		name, ok := pp.fileName(string(fn))
+		if !ok {
+			pp.skipped[string(fn)] = true
+			return nil
+		}
+
+		if err := checkBlock(name, b); err != nil {
+			return fmt.Errorf("line %q is invalid: %v", line, err)
+		}
+		p = &Profile{
+			FileName: name,
+			Mode:     pp.mode,
+		}
+		pp.files[string(fn)] = p
+	} else if err := checkBlock(p.FileName, b); err != nil {
+		return fmt.Errorf("line %q is invalid: %v", line, err)
+	}
+
+	p.Blocks = append(p.Blocks, b)
+	return nil
+}
+
+// fileName returns the mapped name of a file of the profile and whether the
+// file is included.
+func (pp *profileParser) fileName(name string) (string, bool) {
+	name = normalizeProfilePath(name)
+	if pp.mapPath != nil {
+		var ok bool
+		if name, ok = pp.mapPath(name); !ok {
+			return "", false
+		}
+	}
+
+	return name, pp.include == nil || pp.include(name)
+}
+
+// normalizeProfilePath returns the file name of a profile with Windows drive
+// prefixes normalized. The go tool writes absolute paths for packages outside
+// of GOPATH and modules, which on Windows use backslashes and a drive letter
+// of any case (e.g. "c:\work\app\a.go"). Such paths are returned with forward
+// slashes and an upper-case drive letter (e.g. "C:/work/app/a.go") so that
+// they can be split into packages like all other file names and match the
+// same file in profiles written by other setups. Local import paths, which
+// older toolchains wrote for such packages in GOPATH mode (e.g.
+// "_/home/user/app/a.go" or "_/C_/work/app/a.go"), are converted to the same
+// absolute paths, so that the file is not listed twice. This does not affect
+// packages of modules, whose files are named by their import path. All other
+// file names, including names with spaces or non-ASCII characters, are kept
+// as they are.
+func normalizeProfilePath(name string) string {
+	if strings.HasPrefix(name, "_/") {
+		name = legacyLocalPath(name)
+	}
+
+	if len(name) < 3 || name[1] != ':' || (name[2] != '\\' && name[2] != '/') {
+		return name
+	}
+	if !isDriveLetter(name[0]) {
+		return name
+	}
+
+	return strings.ToUpper(name[:1]) + strings.ReplaceAll(name[1:], `\`, "/")
+}
+
+// legacyLocalPath returns the absolute path of a local import path of a
+// package outside of GOPATH in GOPATH mode, which is "_" followed by the
+// directory in which invalid characters like the colon of Windows drives were
+// replaced by "_".
+func legacyLocalPath(name string) string {
+	dir := name[1:]
+	if len(dir) >= 4 && isDriveLetter(dir[1]) && dir[2] == '_' && dir[3] == '/' {
+		return dir[1:2] + ":" + dir[3:] // "/C_/work/a.go" is "C:/work/a.go"
+	}
+
+	return dir
+}
+
+func isDriveLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// profileLine formats a block of the given file as line of a coverage profile
+// (see parseLine).
+func profileLine(fileName string, b ProfileBlock) string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d %d %d", fileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+}
+
+// profiles returns the parsed profiles sorted by file name with the blocks
+// of the same location merged.
+func (pp *profileParser) profiles() ([]*Profile, error) {
+	// Files of the profile may have been mapped to the same name.
+	files := make(map[string]*Profile, len(pp.files))
+	for _, p := range pp.files {
+		if merged := files[p.FileName]; merged != nil {
+			merged.Blocks = append(merged.Blocks, p.Blocks...)
+			continue
+		}
+		files[p.FileName] = p
+	}
+
+	for _, p := range files {
+		sort.Sort(blocksByStart(p.Blocks))
+		// Merge samples from the same location.
+		j := 1
+		for i := 1; i < len(p.Blocks); i++ {
+			b := p.Blocks[i]
+			last := p.Blocks[j-1]
+			if b.StartLine == last.StartLine &&
+				b.StartCol == last.StartCol &&
+				b.EndLine == last.EndLine &&
+				b.EndCol == last.EndCol {
+				if b.NumStmt != last.NumStmt {
+					return nil, fmt.Errorf("inconsistent NumStmt: changed from %d to %d", last.NumStmt, b.NumStmt)
+				}
+				if pp.mode == "set" {
+					p.Blocks[j-1].Count |= b.Count
+				} else {
+					p.Blocks[j-1].Count += b.Count
+				}
+				continue
+			}
+			p.Blocks[j] = b
+			j++
+		}
+		p.Blocks = p.Blocks[:j]
+
+		for _, b := range p.Blocks {
+			p.TotalStmt += int64(b.NumStmt)
+			if b.Count > 0 {
+				// If we got at least a single hit in this block we say it was covered
+				p.CoveredStmt += int64(b.NumStmt)
+			}
+		}
+		p.MissedStmt = p.TotalStmt - p.CoveredStmt
+	}
+	// Generate a sorted slice.
+	profiles := make([]*Profile, 0, len(files))
+	for _, profile := range files {
+		profiles = append(profiles, profile)
+	}
+	sort.Sort(byFileName(profiles))
+	return profiles, nil
+}
+
+// parseLine parses a line from a coverage file.
+// It is equivalent to the regex
+// ^(.+):([0-9]+)\.([0-9]+),([0-9]+)\.([0-9]+) ([0-9]+) ([0-9]+)$
+//
+// However, it is much faster: https://golang.org/cl/179377
+func parseLine(l []byte) (fileName []byte, block ProfileBlock, err error) {
+	end := len(l)
+
+	b := ProfileBlock{}
+	b.Count, end, err = seekBack(l, ' ', end, "Count")
+	if err != nil {
+		return nil, b, err
+	}
+	b.NumStmt, end, err = seekBack(l, ' ', end, "NumStmt")
+	if err != nil {
+		return nil, b, err
+	}
+	b.EndCol, end, err = seekBack(l, '.', end, "EndCol")
+	if err != nil {
+		return nil, b, err
+	}
+	b.EndLine, end, err = seekBack(l, ',', end, "EndLine")
+	if err != nil {
+		return nil, b, err
+	}
+	b.StartCol, end, err = seekBack(l, '.', end, "StartCol")
+	if err != nil {
+		return nil, b, err
+	}
+	b.StartLine, end, err = seekBack(l, ':', end, "StartLine")
+	if err != nil {
+		return nil, b, err
+	}
+	fn := l[0:end]
+	if len(fn) == 0 {
+		return nil, b, errors.New("a FileName cannot be blank")
+	}
+	return fn, b, nil
+}
+
+// checkBlock rejects blocks that cannot have been produced by the go tool
+// and would otherwise cause trouble when processing the profile.
+func checkBlock(fileName string, b ProfileBlock) error {
+	switch {
+	case !utf8.ValidString(fileName):
+		return errors.New("file name is not valid UTF-8")
+	case b.StartLine == 0 || b.EndLine > maxLineNumber:
+		return fmt.Errorf("line numbers must be between 1 and %d", maxLineNumber)
+	case b.StartLine > b.EndLine:
+		return errors.New("block ends before it starts")
+	case b.NumStmt > maxLineNumber:
+		return fmt.Errorf("number of statements must not exceed %d", maxLineNumber)
+	}
+
+	return nil
+}
+
+// seekBack searches backwards from end to find sep in l, then returns the
+// value between sep and end as an integer.
+// If seekBack fails, the returned error will reference what.
+func seekBack(l []byte, sep byte, end int, what string) (value int, nextSep int, err error) {
+	// Since we're seeking backwards and we know only ASCII is legal for these values,
+	// we can ignore the possibility of non-ASCII characters.
+	for start := end - 1; start >= 0; start-- {
+		if l[start] == sep {
+			i, err := atoi(l[start+1 : end])
+			if err != nil {
+				return 0, 0, fmt.Errorf("couldn't parse %q: %v", what, err)
+			}
+			if i < 0 {
+				return 0, 0, fmt.Errorf("negative values are not allowed for %s, found %d", what, i)
+			}
+			return i, start, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("couldn't find a %s before %s", string(sep), what)
+}
+
+// atoi is like strconv.Atoi for byte slices but without allocating a string
+// for the common case of small decimal numbers.
+func atoi(b []byte) (int, error) {
+	// Numbers with up to 18 digits cannot overflow an int64.
+	if len(b) == 0 || len(b) > 18 {
+		return strconv.Atoi(string(b))
+	}
+
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			// Let strconv produce the error (or parse signs).
+			return strconv.Atoi(string(b))
+		}
+		n = n*10 + int(c-'0')
+	}
+
+	return n, nil
+}
+
+type blocksByStart []ProfileBlock
+
+func (b blocksByStart) Len() int      { return len(b) }
+func (b blocksByStart) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b blocksByStart) Less(i, j int) bool {
+	bi, bj := b[i], b[j]
+	return bi.StartLine < bj.StartLine || bi.StartLine == bj.StartLine && bi.StartCol < bj.StartCol
+}
+
+// Boundary represents the position in a source file of the beginning or end of a
+// block as reported by the coverage profile. In HTML mode, it will correspond to
+// the opening or closing of a <span> tag and will be used to colorize the source
+type Boundary struct {
+	Offset int     // Location as a byte offset in the source file.
+	Start  bool    // Is this the start of a block?
+	Count  int     // Event count from the cover profile.
+	Norm   float64 // Count normalized to [0..1].
+	Index  int     // Order in input file.
+}
+
+// Boundaries returns a Profile as a set of Boundary objects within the provided src.
+func (p *Profile) Boundaries(src []byte) (boundaries []Boundary) {
+	// Find maximum count.
+	max := 0
+	for _, b := range p.Blocks {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	// Divisor for normalization.
+	divisor := math.Log(float64(max))
+
+	// boundary returns a Boundary, populating the Norm field with a normalized Count.
+	index := 0
+	boundary := func(offset int, start bool, count int) Boundary {
+		b := Boundary{Offset: offset, Start: start, Count: count, Index: index}
+		index++
+		if !start || count == 0 {
+			return b
+		}
+		if max <= 1 {
+			b.Norm = 0.8 // Profile is in"set" mode; we want a heat map. Use cov8 in the CSS.
+		} else if count > 0 {
+			b.Norm = math.Log(float64(count)) / divisor
+		}
+		return b
+	}
+
+	line, col := 1, 2 // TODO: Why is this 2?
+	for si, bi := 0, 0; si < len(src) && bi < len(p.Blocks); {
+		b := p.Blocks[bi]
+		if b.StartLine == line && b.StartCol == col {
+			boundaries = append(boundaries, boundary(si, true, b.Count))
+		}
+		if b.EndLine == line && b.EndCol == col || line > b.EndLine {
+			boundaries = append(boundaries, boundary(si, false, 0))
+			bi++
+			continue // Don't advance through src; maybe the next block starts here.
+		}
+		if src[si] == '\n' {
+			line++
+			col = 0
+		}
+		col++
+		si++
+	}
+	sort.Sort(boundariesByPos(boundaries))
+	return
+}
+
+func (p *Profile) CoveragePercent() float64 {
+	if p == nil || p.TotalStmt == 0 {
+		return 0
+	}
+	return float64(p.CoveredStmt) / float64(p.TotalStmt) * 100
+}
+
+func (p *Profile) GetTotal() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.TotalStmt
+}
+
+func (p *Profile) GetCovered() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.CoveredStmt
+}
+
+func (p *Profile) GetMissed() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.MissedStmt
+}
+
+type boundariesByPos []Boundary
+
+func (b boundariesByPos) Len() int      { return len(b) }
+func (b boundariesByPos) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b boundariesByPos) Less(i, j int) bool {
+	if b[i].Offset == b[j].Offset {
+		// Boundaries at the same offset should be ordered according to
+		// their original position.
+		return b[i].Index < b[j].Index
+	}
+	return b[i].Offset < b[j].Offset
+}
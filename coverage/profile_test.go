@@ -0,0 +1,276 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfilesFromReader_Hardening(t *testing.T) {
+	cases := map[string]string{
+		"absurd line number":  "mode: set\na.go:1.1,99999999.2 1 1\n",
+		"zero line number":    "mode: set\na.go:0.1,2.2 1 1\n",
+		"inverted block":      "mode: set\na.go:10.1,2.2 1 1\n",
+		"absurd statements":   "mode: set\na.go:1.1,2.2 99999999 1\n",
+		"invalid UTF-8":       "mode: set\n\xff.go:1.1,2.2 1 1\n",
+		"line exceeds length": "mode: set\n" + strings.Repeat("a", defaultMaxLineLength+1) + ".go:1.1,2.2 1 1\n",
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseProfilesFromReader(strings.NewReader(input))
+			assert.Error(t, err)
+		})
+	}
+
+	longName := strings.Repeat("a", 1<<17) + ".go"
+	profiles, err := ParseProfilesFromReader(strings.NewReader("mode: set\n" + longName + ":1.1,2.2 1 1\n"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, longName, profiles[0].FileName)
+}
+
+func FuzzParseProfilesFromReader(f *testing.F) {
+	for _, name := range []string{"testdata/01-new-coverage.txt", "testdata/04-new-coverage.txt"} {
+		data, err := os.ReadFile(name)
+		require.NoError(f, err)
+		f.Add(data)
+	}
+	f.Add([]byte("mode: count\na.go:1.2,3.4 5 6\na.go:1.2,3.4 5 1\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		profiles, err := ParseProfilesFromReader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		var fileNames []string
+		for _, p := range profiles {
+			fileNames = append(fileNames, p.FileName)
+			if p.TotalStmt != p.CoveredStmt+p.MissedStmt {
+				t.Fatalf("inconsistent statement counts for %q", p.FileName)
+			}
+			for _, b := range p.Blocks {
+				if b.StartLine > b.EndLine || b.EndLine > maxLineNumber {
+					t.Fatalf("invalid block %+v", b)
+				}
+			}
+		}
+
+		cov := New(profiles)
+		report := NewReport(cov, cov, fileNames)
+		_ = report.Analyze().Markdown()
+	})
+}
+
+func TestParseProfiles_MatchesReader(t *testing.T) {
+	for _, fileName := range []string{"testdata/01-new-coverage.txt", "testdata/03-old-coverage.txt"} {
+		data, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+
+		fromFile, err := ParseProfiles(fileName)
+		require.NoError(t, err)
+		fromReader, err := ParseProfilesFromReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, fromReader, fromFile)
+	}
+}
+
+func TestParseProfiles_EdgeCases(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		fileName := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0644))
+		return fileName
+	}
+
+	profiles, err := ParseProfiles(write("empty.txt", ""))
+	require.NoError(t, err)
+	assert.Empty(t, profiles)
+
+	profiles, err = ParseProfiles(write("crlf.txt", "mode: set\r\na.go:1.1,2.2 1 1\r\na.go:3.1,4.2 2 0"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "set", profiles[0].Mode)
+	assert.EqualValues(t, 3, profiles[0].TotalStmt)
+
+	profiles, err = ParseProfiles(write("bom.txt", "\xEF\xBB\xBFmode: set\r\na.go:1.1,2.2 1 1\r\n"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "set", profiles[0].Mode)
+
+	// Blocks of the same location (e.g. with -coverpkg) are only counted once.
+	profiles, err = ParseProfiles(write("duplicates.txt", "mode: set\na.go:1.1,2.2 1 0\na.go:3.1,4.2 2 0\na.go:1.1,2.2 1 1\n"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Len(t, profiles[0].Blocks, 2)
+	assert.EqualValues(t, 3, profiles[0].TotalStmt)
+	assert.EqualValues(t, 1, profiles[0].CoveredStmt)
+
+	_, err = ParseProfiles(write("long.txt", "mode: set\n"+strings.Repeat("a", defaultMaxLineLength+1)+".go:1.1,2.2 1 1\n"))
+	assert.ErrorContains(t, err, "exceeds the maximum line length")
+
+	_, err = parseProfilesFile(context.Background(), write("short.txt", "mode: set\na.go:1.1,2.2 1 1\n"), nil, parseOptions{maxLineLength: 10})
+	assert.ErrorContains(t, err, "exceeds the maximum line length of 10 bytes")
+
+	_, err = ParseProfiles(filepath.Join(dir, "missing.txt"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestParseProfiles_SpecialFileNames(t *testing.T) {
+	cases := map[string]struct{ fileName, want string }{
+		"spaces":               {"example.com/my app/sub pkg/a b.go", "example.com/my app/sub pkg/a b.go"},
+		"unicode":              {"example.com/ünïcode/日本/π.go", "example.com/ünïcode/日本/π.go"},
+		"colons and dots":      {"example.com/a:1.2,3.4 5/b.go", "example.com/a:1.2,3.4 5/b.go"},
+		"drive with backslash": {`C:\Users\Jane Doe\go\src\app\a.go`, "C:/Users/Jane Doe/go/src/app/a.go"},
+		"lower-case drive":     {`d:\work space\app\a.go`, "D:/work space/app/a.go"},
+		"drive with slash":     {"C:/mnt/workspace/app/a.go", "C:/mnt/workspace/app/a.go"},
+		"not a drive":          {`pkg\C:\a.go`, `pkg\C:\a.go`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			blocks := []ProfileBlock{
+				{StartLine: 1, StartCol: 2, EndLine: 3, EndCol: 4, NumStmt: 2, Count: 1},
+				{StartLine: 5, StartCol: 1, EndLine: 5, EndCol: 20, NumStmt: 1, Count: 0},
+			}
+
+			input := "mode: set\n"
+			for _, b := range blocks {
+				input += profileLine(c.fileName, b) + "\n"
+			}
+
+			profiles, err := ParseProfilesFromReader(strings.NewReader(input))
+			require.NoError(t, err)
+			require.Len(t, profiles, 1)
+			assert.Equal(t, c.want, profiles[0].FileName)
+			assert.Equal(t, blocks, profiles[0].Blocks)
+			assert.EqualValues(t, 3, profiles[0].TotalStmt)
+
+			// Formatting the parsed profile again yields the same profile.
+			output := "mode: set\n"
+			for _, b := range profiles[0].Blocks {
+				output += profileLine(profiles[0].FileName, b) + "\n"
+			}
+			again, err := ParseProfilesFromReader(strings.NewReader(output))
+			require.NoError(t, err)
+			assert.Equal(t, profiles, again)
+		})
+	}
+}
+
+func TestParseProfiles_MixedDrivePrefixes(t *testing.T) {
+	// The same file in profiles of different setups is merged.
+	profiles, err := ParseProfilesFromReader(strings.NewReader("mode: set\n" +
+		`c:\work\app\a.go:1.1,2.2 1 0` + "\n" +
+		"C:/work/app/a.go:1.1,2.2 1 1\n"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "C:/work/app/a.go", profiles[0].FileName)
+	assert.Len(t, profiles[0].Blocks, 1)
+	assert.EqualValues(t, 1, profiles[0].CoveredStmt)
+
+	cov := New(profiles)
+	assert.Contains(t, cov.ByPackage(), "C:/work/app")
+}
+
+func TestParseProfiles_LegacyLocalImportPaths(t *testing.T) {
+	// Packages outside of GOPATH and modules may be named by their local
+	// import path, which is "_" and the directory, or by their absolute path.
+	profiles, err := ParseProfilesFromReader(strings.NewReader("mode: set\n" +
+		"_/home/u/app/a.go:1.1,2.2 1 0\n" +
+		"/home/u/app/a.go:1.1,2.2 1 1\n" +
+		"_/C_/work/app/b.go:1.1,2.2 1 1\n"))
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "/home/u/app/a.go", profiles[0].FileName)
+	assert.Len(t, profiles[0].Blocks, 1)
+	assert.EqualValues(t, 1, profiles[0].CoveredStmt)
+	assert.Equal(t, "C:/work/app/b.go", profiles[1].FileName)
+
+	assert.Equal(t, "_go/a.go", normalizeProfilePath("_go/a.go"), "not a local import path")
+}
+
+func TestAtoi(t *testing.T) {
+	for _, s := range []string{"0", "7", "123456", "999999999999999999", "9999999999999999999", "-1", "+1", "1a", ""} {
+		want, wantErr := strconv.Atoi(s)
+		got, err := atoi([]byte(s))
+		assert.Equal(t, want, got, s)
+		assert.Equal(t, wantErr != nil, err != nil, s)
+	}
+}
+
+// writeLargeProfile writes a synthetic coverage profile with the given number
+// of files and blocks per file, similar to the profile of a monorepo.
+func writeLargeProfile(tb testing.TB, files, blocksPerFile int) string {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("mode: atomic\n")
+	for f := 0; f < files; f++ {
+		for b := 1; b <= blocksPerFile; b++ {
+			fmt.Fprintf(&buf, "example.com/monorepo/service%d/pkg/file%d.go:%d.2,%d.16 %d %d\n", f%50, f, b*3, b*3+2, b%4+1, b%3)
+		}
+	}
+
+	fileName := filepath.Join(tb.TempDir(), "coverage.txt")
+	require.NoError(tb, os.WriteFile(fileName, buf.Bytes(), 0644))
+	return fileName
+}
+
+func BenchmarkParseProfiles(b *testing.B) {
+	fileName := writeLargeProfile(b, 1000, 200)
+	info, err := os.Stat(fileName)
+	require.NoError(b, err)
+
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseProfiles(fileName); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestProfileBlock_Source(t *testing.T) {
+	lines := map[int]string{1: "func a() {", 2: "\treturn", 3: "}"}
+
+	src, ok := ProfileBlock{StartLine: 1, StartCol: 10, EndLine: 3, EndCol: 2}.Source(lines)
+	assert.True(t, ok)
+	assert.Equal(t, "{\n\treturn\n}\n", src)
+
+	src, ok = ProfileBlock{StartLine: 2, StartCol: 99, EndLine: 2, EndCol: 1}.Source(lines)
+	assert.True(t, ok)
+	assert.Equal(t, "\n", src)
+
+	_, ok = ProfileBlock{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 1}.Source(lines)
+	assert.False(t, ok)
+}
+
+func TestProfileBlock_LineSpan(t *testing.T) {
+	// "\tif err != nil {" with the block starting at the opening brace
+	b := ProfileBlock{StartLine: 2, StartCol: 16, EndLine: 4, EndCol: 3}
+	assert.Equal(t, "", b.LineSpan(1, "package main"))
+	assert.Equal(t, "{", b.LineSpan(2, "\tif err != nil {"))
+	assert.Equal(t, "\t\treturn err", b.LineSpan(3, "\t\treturn err"))
+	assert.Equal(t, "\t}", b.LineSpan(4, "\t} // done"))
+	assert.Equal(t, "", b.LineSpan(5, "}"))
+
+	// One-line if statement: only the body belongs to the block.
+	b = ProfileBlock{StartLine: 7, StartCol: 16, EndLine: 7, EndCol: 30}
+	assert.Equal(t, "{ return err }", b.LineSpan(7, "\tif err != nil { return err }"))
+
+	// Blocks without columns span entire lines.
+	b = ProfileBlock{StartLine: 1, EndLine: 1}
+	assert.Equal(t, "\treturn", b.LineSpan(1, "\treturn"))
+}
@@ -0,0 +1,104 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// progressInterval is the minimum time between two messages about the
+// progress of the same step.
+const progressInterval = 5 * time.Second
+
+// progress reports the progress of long analyses, e.g. of large repositories,
+// so that slow runs can be told apart from hung runs in CI logs. All methods
+// can be called on a nil *progress, which is quiet (see -quiet).
+type progress struct {
+	out   io.Writer
+	group string // name of the GitHub Actions log group of the messages, if any
+	now   func() time.Time
+	start time.Time
+	last  time.Time // time of the last message about the current step
+}
+
+// newProgress returns a progress that writes its messages to out. If group is
+// not empty, the messages are written into a collapsible group of the GitHub
+// Actions log.
+func newProgress(out io.Writer, group string) *progress {
+	p := &progress{out: out, group: group, now: time.Now}
+	p.start = p.now()
+	p.last = p.start
+
+	return p
+}
+
+// newCommandProgress returns the progress of the main command, which is
+// written to stderr and grouped when running in GitHub Actions. It returns nil
+// if quiet is set.
+func newCommandProgress(quiet bool) *progress {
+	if quiet {
+		return nil
+	}
+
+	var group string
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		group = "Analyze code coverage"
+	}
+
+	return newProgress(os.Stderr, group)
+}
+
+// begin restarts the measurement of the elapsed time and opens the log group.
+func (p *progress) begin() {
+	if p == nil {
+		return
+	}
+
+	p.start = p.now()
+	p.last = p.start
+	if p.group != "" {
+		fmt.Fprintf(p.out, "::group::%s\n", p.group)
+	}
+}
+
+// end reports the total elapsed time and closes the log group.
+func (p *progress) end() {
+	if p == nil {
+		return
+	}
+
+	p.printf("Done")
+	if p.group != "" {
+		fmt.Fprintln(p.out, "::endgroup::")
+	}
+}
+
+// step reports the start of the next step of the analysis.
+func (p *progress) step(format string, args ...any) {
+	if p == nil {
+		return
+	}
+
+	p.last = p.now()
+	p.printf(format, args...)
+}
+
+// files reports that done of total files of the current step have been
+// processed. Messages are only written if the step takes longer than
+// progressInterval, and then at most every progressInterval.
+func (p *progress) files(done, total int) {
+	if p == nil || total == 0 {
+		return
+	}
+
+	if now := p.now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.printf("  %d/%d files (%d%%)", done, total, done*100/total)
+	}
+}
+
+func (p *progress) printf(format string, args ...any) {
+	elapsed := p.now().Sub(p.start).Round(100 * time.Millisecond)
+	fmt.Fprintf(p.out, "[%6s] %s\n", elapsed, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,84 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProgress(group string) (*progress, *bytes.Buffer, *time.Time) {
+	var out bytes.Buffer
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := newProgress(&out, group)
+	p.now = func() time.Time { return now }
+	p.start, p.last = now, now
+
+	return p, &out, &now
+}
+
+func TestProgress(t *testing.T) {
+	p, out, now := newTestProgress("Analyze code coverage")
+
+	p.begin()
+	p.step("Parsing %s", "cover.out")
+	*now = now.Add(1500 * time.Millisecond)
+	p.step("Applying exclusions")
+	for i := 1; i <= 100; i++ {
+		*now = now.Add(time.Second)
+		p.files(i, 100)
+	}
+	p.end()
+
+	expected := `::group::Analyze code coverage
+[    0s] Parsing cover.out
+[  1.5s] Applying exclusions
+[  6.5s]   5/100 files (5%)
+[ 11.5s]   10/100 files (10%)
+`
+	assert.Equal(t, expected, out.String()[:len(expected)])
+	assert.Contains(t, out.String(), "[1m41.5s]   100/100 files (100%)\n[1m41.5s] Done\n::endgroup::\n")
+}
+
+func TestProgress_FastStep(t *testing.T) {
+	p, out, now := newTestProgress("")
+
+	p.begin()
+	p.step("Applying exclusions")
+	for i := 1; i <= 100; i++ {
+		*now = now.Add(10 * time.Millisecond)
+		p.files(i, 100)
+	}
+	p.end()
+
+	assert.Equal(t, "[    0s] Applying exclusions\n[    1s] Done\n", out.String())
+}
+
+func TestProgress_Quiet(t *testing.T) {
+	var p *progress
+	assert.NotPanics(t, func() {
+		p.begin()
+		p.step("Parsing")
+		p.files(1, 2)
+		p.end()
+	})
+
+	assert.Nil(t, newCommandProgress(true))
+}
+
+func TestLoadReport_Progress(t *testing.T) {
+	p, out, _ := newTestProgress("")
+	opts := options{root: "github.com/pentohq/pento", diffFile: "testdata/04-diff.patch", maxLineLength: defaultMaxLineLength, sampleRate: 1, progress: p}
+
+	_, err := loadReport(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, `[    0s] Parsing old coverage testdata/04-old-coverage.txt
+[    0s] Parsing new coverage testdata/04-new-coverage.txt
+[    0s] Reading the source code of 1 files to apply exclusions
+[    0s] Parsing diff testdata/04-diff.patch
+`, out.String())
+}
@@ -0,0 +1,205 @@
+package coverage
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var protectUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s protect [OPTIONS] <REPOSITORY...>
+
+Require the check runs of the coverage gate for merging into the protected
+branch of each REPOSITORY ("owner/name"), so enabling coverage gating across
+many repositories is one command. The check names must match the check-name
+and escalation-check-name inputs of the action in the repositories.
+
+Existing required status checks are kept. If the branch is not protected yet,
+a branch protection that only requires the status checks is created. If the
+branch is protected without required status checks, they have to be enabled
+in the settings of the repository first, since updating the whole protection
+could change other rules.
+
+The token (GH_TOKEN or GITHUB_TOKEN environment variable) needs admin access
+to the repositories.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runProtectCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("protect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, protectUsage)
+		fs.PrintDefaults()
+	}
+
+	branch := fs.String("branch", "main", "protected branch that requires the checks")
+	checkName := fs.String("check", os.Getenv("CHECK_NAME"), "name of the check run of the coverage gate (check-name input of the action, required)")
+	escalationCheck := fs.String("escalation-check", "", "name of the check run of the escalation (escalation-check-name input of the action) to require as well")
+	strict := fs.Bool("strict", false, "require branches to be up to date before merging when creating a new branch protection")
+	dryRun := fs.Bool("dry-run", false, "print the required checks of each repository without changing them")
+	apiURL := fs.String("api-url", os.Getenv("GITHUB_API_URL"), "base URL of the GitHub API (default https://api.github.com)")
+	_ = fs.Parse(args)
+
+	switch {
+	case *checkName == "":
+		fs.Usage()
+		return errors.New("missing -check flag")
+	case fs.NArg() == 0:
+		fs.Usage()
+		return errors.New("missing repository argument")
+	}
+
+	checks := []string{*checkName}
+	if *escalationCheck != "" {
+		checks = append(checks, *escalationCheck)
+	}
+
+	var errs []error
+	for _, repo := range fs.Args() {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("invalid repository %q: expected owner/name", repo))
+			continue
+		}
+
+		gh := newGitHubClient(*apiURL, githubToken(os.LookupEnv), repo)
+		if err := protectBranch(ctx, gh, *branch, checks, *strict, *dryRun, os.Stdout); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// protectBranch adds the given checks to the required status checks of the
+// branch. The branch is protected if it is not yet.
+func protectBranch(ctx context.Context, gh *githubClient, branch string, checks []string, strict, dryRun bool, out io.Writer) error {
+	required, err := gh.requiredStatusChecks(ctx, branch)
+	if err != nil {
+		return err
+	}
+
+	if required == nil {
+		protected, err := gh.isProtectedBranch(ctx, branch)
+		switch {
+		case err != nil:
+			return err
+		case protected:
+			return fmt.Errorf("branch %s is protected without required status checks: enable them in the branch protection settings first", branch)
+		}
+
+		fmt.Fprintf(out, "%s: protecting branch %s with required checks %s\n", gh.repo, branch, quoteAll(checks))
+		if dryRun {
+			return nil
+		}
+		return gh.createBranchProtection(ctx, branch, checks, strict)
+	}
+
+	var missing []string
+	for _, name := range checks {
+		if !required.requires(name) {
+			missing = append(missing, name)
+			required.Checks = append(required.Checks, githubRequiredCheck{Context: name})
+		}
+	}
+
+	if len(missing) == 0 {
+		fmt.Fprintf(out, "%s: branch %s already requires %s\n", gh.repo, branch, quoteAll(checks))
+		return nil
+	}
+
+	fmt.Fprintf(out, "%s: adding required checks %s to branch %s\n", gh.repo, quoteAll(missing), branch)
+	if dryRun {
+		return nil
+	}
+	return gh.updateRequiredStatusChecks(ctx, branch, required)
+}
+
+// quoteAll returns the quoted names separated by commas.
+func quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+type githubRequiredStatusChecks struct {
+	Strict bool                  `json:"strict"`
+	Checks []githubRequiredCheck `json:"checks"`
+}
+
+type githubRequiredCheck struct {
+	Context string `json:"context"`
+	AppID   *int64 `json:"app_id,omitempty"`
+}
+
+// requires returns whether the check with the given name is required.
+func (c *githubRequiredStatusChecks) requires(name string) bool {
+	for _, check := range c.Checks {
+		if check.Context == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requiredStatusChecks returns the required status checks of the protected
+// branch. If the branch is not protected or does not require status checks,
+// nil is returned.
+func (c *githubClient) requiredStatusChecks(ctx context.Context, branch string) (*githubRequiredStatusChecks, error) {
+	var checks githubRequiredStatusChecks
+	err := c.do(ctx, http.MethodGet, c.repoPath("branches/%s/protection/required_status_checks", url.PathEscape(branch)), nil, &checks)
+	switch {
+	case isGitHubStatus(err, http.StatusNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return &checks, nil
+}
+
+// isProtectedBranch returns whether the branch has a branch protection.
+func (c *githubClient) isProtectedBranch(ctx context.Context, branch string) (bool, error) {
+	err := c.do(ctx, http.MethodGet, c.repoPath("branches/%s/protection", url.PathEscape(branch)), nil, nil)
+	switch {
+	case isGitHubStatus(err, http.StatusNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
+
+// updateRequiredStatusChecks replaces the required status checks of the
+// protected branch.
+func (c *githubClient) updateRequiredStatusChecks(ctx context.Context, branch string, checks *githubRequiredStatusChecks) error {
+	return c.do(ctx, http.MethodPatch, c.repoPath("branches/%s/protection/required_status_checks", url.PathEscape(branch)), checks, nil)
+}
+
+// createBranchProtection protects the branch with a protection that only
+// requires the given status checks.
+func (c *githubClient) createBranchProtection(ctx context.Context, branch string, checks []string, strict bool) error {
+	required := githubRequiredStatusChecks{Strict: strict}
+	for _, name := range checks {
+		required.Checks = append(required.Checks, githubRequiredCheck{Context: name})
+	}
+
+	return c.do(ctx, http.MethodPut, c.repoPath("branches/%s/protection", url.PathEscape(branch)), map[string]any{
+		"required_status_checks":        required,
+		"enforce_admins":                nil,
+		"required_pull_request_reviews": nil,
+		"restrictions":                  nil,
+	}, nil)
+}
@@ -0,0 +1,96 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectBranch(t *testing.T) {
+	tests := map[string]struct {
+		protection     int    // status of GET .../protection
+		requiredChecks string // reply of GET .../protection/required_status_checks, 404 if empty
+		dryRun         bool
+		out            string
+		request        string
+		err            string
+	}{
+		"unprotected": {
+			protection: http.StatusNotFound,
+			out:        `example/repo: protecting branch release/v1 with required checks "Coverage", "Coverage regression review"`,
+			request:    `PUT /repos/example/repo/branches/release%2Fv1/protection {"enforce_admins":null,"required_pull_request_reviews":null,"required_status_checks":{"strict":false,"checks":[{"context":"Coverage"},{"context":"Coverage regression review"}]},"restrictions":null}`,
+		},
+		"missing checks": {
+			requiredChecks: `{"strict": true, "contexts": ["lint"], "checks": [{"context": "lint", "app_id": 15368}, {"context": "Coverage"}]}`,
+			out:            `example/repo: adding required checks "Coverage regression review" to branch release/v1`,
+			request:        `PATCH /repos/example/repo/branches/release%2Fv1/protection/required_status_checks {"strict":true,"checks":[{"context":"lint","app_id":15368},{"context":"Coverage"},{"context":"Coverage regression review"}]}`,
+		},
+		"dry run": {
+			requiredChecks: `{"strict": false, "checks": []}`,
+			dryRun:         true,
+			out:            `example/repo: adding required checks "Coverage", "Coverage regression review" to branch release/v1`,
+		},
+		"already required": {
+			requiredChecks: `{"strict": false, "checks": [{"context": "Coverage regression review"}, {"context": "Coverage"}]}`,
+			out:            `example/repo: branch release/v1 already requires "Coverage", "Coverage regression review"`,
+		},
+		"protected without status checks": {
+			protection: http.StatusOK,
+			err:        "branch release/v1 is protected without required status checks: enable them in the branch protection settings first",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var requests []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				if r.Method != http.MethodGet {
+					body, _ := io.ReadAll(r.Body)
+					requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+strings.TrimSpace(string(body)))
+					return
+				}
+
+				switch r.URL.EscapedPath() {
+				case "/repos/example/repo/branches/release%2Fv1/protection/required_status_checks":
+					if tt.requiredChecks == "" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					fmt.Fprint(w, tt.requiredChecks)
+				case "/repos/example/repo/branches/release%2Fv1/protection":
+					w.WriteHeader(tt.protection)
+					fmt.Fprint(w, `{}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			var out bytes.Buffer
+			gh := newGitHubClient(srv.URL, "secret", "example/repo")
+			err := protectBranch(context.Background(), gh, "release/v1", []string{"Coverage", "Coverage regression review"}, false, tt.dryRun, &out)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				assert.Empty(t, requests)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out+"\n", out.String())
+			if tt.request == "" {
+				assert.Empty(t, requests)
+			} else {
+				assert.Equal(t, []string{tt.request}, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package coverage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// defaultModuleProxy is used if GOPROXY does not name a proxy.
+const defaultModuleProxy = "https://proxy.golang.org"
+
+// moduleProxy downloads released versions of a module from a Go module proxy
+// (see https://go.dev/ref/mod#goproxy-protocol).
+type moduleProxy struct {
+	url string // e.g. https://proxy.golang.org
+}
+
+// moduleProxyFromEnv returns the first proxy of the GOPROXY environment
+// variable. Entries such as "direct" and "off" are skipped since the module
+// zip is needed.
+func moduleProxyFromEnv(goproxy string) moduleProxy {
+	for _, entry := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		if strings.HasPrefix(entry, "https://") || strings.HasPrefix(entry, "http://") {
+			return moduleProxy{url: strings.TrimSuffix(entry, "/")}
+		}
+	}
+
+	return moduleProxy{url: defaultModuleProxy}
+}
+
+// moduleVersionInfo is the metadata of a module version. The origin is only
+// known for versions that were fetched by a recent proxy.
+type moduleVersionInfo struct {
+	Version string
+	Time    time.Time
+	Origin  *struct {
+		VCS  string
+		URL  string
+		Hash string
+	}
+}
+
+// escapeModulePath escapes the upper case letters of a module path or version
+// as the proxy protocol requires, e.g. "github.com/Azure" becomes
+// "github.com/!azure".
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (p moduleProxy) versionURL(module, version, ext string) string {
+	return fmt.Sprintf("%s/%s/@v/%s.%s", p.url, escapeModulePath(module), escapeModulePath(version), ext)
+}
+
+// info returns the metadata of the given version of the module.
+func (p moduleProxy) info(ctx context.Context, module, version string) (moduleVersionInfo, error) {
+	var info moduleVersionInfo
+	data, err := download(ctx, p.versionURL(module, version, "info"))
+	if err != nil {
+		return info, err
+	}
+
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid info of %s@%s: %w", module, version, err)
+	}
+
+	return info, nil
+}
+
+// extract downloads the zip of the given version of the module and extracts
+// it into dir. It returns the root directory of the module.
+func (p moduleProxy) extract(ctx context.Context, module, version, dir string) (string, error) {
+	data, err := download(ctx, p.versionURL(module, version, "zip"))
+	if err != nil {
+		return "", err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid zip of %s@%s: %w", module, version, err)
+	}
+
+	// All files of a module zip are inside of "module@version/".
+	prefix := module + "@" + version + "/"
+	root := filepath.Join(dir, "module")
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return "", fmt.Errorf("invalid file %q in zip of %s@%s", f.Name, module, version)
+		}
+
+		if err := extractZipFile(f, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			return "", err
+		}
+	}
+
+	return root, nil
+}
+
+func extractZipFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, io.LimitReader(rc, maxArchiveSize))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// coverageRunner runs the tests of the module in dir and writes the coverage
+// profile to profile.
+type coverageRunner func(ctx context.Context, dir, profile string, log io.Writer) error
+
+// goTestCoverage runs "go test -coverprofile" for all packages of the module.
+// The module zip contains no vendor directory and may lack a go.sum, so the
+// dependencies are resolved from the module cache or the proxy.
+func goTestCoverage(ctx context.Context, dir, profile string, log io.Writer) error {
+	cmd := exec.CommandContext(ctx, "go", "test", "-coverprofile="+profile, "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	cmd.Stdout, cmd.Stderr = log, log
+
+	return cmd.Run()
+}
+
+// importReleases adds a snapshot of the coverage of each of the given versions
+// of the module to the history, e.g. to populate the trend of an established
+// project. Each version is downloaded from the proxy into a temporary
+// directory and its tests are run with coverage. The commit of a snapshot is
+// the commit of the version if the proxy knows it and the version otherwise;
+// its time is the release time. Versions that are already part of the
+// history are skipped. If the tests of a version fail, the coverage of the
+// packages that were tested is recorded anyway. Versions without coverage are
+// reported in the returned error after all other versions were imported.
+func (h *History) importReleases(ctx context.Context, proxy moduleProxy, run coverageRunner, module string, versions []string, branch, trim string, log io.Writer) (added, skipped int, err error) {
+	snapshots, err := h.Snapshots()
+	if err != nil {
+		return 0, 0, err
+	}
+	known := make(map[string]bool, len(snapshots))
+	for _, s := range snapshots {
+		known[s.Commit] = true
+	}
+
+	var errs []error
+	for _, version := range versions {
+		info, err := proxy.info(ctx, module, version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", version, err))
+			continue
+		}
+
+		commit := info.Version
+		if info.Origin != nil && info.Origin.Hash != "" {
+			commit = info.Origin.Hash
+		}
+		if known[commit] || known[info.Version] {
+			skipped++
+			continue
+		}
+
+		fmt.Fprintf(log, "Running the tests of %s@%s\n", module, info.Version)
+		cov, err := importRelease(ctx, proxy, run, module, info.Version, log)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", version, err))
+			continue
+		}
+		if trim != "" {
+			cov.TrimPrefix(trim)
+		}
+
+		if err := h.Add(NewSnapshot(cov, commit, branch, info.Time)); err != nil {
+			return added, skipped, err
+		}
+		known[commit] = true
+		added++
+	}
+
+	return added, skipped, errors.Join(errs...)
+}
+
+// importRelease returns the coverage of the tests of the given version of the
+// module.
+func importRelease(ctx context.Context, proxy moduleProxy, run coverageRunner, module, version string, log io.Writer) (*Coverage, error) {
+	dir, err := os.MkdirTemp("", "go-coverage-report-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	root, err := proxy.extract(ctx, module, version, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := filepath.Join(dir, "coverage.txt")
+	runErr := run(ctx, root, profile, log)
+
+	cov, err := ParseCoverageContext(ctx, profile)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("tests failed without coverage: %w", runErr)
+		}
+		return nil, err
+	}
+	if runErr != nil {
+		fmt.Fprintf(log, "WARNING: the tests of %s@%s failed (%v), recording the coverage of the packages that were tested\n", module, version, runErr)
+	}
+
+	return cov, nil
+}
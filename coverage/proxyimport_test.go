@@ -0,0 +1,116 @@
+package coverage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleProxyFromEnv(t *testing.T) {
+	assert.Equal(t, defaultModuleProxy, moduleProxyFromEnv("").url)
+	assert.Equal(t, defaultModuleProxy, moduleProxyFromEnv("direct").url)
+	assert.Equal(t, "https://goproxy.example.com", moduleProxyFromEnv("off|https://goproxy.example.com/,direct").url)
+	assert.Equal(t, "https://proxy.example.com/@v/github.com/!azure/sdk/@v/v1.0.0-!r!c1.zip",
+		moduleProxy{url: "https://proxy.example.com/@v"}.versionURL("github.com/Azure/sdk", "v1.0.0-RC1", "zip"))
+}
+
+// newFakeModuleProxy serves the versions v1.0.0 (without origin), v1.1.0 and
+// v1.2.0 of example.com/lib. Each zip contains a marker file with the
+// version.
+func newFakeModuleProxy(t *testing.T) moduleProxy {
+	release := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	infos := map[string]string{
+		"v1.0.0": fmt.Sprintf(`{"Version": "v1.0.0", "Time": %q}`, release.Format(time.RFC3339)),
+		"v1.1.0": fmt.Sprintf(`{"Version": "v1.1.0", "Time": %q, "Origin": {"VCS": "git", "Hash": "1111111"}}`, release.AddDate(0, 1, 0).Format(time.RFC3339)),
+		"v1.2.0": fmt.Sprintf(`{"Version": "v1.2.0", "Time": %q, "Origin": {"VCS": "git", "Hash": "2222222"}}`, release.AddDate(0, 2, 0).Format(time.RFC3339)),
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var version, ext string
+		if _, err := fmt.Sscanf(filepath.Base(r.URL.Path), "%6s.%s", &version, &ext); err != nil || infos[version] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		require.Equal(t, "/example.com/lib/@v/"+version+"."+ext, r.URL.Path)
+
+		if ext == "info" {
+			fmt.Fprint(w, infos[version])
+			return
+		}
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range map[string]string{"go.mod": "module example.com/lib\n", "version.txt": version} {
+			fw, err := zw.Create("example.com/lib@" + version + "/" + name)
+			require.NoError(t, err)
+			_, _ = io.WriteString(fw, content)
+		}
+		require.NoError(t, zw.Close())
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+
+	return moduleProxy{url: srv.URL}
+}
+
+func TestHistory_ImportReleases(t *testing.T) {
+	proxy := newFakeModuleProxy(t)
+
+	// The fake tests of v1.2.0 fail after covering 2 of 4 statements, and
+	// those of v1.1.0 do not even compile.
+	run := func(ctx context.Context, dir, profile string, log io.Writer) error {
+		version, err := os.ReadFile(filepath.Join(dir, "version.txt"))
+		require.NoError(t, err)
+		_, err = os.Stat(filepath.Join(dir, "go.mod"))
+		require.NoError(t, err)
+
+		switch string(version) {
+		case "v1.1.0":
+			return errors.New("exit status 2")
+		case "v1.2.0":
+			require.NoError(t, os.WriteFile(profile, []byte("mode: set\nexample.com/lib/lib.go:1.1,2.2 2 1\nexample.com/lib/lib.go:3.1,4.2 2 0\n"), 0644))
+			return errors.New("exit status 1")
+		default:
+			return os.WriteFile(profile, []byte("mode: set\nexample.com/lib/lib.go:1.1,2.2 2 1\n"), 0644)
+		}
+	}
+
+	h := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	var log bytes.Buffer
+	added, skipped, err := h.importReleases(context.Background(), proxy, run, "example.com/lib", []string{"v1.0.0", "v1.1.0", "v1.2.0", "v9.9.9"}, "main", "example.com/lib", &log)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 0, skipped)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "v1.1.0: tests failed without coverage: exit status 2")
+	assert.Contains(t, err.Error(), "v9.9.9: GET ")
+	assert.Contains(t, log.String(), "WARNING: the tests of example.com/lib@v1.2.0 failed (exit status 1)")
+
+	snapshots, err := h.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "v1.0.0", snapshots[0].Commit)
+	assert.Equal(t, "main", snapshots[0].Branch)
+	assert.Equal(t, 100.0, snapshots[0].Percent())
+	assert.Equal(t, "2222222", snapshots[1].Commit)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), snapshots[1].Time)
+	assert.Equal(t, 50.0, snapshots[1].Percent())
+	assert.Contains(t, snapshots[1].Files, "lib.go")
+
+	// Versions that are already part of the history are skipped.
+	added, skipped, err = h.importReleases(context.Background(), proxy, run, "example.com/lib", []string{"v1.0.0", "v1.2.0"}, "main", "", &log)
+	require.NoError(t, err)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 2, skipped)
+}
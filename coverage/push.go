@@ -0,0 +1,122 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pushEvent is the part of the payload of a push event that is used by the
+// action.
+type pushEvent struct {
+	Ref    string `json:"ref"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// readPushEvent reads the payload of the push event that triggered the
+// workflow.
+func readPushEvent(eventPath string) (*pushEvent, error) {
+	if eventPath == "" {
+		return nil, errors.New("missing GITHUB_EVENT_PATH environment variable")
+	}
+
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event payload: %w", err)
+	}
+
+	var event pushEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+
+	switch {
+	case event.After == "":
+		return nil, errors.New("the push event payload contains no commit")
+	case strings.Trim(event.Before, "0") == "":
+		// GitHub sends a zero SHA if the push created the branch.
+		return nil, errors.New("the push created the branch, so there is no previous commit to compare with")
+	}
+
+	return &event, nil
+}
+
+// CondensedMarkdown returns the title, the overall summary and the impacted
+// packages of the report. The details of files and lines are left out, so the
+// report fits into a commit comment.
+func (r *Result) CondensedMarkdown() string {
+	report := new(strings.Builder)
+
+	fmt.Fprintln(report, r.Title())
+	r.addOverallCoverageSummary(report)
+	r.addPackageDetails(report)
+	fmt.Fprintln(report, reportMarker)
+
+	return report.String()
+}
+
+// postCommitComment posts the report as comment of the commit and removes the
+// report of a previous run. An unchanged report is not posted again (see
+// postReport).
+func (a *action) postCommitComment(ctx context.Context, sha, markdown string) error {
+	markdown, marker := withReportDigest(markdown)
+
+	comments, err := a.gh.commitComments(ctx, sha)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range comments {
+		if c.User.Login == "github-actions[bot]" && isReportComment(c.Body) {
+			if strings.Contains(c.Body, marker) {
+				fmt.Fprintln(a.out, "Coverage report is unchanged, keeping the existing comment")
+				return nil
+			}
+			fmt.Fprintln(a.out, "Replacing old coverage report comment")
+			if err := a.gh.deleteCommitComment(ctx, c.ID); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	fmt.Fprintf(a.out, "Creating coverage report comment on commit %s\n", shortCommit(sha))
+	return a.gh.createCommitComment(ctx, sha, markdown)
+}
+
+// postCommitReport posts the condensed report as comment of the pushed commit,
+// replacing the report of a previous run, and sets the commit status to the
+// result of the coverage checks.
+func (a *action) postCommitReport(ctx context.Context, result *Result, checkErr error) error {
+	sha := a.cfg.PushAfter
+
+	if a.cfg.SkipComment {
+		fmt.Fprintln(a.out, "Skipping commit comment (SKIP_COMMENT=true)")
+	} else {
+		if err := a.postCommitComment(ctx, sha, result.CondensedMarkdown()); err != nil {
+			return err
+		}
+	}
+
+	state := "success"
+	if checkErr != nil {
+		state = "failure"
+	}
+
+	var targetURL string
+	if a.cfg.ServerURL != "" {
+		targetURL = fmt.Sprintf("%s/%s/actions/runs/%d", strings.TrimSuffix(a.cfg.ServerURL, "/"), a.cfg.Repository, a.cfg.RunID)
+	}
+
+	description, err := result.statusSummary(a.cfg.StatusTemplate, checkErr == nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Setting commit status %q to %s\n", a.cfg.StatusContext, state)
+	return a.gh.createCommitStatus(ctx, sha, state, a.cfg.StatusContext, description, targetURL)
+}
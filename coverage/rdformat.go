@@ -0,0 +1,136 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The types below implement the subset of the reviewdog diagnostic format
+// (https://github.com/reviewdog/reviewdog/tree/master/proto/rdf) that is
+// needed to report uncovered new code.
+
+type rdDiagnosticResult struct {
+	Source      rdSource       `json:"source"`
+	Severity    string         `json:"severity,omitempty"`
+	Diagnostics []rdDiagnostic `json:"diagnostics"`
+}
+
+type rdDiagnostic struct {
+	Message  string     `json:"message"`
+	Location rdLocation `json:"location"`
+	Severity string     `json:"severity,omitempty"`
+	Source   *rdSource  `json:"source,omitempty"`
+	Code     *rdCode    `json:"code,omitempty"`
+}
+
+type rdSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdCode struct {
+	Value string `json:"value"`
+}
+
+type rdLocation struct {
+	Path  string  `json:"path"`
+	Range rdRange `json:"range"`
+}
+
+type rdRange struct {
+	Start rdPosition `json:"start"`
+	End   rdPosition `json:"end"`
+}
+
+type rdPosition struct {
+	Line int `json:"line"`
+}
+
+var rdToolSource = rdSource{
+	Name: "go-coverage-report",
+	URL:  "https://github.com/fgrosse/go-coverage-report",
+}
+
+// diagnostics returns a reviewdog diagnostic for each new code block that is
+// not covered by any test.
+func (r *Result) diagnostics() []rdDiagnostic {
+	blocks := r.getNewCodeBlocks()
+
+	diagnostics := []rdDiagnostic{}
+	for _, block := range blocks {
+		if block.Covered {
+			continue
+		}
+
+		stmtText := "statement"
+		if block.NumStmt != 1 {
+			stmtText = "statements"
+		}
+
+		diagnostics = append(diagnostics, rdDiagnostic{
+			Message: fmt.Sprintf("New code is not covered by tests (%d %s)", block.NumStmt, stmtText),
+			Location: rdLocation{
+				Path: r.repositoryPath(block.FileName),
+				Range: rdRange{
+					Start: rdPosition{Line: block.StartLine},
+					End:   rdPosition{Line: block.EndLine},
+				},
+			},
+			Severity: "WARNING",
+			Source:   &rdToolSource,
+			Code:     &rdCode{Value: "uncovered-new-code"},
+		})
+	}
+
+	return diagnostics
+}
+
+// RDJSON returns the uncovered new code in reviewdog's rdjson format.
+func (r *Result) RDJSON() string {
+	result := rdDiagnosticResult{
+		Source:      rdToolSource,
+		Severity:    "WARNING",
+		Diagnostics: r.diagnostics(),
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		panic(err) // should never happen
+	}
+
+	return string(data)
+}
+
+// RDJSONL returns the uncovered new code in reviewdog's rdjsonl format, i.e.
+// a single JSON encoded diagnostic per line.
+func (r *Result) RDJSONL() string {
+	var lines []string
+	for _, d := range r.diagnostics() {
+		data, err := json.Marshal(d)
+		if err != nil {
+			panic(err) // should never happen
+		}
+		lines = append(lines, string(data))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// repositoryPath maps a file name of the coverage profile to the path of the
+// file relative to the repository root, which is what code review tools
+// expect. If the file is part of the diff we use the path from the diff,
+// otherwise the root package is stripped from the file name.
+func (r *Report) repositoryPath(fileName string) string {
+	if fileDiff := r.DiffInfo.findFileDiff(fileName); fileDiff != nil && strings.HasSuffix(fileName, fileDiff.FileName) {
+		return fileDiff.FileName
+	}
+
+	if r.RootPackage != "" {
+		if rel, ok := strings.CutPrefix(fileName, r.RootPackage+"/"); ok {
+			return rel
+		}
+	}
+
+	return fileName
+}
@@ -0,0 +1,69 @@
+package coverage
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_RDJSONL(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+
+	changedFiles, err := ParseChangedFiles("testdata/04-changed-files.json", "github.com/pentohq/pento")
+	require.NoError(t, err)
+
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+
+	expected := []string{
+		`{"message":"New code is not covered by tests (1 statement)","location":{"path":"pkg/age/age.go","range":{"start":{"line":55},"end":{"line":57}}},"severity":"WARNING","source":{"name":"go-coverage-report","url":"https://github.com/fgrosse/go-coverage-report"},"code":{"value":"uncovered-new-code"}}`,
+		`{"message":"New code is not covered by tests (1 statement)","location":{"path":"pkg/age/age.go","range":{"start":{"line":58},"end":{"line":58}}},"severity":"WARNING","source":{"name":"go-coverage-report","url":"https://github.com/fgrosse/go-coverage-report"},"code":{"value":"uncovered-new-code"}}`,
+		`{"message":"New code is not covered by tests (1 statement)","location":{"path":"pkg/age/age.go","range":{"start":{"line":58},"end":{"line":60}}},"severity":"WARNING","source":{"name":"go-coverage-report","url":"https://github.com/fgrosse/go-coverage-report"},"code":{"value":"uncovered-new-code"}}`,
+	}
+
+	assert.Equal(t, strings.Join(expected, "\n"), report.Analyze().RDJSONL())
+}
+
+func TestReport_RDJSON(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/03-old-coverage.txt")
+	require.NoError(t, err)
+
+	newCov, err := ParseCoverage("testdata/03-new-coverage.txt")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, []string{"example.com/calculator/math.go"})
+	report.RootPackage = "example.com/calculator"
+
+	var result rdDiagnosticResult
+	err = json.Unmarshal([]byte(report.Analyze().RDJSON()), &result)
+	require.NoError(t, err)
+
+	assert.Equal(t, "go-coverage-report", result.Source.Name)
+	require.Len(t, result.Diagnostics, 4)
+	for _, d := range result.Diagnostics {
+		assert.Equal(t, "math.go", d.Location.Path)
+		assert.Equal(t, "WARNING", d.Severity)
+	}
+
+	assert.Equal(t, "New code is not covered by tests (2 statements)", result.Diagnostics[2].Message)
+	assert.Equal(t, rdRange{Start: rdPosition{Line: 26}, End: rdPosition{Line: 26}}, result.Diagnostics[2].Location.Range)
+}
+
+func TestReport_RDJSON_NoDiagnostics(t *testing.T) {
+	cov, err := ParseCoverage("testdata/02-new-coverage.txt")
+	require.NoError(t, err)
+
+	report := NewReport(cov, cov, []string{"github.com/fgrosse/prioqueue/min_heap.go"})
+	assert.Empty(t, report.Analyze().RDJSONL())
+	assert.Contains(t, report.Analyze().RDJSON(), `"diagnostics": []`)
+}
@@ -0,0 +1,269 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var releaseReportUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s release-report [OPTIONS] <OLD_COVERAGE_FILE> <NEW_COVERAGE_FILE>
+
+Generate a coverage appendix for the release notes of NEW_TAG that summarizes
+the changes since the release OLD_TAG: the coverage of each package that
+changed and the exported functions and methods that were added since OLD_TAG
+but are not covered by any test.
+
+OLD_COVERAGE_FILE and NEW_COVERAGE_FILE are the coverage profiles of the two
+releases. The source code of the new release is read from the working tree and
+the source code of the old release via "git show OLD_TAG:<file>", so the command
+must run in a checkout of NEW_TAG that contains OLD_TAG.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+// ReleaseReport summarizes the coverage changes between two releases.
+type ReleaseReport struct {
+	OldTag, NewTag string
+	OldCoverage    float64
+	NewCoverage    float64
+	Packages       []PackageChange
+	UntestedAPIs   []FunctionCoverage // exported functions added since OldTag without any coverage
+	UnknownFiles   []string           `json:",omitempty"` // files whose old source code could not be read
+}
+
+// PackageChange is the coverage of a package in two releases. Packages that
+// were added or removed have a zero old or new coverage respectively.
+type PackageChange struct {
+	Package        string
+	Old, New       StmtCount
+	Added, Removed bool `json:",omitempty"`
+}
+
+func runReleaseReportCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("release-report", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, releaseReportUsage)
+		fs.PrintDefaults()
+	}
+
+	oldTag := fs.String("old-tag", "", "git tag of the previous release (required)")
+	newTag := fs.String("new-tag", "HEAD", "name of the new release")
+	trim := fs.String("trim", "", "trim a prefix in the package and file names")
+	format := fs.String("format", "markdown", "output format (markdown or json)")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly 2 arguments but got %d", fs.NArg())
+	}
+	if *oldTag == "" {
+		fs.Usage()
+		return fmt.Errorf("missing -old-tag flag")
+	}
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+
+	oldCov, err := ParseCoverageContext(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to parse old coverage: %w", err)
+	}
+
+	newCov, err := ParseCoverageContext(ctx, fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("failed to parse new coverage: %w", err)
+	}
+
+	report := newReleaseReport(*oldTag, *newTag, oldCov, newCov, func(fileName string) ([]byte, error) {
+		return gitSourceFile(ctx, *oldTag, fileName)
+	}, sourceTree{})
+	report.TrimPrefix(*trim)
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(report)
+	}
+
+	fmt.Fprintln(os.Stdout, report.Markdown())
+	return nil
+}
+
+// newReleaseReport compares the coverage of two releases. The old source code
+// is read via oldSource and the new source code from the tree, which is used
+// to detect exported functions that were added since the old release.
+func newReleaseReport(oldTag, newTag string, oldCov, newCov *Coverage, oldSource func(fileName string) ([]byte, error), tree sourceTree) *ReleaseReport {
+	r := &ReleaseReport{
+		OldTag:      oldTag,
+		NewTag:      newTag,
+		OldCoverage: oldCov.Percent(),
+		NewCoverage: newCov.Percent(),
+	}
+
+	oldPkgs, newPkgs := oldCov.ByPackage(), newCov.ByPackage()
+	for _, pkg := range sortedKeys(unionKeys(oldPkgs, newPkgs)) {
+		var change PackageChange
+		change.Package = pkg
+		if c, ok := oldPkgs[pkg]; ok {
+			change.Old = StmtCount{Total: c.TotalStmt, Covered: c.CoveredStmt}
+		} else {
+			change.Added = true
+		}
+		if c, ok := newPkgs[pkg]; ok {
+			change.New = StmtCount{Total: c.TotalStmt, Covered: c.CoveredStmt}
+		} else {
+			change.Removed = true
+		}
+
+		if change.Old != change.New {
+			r.Packages = append(r.Packages, change)
+		}
+	}
+
+	mapper := NewStatementLineMapper()
+	for _, fileName := range sortedKeys(newCov.Files) {
+		sourcePath, ok := tree.find(fileName)
+		if !ok {
+			r.UnknownFiles = append(r.UnknownFiles, fileName)
+			continue
+		}
+
+		src, err := os.ReadFile(sourcePath)
+		if err != nil {
+			r.UnknownFiles = append(r.UnknownFiles, fileName)
+			continue
+		}
+
+		extents, err := mapper.exportedFunctions(sourcePath, src)
+		if err != nil || len(extents) == 0 {
+			continue
+		}
+
+		var existing map[string]bool
+		if old, err := oldSource(fileName); err == nil {
+			oldExtents, _ := mapper.exportedFunctions(sourcePath, old)
+			existing = map[string]bool{}
+			for _, e := range oldExtents {
+				existing[e.name] = true
+			}
+		} else if _, ok := oldCov.Files[fileName]; ok {
+			// The file existed in the old release but we can't tell which
+			// of its functions are new.
+			r.UnknownFiles = append(r.UnknownFiles, fileName)
+			continue
+		}
+
+		for _, f := range functionCoverage(fileName, newCov.Files[fileName], extents) {
+			if !existing[f.Name] && f.TotalStmt > 0 && f.CoveredStmt == 0 {
+				f.Name = path.Base(path.Dir(fileName)) + "." + f.Name
+				r.UntestedAPIs = append(r.UntestedAPIs, f)
+			}
+		}
+	}
+
+	return r
+}
+
+// exportedFunctions returns the extents of all exported functions and of the
+// exported methods of exported types in the given source code. Methods are
+// named like "(*Server).Run".
+func (m *StatementLineMapper) exportedFunctions(fileName string, src []byte) ([]funcExtent, error) {
+	file, err := parser.ParseFile(m.fset, fileName, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var extents []funcExtent
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !fn.Name.IsExported() {
+			continue
+		}
+
+		if fn.Recv != nil && len(fn.Recv.List) > 0 && !ast.IsExported(receiverBaseName(fn.Recv.List[0].Type)) {
+			continue
+		}
+
+		extents = append(extents, m.extent(qualifiedFuncName(fn), false, fn))
+	}
+
+	return extents, nil
+}
+
+// receiverBaseName returns the name of the type of a method receiver without
+// pointer and type parameters.
+func receiverBaseName(expr ast.Expr) string {
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		default:
+			return receiverTypeName(t)
+		}
+	}
+}
+
+func (r *ReleaseReport) TrimPrefix(prefix string) {
+	for i := range r.Packages {
+		r.Packages[i].Package = trimPrefix(r.Packages[i].Package, prefix)
+	}
+	for i := range r.UntestedAPIs {
+		r.UntestedAPIs[i].FileName = trimPrefix(r.UntestedAPIs[i].FileName, prefix)
+	}
+	for i, name := range r.UnknownFiles {
+		r.UnknownFiles[i] = trimPrefix(name, prefix)
+	}
+}
+
+// Markdown returns the report as appendix for release notes.
+func (r *ReleaseReport) Markdown() string {
+	var report strings.Builder
+
+	fmt.Fprintf(&report, "## Test coverage of %s\n\n", r.NewTag)
+	fmt.Fprintf(&report, "The overall coverage is **%.2f%%** (%s: %.2f%%, %+.2f%%).\n\n", r.NewCoverage, r.OldTag, r.OldCoverage, r.NewCoverage-r.OldCoverage)
+
+	if len(r.Packages) == 0 {
+		fmt.Fprintf(&report, "The coverage of all packages is unchanged since %s.\n", r.OldTag)
+	} else {
+		fmt.Fprintf(&report, "### Package changes since %s\n\n", r.OldTag)
+		fmt.Fprintf(&report, "| Package | %s | %s | Change | Statements |\n", r.OldTag, r.NewTag)
+		fmt.Fprintln(&report, "|---------|------|------|--------|------------|")
+		for _, p := range r.Packages {
+			oldPercent, newPercent := fmt.Sprintf("%.2f%%", p.Old.Percent()), fmt.Sprintf("%.2f%%", p.New.Percent())
+			delta := fmt.Sprintf("%+.2f%%", p.New.Percent()-p.Old.Percent())
+			switch {
+			case p.Added:
+				oldPercent, delta = "-", "new"
+			case p.Removed:
+				newPercent, delta = "-", "removed"
+			}
+			fmt.Fprintf(&report, "| %s | %s | %s | %s | %+d |\n", p.Package, oldPercent, newPercent, delta, p.New.Total-p.Old.Total)
+		}
+	}
+
+	if len(r.UntestedAPIs) > 0 {
+		fmt.Fprintf(&report, "\n### New untested public APIs\n\n")
+		fmt.Fprintf(&report, "The following exported functions were added since %s and are not covered by any test:\n\n", r.OldTag)
+		for _, f := range r.UntestedAPIs {
+			fmt.Fprintf(&report, "- `%s` (%s:%d)\n", f.Name, f.FileName, f.Line)
+		}
+	}
+
+	if len(r.UnknownFiles) > 0 {
+		fmt.Fprintf(&report, "\n_The source code of %d files was not available, so their new public APIs are not listed._\n", len(r.UnknownFiles))
+	}
+
+	return strings.TrimSpace(report.String())
+}
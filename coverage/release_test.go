@@ -0,0 +1,79 @@
+package coverage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReleaseReport(t *testing.T) {
+	oldSrc := `package svc
+
+func Load() {
+	println("load")
+}
+`
+	newSrc := `package svc
+
+func Load() {
+	println("load")
+}
+
+func Store() {
+	println("store")
+}
+
+type client struct{}
+
+func (c *client) Send() {
+	println("send")
+}
+
+func helper() {
+	println("helper")
+}
+`
+	fileName := filepath.Join(t.TempDir(), "svc", "svc.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fileName), 0755))
+	require.NoError(t, os.WriteFile(fileName, []byte(newSrc), 0644))
+
+	oldCov := New([]*Profile{
+		{FileName: fileName, Blocks: []ProfileBlock{{StartLine: 3, StartCol: 13, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0}}, TotalStmt: 1},
+		{FileName: "example.com/gone/gone.go", TotalStmt: 2, CoveredStmt: 2},
+	})
+	newCov := New([]*Profile{{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 13, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+		{StartLine: 7, StartCol: 14, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 0},
+		{StartLine: 13, StartCol: 25, EndLine: 15, EndCol: 2, NumStmt: 1, Count: 0},
+		{StartLine: 17, StartCol: 15, EndLine: 19, EndCol: 2, NumStmt: 1, Count: 1},
+	}, TotalStmt: 4, CoveredStmt: 1}})
+
+	r := newReleaseReport("v1.0.0", "v1.1.0", oldCov, newCov, func(string) ([]byte, error) {
+		return []byte(oldSrc), nil
+	}, sourceTree{})
+
+	require.Len(t, r.UntestedAPIs, 1, "Load existed before and client is unexported")
+	assert.Equal(t, "svc.Store", r.UntestedAPIs[0].Name)
+	assert.Equal(t, 7, r.UntestedAPIs[0].Line)
+
+	require.Len(t, r.Packages, 2)
+	assert.True(t, r.Packages[1].Removed)
+	assert.Equal(t, StmtCount{Total: 4, Covered: 1}, r.Packages[0].New)
+
+	md := r.Markdown()
+	assert.Contains(t, md, "The overall coverage is **25.00%** (v1.0.0: 66.67%, -41.67%).")
+	assert.Contains(t, md, "| example.com/gone | 100.00% | - | removed | -2 |")
+	assert.Contains(t, md, "- `svc.Store` ("+fileName+":7)")
+
+	// Without the old source code, new APIs of existing files are unknown.
+	r = newReleaseReport("v1.0.0", "v1.1.0", oldCov, newCov, func(string) ([]byte, error) {
+		return nil, errors.New("not found")
+	}, sourceTree{})
+	assert.Empty(t, r.UntestedAPIs)
+	assert.Equal(t, []string{fileName}, r.UnknownFiles)
+	assert.Contains(t, r.Markdown(), "The source code of 1 files was not available")
+}
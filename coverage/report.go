@@ -0,0 +1,1113 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Report struct {
+	Old, New        *Coverage
+	ChangedFiles    []string
+	ChangedPackages []string
+	MinCoverage     float64   // Minimum coverage threshold for new code (0 to disable)
+	DiffInfo        *DiffInfo // Optional: git diff information for line-level coverage
+	Config          *Config   `json:"-"`          // Optional: settings loaded from the -config file
+	RootPackage     string    `json:"-"`          // Optional: import path of the repository root
+	HTMLTheme       string    `json:"-"`          // Optional: color theme of the HTML report (auto, light or dark)
+	Theme           string    `json:"-"`          // Optional: emojis and tone of the Markdown report (see reportThemes)
+	Layout          string    `json:"-"`          // Optional: structure of the Markdown report (flat, drilldown or auto)
+	BaseRef         string    `json:"-"`          // Optional: git revision of the old coverage, used to read the old source code
+	Commit          string    `json:"-"`          // Optional: commit of the new coverage, recorded in the audit exports (see CSV and PDF)
+	Sample          *Sample   `json:",omitempty"` // Optional: set if the coverage was estimated from a sample of files
+
+	// PackageCoverage is the optional coverage of the same tests recorded
+	// without -coverpkg. It is used to detect new code that is only covered by
+	// tests of other packages.
+	PackageCoverage        *Coverage `json:"-"`
+	RequirePackageCoverage bool      `json:"-"` // Optional: treat new code that is only covered by tests of other packages as uncovered
+
+	// TestFileCoverage is the optional coverage of only the tests of single
+	// test files by the name of the test file. It is used to show how many
+	// statements the tests of each changed test file cover uniquely.
+	TestFileCoverage map[string]*Coverage `json:"-"`
+
+	// ExcludeDeprecated leaves the new code of deprecated functions out of the
+	// coverage of new code and therefore out of its thresholds.
+	ExcludeDeprecated bool `json:"-"`
+
+	// Analysis is the new code analysis of each changed file. It is only set
+	// by JSON so a later run can reuse it via Previous.
+	Analysis map[string]FileAnalysis `json:",omitempty"`
+	Previous map[string]FileAnalysis `json:"-"` // Optional: analysis of a previous run on the same PR
+
+	SkippedTests []SkippedTest   `json:"-"` // Optional: skipped tests from the output of "go test -json"
+	TestTimings  []PackageTiming `json:"-"` // Optional: test duration of each package from the output of "go test -json"
+
+	FailedPackages   []string `json:"-"` // Optional: packages whose tests failed according to the output of "go test -json"
+	UntestedPackages []string `json:"-"` // Optional: packages without test files according to the output of "go test -json"
+
+	Neutral        bool    `json:"-"` // Optional: the PR must not change the coverage (e.g. a mechanical refactoring)
+	NeutralEpsilon float64 `json:"-"` // Maximum change of a package coverage in percentage points if Neutral is set
+
+	Graded bool   `json:"-"`          // Optional: show a composite grade in the title (see ComputeGrade)
+	Grade  *Grade `json:",omitempty"` // Only set by JSON if Graded is true
+
+	TestGapPriorities    bool `json:"-"` // Optional: rank the changed files by where tests are needed most (see TestGaps)
+	ReviewEffortEstimate bool `json:"-"` // Optional: estimate the review effort in the summary (see ReviewEffort)
+
+	Summary *ReportSummary `json:",omitempty"` // Only set by JSON, used by the pull request dashboard of the site
+
+	Reproduction *Reproduction `json:",omitempty"` // Optional: how to re-run the report with the same inputs (see -format=json)
+
+	Commits []CommitCoverage `json:",omitempty"` // Optional: coverage of the new lines of each commit (see -per-commit)
+	Shards  []Shard          `json:",omitempty"` // Optional: the runs of a CI matrix that New was merged from
+
+	Numbers *NumberFormat `json:"-"` // Optional: precision and separators of numbers (see defaultNumberFormat)
+
+	// NotBuilt are the changed files without coverage because their build
+	// constraints exclude them on the platform of the tests.
+	NotBuilt []NotBuiltFile `json:",omitempty"`
+
+	ZeroCoverage map[string]string `json:",omitempty"` // Only set by JSON, see ZeroCoverageReasons
+
+	astMapper *StatementLineMapper
+	astCache  map[string]map[int]bool // Cache of file -> statement lines
+
+	oldSourceLines  func(fileName string) (map[int]string, error) // reads the old source code (see BaseRef)
+	oldSourceCache  map[string]map[int]string                     // Cache of file -> old source lines
+	fingerprints    map[string]string                             // Cache of file -> fingerprint of its analysis
+	externalCache   map[string]map[blockPosition]bool             // Cache of file -> blocks only covered by tests of other packages
+	deprecatedCache map[string][]funcExtent                       // Cache of file -> deprecated functions
+
+	source sourceTree // locates the source code of the files
+}
+
+func NewReport(oldCov, newCov *Coverage, changedFiles []string) *Report {
+	sort.Strings(changedFiles)
+	return &Report{
+		Old:             oldCov,
+		astMapper:       NewStatementLineMapper(),
+		astCache:        make(map[string]map[int]bool),
+		New:             newCov,
+		ChangedFiles:    changedFiles,
+		ChangedPackages: changedPackages(changedFiles),
+	}
+}
+
+// Close removes the source files that were fetched for the report (see
+// -fetch-source). The report must not be rendered afterwards.
+func (r *Report) Close() error {
+	return r.source.removeFetched()
+}
+
+func changedPackages(changedFiles []string) []string {
+	packages := map[string]bool{}
+	for _, file := range changedFiles {
+		pkg := filepath.Dir(file)
+		packages[pkg] = true
+	}
+
+	result := make([]string, 0, len(packages))
+	for pkg := range packages {
+		result = append(result, pkg)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// OverallCoverageDelta returns the difference between new and old overall coverage
+func (r *Report) OverallCoverageDelta() float64 {
+	return r.New.Percent() - r.Old.Percent()
+}
+
+// OverallCoverageInfo returns formatted strings for old, new coverage percentages and delta
+func (r *Report) OverallCoverageInfo() (oldCov, newCov, deltaStr string, emoji string) {
+	oldPercent := r.Old.Percent()
+	newPercent := r.New.Percent()
+
+	oldCov = r.numbers().Percent(oldPercent)
+	newCov = r.numbers().Percent(newPercent)
+
+	emoji, deltaStr = r.emojiScore(newPercent, oldPercent)
+
+	return oldCov, newCov, deltaStr, emoji
+}
+
+// PRCoverageInfo returns coverage information for newly added code in this PR
+func (r *Result) PRCoverageInfo() (prCov string, emoji string, totalNew, coveredNew int64) {
+	totalNew, coveredNew = r.calculateNewCodeCoverage()
+
+	var prPercent float64
+	if totalNew > 0 {
+		prPercent = float64(coveredNew) / float64(totalNew) * 100
+	}
+
+	prCov = r.numbers().Percent(prPercent)
+
+	return prCov, r.theme().newCodeStatus(prPercent), totalNew, coveredNew
+}
+
+// NewCodeBlock represents a block of new code with coverage information
+type NewCodeBlock struct {
+	FileName  string
+	StartLine int
+	StartCol  int `json:",omitempty"`
+	EndLine   int
+	EndCol    int `json:",omitempty"`
+	NumStmt   int
+	Covered   bool
+	Count     int      // Execution count of the underlying coverage block
+	External  bool     // True if the block is only covered by tests of other packages
+	Lines     []string // Source code of the changed lines (see ProfileBlock.LineSpan)
+}
+
+// profileBlock returns the position of the block in the coverage profile.
+func (b NewCodeBlock) profileBlock() ProfileBlock {
+	return ProfileBlock{StartLine: b.StartLine, StartCol: b.StartCol, EndLine: b.EndLine, EndCol: b.EndCol, NumStmt: b.NumStmt, Count: b.Count}
+}
+
+// calculateNewCodeCoverage returns the coverage of the statements that are
+// new in this PR.
+func (r *Result) calculateNewCodeCoverage() (totalNew, coveredNew int64) {
+	return r.NewStmt, r.CoveredNewStmt
+}
+
+// newCodeCoverageOf returns the coverage of the statements of a single file
+// that are new in this PR.
+func (r *Result) newCodeCoverageOf(fileName string) (totalNew, coveredNew int64) {
+	a := r.Files[fileName]
+	return a.TotalNew, a.CoveredNew
+}
+
+// fileNewCodeCoverage calculates coverage for statements of a single file
+// that are new in this PR.
+func (r *Report) fileNewCodeCoverage(fileName string) (totalNew, coveredNew int64) {
+	// If we have diff information, use it for accurate line-level coverage
+	if r.DiffInfo != nil {
+		return r.fileNewCodeCoverageFromDiff(fileName)
+	}
+
+	// Fallback to block-based comparison (old behavior)
+	oldProfile := r.Old.Files[fileName]
+	newProfile := r.newProfile(fileName)
+
+	if newProfile == nil {
+		return 0, 0 // File was deleted or no coverage data
+	}
+
+	if oldProfile == nil {
+		// Entire file is new
+		return newProfile.TotalStmt, newProfile.CoveredStmt
+	}
+
+	// Compare blocks to find new code
+	for _, newBlock := range r.newBlocks(fileName, oldProfile, newProfile) {
+		totalNew += int64(newBlock.NumStmt)
+		if newBlock.Count > 0 {
+			coveredNew += int64(newBlock.NumStmt)
+		}
+	}
+
+	return totalNew, coveredNew
+}
+
+// scanLines reads the lines of a source file from r and returns them by line
+// number. Lines longer than the maximum line length are truncated.
+func (t sourceTree) scanLines(r io.Reader) (map[int]string, error) {
+	lines := make(map[int]string)
+	scanner := newLineReader(r, t.maxLineLength)
+	lineNum := 1
+	for scanner.Scan() {
+		lines[lineNum] = scanner.Text()
+		if scanner.Truncated() {
+			// The cut might have split a multi-byte character.
+			lines[lineNum] = strings.ToValidUTF8(lines[lineNum], "") + truncationMarker
+		}
+		lineNum++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// getNewCodeBlocks returns detailed information about all new code blocks
+func (r *Result) getNewCodeBlocks() []NewCodeBlock {
+	var blocks []NewCodeBlock
+	for _, fileName := range r.ChangedFiles {
+		blocks = append(blocks, r.Files[fileName].Blocks...)
+	}
+
+	return blocks
+}
+
+// fileNewCodeBlocks returns detailed information about the new code blocks
+// of a single file including their source code.
+func (r *Report) fileNewCodeBlocks(fileName string) []NewCodeBlock {
+	var blocks []NewCodeBlock
+
+	// If we have diff information, use it for accurate line-level coverage
+	if r.DiffInfo != nil {
+		blocks = r.fileNewCodeBlocksFromDiff(fileName)
+	} else {
+		blocks = r.fileNewCodeBlocksFromComparison(fileName)
+	}
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	// Try to populate actual source code lines for each block
+	sourceLines, err := r.source.readLines(fileName)
+	if err != nil {
+		// If we can't read the file, just skip adding source lines
+		// This can happen if the file path doesn't exist locally
+		return blocks
+	}
+
+	// Only include lines that were actually added/modified according to the diff
+	// This prevents showing unchanged lines that happen to be in the same coverage block
+	var fileDiff *FileDiff
+	if r.DiffInfo != nil {
+		fileDiff = r.DiffInfo.findFileDiff(fileName)
+	}
+
+	for i := range blocks {
+		block := &blocks[i]
+
+		// Stop at the end of the file in case the profile does not match the source
+		endLine := min(block.EndLine, len(sourceLines))
+		for lineNum := block.StartLine; lineNum <= endLine; lineNum++ {
+			// Only add lines that were actually changed
+			if fileDiff != nil && !fileDiff.AddedLines[lineNum] && !fileDiff.ModifiedLines[lineNum] {
+				continue
+			}
+
+			if line, exists := sourceLines[lineNum]; exists {
+				block.Lines = append(block.Lines, block.profileBlock().LineSpan(lineNum, line))
+			}
+		}
+	}
+
+	return blocks
+}
+
+// fileNewCodeBlocksFromComparison gets the new code blocks of a single file
+// by comparing old and new profiles.
+func (r *Report) fileNewCodeBlocksFromComparison(fileName string) []NewCodeBlock {
+	oldProfile := r.Old.Files[fileName]
+	newProfile := r.newProfile(fileName)
+
+	if newProfile == nil {
+		return nil // File was deleted or no coverage data
+	}
+
+	if oldProfile == nil {
+		// Entire file is new
+		return r.newCodeBlocks(fileName, newProfile.Blocks)
+	}
+
+	// Compare blocks to find new code
+	return r.newCodeBlocks(fileName, r.newBlocks(fileName, oldProfile, newProfile))
+}
+
+// fileNewCodeBlocksFromDiff gets the new code blocks of a single file using
+// git diff information.
+func (r *Report) fileNewCodeBlocksFromDiff(fileName string) []NewCodeBlock {
+	oldProfile := r.Old.Files[fileName]
+	newProfile := r.newProfile(fileName)
+
+	if newProfile == nil {
+		return nil // File was deleted or no coverage data
+	}
+
+	// If file is entirely new (not in old coverage), count all blocks
+	if oldProfile == nil {
+		return r.newCodeBlocks(fileName, newProfile.Blocks)
+	}
+
+	// Check if we have diff info for this file
+	fileDiff := r.DiffInfo.findFileDiff(fileName)
+	if fileDiff == nil || len(fileDiff.AddedLines) == 0 {
+		// No diff info for this file, fall back to counting all blocks as new
+		return r.newCodeBlocks(fileName, newProfile.Blocks)
+	}
+
+	// Check each block in the new coverage
+	var blocks []ProfileBlock
+	for _, block := range newProfile.Blocks {
+		// Check if this block contains any lines that were added/modified
+		if r.DiffInfo.IsLineInRange(fileName, block.StartLine, block.EndLine) {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return r.newCodeBlocks(fileName, blocks)
+}
+
+// newCodeBlocks converts the given profile blocks of a file to NewCodeBlocks.
+func (r *Report) newCodeBlocks(fileName string, blocks []ProfileBlock) []NewCodeBlock {
+	external := r.externalBlocks(fileName)
+
+	var result []NewCodeBlock
+	for _, block := range blocks {
+		result = append(result, NewCodeBlock{
+			FileName:  fileName,
+			StartLine: block.StartLine,
+			StartCol:  block.StartCol,
+			EndLine:   block.EndLine,
+			EndCol:    block.EndCol,
+			NumStmt:   block.NumStmt,
+			Covered:   block.Count > 0,
+			Count:     block.Count,
+			External:  external[positionOf(block)],
+		})
+	}
+
+	return result
+}
+
+// calculateNewCodeCoverageFromDiff calculates coverage using git diff information
+// This is more accurate as it only considers lines that were actually added/modified
+func (r *Report) calculateNewCodeCoverageFromDiff() (totalNew, coveredNew int64) {
+	for _, fileName := range r.ChangedFiles {
+		total, covered := r.fileNewCodeCoverageFromDiff(fileName)
+		totalNew += total
+		coveredNew += covered
+	}
+
+	return totalNew, coveredNew
+}
+
+// fileNewCodeCoverageFromDiff calculates the coverage of a single file using
+// git diff information.
+//
+// Note: Go coverage works at the block/statement level, not line level. A coverage block
+// may span multiple lines, and we can't know which specific lines contain which statements.
+// When a block contains both changed and unchanged lines, we estimate the number of changed
+// statements based on the proportion of changed lines in that block.
+func (r *Report) fileNewCodeCoverageFromDiff(fileName string) (totalNew, coveredNew int64) {
+	oldProfile := r.Old.Files[fileName]
+	newProfile := r.newProfile(fileName)
+
+	if newProfile == nil {
+		return 0, 0 // File was deleted or no coverage data
+	}
+
+	// If file is entirely new (not in old coverage), count all statements
+	if oldProfile == nil {
+		return newProfile.TotalStmt, newProfile.CoveredStmt
+	}
+
+	// Check if we have diff info for this file
+	fileDiff := r.DiffInfo.findFileDiff(fileName)
+	if fileDiff == nil || len(fileDiff.AddedLines) == 0 {
+		// No diff info for this file, fall back to counting all blocks as new
+		// This handles the case where diff wasn't generated for this file
+		return newProfile.TotalStmt, newProfile.CoveredStmt
+	}
+
+	// Check each block in the new coverage
+	for _, block := range newProfile.Blocks {
+		// Try AST-based counting first (more accurate)
+		stmtCount, covered := r.countStatementsInBlockUsingAST(fileName, block, fileDiff)
+
+		if stmtCount >= 0 {
+			// AST-based counting succeeded
+			totalNew += int64(stmtCount)
+			if covered {
+				coveredNew += int64(stmtCount)
+			}
+			continue
+		}
+
+		// Fallback to proportional estimation if AST parsing fails
+		changedLinesInBlock := len(fileDiff.changedLinesInRange(block.StartLine, block.EndLine))
+		totalLinesInBlock := block.EndLine - block.StartLine + 1
+
+		// Only count this block if at least one line was changed
+		// Estimate the number of statements that were changed based on the proportion of changed lines
+		if changedLinesInBlock > 0 {
+			// Calculate the proportion of lines that were changed
+			proportion := float64(changedLinesInBlock) / float64(totalLinesInBlock)
+
+			// Estimate the number of statements that were actually new/changed
+			// Round up to ensure we count at least 1 statement if any line changed
+			estimatedStmts := int64(float64(block.NumStmt) * proportion)
+			if estimatedStmts == 0 && changedLinesInBlock > 0 {
+				estimatedStmts = 1
+			}
+
+			totalNew += estimatedStmts
+			if block.Count > 0 {
+				coveredNew += estimatedStmts
+			}
+		}
+	}
+
+	return totalNew, coveredNew
+}
+
+func (r *Result) Title() string {
+	title := r.coverageTitle()
+	if r.Grade != nil {
+		title += fmt.Sprintf(" - Grade **%s**", r.Grade.Letter)
+	}
+
+	return title
+}
+
+func (r *Report) coverageTitle() string {
+	// Use overall coverage delta to determine increase/decrease
+	overallDelta := r.OverallCoverageDelta()
+	_, newCov, deltaStr, _ := r.OverallCoverageInfo()
+	theme := r.theme()
+
+	switch {
+	case overallDelta == 0:
+		return fmt.Sprintf("### Coverage Report - %s (%s)", newCov, theme.noChange)
+	case overallDelta > 0:
+		return fmt.Sprintf("### Coverage Report - %s (%s) - %s", newCov, deltaStr, theme.increase)
+	case overallDelta < 0:
+		return fmt.Sprintf("### Coverage Report - %s (%s) - %s", newCov, deltaStr, theme.decrease)
+	default:
+		// This should never happen, but just in case
+		return fmt.Sprintf("### Coverage Report - %s (%s)", newCov, deltaStr)
+	}
+}
+
+// reportMarker is a hidden comment at the end of every Markdown report. It
+// identifies the comments of previous runs independent of the layout.
+const reportMarker = "<!-- go-coverage-report -->"
+
+func (r *Result) Markdown() string {
+	report := new(strings.Builder)
+
+	fmt.Fprintln(report, r.Title())
+	r.addIdenticalProfilesWarning(report)
+	r.addOverallCoverageSummary(report)
+	if r.drilldown() {
+		r.addDrilldown(report)
+		r.addShardDetails(report)
+	} else {
+		r.addPackageDetails(report)
+		r.addFileDetails(report)
+		r.addShardDetails(report)
+		r.addNewCodeDetailsSection(report)
+	}
+	r.addCommitDetails(report)
+	r.addLineCoverageChanges(report)
+	r.addTestGapDetails(report)
+	r.addSkippedTestsDetails(report)
+	r.addShardingAdvice(report)
+	r.addCoverageTargets(report)
+	r.addDeprecatedDetails(report)
+	r.addExclusionDetails(report)
+	fmt.Fprintln(report, reportMarker)
+
+	return report.String()
+}
+
+func (r *Result) addOverallCoverageSummary(report *strings.Builder) {
+	oldCov, newCov, deltaStr, emoji := r.OverallCoverageInfo()
+	prCov, prEmoji, totalNew, coveredNew := r.PRCoverageInfo()
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "#### Overall Coverage Summary")
+	fmt.Fprintln(report)
+	fmt.Fprintf(report, "| Metric | Old Coverage | New Coverage | Change | %s |\n", r.theme().statusHeader)
+	fmt.Fprintln(report, "|--------|-------------|-------------|--------|---------|")
+	fmt.Fprintf(report, "| **Total** | %s | %s | %s | %s |\n", oldCov, newCov, deltaStr, emoji)
+
+	// Add PR-specific coverage if there's new code
+	if totalNew > 0 {
+		fmt.Fprintf(report, "| **New Code** | N/A | %s | %s/%s statements | %s |\n", prCov, r.numbers().Count(coveredNew), r.numbers().Count(totalNew), prEmoji)
+	}
+
+	fmt.Fprintln(report)
+
+	r.addGradeDetails(report)
+
+	if r.Sample != nil {
+		fmt.Fprintln(report, "> [!NOTE]")
+		fmt.Fprintln(report, "> "+r.Sample.note())
+		fmt.Fprintln(report)
+	}
+
+	r.addExternalCoverageNote(report)
+
+	// Add threshold warning if enabled and not met this will make the CI Step fail
+	if r.MinCoverage > 0 && totalNew > 0 {
+		newCodeCoverage := float64(coveredNew) / float64(totalNew) * 100
+		if newCodeCoverage < r.MinCoverage {
+			fmt.Fprintln(report, "> [!WARNING]")
+			fmt.Fprintf(report, "> **Coverage threshold not met:** New code coverage is **%s**, which is below the required threshold of **%s**.\n", r.numbers().Percent(newCodeCoverage), r.numbers().Percent(r.MinCoverage))
+			fmt.Fprintln(report)
+		}
+	}
+
+	r.addGateWarning(report)
+	r.addIneffectiveTestsWarning(report)
+	r.addNeutralityDetails(report)
+
+	// Add statements summary
+	oldStmt := r.Old.TotalStmt
+	newStmt := r.New.TotalStmt
+	oldCovered := r.Old.CoveredStmt
+	newCovered := r.New.CoveredStmt
+
+	stmtChange := newStmt - oldStmt
+	coveredChange := newCovered - oldCovered
+
+	count := r.numbers().Count
+
+	stmtChangeStr := ""
+	if stmtChange > 0 {
+		stmtChangeStr = fmt.Sprintf(" (+%s)", count(stmtChange))
+	} else if stmtChange < 0 {
+		stmtChangeStr = fmt.Sprintf(" (%s)", count(stmtChange))
+	}
+
+	coveredChangeStr := ""
+	if coveredChange > 0 {
+		coveredChangeStr = fmt.Sprintf(" (+%s)", count(coveredChange))
+	} else if coveredChange < 0 {
+		coveredChangeStr = fmt.Sprintf(" (%s)", count(coveredChange))
+	}
+
+	fmt.Fprintln(report, "| **Statements** | Total | Covered | Missed |")
+	fmt.Fprintln(report, "|---|---|---|---|")
+	fmt.Fprintf(report, "| **Old** | %s | %s | %s |\n", count(oldStmt), count(oldCovered), count(r.Old.MissedStmt))
+	fmt.Fprintf(report, "| **New** | %s%s | %s%s | %s |\n", count(newStmt), stmtChangeStr, count(newCovered), coveredChangeStr, count(r.New.MissedStmt))
+	fmt.Fprintln(report)
+
+	r.addReviewEffort(report)
+}
+
+// addNewCodeDetailsSection adds the new code coverage details section at the end of the report
+func (r *Result) addNewCodeDetailsSection(report *strings.Builder) {
+	// Check if there's new code to report
+	totalNew, _ := r.calculateNewCodeCoverage()
+	if totalNew == 0 {
+		return
+	}
+
+	r.addNewCodeDetails(report)
+}
+
+// addNewCodeDetails adds a detailed breakdown of new code coverage
+func (r *Result) addNewCodeDetails(report *strings.Builder) {
+	blocks := r.getNewCodeBlocks()
+	if len(blocks) == 0 {
+		return
+	}
+
+	// Group blocks by file
+	fileBlocks := make(map[string][]NewCodeBlock)
+	for _, block := range blocks {
+		fileBlocks[block.FileName] = append(fileBlocks[block.FileName], block)
+	}
+
+	// Sort files for consistent output
+	var sortedFiles []string
+	for fileName := range fileBlocks {
+		sortedFiles = append(sortedFiles, fileName)
+	}
+	sort.Strings(sortedFiles)
+
+	fmt.Fprintln(report, r.detailsTag(foldNewCode, r.belowMinCoverage))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>New Code Coverage Details</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "This section shows the coverage status of each new code block added in this PR.")
+	fmt.Fprintln(report)
+
+	for _, fileName := range sortedFiles {
+		fmt.Fprintf(report, "#### %s\n", fileName)
+		fmt.Fprintln(report)
+
+		// Read source file to get actual line content
+		sourceLines, _ := r.source.readLines(fileName)
+		r.writeNewCodeDiff(report, fileName, sourceLines, fileBlocks[fileName])
+	}
+
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+// writeNewCodeDiff writes the new code of the given blocks of a file as a diff
+// code block in which covered lines are added and uncovered lines are removed.
+// If the source code could not be read, the line ranges of the blocks are
+// listed instead.
+func (r *Report) writeNewCodeDiff(report *strings.Builder, fileName string, sourceLines map[int]string, blocks []NewCodeBlock) {
+	fmt.Fprintln(report, "```diff")
+
+	if sourceLines == nil {
+		// Fallback to block-based display if we can't read the source
+		type blockRange struct{ start, end int }
+		printed := make(map[blockRange]bool)
+		for _, block := range blocks {
+			// Blocks that are split by columns (e.g. a one-line if
+			// statement) would print the same line range twice.
+			key := blockRange{block.StartLine, block.EndLine}
+			if printed[key] {
+				continue
+			}
+			printed[key] = true
+
+			lineRange := fmt.Sprintf("Lines %d-%d", block.StartLine, block.EndLine)
+			if block.StartLine == block.EndLine {
+				lineRange = fmt.Sprintf("Line %d", block.StartLine)
+			}
+
+			stmtText := "statement"
+			if block.NumStmt != 1 {
+				stmtText = "statements"
+			}
+
+			if block.Covered {
+				fmt.Fprintf(report, "+ %s (%d %s) - COVERED ✓\n", lineRange, block.NumStmt, stmtText)
+			} else {
+				fmt.Fprintf(report, "- %s (%d %s) - NOT COVERED ✗\n", lineRange, block.NumStmt, stmtText)
+			}
+		}
+	} else {
+		lineCoverage := r.newLineCoverage(fileName, sourceLines, blocks)
+
+		// Output lines in order
+		var lineNumbers []int
+		for lineNum := range lineCoverage {
+			lineNumbers = append(lineNumbers, lineNum)
+		}
+		sort.Ints(lineNumbers)
+
+		for _, lineNum := range lineNumbers {
+			if lineContent, exists := sourceLines[lineNum]; exists {
+				prefix := "+"
+				if !lineCoverage[lineNum] {
+					prefix = "-"
+				}
+				fmt.Fprintf(report, "%s %s\n", prefix, lineContent)
+			}
+		}
+	}
+
+	fmt.Fprintln(report, "```")
+	fmt.Fprintln(report)
+}
+
+// newLineCoverage returns the coverage status of each new line of the given
+// file. The blocks are merged per line before anything is printed: a line is
+// covered only if all blocks with code on that line are covered, so that e.g.
+// a one-line if statement whose body never ran is reported as uncovered.
+// Blocks that only contribute braces to a line (e.g. the opening brace of a
+// function body) are only considered if no block has code on that line. If
+// diff information is available, only lines that were actually changed are
+// included.
+func (r *Report) newLineCoverage(fileName string, sourceLines map[int]string, blocks []NewCodeBlock) map[int]bool {
+	// Get the set of changed lines from diff
+	var changedLines map[int]bool
+	if r.DiffInfo != nil {
+		fileDiff := r.DiffInfo.findFileDiff(fileName)
+		if fileDiff != nil {
+			changedLines = make(map[int]bool)
+			for line := range fileDiff.AddedLines {
+				changedLines[line] = true
+			}
+			for line := range fileDiff.ModifiedLines {
+				changedLines[line] = true
+			}
+		}
+	}
+
+	codeCoverage := make(map[int]bool)  // lines with code: covered if all blocks are covered
+	braceCoverage := make(map[int]bool) // lines with braces only: covered if any block is covered
+	for _, block := range blocks {
+		for lineNum := block.StartLine; lineNum <= block.EndLine; lineNum++ {
+			// Only consider lines that were actually changed
+			if changedLines != nil && !changedLines[lineNum] {
+				continue
+			}
+
+			if !blockHasCodeOnLine(block, lineNum, sourceLines) {
+				braceCoverage[lineNum] = braceCoverage[lineNum] || block.Covered
+				continue
+			}
+
+			covered, seen := codeCoverage[lineNum]
+			codeCoverage[lineNum] = block.Covered && (covered || !seen)
+		}
+	}
+
+	for lineNum, covered := range braceCoverage {
+		if _, ok := codeCoverage[lineNum]; !ok {
+			codeCoverage[lineNum] = covered
+		}
+	}
+
+	return codeCoverage
+}
+
+// blockHasCodeOnLine returns whether the part of the block on the given line
+// contains more than whitespace and braces. Without source code, every line
+// of the block counts.
+func blockHasCodeOnLine(block NewCodeBlock, lineNum int, sourceLines map[int]string) bool {
+	line, ok := sourceLines[lineNum]
+	if !ok {
+		return true
+	}
+
+	return strings.Trim(block.profileBlock().LineSpan(lineNum, line), " \t{}") != ""
+}
+
+// addExclusionDetails lists all code regions that have been excluded from the
+// coverage calculation so the numbers above remain transparent.
+func (r *Report) addExclusionDetails(report *strings.Builder) {
+	if len(r.New.Exclusions) == 0 {
+		return
+	}
+
+	exclusions := make([]Exclusion, len(r.New.Exclusions))
+	copy(exclusions, r.New.Exclusions)
+	sort.Slice(exclusions, func(i, j int) bool {
+		if exclusions[i].FileName != exclusions[j].FileName {
+			return exclusions[i].FileName < exclusions[j].FileName
+		}
+		return exclusions[i].StartLine < exclusions[j].StartLine
+	})
+
+	fmt.Fprintln(report, r.detailsTag(foldExcluded, func() bool { return false }))
+	fmt.Fprintln(report)
+	fmt.Fprintf(report, "<summary>Excluded Code (%s statements)</summary>\n", r.numbers().Count(r.New.ExcludedStmt()))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The following code is excluded from all coverage numbers of this report.")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| File | Lines | Statements | Reason |")
+	fmt.Fprintln(report, "|------|-------|------------|--------|")
+	for _, e := range exclusions {
+		lines := fmt.Sprintf("%d-%d", e.StartLine, e.EndLine)
+		if e.Statement {
+			lines = fmt.Sprint(e.StartLine)
+		}
+		fmt.Fprintf(report, "| %s | %s | %s | %s |\n", e.FileName, lines, r.numbers().Count(int64(e.NumStmt)), e.Reason)
+	}
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+func (r *Report) addPackageDetails(report *strings.Builder) {
+	fmt.Fprintln(report, "---")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, r.detailsTag(foldPackages, r.hasPackageRegression))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Impacted Packages</summary>")
+	fmt.Fprintln(report)
+
+	fmt.Fprintf(report, "| Impacted Packages | Coverage Δ | %s |\n", r.theme().statusHeader)
+	fmt.Fprintln(report, "|-------------------|------------|---------|")
+
+	oldCovPkgs := r.Old.ByPackage()
+	newCovPkgs := r.New.ByPackage()
+	for _, pkg := range r.ChangedPackages {
+		var oldPercent, newPercent float64
+
+		if cov, ok := oldCovPkgs[pkg]; ok {
+			oldPercent = cov.Percent()
+		}
+
+		if cov, ok := newCovPkgs[pkg]; ok {
+			newPercent = cov.Percent()
+		}
+
+		emoji, diffStr := r.emojiScore(newPercent, oldPercent)
+		fmt.Fprintf(report, "| %s | %s (%s) | %s |\n",
+			pkg,
+			r.numbers().Percent(newPercent),
+			diffStr,
+			emoji,
+		)
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
+
+func (r *Result) addFileDetails(report *strings.Builder) {
+	fmt.Fprintln(report, r.detailsTag(foldFiles, r.hasFileRegression))
+	fmt.Fprintln(report)
+
+	fmt.Fprintln(report, "<summary>Coverage by file</summary>")
+	fmt.Fprintln(report)
+
+	var codeFiles, unitTestFiles []string
+	for _, f := range r.ChangedFiles {
+		if strings.HasSuffix(f, "_test.go") {
+			unitTestFiles = append(unitTestFiles, f)
+		} else {
+			codeFiles = append(codeFiles, f)
+		}
+	}
+
+	var builtFiles []string
+	for _, f := range codeFiles {
+		if _, ok := r.notBuilt(f); !ok {
+			builtFiles = append(builtFiles, f)
+		}
+	}
+
+	if len(builtFiles) > 0 {
+		r.addCodeFileDetails(report, builtFiles)
+	}
+	r.addNotBuiltFiles(report)
+	if len(unitTestFiles) > 0 {
+		r.addTestFileDetails(report, unitTestFiles)
+	}
+
+	fmt.Fprint(report, "</details>")
+}
+
+func (r *Report) addCodeFileDetails(report *strings.Builder, files []string) {
+	// The reason column is only shown if it explains at least one file with a
+	// coverage of 0%.
+	reasons := map[string]string{}
+	for _, name := range files {
+		if reason := r.zeroCoverageReason(name); reason != "" {
+			reasons[name] = reason
+		}
+	}
+
+	fmt.Fprintln(report, "### Changed files (no unit tests)")
+	fmt.Fprintln(report)
+	if len(reasons) > 0 {
+		fmt.Fprintf(report, "| Changed File | Coverage Δ | Total | Covered | Missed | Reason | %s |\n", r.theme().statusHeader)
+		fmt.Fprintln(report, "|--------------|------------|-------|---------|--------|--------|---------|")
+	} else {
+		fmt.Fprintf(report, "| Changed File | Coverage Δ | Total | Covered | Missed | %s |\n", r.theme().statusHeader)
+		fmt.Fprintln(report, "|--------------|------------|-------|---------|--------|---------|")
+	}
+
+	for _, name := range files {
+		var oldPercent, newPercent float64
+
+		oldProfile := r.Old.Files[name]
+		newProfile := r.New.Files[name]
+
+		if oldProfile != nil {
+			oldPercent = oldProfile.CoveragePercent()
+		}
+
+		if newProfile != nil {
+			newPercent = newProfile.CoveragePercent()
+		}
+
+		emoji, diffStr := r.emojiScore(newPercent, oldPercent)
+		row := fmt.Sprintf("| %s | %s (%s) | %s | %s | %s |",
+			name,
+			r.numbers().Percent(newPercent), diffStr,
+			r.valueWithDelta(oldProfile.GetTotal(), newProfile.GetTotal()),
+			r.valueWithDelta(oldProfile.GetCovered(), newProfile.GetCovered()),
+			r.valueWithDelta(oldProfile.GetMissed(), newProfile.GetMissed()),
+		)
+		if len(reasons) > 0 {
+			row += " " + reasons[name] + " |"
+		}
+		fmt.Fprintf(report, "%s %s |\n", row, emoji)
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, `_Please note that the "Total", "Covered", and "Missed" counts `+
+		"above refer to ***code statements*** instead of lines of code. The value in brackets "+
+		"refers to the test coverage of that file in the old version of the code._")
+	fmt.Fprintln(report)
+}
+
+// valueWithDelta formats a statement count with its change since the old
+// version of the code, e.g. "24 (+9)".
+func (r *Report) valueWithDelta(oldVal, newVal int64) string {
+	count := r.numbers().Count
+	diff := oldVal - newVal
+	switch {
+	case diff < 0:
+		return fmt.Sprintf("%s (+%s)", count(newVal), count(-diff))
+	case diff > 0:
+		return fmt.Sprintf("%s (-%s)", count(newVal), count(diff))
+	default:
+		return count(newVal)
+	}
+}
+
+func (r *Result) addTestFileDetails(report *strings.Builder, files []string) {
+	fmt.Fprintln(report, "### Changed unit test files")
+	fmt.Fprintln(report)
+
+	if r.TestFileCoverage != nil {
+		r.addTestFileContributions(report, files)
+		r.addExampleDetails(report)
+		return
+	}
+
+	for _, name := range files {
+		fmt.Fprintf(report, "- %s\n", name)
+	}
+
+	fmt.Fprintln(report)
+	r.addExampleDetails(report)
+}
+
+func (r *Result) JSON() string {
+	// The analysis is included in a copy, so the report itself is unchanged.
+	report := *r.Report
+	report.Analysis = r.Files
+	report.Grade = r.Grade
+	report.Summary = &r.Summary
+	if len(r.ZeroCoverage) > 0 {
+		report.ZeroCoverage = r.ZeroCoverage
+	}
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		panic(err) // should never happen
+	}
+
+	return string(data)
+}
+
+// countStatementsInBlockUsingAST uses AST parsing to accurately count statements
+// in changed lines within a coverage block. Returns -1 if AST parsing fails.
+func (r *Report) countStatementsInBlockUsingAST(fileName string, block ProfileBlock, fileDiff *FileDiff) (count int, covered bool) {
+	// Check if AST mapper is available
+	if r.astMapper == nil {
+		return -1, false
+	}
+
+	// Get or compute statement lines for this file
+	statementLines, ok := r.astCache[fileName]
+	if !ok {
+		// Try to resolve the file path
+		paths := r.resolveFilePath(fileName)
+		var err error
+
+		for _, path := range paths {
+			statementLines, err = r.astMapper.GetStatementLines(path)
+			if err == nil {
+				r.astCache[fileName] = statementLines
+				break
+			}
+		}
+
+		if err != nil {
+			// AST parsing failed, return -1 to indicate fallback needed
+			return -1, false
+		}
+	}
+
+	// Count statements on changed lines within this block
+	count = 0
+	waived, waivedLines := 0, r.New.waivedLines(fileName)
+	for _, line := range fileDiff.changedLinesInRange(block.StartLine, block.EndLine) {
+		// Check if this changed line contains a statement
+		switch {
+		case waivedLines[line]:
+			waived++
+		case statementLines[line]:
+			count++
+		}
+	}
+
+	// If no statements found on changed lines, return -1 to use fallback
+	if count == 0 && waived == 0 {
+		return -1, false
+	}
+
+	covered = block.Count > 0
+	return count, covered
+}
+
+// resolveFilePath tries multiple paths to locate the source file
+func (r *Report) resolveFilePath(fileName string) []string {
+	if path, ok := r.source.find(fileName); ok {
+		return []string{path}
+	}
+
+	return nil
+}
+
+// sourcePathCandidates returns the paths at which the source of the given
+// file of a coverage profile may be found, in order of preference.
+func sourcePathCandidates(fileName string) []string {
+	paths := []string{fileName}
+
+	// Try stripping package path prefixes
+	parts := strings.Split(fileName, "/")
+	for i := range parts {
+		if i > 0 {
+			relativePath := filepath.Join(parts[i:]...)
+			paths = append(paths, relativePath)
+		}
+	}
+
+	// Try testdata directory
+	paths = append(paths, filepath.Join("testdata", fileName))
+
+	return paths
+}
+
+func (r *Report) TrimPrefix(prefix string) {
+	for i, name := range r.ChangedPackages {
+		r.ChangedPackages[i] = trimPrefix(name, prefix)
+	}
+	for i, name := range r.ChangedFiles {
+		r.ChangedFiles[i] = trimPrefix(name, prefix)
+	}
+
+	r.Old.TrimPrefix(prefix)
+	r.New.TrimPrefix(prefix)
+	if r.PackageCoverage != nil {
+		r.PackageCoverage.TrimPrefix(prefix)
+	}
+	if r.TestFileCoverage != nil {
+		trimmed := make(map[string]*Coverage, len(r.TestFileCoverage))
+		for testFile, cov := range r.TestFileCoverage {
+			cov.TrimPrefix(prefix)
+			trimmed[trimPrefix(testFile, prefix)] = cov
+		}
+		r.TestFileCoverage = trimmed
+	}
+	for _, shard := range r.Shards {
+		shard.Coverage.TrimPrefix(prefix)
+	}
+	for i, f := range r.NotBuilt {
+		r.NotBuilt[i].FileName = trimPrefix(f.FileName, prefix)
+	}
+	for i, t := range r.SkippedTests {
+		r.SkippedTests[i].Package = trimPrefix(t.Package, prefix)
+	}
+	for i, t := range r.TestTimings {
+		r.TestTimings[i].Package = trimPrefix(t.Package, prefix)
+	}
+	for i, pkg := range r.FailedPackages {
+		r.FailedPackages[i] = trimPrefix(pkg, prefix)
+	}
+	for i, pkg := range r.UntestedPackages {
+		r.UntestedPackages[i] = trimPrefix(pkg, prefix)
+	}
+}
+
+func trimPrefix(name, prefix string) string {
+	trimmed := strings.TrimPrefix(name, prefix)
+	trimmed = strings.TrimPrefix(trimmed, "/")
+	if trimmed == "" {
+		trimmed = "."
+	}
+
+	return trimmed
+}
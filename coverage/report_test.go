@@ -1,4 +1,4 @@
-package main
+package coverage
 
 import (
 	"testing"
@@ -18,95 +18,9 @@ func TestReport_Markdown(t *testing.T) {
 	require.NoError(t, err)
 
 	report := NewReport(oldCov, newCov, changedFiles)
-	actual := report.Markdown()
+	actual := report.Analyze().Markdown()
 
-	expected := `### Coverage Report - 90.20% (**-9.80%**) - **decrease**
-
-#### Overall Coverage Summary
-
-| Metric | Old Coverage | New Coverage | Change | :robot: |
-|--------|-------------|-------------|--------|---------|
-| **Total** | 100.00% | 90.20% | **-9.80%** | :thumbsdown: |
-| **New Code** | N/A | 85.71% | 42/49 statements | :tada: |
-
-| **Statements** | Total | Covered | Missed |
-|---|---|---|---|
-| **Old** | 100 | 100 | 0 |
-| **New** | 102 (+2) | 92 (-8) | 10 |
-
----
-
-<details>
-
-<summary>Impacted Packages</summary>
-
-| Impacted Packages | Coverage Δ | :robot: |
-|-------------------|------------|---------|
-| github.com/fgrosse/prioqueue | 90.20% (**-9.80%**) | :thumbsdown: |
-| github.com/fgrosse/prioqueue/foo/bar | 0.00% (ø) |  |
-
-</details>
-
-<details>
-
-<summary>Coverage by file</summary>
-
-### Changed files (no unit tests)
-
-| Changed File | Coverage Δ | Total | Covered | Missed | :robot: |
-|--------------|------------|-------|---------|--------|---------|
-| github.com/fgrosse/prioqueue/foo/bar/baz.go | 0.00% (ø) | 0 | 0 | 0 |  |
-| github.com/fgrosse/prioqueue/min_heap.go | 80.77% (**-19.23%**) | 52 (+2) | 42 (-8) | 10 (+10) | :skull:  |
-
-_Please note that the "Total", "Covered", and "Missed" counts above refer to ***code statements*** instead of lines of code. The value in brackets refers to the test coverage of that file in the old version of the code._
-
-</details><details>
-
-<summary>New Code Coverage Details</summary>
-
-This section shows the coverage status of each new code block added in this PR.
-
-#### github.com/fgrosse/prioqueue/min_heap.go
-
-` + "```diff" + `
-- Line 48 (1 statement) - NOT COVERED ✗
-- Lines 48-50 (1 statement) - NOT COVERED ✗
-- Line 52 (1 statement) - NOT COVERED ✗
-+ Lines 57-59 (2 statements) - COVERED ✓
-- Lines 59-61 (1 statement) - NOT COVERED ✗
-+ Line 63 (1 statement) - COVERED ✓
-+ Lines 68-69 (1 statement) - COVERED ✓
-- Lines 69-71 (1 statement) - NOT COVERED ✗
-+ Line 72 (1 statement) - COVERED ✓
-+ Lines 76-78 (1 statement) - COVERED ✓
-+ Lines 84-86 (1 statement) - COVERED ✓
-+ Lines 91-93 (1 statement) - COVERED ✓
-+ Lines 98-101 (2 statements) - COVERED ✓
-+ Lines 104-107 (2 statements) - COVERED ✓
-+ Lines 110-116 (3 statements) - COVERED ✓
-+ Lines 116-118 (2 statements) - COVERED ✓
-+ Lines 118-121 (1 statement) - COVERED ✓
-+ Lines 123-124 (2 statements) - COVERED ✓
-+ Lines 135-137 (2 statements) - COVERED ✓
-- Lines 137-139 (1 statement) - NOT COVERED ✗
-+ Line 141 (1 statement) - COVERED ✓
-+ Lines 145-146 (1 statement) - COVERED ✓
-- Lines 146-148 (1 statement) - NOT COVERED ✗
-+ Lines 150-160 (6 statements) - COVERED ✓
-+ Lines 165-168 (3 statements) - COVERED ✓
-+ Lines 168-171 (2 statements) - COVERED ✓
-+ Lines 171-172 (1 statement) - COVERED ✓
-+ Line 175 (1 statement) - COVERED ✓
-+ Lines 175-177 (1 statement) - COVERED ✓
-+ Line 179 (1 statement) - COVERED ✓
-+ Lines 179-181 (1 statement) - COVERED ✓
-+ Lines 185-188 (2 statements) - COVERED ✓
-` + "```" + `
-
-</details>
-
-`
-	assert.Equal(t, expected, actual)
+	assertGolden(t, "01", actual)
 }
 
 func TestReport_Markdown_OnlyChangedUnitTests(t *testing.T) {
@@ -120,43 +34,9 @@ func TestReport_Markdown_OnlyChangedUnitTests(t *testing.T) {
 	require.NoError(t, err)
 
 	report := NewReport(oldCov, newCov, changedFiles)
-	actual := report.Markdown()
-
-	expected := `### Coverage Report - 99.02% (**+8.82%**) - **increase**
+	actual := report.Analyze().Markdown()
 
-#### Overall Coverage Summary
-
-| Metric | Old Coverage | New Coverage | Change | :robot: |
-|--------|-------------|-------------|--------|---------|
-| **Total** | 90.20% | 99.02% | **+8.82%** | :thumbsup: |
-
-| **Statements** | Total | Covered | Missed |
-|---|---|---|---|
-| **Old** | 102 | 92 | 10 |
-| **New** | 102 | 101 (+9) | 1 |
-
----
-
-<details>
-
-<summary>Impacted Packages</summary>
-
-| Impacted Packages | Coverage Δ | :robot: |
-|-------------------|------------|---------|
-| github.com/fgrosse/prioqueue | 99.02% (**+8.82%**) | :thumbsup: |
-
-</details>
-
-<details>
-
-<summary>Coverage by file</summary>
-
-### Changed unit test files
-
-- github.com/fgrosse/prioqueue/min_heap_test.go
-
-</details>`
-	assert.Equal(t, expected, actual)
+	assertGolden(t, "02", actual)
 }
 
 func TestReport_MinimumCoverageThreshold(t *testing.T) {
@@ -172,7 +52,7 @@ func TestReport_MinimumCoverageThreshold(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 
 	// Test that new code coverage is calculated correctly
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 	require.Equal(t, int64(49), totalNew)
 	require.Equal(t, int64(42), coveredNew)
 
@@ -215,7 +95,7 @@ func TestReport_Markdown_WithFailedThreshold(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.MinCoverage = 90.0 // Set threshold to 90%, which is above the actual 85.71%
 
-	actual := report.Markdown()
+	actual := report.Analyze().Markdown()
 
 	expected := `### Coverage Report - 90.20% (**-9.80%**) - **decrease**
 
@@ -305,6 +185,7 @@ This section shows the coverage status of each new code block added in this PR.
 
 </details>
 
+<!-- go-coverage-report -->
 `
 	assert.Equal(t, expected, actual)
 }
@@ -322,7 +203,7 @@ func TestReport_Markdown_WithPassedThreshold(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.MinCoverage = 80.0 // Set threshold to 80%, which is below the actual 85.71%
 
-	actual := report.Markdown()
+	actual := report.Analyze().Markdown()
 
 	// Verify the warning message is NOT present
 	assert.NotContains(t, actual, "> [!WARNING]")
@@ -362,12 +243,12 @@ func TestReport_WithGitDiff(t *testing.T) {
 
 	// Test WITHOUT diff (old behavior - block-based comparison)
 	reportWithoutDiff := NewReport(oldCov, newCov, changedFiles)
-	totalNewWithoutDiff, coveredNewWithoutDiff := reportWithoutDiff.calculateNewCodeCoverage()
+	totalNewWithoutDiff, coveredNewWithoutDiff := reportWithoutDiff.Analyze().calculateNewCodeCoverage()
 
 	// Test WITH diff (new behavior - line-based comparison)
 	reportWithDiff := NewReport(oldCov, newCov, changedFiles)
 	reportWithDiff.DiffInfo = diffInfo
-	totalNewWithDiff, coveredNewWithDiff := reportWithDiff.calculateNewCodeCoverage()
+	totalNewWithDiff, coveredNewWithDiff := reportWithDiff.Analyze().calculateNewCodeCoverage()
 
 	// Without diff: treats many blocks as "new" because positions changed
 	// This is the problematic behavior we're fixing
@@ -435,7 +316,7 @@ func TestReport_WithGitDiff_EntireFileNew(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// When entire file is new, should count all statements
 	minHeapProfile := newCov.Files["github.com/fgrosse/prioqueue/min_heap.go"]
@@ -475,7 +356,7 @@ func TestReport_WithGitDiff_NoMatchingLines(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// Should report 0 new statements since no blocks contain the changed lines
 	assert.Equal(t, int64(0), totalNew, "Should report 0 new statements when no blocks match")
@@ -511,7 +392,7 @@ func TestReport_WithGitDiff_Markdown(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	markdown := report.Markdown()
+	markdown := report.Analyze().Markdown()
 
 	// Verify the markdown contains expected sections
 	assert.Contains(t, markdown, "### Coverage Report", "Should contain title")
@@ -555,7 +436,7 @@ func TestReport_WithGitDiff_OnlyComments(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// Comments don't have coverage blocks, so should be 0
 	assert.Equal(t, int64(0), totalNew,
@@ -614,7 +495,7 @@ func TestReport_WithGitDiff_NonGoFiles(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// Should only count the Go file changes, non-Go files should be ignored
 	// because they won't have coverage profiles
@@ -693,7 +574,7 @@ func TestReport_WithGitDiff_MixedCommentsAndCode(t *testing.T) {
 		DiffInfo:     diffInfo,
 	}
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// Should only count the block that overlaps with lines 30-35
 	// Lines 1-5 (comments) don't have coverage blocks, so shouldn't be counted
@@ -728,7 +609,7 @@ func TestReport_WithGitDiff_OnlyDeletedLines(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// When a file is in changed files but has no added lines in diff,
 	// it falls back to counting all statements (this is the current behavior)
@@ -754,70 +635,9 @@ func TestReport_WithActualSourceCode(t *testing.T) {
 	require.NoError(t, err)
 
 	report := NewReport(oldCov, newCov, changedFiles)
-	actual := report.Markdown()
-
-	expected := `### Coverage Report - 54.55% (**-45.45%**) - **decrease**
-
-#### Overall Coverage Summary
-
-| Metric | Old Coverage | New Coverage | Change | :robot: |
-|--------|-------------|-------------|--------|---------|
-| **Total** | 100.00% | 54.55% | **-45.45%** | :skull: :skull: :skull: :skull:  |
-| **New Code** | N/A | 37.50% | 3/8 statements | :thumbsdown: |
-
-| **Statements** | Total | Covered | Missed |
-|---|---|---|---|
-| **Old** | 3 | 3 | 0 |
-| **New** | 11 (+8) | 6 (+3) | 5 |
-
----
-
-<details>
-
-<summary>Impacted Packages</summary>
-
-| Impacted Packages | Coverage Δ | :robot: |
-|-------------------|------------|---------|
-| example.com/calculator | 54.55% (**-45.45%**) | :skull: :skull: :skull: :skull:  |
-
-</details>
-
-<details>
-
-<summary>Coverage by file</summary>
-
-### Changed files (no unit tests)
-
-| Changed File | Coverage Δ | Total | Covered | Missed | :robot: |
-|--------------|------------|-------|---------|--------|---------|
-| example.com/calculator/math.go | 54.55% (**-45.45%**) | 11 (+8) | 6 (+3) | 5 (+5) | :skull: :skull: :skull: :skull:  |
-
-_Please note that the "Total", "Covered", and "Missed" counts above refer to ***code statements*** instead of lines of code. The value in brackets refers to the test coverage of that file in the old version of the code._
+	actual := report.Analyze().Markdown()
 
-</details><details>
-
-<summary>New Code Coverage Details</summary>
-
-This section shows the coverage status of each new code block added in this PR.
-
-#### example.com/calculator/math.go
-
-` + "```diff" + `
-+ func Divide(a, b int) (int, error) {
-+ 	if b == 0 {
-+ 		return 0, errors.New("division by zero")
-+ 	}
-+ 	return a / b, nil
-- func Power(base, exp int) int {
-- 	result := 1
-- 	for i := 0; i < exp; i++ {
-- 	}
-` + "```" + `
-
-</details>
-
-`
-	assert.Equal(t, expected, actual)
+	assertGolden(t, "03", actual)
 }
 
 func TestReport_ProportionalStatementCounting(t *testing.T) {
@@ -892,7 +712,7 @@ func TestReport_ProportionalStatementCounting(t *testing.T) {
 
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	// Block 1: 3 statements * (3 changed / 6 total) = 1.5 → 1 statement
 	// Block 2: 2 statements * (3 changed / 3 total) = 2 statements
@@ -947,7 +767,7 @@ func TestReport_ASTBasedCounting(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	totalNew, coveredNew := report.calculateNewCodeCoverage()
+	totalNew, coveredNew := report.Analyze().calculateNewCodeCoverage()
 
 	t.Logf("AST-based counting: %d/%d statements = %.2f%% coverage",
 		coveredNew, totalNew, float64(coveredNew)/float64(totalNew)*100)
@@ -986,7 +806,7 @@ func TestReport_DuplicateLinesAndIncorrectCoverage(t *testing.T) {
 	report := NewReport(oldCov, newCov, changedFiles)
 	report.DiffInfo = diffInfo
 
-	actual := report.Markdown()
+	actual := report.Analyze().Markdown()
 
 	// Check for duplicate lines in the output
 	// The line "if daysSinceBirth > 100000 {" should only appear once
@@ -1013,6 +833,57 @@ func TestReport_DuplicateLinesAndIncorrectCoverage(t *testing.T) {
 	t.Logf("Actual output:\n%s", actual)
 }
 
+func TestReport_NewLineCoverage_MergesBlocksPerLine(t *testing.T) {
+	sourceLines := map[int]string{
+		3:  "func F(x int) int {",
+		4:  "\tf := func() int {",
+		5:  "\t\treturn 1",
+		6:  "\t}",
+		7:  "\tif x > 0 { return f() }",
+		8:  "\tg := func() int { return 2 }",
+		9:  "\t_ = g",
+		10: "\treturn 0",
+		11: "}",
+	}
+
+	// The profile that "go test -coverprofile" writes for the code above if
+	// F is only called with x <= 0.
+	blocks := []NewCodeBlock{
+		{StartLine: 3, StartCol: 19, EndLine: 4, EndCol: 18, NumStmt: 1, Covered: true},
+		{StartLine: 5, StartCol: 3, EndLine: 6, EndCol: 1, NumStmt: 1, Covered: false}, // closure body
+		{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 11, NumStmt: 1, Covered: true},
+		{StartLine: 7, StartCol: 13, EndLine: 7, EndCol: 25, NumStmt: 1, Covered: false}, // body of the one-line if
+		{StartLine: 8, StartCol: 2, EndLine: 8, EndCol: 18, NumStmt: 1, Covered: true},
+		{StartLine: 8, StartCol: 20, EndLine: 8, EndCol: 30, NumStmt: 1, Covered: false}, // one-line closure
+		{StartLine: 9, StartCol: 2, EndLine: 10, EndCol: 10, NumStmt: 2, Covered: true},
+	}
+
+	report := NewReport(New(nil), New(nil), nil)
+	assert.Equal(t, map[int]bool{
+		3:  true, // only the opening brace of the function body
+		4:  true, // the closure body starts on the next line
+		5:  false,
+		6:  false,
+		7:  false, // the body of the if statement was not executed
+		8:  false, // the closure was not called
+		9:  true,
+		10: true,
+	}, report.newLineCoverage("example.com/cl/a.go", sourceLines, blocks))
+
+	// Without source code, every line of a block counts and a line is
+	// only covered if all of its blocks are covered.
+	assert.Equal(t, map[int]bool{
+		3:  true,
+		4:  true,
+		5:  false,
+		6:  false,
+		7:  false,
+		8:  false,
+		9:  true,
+		10: true,
+	}, report.newLineCoverage("example.com/cl/a.go", nil, blocks))
+}
+
 // Helper functions to avoid importing strings package issues
 func countOccurrences(s, substr string) int {
 	count := 0
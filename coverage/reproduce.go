@@ -0,0 +1,132 @@
+package coverage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reproduction describes how to re-run a report locally with exactly the same
+// inputs, e.g. to settle disputes about its numbers. It is only set by JSON.
+type Reproduction struct {
+	Version string            // version of go-coverage-report that created the report
+	Command string            // command line with the effective value of every option
+	Inputs  map[string]string // SHA-256 digest of each input file by its path
+}
+
+// effectiveFlags returns the flags of fs whose effective value differs from
+// their default as command line arguments (e.g. "-min-coverage=80"). Values
+// from environment variables and the config file are included, since
+// resolveFlags applies them to fs.
+func effectiveFlags(fs *flag.FlagSet) []string {
+	var flags []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if val := f.Value.String(); val != f.DefValue {
+			flags = append(flags, "-"+f.Name+"="+val)
+		}
+	})
+
+	return flags
+}
+
+// newReproduction returns the Reproduction of a run of the main command with
+// the given arguments and options.
+func newReproduction(oldCovPath, newCovPath, changedFilesPath string, opts options) (*Reproduction, error) {
+	args := append([]string{"go-coverage-report"}, opts.flags...)
+	args = append(args, oldCovPath, newCovPath, changedFilesPath)
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+
+	inputs := []string{oldCovPath, changedFilesPath, opts.diffFile, opts.configFile, opts.pkgCoverage, opts.testJSON, opts.previous, opts.ignoreFile, opts.pathPlugin}
+	if shards := parseShards(newCovPath); shards != nil {
+		inputs = append(inputs, shardPaths(shards)...)
+	} else {
+		inputs = append(inputs, newCovPath)
+	}
+	if opts.fillFrom != "" {
+		inputs = append(inputs, shardPaths(parseFillCoverage(opts.fillFrom))...)
+	}
+	if opts.testFiles != "" {
+		coverageFiles, err := parseTestFileCoverage(opts.testFiles, opts.root)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range coverageFiles {
+			inputs = append(inputs, f)
+		}
+	}
+
+	r := &Reproduction{
+		Version: version,
+		Command: strings.Join(args, " "),
+		Inputs:  make(map[string]string),
+	}
+	for _, fileName := range inputs {
+		if fileName == "" {
+			continue
+		}
+		digest, err := fileDigest(fileName)
+		if err != nil {
+			return nil, err
+		}
+		r.Inputs[fileName] = digest
+	}
+
+	// The default ignore file is only an input if it exists.
+	if opts.ignoreFile == "" {
+		fileName := filepath.Join(opts.repoRoot, defaultIgnoreFile)
+		digest, err := fileDigest(fileName)
+		switch {
+		case err == nil:
+			r.Inputs[fileName] = digest
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// fileDigest returns the hex encoded SHA-256 digest of the file.
+func fileDigest(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// shellQuote quotes the argument for POSIX shells if it contains characters
+// with a special meaning.
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+
+	safe := true
+	for _, c := range arg {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_./=,:@%+", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,72 @@
+package coverage
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-root=github.com/acme/app", "-format=json"}))
+
+	env := map[string]string{"GO_COVERAGE_REPORT_MIN_COVERAGE": "80", "GO_COVERAGE_REPORT_ROOT": "ignored"}
+	_, _, err := resolveFlags(fs, func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	})
+	require.NoError(t, err)
+
+	// Values of environment variables are expanded.
+	assert.Equal(t, []string{"-format=json", "-min-coverage=80", "-root=github.com/acme/app"}, effectiveFlags(fs))
+}
+
+func TestNewReproduction(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		fileName := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0644))
+		return fileName
+	}
+
+	oldCov := write("old.txt", "mode: set\n")
+	linux := write("linux.txt", "mode: set\n")
+	windows := write("windows.txt", "mode: atomic\n")
+	changed := write("changed.json", "[]")
+	ignore := write(defaultIgnoreFile, "vendor/\n")
+
+	opts := options{
+		repoRoot: dir,
+		flags:    []string{"-min-coverage=80", "-only=pkg/service/**"},
+	}
+	r, err := newReproduction(oldCov, "linux="+linux+",windows="+windows, changed, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, "dev", r.Version)
+	assert.Equal(t, "go-coverage-report -min-coverage=80 '-only=pkg/service/**' "+oldCov+" linux="+linux+",windows="+windows+" "+changed, r.Command)
+	assert.Equal(t, map[string]string{
+		oldCov:  "22b0c386ce56856f44063cacf33009cf7f5bed9328092526b20e546186c2f3b8",
+		linux:   "22b0c386ce56856f44063cacf33009cf7f5bed9328092526b20e546186c2f3b8",
+		windows: "15e39ee7d1ea1e23c2caa82c7fa07907bc8ae826847b1499a894308734780a89",
+		changed: "4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+		ignore:  "e15b1b0e0f10012abb6f06891455da689a7a4ff323b70ce46144d8611b7d4d8a",
+	}, r.Inputs)
+
+	_, err = newReproduction(filepath.Join(dir, "missing.txt"), linux, changed, opts)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "-root=github.com/acme/app", shellQuote("-root=github.com/acme/app"))
+	assert.Equal(t, "''", shellQuote(""))
+	assert.Equal(t, "'a b'", shellQuote("a b"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+	assert.Equal(t, "'$HOME'", shellQuote("$HOME"))
+}
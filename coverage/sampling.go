@@ -0,0 +1,92 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Sample describes the deterministic sample of files that was used to
+// estimate the overall and package coverage of very large profiles.
+type Sample struct {
+	Rate     float64 // fraction of all files that are part of the sample
+	OldError float64 // half width of the 95% confidence interval of the old coverage in percentage points
+	NewError float64 // half width of the 95% confidence interval of the new coverage in percentage points
+}
+
+// inSample reports whether the file is part of the deterministic sample with
+// the given rate. The decision only depends on the file name so the old and
+// new profile are sampled the same way and their delta remains meaningful.
+func inSample(fileName string, rate float64) bool {
+	const buckets = 1_000_000
+
+	h := fnv.New64a()
+	h.Write([]byte(fileName))
+	return float64(h.Sum64()%buckets) < rate*buckets
+}
+
+// parseCoverageSample parses the coverage profile but only keeps the files
+// that are part of the sample with the given rate and the files in keep,
+// which are always included in full.
+func parseCoverageSample(ctx context.Context, filename string, rate float64, keep map[string]bool, o parseOptions) (*Coverage, error) {
+	pp, err := parseProfilesFile(ctx, filename, func(fileName string) bool {
+		return keep[fileName] || inSample(fileName, rate)
+	}, o)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse profiles")
+	}
+
+	return New(pp), nil
+}
+
+// sampleError returns the half width of the 95% confidence interval of the
+// coverage percentage estimated from a sample of the files of cov, which were
+// drawn with the given rate. Files are the sampling units, so the coverage is
+// a ratio estimate of covered to total statements (cluster sampling).
+func sampleError(cov *Coverage, rate float64) float64 {
+	n := float64(len(cov.Files))
+	if n < 2 || cov.TotalStmt == 0 {
+		// We cannot say anything about the error of tiny samples.
+		return 100
+	}
+
+	ratio := float64(cov.CoveredStmt) / float64(cov.TotalStmt)
+	var sumSquares float64
+	for _, p := range cov.Files {
+		residual := float64(p.CoveredStmt) - ratio*float64(p.TotalStmt)
+		sumSquares += residual * residual
+	}
+
+	variance := sumSquares / (n - 1)
+	meanTotal := float64(cov.TotalStmt) / n
+	stdErr := math.Sqrt((1-min(rate, 1))/n*variance) / meanTotal
+
+	return min(1.96*stdErr*100, 100)
+}
+
+// exceedsSize reports whether the combined size of the given files is larger
+// than the given number of megabytes.
+func exceedsSize(megabytes int, fileNames ...string) (bool, error) {
+	var size int64
+	for _, fileName := range fileNames {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return false, err
+		}
+		size += info.Size()
+	}
+
+	return size > int64(megabytes)<<20, nil
+}
+
+// note explains that the report is based on a sample.
+func (s *Sample) note() string {
+	return fmt.Sprintf("The coverage profiles are very large, so the total and package coverage were estimated "+
+		"from a %.0f%% sample of the files (old ±%.2f%%, new ±%.2f%% at 95%% confidence). "+
+		"Statement counts refer to the sample. Changed files are always analyzed in full.",
+		s.Rate*100, s.OldError, s.NewError)
+}
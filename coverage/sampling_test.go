@@ -0,0 +1,66 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInSample(t *testing.T) {
+	var sampled int
+	for i := 0; i < 10000; i++ {
+		name := fmt.Sprintf("example.com/pkg%d/file.go", i)
+		if inSample(name, 0.1) {
+			sampled++
+		}
+		assert.Equal(t, inSample(name, 0.1), inSample(name, 0.1), "sampling must be deterministic")
+	}
+
+	assert.InDelta(t, 1000, sampled, 100)
+	assert.True(t, inSample("a.go", 1))
+	assert.False(t, inSample("a.go", 0))
+}
+
+func TestParseCoverageSample(t *testing.T) {
+	fileName := writeLargeProfile(t, 200, 10)
+	full, err := ParseCoverage(fileName)
+	require.NoError(t, err)
+
+	changed := "example.com/monorepo/service3/pkg/file3.go"
+	require.False(t, inSample(changed, 0.2), "the test requires a changed file that is not sampled")
+
+	cov, err := parseCoverageSample(context.Background(), fileName, 0.2, map[string]bool{changed: true}, parseOptions{})
+	require.NoError(t, err)
+
+	assert.Contains(t, cov.Files, changed)
+	assert.Less(t, len(cov.Files), len(full.Files)/2)
+	assert.Greater(t, len(cov.Files), len(full.Files)/10)
+
+	// All files of the synthetic profile have the same coverage.
+	assert.InDelta(t, full.Percent(), cov.Percent(), 0.001)
+	assert.InDelta(t, 0, sampleError(cov, 0.2), 0.001)
+}
+
+func TestSampleError(t *testing.T) {
+	cov := New([]*Profile{
+		{FileName: "a.go", TotalStmt: 10, CoveredStmt: 10},
+		{FileName: "b.go", TotalStmt: 10, CoveredStmt: 0},
+		{FileName: "c.go", TotalStmt: 10, CoveredStmt: 5},
+	})
+
+	// 1.96 * sqrt((1-0.1)/3 * 25) / 10 * 100
+	assert.InDelta(t, 53.68, sampleError(cov, 0.1), 0.01)
+	assert.Zero(t, sampleError(cov, 1), "a full sample has no error")
+	assert.EqualValues(t, 100, sampleError(New([]*Profile{cov.Files["a.go"]}), 0.1))
+}
+
+func TestReport_Markdown_Sample(t *testing.T) {
+	report := newTestReport(t, "testdata/01-old-coverage.txt", "testdata/01-new-coverage.txt", "testdata/01-changed-files.json", "github.com/fgrosse/prioqueue", "")
+	assert.NotContains(t, report.Analyze().Markdown(), "sample")
+
+	report.Sample = &Sample{Rate: 0.1, OldError: 1.5, NewError: 0.25}
+	assert.Contains(t, report.Analyze().Markdown(), "> The coverage profiles are very large, so the total and package coverage were estimated from a 10% sample of the files (old ±1.50%, new ±0.25% at 95% confidence).")
+}
@@ -0,0 +1,161 @@
+package coverage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Shard is the new coverage of a single job of a CI matrix, e.g. the tests
+// on one operating system or Go version.
+type Shard struct {
+	Label    string
+	Path     string    `json:"-"`
+	Coverage *Coverage `json:"-"`
+}
+
+// parseShards returns the shards of the NEW_COVERAGE_FILE argument, which
+// may be a comma separated list of coverage files of a CI matrix, each with
+// an optional label (e.g. "linux=cover-linux.out,windows=cover-windows.out").
+// Files without a label are labeled with their name. It returns nil for a
+// single file without a label.
+func parseShards(arg string) []Shard {
+	if !strings.Contains(arg, ",") && !strings.Contains(arg, "=") {
+		return nil
+	}
+
+	var shards []Shard
+	for _, entry := range strings.Split(arg, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		label, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			label, path = strings.TrimSuffix(filepath.Base(entry), filepath.Ext(entry)), entry
+		}
+		shards = append(shards, Shard{Label: label, Path: path})
+	}
+
+	return shards
+}
+
+// shardPaths returns the coverage files of the shards.
+func shardPaths(shards []Shard) []string {
+	paths := make([]string, len(shards))
+	for i, s := range shards {
+		paths[i] = s.Path
+	}
+
+	return paths
+}
+
+// MergeCoverage merges the coverage of multiple runs of the tests of the same
+// code, e.g. the shards of a CI matrix. Blocks of the same location are merged
+// like the blocks of a single profile: a block is covered if it is covered in
+// any of the runs. Files that are only compiled in some of the runs (e.g.
+// because of build constraints) are included as they are.
+func MergeCoverage(covs ...*Coverage) (*Coverage, error) {
+	pp := newProfileParser(parseOptions{})
+	for _, c := range covs {
+		for name, p := range c.Files {
+			if pp.mode == "" {
+				pp.mode = p.Mode
+			}
+
+			merged := pp.files[name]
+			if merged == nil {
+				merged = &Profile{FileName: name, Mode: p.Mode}
+				pp.files[name] = merged
+			}
+			merged.Blocks = append(merged.Blocks, p.Blocks...)
+		}
+	}
+
+	profiles, err := pp.profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(profiles), nil
+}
+
+// hasShardGap returns true if a changed file is compiled in a shard but not
+// covered by its tests at all although it is covered by other shards.
+func (r *Report) hasShardGap() bool {
+	for _, name := range r.ChangedFiles {
+		if r.New.Files[name].GetCovered() == 0 {
+			continue
+		}
+		for _, s := range r.Shards {
+			if p := s.Coverage.Files[name]; p != nil && p.TotalStmt > 0 && p.CoveredStmt == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// addShardDetails lists the coverage of the changed files in each shard, so
+// that code that is only tested on some platforms stands out.
+func (r *Report) addShardDetails(report *strings.Builder) {
+	if len(r.Shards) < 2 {
+		return
+	}
+
+	var files []string
+	for _, name := range r.ChangedFiles {
+		if r.New.Files[name] != nil {
+			files = append(files, name)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldShards, r.hasShardGap))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Coverage by Shard</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The new coverage was merged from multiple test runs. Files that are not compiled in a run are marked with \"-\".")
+	fmt.Fprintln(report)
+
+	header := "| Changed File | Merged |"
+	separator := "|--------------|--------|"
+	for _, s := range r.Shards {
+		header += " " + strings.ReplaceAll(s.Label, "|", `\|`) + " |"
+		separator += strings.Repeat("-", len(s.Label)+2) + "|"
+	}
+	fmt.Fprintln(report, header)
+	fmt.Fprintln(report, separator)
+
+	n := r.numbers()
+	total := "| **Total** | " + n.Percent(r.New.Percent()) + " |"
+	for _, s := range r.Shards {
+		total += " " + n.Percent(s.Coverage.Percent()) + " |"
+	}
+	fmt.Fprintln(report, total)
+
+	for _, name := range files {
+		merged := r.New.Files[name]
+		row := fmt.Sprintf("| %s | %s |", name, n.Percent(merged.CoveragePercent()))
+		for _, s := range r.Shards {
+			p := s.Coverage.Files[name]
+			switch {
+			case p == nil:
+				row += " - |"
+			case p.TotalStmt > 0 && p.CoveredStmt == 0 && merged.CoveredStmt > 0:
+				row += fmt.Sprintf(" **%s** %s |", n.Percent(p.CoveragePercent()), r.theme().warning)
+			default:
+				row += fmt.Sprintf(" %s |", n.Percent(p.CoveragePercent()))
+			}
+		}
+		fmt.Fprintln(report, row)
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
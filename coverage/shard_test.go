@@ -0,0 +1,96 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShards(t *testing.T) {
+	assert.Nil(t, parseShards("new-coverage.txt"))
+	assert.Equal(t, []Shard{{Label: "linux", Path: "cover.out"}}, parseShards("linux=cover.out"))
+	assert.Equal(t, []Shard{
+		{Label: "linux", Path: "out/linux.out"},
+		{Label: "windows", Path: "out/windows.out"},
+	}, parseShards("linux=out/linux.out, out/windows.out,"))
+}
+
+func TestMergeCoverage(t *testing.T) {
+	parse := func(profile string) *Coverage {
+		pp, err := ParseProfilesFromReader(strings.NewReader(profile))
+		require.NoError(t, err)
+		return New(pp)
+	}
+
+	linux := parse(`mode: set
+example.com/app/a.go:1.1,2.2 2 1
+example.com/app/a.go:3.1,4.2 3 0
+example.com/app/a_linux.go:1.1,2.2 1 1
+`)
+	windows := parse(`mode: set
+example.com/app/a.go:1.1,2.2 2 0
+example.com/app/a.go:3.1,4.2 3 1
+example.com/app/a_windows.go:1.1,2.2 4 0
+`)
+
+	merged, err := MergeCoverage(linux, windows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), merged.TotalStmt)
+	assert.Equal(t, int64(6), merged.CoveredStmt)
+	assert.Equal(t, int64(5), merged.Files["example.com/app/a.go"].CoveredStmt)
+	assert.Len(t, merged.Files, 3)
+
+	// The shards are not modified.
+	assert.Equal(t, int64(2), linux.Files["example.com/app/a.go"].CoveredStmt)
+	assert.Len(t, linux.Files["example.com/app/a.go"].Blocks, 2)
+
+	_, err = MergeCoverage(linux, parse("mode: set\nexample.com/app/a.go:1.1,2.2 5 1\n"))
+	assert.Error(t, err, "inconsistent number of statements")
+}
+
+func TestReport_ShardDetails(t *testing.T) {
+	linux := New([]*Profile{
+		{FileName: "example.com/app/a.go", TotalStmt: 4, CoveredStmt: 4},
+		{FileName: "example.com/app/b.go", TotalStmt: 2, CoveredStmt: 1},
+	})
+	windows := New([]*Profile{
+		{FileName: "example.com/app/a.go", TotalStmt: 4, CoveredStmt: 0},
+		{FileName: "example.com/app/c_windows.go", TotalStmt: 2, CoveredStmt: 2},
+	})
+	merged := New([]*Profile{
+		{FileName: "example.com/app/a.go", TotalStmt: 4, CoveredStmt: 4},
+		{FileName: "example.com/app/b.go", TotalStmt: 2, CoveredStmt: 1},
+		{FileName: "example.com/app/c_windows.go", TotalStmt: 2, CoveredStmt: 2},
+	})
+
+	report := NewReport(New(nil), merged, []string{"example.com/app/a.go", "example.com/app/b.go", "example.com/app/c_windows.go", "example.com/app/README.md"})
+	report.Shards = []Shard{{Label: "linux", Coverage: linux}, {Label: "windows", Coverage: windows}}
+	report.Config = &Config{Fold: map[string]string{foldShards: foldAuto}}
+
+	var md strings.Builder
+	report.addShardDetails(&md)
+	assert.Equal(t, `<details open>
+
+<summary>Coverage by Shard</summary>
+
+The new coverage was merged from multiple test runs. Files that are not compiled in a run are marked with "-".
+
+| Changed File | Merged | linux | windows |
+|--------------|--------|-------|---------|
+| **Total** | 87.50% | 83.33% | 33.33% |
+| example.com/app/a.go | 100.00% | 100.00% | **0.00%** :warning: |
+| example.com/app/b.go | 50.00% | 50.00% | - |
+| example.com/app/c_windows.go | 100.00% | - | 100.00% |
+
+</details>
+
+`, md.String())
+
+	// A single shard is the same as the merged coverage.
+	report.Shards = report.Shards[:1]
+	md.Reset()
+	report.addShardDetails(&md)
+	assert.Empty(t, md.String())
+}
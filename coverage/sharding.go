@@ -0,0 +1,168 @@
+package coverage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Thresholds of ShardingAdvice.
+const (
+	shardingMinElapsed   = 30 * time.Second // faster packages are not worth the effort
+	shardingMinTimeShare = 20               // percent of the total test time that warrants an own shard
+	shardingSignalRatio  = 4                // time share / coverage share above which a package contributes little coverage
+)
+
+// PackageTiming is the test duration of a package according to the output of
+// "go test -json".
+type PackageTiming struct {
+	Package        string
+	Elapsed        time.Duration
+	Tests          int    // number of top-level tests
+	Slowest        string // name of the slowest top-level test
+	SlowestElapsed time.Duration
+	Parallel       bool // true if any test of the package called t.Parallel
+}
+
+// packageTimings collects the PackageTiming of every package from the events
+// of "go test -json".
+type packageTimings map[string]*PackageTiming
+
+func newPackageTimings() packageTimings {
+	return packageTimings{}
+}
+
+func (t packageTimings) add(e testEvent) {
+	if e.Package == "" {
+		return
+	}
+
+	p := t[e.Package]
+	if p == nil {
+		p = &PackageTiming{Package: e.Package}
+		t[e.Package] = p
+	}
+
+	elapsed := time.Duration(e.Elapsed * float64(time.Second))
+	switch {
+	case e.Action == "pause":
+		p.Parallel = true // only parallel tests are paused
+	case e.Action != "pass" && e.Action != "fail":
+	case e.Test == "":
+		p.Elapsed = elapsed
+	case !strings.Contains(e.Test, "/"):
+		p.Tests++
+		if elapsed > p.SlowestElapsed {
+			p.Slowest, p.SlowestElapsed = e.Test, elapsed
+		}
+	}
+}
+
+// result returns the timings of all packages that ran tests ordered by
+// package.
+func (t packageTimings) result() []PackageTiming {
+	var result []PackageTiming
+	for _, pkg := range sortedKeys(t) {
+		if t[pkg].Elapsed > 0 {
+			result = append(result, *t[pkg])
+		}
+	}
+
+	return result
+}
+
+// ShardingAdvice is a suggestion to speed up the tests of a slow package.
+type ShardingAdvice struct {
+	Package       string
+	Elapsed       time.Duration
+	TimeShare     float64 // percent of the total test time
+	CoverageShare float64 // percent of all covered statements that are in the package
+	Suggestions   []string
+}
+
+// ShardingAdvice returns suggestions for the packages whose tests take at
+// least shardingMinElapsed, ordered by their test duration. Packages are
+// suggested to be run on their own shard if they take a large part of the
+// total test time, to use t.Parallel if none of their tests run in parallel
+// and to be moved to a separate job if they take much more time than their
+// share of the covered statements.
+func (r *Report) ShardingAdvice() []ShardingAdvice {
+	var total time.Duration
+	for _, t := range r.TestTimings {
+		total += t.Elapsed
+	}
+	if total < shardingMinElapsed {
+		return nil
+	}
+
+	pkgCov := r.New.ByPackage()
+
+	var result []ShardingAdvice
+	for _, t := range r.TestTimings {
+		if t.Elapsed < shardingMinElapsed {
+			continue
+		}
+
+		advice := ShardingAdvice{
+			Package:   t.Package,
+			Elapsed:   t.Elapsed,
+			TimeShare: float64(t.Elapsed) / float64(total) * 100,
+		}
+		if c := pkgCov[t.Package]; c != nil && r.New.CoveredStmt > 0 {
+			advice.CoverageShare = float64(c.CoveredStmt) / float64(r.New.CoveredStmt) * 100
+		}
+
+		dominated := t.SlowestElapsed >= t.Elapsed/2
+		switch {
+		case advice.TimeShare >= shardingMinTimeShare && dominated && t.Tests > 1:
+			advice.Suggestions = append(advice.Suggestions, fmt.Sprintf("Split `%s` (%s) into tests that can run on separate shards.", t.Slowest, formatElapsed(t.SlowestElapsed)))
+		case advice.TimeShare >= shardingMinTimeShare:
+			advice.Suggestions = append(advice.Suggestions, "Run the package on its own shard, e.g. by splitting its tests via `go test -run`.")
+		}
+		if !t.Parallel && t.Tests > 1 && !dominated {
+			advice.Suggestions = append(advice.Suggestions, fmt.Sprintf("None of its %d tests run in parallel. Mark independent tests with `t.Parallel()`.", t.Tests))
+		}
+		if advice.CoverageShare*shardingSignalRatio < advice.TimeShare {
+			advice.Suggestions = append(advice.Suggestions, "It contributes little to the coverage, so it can run in a separate (e.g. nightly) job without losing much coverage signal.")
+		}
+
+		if len(advice.Suggestions) > 0 {
+			result = append(result, advice)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Elapsed > result[j].Elapsed
+	})
+
+	return result
+}
+
+func formatElapsed(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func (r *Report) addShardingAdvice(report *strings.Builder) {
+	advice := r.ShardingAdvice()
+	if len(advice) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldSharding, func() bool { return false }))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Sharding Advice</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The tests of the packages below take the most time. The coverage share is the percentage of all covered statements that are in the package.")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| Package | Test Time | Time Share | Coverage Share | Suggestion |")
+	fmt.Fprintln(report, "|---------|-----------|------------|----------------|------------|")
+
+	for _, a := range advice {
+		fmt.Fprintf(report, "| %s | %s | %.1f%% | %.1f%% | %s |\n", a.Package, formatElapsed(a.Elapsed), a.TimeShare, a.CoverageShare, strings.Join(a.Suggestions, "<br>"))
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
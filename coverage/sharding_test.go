@@ -0,0 +1,69 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestOutput_Timings(t *testing.T) {
+	input := strings.TrimSpace(`
+{"Action":"run","Package":"example.com/a","Test":"TestA"}
+{"Action":"pause","Package":"example.com/a","Test":"TestA"}
+{"Action":"pass","Package":"example.com/a","Test":"TestA/sub","Elapsed":3}
+{"Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":4}
+{"Action":"pass","Package":"example.com/a","Test":"TestB","Elapsed":1.5}
+{"Action":"pass","Package":"example.com/a","Elapsed":5.2}
+{"Action":"fail","Package":"example.com/b","Test":"TestC","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/b","Elapsed":0.3}
+{"Action":"skip","Package":"example.com/c","Elapsed":0}
+`)
+
+	out, err := parseTestOutput(strings.NewReader(input), parseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []PackageTiming{
+		{Package: "example.com/a", Elapsed: 5200 * time.Millisecond, Tests: 2, Slowest: "TestA", SlowestElapsed: 4 * time.Second, Parallel: true},
+		{Package: "example.com/b", Elapsed: 300 * time.Millisecond, Tests: 1, Slowest: "TestC", SlowestElapsed: 100 * time.Millisecond},
+	}, out.Packages)
+}
+
+func TestReport_ShardingAdvice(t *testing.T) {
+	cov := New([]*Profile{
+		{FileName: "example.com/app/db/db.go", TotalStmt: 10, CoveredStmt: 2},
+		{FileName: "example.com/app/api/api.go", TotalStmt: 100, CoveredStmt: 60},
+		{FileName: "example.com/app/util/util.go", TotalStmt: 50, CoveredStmt: 38},
+	})
+
+	report := NewReport(cov, cov, []string{"example.com/app/api/api.go"})
+	assert.Empty(t, report.ShardingAdvice(), "without test timings")
+
+	report.TestTimings = []PackageTiming{
+		{Package: "example.com/app/api", Elapsed: 60 * time.Second, Tests: 40, Slowest: "TestLogin", SlowestElapsed: 5 * time.Second},
+		{Package: "example.com/app/db", Elapsed: 90 * time.Second, Tests: 3, Slowest: "TestMigrations", SlowestElapsed: 80 * time.Second, Parallel: true},
+		{Package: "example.com/app/util", Elapsed: 10 * time.Second, Tests: 12},
+	}
+
+	advice := report.ShardingAdvice()
+	require.Len(t, advice, 2)
+
+	assert.Equal(t, "example.com/app/db", advice[0].Package)
+	assert.InDelta(t, 56.25, advice[0].TimeShare, 0.01)
+	assert.InDelta(t, 2.0, advice[0].CoverageShare, 0.01)
+	assert.Equal(t, []string{
+		"Split `TestMigrations` (1m20s) into tests that can run on separate shards.",
+		"It contributes little to the coverage, so it can run in a separate (e.g. nightly) job without losing much coverage signal.",
+	}, advice[0].Suggestions)
+
+	assert.Equal(t, "example.com/app/api", advice[1].Package)
+	assert.Equal(t, []string{
+		"Run the package on its own shard, e.g. by splitting its tests via `go test -run`.",
+		"None of its 40 tests run in parallel. Mark independent tests with `t.Parallel()`.",
+	}, advice[1].Suggestions)
+
+	md := report.Analyze().Markdown()
+	assert.Contains(t, md, "<summary>Sharding Advice</summary>")
+	assert.Contains(t, md, "| example.com/app/db | 1m30s | 56.2% | 2.0% | Split `TestMigrations` (1m20s) into tests that can run on separate shards.<br>It contributes")
+}
@@ -0,0 +1,287 @@
+package coverage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var shareUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s share [OPTIONS] <OLD_COVERAGE_FILE> <NEW_COVERAGE_FILE> <CHANGED_FILES_FILE>
+
+Create a redacted bundle of the given inputs that can be attached to a bug report
+for this tool. All file and package paths are replaced with salted hashes and
+the diff is reduced to the line numbers that were added. The bundle contains no
+source code, but the report can be reproduced from it by running:
+
+  go-coverage-report [-diff=diff.patch] old-coverage.txt new-coverage.txt changed-files.json
+
+The diff.patch file is only part of the bundle if -diff is set. Since the source
+code is missing, new code is attributed using the coverage blocks instead of the
+exact statement lines.
+
+The salt is random unless -salt is set. Use -mapping to write the original and
+redacted paths to a local file so you can map the maintainer's findings back to
+your code. The mapping file is not part of the bundle.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+// Names of the files in a share bundle.
+const (
+	bundleOldCoverage  = "old-coverage.txt"
+	bundleNewCoverage  = "new-coverage.txt"
+	bundleChangedFiles = "changed-files.json"
+	bundleDiff         = "diff.patch"
+)
+
+func runShareCommand(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, shareUsage)
+		fs.PrintDefaults()
+	}
+
+	root := fs.String("root", "", "The import path of the tested repository to add as prefix to all paths of the changed files")
+	diffFile := fs.String("diff", "", "path to git diff file (unified diff format) whose shape should be included in the bundle")
+	output := fs.String("o", "go-coverage-report-bundle.tar.gz", "path of the bundle to create")
+	salt := fs.String("salt", "", "salt for hashing paths (random by default)")
+	mappingFile := fs.String("mapping", "", "optional path to write the mapping from original to redacted paths as JSON")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly 3 arguments but got %d", fs.NArg())
+	}
+
+	r, err := newRedactor(*salt)
+	if err != nil {
+		return err
+	}
+
+	files, err := r.bundle(fs.Arg(0), fs.Arg(1), fs.Arg(2), *root, *diffFile)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	err = writeBundle(f, files)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if *mappingFile != "" {
+		data, err := json.MarshalIndent(r.paths, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*mappingFile, data, 0600); err != nil {
+			return fmt.Errorf("failed to write mapping: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote redacted bundle to %s\n", *output)
+	return nil
+}
+
+// redactor replaces paths with salted hashes. Each path segment is hashed on
+// its own so the package structure and suffix relations between diff paths and
+// profile paths are preserved.
+type redactor struct {
+	salt  []byte
+	paths map[string]string // original path -> redacted path
+}
+
+func newRedactor(salt string) (*redactor, error) {
+	r := &redactor{salt: []byte(salt), paths: map[string]string{}}
+	if salt == "" {
+		r.salt = make([]byte, 16)
+		if _, err := rand.Read(r.salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// path returns the redacted version of the given slash separated path.
+func (r *redactor) path(p string) string {
+	if redacted, ok := r.paths[p]; ok {
+		return redacted
+	}
+
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." {
+			continue
+		}
+		ext := path.Ext(seg)
+		if ext != ".go" {
+			ext = ""
+		}
+		h := sha256.New()
+		h.Write(r.salt)
+		h.Write([]byte(strings.TrimSuffix(seg, ext)))
+		segments[i] = hex.EncodeToString(h.Sum(nil))[:10] + ext
+	}
+
+	redacted := strings.Join(segments, "/")
+	r.paths[p] = redacted
+	return redacted
+}
+
+// bundle returns the redacted contents of all files in a share bundle.
+func (r *redactor) bundle(oldCovPath, newCovPath, changedFilesPath, root, diffFile string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	var err error
+	files[bundleOldCoverage], err = r.profiles(oldCovPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old coverage: %w", err)
+	}
+
+	files[bundleNewCoverage], err = r.profiles(newCovPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new coverage: %w", err)
+	}
+
+	files[bundleChangedFiles], err = r.changedFiles(changedFilesPath, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load changed files: %w", err)
+	}
+
+	if diffFile != "" {
+		files[bundleDiff], err = r.diff(diffFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse diff file: %w", err)
+		}
+	}
+
+	return files, nil
+}
+
+// profiles returns the coverage profile at the given path in the format
+// produced by go test -coverprofile with all file names redacted.
+func (r *redactor) profiles(filename string) ([]byte, error) {
+	profiles, err := ParseProfiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	mode := "set"
+	if len(profiles) > 0 {
+		mode = profiles[0].Mode
+	}
+	fmt.Fprintf(&buf, "mode: %s\n", mode)
+
+	for _, p := range profiles {
+		fileName := r.path(p.FileName)
+		for _, b := range p.Blocks {
+			fmt.Fprintln(&buf, profileLine(fileName, b))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// changedFiles returns the redacted list of changed files as JSON. The root
+// is applied before redacting so the bundle does not need a -root flag.
+func (r *redactor) changedFiles(filename, root string) ([]byte, error) {
+	changedFiles, err := ParseChangedFiles(filename, root)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make([]string, len(changedFiles))
+	for i, f := range changedFiles {
+		redacted[i] = r.path(filepath.ToSlash(f))
+	}
+
+	return json.MarshalIndent(redacted, "", "  ")
+}
+
+// diff returns a unified diff that only contains the shape of the given diff,
+// i.e. the redacted file names and a hunk for each run of added lines without
+// any of the actual content.
+func (r *redactor) diff(filename string) ([]byte, error) {
+	diffInfo, err := ParseUnifiedDiff(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, fileName := range sortedKeys(diffInfo.Files) {
+		fd := diffInfo.Files[fileName]
+		redacted := r.path(fileName)
+		fmt.Fprintf(&buf, "--- a/%s\n", redacted)
+		fmt.Fprintf(&buf, "+++ b/%s\n", redacted)
+
+		lines := make([]int, 0, len(fd.AddedLines))
+		for line := range fd.AddedLines {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		for i := 0; i < len(lines); {
+			j := i + 1
+			for j < len(lines) && lines[j] == lines[j-1]+1 {
+				j++
+			}
+			fmt.Fprintf(&buf, "@@ -0,0 +%d,%d @@\n", lines[i], j-i)
+			buf.WriteString(strings.Repeat("+\n", j-i))
+			i = j
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeBundle writes the given files as gzip compressed tar archive to w.
+func writeBundle(w io.Writer, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	now := time.Now()
+	for _, name := range sortedKeys(files) {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(files[name])),
+			ModTime: now,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
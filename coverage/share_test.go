@@ -0,0 +1,109 @@
+package coverage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Bundle(t *testing.T) {
+	r, err := newRedactor("test-salt")
+	require.NoError(t, err)
+
+	files, err := r.bundle(
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		"github.com/pentohq/pento",
+		"testdata/04-diff.patch",
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeBundle(&buf, files))
+
+	dir := t.TempDir()
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "pento")
+		assert.NotContains(t, string(data), "age")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, hdr.Name), data, 0644))
+		names = append(names, hdr.Name)
+	}
+	assert.Equal(t, []string{bundleChangedFiles, bundleDiff, bundleNewCoverage, bundleOldCoverage}, names)
+
+	original := newTestReport(t,
+		"testdata/04-old-coverage.txt",
+		"testdata/04-new-coverage.txt",
+		"testdata/04-changed-files.json",
+		"github.com/pentohq/pento",
+		"testdata/04-diff.patch",
+	)
+	redacted := newTestReport(t,
+		filepath.Join(dir, bundleOldCoverage),
+		filepath.Join(dir, bundleNewCoverage),
+		filepath.Join(dir, bundleChangedFiles),
+		"",
+		filepath.Join(dir, bundleDiff),
+	)
+
+	totalNew, coveredNew := original.Analyze().calculateNewCodeCoverage()
+	require.NotZero(t, totalNew)
+	redactedTotalNew, redactedCoveredNew := redacted.Analyze().calculateNewCodeCoverage()
+	assert.Equal(t, totalNew, redactedTotalNew)
+	assert.Equal(t, coveredNew, redactedCoveredNew)
+	assert.Equal(t, original.Old.TotalStmt, redacted.Old.TotalStmt)
+	assert.Equal(t, original.New.CoveredStmt, redacted.New.CoveredStmt)
+}
+
+func TestRedactor_Path(t *testing.T) {
+	r, err := newRedactor("test-salt")
+	require.NoError(t, err)
+
+	full := r.path("github.com/acme/app/pkg/age/age.go")
+	relative := r.path("pkg/age/age.go")
+
+	assert.Regexp(t, `^[0-9a-f]{10}(/[0-9a-f]{10}){4}/[0-9a-f]{10}\.go$`, full)
+	assert.True(t, len(full) > len(relative) && full[len(full)-len(relative):] == relative,
+		"redacted relative path %q should be a suffix of %q", relative, full)
+	assert.Equal(t, full, r.path("github.com/acme/app/pkg/age/age.go"))
+
+	other, err := newRedactor("other-salt")
+	require.NoError(t, err)
+	assert.NotEqual(t, full, other.path("github.com/acme/app/pkg/age/age.go"))
+}
+
+func newTestReport(t *testing.T, oldCovPath, newCovPath, changedFilesPath, root, diffPath string) *Report {
+	t.Helper()
+
+	oldCov, err := ParseCoverage(oldCovPath)
+	require.NoError(t, err)
+	newCov, err := ParseCoverage(newCovPath)
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles(changedFilesPath, root)
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff(diffPath)
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+	report.DiffInfo = diffInfo
+	report.astMapper = nil // a bundle contains no source code
+	return report
+}
@@ -0,0 +1,35 @@
+package coverage
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// loadSigningKey reads the Ed25519 private key of -sign-key from a PEM file
+// in PKCS #8 format, e.g. as created by "openssl genpkey -algorithm ed25519".
+func loadSigningKey(fileName string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("invalid signing key %s: expected a PEM encoded PKCS #8 private key", fileName)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", fileName, err)
+	}
+
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid signing key %s: expected an Ed25519 key but got %T", fileName, key)
+	}
+
+	return ed, nil
+}
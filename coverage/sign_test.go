@@ -0,0 +1,81 @@
+package coverage
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePrivateKey writes the key as PEM encoded PKCS #8 private key.
+func writePrivateKey(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	fileName := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(fileName, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return fileName
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	loaded, err := loadSigningKey(writePrivateKey(t, key))
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = loadSigningKey(writePrivateKey(t, ecKey))
+	assert.ErrorContains(t, err, "expected an Ed25519 key but got *ecdsa.PrivateKey")
+
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("secret"), 0600))
+	_, err = loadSigningKey(notPEM)
+	assert.ErrorContains(t, err, "expected a PEM encoded PKCS #8 private key")
+}
+
+func TestRun_Signature(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "coverage.csv"))
+	require.NoError(t, err)
+	defer stdout.Close()
+
+	orig := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = orig }()
+
+	opts := options{
+		root:          "github.com/pentohq/pento",
+		format:        "csv",
+		signKey:       writePrivateKey(t, key),
+		signature:     filepath.Join(dir, "coverage.csv.sig"),
+		quiet:         true,
+		maxLineLength: defaultMaxLineLength,
+		sampleRate:    1,
+	}
+	require.NoError(t, run(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts))
+
+	report, err := os.ReadFile(stdout.Name())
+	require.NoError(t, err)
+	require.Contains(t, string(report), "github.com/pentohq/pento/pkg/age/age.go")
+	signature, err := os.ReadFile(opts.signature)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(public, report, signature))
+
+	opts.signKey = ""
+	assert.EqualError(t, run(context.Background(), "", "", "", opts), "-sign-key and -signature must be used together")
+}
@@ -0,0 +1,310 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var siteUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s site [OPTIONS]
+
+Generate a static website from the coverage history (see "history record") that
+shows the coverage trend of the project and of each package as well as the
+coverage of each file at the latest commit. The generated directory can be
+published as is, e.g. via GitHub Pages.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runSiteCommand(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("site", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, siteUsage)
+		fs.PrintDefaults()
+	}
+
+	historyFile := fs.String("history", "coverage-history.jsonl", "path to the history file")
+	output := fs.String("o", "coverage-site", "directory to write the website to")
+	branch := fs.String("branch", "", "only include snapshots of this branch")
+	title := fs.String("title", "Code Coverage", "title of the website")
+	report := fs.String("report", "", "optional HTML report of the latest commit (see -format=html) to include in the website")
+	pulls := fs.String("pulls", "", "optional glob pattern of JSON reports of pull requests (see -format=json) to list on a dashboard page")
+	pullURL := fs.String("pull-url", "", "optional URL to link pull requests on the dashboard, in which {number} is replaced by the number of the pull request, e.g. https://github.com/OWNER/REPO/pull/{number}")
+	_ = fs.Parse(args)
+
+	snapshots, err := OpenHistory(*historyFile).Snapshots()
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if *branch != "" {
+		var filtered []Snapshot
+		for _, s := range snapshots {
+			if s.Branch == *branch {
+				filtered = append(filtered, s)
+			}
+		}
+		snapshots = filtered
+	}
+
+	if len(snapshots) == 0 {
+		return errors.New("the history does not contain any snapshots")
+	}
+
+	var reportHTML []byte
+	if *report != "" {
+		reportHTML, err = os.ReadFile(*report)
+		if err != nil {
+			return fmt.Errorf("failed to read report: %w", err)
+		}
+	}
+
+	if err := generateSite(*output, *title, snapshots, reportHTML, *pulls != ""); err != nil {
+		return err
+	}
+
+	if *pulls == "" {
+		return nil
+	}
+
+	pullReports, err := readPullRequestReports(*pulls, *pullURL)
+	if err != nil {
+		return fmt.Errorf("failed to read pull request reports: %w", err)
+	}
+
+	return writeDashboard(*output, *title, pullReports)
+}
+
+// sitePage is the data that is passed to siteTemplate.
+type sitePage struct {
+	SiteTitle string
+	Title     string
+	Root      string // relative path from the page to the root of the site
+	Latest    Snapshot
+	Coverage  string
+	Report    bool // true if the site contains the report of the latest commit
+	Pulls     bool // true if the site contains the pull request dashboard
+	Chart     trendChart
+	RowKind   string
+	Rows      []siteRow
+}
+
+type siteRow struct {
+	Name     string
+	Link     string // empty if there is no page for the row
+	Coverage string
+	Delta    string
+	Total    int64
+	Covered  int64
+}
+
+type trendChart struct {
+	Width, Height int
+	Points        string // the points attribute of the SVG polyline
+	Dots          []trendDot
+}
+
+type trendDot struct {
+	X, Y  float64
+	Label string
+}
+
+// generateSite writes the static website for the given snapshots which must
+// be ordered by time to dir. If reportHTML is not empty, it is included as
+// report of the latest snapshot. If pulls is true, the index links to the
+// pull request dashboard (see writeDashboard).
+func generateSite(dir, title string, snapshots []Snapshot, reportHTML []byte, pulls bool) error {
+	latest := snapshots[len(snapshots)-1]
+	var previous *Snapshot
+	if len(snapshots) > 1 {
+		previous = &snapshots[len(snapshots)-2]
+	}
+
+	index := sitePage{
+		SiteTitle: title,
+		Title:     title,
+		Latest:    latest,
+		Coverage:  fmt.Sprintf("%.2f%%", latest.Percent()),
+		Report:    len(reportHTML) > 0,
+		Pulls:     pulls,
+		RowKind:   "Package",
+		Chart: newTrendChart(snapshots, func(s Snapshot) (StmtCount, bool) {
+			return StmtCount{Total: s.TotalStmt, Covered: s.CoveredStmt}, true
+		}),
+	}
+
+	for _, pkg := range sortedKeys(latest.Packages) {
+		index.Rows = append(index.Rows, newSiteRow(pkg, "packages/"+packageDir(pkg)+"/index.html", latest.Packages, previous, func(s *Snapshot) map[string]StmtCount { return s.Packages }))
+
+		page := sitePage{
+			SiteTitle: title,
+			Title:     pkg,
+			Root:      strings.Repeat("../", strings.Count(packageDir(pkg), "/")+2),
+			Latest:    latest,
+			Coverage:  fmt.Sprintf("%.2f%%", latest.Packages[pkg].Percent()),
+			RowKind:   "File",
+			Chart: newTrendChart(snapshots, func(s Snapshot) (StmtCount, bool) {
+				c, ok := s.Packages[pkg]
+				return c, ok
+			}),
+		}
+
+		for _, file := range sortedKeys(latest.Files) {
+			if path.Dir(file) == pkg {
+				page.Rows = append(page.Rows, newSiteRow(file, "", latest.Files, previous, func(s *Snapshot) map[string]StmtCount { return s.Files }))
+			}
+		}
+
+		err := writeSitePage(filepath.Join(dir, "packages", filepath.FromSlash(packageDir(pkg)), "index.html"), page)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writeSitePage(filepath.Join(dir, "index.html"), index); err != nil {
+		return err
+	}
+
+	if len(reportHTML) > 0 {
+		return os.WriteFile(filepath.Join(dir, "report.html"), reportHTML, 0644)
+	}
+
+	return nil
+}
+
+// packageDir returns the directory of the page of the given package relative
+// to the "packages" directory of the site.
+func packageDir(pkg string) string {
+	if pkg == "." || pkg == "" {
+		return "_root"
+	}
+
+	return pkg
+}
+
+func newSiteRow(name, link string, counts map[string]StmtCount, previous *Snapshot, previousCounts func(*Snapshot) map[string]StmtCount) siteRow {
+	c := counts[name]
+	row := siteRow{
+		Name:     name,
+		Link:     link,
+		Coverage: fmt.Sprintf("%.2f%%", c.Percent()),
+		Delta:    "new",
+		Total:    c.Total,
+		Covered:  c.Covered,
+	}
+
+	if previous != nil {
+		if prev, ok := previousCounts(previous)[name]; ok {
+			row.Delta = fmt.Sprintf("%+.2f%%", c.Percent()-prev.Percent())
+		}
+	}
+
+	return row
+}
+
+// newTrendChart returns an SVG line chart of the coverage of all snapshots
+// for which value returns true.
+func newTrendChart(snapshots []Snapshot, value func(Snapshot) (StmtCount, bool)) trendChart {
+	const width, height, padding = 640, 160, 8
+
+	var counts []StmtCount
+	var labels []string
+	for _, s := range snapshots {
+		c, ok := value(s)
+		if !ok {
+			continue
+		}
+		counts = append(counts, c)
+		labels = append(labels, fmt.Sprintf("%s (%s): %.2f%%", shortCommit(s.Commit), s.Time.Format("2006-01-02"), c.Percent()))
+	}
+
+	chart := trendChart{Width: width, Height: height}
+	var points []string
+	for i, c := range counts {
+		x := float64(width) / 2
+		if len(counts) > 1 {
+			x = padding + float64(i)*float64(width-2*padding)/float64(len(counts)-1)
+		}
+		y := padding + (100-c.Percent())*float64(height-2*padding)/100
+
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		chart.Dots = append(chart.Dots, trendDot{X: x, Y: y, Label: labels[i]})
+	}
+	chart.Points = strings.Join(points, " ")
+
+	return chart
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+
+	return commit
+}
+
+func writeSitePage(fileName string, page sitePage) error {
+	var buf bytes.Buffer
+	if err := siteTemplate.Execute(&buf, page); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, buf.Bytes(), 0644)
+}
+
+var siteTemplate = template.Must(template.New("site").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}{{ if ne .Title .SiteTitle }} - {{ .SiteTitle }}{{ end }}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 4px 12px; text-align: left; }
+td.num { text-align: right; }
+svg.trend { border: 1px solid #d0d7de; margin: 1em 0; }
+svg.trend polyline { fill: none; stroke: #0969da; stroke-width: 2; }
+svg.trend circle { fill: #0969da; }
+svg.trend line { stroke: #d0d7de; }
+.muted { color: #6e7781; }
+</style>
+</head>
+<body>
+{{- if .Root }}
+<p><a href="{{ .Root }}index.html">{{ .SiteTitle }}</a></p>
+{{- end }}
+<h1>{{ .Title }} <small>{{ .Coverage }}</small></h1>
+<p class="muted">Latest commit {{ .Latest.Commit }}{{ with .Latest.Branch }} on {{ . }}{{ end }} at {{ .Latest.Time.Format "2006-01-02 15:04 MST" }}
+{{- if .Report }} &middot; <a href="report.html">Latest report</a>{{ end }}
+{{- if .Pulls }} &middot; <a href="pulls.html">Pull requests</a>{{ end }}</p>
+{{- with .Chart }}
+<svg class="trend" width="{{ .Width }}" height="{{ .Height }}" viewBox="0 0 {{ .Width }} {{ .Height }}" role="img" aria-label="Coverage trend">
+<line x1="0" y1="{{ .Height }}" x2="{{ .Width }}" y2="{{ .Height }}"></line>
+<polyline points="{{ .Points }}"></polyline>
+{{- range .Dots }}
+<circle cx="{{ printf "%.1f" .X }}" cy="{{ printf "%.1f" .Y }}" r="3"><title>{{ .Label }}</title></circle>
+{{- end }}
+</svg>
+{{- end }}
+<table>
+<tr><th>{{ .RowKind }}</th><th>Coverage</th><th>Change</th><th>Covered</th><th>Total</th></tr>
+{{- range .Rows }}
+<tr><td>{{ if .Link }}<a href="{{ .Link }}">{{ .Name }}</a>{{ else }}{{ .Name }}{{ end }}</td><td class="num">{{ .Coverage }}</td><td class="num">{{ .Delta }}</td><td class="num">{{ .Covered }}</td><td class="num">{{ .Total }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,50 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSite(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+
+	snapshots := []Snapshot{
+		NewSnapshot(oldCov, "1111111111", "main", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		NewSnapshot(newCov, "2222222222", "main", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}
+
+	dir := t.TempDir()
+	err = generateSite(dir, "Prioqueue", snapshots, []byte("<html>report</html>"), false)
+	require.NoError(t, err)
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "<h1>Prioqueue <small>90.20%</small></h1>")
+	assert.Contains(t, string(index), `<a href="report.html">Latest report</a>`)
+	assert.Contains(t, string(index), `<polyline points="8.0,8.0 632.0,22.1">`)
+	assert.Contains(t, string(index), "<title>22222222 (2026-01-02): 90.20%</title>")
+	assert.Contains(t, string(index), `<a href="packages/github.com/fgrosse/prioqueue/index.html">github.com/fgrosse/prioqueue</a></td><td class="num">90.20%</td><td class="num">-9.80%</td>`)
+
+	pkg, err := os.ReadFile(filepath.Join(dir, "packages", "github.com", "fgrosse", "prioqueue", "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(pkg), `<a href="../../../../index.html">Prioqueue</a>`)
+	assert.Contains(t, string(pkg), "<td>github.com/fgrosse/prioqueue/min_heap.go</td>")
+	assert.Contains(t, string(pkg), "<td>github.com/fgrosse/prioqueue/max_heap.go</td>")
+
+	report, err := os.ReadFile(filepath.Join(dir, "report.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "<html>report</html>", string(report))
+}
+
+func TestPackageDir(t *testing.T) {
+	assert.Equal(t, "_root", packageDir("."))
+	assert.Equal(t, "example.com/foo", packageDir("example.com/foo"))
+}
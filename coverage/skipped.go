@@ -0,0 +1,213 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SkippedTest is a test that was skipped (e.g. via t.Skip or in -short mode)
+// according to the output of "go test -json".
+type SkippedTest struct {
+	Package string // import path of the package of the test
+	Test    string // name of the test, including subtests
+	Reason  string // output of the test, usually the message passed to t.Skip
+}
+
+// SkippedTestImpact describes a package with skipped tests that is relevant
+// for the report, either because the PR changes files of the package or
+// because the coverage of the package decreased.
+type SkippedTestImpact struct {
+	Package string
+	Tests   []SkippedTest
+	Files   []string // changed files of the package
+	Delta   float64  // change of the package coverage in percentage points
+}
+
+// testEvent is a single line of the output of "go test -json" (see "go doc
+// test2json").
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64 // seconds
+}
+
+// TestOutput is the information of the output of "go test -json" that is
+// used in the report.
+type TestOutput struct {
+	Skipped     []SkippedTest
+	Packages    []PackageTiming
+	Failed      []string // packages whose tests failed or did not build
+	NoTestFiles []string // packages without test files
+}
+
+// parseTestOutputFile reads the output of "go test -json" and returns all skipped
+// tests, the test duration of every package and the packages whose tests
+// failed or that have no tests. Lines that are not JSON (e.g.
+// build errors) are ignored.
+func parseTestOutputFile(ctx context.Context, fileName string, o parseOptions) (*TestOutput, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseTestOutput(contextReader{ctx: ctx, r: f}, o)
+}
+
+func parseTestOutput(r io.Reader, o parseOptions) (*TestOutput, error) {
+	type testKey struct{ pkg, test string }
+	output := map[testKey][]string{}
+	timings := newPackageTimings()
+
+	result := new(TestOutput)
+	scanner := newLineReader(r, o.maxLineLength)
+	for scanner.Scan() {
+		var e testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		timings.add(e)
+		if e.Test == "" {
+			// Events without a test belong to the package as a whole. A
+			// skipped package simply has no test files.
+			switch e.Action {
+			case "fail":
+				result.Failed = append(result.Failed, e.Package)
+			case "skip":
+				result.NoTestFiles = append(result.NoTestFiles, e.Package)
+			}
+			continue
+		}
+
+		key := testKey{e.Package, e.Test}
+		switch e.Action {
+		case "output":
+			if line := skipReasonLine(e.Output); line != "" {
+				output[key] = append(output[key], line)
+			}
+		case "skip":
+			result.Skipped = append(result.Skipped, SkippedTest{
+				Package: e.Package,
+				Test:    e.Test,
+				Reason:  strings.Join(output[key], " "),
+			})
+			delete(output, key)
+		case "pass", "fail":
+			delete(output, key)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Packages = timings.result()
+	return result, nil
+}
+
+// skipReasonLine returns the given output line of a test unless it is one of
+// the status lines that are printed by "go test -v" for every test.
+func skipReasonLine(line string) string {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"=== ", "--- "} {
+		if strings.HasPrefix(line, prefix) {
+			return ""
+		}
+	}
+
+	return line
+}
+
+// SkippedTestImpacts returns all packages with skipped tests whose files are
+// changed by the PR or whose coverage decreased. In both cases the skipped
+// tests may explain coverage that is lower than expected.
+func (r *Report) SkippedTestImpacts() []SkippedTestImpact {
+	if len(r.SkippedTests) == 0 {
+		return nil
+	}
+
+	byPackage := map[string][]SkippedTest{}
+	for _, t := range r.SkippedTests {
+		byPackage[t.Package] = append(byPackage[t.Package], t)
+	}
+
+	oldCovPkgs := r.Old.ByPackage()
+	newCovPkgs := r.New.ByPackage()
+
+	var result []SkippedTestImpact
+	for pkg, tests := range byPackage {
+		impact := SkippedTestImpact{Package: pkg, Tests: tests}
+		for _, f := range r.ChangedFiles {
+			if path.Dir(f) == pkg {
+				impact.Files = append(impact.Files, f)
+			}
+		}
+
+		oldCov, newCov := oldCovPkgs[pkg], newCovPkgs[pkg]
+		if oldCov != nil && newCov != nil {
+			impact.Delta = newCov.Percent() - oldCov.Percent()
+		}
+
+		if len(impact.Files) > 0 || impact.Delta < 0 {
+			result = append(result, impact)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Package < result[j].Package
+	})
+
+	return result
+}
+
+// addSkippedTestsDetails lists the skipped tests of all packages returned by
+// SkippedTestImpacts.
+func (r *Report) addSkippedTestsDetails(report *strings.Builder) {
+	impacts := r.SkippedTestImpacts()
+	if len(impacts) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, "---")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, r.detailsTag(foldSkippedTests, func() bool {
+		return slices.ContainsFunc(impacts, func(i SkippedTestImpact) bool { return i.Delta < 0 })
+	}))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Skipped Tests</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The following tests were skipped (e.g. via `t.Skip` or in `-short` mode). Code that is only covered by these tests is reported as uncovered.")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| Package | Coverage Δ | Changed Files | Skipped Test | Reason |")
+	fmt.Fprintln(report, "|---------|------------|---------------|--------------|--------|")
+
+	for _, impact := range impacts {
+		files := "-"
+		if len(impact.Files) > 0 {
+			files = codeList(baseNames(impact.Files))
+		}
+
+		for _, t := range impact.Tests {
+			reason := "-"
+			if t.Reason != "" {
+				reason = strings.ReplaceAll(t.Reason, "|", `\|`)
+			}
+
+			fmt.Fprintf(report, "| %s | %s | %s | `%s` | %s |\n", impact.Package, r.numbers().Delta(impact.Delta), files, t.Test, reason)
+		}
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
@@ -0,0 +1,81 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSkippedTests(t *testing.T) {
+	input := strings.TrimSpace(`
+{"Action":"start","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestSlow"}
+{"Action":"output","Package":"example.com/a","Test":"TestSlow","Output":"=== RUN   TestSlow\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestSlow","Output":"    a_test.go:12: skipping in short mode\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestSlow","Output":"--- SKIP: TestSlow (0.00s)\n"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSlow","Elapsed":0}
+{"Action":"run","Package":"example.com/a","Test":"TestFast"}
+{"Action":"output","Package":"example.com/a","Test":"TestFast","Output":"    a_test.go:20: some log output\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestFast","Elapsed":0}
+# example.com/broken
+{"Action":"skip","Package":"example.com/b","Test":"TestNoReason/sub","Elapsed":0}
+{"Action":"skip","Package":"example.com/c","Elapsed":0}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0}
+`)
+
+	out, err := parseTestOutput(strings.NewReader(input), parseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []SkippedTest{
+		{Package: "example.com/a", Test: "TestSlow", Reason: "a_test.go:12: skipping in short mode"},
+		{Package: "example.com/b", Test: "TestNoReason/sub"},
+	}, out.Skipped)
+	assert.Equal(t, []string{"example.com/broken"}, out.Failed)
+	assert.Equal(t, []string{"example.com/c"}, out.NoTestFiles)
+}
+
+func TestReport_SkippedTestImpacts(t *testing.T) {
+	profile := func(fileName string, count int) *Profile {
+		return &Profile{
+			FileName:    fileName,
+			Mode:        "set",
+			Blocks:      []ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 5, EndCol: 2, NumStmt: 3, Count: count}},
+			TotalStmt:   3,
+			CoveredStmt: int64(min(count, 1) * 3),
+		}
+	}
+
+	oldCov := New([]*Profile{
+		profile("example.com/a/a.go", 1),
+		profile("example.com/b/b.go", 1),
+		profile("example.com/c/c.go", 1),
+	})
+	newCov := New([]*Profile{
+		profile("example.com/a/a.go", 1),
+		profile("example.com/b/b.go", 0), // unchanged but coverage dropped
+		profile("example.com/c/c.go", 1),
+	})
+
+	report := NewReport(oldCov, newCov, []string{"example.com/a/a.go"})
+	report.astMapper = nil
+	report.SkippedTests = []SkippedTest{
+		{Package: "example.com/a", Test: "TestA", Reason: "flaky | see #12"},
+		{Package: "example.com/b", Test: "TestB"},
+		{Package: "example.com/c", Test: "TestC"}, // neither changed nor decreased
+	}
+
+	impacts := report.SkippedTestImpacts()
+	require.Len(t, impacts, 2)
+	assert.Equal(t, "example.com/a", impacts[0].Package)
+	assert.Equal(t, []string{"example.com/a/a.go"}, impacts[0].Files)
+	assert.Equal(t, "example.com/b", impacts[1].Package)
+	assert.Empty(t, impacts[1].Files)
+	assert.Equal(t, -100.0, impacts[1].Delta)
+
+	markdown := report.Analyze().Markdown()
+	assert.Contains(t, markdown, "<summary>Skipped Tests</summary>")
+	assert.Contains(t, markdown, "| example.com/a | +0.00% | `a.go` | `TestA` | flaky \\| see #12 |\n")
+	assert.Contains(t, markdown, "| example.com/b | -100.00% | - | `TestB` | - |\n")
+	assert.NotContains(t, markdown, "TestC")
+}
@@ -0,0 +1,146 @@
+package coverage
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sourceTree locates and reads the source code of the files of coverage
+// profiles, which is needed to render new code and to match code blocks. The
+// zero value searches relative to the working directory.
+type sourceTree struct {
+	// root is the directory of the repository (see -repo-root). If it is
+	// set, no files outside of it are read.
+	root string
+
+	maxLineLength int    // see -max-line-length and lineLimit
+	fetched       string // directory of the fetched source files, if any (see fetchMissingSources)
+}
+
+// majorVersionDir matches the last element of import paths of major versions
+// (e.g. "github.com/user/repo/v2"), whose package is named after its parent.
+var majorVersionDir = regexp.MustCompile(`^v[0-9]+$`)
+
+// newSourceTree returns the sourceTree of the -repo-root in the options.
+func newSourceTree(opts options) (sourceTree, error) {
+	if opts.repoRoot == "" {
+		return sourceTree{maxLineLength: opts.maxLineLength}, nil
+	}
+
+	root, err := filepath.Abs(opts.repoRoot)
+	if err != nil {
+		return sourceTree{}, fmt.Errorf("invalid repository root: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return sourceTree{}, fmt.Errorf("repository root %s is not a directory", opts.repoRoot)
+	}
+
+	return sourceTree{root: root, maxLineLength: opts.maxLineLength}, nil
+}
+
+// find returns the first existing path from sourcePathCandidates whose
+// package clause matches the package of the file (see packageClauseMatches).
+// If the root is set, the candidates are resolved relative to it and paths
+// outside of it are never returned. If the file is not available locally, its
+// fetched source is returned (see -fetch-source).
+func (t sourceTree) find(fileName string) (string, bool) {
+	for _, path := range sourcePathCandidates(fileName) {
+		path, ok := t.resolve(path)
+		if !ok {
+			continue
+		}
+
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && packageClauseMatches(path, fileName) {
+			return path, true
+		}
+	}
+
+	return t.fetchedPath(fileName)
+}
+
+// readLines reads the lines of the source file of a coverage profile file and
+// returns them by line number.
+func (t sourceTree) readLines(fileName string) (map[int]string, error) {
+	path, ok := t.find(fileName)
+	if !ok {
+		return nil, fmt.Errorf("source of %s not found: %w", fileName, os.ErrNotExist)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return t.scanLines(file)
+}
+
+// resolve returns the path at which a candidate of sourcePathCandidates is
+// read. Without a root, the candidate is returned as is. Otherwise relative
+// candidates are resolved relative to the root and false is returned if the
+// path (after following symlinks) is outside of it.
+func (t sourceTree) resolve(candidate string) (string, bool) {
+	if t.root == "" {
+		return candidate, true
+	}
+
+	p := candidate
+	if !filepath.IsAbs(p) {
+		if !filepath.IsLocal(p) {
+			return "", false
+		}
+		p = filepath.Join(t.root, p)
+	}
+
+	root, err := filepath.EvalSymlinks(t.root)
+	if err != nil {
+		return "", false
+	}
+
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", false
+	}
+
+	if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+
+	return p, true
+}
+
+// packageClauseMatches returns whether the package clause of the Go source
+// file at the given path is consistent with the import path of the file in
+// the coverage profile. This prevents attributing the lines of an unrelated
+// file that merely has the same path suffix. Files whose package clause
+// cannot be parsed and packages whose directory is not a valid identifier
+// (e.g. "go-coverage-report") are accepted.
+func packageClauseMatches(filePath, fileName string) bool {
+	if !strings.HasSuffix(fileName, ".go") {
+		return true
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.PackageClauseOnly)
+	if err != nil || f.Name == nil {
+		return true
+	}
+
+	dir := path.Dir(filepath.ToSlash(fileName))
+	if majorVersionDir.MatchString(path.Base(dir)) {
+		dir = path.Dir(dir)
+	}
+
+	expected := path.Base(dir)
+	if dir == "." || !token.IsIdentifier(expected) {
+		return true
+	}
+
+	name := f.Name.Name
+	return name == "main" || name == expected || name == expected+"_test"
+}
@@ -0,0 +1,90 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceTree_RepoRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "repo")
+	writeFile := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0644))
+	}
+
+	writeFile(filepath.Join(root, "pkg", "util", "util.go"), "package util\n")
+	writeFile(filepath.Join(dir, "secret", "util", "util.go"), "package util\n")
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret"), filepath.Join(root, "link")))
+
+	tree := sourceTree{root: root}
+
+	path, ok := tree.find("example.com/app/pkg/util/util.go")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "pkg", "util", "util.go"), path)
+
+	// Paths that escape the repository root are never read.
+	_, ok = tree.find("../secret/util/util.go")
+	assert.False(t, ok)
+	_, ok = tree.find(filepath.Join(dir, "secret", "util", "util.go"))
+	assert.False(t, ok)
+	_, ok = tree.find("example.com/app/link/util/util.go")
+	assert.False(t, ok, "symlinks must not escape the repository root")
+
+	_, err := tree.readLines("example.com/app/missing.go")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSourceTree_PackageClause(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0644))
+	}
+
+	// An unrelated file with the same path suffix must not be attributed.
+	writeFile(filepath.Join(root, "example.com", "other", "config", "config.go"), "package settings\n")
+	writeFile(filepath.Join(root, "config", "config.go"), "package config\n")
+
+	tree := sourceTree{root: root}
+
+	path, ok := tree.find("example.com/other/config/config.go")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "config", "config.go"), path)
+}
+
+func TestPackageClauseMatches(t *testing.T) {
+	dir := t.TempDir()
+	file := func(content string) string {
+		t.Helper()
+		name := filepath.Join(dir, t.Name()+".go")
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0644))
+		return name
+	}
+
+	cases := map[string]struct {
+		content, fileName string
+		expected          bool
+	}{
+		"same name":      {"package util\n", "example.com/app/util/a.go", true},
+		"external test":  {"package util_test\n", "example.com/app/util/a_test.go", true},
+		"main":           {"// Command app.\npackage main\n", "example.com/app/cmd/app/main.go", true},
+		"major version":  {"package app\n", "example.com/app/v2/a.go", true},
+		"no identifier":  {"package report\n", "example.com/go-report/a.go", true},
+		"invalid source": {"not go", "example.com/app/util/a.go", true},
+		"other package":  {"package other\n", "example.com/app/util/a.go", false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, packageClauseMatches(file(c.content), c.fileName))
+		})
+	}
+}
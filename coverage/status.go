@@ -0,0 +1,84 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// defaultStatusTemplate renders the short summary of a report that is used as
+// description of commit statuses and as title of check runs.
+const defaultStatusTemplate = `Coverage {{ printf "%.2f" .Coverage }}% ({{ printf "%+.2f" .Delta }}%)` +
+	`{{ if .NewStatements }}, new code {{ printf "%.2f" .NewCode }}%{{ end }}`
+
+// statusData is the data of the status template.
+type statusData struct {
+	Coverage      float64 // overall coverage in percent
+	OldCoverage   float64 // overall coverage of the target branch in percent
+	Delta         float64 // change of the overall coverage in percentage points
+	NewCode       float64 // coverage of the new code in percent
+	NewStatements int64   // number of new statements
+	Grade         string  // letter grade if grading is enabled
+	Passed        bool    // whether the coverage checks passed
+}
+
+// parseStatusTemplate parses the text template of the short status summary.
+func parseStatusTemplate(text string) (*template.Template, error) {
+	return template.New("status").Option("missingkey=error").Parse(text)
+}
+
+// statusSummary returns the short summary of the report rendered with the
+// given status template.
+func (r *Result) statusSummary(text string, passed bool) (string, error) {
+	if text == "" {
+		text = defaultStatusTemplate
+	}
+
+	tmpl, err := parseStatusTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid status template: %w", err)
+	}
+
+	_, _, totalNew, coveredNew := r.PRCoverageInfo()
+
+	data := statusData{
+		Coverage:      r.New.Percent(),
+		OldCoverage:   r.Old.Percent(),
+		Delta:         r.OverallCoverageDelta(),
+		NewStatements: totalNew,
+		Passed:        passed,
+	}
+	if totalNew > 0 {
+		data.NewCode = float64(coveredNew) / float64(totalNew) * 100
+	}
+	if r.Grade != nil {
+		data.Grade = r.Grade.Letter
+	}
+
+	var summary strings.Builder
+	if err := tmpl.Execute(&summary, data); err != nil {
+		return "", fmt.Errorf("invalid status template: %w", err)
+	}
+
+	return strings.TrimSpace(summary.String()), nil
+}
+
+// createGateCheck reports the result of the coverage checks as completed check
+// run with the configured name on the given commit.
+func (a *action) createGateCheck(ctx context.Context, sha string, result *Result, checkErr error) error {
+	title, err := result.statusSummary(a.cfg.StatusTemplate, checkErr == nil)
+	if err != nil {
+		return err
+	}
+
+	conclusion := "success"
+	summary := result.CondensedMarkdown()
+	if checkErr != nil {
+		conclusion = "failure"
+		summary = fmt.Sprintf("%s\n\n%s", checkErr, summary)
+	}
+
+	fmt.Fprintf(a.out, "Creating check run %q on commit %s: %s\n", a.cfg.CheckName, shortCommit(sha), title)
+	return a.gh.createCheckRun(ctx, a.cfg.CheckName, sha, conclusion, title, summary)
+}
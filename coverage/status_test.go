@@ -0,0 +1,31 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_StatusSummary(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, changedFiles)
+
+	summary, err := report.Analyze().statusSummary("", true)
+	require.NoError(t, err)
+	assert.Equal(t, "Coverage 90.20% (-9.80%), new code 85.71%", summary)
+
+	report.Graded = true
+	summary, err = report.Analyze().statusSummary(`{{ if not .Passed }}FAILED {{ end }}{{ printf "%.0f" .OldCoverage }}% → {{ printf "%.0f" .Coverage }}% ({{ .Grade }})`, false)
+	require.NoError(t, err)
+	assert.Equal(t, "FAILED 100% → 90% ("+report.Analyze().ComputeGrade().Letter+")", summary)
+
+	_, err = report.Analyze().statusSummary("{{ .Unknown }}", true)
+	assert.ErrorContains(t, err, "invalid status template")
+}
@@ -0,0 +1,82 @@
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// StrictErrors returns an error for every heuristic fallback that the analysis
+// of the changed files would silently use to approximate the coverage of the
+// new code (see -strict):
+//
+//   - the source code of a file cannot be read, so that exclusions, waivers
+//     and the statements on changed lines cannot be determined,
+//   - a file of the coverage is only matched by path suffix to a file of the
+//     diff instead of by its path relative to the root package,
+//   - the diff has no added lines of a file, so that all of its statements
+//     would be counted as new,
+//   - the statements of a block on changed lines cannot be determined via the
+//     AST and would be estimated from the proportion of changed lines,
+//   - the old source code of a file is not available at -base-ref, so that
+//     moved code would be matched by position instead of by content.
+//
+// It returns nil if the report is exact.
+func (r *Report) StrictErrors() error {
+	var errs []error
+	for _, fileName := range r.ChangedFiles {
+		if err := r.strictFileErrors(fileName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Report) strictFileErrors(fileName string) error {
+	newProfile := r.newProfile(fileName)
+	if newProfile == nil {
+		return nil // file was deleted or has no coverage data
+	}
+
+	if _, err := r.source.readLines(fileName); err != nil {
+		return fmt.Errorf("%s: cannot read the source code: %w", fileName, err)
+	}
+
+	oldProfile := r.Old.Files[fileName]
+	if oldProfile == nil {
+		return nil // all statements of new files are new
+	}
+
+	if r.DiffInfo == nil {
+		if r.BaseRef == "" {
+			return nil
+		}
+		if _, _, ok := r.blockContentKeys(fileName, oldProfile.Blocks, newProfile.Blocks); !ok {
+			return fmt.Errorf("%s: the source code at %s is not available or does not match the old coverage, so code blocks would be matched by position", fileName, r.BaseRef)
+		}
+		return nil
+	}
+
+	fileDiff, diffPath := r.DiffInfo.matchFileDiff(fileName)
+	switch {
+	case fileDiff == nil:
+		return fmt.Errorf("%s: the file is missing in the diff, so all of its statements would be counted as new", fileName)
+	case diffPath != fileName && path.Join(r.RootPackage, diffPath) != fileName:
+		return fmt.Errorf("%s: the file is only matched by path suffix to %s of the diff (paths of the diff must be relative to -root)", fileName, diffPath)
+	case len(fileDiff.AddedLines) == 0:
+		return fmt.Errorf("%s: the diff has no added lines of the file, so all of its statements would be counted as new", fileName)
+	}
+
+	var errs []error
+	for _, block := range newProfile.Blocks {
+		if len(fileDiff.changedLinesInRange(block.StartLine, block.EndLine)) == 0 {
+			continue
+		}
+		if count, _ := r.countStatementsInBlockUsingAST(fileName, block, fileDiff); count < 0 {
+			errs = append(errs, fmt.Errorf("%s:%d-%d: the changed statements of the block cannot be determined and would be estimated from the proportion of changed lines", fileName, block.StartLine, block.EndLine))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,80 @@
+package coverage
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStrictTestReport(t *testing.T) *Report {
+	t.Helper()
+
+	oldCov, err := ParseCoverage("testdata/04-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, []string{"github.com/pentohq/pento/pkg/age/age.go"})
+	report.DiffInfo = diffInfo
+	report.RootPackage = "github.com/pentohq/pento"
+
+	return report
+}
+
+func TestReport_StrictErrors(t *testing.T) {
+	report := newStrictTestReport(t)
+	assert.NoError(t, report.StrictErrors())
+
+	report = newStrictTestReport(t)
+	report.RootPackage = ""
+	assert.ErrorContains(t, report.StrictErrors(), "only matched by path suffix to pkg/age/age.go")
+
+	report = newStrictTestReport(t)
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{}}
+	assert.ErrorContains(t, report.StrictErrors(), "missing in the diff")
+
+	report = newStrictTestReport(t)
+	report.DiffInfo.Files["pkg/age/age.go"].AddedLines = map[int]bool{}
+	assert.ErrorContains(t, report.StrictErrors(), "has no added lines")
+
+	report = newStrictTestReport(t)
+	report.astMapper = nil
+	assert.ErrorContains(t, report.StrictErrors(), "estimated from the proportion of changed lines")
+
+	report = newStrictTestReport(t)
+	report.source = sourceTree{root: t.TempDir()}
+	assert.ErrorContains(t, report.StrictErrors(), "cannot read the source code")
+}
+
+func TestReport_StrictErrors_NewFile(t *testing.T) {
+	report := newStrictTestReport(t)
+	report.Old = New(nil)
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{}}
+
+	// All statements of new files are new, so the diff is not needed.
+	assert.NoError(t, report.StrictErrors())
+}
+
+func TestLoadReport_Strict(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-strict", "-diff=testdata/04-diff.patch"}))
+	opts := optionsFromFlags(fs)
+	assert.True(t, opts.strict)
+
+	load := func() error {
+		_, err := loadReport(context.Background(), "testdata/04-old-coverage.txt", "testdata/04-new-coverage.txt", "testdata/04-changed-files.json", opts)
+		return err
+	}
+
+	opts.root = "github.com/pentohq/pento"
+	assert.NoError(t, load())
+
+	opts.diffFile = "testdata/01-diff.patch"
+	assert.ErrorContains(t, load(), "strict mode: github.com/pentohq/pento/pkg/age/age.go: the file is missing in the diff")
+}
@@ -0,0 +1,119 @@
+package coverage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Parameters of CoverageTargets.
+const (
+	minTargetSamples  = 5 // fewer similar packages are no meaningful comparison
+	similarSizeFactor = 2 // similar packages have between 1/factor and factor times as many statements
+)
+
+// CoverageTarget compares the coverage of a changed package to the coverage
+// of packages of a similar size in the repository.
+type CoverageTarget struct {
+	Package    string
+	Coverage   float64
+	Statements int64
+	Similar    int     // number of packages of a similar size
+	Average    float64 // average coverage of the similar packages
+	Median     float64 // median coverage of the similar packages
+	P75        float64 // 75th percentile of the coverage of the similar packages
+	Target     float64 // suggested coverage target
+}
+
+// CoverageTargets suggests coverage targets for the changed packages whose
+// coverage is below the 75th percentile of packages of a similar size: the
+// median if the package is below it, the 75th percentile otherwise. Packages
+// with fewer than minTargetSamples similar packages are skipped.
+func (r *Report) CoverageTargets() []CoverageTarget {
+	pkgCov := r.New.ByPackage()
+
+	var targets []CoverageTarget
+	for _, pkg := range r.ChangedPackages {
+		c := pkgCov[pkg]
+		if c == nil || c.TotalStmt == 0 {
+			continue
+		}
+
+		var similar []float64
+		for name, other := range pkgCov {
+			if name != pkg && other.TotalStmt*similarSizeFactor >= c.TotalStmt && other.TotalStmt <= c.TotalStmt*similarSizeFactor {
+				similar = append(similar, other.Percent())
+			}
+		}
+		if len(similar) < minTargetSamples {
+			continue
+		}
+		sort.Float64s(similar)
+
+		t := CoverageTarget{
+			Package:    pkg,
+			Coverage:   c.Percent(),
+			Statements: c.TotalStmt,
+			Similar:    len(similar),
+			Median:     percentile(similar, 50),
+			P75:        percentile(similar, 75),
+		}
+		for _, p := range similar {
+			t.Average += p
+		}
+		t.Average /= float64(len(similar))
+
+		switch {
+		case t.Coverage < t.Median:
+			t.Target = math.Floor(t.Median)
+		case t.Coverage < t.P75:
+			t.Target = math.Floor(t.P75)
+		default:
+			continue
+		}
+		targets = append(targets, t)
+	}
+
+	return targets
+}
+
+// percentile returns the p-th percentile of the sorted values using linear
+// interpolation between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func (r *Report) addCoverageTargets(report *strings.Builder) {
+	targets := r.CoverageTargets()
+	if len(targets) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldTargets, func() bool { return false }))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Coverage Targets</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintf(report, "The changed packages below are covered less than other packages of a similar size (between 1/%d and %d times as many statements). The suggested targets are based on the median and the 75th percentile of these packages.\n", similarSizeFactor, similarSizeFactor)
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| Package | Statements | Coverage | Similar Packages | Average | Median | P75 | Suggested Target |")
+	fmt.Fprintln(report, "|---------|------------|----------|------------------|---------|--------|-----|------------------|")
+
+	n := r.numbers()
+	for _, t := range targets {
+		fmt.Fprintf(report, "| %s | %s | %s | %d | %s | %s | %s | %.0f%% |\n",
+			t.Package, n.Count(int64(t.Statements)), n.Percent(t.Coverage), t.Similar, n.Percent(t.Average), n.Percent(t.Median), n.Percent(t.P75), t.Target)
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
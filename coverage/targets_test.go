@@ -0,0 +1,49 @@
+package coverage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{10, 20, 30, 40, 50}
+	assert.Equal(t, 30.0, percentile(values, 50))
+	assert.Equal(t, 40.0, percentile(values, 75))
+	assert.Equal(t, 25.0, percentile([]float64{20, 30}, 50))
+	assert.Equal(t, 0.0, percentile(nil, 50))
+}
+
+func TestReport_CoverageTargets(t *testing.T) {
+	profiles := []*Profile{
+		{FileName: "example.com/app/changed/a.go", TotalStmt: 100, CoveredStmt: 42},
+		{FileName: "example.com/app/good/a.go", TotalStmt: 100, CoveredStmt: 95},
+		{FileName: "example.com/app/tiny/a.go", TotalStmt: 10, CoveredStmt: 10},
+		{FileName: "example.com/app/huge/a.go", TotalStmt: 1000, CoveredStmt: 0},
+	}
+	for i, covered := range []int64{60, 65, 70, 75, 80, 90} {
+		profiles = append(profiles, &Profile{FileName: fmt.Sprintf("example.com/app/p%d/a.go", i), TotalStmt: 100, CoveredStmt: covered})
+	}
+
+	report := NewReport(New(nil), New(profiles), []string{"example.com/app/changed/a.go", "example.com/app/good/a.go", "example.com/app/tiny/a.go"})
+	targets := report.CoverageTargets()
+	require.Len(t, targets, 1)
+
+	target := targets[0]
+	assert.Equal(t, "example.com/app/changed", target.Package)
+	assert.Equal(t, 42.0, target.Coverage)
+	assert.Equal(t, 7, target.Similar)
+	assert.Equal(t, 75.0, target.Median)
+	assert.Equal(t, 85.0, target.P75)
+	assert.Equal(t, 75.0, target.Target)
+
+	report.Config = &Config{Fold: map[string]string{foldTargets: foldOpen}}
+	var md strings.Builder
+	report.addCoverageTargets(&md)
+	assert.Contains(t, md.String(), "<details open>")
+	assert.Contains(t, md.String(), "| example.com/app/changed | 100 | 42.00% | 7 |")
+	assert.Contains(t, md.String(), "| 75% |\n")
+}
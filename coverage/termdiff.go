@@ -0,0 +1,335 @@
+package coverage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences of the term-diff format.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+)
+
+// termDiffContext is the number of unchanged lines that are shown around
+// each change.
+const termDiffContext = 3
+
+// maxDiffEdits limits the work of diffLines. Files with more changed lines are
+// shown as completely replaced.
+const maxDiffEdits = 2000
+
+// termDiffRow is a single row of the side-by-side view. A line number of 0
+// means that the side is empty.
+type termDiffRow struct {
+	oldNum, newNum   int
+	oldText, newText string
+	changed          bool
+}
+
+// TermDiff renders the changed files side-by-side (old on the left, new on
+// the right) for a terminal of the given width. Only the changes and a few
+// lines of context are shown. Lines with statements are colored by their
+// coverage, or marked with ✓ and ✗ if color is false. The old source code is
+// read via -base-ref. Without it, the new side is shown on its own.
+func (r *Result) TermDiff(width int, color bool) string {
+	var out strings.Builder
+
+	paint := func(code, s string) string {
+		if !color || code == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	fileBlocks := map[string][]NewCodeBlock{}
+	for _, block := range r.getNewCodeBlocks() {
+		fileBlocks[block.FileName] = append(fileBlocks[block.FileName], block)
+	}
+
+	side := max((width-3)/2, 24)
+	for _, name := range r.ChangedFiles {
+		newProfile := r.New.Files[name]
+		if newProfile == nil || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		oldProfile := r.Old.Files[name]
+		fmt.Fprintf(&out, "%s %s → %s\n", paint(ansiBold, name), r.numbers().Percent(oldProfile.CoveragePercent()), r.numbers().Percent(newProfile.CoveragePercent()))
+
+		newSource, err := r.source.readLines(name)
+		if err != nil {
+			fmt.Fprintf(&out, "%s\n\n", paint(ansiDim, "source code not found"))
+			continue
+		}
+
+		oldSource, _ := r.readOldSourceLines(name)
+		if oldSource == nil {
+			fmt.Fprintln(&out, paint(ansiDim, "old source code not available (see -base-ref)"))
+		}
+
+		oldCoverage := map[int]bool{}
+		if oldProfile != nil {
+			oldCoverage = lineCoverage(oldProfile, len(oldSource))
+		}
+
+		// The coverage of new lines is determined like in the markdown report.
+		newCoverage := lineCoverage(newProfile, len(newSource))
+		newLines := r.newLineCoverage(name, newSource, fileBlocks[name])
+		for line, covered := range newLines {
+			newCoverage[line] = covered
+		}
+
+		cell := func(num int, text string, marker byte, coverage map[int]bool) string {
+			if num == 0 {
+				return strings.Repeat(" ", side)
+			}
+
+			status, code := " ", ""
+			if covered, ok := coverage[num]; ok {
+				status, code = "✗", ansiRed
+				if covered {
+					status, code = "✓", ansiGreen
+				}
+			}
+			if color {
+				status = " " // the color shows the coverage
+			}
+
+			prefix := fmt.Sprintf("%4d %c%s ", num, marker, status)
+			return paint(code, prefix+fitColumn(text, side-utf8.RuneCountInString(prefix)))
+		}
+
+		var rows []termDiffRow
+		if oldSource == nil {
+			for i, text := range sourceSlice(newSource) {
+				_, isNew := newLines[i+1]
+				rows = append(rows, termDiffRow{newNum: i + 1, newText: text, changed: isNew})
+			}
+		} else {
+			rows = termDiffRows(sourceSlice(oldSource), sourceSlice(newSource))
+		}
+
+		visible := make([]bool, len(rows))
+		for i, row := range rows {
+			if row.changed {
+				for j := max(i-termDiffContext, 0); j <= min(i+termDiffContext, len(rows)-1); j++ {
+					visible[j] = true
+				}
+			}
+		}
+
+		shown, gap := false, false
+		for i, row := range rows {
+			if !visible[i] {
+				gap = shown
+				continue
+			}
+			if gap {
+				fmt.Fprintln(&out, paint(ansiDim, "···"))
+				gap = false
+			}
+			shown = true
+
+			oldMarker, newMarker := byte(' '), byte(' ')
+			if row.changed {
+				oldMarker, newMarker = '-', '+'
+			}
+			fmt.Fprintf(&out, "%s │ %s\n", cell(row.oldNum, row.oldText, oldMarker, oldCoverage), cell(row.newNum, row.newText, newMarker, newCoverage))
+		}
+
+		if !shown {
+			fmt.Fprintln(&out, paint(ansiDim, "no changed lines"))
+		}
+		fmt.Fprintln(&out)
+	}
+
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// termDiffRows aligns the lines of the old and new version of a file. Deleted
+// and inserted lines between two unchanged lines are shown next to each other.
+func termDiffRows(oldLines, newLines []string) []termDiffRow {
+	var rows []termDiffRow
+	var deleted, inserted []int
+	flush := func() {
+		for i := 0; i < max(len(deleted), len(inserted)); i++ {
+			row := termDiffRow{changed: true}
+			if i < len(deleted) {
+				row.oldNum, row.oldText = deleted[i]+1, oldLines[deleted[i]]
+			}
+			if i < len(inserted) {
+				row.newNum, row.newText = inserted[i]+1, newLines[inserted[i]]
+			}
+			rows = append(rows, row)
+		}
+		deleted, inserted = deleted[:0], inserted[:0]
+	}
+
+	for _, op := range diffLines(oldLines, newLines) {
+		switch op.kind {
+		case '-':
+			deleted = append(deleted, op.old)
+		case '+':
+			inserted = append(inserted, op.new)
+		default:
+			flush()
+			rows = append(rows, termDiffRow{oldNum: op.old + 1, newNum: op.new + 1, oldText: oldLines[op.old], newText: newLines[op.new]})
+		}
+	}
+	flush()
+
+	return rows
+}
+
+// diffOp is a single edit of diffLines: '=' if line old of a equals line new
+// of b, '-' if line old of a was deleted and '+' if line new of b was inserted.
+type diffOp struct {
+	kind     byte
+	old, new int
+}
+
+// diffLines returns the shortest edit script from a to b using the algorithm
+// of Myers ("An O(ND) Difference Algorithm and Its Variations"). If more than
+// maxDiffEdits edits are needed, all lines are treated as replaced.
+func diffLines(a, b []string) []diffOp {
+	// Common prefixes and suffixes are cheap to match up front.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{'=', i, i})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := suffix; i > 0; i-- {
+		ops = append(ops, diffOp{'=', len(a) - i, len(b) - i})
+	}
+
+	return ops
+}
+
+func myersDiff(a, b []string, offset int) []diffOp {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxDiffEdits)
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[limit+k-1] < v[limit+k+1]) {
+				x = v[limit+k+1]
+			} else {
+				x = v[limit+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[limit+k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var ops []diffOp
+	if !found {
+		for i := range a {
+			ops = append(ops, diffOp{'-', offset + i, -1})
+		}
+		for j := range b {
+			ops = append(ops, diffOp{'+', -1, offset + j})
+		}
+		return ops
+	}
+
+	// Walk back through the trace to collect the edits in reverse.
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[limit+k-1] < v[limit+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[limit+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{'=', offset + x, offset + y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', -1, offset + y})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', offset + x, -1})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{'=', offset + x, offset + y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// sourceSlice returns the lines of sourceTree.readLines in order.
+func sourceSlice(lines map[int]string) []string {
+	result := make([]string, len(lines))
+	for num, text := range lines {
+		if num >= 1 && num <= len(lines) {
+			result[num-1] = text
+		}
+	}
+
+	return result
+}
+
+// fitColumn expands tabs and pads or truncates the text to exactly width
+// columns.
+func fitColumn(text string, width int) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	if n := utf8.RuneCountInString(text); n <= width {
+		return text + strings.Repeat(" ", width-n)
+	}
+
+	runes := []rune(text)
+	return string(runes[:width-1]) + "…"
+}
+
+// terminalWidth returns the width of the terminal from the COLUMNS environment
+// variable or a default that fits two files with 80 columns.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+
+	return 165
+}
@@ -0,0 +1,66 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := strings.Split("a b c d e f", " ")
+	b := strings.Split("a x c d f g", " ")
+
+	var kinds []string
+	for _, op := range diffLines(a, b) {
+		switch op.kind {
+		case '=':
+			assert.Equal(t, a[op.old], b[op.new])
+			kinds = append(kinds, "="+a[op.old])
+		case '-':
+			kinds = append(kinds, "-"+a[op.old])
+		case '+':
+			kinds = append(kinds, "+"+b[op.new])
+		}
+	}
+
+	assert.Equal(t, []string{"=a", "-b", "+x", "=c", "=d", "-e", "=f", "+g"}, kinds)
+}
+
+func TestReport_TermDiff(t *testing.T) {
+	oldSrc := "package svc\n\nfunc Load() int {\n\treturn 1\n}\n"
+	newSrc := "package svc\n\nfunc Load() int {\n\tif debug {\n\t\treturn 2\n\t}\n\treturn 1\n}\n"
+
+	fileName := filepath.Join(t.TempDir(), "svc", "svc.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fileName), 0755))
+	require.NoError(t, os.WriteFile(fileName, []byte(newSrc), 0644))
+
+	oldCov := New([]*Profile{{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 18, EndLine: 4, EndCol: 10, NumStmt: 1, Count: 1},
+	}, TotalStmt: 1, CoveredStmt: 1}})
+	newCov := New([]*Profile{{FileName: fileName, Blocks: []ProfileBlock{
+		{StartLine: 3, StartCol: 18, EndLine: 4, EndCol: 11, NumStmt: 1, Count: 1},
+		{StartLine: 4, StartCol: 11, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+		{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 1, Count: 1},
+	}, TotalStmt: 3, CoveredStmt: 2}})
+
+	report := NewReport(oldCov, newCov, []string{fileName})
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{fileName: {AddedLines: map[int]bool{4: true, 5: true, 6: true}}}}
+	report.oldSourceLines = func(string) (map[int]string, error) {
+		return sourceTree{}.scanLines(strings.NewReader(oldSrc))
+	}
+
+	out := report.Analyze().TermDiff(80, false)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 1+8, out)
+	assert.Equal(t, fileName+" 100.00% → 66.67%", lines[0])
+	assert.Equal(t, "   1    package svc                    │    1    package svc", strings.TrimRight(lines[1], " "))
+	assert.Equal(t, strings.Repeat(" ", 38)+" │    5 +✗         return 2", strings.TrimRight(lines[5], " "))
+	assert.Equal(t, "   4  ✓     return 1                   │    7  ✓     return 1", strings.TrimRight(lines[7], " "))
+
+	colored := report.Analyze().TermDiff(80, true)
+	assert.Contains(t, colored, ansiRed+"   5 +          return 2")
+}
@@ -0,0 +1,145 @@
+package coverage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TestGap summarizes how urgently a changed file needs additional tests.
+type TestGap struct {
+	FileName      string
+	NewStmt       int64   // Number of new statements in the file
+	UncoveredStmt int64   // Number of new statements that are not covered at all
+	Complexity    int     // Highest cyclomatic complexity of a function containing new code
+	Criticality   float64 // Configured weight of the package of the file
+	Score         float64
+}
+
+// TestGaps returns a test gap score for each changed file with new code,
+// sorted by descending score.
+//
+// Each new code block contributes its number of statements weighted by how
+// often it was executed (1 for uncovered blocks, quickly decreasing for
+// blocks with higher execution counts) and by the cyclomatic complexity of the
+// function that contains it. The sum is multiplied by the criticality of the
+// package as configured in the config file.
+func (r *Result) TestGaps() []TestGap {
+	blocks := r.getNewCodeBlocks()
+
+	gaps := map[string]*TestGap{}
+	funcs := map[string][]FunctionInfo{}
+	for _, block := range blocks {
+		gap, ok := gaps[block.FileName]
+		if !ok {
+			gap = &TestGap{
+				FileName:    block.FileName,
+				Complexity:  1,
+				Criticality: r.Config.PackageCriticality(block.FileName),
+			}
+			gaps[block.FileName] = gap
+			funcs[block.FileName] = r.functions(block.FileName)
+		}
+
+		complexity := 1
+		for _, fn := range funcs[block.FileName] {
+			if fn.StartLine <= block.StartLine && block.EndLine <= fn.EndLine {
+				complexity = fn.Complexity
+				break
+			}
+		}
+
+		gap.NewStmt += int64(block.NumStmt)
+		if !block.Covered {
+			gap.UncoveredStmt += int64(block.NumStmt)
+		}
+		if complexity > gap.Complexity {
+			gap.Complexity = complexity
+		}
+
+		gap.Score += float64(block.NumStmt) * executionWeight(block.Count) * float64(complexity)
+	}
+
+	result := make([]TestGap, 0, len(gaps))
+	for _, gap := range gaps {
+		gap.Score *= gap.Criticality
+		result = append(result, *gap)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].FileName < result[j].FileName
+	})
+
+	return result
+}
+
+// executionWeight returns how much a block with the given execution count
+// contributes to the test gap score. Uncovered blocks have a weight of 1 while
+// blocks that were executed only a few times still contribute a little.
+func executionWeight(count int) float64 {
+	n := float64(1 + count)
+	return 1 / (n * n)
+}
+
+// functions returns the function declarations of the given file or nil if the
+// source file cannot be found or parsed.
+func (r *Report) functions(fileName string) []FunctionInfo {
+	if r.astMapper == nil {
+		return nil
+	}
+
+	for _, path := range r.resolveFilePath(fileName) {
+		funcs, err := r.astMapper.GetFunctions(path)
+		if err == nil {
+			return funcs
+		}
+	}
+
+	return nil
+}
+
+// addTestGapDetails adds a table of the changed files ordered by where tests
+// are most urgently needed if TestGapPriorities is set.
+func (r *Result) addTestGapDetails(report *strings.Builder) {
+	if !r.TestGapPriorities {
+		return
+	}
+
+	var gaps []TestGap
+	for _, gap := range r.TestGaps() {
+		if gap.UncoveredStmt > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+
+	if len(gaps) == 0 {
+		return
+	}
+
+	fmt.Fprintln(report, r.detailsTag(foldTestGaps, r.belowMinCoverage))
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "<summary>Test Gap Priorities</summary>")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "The files below are ordered by where new tests would have the biggest impact.")
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "| File | New Statements | Uncovered | Complexity | Criticality | Test Gap Score |")
+	fmt.Fprintln(report, "|------|----------------|-----------|------------|-------------|----------------|")
+
+	for _, gap := range gaps {
+		fmt.Fprintf(report, "| %s | %s | %s | %d | %g | %.2f |\n",
+			gap.FileName,
+			r.numbers().Count(int64(gap.NewStmt)),
+			r.numbers().Count(int64(gap.UncoveredStmt)),
+			gap.Complexity,
+			gap.Criticality,
+			gap.Score,
+		)
+	}
+
+	fmt.Fprintln(report)
+	fmt.Fprintln(report, "</details>")
+	fmt.Fprintln(report)
+}
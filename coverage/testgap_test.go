@@ -0,0 +1,87 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_TestGaps(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/03-old-coverage.txt")
+	require.NoError(t, err)
+
+	newCov, err := ParseCoverage("testdata/03-new-coverage.txt")
+	require.NoError(t, err)
+
+	report := NewReport(oldCov, newCov, []string{"example.com/calculator/math.go"})
+	gaps := report.Analyze().TestGaps()
+	require.Len(t, gaps, 1)
+
+	gap := gaps[0]
+	assert.Equal(t, "example.com/calculator/math.go", gap.FileName)
+	assert.EqualValues(t, 8, gap.NewStmt)
+	assert.EqualValues(t, 5, gap.UncoveredStmt)
+	assert.Equal(t, 2, gap.Complexity) // both Divide and Power have a single decision point
+	assert.Equal(t, 1.0, gap.Criticality)
+
+	// The uncovered blocks of Power contribute 5 statements with a complexity
+	// of 2 and the covered blocks of Divide contribute a little bit as well.
+	assert.InDelta(t, 10.705, gap.Score, 0.001)
+
+	report.Config = &Config{Criticality: map[string]float64{"example.com/calculator": 2}}
+	gaps = report.Analyze().TestGaps()
+	require.Len(t, gaps, 1)
+	assert.Equal(t, 2.0, gaps[0].Criticality)
+	assert.InDelta(t, 21.41, gaps[0].Score, 0.001)
+}
+
+func TestReport_TestGaps_SortedByScore(t *testing.T) {
+	newCov := New([]*Profile{
+		{
+			FileName: "example.com/app/a.go",
+			Blocks: []ProfileBlock{
+				{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 1},
+			},
+		},
+		{
+			FileName: "example.com/app/b.go",
+			Blocks: []ProfileBlock{
+				{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 0},
+			},
+		},
+		{
+			FileName: "example.com/app/critical/c.go",
+			Blocks: []ProfileBlock{
+				{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 0},
+			},
+		},
+	})
+
+	changedFiles := []string{"example.com/app/a.go", "example.com/app/b.go", "example.com/app/critical/c.go"}
+	report := NewReport(New(nil), newCov, changedFiles)
+	report.Config = &Config{Criticality: map[string]float64{"example.com/app/critical/...": 10}}
+
+	var files []string
+	for _, gap := range report.Analyze().TestGaps() {
+		files = append(files, gap.FileName)
+	}
+
+	assert.Equal(t, []string{"example.com/app/critical/c.go", "example.com/app/b.go", "example.com/app/a.go"}, files)
+
+	markdown := new(strings.Builder)
+	report.Analyze().addTestGapDetails(markdown)
+	assert.Empty(t, markdown.String(), "the section is only added with -test-gaps")
+
+	report.TestGapPriorities = true
+	report.Analyze().addTestGapDetails(markdown)
+	assert.Contains(t, markdown.String(), "| example.com/app/critical/c.go | 1 | 1 | 1 | 10 | 10.00 |")
+	assert.NotContains(t, markdown.String(), "example.com/app/a.go", "files without uncovered code should not be listed")
+}
+
+func TestExecutionWeight(t *testing.T) {
+	assert.Equal(t, 1.0, executionWeight(0))
+	assert.Equal(t, 0.25, executionWeight(1))
+	assert.Less(t, executionWeight(10), executionWeight(2))
+}
@@ -0,0 +1,188 @@
+package coverage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The themes of the Markdown report that can be selected via Report.Theme.
+const (
+	themeClassic     = "classic" // emojis scale with the change, down to skulls for large regressions
+	themeMinimal     = "minimal" // arrows instead of emojis
+	themeStrictNoFun = "strict-no-fun"
+	themeCelebratory = "celebratory" // more emojis for improvements and an encouraging tone for regressions
+)
+
+// reportTheme determines the emojis and the tone of all sections of the
+// Markdown report.
+type reportTheme struct {
+	increase, decrease, noChange string // change of the overall coverage in the title
+
+	statusHeader string // header of the status column of the tables
+	passed       string // status of commits or examples that meet the requirements
+	failed       string // status of commits or examples that do not meet the requirements
+	warning      string // marker of values that need attention
+
+	deltaStatus   func(diff float64) string    // status of a coverage change in percentage points
+	newCodeStatus func(percent float64) string // status of the coverage of new code
+}
+
+var reportThemes = map[string]reportTheme{
+	themeClassic: {
+		increase:      "**increase**",
+		decrease:      "**decrease**",
+		noChange:      "no change",
+		statusHeader:  ":robot:",
+		passed:        ":white_check_mark:",
+		failed:        ":x:",
+		warning:       ":warning:",
+		deltaStatus:   classicDeltaStatus,
+		newCodeStatus: classicNewCodeStatus,
+	},
+	themeMinimal: {
+		increase:     "increase",
+		decrease:     "decrease",
+		noChange:     "no change",
+		statusHeader: "",
+		passed:       "✓",
+		failed:       "✗",
+		warning:      "(!)",
+		deltaStatus: func(diff float64) string {
+			switch {
+			case diff < 0:
+				return "↓"
+			case diff > 0:
+				return "↑"
+			default:
+				return ""
+			}
+		},
+		newCodeStatus: func(float64) string { return "" },
+	},
+	themeStrictNoFun: {
+		increase:     "increase",
+		decrease:     "**regression**",
+		noChange:     "no change",
+		statusHeader: "Status",
+		passed:       "passed",
+		failed:       "**failed**",
+		warning:      "(needs attention)",
+		deltaStatus: func(diff float64) string {
+			if diff < 0 {
+				return "**regression**"
+			}
+			return ""
+		},
+		newCodeStatus: func(percent float64) string {
+			if percent < 50 {
+				return "**insufficient**"
+			}
+			return ""
+		},
+	},
+	themeCelebratory: {
+		increase:     "**increase** :tada:",
+		decrease:     "decrease",
+		noChange:     "no change",
+		statusHeader: ":sparkles:",
+		passed:       ":white_check_mark:",
+		failed:       ":construction:",
+		warning:      ":eyes:",
+		deltaStatus: func(diff float64) string {
+			switch {
+			case diff > 20:
+				return ":star2: :rocket: :tada:"
+			case diff > 10:
+				return ":rocket: :tada:"
+			case diff > 0:
+				return ":rocket:"
+			case diff < 0:
+				return ":seedling:" // room to grow
+			default:
+				return ""
+			}
+		},
+		newCodeStatus: func(percent float64) string {
+			switch {
+			case percent >= 90:
+				return ":trophy:"
+			case percent >= 80:
+				return ":tada:"
+			case percent >= 70:
+				return ":sparkles:"
+			case percent >= 50:
+				return ":seedling:"
+			default:
+				return ":muscle:"
+			}
+		},
+	},
+}
+
+// validateTheme returns an error if the given theme of the Markdown report is
+// not supported. An empty theme selects the classic theme.
+func validateTheme(theme string) error {
+	if _, ok := reportThemes[theme]; theme != "" && !ok {
+		return fmt.Errorf("unsupported theme: %q (supported themes: %s, %s, %s and %s)", theme, themeClassic, themeMinimal, themeStrictNoFun, themeCelebratory)
+	}
+
+	return nil
+}
+
+// theme returns the theme of the Markdown report.
+func (r *Report) theme() reportTheme {
+	if t, ok := reportThemes[r.Theme]; ok {
+		return t
+	}
+
+	return reportThemes[themeClassic]
+}
+
+// emojiScore returns the status of the change from oldPercent to newPercent
+// according to the theme of the report and the formatted change.
+func (r *Report) emojiScore(newPercent, oldPercent float64) (emoji, diffStr string) {
+	diff := newPercent - oldPercent
+	if diff == 0 {
+		return "", "ø"
+	}
+
+	return r.theme().deltaStatus(diff), "**" + r.numbers().Delta(diff) + "**"
+}
+
+func classicDeltaStatus(diff float64) string {
+	switch {
+	case diff < -50:
+		return strings.Repeat(":skull: ", 5)
+	case diff < -10:
+		return strings.Repeat(":skull: ", int(-diff/10))
+	case diff < 0:
+		return ":thumbsdown:"
+	case diff > 20:
+		return ":star2:"
+	case diff > 10:
+		return ":tada:"
+	case diff > 0:
+		return ":thumbsup:"
+	default:
+		return ""
+	}
+}
+
+// classicNewCodeStatus returns a simplified emoji scoring for the coverage of
+// new code.
+func classicNewCodeStatus(percent float64) string {
+	switch {
+	case percent >= 90:
+		return ":star2:"
+	case percent >= 80:
+		return ":tada:"
+	case percent >= 70:
+		return ":thumbsup:"
+	case percent >= 50:
+		return ":neutral_face:"
+	case percent >= 30:
+		return ":thumbsdown:"
+	default:
+		return ":skull:"
+	}
+}
@@ -0,0 +1,84 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Theme(t *testing.T) {
+	oldCov, err := ParseCoverage("testdata/01-old-coverage.txt")
+	require.NoError(t, err)
+	newCov, err := ParseCoverage("testdata/01-new-coverage.txt")
+	require.NoError(t, err)
+	changedFiles, err := ParseChangedFiles("testdata/01-changed-files.json", "github.com/fgrosse/prioqueue")
+	require.NoError(t, err)
+
+	render := func(theme string) string {
+		report := NewReport(oldCov, newCov, changedFiles)
+		report.Theme = theme
+		return report.Analyze().Markdown()
+	}
+
+	// The classic theme is the default.
+	assert.Equal(t, render(""), render(themeClassic))
+
+	cases := map[string]struct{ title, summary string }{
+		themeClassic: {
+			title:   "### Coverage Report - 90.20% (**-9.80%**) - **decrease**",
+			summary: "| Metric | Old Coverage | New Coverage | Change | :robot: |",
+		},
+		themeMinimal: {
+			title:   "### Coverage Report - 90.20% (**-9.80%**) - decrease",
+			summary: "| **Total** | 100.00% | 90.20% | **-9.80%** | ↓ |",
+		},
+		themeStrictNoFun: {
+			title:   "### Coverage Report - 90.20% (**-9.80%**) - **regression**",
+			summary: "| **New Code** | N/A | 85.71% | 42/49 statements |  |",
+		},
+		themeCelebratory: {
+			title:   "### Coverage Report - 90.20% (**-9.80%**) - decrease",
+			summary: "| **Total** | 100.00% | 90.20% | **-9.80%** | :seedling: |",
+		},
+	}
+
+	for theme, c := range cases {
+		t.Run(theme, func(t *testing.T) {
+			markdown := render(theme)
+			assert.Equal(t, c.title, strings.SplitN(markdown, "\n", 2)[0])
+			assert.Contains(t, markdown, c.summary)
+			if theme != themeClassic {
+				assert.NotContains(t, markdown, ":thumbsdown:")
+				assert.NotContains(t, markdown, ":robot:")
+			}
+		})
+	}
+}
+
+func TestReport_Theme_DeltaStatus(t *testing.T) {
+	report := &Report{Theme: themeCelebratory}
+	emoji, diff := report.emojiScore(80, 60)
+	assert.Equal(t, ":rocket: :tada:", emoji)
+	assert.Equal(t, "**+20.00%**", diff)
+
+	emoji, diff = report.emojiScore(50, 50)
+	assert.Empty(t, emoji)
+	assert.Equal(t, "ø", diff)
+
+	report.Theme = themeClassic
+	emoji, _ = report.emojiScore(10, 45)
+	assert.Equal(t, ":skull: :skull: :skull: ", emoji)
+
+	report.Theme = themeStrictNoFun
+	emoji, _ = report.emojiScore(10, 45)
+	assert.Equal(t, "**regression**", emoji)
+}
+
+func TestValidateTheme(t *testing.T) {
+	for _, theme := range []string{"", themeClassic, themeMinimal, themeStrictNoFun, themeCelebratory} {
+		assert.NoError(t, validateTheme(theme), theme)
+	}
+	assert.EqualError(t, validateTheme("fun"), `unsupported theme: "fun" (supported themes: classic, minimal, strict-no-fun and celebratory)`)
+}
@@ -0,0 +1,129 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var uncoveredUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s uncovered [OPTIONS] <COVERAGE_FILE>
+
+Print the uncovered new lines as "file:line" (one per line, relative to the
+repository root) and fail if there are any. New lines are the lines added by
+"git diff <REVISION>", which by default are the staged and unstaged changes
+of the working tree. Unlike the main command, no old coverage and no report
+are needed, so the command is fast enough for pre-commit and pre-push hooks:
+
+  go test -coverprofile=cover.out ./... && %[1]s uncovered cover.out
+
+In a pre-push hook, compare with the upstream branch instead (e.g.
+-diff=@{upstream}). Files that are not known to git yet are not part of the
+diff until they are added. Code excluded via coverage directives or waivers
+is not reported.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runUncoveredCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("uncovered", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, uncoveredUsage)
+		fs.PrintDefaults()
+	}
+
+	revision := fs.String("diff", "HEAD", "git revision (or revision range) to diff against")
+	diffFile := fs.String("diff-file", "", "path to a git diff file (unified diff format) to use instead of running git diff")
+	excludeWiring := fs.Bool("exclude-wiring", false, "exclude func main and dependency injection wiring code (wire_gen.go, fx modules)")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one coverage file")
+	}
+
+	cov, err := ParseCoverageContext(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to parse coverage: %w", err)
+	}
+
+	for _, find := range exclusionFinders(options{excludeWiring: *excludeWiring}) {
+		if err := applySourceExclusions(sourceTree{}, cov, nil, find, nil); err != nil {
+			return fmt.Errorf("failed to apply exclusions: %w", err)
+		}
+	}
+
+	var diff []byte
+	if *diffFile != "" {
+		diff, err = os.ReadFile(*diffFile)
+	} else {
+		diff, err = runGit(ctx, "diff", "--no-color", "--no-ext-diff", *revision, "--", "*.go")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	diffInfo, err := parseUnifiedDiff(bytes.NewReader(diff), parseOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	return writeUncoveredLines(os.Stdout, uncoveredNewLines(sourceTree{}, cov, diffInfo))
+}
+
+// uncoveredNewLines returns the uncovered lines of the coverage profile that
+// were added according to the diff as "file:line", where the file name is the
+// path of the diff. Lines are merged from the coverage blocks like in the new
+// code section of the report, so that e.g. lines with only a closing brace
+// are not reported.
+func uncoveredNewLines(tree sourceTree, cov *Coverage, diffInfo *DiffInfo) []string {
+	r := &Report{New: cov, DiffInfo: diffInfo, source: tree}
+
+	var lines []string
+	for _, fileName := range sortedKeys(cov.Files) {
+		fileDiff, diffPath := diffInfo.matchFileDiff(fileName)
+		if fileDiff == nil || len(fileDiff.AddedLines) == 0 {
+			continue
+		}
+
+		var blocks []ProfileBlock
+		for _, b := range cov.Files[fileName].Blocks {
+			if len(fileDiff.changedLinesInRange(b.StartLine, b.EndLine)) > 0 {
+				blocks = append(blocks, b)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		sourceLines, _ := r.source.readLines(fileName)
+		lineCoverage := LineCoverage(r.newLineCoverage(fileName, sourceLines, r.newCodeBlocks(fileName, blocks)))
+		for _, line := range lineCoverage.Uncovered() {
+			lines = append(lines, fmt.Sprintf("%s:%d", diffPath, line))
+		}
+	}
+
+	return lines
+}
+
+// writeUncoveredLines prints the uncovered lines and returns an error if there
+// are any, so the command exits with a non-zero status.
+func writeUncoveredLines(w io.Writer, lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	if len(lines) > 0 {
+		return fmt.Errorf("%d uncovered new lines", len(lines))
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUncoveredNewLines(t *testing.T) {
+	cov, err := ParseCoverage("testdata/04-new-coverage.txt")
+	require.NoError(t, err)
+	diffInfo, err := ParseUnifiedDiff("testdata/04-diff.patch")
+	require.NoError(t, err)
+
+	// The same lines as in the new code section of the report: the bodies of
+	// both if statements of Days and the second condition never ran.
+	assert.Equal(t, []string{
+		"pkg/age/age.go:56",
+		"pkg/age/age.go:57",
+		"pkg/age/age.go:58",
+		"pkg/age/age.go:59",
+		"pkg/age/age.go:60",
+	}, uncoveredNewLines(sourceTree{}, cov, diffInfo))
+
+	// Files without changes are not reported.
+	diffInfo, err = parseUnifiedDiff(strings.NewReader("+++ b/pkg/other/other.go\n@@ -0,0 +1 @@\n+package other\n"), parseOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, uncoveredNewLines(sourceTree{}, cov, diffInfo))
+}
+
+func TestWriteUncoveredLines(t *testing.T) {
+	var out bytes.Buffer
+	err := writeUncoveredLines(&out, []string{"a.go:1", "b.go:7"})
+	assert.EqualError(t, err, "2 uncovered new lines")
+	assert.Equal(t, "a.go:1\nb.go:7\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, writeUncoveredLines(&out, nil))
+	assert.Empty(t, out.String())
+}
+
+func TestRunUncoveredCommand(t *testing.T) {
+	err := runUncoveredCommand(context.Background(), []string{"-diff-file=testdata/04-diff.patch", "testdata/04-new-coverage.txt"})
+	assert.EqualError(t, err, "5 uncovered new lines")
+
+	err = runUncoveredCommand(context.Background(), []string{"-diff-file=testdata/01-diff.patch", "testdata/04-new-coverage.txt"})
+	assert.NoError(t, err)
+}
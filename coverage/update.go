@@ -0,0 +1,387 @@
+package coverage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// version is the release version of the binary that is passed to Main.
+var version = "dev"
+
+// releaseURL is the base URL of the GitHub releases of this tool.
+var releaseURL = "https://github.com/OscarClemente/go-coverage-report/releases"
+
+// maxArchiveSize limits the size of a downloaded release archive.
+const maxArchiveSize = 100 << 20
+
+var versionUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s version [OPTIONS]
+
+Print the version of this binary. With -check, the latest release is looked up
+on GitHub and the command fails if this binary is outdated.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+var updateUsage = strings.TrimSpace(fmt.Sprintf(`
+Usage: %s update [OPTIONS]
+
+Download a release binary from GitHub and replace this binary (or the file given
+via -o) with it. The archive is verified against the checksums.txt file of the
+release or against the checksum given via -sha256. Use -version to pin a release
+instead of using the latest one. Nothing is downloaded if the binary already has
+the requested version.
+
+OPTIONS:
+`, filepath.Base(os.Args[0])))
+
+func runVersionCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, versionUsage)
+		fs.PrintDefaults()
+	}
+
+	check := fs.Bool("check", false, "fail if a newer release is available")
+	_ = fs.Parse(args)
+
+	fmt.Fprintln(os.Stdout, normalizeVersion(version))
+	if !*check {
+		return nil
+	}
+
+	latest, err := latestRelease(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to determine latest release: %w", err)
+	}
+
+	if compareVersions(normalizeVersion(version), latest) < 0 {
+		return fmt.Errorf("a newer release is available: %s (run %q to install it)", latest, filepath.Base(os.Args[0])+" update")
+	}
+
+	log.Println("go-coverage-report is up to date")
+	return nil
+}
+
+func runUpdateCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, updateUsage)
+		fs.PrintDefaults()
+	}
+
+	want := fs.String("version", "latest", "the release to install (e.g. v1.5.1)")
+	checksum := fs.String("sha256", "", "expected SHA256 checksum of the release archive (default: use checksums.txt of the release)")
+	output := fs.String("o", "", "path of the binary to write (default: the running binary)")
+	force := fs.Bool("force", false, "download the release even if the binary already has the requested version")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	tag := normalizeVersion(*want)
+	if *want == "latest" {
+		var err error
+		tag, err = latestRelease(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to determine latest release: %w", err)
+		}
+	}
+
+	target := *output
+	if target == "" {
+		if tag == normalizeVersion(version) && !*force {
+			log.Printf("go-coverage-report is already at %s", tag)
+			return nil
+		}
+
+		var err error
+		target, err = os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to determine path of the running binary: %w", err)
+		}
+	}
+
+	binary, err := downloadRelease(ctx, tag, runtime.GOOS, runtime.GOARCH, *checksum)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceBinary(target, binary); err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+
+	log.Printf("Installed go-coverage-report %s to %s", tag, target)
+	return nil
+}
+
+// normalizeVersion adds the "v" prefix of the release tags to a version.
+func normalizeVersion(v string) string {
+	if v == "" || v == "dev" || strings.HasPrefix(v, "v") {
+		return v
+	}
+
+	return "v" + v
+}
+
+// compareVersions compares two semantic versions of the form vMAJOR.MINOR.PATCH
+// and returns -1, 0 or +1. Pre-release suffixes are ignored and versions that
+// cannot be parsed (e.g. "dev") are older than all others.
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var result [3]int
+
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != len(result) {
+		return result, false
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return result, false
+		}
+		result[i] = n
+	}
+
+	return result, true
+}
+
+// latestRelease returns the tag of the latest release. GitHub redirects the
+// "latest" page to the page of the tag, which avoids the rate limits of the API.
+func latestRelease(ctx context.Context) (string, error) {
+	resp, err := httpGet(ctx, releaseURL+"/latest")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	tag := path.Base(resp.Request.URL.Path)
+	if _, ok := parseVersion(tag); !ok || path.Base(path.Dir(resp.Request.URL.Path)) != "tag" {
+		return "", fmt.Errorf("unexpected release URL %s", resp.Request.URL)
+	}
+
+	return tag, nil
+}
+
+// releaseArchive returns the file name of the release archive of the given
+// version and platform as created by GoReleaser.
+func releaseArchive(tag, goos, goarch string) string {
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+
+	return fmt.Sprintf("go-coverage-report-%s-%s-%s%s", tag, goos, goarch, ext)
+}
+
+// downloadRelease downloads the release archive of the given version and
+// platform, verifies its checksum and returns the binary it contains. If
+// checksum is empty, the checksums.txt file of the release is used.
+func downloadRelease(ctx context.Context, tag, goos, goarch, checksum string) ([]byte, error) {
+	archive := releaseArchive(tag, goos, goarch)
+	data, err := download(ctx, releaseURL+"/download/"+tag+"/"+archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", archive, err)
+	}
+
+	if checksum == "" {
+		sums, err := download(ctx, releaseURL+"/download/"+tag+"/checksums.txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to download checksums: %w", err)
+		}
+
+		checksum, err = findChecksum(sums, archive)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, strings.TrimSpace(checksum)) {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s but got %s", archive, checksum, actual)
+	}
+
+	binaryName := "go-coverage-report"
+	if goos == "windows" {
+		binaryName += ".exe"
+	}
+
+	if strings.HasSuffix(archive, ".zip") {
+		return extractZip(data, binaryName)
+	}
+
+	return extractTarGz(data, binaryName)
+}
+
+// findChecksum returns the checksum of the given file from the contents of a
+// checksums.txt file as written by sha256sum.
+func findChecksum(sums []byte, fileName string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == fileName {
+			return fields[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("checksums.txt does not contain %s", fileName)
+}
+
+func extractTarGz(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid release archive: %w", err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("release archive does not contain %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid release archive: %w", err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == name {
+			return io.ReadAll(io.LimitReader(tr, maxArchiveSize))
+		}
+	}
+}
+
+func extractZip(data []byte, name string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid release archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if path.Base(f.Name) != name {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid release archive: %w", err)
+		}
+		defer rc.Close()
+
+		return io.ReadAll(io.LimitReader(rc, maxArchiveSize))
+	}
+
+	return nil, fmt.Errorf("release archive does not contain %s", name)
+}
+
+// replaceBinary atomically replaces the file at target with the given binary.
+func replaceBinary(target string, binary []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(target), ".go-coverage-report-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(binary)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		// A running executable cannot be replaced on Windows but it can be
+		// renamed.
+		_ = os.Remove(target + ".old")
+		if err := os.Rename(target, target+".old"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(f.Name(), target)
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", url, maxArchiveSize>>20)
+	}
+
+	return data, nil
+}
+
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return resp, nil
+}
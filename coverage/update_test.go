@@ -0,0 +1,119 @@
+package coverage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("v1.5.1", "v1.5.1"))
+	assert.Equal(t, -1, compareVersions("v1.5.1", "v1.10.0"))
+	assert.Equal(t, 1, compareVersions("v2.0.0", "v1.99.99"))
+	assert.Equal(t, 0, compareVersions("v1.2.3-rc1", "v1.2.3"))
+	assert.Equal(t, -1, compareVersions("dev", "v0.0.1"))
+	assert.Equal(t, 1, compareVersions("v0.0.1", "dev"))
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	assert.Equal(t, "v1.5.1", normalizeVersion("1.5.1"))
+	assert.Equal(t, "v1.5.1", normalizeVersion("v1.5.1"))
+	assert.Equal(t, "dev", normalizeVersion("dev"))
+}
+
+// newReleaseServer serves a fake GitHub release v1.2.3 that contains the
+// given binary for linux/amd64.
+func newReleaseServer(t *testing.T, binary []byte) *httptest.Server {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: 2}))
+	_, err := tw.Write([]byte("hi"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "go-coverage-report", Mode: 0755, Size: int64(len(binary))}))
+	_, err = tw.Write(binary)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	archive := buf.Bytes()
+	sum := sha256.Sum256(archive)
+	name := releaseArchive("v1.2.3", "linux", "amd64")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/tag/v1.2.3", http.StatusFound)
+	})
+	mux.HandleFunc("/tag/v1.2.3", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/download/v1.2.3/"+name, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	mux.HandleFunc("/download/v1.2.3/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  go-coverage-report-v1.2.3-darwin-arm64.tar.gz\n", hex.EncodeToString(make([]byte, 32)))
+		fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	oldURL := releaseURL
+	releaseURL = srv.URL
+	t.Cleanup(func() { releaseURL = oldURL })
+
+	return srv
+}
+
+func TestLatestRelease(t *testing.T) {
+	newReleaseServer(t, nil)
+
+	tag, err := latestRelease(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3", tag)
+}
+
+func TestDownloadRelease(t *testing.T) {
+	newReleaseServer(t, []byte("binary"))
+
+	binary, err := downloadRelease(context.Background(), "v1.2.3", "linux", "amd64", "")
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(binary))
+
+	_, err = downloadRelease(context.Background(), "v1.2.3", "linux", "amd64", hex.EncodeToString(make([]byte, 32)))
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	_, err = downloadRelease(context.Background(), "v1.2.3", "linux", "arm64", "")
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestReplaceBinary(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "go-coverage-report")
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0644))
+
+	require.NoError(t, replaceBinary(target, []byte("new")))
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	info, err := os.Stat(target)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(target))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the temporary file must be removed")
+}
@@ -0,0 +1,162 @@
+package coverage
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+const covignoreDirective = "//covignore"
+
+// coverageWaivers returns the statements of the given Go source code that are
+// waived via a trailing "//covignore" comment on their line, optionally
+// followed by a reason (e.g. "//covignore unreachable"). Only statements of
+// statement lists are waived since these are the statements that are counted
+// by go test -cover. The regions have the line and column of the statement.
+func coverageWaivers(fileName string, src []byte) ([]excludedRegion, error) {
+	if !bytes.Contains(src, []byte(covignoreDirective)) {
+		return nil, nil // fast path, no need to parse the file
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// The waivers by line. Comments on the line of a block opening brace are
+	// associated with the first statement of the block by the comment map, so
+	// the statements are matched by the line of the comment instead.
+	waivers := map[int]*ast.Comment{}
+	for _, groups := range ast.NewCommentMap(fset, file, file.Comments) {
+		for _, group := range groups {
+			for _, c := range group.List {
+				if isCovignore(c.Text) {
+					waivers[fset.Position(c.Pos()).Line] = c
+				}
+			}
+		}
+	}
+	if len(waivers) == 0 {
+		return nil, nil
+	}
+
+	var regions []excludedRegion
+	waive := func(stmts []ast.Stmt) {
+		for _, stmt := range stmts {
+			pos := fset.Position(stmt.Pos())
+			c, ok := waivers[pos.Line]
+			if !ok || c.Pos() < stmt.Pos() {
+				continue
+			}
+
+			reason := "//covignore waiver"
+			text := strings.TrimPrefix(strings.TrimPrefix(c.Text, covignoreDirective), ":")
+			if text = strings.TrimSpace(text); text != "" {
+				reason += ": " + text
+			}
+			regions = append(regions, excludedRegion{
+				LineRange: LineRange{StartLine: pos.Line, EndLine: pos.Line},
+				Column:    pos.Column,
+				Reason:    reason,
+			})
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			waive(n.List)
+		case *ast.CaseClause:
+			waive(n.Body)
+		case *ast.CommClause:
+			waive(n.Body)
+		}
+		return true
+	})
+
+	return regions, nil
+}
+
+// isCovignore returns whether the text of a comment is a covignore directive.
+func isCovignore(text string) bool {
+	rest, ok := strings.CutPrefix(text, covignoreDirective)
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == ':')
+}
+
+// ExcludeStatement removes the statement that starts at the given line and
+// column of the file from the coverage calculation and records the exclusion
+// with the given reason. It returns the number of excluded statements, which
+// is zero if no block of the profile contains the position.
+func (c *Coverage) ExcludeStatement(fileName string, line, col int, reason string) int64 {
+	p, ok := c.Files[fileName]
+	if !ok {
+		return 0
+	}
+
+	for i, b := range p.Blocks {
+		if b.NumStmt == 0 || !b.contains(line, col) {
+			continue
+		}
+
+		var covered int64
+		if b.Count > 0 {
+			covered = 1
+		}
+
+		// The blocks may be shared with other profiles, e.g. of merged shards.
+		blocks := slices.Clone(p.Blocks)
+		blocks[i].NumStmt--
+		if blocks[i].NumStmt == 0 {
+			blocks = slices.Delete(blocks, i, i+1)
+		}
+		p.Blocks = blocks
+
+		p.TotalStmt--
+		p.CoveredStmt -= covered
+		p.MissedStmt = p.TotalStmt - p.CoveredStmt
+
+		c.TotalStmt--
+		c.CoveredStmt -= covered
+		c.MissedStmt = c.TotalStmt - c.CoveredStmt
+
+		c.Exclusions = append(c.Exclusions, Exclusion{
+			FileName:  fileName,
+			StartLine: line,
+			EndLine:   line,
+			NumStmt:   1,
+			Reason:    reason,
+			Statement: true,
+		})
+
+		return 1
+	}
+
+	return 0
+}
+
+// contains returns whether the position is within the block.
+func (b ProfileBlock) contains(line, col int) bool {
+	after := line > b.StartLine || (line == b.StartLine && col >= b.StartCol)
+	before := line < b.EndLine || (line == b.EndLine && col <= b.EndCol)
+	return after && before
+}
+
+// waivedLines returns the lines of the file with statements that have been
+// excluded via ExcludeStatement.
+func (c *Coverage) waivedLines(fileName string) map[int]bool {
+	var lines map[int]bool
+	for _, e := range c.Exclusions {
+		if e.Statement && e.FileName == fileName {
+			if lines == nil {
+				lines = map[int]bool{}
+			}
+			lines[e.StartLine] = true
+		}
+	}
+
+	return lines
+}
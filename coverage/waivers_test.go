@@ -0,0 +1,87 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const waiversTestSource = `package foo
+
+func Load(name string) error {
+	data, err := read(name)
+	if err != nil { //covignore the reader never fails
+		return err //covignore
+	}
+	x := 1; y := 2 //covignore:defensive
+	use(data, x, y)
+	// covignore is only detected without a space
+	return nil //covignored
+}
+`
+
+func TestCoverageWaivers(t *testing.T) {
+	regions, err := coverageWaivers("foo.go", []byte(waiversTestSource))
+	require.NoError(t, err)
+
+	assert.Equal(t, []excludedRegion{
+		{LineRange: LineRange{StartLine: 5, EndLine: 5}, Column: 2, Reason: "//covignore waiver: the reader never fails"},
+		{LineRange: LineRange{StartLine: 8, EndLine: 8}, Column: 2, Reason: "//covignore waiver: defensive"},
+		{LineRange: LineRange{StartLine: 8, EndLine: 8}, Column: 10, Reason: "//covignore waiver: defensive"},
+		{LineRange: LineRange{StartLine: 6, EndLine: 6}, Column: 3, Reason: "//covignore waiver"},
+	}, regions)
+}
+
+func TestCoverageWaivers_NoWaivers(t *testing.T) {
+	regions, err := coverageWaivers("foo.go", []byte("this is not even Go code"))
+	require.NoError(t, err)
+	assert.Empty(t, regions)
+}
+
+func TestApplySourceExclusions_Waivers(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "foo.go")
+	require.NoError(t, os.WriteFile(fileName, []byte(waiversTestSource), 0644))
+
+	// The blocks as reported by go test -cover.
+	cov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 3, StartCol: 31, EndLine: 5, EndCol: 16, NumStmt: 2, Count: 1},
+		ProfileBlock{StartLine: 5, StartCol: 16, EndLine: 7, EndCol: 3, NumStmt: 1, Count: 0},
+		ProfileBlock{StartLine: 8, StartCol: 2, EndLine: 11, EndCol: 12, NumStmt: 4, Count: 1},
+	)})
+
+	require.NoError(t, applySourceExclusions(sourceTree{}, cov, nil, coverageWaivers, nil))
+
+	assert.EqualValues(t, 3, cov.TotalStmt)
+	assert.EqualValues(t, 3, cov.CoveredStmt)
+	assert.EqualValues(t, 4, cov.ExcludedStmt())
+	assert.Len(t, cov.Files[fileName].Blocks, 2, "the block without statements is removed")
+	assert.Equal(t, map[int]bool{5: true, 6: true, 8: true}, cov.waivedLines(fileName))
+
+	report := NewReport(New(nil), cov, []string{fileName})
+	actual := report.Analyze().Markdown()
+	assert.Contains(t, actual, "<summary>Excluded Code (4 statements)</summary>")
+	assert.Contains(t, actual, "| "+fileName+" | 5 | 1 | //covignore waiver: the reader never fails |")
+	assert.Contains(t, actual, "| "+fileName+" | 6 | 1 | //covignore waiver |")
+}
+
+func TestReport_NewCodeCoverage_Waivers(t *testing.T) {
+	fileName := "example.com/foo/foo.go"
+	cov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 3, StartCol: 31, EndLine: 5, EndCol: 16, NumStmt: 2, Count: 1},
+		ProfileBlock{StartLine: 5, StartCol: 16, EndLine: 7, EndCol: 3, NumStmt: 1, Count: 0},
+	)})
+	cov.ExcludeStatement(fileName, 6, 3, "//covignore waiver")
+
+	report := NewReport(New([]*Profile{newTestProfile(fileName)}), cov, []string{fileName})
+	report.DiffInfo = &DiffInfo{Files: map[string]*FileDiff{
+		fileName: {FileName: fileName, AddedLines: map[int]bool{5: true, 6: true}, ModifiedLines: map[int]bool{}},
+	}}
+	report.astCache[fileName] = map[int]bool{4: true, 5: true, 6: true}
+
+	total, covered := report.Analyze().calculateNewCodeCoverage()
+	assert.EqualValues(t, 1, total, "only the if statement is new code")
+	assert.EqualValues(t, 1, covered)
+}
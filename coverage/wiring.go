@@ -0,0 +1,145 @@
+package coverage
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const fxImportPath = "go.uber.org/fx"
+
+// wiringRegions returns the regions of the given Go source file that contain
+// application wiring code which is conventionally exercised by smoke tests
+// rather than unit tests. These are:
+//   - the body of func main
+//   - files generated by github.com/google/wire (wire_gen.go)
+//   - go.uber.org/fx modules declared as package level variables or returned
+//     by functions that consist of nothing else
+func wiringRegions(fileName string, src []byte) ([]excludedRegion, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if isWireGenerated(fileName, file) {
+		end := fset.File(file.Pos()).LineCount()
+		return []excludedRegion{{LineRange: LineRange{StartLine: 1, EndLine: end}, Reason: "wire generated code"}}, nil
+	}
+
+	fxName := importName(file, fxImportPath)
+
+	var regions []excludedRegion
+	region := func(n ast.Node, reason string) excludedRegion {
+		return excludedRegion{
+			LineRange: LineRange{
+				StartLine: fset.Position(n.Pos()).Line,
+				EndLine:   fset.Position(n.End()).Line,
+			},
+			Reason: reason,
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Body == nil {
+				continue
+			}
+			if file.Name.Name == "main" && decl.Recv == nil && decl.Name.Name == "main" {
+				regions = append(regions, region(decl.Body, "func main"))
+				continue
+			}
+			if fxName != "" && len(decl.Body.List) == 1 {
+				if ret, ok := decl.Body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 && isFxCall(ret.Results[0], fxName) {
+					regions = append(regions, region(decl.Body, "fx module"))
+				}
+			}
+		case *ast.GenDecl:
+			if fxName == "" || decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for _, v := range vs.Values {
+					if isFxCall(v, fxName) {
+						regions = append(regions, region(v, "fx module"))
+					}
+				}
+			}
+		}
+	}
+
+	return regions, nil
+}
+
+// ApplyWiringExclusions excludes all wiring code (see wiringRegions) from the
+// given coverage profile. Files in skip are ignored.
+func ApplyWiringExclusions(cov *Coverage, skip map[string]bool) error {
+	return applySourceExclusions(sourceTree{}, cov, skip, wiringRegions, nil)
+}
+
+func isWireGenerated(fileName string, file *ast.File) bool {
+	if filepath.Base(fileName) == "wire_gen.go" {
+		return true
+	}
+
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "// Code generated by Wire.") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// importName returns the name under which the given import path is imported
+// in the file or an empty string if it is not imported at all.
+func importName(file *ast.File, importPath string) string {
+	for _, imp := range file.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || p != importPath {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return filepath.Base(importPath)
+	}
+
+	return ""
+}
+
+// isFxCall reports whether the expression is a call to fx.Module, fx.Options
+// or fx.Provide.
+func isFxCall(expr ast.Expr, fxName string) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Name != fxName {
+		return false
+	}
+
+	switch sel.Sel.Name {
+	case "Module", "Options", "Provide":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,118 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWiringRegions_Main(t *testing.T) {
+	src := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+
+func run() {
+	fmt.Println("world")
+}
+
+type T struct{}
+
+func (T) main() {}
+`
+
+	regions, err := wiringRegions("main.go", []byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, []excludedRegion{
+		{LineRange: LineRange{StartLine: 5, EndLine: 7}, Reason: "func main"},
+	}, regions)
+
+	// func main outside of package main is just a regular function
+	regions, err = wiringRegions("foo.go", []byte("package foo\n\nfunc main() {\n}\n"))
+	require.NoError(t, err)
+	assert.Empty(t, regions)
+}
+
+func TestWiringRegions_Fx(t *testing.T) {
+	src := `package server
+
+import (
+	"net/http"
+
+	uberfx "go.uber.org/fx"
+)
+
+var Module = uberfx.Module("server",
+	uberfx.Provide(NewServer),
+)
+
+func Options() uberfx.Option {
+	return uberfx.Options(
+		Module,
+	)
+}
+
+func NewServer() *http.Server {
+	return &http.Server{}
+}
+`
+
+	regions, err := wiringRegions("server.go", []byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, []excludedRegion{
+		{LineRange: LineRange{StartLine: 9, EndLine: 11}, Reason: "fx module"},
+		{LineRange: LineRange{StartLine: 13, EndLine: 17}, Reason: "fx module"},
+	}, regions)
+}
+
+func TestWiringRegions_Wire(t *testing.T) {
+	src := `// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+
+package app
+
+func InitializeApp() *App {
+	return &App{}
+}
+`
+
+	regions, err := wiringRegions("app/injector.go", []byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, []excludedRegion{
+		{LineRange: LineRange{StartLine: 1, EndLine: 10}, Reason: "wire generated code"},
+	}, regions)
+
+	regions, err = wiringRegions("app/wire_gen.go", []byte("package app\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []excludedRegion{
+		{LineRange: LineRange{StartLine: 1, EndLine: 1}, Reason: "wire generated code"},
+	}, regions)
+}
+
+func TestApplyWiringExclusions(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "main.go")
+	err := os.WriteFile(fileName, []byte("package main\n\nfunc main() {\n\tprintln(1)\n}\n\nfunc run() {\n\tprintln(2)\n}\n"), 0644)
+	require.NoError(t, err)
+
+	cov := New([]*Profile{newTestProfile(fileName,
+		ProfileBlock{StartLine: 3, StartCol: 13, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+		ProfileBlock{StartLine: 7, StartCol: 12, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 1},
+	)})
+
+	err = ApplyWiringExclusions(cov, nil)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, cov.TotalStmt)
+	assert.EqualValues(t, 1, cov.CoveredStmt)
+	assert.Equal(t, []Exclusion{
+		{FileName: fileName, StartLine: 3, EndLine: 5, NumStmt: 1, Reason: "func main"},
+	}, cov.Exclusions)
+}
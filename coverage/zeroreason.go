@@ -0,0 +1,132 @@
+package coverage
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The reasons why a changed file has no coverage (see zeroCoverageReason).
+const (
+	reasonNotBuilt    = "file excluded by build tags"
+	reasonTestsFailed = "tests failed"
+	reasonGenerated   = "generated file"
+	reasonNoStmt      = "no statements"
+	reasonNoTests     = "no tests in package"
+)
+
+// generatedPattern matches the comment that marks generated Go files (see
+// "go help generate").
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// zeroCoverageReason explains why the changed file has a coverage of 0%, so a
+// bare 0.00% in the report does not leave readers guessing. It returns an
+// empty string if the file has coverage or the reason is unknown.
+func (r *Report) zeroCoverageReason(fileName string) string {
+	if _, ok := r.notBuilt(fileName); ok {
+		return reasonNotBuilt
+	}
+
+	profile := r.New.Files[fileName]
+	if profile.GetCovered() > 0 {
+		return ""
+	}
+
+	pkg := path.Dir(fileName)
+	switch {
+	case slices.Contains(r.FailedPackages, pkg):
+		return reasonTestsFailed
+	case isGeneratedFile(r.source, fileName):
+		return reasonGenerated
+	case r.hasNoStatements(fileName, profile):
+		return reasonNoStmt
+	case slices.Contains(r.UntestedPackages, pkg), !packageHasTests(r.source, fileName):
+		return reasonNoTests
+	}
+
+	return ""
+}
+
+// hasNoStatements returns whether the changed file has no statements. Since
+// "go test -coverprofile" does not write a profile for such a file, this is
+// the case if the source file exists but its package has profiles of other
+// files only.
+func (r *Report) hasNoStatements(fileName string, profile *Profile) bool {
+	if profile != nil {
+		return profile.GetTotal() == 0
+	}
+
+	if _, ok := r.source.find(fileName); !ok {
+		return false // e.g. a deleted file
+	}
+
+	pkg := path.Dir(fileName)
+	for name := range r.New.Files {
+		if path.Dir(name) == pkg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ZeroCoverageReasons returns the reason of each changed file (except test
+// files) with a coverage of 0% whose reason is known.
+func (r *Report) ZeroCoverageReasons() map[string]string {
+	reasons := map[string]string{}
+	for _, name := range r.ChangedFiles {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if reason := r.zeroCoverageReason(name); reason != "" {
+			reasons[name] = reason
+		}
+	}
+
+	return reasons
+}
+
+// isGeneratedFile returns whether the source file of the coverage profile
+// name has the comment of generated files before its package clause.
+func isGeneratedFile(tree sourceTree, fileName string) bool {
+	sourcePath, ok := tree.find(fileName)
+	if !ok {
+		return false
+	}
+
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if generatedPattern.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+
+	return false
+}
+
+// packageHasTests returns whether the directory of the source file contains
+// test files. If the source code is not available or was fetched without the
+// rest of the package, it is assumed that it does.
+func packageHasTests(tree sourceTree, fileName string) bool {
+	sourcePath, ok := tree.find(fileName)
+	if !ok || tree.isFetched(sourcePath) {
+		return true
+	}
+
+	tests, err := filepath.Glob(filepath.Join(filepath.Dir(sourcePath), "*_test.go"))
+	return err != nil || len(tests) > 0
+}
@@ -0,0 +1,75 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_ZeroCoverageReasons(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"gen/types.go":          "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage gen\n",
+		"gen/types_test.go":     "package gen\n",
+		"untested/untested.go":  "package untested\n\nfunc f() {}\n",
+		"tested/tested.go":      "package tested\n\nfunc f() {}\n",
+		"tested/tested_test.go": "package tested\n",
+		"tested/consts.go":      "package tested\n\nconst c = 1\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+
+	const pkg = "example.com/app/"
+	// Like "go test -coverprofile", the coverage has no profile of
+	// tested/consts.go, which has no statements.
+	uncovered := ProfileBlock{StartLine: 3, StartCol: 1, EndLine: 3, EndCol: 12, NumStmt: 1, Count: 0}
+	newCov := New([]*Profile{
+		newTestProfile(pkg+"gen/types.go", uncovered),
+		newTestProfile(pkg+"untested/untested.go", uncovered),
+		newTestProfile(pkg+"tested/tested.go", uncovered),
+		newTestProfile(pkg+"failing/failing.go", uncovered),
+		newTestProfile(pkg+"covered/covered.go", ProfileBlock{StartLine: 3, EndLine: 3, NumStmt: 1, Count: 1}),
+	})
+
+	report := NewReport(New(nil), newCov, []string{
+		pkg + "covered/covered.go",
+		pkg + "failing/failing.go",
+		pkg + "gen/types.go",
+		pkg + "sys/sys_windows.go",
+		pkg + "tested/consts.go",
+		pkg + "tested/deleted.go",
+		pkg + "tested/tested.go",
+		pkg + "tested/tested_test.go",
+		pkg + "untested/untested.go",
+	})
+	report.source = sourceTree{root: root}
+	report.FailedPackages = []string{pkg + "failing"}
+	report.NotBuilt = []NotBuiltFile{{FileName: pkg + "sys/sys_windows.go", Platform: "linux/amd64", Constraint: "file name"}}
+
+	assert.Equal(t, map[string]string{
+		pkg + "failing/failing.go":   "tests failed",
+		pkg + "gen/types.go":         "generated file",
+		pkg + "sys/sys_windows.go":   "file excluded by build tags",
+		pkg + "tested/consts.go":     "no statements",
+		pkg + "untested/untested.go": "no tests in package",
+	}, report.ZeroCoverageReasons())
+
+	markdown := report.Analyze().Markdown()
+	assert.Contains(t, markdown, "| Changed File | Coverage Δ | Total | Covered | Missed | Reason | :robot: |\n")
+	assert.Contains(t, markdown, "| "+pkg+"gen/types.go | 0.00% (ø) | 1 (+1) | 0 | 1 (+1) | generated file |  |\n")
+	assert.Contains(t, markdown, "| "+pkg+"covered/covered.go | 100.00% (**+100.00%**) | 1 (+1) | 1 (+1) | 0 |  | :star2: |\n")
+
+	report.Layout = layoutDrilldown
+	assert.Contains(t, report.Analyze().Markdown(), "<summary>untested.go · 0.00% (ø) · 1 (+1) statements, 1 (+1) missed · new code 0.00% (0/1 statements) · no tests in package</summary>\n")
+
+	report.TrimPrefix("example.com/app")
+	assert.Equal(t, "tests failed", report.ZeroCoverageReasons()["failing/failing.go"])
+
+	// Without a file of 0% with a known reason, the table has no reason column.
+	report = NewReport(New(nil), newCov, []string{pkg + "covered/covered.go"})
+	assert.NotContains(t, report.Analyze().Markdown(), "| Reason |")
+}
@@ -2,9 +2,16 @@
 
 set -e -o pipefail
 
-type gh > /dev/null 2>&1 || { echo >&2 'ERROR: Script requires "gh" (see https://cli.github.com)'; exit 1; }
 type go-coverage-report > /dev/null 2>&1 || { echo >&2 'ERROR: Script requires "go-coverage-report" binary in PATH'; exit 1; }
 
+# Releases that contain the action subcommand implement all of the following
+# steps (and the inputs added since) in Go. Older releases fail on "action -h".
+if go-coverage-report action -h > /dev/null 2>&1; then
+  exec go-coverage-report action
+fi
+
+type gh > /dev/null 2>&1 || { echo >&2 'ERROR: Script requires "gh" (see https://cli.github.com)'; exit 1; }
+
 USAGE="$0: Execute go-coverage-report as GitHub action.
 
 This script is meant to be used as a GitHub action and makes use of Workflow commands as
@@ -32,9 +39,17 @@ You can use the following environment variables to configure the script:
 - CHANGED_FILES_PATH: The path to the file containing the list of changed files (default: .github/outputs/all_modified_files.json)
 - ROOT_PACKAGE: The import path of the tested repository to add as a prefix to all paths of the changed files (optional)
 - TRIM_PACKAGE: Trim a prefix in the \"Impacted Packages\" column of the markdown report (optional)
+- ONLY: Comma separated glob patterns (e.g. "pkg/service/**") to restrict the report to matching files (optional)
+- TIMEOUT: Abort go-coverage-report if it takes longer than this duration, e.g. "5m" (optional)
 - SKIP_COMMENT: Skip creating or updating the pull request comment (default: false)
+- COMMENT_MODE: Where to post the report: "comment" or "description" to keep it in a section of the pull request description (default: comment)
+- PASSING_LABEL: Label to add to the pull request when the coverage checks pass and remove otherwise (optional)
+- FAILING_LABEL: Label to add to the pull request when the coverage checks fail and remove otherwise (optional)
+- ESCALATION_TEAM: Team ("org/team-slug") whose review is requested when the overall coverage drops by more than ESCALATION_THRESHOLD (optional)
+- ESCALATION_THRESHOLD: Drop of the overall coverage in percentage points that requires a review by ESCALATION_TEAM (default: 0, disabled)
 - MIN_COVERAGE_NEW_CODE: Minimum coverage threshold for new code in percentage (default: 0, disabled)
 - USE_GIT_DIFF: Use git diff for line-level coverage calculation (default: true)
+- EXCLUDE_WIRING: Exclude func main and dependency injection wiring code from the coverage calculation (default: false)
 "
 
 if [[ $# != 3 ]]; then
@@ -52,13 +67,23 @@ COVERAGE_ARTIFACT_NAME=${COVERAGE_ARTIFACT_NAME:-code-coverage}
 COVERAGE_FILE_NAME=${COVERAGE_FILE_NAME:-coverage.txt}
 MIN_COVERAGE_NEW_CODE=${MIN_COVERAGE_NEW_CODE:-0}
 USE_GIT_DIFF=${USE_GIT_DIFF:-true}
+EXCLUDE_WIRING=${EXCLUDE_WIRING:-false}
+ESCALATION_THRESHOLD=${ESCALATION_THRESHOLD:-0}
 
 OLD_COVERAGE_PATH=.github/outputs/old-coverage.txt
 NEW_COVERAGE_PATH=.github/outputs/new-coverage.txt
 COVERAGE_COMMENT_PATH=.github/outputs/coverage-comment.md
+COVERAGE_JSON_PATH=.github/outputs/coverage-report.json
 DIFF_FILE_PATH=.github/outputs/pr-diff.patch
 CHANGED_FILES_PATH=${CHANGED_FILES_PATH:-.github/outputs/all_modified_files.json}
 SKIP_COMMENT=${SKIP_COMMENT:-false}
+COMMENT_MODE=${COMMENT_MODE:-comment}
+DESCRIPTION_PATH=.github/outputs/pr-description.md
+
+if [[ "$COMMENT_MODE" != "comment" && "$COMMENT_MODE" != "description" ]]; then
+    echo "Invalid COMMENT_MODE \"$COMMENT_MODE\": must be \"comment\" or \"description\""
+    exit 1
+fi
 
 if [[ -z ${GITHUB_REPOSITORY+x} ]]; then
     echo "Missing github_repository argument"
@@ -87,6 +112,10 @@ fi
 
 export GH_REPO="$GITHUB_REPOSITORY"
 
+if [[ -n "$TIMEOUT" ]]; then
+    export GO_COVERAGE_REPORT_TIMEOUT="$TIMEOUT"
+fi
+
 start_group(){
     echo "::group::$*"
     { set -x; return; } 2>/dev/null
@@ -132,6 +161,14 @@ if [ "$USE_GIT_DIFF" = "true" ]; then
 else
   echo "Git diff disabled, using block-based comparison"
 fi
+
+if [ ! -f "$DIFF_FILE_PATH" ]; then
+  # Let the block-based comparison match moved code by reading the old source code from the baseline commit.
+  BASELINE_SHA=$(gh run view "$LAST_SUCCESSFUL_RUN_ID" --json=headSha -q .headSha)
+  if git fetch --depth=1 origin "$BASELINE_SHA" 2>/dev/null || git cat-file -e "$BASELINE_SHA^{commit}" 2>/dev/null; then
+    BASE_REF=$BASELINE_SHA
+  fi
+fi
 end_group
 
 start_group "Compare code coverage results"
@@ -139,9 +176,14 @@ start_group "Compare code coverage results"
 set +e
 
 # Build the command arguments
-COVERAGE_ARGS=(-root="$ROOT_PACKAGE" -trim="$TRIM_PACKAGE" -min-coverage="$MIN_COVERAGE_NEW_CODE")
+COVERAGE_ARGS=(-root="$ROOT_PACKAGE" -trim="$TRIM_PACKAGE" -min-coverage="$MIN_COVERAGE_NEW_CODE" -exclude-wiring="$EXCLUDE_WIRING")
+if [ -n "$ONLY" ]; then
+  COVERAGE_ARGS+=(-only="$ONLY")
+fi
 if [ -f "$DIFF_FILE_PATH" ]; then
   COVERAGE_ARGS+=(-diff="$DIFF_FILE_PATH")
+elif [ -n "$BASE_REF" ]; then
+  COVERAGE_ARGS+=(-base-ref="$BASE_REF")
 fi
 COVERAGE_ARGS+=("$OLD_COVERAGE_PATH" "$NEW_COVERAGE_PATH" "$CHANGED_FILES_PATH")
 
@@ -163,22 +205,91 @@ echo "Writing GitHub output parameter to \"$GITHUB_OUTPUT\""
   echo "END_OF_COVERAGE_REPORT"
 } >> "$GITHUB_OUTPUT"
 
+if [ -n "$PASSING_LABEL" ] || [ -n "$FAILING_LABEL" ]; then
+  start_group "Update pull request labels"
+  if [ $COVERAGE_EXIT_CODE -eq 0 ]; then
+    ADD_LABEL=$PASSING_LABEL
+    REMOVE_LABEL=$FAILING_LABEL
+  else
+    ADD_LABEL=$FAILING_LABEL
+    REMOVE_LABEL=$PASSING_LABEL
+  fi
+
+  LABEL_ARGS=()
+  if [ -n "$ADD_LABEL" ]; then
+    # Labels must exist in the repository before they can be added.
+    gh label create "$ADD_LABEL" --description="Set by go-coverage-report" > /dev/null 2>&1 || true
+    LABEL_ARGS+=(--add-label="$ADD_LABEL")
+  fi
+  if [ -n "$REMOVE_LABEL" ] && gh pr view "$GITHUB_PULL_REQUEST_NUMBER" --json=labels -q '.labels[].name' | grep -Fqx "$REMOVE_LABEL"; then
+    LABEL_ARGS+=(--remove-label="$REMOVE_LABEL")
+  fi
+  if [ ${#LABEL_ARGS[@]} -gt 0 ]; then
+    gh pr edit "$GITHUB_PULL_REQUEST_NUMBER" "${LABEL_ARGS[@]}"
+  fi
+  end_group
+fi
+
+if [ -n "$ESCALATION_TEAM" ] && [ "$ESCALATION_THRESHOLD" != "0" ]; then
+  start_group "Check coverage regression escalation"
+  go-coverage-report -format=json "${COVERAGE_ARGS[@]}" > "$COVERAGE_JSON_PATH" 2>/dev/null || true
+  ESCALATE=$(jq --argjson max "$ESCALATION_THRESHOLD" '
+    def percent: if .TotalStmt > 0 then .CoveredStmt / .TotalStmt * 100 else 0 end;
+    (.Old | percent) - (.New | percent) > $max' "$COVERAGE_JSON_PATH")
+
+  CHECK_CONCLUSION=success
+  CHECK_TITLE="No review required"
+  CHECK_SUMMARY="The overall coverage did not drop by more than $ESCALATION_THRESHOLD%."
+  if [ "$ESCALATE" = "true" ]; then
+    # An approval by any active member of the escalation team resolves the escalation.
+    APPROVED=false
+    for LOGIN in $(gh api "repos/${GITHUB_REPOSITORY}/pulls/${GITHUB_PULL_REQUEST_NUMBER}/reviews" --paginate -q '.[] | select(.state=="APPROVED") | .user.login' | sort -u); do
+      if [ "$(gh api "orgs/${ESCALATION_TEAM%%/*}/teams/${ESCALATION_TEAM#*/}/memberships/$LOGIN" -q .state 2>/dev/null)" = "active" ]; then
+        APPROVED=true
+        CHECK_TITLE="Approved by @$LOGIN"
+        break
+      fi
+    done
+
+    CHECK_SUMMARY="The overall coverage dropped by more than $ESCALATION_THRESHOLD%, which requires an approval by @$ESCALATION_TEAM."
+    if [ "$APPROVED" = "false" ]; then
+      echo "::warning::Overall coverage dropped by more than $ESCALATION_THRESHOLD%, requesting review from $ESCALATION_TEAM"
+      gh pr edit "$GITHUB_PULL_REQUEST_NUMBER" --add-reviewer="$ESCALATION_TEAM"
+      CHECK_CONCLUSION=neutral
+      CHECK_TITLE="Waiting for approval by @$ESCALATION_TEAM"
+    fi
+  fi
+
+  HEAD_SHA=$(gh pr view "$GITHUB_PULL_REQUEST_NUMBER" --json=headRefOid -q .headRefOid)
+  gh api "repos/${GITHUB_REPOSITORY}/check-runs" --silent \
+    -f name="Coverage regression review" -f head_sha="$HEAD_SHA" -f status=completed -f conclusion="$CHECK_CONCLUSION" \
+    -f "output[title]=$CHECK_TITLE" -f "output[summary]=$CHECK_SUMMARY"
+  end_group
+fi
+
 if [ "$SKIP_COMMENT" = "true" ]; then
   echo "Skipping pull request comment (\$SKIP_COMMENT=true))"
   exit 0
 fi
 
-start_group "Comment on pull request"
-COMMENT_ID=$(gh api "repos/${GITHUB_REPOSITORY}/issues/${GITHUB_PULL_REQUEST_NUMBER}/comments" -q '.[] | select(.user.login=="github-actions[bot]" and (.body | test("Coverage Δ")) ) | .id' | head -n 1)
-if [ -z "$COMMENT_ID" ]; then
-  echo "Creating new coverage report comment"
+if [ "$COMMENT_MODE" = "description" ]; then
+  start_group "Update pull request description"
+  gh pr view "$GITHUB_PULL_REQUEST_NUMBER" --json=body -q .body | go-coverage-report description "$COVERAGE_COMMENT_PATH" > "$DESCRIPTION_PATH"
+  gh pr edit "$GITHUB_PULL_REQUEST_NUMBER" --body-file="$DESCRIPTION_PATH"
+  end_group
 else
-  echo "Replacing old coverage report comment"
-  gh api -X DELETE "repos/${GITHUB_REPOSITORY}/issues/comments/${COMMENT_ID}"
-fi
+  start_group "Comment on pull request"
+  COMMENT_ID=$(gh api "repos/${GITHUB_REPOSITORY}/issues/${GITHUB_PULL_REQUEST_NUMBER}/comments" -q '.[] | select(.user.login=="github-actions[bot]" and (.body | test("Coverage Δ")) ) | .id' | head -n 1)
+  if [ -z "$COMMENT_ID" ]; then
+    echo "Creating new coverage report comment"
+  else
+    echo "Replacing old coverage report comment"
+    gh api -X DELETE "repos/${GITHUB_REPOSITORY}/issues/comments/${COMMENT_ID}"
+  fi
 
-gh pr comment "$GITHUB_PULL_REQUEST_NUMBER" --body-file=$COVERAGE_COMMENT_PATH
-end_group
+  gh pr comment "$GITHUB_PULL_REQUEST_NUMBER" --body-file=$COVERAGE_COMMENT_PATH
+  end_group
+fi
 
 # Now check if the coverage report failed the threshold check
 if [ $COVERAGE_EXIT_CODE -ne 0 ]; then